@@ -323,7 +323,7 @@ func (c *cmac) checkSum() []byte {
 		subtle.XORBytes(c.tag, c.x, c.tag)
 		subtle.XORBytes(c.tag, c.k1, c.tag)
 	default:
-		subtle.XORBytes(c.tag, c.x, c.tag)
+		subtle.XORBytes(c.tag, c.x[:c.nx], c.tag)
 		c.tag[c.nx] ^= 0b10000000
 		subtle.XORBytes(c.tag, c.k2, c.tag)
 	}
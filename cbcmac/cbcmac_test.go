@@ -415,6 +415,26 @@ func TestCMAC(t *testing.T) {
 	}
 }
 
+func TestCMACSplitWrites(t *testing.T) {
+	key := []byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef, 0xfe, 0xdc, 0xba, 0x98, 0x76, 0x54, 0x32, 0x10}
+	block, err := sm4.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := []byte("This is the test message for mac, This is the test message ")
+	for l := 0; l <= len(src); l++ {
+		expected := cbcmac.NewCMAC(block, 16).MAC(src[:l])
+		for i := 0; i <= l; i++ {
+			mac := cbcmac.NewCMAC(block, 16)
+			mac.Write(src[:i])
+			mac.Write(src[i:l])
+			if tag := mac.Sum(nil); !bytes.Equal(tag, expected) {
+				t.Fatalf("length %d split at %d: expect tag %x, got %x", l, i, expected, tag)
+			}
+		}
+	}
+}
+
 func TestLMAC(t *testing.T) {
 	// Test vectors from GB/T 15821.1-2020 Appendix B.
 	cases := []struct {
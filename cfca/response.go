@@ -0,0 +1,107 @@
+// Copyright 2024 Sun Yimin. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cfca
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+
+	"github.com/yunmoon/gmsm/sm2"
+	"github.com/yunmoon/gmsm/smx509"
+)
+
+// resultCodeSuccess is the result code of a successful application.
+const resultCodeSuccess = "0000"
+
+// ResponseError is returned when CFCA reports that a certificate application
+// failed, with the result code and message of the response.
+type ResponseError struct {
+	Code    string
+	Message string
+}
+
+func (e *ResponseError) Error() string {
+	return "cfca: certificate application failed with result code " + e.Code + ": " + e.Message
+}
+
+// Response holds the fields of CFCA's response to a dual certificate
+// application.
+type Response struct {
+	// ResultCode and ResultMessage are the result of the application.
+	// ResultCode is "0000", or empty, on success.
+	ResultCode    string
+	ResultMessage string
+
+	// SignCertificate and EncryptCertificate are PEM or base64 encoded.
+	SignCertificate    []byte
+	EncryptCertificate []byte
+	// EncryptPrivateKey is the escrowed encryption private key, in the format
+	// of [ParseEscrowPrivateKey].
+	EncryptPrivateKey []byte
+}
+
+// EnvelopedResponse represents the certificates and the encryption private key
+// CFCA returns for a dual certificate application.
+type EnvelopedResponse struct {
+	SignCertificate    *smx509.Certificate
+	EncryptCertificate *smx509.Certificate
+	EncryptPrivateKey  *sm2.PrivateKey
+}
+
+// ParseEnvelopedResponse parses CFCA's response to a dual certificate application,
+// and checks that the encryption private key matches the encryption certificate.
+// A failed application is reported as [*ResponseError].
+//
+// The encryption private key is decrypted with the temporary private key whose public key
+// was embedded in the certificate request, see [CreateCertificateRequest].
+func ParseEnvelopedResponse(resp *Response, tmpPriv *sm2.PrivateKey) (*EnvelopedResponse, error) {
+	if resp == nil {
+		return nil, errors.New("cfca: missing response")
+	}
+	if resp.ResultCode != "" && resp.ResultCode != resultCodeSuccess {
+		return nil, &ResponseError{Code: resp.ResultCode, Message: resp.ResultMessage}
+	}
+	if tmpPriv == nil {
+		return nil, errors.New("cfca: missing temporary private key")
+	}
+	signCertificate, err := parseResponseCertificate(resp.SignCertificate)
+	if err != nil {
+		return nil, err
+	}
+	encCertificate, err := parseResponseCertificate(resp.EncryptCertificate)
+	if err != nil {
+		return nil, err
+	}
+	encPrivateKey, err := ParseEscrowPrivateKey(tmpPriv, resp.EncryptPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	if !encPrivateKey.PublicKey.Equal(encCertificate.PublicKey) {
+		return nil, errors.New("cfca: encrypt key pair mismatch")
+	}
+	return &EnvelopedResponse{
+		SignCertificate:    signCertificate,
+		EncryptCertificate: encCertificate,
+		EncryptPrivateKey:  encPrivateKey,
+	}, nil
+}
+
+// parseResponseCertificate parses a PEM or base64 encoded certificate.
+func parseResponseCertificate(data []byte) (*smx509.Certificate, error) {
+	data = bytes.TrimSpace(data)
+	if block, _ := pem.Decode(data); block != nil {
+		if block.Type != "CERTIFICATE" {
+			return nil, errors.New("cfca: unexpected PEM block type " + block.Type)
+		}
+		return smx509.ParseCertificate(block.Bytes)
+	}
+	der, err := base64.StdEncoding.DecodeString(string(data))
+	if err != nil {
+		return nil, err
+	}
+	return smx509.ParseCertificate(der)
+}
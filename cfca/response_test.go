@@ -0,0 +1,130 @@
+// Copyright 2024 Sun Yimin. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cfca
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/yunmoon/gmsm/sm2"
+	"github.com/yunmoon/gmsm/smx509"
+)
+
+// The escrowed encryption key of the first case of TestParseEscrowPrivateKey,
+// with its temporary and encryption keys.
+const (
+	escrowTmpKeyHex = "cacece36cac24aab94e52bcd5c0f552c95028f2856053135a1e47510b4c307ba"
+	escrowEncKeyHex = "f6e02c941a0dfdac58d8b3b1bc1bd136f179741b7465ebc7b0b25bb381840a3b"
+	escrowEncKey    = "00000000000000010000000000000001000000000000000000000000000000000000000000000268MIHGAgEBBIHArhtKwTVT8dPEkykVRpvQNMxHv/yeqtaKZiSp2MbjcqMZtPfKW8IatiIPPitNhQtU5C7gMbsUxgf5Yo16vDSXdoWqoOOaes2pEJwmXWZI55lMMWc168WgzQ82fmMi05Vhlw9HNjGI3azE6MS5/ujSNGLZ0qAAmLnBiHlXFAXXAWRiy9MxZKwF4xKn6qMaKmkqbYmTbBbEJEhzJBmu0IJ1kNDcTFirAyapghHSw267erSUwsHjkQis9mKYpzGied0E"
+)
+
+func TestParseEnvelopedResponse(t *testing.T) {
+	issuer, err := createTestSM2CertificateByIssuer("CFCA TEST CA", nil, smx509.SM2WithSM3, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signPair, err := createTestSM2CertificateByIssuer("Sign", issuer, smx509.SM2WithSM3, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpKeyBytes, _ := hex.DecodeString(escrowTmpKeyHex)
+	tmpKey, err := sm2.NewPrivateKey(tmpKeyBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encKeyBytes, _ := hex.DecodeString(escrowEncKeyHex)
+	encKey, err := sm2.NewPrivateKey(encKeyBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "Enc", Organization: []string{"Acme Co"}},
+		NotBefore:    time.Now().Add(-1 * time.Second),
+		NotAfter:     time.Now().AddDate(1, 0, 0),
+		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDataEncipherment,
+	}
+	encCertDER, err := smx509.CreateCertificate(rand.Reader, template, issuer.Certificate.ToX509(), &encKey.PublicKey, issuer.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signCertBase64 := []byte(base64.StdEncoding.EncodeToString(signPair.Certificate.Raw))
+	encCertPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: encCertDER})
+
+	response := &Response{
+		ResultCode:         "0000",
+		ResultMessage:      "success",
+		SignCertificate:    signCertBase64,
+		EncryptCertificate: encCertPEM,
+		EncryptPrivateKey:  []byte(escrowEncKey),
+	}
+	resp, err := ParseEnvelopedResponse(response, tmpKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.SignCertificate.Equal(signPair.Certificate) {
+		t.Errorf("sign certificate mismatch")
+	}
+	if string(resp.EncryptCertificate.Raw) != string(encCertDER) {
+		t.Errorf("encrypt certificate mismatch")
+	}
+	if !resp.EncryptPrivateKey.Equal(encKey) {
+		t.Errorf("encrypt private key mismatch")
+	}
+
+	// without result code
+	withoutCode := *response
+	withoutCode.ResultCode, withoutCode.ResultMessage = "", ""
+	if _, err = ParseEnvelopedResponse(&withoutCode, tmpKey); err != nil {
+		t.Errorf("response without result code: %v", err)
+	}
+
+	// failed application
+	failed := &Response{ResultCode: "3001", ResultMessage: "certificate already exists"}
+	_, err = ParseEnvelopedResponse(failed, tmpKey)
+	var respErr *ResponseError
+	if !errors.As(err, &respErr) || respErr.Code != "3001" || respErr.Message != "certificate already exists" {
+		t.Errorf("expected response error, got %v", err)
+	}
+
+	// missing temporary key
+	if _, err = ParseEnvelopedResponse(response, nil); err == nil {
+		t.Errorf("expected error without temporary key")
+	}
+
+	// wrong temporary key
+	otherKey, _ := sm2.GenerateKey(rand.Reader)
+	if _, err = ParseEnvelopedResponse(response, otherKey); err == nil {
+		t.Errorf("expected error with wrong temporary key")
+	}
+
+	// encrypt certificate does not match the returned key
+	mismatch := *response
+	mismatch.EncryptCertificate = signCertBase64
+	_, err = ParseEnvelopedResponse(&mismatch, tmpKey)
+	if err == nil || err.Error() != "cfca: encrypt key pair mismatch" {
+		t.Errorf("expected key pair mismatch error, got %v", err)
+	}
+
+	// invalid certificates
+	invalid := *response
+	invalid.SignCertificate = []byte("not base64")
+	if _, err = ParseEnvelopedResponse(&invalid, tmpKey); err == nil {
+		t.Errorf("expected error with invalid sign certificate")
+	}
+	invalid = *response
+	invalid.EncryptCertificate = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: encCertDER})
+	if _, err = ParseEnvelopedResponse(&invalid, tmpKey); err == nil {
+		t.Errorf("expected error with unexpected PEM block type")
+	}
+}
@@ -28,6 +28,9 @@ const (
 
 var ErrReseedRequired = errors.New("drbg: reseed reuqired")
 
+// ErrNotInstantiated is returned when a DRBG is used before instantiation.
+var ErrNotInstantiated = errors.New("drbg: not instantiated")
+
 type SecurityLevel byte
 
 const (
@@ -38,9 +41,10 @@ const (
 
 // DrbgPrng sample pseudo random number generator base on DRBG
 type DrbgPrng struct {
-	entropySource    io.Reader
-	securityStrength int
-	impl             DRBG
+	entropySource        io.Reader
+	securityStrength     int
+	predictionResistance bool
+	impl                 DRBG
 }
 
 // NewCtrDrbgPrng create pseudo random number generator base on CTR DRBG
@@ -185,7 +189,30 @@ func (prng *DrbgPrng) getEntropy(entropyInput []byte) error {
 	return nil
 }
 
+// SetPredictionResistance enables or disables prediction resistance. When enabled,
+// the DRBG is reseeded with fresh entropy before every generate request.
+func (prng *DrbgPrng) SetPredictionResistance(enabled bool) {
+	prng.predictionResistance = enabled
+}
+
+func (prng *DrbgPrng) reseed() error {
+	entropyInput := make([]byte, prng.securityStrength)
+	err := prng.getEntropy(entropyInput)
+	if err != nil {
+		return err
+	}
+	return prng.impl.Reseed(entropyInput, nil)
+}
+
 func (prng *DrbgPrng) Read(data []byte) (int, error) {
+	if prng.impl == nil {
+		return 0, ErrNotInstantiated
+	}
+	if hs, ok := prng.entropySource.(*HealthTestedSource); ok {
+		if err := hs.Err(); err != nil {
+			return 0, err
+		}
+	}
 	maxBytesPerRequest := prng.impl.MaxBytesPerRequest()
 	total := 0
 
@@ -195,15 +222,14 @@ func (prng *DrbgPrng) Read(data []byte) (int, error) {
 			b = data[:maxBytesPerRequest]
 		}
 
-		err := prng.impl.Generate(b, nil)
-		if err == ErrReseedRequired {
-			entropyInput := make([]byte, prng.securityStrength)
-			err := prng.getEntropy(entropyInput)
-			if err != nil {
+		if prng.predictionResistance {
+			if err := prng.reseed(); err != nil {
 				return 0, err
 			}
-			err = prng.impl.Reseed(entropyInput, nil)
-			if err != nil {
+		}
+		err := prng.impl.Generate(b, nil)
+		if err == ErrReseedRequired {
+			if err := prng.reseed(); err != nil {
 				return 0, err
 			}
 		} else if err != nil {
@@ -3,10 +3,23 @@ package drbg
 import (
 	"bytes"
 	"crypto/aes"
+	"crypto/rand"
 	"crypto/sha256"
+	"io"
 	"testing"
+
+	"github.com/yunmoon/gmsm/sm2"
 )
 
+type countingReader struct {
+	n int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	r.n++
+	return rand.Read(p)
+}
+
 func TestGmCtrDrbgPrng(t *testing.T) {
 	prng, err := NewGmCtrDrbgPrng(nil, 32, SECURITY_LEVEL_TEST, nil)
 	if err != nil {
@@ -121,3 +134,83 @@ func TestGMSecurityStrengthValidation(t *testing.T) {
 		t.Fatalf("expected error here")
 	}
 }
+
+func TestGmHashDrbgPrngPredictionResistance(t *testing.T) {
+	entropy := &countingReader{}
+	prng, err := NewGmHashDrbgPrng(entropy, 32, SECURITY_LEVEL_ONE, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// entropy input and nonce
+	if entropy.n != 2 {
+		t.Fatalf("unexpected entropy reads %d", entropy.n)
+	}
+	data := make([]byte, 64)
+	if _, err := prng.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	if entropy.n != 2 {
+		t.Fatalf("unexpected reseed without prediction resistance")
+	}
+	prng.SetPredictionResistance(true)
+	if _, err := prng.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	// two generate requests, each one is preceded by a reseed
+	if entropy.n != 4 {
+		t.Fatalf("unexpected entropy reads %d with prediction resistance", entropy.n)
+	}
+}
+
+func TestGmDrbgPrngInterchangeable(t *testing.T) {
+	newPrngs := map[string]func(entropySource io.Reader) (*DrbgPrng, error){
+		"ctr": func(entropySource io.Reader) (*DrbgPrng, error) {
+			return NewGmCtrDrbgPrng(entropySource, 32, SECURITY_LEVEL_TEST, nil)
+		},
+		"hash": func(entropySource io.Reader) (*DrbgPrng, error) {
+			return NewGmHashDrbgPrng(entropySource, 32, SECURITY_LEVEL_TEST, nil)
+		},
+	}
+	for name, newPrng := range newPrngs {
+		t.Run(name, func(t *testing.T) {
+			var prng io.Reader
+			prng, err := newPrng(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			priv, err := sm2.GenerateKey(prng)
+			if err != nil {
+				t.Fatal(err)
+			}
+			hashed := []byte("testing")
+			sig, err := sm2.SignASN1(prng, priv, hashed, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !sm2.VerifyASN1(&priv.PublicKey, hashed, sig) {
+				t.Errorf("verify failed")
+			}
+
+			// same entropy source, same output
+			entropy := bytes.Repeat([]byte{0x5a}, 1024)
+			prng1, err := newPrng(bytes.NewReader(entropy))
+			if err != nil {
+				t.Fatal(err)
+			}
+			prng2, err := newPrng(bytes.NewReader(entropy))
+			if err != nil {
+				t.Fatal(err)
+			}
+			out1, out2 := make([]byte, 100), make([]byte, 100)
+			if _, err = io.ReadFull(prng1, out1); err != nil {
+				t.Fatal(err)
+			}
+			if _, err = io.ReadFull(prng2, out2); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out1, out2) {
+				t.Errorf("expected same output with same entropy input")
+			}
+		})
+	}
+}
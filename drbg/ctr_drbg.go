@@ -76,6 +76,9 @@ func NewGMCtrDrbg(securityLevel SecurityLevel, entropy, nonce, personalization [
 }
 
 func (cd *CtrDrbg) Reseed(entropy, additional []byte) error {
+	if cd.cipherProvider == nil {
+		return ErrNotInstantiated
+	}
 	// here for the min length, we just check <=0 now
 	if len(entropy) == 0 || (cd.gm && len(entropy) < 32) || len(entropy) >= maxBytes {
 		return errors.New("drbg: invalid entropy length")
@@ -121,6 +124,9 @@ func (cd *CtrDrbg) MaxBytesPerRequest() int {
 
 // Generate CTR DRBG pseudorandom bits generate process.
 func (cd *CtrDrbg) Generate(out, additional []byte) error {
+	if cd.cipherProvider == nil {
+		return ErrNotInstantiated
+	}
 	if cd.NeedReseed() {
 		return ErrReseedRequired
 	}
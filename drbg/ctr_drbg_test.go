@@ -303,3 +303,13 @@ func TestGmCtrDRBG_Validation(t *testing.T) {
 		t.Fatalf("expected error here")
 	}
 }
+
+func TestCtrDrbgNotInstantiated(t *testing.T) {
+	cd := &CtrDrbg{}
+	if err := cd.Generate(make([]byte, 16), nil); err != ErrNotInstantiated {
+		t.Errorf("expected ErrNotInstantiated, got %v", err)
+	}
+	if err := cd.Reseed(make([]byte, 32), nil); err != ErrNotInstantiated {
+		t.Errorf("expected ErrNotInstantiated, got %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package drbg
+
+import (
+	"io"
+	"sync"
+)
+
+// defaultPool caches GM/T 0105-2021 hash DRBG instances, so that concurrent
+// readers never share an instance and don't need to lock.
+var defaultPool sync.Pool
+
+// defaultEntropySource is the entropy source of the default reader, nil means crypto/rand.
+var defaultEntropySource io.Reader
+
+type defaultReader struct{}
+
+// Default returns a process-wide reader backed by GM/T 0105-2021 SM3 hash DRBGs
+// seeded from crypto/rand. It is safe for concurrent use.
+//
+// The DRBGs are instantiated lazily on first use and reseeded automatically
+// according to the reseed counter and time intervals of security level one.
+// If the entropy source fails, Read returns the error instead of any output.
+func Default() io.Reader {
+	return defaultReader{}
+}
+
+func (defaultReader) Read(b []byte) (int, error) {
+	prng, _ := defaultPool.Get().(*DrbgPrng)
+	if prng == nil {
+		var err error
+		prng, err = NewGmHashDrbgPrng(defaultEntropySource, 32, SECURITY_LEVEL_ONE, nil)
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err := prng.Read(b)
+	if err != nil {
+		// drop the instance, the next call will instantiate a fresh one.
+		return n, err
+	}
+	defaultPool.Put(prng)
+	return n, nil
+}
@@ -0,0 +1,82 @@
+package drbg
+
+import (
+	"bytes"
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/yunmoon/gmsm/sm2"
+)
+
+type failingReader struct{}
+
+func (failingReader) Read(p []byte) (int, error) {
+	return 0, errors.New("entropy source failure")
+}
+
+func TestDefault(t *testing.T) {
+	var wg sync.WaitGroup
+	results := make([][]byte, 16)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			b := make([]byte, 100)
+			if _, err := Default().Read(b); err != nil {
+				t.Error(err)
+			}
+			results[i] = b
+		}(i)
+	}
+	wg.Wait()
+	for i := range results {
+		for j := i + 1; j < len(results); j++ {
+			if bytes.Equal(results[i], results[j]) {
+				t.Fatalf("same output from #%d and #%d", i, j)
+			}
+		}
+	}
+
+	priv, err := sm2.GenerateKey(Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := sm2.SignASN1(Default(), priv, []byte("testing"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sm2.VerifyASN1(&priv.PublicKey, []byte("testing"), sig) {
+		t.Errorf("verify failed")
+	}
+}
+
+func TestDefaultEntropyFailure(t *testing.T) {
+	defaultEntropySource = failingReader{}
+	defer func() { defaultEntropySource = nil }()
+	// drain the cached instances
+	for defaultPool.Get() != nil {
+	}
+	b := make([]byte, 32)
+	if n, err := Default().Read(b); err == nil || n != 0 {
+		t.Fatalf("expected error, got %d bytes", n)
+	}
+	if !bytes.Equal(b, make([]byte, 32)) {
+		t.Errorf("unexpected output on entropy failure")
+	}
+}
+
+func BenchmarkDefault(b *testing.B) {
+	// 64 goroutines
+	b.SetParallelism((64 + runtime.GOMAXPROCS(0) - 1) / runtime.GOMAXPROCS(0))
+	b.SetBytes(32)
+	b.RunParallel(func(pb *testing.PB) {
+		buf := make([]byte, 32)
+		for pb.Next() {
+			if _, err := Default().Read(buf); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -90,6 +90,9 @@ func NewGMHashDrbg(securityLevel SecurityLevel, entropy, nonce, personalization
 
 // Reseed hash DRBG reseed process. GM/T 0105-2021 has a little different with NIST.
 func (hd *HashDrbg) Reseed(entropy, additional []byte) error {
+	if hd.newHash == nil {
+		return ErrNotInstantiated
+	}
 	// here for the min length, we just check <=0 now
 	if len(entropy) == 0 || (hd.gm && len(entropy) < hd.hashSize) || len(entropy) >= maxBytes {
 		return errors.New("drbg: invalid entropy length")
@@ -160,6 +163,9 @@ func (hd *HashDrbg) MaxBytesPerRequest() int {
 // Generate hash DRBG pseudorandom bits process. GM/T 0105-2021 has a little different with NIST.
 // GM/T 0105-2021 can only generate no more than hash.Size bytes once.
 func (hd *HashDrbg) Generate(b, additional []byte) error {
+	if hd.newHash == nil {
+		return ErrNotInstantiated
+	}
 	if hd.NeedReseed() {
 		return ErrReseedRequired
 	}
@@ -249,3 +249,16 @@ func TestGmHashDRBG_Validation(t *testing.T) {
 		t.Fatalf("expected error here")
 	}
 }
+
+func TestHashDrbgNotInstantiated(t *testing.T) {
+	hd := &HashDrbg{}
+	if err := hd.Generate(make([]byte, 32), nil); err != ErrNotInstantiated {
+		t.Errorf("expected ErrNotInstantiated, got %v", err)
+	}
+	if err := hd.Reseed(make([]byte, 32), nil); err != ErrNotInstantiated {
+		t.Errorf("expected ErrNotInstantiated, got %v", err)
+	}
+	if _, err := new(DrbgPrng).Read(make([]byte, 32)); err != ErrNotInstantiated {
+		t.Errorf("expected ErrNotInstantiated, got %v", err)
+	}
+}
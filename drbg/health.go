@@ -0,0 +1,140 @@
+package drbg
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+const (
+	// default cutoff of the repetition count test, 1 + ceil(20/H) with H = 1 bit per byte sample.
+	defaultRepetitionCutoff = 21
+	// default window size of the adaptive proportion test for non-binary samples.
+	defaultProportionWindow = 512
+	// default cutoff of the adaptive proportion test with H = 1 bit per byte sample.
+	defaultProportionCutoff = 410
+)
+
+// ErrEntropySourceUnhealthy is returned once the continuous health tests of an
+// entropy source failed.
+var ErrEntropySourceUnhealthy = errors.New("drbg: entropy source is unhealthy")
+
+// HealthTestedSource wraps an entropy source with the continuous health tests
+// of GM/T 0105-2021 and NIST SP 800-90B 4.4, each byte read from the source is
+// a sample:
+//
+//   - the repetition count test fails if the same sample repeats repetitionCutoff times in a row;
+//   - the adaptive proportion test fails if the first sample of a window of
+//     proportionWindow samples occurs proportionCutoff times in that window.
+//
+// Once a test failed, the source is unhealthy and all subsequent reads fail with an error
+// wrapping [ErrEntropySourceUnhealthy]. A [DrbgPrng] using an unhealthy source refuses to
+// generate any output. It is safe for concurrent use.
+type HealthTestedSource struct {
+	source           io.Reader
+	repetitionCutoff int
+	proportionWindow int
+	proportionCutoff int
+
+	mu              sync.Mutex
+	err             error
+	last            byte
+	repetitions     int
+	reference       byte
+	windowSamples   int
+	referenceCounts int
+}
+
+// NewHealthTestedSource returns a health tested entropy source which reads from source.
+// Zero values of repetitionCutoff, proportionWindow and proportionCutoff select the
+// defaults 21, 512 and 410, which assume at least 1 bit of min-entropy per byte.
+func NewHealthTestedSource(source io.Reader, repetitionCutoff, proportionWindow, proportionCutoff int) (*HealthTestedSource, error) {
+	if source == nil {
+		return nil, errors.New("drbg: nil entropy source")
+	}
+	if repetitionCutoff == 0 {
+		repetitionCutoff = defaultRepetitionCutoff
+	}
+	if proportionWindow == 0 {
+		proportionWindow = defaultProportionWindow
+	}
+	if proportionCutoff == 0 {
+		proportionCutoff = defaultProportionCutoff
+	}
+	if repetitionCutoff < 2 || proportionWindow < 2 || proportionCutoff < 2 || proportionCutoff > proportionWindow {
+		return nil, errors.New("drbg: invalid health test parameters")
+	}
+	return &HealthTestedSource{
+		source:           source,
+		repetitionCutoff: repetitionCutoff,
+		proportionWindow: proportionWindow,
+		proportionCutoff: proportionCutoff,
+	}, nil
+}
+
+// Read reads entropy from the underlying source and runs the health tests on it.
+func (s *HealthTestedSource) Read(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return 0, s.err
+	}
+	n, err := io.ReadFull(s.source, p)
+	if err != nil {
+		return n, err
+	}
+	for _, sample := range p {
+		s.repetitionCountTest(sample)
+		s.adaptiveProportionTest(sample)
+		if s.err != nil {
+			clear(p)
+			return 0, s.err
+		}
+	}
+	return n, nil
+}
+
+func (s *HealthTestedSource) repetitionCountTest(sample byte) {
+	if s.repetitions > 0 && sample == s.last {
+		s.repetitions++
+		if s.repetitions >= s.repetitionCutoff {
+			s.err = fmt.Errorf("%w: repetition count test failed", ErrEntropySourceUnhealthy)
+		}
+		return
+	}
+	s.last = sample
+	s.repetitions = 1
+}
+
+func (s *HealthTestedSource) adaptiveProportionTest(sample byte) {
+	if s.windowSamples == 0 {
+		s.reference = sample
+		s.referenceCounts = 1
+		s.windowSamples = 1
+		return
+	}
+	if sample == s.reference {
+		s.referenceCounts++
+		if s.referenceCounts >= s.proportionCutoff {
+			s.err = fmt.Errorf("%w: adaptive proportion test failed", ErrEntropySourceUnhealthy)
+			return
+		}
+	}
+	s.windowSamples++
+	if s.windowSamples == s.proportionWindow {
+		s.windowSamples = 0
+	}
+}
+
+// Healthy reports whether the source passed all health tests so far.
+func (s *HealthTestedSource) Healthy() bool {
+	return s.Err() == nil
+}
+
+// Err returns the health test failure, or nil if the source is healthy.
+func (s *HealthTestedSource) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
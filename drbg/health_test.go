@@ -0,0 +1,109 @@
+package drbg
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+)
+
+type patternReader struct {
+	pattern []byte
+	pos     int
+}
+
+func (r *patternReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = r.pattern[r.pos%len(r.pattern)]
+		r.pos++
+	}
+	return len(p), nil
+}
+
+func TestHealthTestedSourceStuck(t *testing.T) {
+	src, err := NewHealthTestedSource(bytes.NewReader(make([]byte, 1024)), 0, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// detected within the repetition count cutoff
+	buf := make([]byte, defaultRepetitionCutoff)
+	if _, err := src.Read(buf); !errors.Is(err, ErrEntropySourceUnhealthy) {
+		t.Fatalf("expected ErrEntropySourceUnhealthy, got %v", err)
+	}
+	if src.Healthy() {
+		t.Errorf("expected unhealthy source")
+	}
+	if _, err := src.Read(buf); !errors.Is(err, ErrEntropySourceUnhealthy) {
+		t.Errorf("expected ErrEntropySourceUnhealthy for subsequent reads, got %v", err)
+	}
+}
+
+func TestHealthTestedSourceBiased(t *testing.T) {
+	// 95% zeros, runs are shorter than the repetition count cutoff
+	pattern := append(make([]byte, 19), 1)
+	src, err := NewHealthTestedSource(&patternReader{pattern: pattern}, 0, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 16)
+	read := 0
+	for read < defaultProportionWindow {
+		if _, err = src.Read(buf); err != nil {
+			break
+		}
+		read += len(buf)
+	}
+	if !errors.Is(err, ErrEntropySourceUnhealthy) {
+		t.Fatalf("expected ErrEntropySourceUnhealthy within %d samples, got %v", defaultProportionWindow, err)
+	}
+	if src.Err().Error() != "drbg: entropy source is unhealthy: adaptive proportion test failed" {
+		t.Errorf("unexpected error %v", src.Err())
+	}
+}
+
+func TestHealthTestedSourceHealthy(t *testing.T) {
+	src, err := NewHealthTestedSource(rand.Reader, 0, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.CopyN(io.Discard, src, 1<<16); err != nil {
+		t.Fatal(err)
+	}
+	if !src.Healthy() {
+		t.Errorf("expected healthy source, got %v", src.Err())
+	}
+	if _, err := NewHealthTestedSource(rand.Reader, 1, 0, 0); err == nil {
+		t.Errorf("expected error with invalid parameters")
+	}
+	if _, err := NewHealthTestedSource(rand.Reader, 0, 16, 32); err == nil {
+		t.Errorf("expected error with invalid parameters")
+	}
+}
+
+func TestDrbgPrngUnhealthySource(t *testing.T) {
+	entropy := make([]byte, 48)
+	rand.Read(entropy)
+	// healthy instantiation, then the source gets stuck
+	src, err := NewHealthTestedSource(io.MultiReader(bytes.NewReader(entropy), bytes.NewReader(make([]byte, 1024))), 0, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prng, err := NewGmHashDrbgPrng(src, 32, SECURITY_LEVEL_ONE, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 32)
+	if _, err := prng.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+	// a reseed reads from the stuck source
+	prng.SetPredictionResistance(true)
+	if _, err := prng.Read(buf); !errors.Is(err, ErrEntropySourceUnhealthy) {
+		t.Fatalf("expected ErrEntropySourceUnhealthy, got %v", err)
+	}
+	prng.SetPredictionResistance(false)
+	if _, err := prng.Read(buf); !errors.Is(err, ErrEntropySourceUnhealthy) {
+		t.Fatalf("expected ErrEntropySourceUnhealthy for subsequent reads, got %v", err)
+	}
+}
@@ -162,6 +162,147 @@ func TestBilinearity(t *testing.T) {
 	}
 }
 
+// refPoint is an affine point of the curve over GF(p¹²), used by the textbook
+// reference pairing.
+type refPoint struct {
+	x, y gfP12
+}
+
+// refUntwist maps the twist point q to the curve over GF(p¹²). The twist is
+// y² = x³ + 5u = x³ + 5w⁶, so (x, y) maps to (x/w², y/w³).
+func refUntwist(q *twistPoint) *refPoint {
+	a := &twistPoint{}
+	a.Set(q)
+	a.MakeAffine()
+	w2, w3 := &gfP12{}, &gfP12{}
+	w2.x.y.SetOne()
+	w3.z.x.SetOne()
+	w2.Invert(w2)
+	w3.Invert(w3)
+
+	r := &refPoint{}
+	r.x.z.y.Set(&a.x)
+	r.x.Mul(&r.x, w2)
+	r.y.z.y.Set(&a.y)
+	r.y.Mul(&r.y, w3)
+	return r
+}
+
+func refIsOnCurve(r *refPoint) bool {
+	y2 := (&gfP12{}).Square(&r.y)
+	x3 := (&gfP12{}).Square(&r.x)
+	x3.Mul(x3, &r.x)
+	b := &gfP12{}
+	b.z.y.y.Set(curveB)
+	x3.Add(x3, b)
+	return *y2 == *x3
+}
+
+// refLine returns the line through t and q, the tangent if q is nil,
+// evaluated at (xP, yP), and sets t to t+q, or 2t.
+func refLine(t, q *refPoint, xP, yP *gfP12) *gfP12 {
+	lambda, den := &gfP12{}, &gfP12{}
+	if q == nil {
+		// λ = 3x²/2y
+		lambda.Square(&t.x)
+		lambda.Add(lambda, (&gfP12{}).Add(lambda, lambda))
+		den.Add(&t.y, &t.y)
+	} else {
+		// λ = (y_q-y_t)/(x_q-x_t)
+		lambda.Sub(&q.y, &t.y)
+		den.Sub(&q.x, &t.x)
+	}
+	lambda.Mul(lambda, den.Invert(den))
+
+	// l = (y_P-y_t) - λ(x_P-x_t)
+	l := (&gfP12{}).Sub(xP, &t.x)
+	l.Mul(l, lambda)
+	l.Sub((&gfP12{}).Sub(yP, &t.y), l)
+
+	// x = λ²-x_t-x_q, y = λ(x_t-x)-y_t
+	qx := &t.x
+	if q != nil {
+		qx = &q.x
+	}
+	x := (&gfP12{}).Square(lambda)
+	x.Sub(x, &t.x)
+	x.Sub(x, qx)
+	y := (&gfP12{}).Sub(&t.x, x)
+	y.Mul(y, lambda)
+	y.Sub(y, &t.y)
+	t.x, t.y = *x, *y
+	return l
+}
+
+// refPairing is a textbook R-ate pairing of GM/T 0044 Part 5, Appendix B,
+// independent of the optimized Miller loop: the points are untwisted to the
+// curve over GF(p¹²), all the arithmetic is affine, the loop runs over the
+// binary digits of 6u+2, the Frobenius map raises the coordinates to the power
+// p, and the final exponentiation is a plain exponentiation to (p¹²-1)/n.
+func refPairing(q *twistPoint, pt *curvePoint) *gfP12 {
+	a := &curvePoint{}
+	a.Set(pt)
+	a.MakeAffine()
+	xP, yP := &gfP12{}, &gfP12{}
+	xP.z.y.y.Set(&a.x)
+	yP.z.y.y.Set(&a.y)
+
+	Q := refUntwist(q)
+	T := &refPoint{}
+	*T = *Q
+	f := (&gfP12{}).SetOne()
+	for i := sixUPlus2.BitLen() - 2; i >= 0; i-- {
+		f.Square(f)
+		f.Mul(f, refLine(T, nil, xP, yP))
+		if sixUPlus2.Bit(i) == 1 {
+			f.Mul(f, refLine(T, Q, xP, yP))
+		}
+	}
+
+	// Q1 = π(Q), Q2 = -π²(Q)
+	Q1, Q2 := &refPoint{}, &refPoint{}
+	Q1.x.Exp(&Q.x, p)
+	Q1.y.Exp(&Q.y, p)
+	Q2.x.Exp(&Q1.x, p)
+	Q2.y.Exp(&Q1.y, p)
+	Q2.y.Neg(&Q2.y)
+	f.Mul(f, refLine(T, Q1, xP, yP))
+	f.Mul(f, refLine(T, Q2, xP, yP))
+
+	exp := new(big.Int).Exp(p, big.NewInt(12), nil)
+	exp.Sub(exp, big.NewInt(1))
+	exp.Div(exp, Order)
+	return f.Exp(f, exp)
+}
+
+// TestPairingDifferential compares the optimized pairings with the textbook
+// reference pairing refPairing.
+func TestPairingDifferential(t *testing.T) {
+	if !refIsOnCurve(refUntwist(twistGen)) {
+		t.Fatal("untwisted generator of G2 is not on the curve")
+	}
+	for i := 0; i < 3; i++ {
+		_, p1, err := RandomG1(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, p2, err := RandomG2(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := refPairing(p2.p, p1.p)
+		if got := pairing(p2.p, p1.p); *got != *expected {
+			t.Fatalf("pairing mismatch: got %v, expected %v", got, expected)
+		}
+		if got := pairingB6(p2.p, p1.p); *got != *expected {
+			t.Fatalf("pairingB6 mismatch: got %v, expected %v", got, expected)
+		}
+		if got := Pair(p1, p2); *got.p != *expected {
+			t.Fatalf("Pair mismatch: got %v, expected %v", got.p, expected)
+		}
+	}
+}
+
 func BenchmarkFinalExponentiation(b *testing.B) {
 	x := testGfp12
 	exp := new(big.Int).Exp(p, big.NewInt(12), nil)
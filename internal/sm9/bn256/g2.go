@@ -364,3 +364,16 @@ func (e *G2) Equal(other *G2) bool {
 func (e *G2) IsOnCurve() bool {
 	return e.p.IsOnCurve()
 }
+
+// IsInfinity returns true if e is the point at infinity.
+func (e *G2) IsInfinity() bool {
+	return e.p == nil || e.p.IsInfinity()
+}
+
+// IsInSubgroup returns true if e is in the subgroup of order Order. Unlike G1,
+// the twist curve has a cofactor, so a point on it may not be in G2.
+func (e *G2) IsInSubgroup() bool {
+	t := &twistPoint{}
+	t.Mul(e.p, Order)
+	return t.IsInfinity()
+}
@@ -124,3 +124,29 @@ func BenchmarkG2(b *testing.B) {
 		new(G2).ScalarBaseMult(xb)
 	}
 }
+
+func TestG2IsInSubgroup(t *testing.T) {
+	_, Ga, err := RandomG2(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Ga.IsInSubgroup() || !Gen2.IsInSubgroup() {
+		t.Errorf("expected points in G2")
+	}
+
+	// The twist curve has a cofactor, so most of its points are not in G2.
+	data := make([]byte, 65)
+	data[0] = 2
+	for i := byte(1); i != 0; i++ {
+		data[64] = i
+		e := &G2{}
+		if _, err := e.UnmarshalCompressed(data); err != nil {
+			continue
+		}
+		if e.IsInSubgroup() {
+			t.Errorf("expected %v not in G2", e)
+		}
+		return
+	}
+	t.Fatal("no point found on the twist curve")
+}
@@ -65,6 +65,16 @@ func hashH2(z []byte) *bigmod.Nat {
 	return hash(z, H2)
 }
 
+// HashH1 returns the big-endian bytes of H1(z, n).
+func HashH1(z []byte) []byte {
+	return hashH1(z).Bytes(orderNat)
+}
+
+// HashH2 returns the big-endian bytes of H2(z, n).
+func HashH2(z []byte) []byte {
+	return hashH2(z).Bytes(orderNat)
+}
+
 func randomScalar(rand io.Reader) (k *bigmod.Nat, err error) {
 	k = bigmod.NewNat()
 	for {
@@ -230,7 +240,8 @@ func (priv *EncryptPrivateKey) UnwrapKey(uid, cipher []byte, kLen int) (key []by
 		return nil, ErrDecryption
 	}
 	p := new(bn256.G1)
-	if _, err = p.Unmarshal(cipher); err != nil || !p.IsOnCurve() {
+	// C must be a valid G1 element other than the point at infinity.
+	if _, err = p.Unmarshal(cipher); err != nil || !p.IsOnCurve() || p.IsInfinity() {
 		return nil, ErrDecryption
 	}
 
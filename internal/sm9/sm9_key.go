@@ -216,7 +216,12 @@ func (priv *SignPrivateKey) SetMasterPublicKey(pub *SignMasterPublicKey) {
 	}
 }
 
+// unmarshalG2 parses a point of a master public key, which must be in G2 and
+// not the point at infinity.
 func unmarshalG2(bytes []byte) (*bn256.G2, error) {
+	if len(bytes) == 0 {
+		return nil, errors.New("sm9: invalid point encoding")
+	}
 	g2 := new(bn256.G2)
 	switch bytes[0] {
 	case 4:
@@ -232,6 +237,9 @@ func unmarshalG2(bytes []byte) (*bn256.G2, error) {
 	default:
 		return nil, errors.New("sm9: invalid point identity byte")
 	}
+	if g2.IsInfinity() || !g2.IsInSubgroup() {
+		return nil, errors.New("sm9: point is not in G2")
+	}
 	return g2, nil
 }
 
@@ -246,7 +254,13 @@ func (pub *SignMasterPublicKey) UnmarshalRaw(bytes []byte) error {
 	return nil
 }
 
+// unmarshalG1 parses a point of a master public key or a user private key,
+// which must not be the point at infinity. The points on the curve are all in
+// G1, its cofactor is 1.
 func unmarshalG1(bytes []byte) (*bn256.G1, error) {
+	if len(bytes) == 0 {
+		return nil, errors.New("sm9: invalid point encoding")
+	}
 	g := new(bn256.G1)
 	switch bytes[0] {
 	case 4:
@@ -262,6 +276,9 @@ func unmarshalG1(bytes []byte) (*bn256.G1, error) {
 	default:
 		return nil, errors.New("sm9: invalid point encoding")
 	}
+	if g.IsInfinity() {
+		return nil, errors.New("sm9: point is the point at infinity")
+	}
 	return g, nil
 }
 
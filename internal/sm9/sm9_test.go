@@ -196,6 +196,22 @@ func TestWrapKey(t *testing.T) {
 	}
 }
 
+func TestUnwrapKeyInfinity(t *testing.T) {
+	masterKey, err := GenerateEncryptMasterKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userKey, err := masterKey.GenerateUserKey([]byte("emmansun"), 0x03)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cipher := make([]byte, 65)
+	cipher[0] = 4
+	if _, err = userKey.UnwrapKey([]byte("emmansun"), cipher, 16); err != ErrDecryption {
+		t.Errorf("expected ErrDecryption, got %v", err)
+	}
+}
+
 // SM9 Appendix C
 func TestWrapKeySM9Sample(t *testing.T) {
 	expectedMasterPublicKey := "787ed7b8a51f3ab84e0a66003f32da5c720b17eca7137d39abc66e3c80a892ff769de61791e5adc4b9ff85a31354900b202871279a8c49dc3f220f644c57a7b1"
@@ -210,6 +210,15 @@ func (c *eea) seek(offset uint64) {
 	}
 }
 
+// XORKeyStreamAt behaves like XORKeyStream but starts at the given key stream offset.
+//
+// Cost model: seeking forward from the current offset generates and discards the
+// key stream in RoundBytes steps, the unaligned tail (including offsets that fall
+// in the middle of a 32-bit word) is served from the buffered round. Seeking
+// backward re-initializes from the nearest saved state, that is the initial state
+// when bucketSize is 0, so it costs O(offset); with a bucketSize, at most
+// bucketSize bytes of key stream are regenerated, at the price of keeping one
+// state per bucket.
 func (c *eea) XORKeyStreamAt(dst, src []byte, offset uint64) {
 	c.seek(offset)
 	c.XORKeyStream(dst, src)
@@ -0,0 +1,119 @@
+package kdf
+
+import (
+	"crypto/cipher"
+	"crypto/hmac"
+	"errors"
+	"hash"
+
+	"github.com/yunmoon/gmsm/cbcmac"
+	"github.com/yunmoon/gmsm/internal/byteorder"
+	"github.com/yunmoon/gmsm/sm3"
+	"github.com/yunmoon/gmsm/sm4"
+)
+
+// PRF is a pseudorandom function of NIST SP 800-108, it returns a MAC keyed with key.
+type PRF func(key []byte) (hash.Hash, error)
+
+// HMACPRF returns a HMAC based PRF with the given hash function.
+func HMACPRF(newHash func() hash.Hash) PRF {
+	return func(key []byte) (hash.Hash, error) {
+		return hmac.New(newHash, key), nil
+	}
+}
+
+// CMACPRF returns a CMAC based PRF with the given block cipher.
+func CMACPRF(newCipher func(key []byte) (cipher.Block, error)) PRF {
+	return func(key []byte) (hash.Hash, error) {
+		b, err := newCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cbcmac.NewCMAC(b, b.BlockSize()), nil
+	}
+}
+
+var (
+	// HMACSM3 is the HMAC-SM3 PRF.
+	HMACSM3 = HMACPRF(sm3.New)
+	// CMACSM4 is the CMAC-SM4 PRF, the key derivation key must be 16 bytes.
+	CMACSM4 = CMACPRF(sm4.NewCipher)
+)
+
+// CounterLocation is the location of the counter relative to the fixed input data.
+type CounterLocation int
+
+const (
+	// CounterBeforeFixedInput places the counter before Label || 0x00 || Context || L.
+	CounterBeforeFixedInput CounterLocation = iota
+	// CounterAfterFixedInput places the counter after Label || 0x00 || Context || L.
+	CounterAfterFixedInput
+)
+
+// KBKDFOptions are the options of the KBKDF in counter mode.
+type KBKDFOptions struct {
+	// CounterBits is the size of the counter in bits, 8, 16 or 32. Zero means 32.
+	CounterBits int
+	// CounterLocation is the location of the counter, it defaults to CounterBeforeFixedInput.
+	CounterLocation CounterLocation
+}
+
+var defaultKBKDFOptions = &KBKDFOptions{CounterBits: 32, CounterLocation: CounterBeforeFixedInput}
+
+// KBKDFCounter derives length bytes from key with the KBKDF in counter mode,
+// NIST SP 800-108r1 section 4.1, using a 32 bits counter placed before the fixed input data
+// Label || 0x00 || Context || L, where L is the output length in bits encoded as 32 bits big endian integer.
+func KBKDFCounter(prf PRF, key, label, context []byte, length int) ([]byte, error) {
+	return KBKDFCounterWithOptions(prf, key, label, context, length, nil)
+}
+
+// KBKDFCounterWithOptions is like [KBKDFCounter], but with configurable counter size and location.
+// It returns an error if the output length exceeds the counter space.
+func KBKDFCounterWithOptions(prf PRF, key, label, context []byte, length int, opts *KBKDFOptions) ([]byte, error) {
+	if opts == nil {
+		opts = defaultKBKDFOptions
+	}
+	counterBits := opts.CounterBits
+	if counterBits == 0 {
+		counterBits = 32
+	}
+	if counterBits != 8 && counterBits != 16 && counterBits != 32 {
+		return nil, errors.New("kdf: invalid counter size")
+	}
+	if opts.CounterLocation != CounterBeforeFixedInput && opts.CounterLocation != CounterAfterFixedInput {
+		return nil, errors.New("kdf: invalid counter location")
+	}
+	if length <= 0 || uint64(length) > (1<<32-1)/8 {
+		return nil, errors.New("kdf: invalid output length")
+	}
+	mac, err := prf(key)
+	if err != nil {
+		return nil, err
+	}
+	n := (uint64(length) + uint64(mac.Size()) - 1) / uint64(mac.Size())
+	if n > 1<<counterBits-1 {
+		return nil, errors.New("kdf: output length exceeds the counter space")
+	}
+
+	fixedInput := make([]byte, 0, len(label)+1+len(context)+4)
+	fixedInput = append(fixedInput, label...)
+	fixedInput = append(fixedInput, 0)
+	fixedInput = append(fixedInput, context...)
+	fixedInput = byteorder.BEAppendUint32(fixedInput, uint32(length)*8)
+
+	var counter [4]byte
+	out := make([]byte, 0, int(n)*mac.Size())
+	for i := uint64(1); i <= n; i++ {
+		byteorder.BEPutUint32(counter[:], uint32(i))
+		mac.Reset()
+		if opts.CounterLocation == CounterBeforeFixedInput {
+			mac.Write(counter[4-counterBits/8:])
+			mac.Write(fixedInput)
+		} else {
+			mac.Write(fixedInput)
+			mac.Write(counter[4-counterBits/8:])
+		}
+		out = mac.Sum(out)
+	}
+	return out[:length], nil
+}
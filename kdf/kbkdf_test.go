@@ -0,0 +1,130 @@
+package kdf
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/yunmoon/gmsm/sm3"
+)
+
+// The expected values were generated with OpenSSL 3.0 KBKDF (counter mode, 32 bits counter before fixed input).
+func TestKBKDFCounter(t *testing.T) {
+	key, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	tests := []struct {
+		name   string
+		prf    PRF
+		length int
+		want   string
+	}{
+		{"HMAC-SM3 32", HMACSM3, 32, "5c7720259355db11ca993dd32b68125a75b7ac1a59bda0c665cb5f531c73435c"},
+		{"HMAC-SM3 40", HMACSM3, 40, "e7fb75edb41bd05fa9387ed862ee94ffd285cefa8ce4486086ea72dbcc2e6e0f0428b405afcf292c"},
+		{"CMAC-SM4 32", CMACSM4, 32, "b13f301960d40192ba24bec1c9d8a4be0bc2270f230a2052297abddb5090451b"},
+		{"CMAC-SM4 40", CMACSM4, 40, "a7de8f051b57c9407f79a1f71c279a24f5bb02b6208b0316b16b7d6ce00e537d83efcbf91fbb1552"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := KBKDFCounter(tt.prf, key, []byte("label"), []byte("context"), tt.length)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hex.EncodeToString(got) != tt.want {
+				t.Errorf("got %x, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKBKDFCounterOptions(t *testing.T) {
+	key := []byte("key derivation key")
+	label, context := []byte("label"), []byte("context")
+	for _, counterBits := range []int{8, 16, 32} {
+		for _, location := range []CounterLocation{CounterBeforeFixedInput, CounterAfterFixedInput} {
+			t.Run(fmt.Sprintf("%d bits counter at %d", counterBits, location), func(t *testing.T) {
+				got, err := KBKDFCounterWithOptions(HMACSM3, key, label, context, 48, &KBKDFOptions{counterBits, location})
+				if err != nil {
+					t.Fatal(err)
+				}
+				// build the expected output by hand
+				fixedInput := append(append(append([]byte{}, label...), 0), context...)
+				fixedInput = append(fixedInput, 0, 0, 1, 128)
+				var want []byte
+				for i := 1; i <= 2; i++ {
+					counter := make([]byte, counterBits/8)
+					counter[len(counter)-1] = byte(i)
+					mac := hmac.New(sm3.New, key)
+					if location == CounterBeforeFixedInput {
+						mac.Write(counter)
+						mac.Write(fixedInput)
+					} else {
+						mac.Write(fixedInput)
+						mac.Write(counter)
+					}
+					want = mac.Sum(want)
+				}
+				if !bytes.Equal(got, want[:48]) {
+					t.Errorf("got %x, want %x", got, want[:48])
+				}
+			})
+		}
+	}
+}
+
+func TestKBKDFCounterInvalid(t *testing.T) {
+	key := make([]byte, 16)
+	if _, err := KBKDFCounterWithOptions(HMACSM3, key, nil, nil, 255*32, &KBKDFOptions{CounterBits: 8}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err := KBKDFCounterWithOptions(HMACSM3, key, nil, nil, 255*32+1, &KBKDFOptions{CounterBits: 8}); err == nil {
+		t.Error("expected error when output length exceeds the 8 bits counter space")
+	}
+	if _, err := KBKDFCounterWithOptions(CMACSM4, key, nil, nil, 65536*16, &KBKDFOptions{CounterBits: 16}); err == nil {
+		t.Error("expected error when output length exceeds the 16 bits counter space")
+	}
+	if _, err := KBKDFCounterWithOptions(HMACSM3, key, nil, nil, 32, &KBKDFOptions{CounterBits: 24}); err == nil {
+		t.Error("expected error with invalid counter size")
+	}
+	if _, err := KBKDFCounterWithOptions(HMACSM3, key, nil, nil, 32, &KBKDFOptions{CounterLocation: 2}); err == nil {
+		t.Error("expected error with invalid counter location")
+	}
+	if _, err := KBKDFCounter(HMACSM3, key, nil, nil, 0); err == nil {
+		t.Error("expected error with zero length")
+	}
+	if _, err := KBKDFCounter(HMACSM3, key, nil, nil, 1<<29); err == nil {
+		t.Error("expected error when L overflows 32 bits")
+	}
+	if _, err := KBKDFCounter(CMACSM4, key[:15], nil, nil, 16); err == nil {
+		t.Error("expected error with invalid SM4 key")
+	}
+}
+
+func TestKBKDFCounterNoCollision(t *testing.T) {
+	key := []byte("key derivation key")
+	inputs := [][2]string{
+		{"", ""}, {"a", ""}, {"", "a"}, {"ab", ""}, {"a", "b"}, {"a\x00b", ""}, {"a", "\x00b"},
+		{"encryption", "alice"}, {"encryption", "bob"}, {"mac", "alice"}, {"mac", "bob"},
+	}
+	for _, prf := range []PRF{HMACSM3, CMACSM4} {
+		seen := make(map[string]string)
+		k := key[:16]
+		for _, in := range inputs {
+			out, err := KBKDFCounter(prf, k, []byte(in[0]), []byte(in[1]), 32)
+			if err != nil {
+				t.Fatal(err)
+			}
+			id := fmt.Sprintf("%q/%q", in[0], in[1])
+			if prev, ok := seen[string(out)]; ok {
+				t.Errorf("%s and %s derived the same key", prev, id)
+			}
+			seen[string(out)] = id
+		}
+		// output of different length is not a prefix of each other
+		short, _ := KBKDFCounter(prf, k, []byte("label"), nil, 16)
+		long, _ := KBKDFCounter(prf, k, []byte("label"), nil, 32)
+		if bytes.Equal(short, long[:16]) {
+			t.Error("output length is not bound to the output")
+		}
+	}
+}
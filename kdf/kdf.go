@@ -1,4 +1,5 @@
-// Package kdf implements ShangMi(SM) used Key Derivation Function, compliances with GB/T 32918.4-2016 5.4.3.
+// Package kdf implements ShangMi(SM) used Key Derivation Function, compliances with GB/T 32918.4-2016 5.4.3,
+// and the NIST SP 800-108 key-based KDF in counter mode.
 package kdf
 
 import (
@@ -94,6 +94,9 @@ func (ecb *ecbBlockCipher) Decrypt(key []byte, parameters *asn1.RawValue, cipher
 	if err != nil {
 		return nil, err
 	}
+	if len(ciphertext) == 0 || len(ciphertext)%block.BlockSize() != 0 {
+		return nil, ErrInvalidCiphertextLength
+	}
 	mode := smcipher.NewECBDecrypter(block)
 	plaintext := make([]byte, len(ciphertext))
 	mode.CryptBlocks(plaintext, ciphertext)
@@ -163,6 +166,12 @@ func cbcEncrypt(block cipher.Block, iv, plaintext []byte) ([]byte, error) {
 }
 
 func cbcDecrypt(block cipher.Block, iv, ciphertext []byte) ([]byte, error) {
+	if len(iv) != block.BlockSize() {
+		return nil, errors.New("pbes: invalid cipher parameters")
+	}
+	if len(ciphertext) == 0 || len(ciphertext)%block.BlockSize() != 0 {
+		return nil, ErrInvalidCiphertextLength
+	}
 	mode := cipher.NewCBCDecrypter(block, iv)
 	pkcs7 := padding.NewPKCS7Padding(uint(block.BlockSize()))
 	plaintext := make([]byte, len(ciphertext))
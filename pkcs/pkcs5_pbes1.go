@@ -148,6 +148,9 @@ func (pbes1 *PBES1) Decrypt(password, ciphertext []byte) ([]byte, KDFParameters,
 		return nil, nil, err
 	}
 	plaintext, err := cbcDecrypt(block, key[8:16], ciphertext)
+	if err == ErrInvalidCiphertextLength {
+		return nil, nil, err
+	}
 	if err != nil {
 		return nil, nil, ErrPBEDecryption
 	}
@@ -62,6 +62,9 @@ func (h Hash) New() hash.Hash {
 
 var (
 	ErrPBEDecryption = errors.New("pbes: decryption error, please verify the password and try again")
+	// ErrInvalidCiphertextLength is returned when the encrypted data is not a positive multiple
+	// of the cipher block size, which usually means it was truncated.
+	ErrInvalidCiphertextLength = errors.New("pbes: invalid ciphertext length, the encrypted data may be truncated")
 )
 
 // PBES2Params contains algorithm identifiers and related parameters for PBKDF2 key derivation function.
@@ -186,6 +189,9 @@ func (pbes2Params *PBES2Params) Decrypt(password, ciphertext []byte) ([]byte, KD
 	}
 
 	plaintext, err := cipher.Decrypt(symkey, &pbes2Params.EncryptionScheme.Parameters, ciphertext)
+	if err == ErrInvalidCiphertextLength {
+		return nil, nil, err
+	}
 	if err != nil {
 		return nil, nil, ErrPBEDecryption
 	}
@@ -116,6 +116,16 @@ var (
 	OIDKeyEncryptionAlgorithmSM9 = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 302, 3}
 )
 
+var (
+	// Known digest encryption algorithm aliases used by other implementations for SM2 signatures.
+	oidPublicKeySM2   = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 301}
+	oidPublicKeyECDSA = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+)
+
+func isSM3DigestOID(oid asn1.ObjectIdentifier) bool {
+	return oid.Equal(OIDDigestAlgorithmSM3) || oid.Equal(OIDDigestAlgorithmSM2SM3)
+}
+
 func getHashForOID(oid asn1.ObjectIdentifier) (crypto.Hash, error) {
 	switch {
 	case oid.Equal(OIDDigestAlgorithmSHA1), oid.Equal(OIDDigestAlgorithmECDSASHA1),
@@ -128,7 +138,7 @@ func getHashForOID(oid asn1.ObjectIdentifier) (crypto.Hash, error) {
 		return crypto.SHA384, nil
 	case oid.Equal(OIDDigestAlgorithmSHA512), oid.Equal(OIDDigestAlgorithmECDSASHA512):
 		return crypto.SHA512, nil
-	case oid.Equal(OIDDigestAlgorithmSM3), oid.Equal(OIDDigestAlgorithmSM2SM3):
+	case isSM3DigestOID(oid):
 		return crypto.Hash(0), nil
 	}
 	return crypto.Hash(0), fmt.Errorf("pkcs7: cannot get hash from oid %v", oid)
@@ -265,6 +275,9 @@ func (raw rawCertificates) Parse() ([]*smx509.Certificate, error) {
 	if _, err := asn1.Unmarshal(raw.Raw, &val); err != nil {
 		return nil, err
 	}
+	if len(val.Bytes) == 0 {
+		return nil, nil
+	}
 
 	return smx509.ParseCertificates(val.Bytes)
 }
@@ -27,6 +27,7 @@ type SignedData struct {
 	contentTypeOid   asn1.ObjectIdentifier
 	digestOid        asn1.ObjectIdentifier
 	encryptionOid    asn1.ObjectIdentifier
+	omitParameters   bool
 }
 
 // NewSignedData takes data and initializes a PKCS7 SignedData struct that is
@@ -164,6 +165,23 @@ func (sd *SignedData) SetEncryptionAlgorithm(d asn1.ObjectIdentifier) {
 	sd.encryptionOid = d
 }
 
+// SetOmitAlgorithmParameters controls whether the parameters of the digest and
+// digest encryption AlgorithmIdentifiers are omitted. By default they are encoded
+// as explicit NULL, which is the canonical GM/T 0010 form. Omitting them is only
+// useful to mimic the peer's encoding in re-sign workflows.
+//
+// This should be called before adding signers
+func (sd *SignedData) SetOmitAlgorithmParameters(omit bool) {
+	sd.omitParameters = omit
+}
+
+func (sd *SignedData) algorithmIdentifier(oid asn1.ObjectIdentifier) pkix.AlgorithmIdentifier {
+	if sd.omitParameters {
+		return pkix.AlgorithmIdentifier{Algorithm: oid}
+	}
+	return pkix.AlgorithmIdentifier{Algorithm: oid, Parameters: asn1.NullRawValue}
+}
+
 // AddSigner is a wrapper around AddSignerChain() that adds a signer without any parent.
 func (sd *SignedData) AddSigner(ee *smx509.Certificate, pkey crypto.PrivateKey, config SignerInfoConfig) error {
 	var parents []*smx509.Certificate
@@ -200,9 +218,7 @@ func (sd *SignedData) AddSignerChain(ee *smx509.Certificate, pkey crypto.Private
 		// the first parent is the issuer
 		ias.IssuerName = asn1.RawValue{FullBytes: parents[0].RawSubject}
 	}
-	sd.sd.DigestAlgorithmIdentifiers = append(sd.sd.DigestAlgorithmIdentifiers,
-		pkix.AlgorithmIdentifier{Algorithm: sd.digestOid, Parameters: asn1.NullRawValue},
-	)
+	sd.sd.DigestAlgorithmIdentifiers = append(sd.sd.DigestAlgorithmIdentifiers, sd.algorithmIdentifier(sd.digestOid))
 	encryptionOid, err := getOIDForEncryptionAlgorithm(pkey, sd.digestOid)
 	if err != nil {
 		return err
@@ -213,8 +229,8 @@ func (sd *SignedData) AddSignerChain(ee *smx509.Certificate, pkey crypto.Private
 	}
 	signer := signerInfo{
 		AuthenticatedAttributes:   finalAttrs,
-		DigestAlgorithm:           pkix.AlgorithmIdentifier{Algorithm: sd.digestOid, Parameters: asn1.NullRawValue},
-		DigestEncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: encryptionOid, Parameters: asn1.NullRawValue},
+		DigestAlgorithm:           sd.algorithmIdentifier(sd.digestOid),
+		DigestEncryptionAlgorithm: sd.algorithmIdentifier(encryptionOid),
 		IssuerAndSerialNumber:     ias,
 		EncryptedDigest:           signature,
 		Version:                   1,
@@ -266,7 +282,7 @@ func (sd *SignedData) signWithAttributes(pkey crypto.PrivateKey, config SignerIn
 
 func newHash(hasher crypto.Hash, hashOid asn1.ObjectIdentifier) hash.Hash {
 	var h hash.Hash
-	if hashOid.Equal(OIDDigestAlgorithmSM3) || hashOid.Equal(OIDDigestAlgorithmSM2SM3) {
+	if isSM3DigestOID(hashOid) {
 		h = sm3.New()
 	} else {
 		h = hasher.New()
@@ -283,7 +299,7 @@ func newHash(hasher crypto.Hash, hashOid asn1.ObjectIdentifier) hash.Hash {
 // applications.
 func (sd *SignedData) SignWithoutAttr(ee *smx509.Certificate, pkey crypto.PrivateKey, config SignerInfoConfig) error {
 	var signature []byte
-	sd.sd.DigestAlgorithmIdentifiers = append(sd.sd.DigestAlgorithmIdentifiers, pkix.AlgorithmIdentifier{Algorithm: sd.digestOid, Parameters: asn1.NullRawValue})
+	sd.sd.DigestAlgorithmIdentifiers = append(sd.sd.DigestAlgorithmIdentifiers, sd.algorithmIdentifier(sd.digestOid))
 	hasher, err := getHashForOID(sd.digestOid)
 	if err != nil {
 		return err
@@ -305,8 +321,8 @@ func (sd *SignedData) SignWithoutAttr(ee *smx509.Certificate, pkey crypto.Privat
 		return err
 	}
 	signer := signerInfo{
-		DigestAlgorithm:           pkix.AlgorithmIdentifier{Algorithm: sd.digestOid, Parameters: asn1.NullRawValue},
-		DigestEncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: sd.encryptionOid, Parameters: asn1.NullRawValue},
+		DigestAlgorithm:           sd.algorithmIdentifier(sd.digestOid),
+		DigestEncryptionAlgorithm: sd.algorithmIdentifier(sd.encryptionOid),
 		IssuerAndSerialNumber:     ias,
 		EncryptedDigest:           signature,
 		Version:                   1,
@@ -5,6 +5,7 @@ import (
 	"crypto"
 	"crypto/ecdsa"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/asn1"
 	"encoding/pem"
 	"fmt"
@@ -562,3 +563,37 @@ func TestSignWithoutAttrWithDigest(t *testing.T) {
 		}
 	}
 }
+
+func TestSignSMOmitAlgorithmParameters(t *testing.T) {
+	cert, err := createTestCertificate(smx509.SM2WithSM3, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, omit := range []bool{false, true} {
+		toBeSigned, err := NewSMSignedData([]byte("Hello World"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		toBeSigned.SetOmitAlgorithmParameters(omit)
+		if err := toBeSigned.AddSigner(cert.Certificate, *cert.PrivateKey, SignerInfoConfig{}); err != nil {
+			t.Fatal(err)
+		}
+		signed, err := toBeSigned.Finish()
+		if err != nil {
+			t.Fatal(err)
+		}
+		p7, err := Parse(signed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signer := p7.Signers[0]
+		for _, ai := range []pkix.AlgorithmIdentifier{signer.DigestAlgorithm, signer.DigestEncryptionAlgorithm} {
+			if omit != (len(ai.Parameters.FullBytes) == 0) {
+				t.Errorf("omit=%v, got parameters %x", omit, ai.Parameters.FullBytes)
+			}
+		}
+		if err := p7.Verify(); err != nil {
+			t.Errorf("omit=%v: %v", omit, err)
+		}
+	}
+}
@@ -302,8 +302,17 @@ func getSignatureAlgorithm(digestEncryption, digest pkix.AlgorithmIdentifier) (x
 			return -1, fmt.Errorf("pkcs7: unsupported digest %q for encryption algorithm %q",
 				digest.Algorithm.String(), digestEncryption.Algorithm.String())
 		}
-	case digestEncryption.Algorithm.Equal(OIDDigestEncryptionAlgorithmSM2):
+	case digestEncryption.Algorithm.Equal(OIDDigestEncryptionAlgorithmSM2),
+		digestEncryption.Algorithm.Equal(OIDDigestAlgorithmSM2SM3),
+		digestEncryption.Algorithm.Equal(oidPublicKeySM2):
 		return smx509.SM2WithSM3, nil
+	case digestEncryption.Algorithm.Equal(oidPublicKeyECDSA):
+		// Some implementations use the ECDSA public key OID with SM3 digest for SM2 signatures.
+		if isSM3DigestOID(digest.Algorithm) {
+			return smx509.SM2WithSM3, nil
+		}
+		return -1, fmt.Errorf("pkcs7: unsupported digest %q for encryption algorithm %q",
+			digest.Algorithm.String(), digestEncryption.Algorithm.String())
 	default:
 		return -1, fmt.Errorf("pkcs7: unsupported algorithm %q",
 			digestEncryption.Algorithm.String())
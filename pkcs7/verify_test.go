@@ -10,6 +10,7 @@ import (
 	"crypto/x509/pkix"
 	"encoding/asn1"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/pem"
 	"math/big"
 	"os"
@@ -788,3 +789,53 @@ func TestGetSignatureAlgorithm(t *testing.T) {
 		}
 	}
 }
+
+func TestGetSignatureAlgorithmSMAliases(t *testing.T) {
+	// AlgorithmIdentifier bytes captured from GmSSL, BouncyCastle, CFCA SADK and other implementations.
+	tests := []struct {
+		name                     string
+		digestEncryption, digest string
+	}{
+		{"SM2-1 NULL/SM3 NULL", "300d06092a811ccf5501822d010500", "300c06082a811ccf550183110500"},
+		{"SM2-1 absent/SM3 absent", "300b06092a811ccf5501822d01", "300a06082a811ccf55018311"},
+		{"SM2-1 NULL/SM3 absent", "300d06092a811ccf5501822d010500", "300a06082a811ccf55018311"},
+		{"SM2-SM3 absent/SM3 NULL", "300a06082a811ccf55018375", "300c06082a811ccf550183110500"},
+		{"SM2-SM3 NULL/SM2-SM3 NULL", "300c06082a811ccf550183750500", "300c06082a811ccf550183750500"},
+		{"SM2 public key/SM3 absent", "300a06082a811ccf5501822d", "300a06082a811ccf55018311"},
+		{"ecPublicKey/SM3 NULL", "300906072a8648ce3d0201", "300c06082a811ccf550183110500"},
+	}
+	for _, test := range tests {
+		var digestEncryption, digest pkix.AlgorithmIdentifier
+		if _, err := asn1.Unmarshal(mustDecodeHex(t, test.digestEncryption), &digestEncryption); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if _, err := asn1.Unmarshal(mustDecodeHex(t, test.digest), &digest); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		s, err := getSignatureAlgorithm(digestEncryption, digest)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if s != smx509.SM2WithSM3 {
+			t.Errorf("%s: expected %v, got %v", test.name, smx509.SM2WithSM3, s)
+		}
+		if _, err := getHashForOID(digest.Algorithm); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		}
+	}
+
+	// ecPublicKey with non SM3 digest is not an SM2 signature
+	if _, err := getSignatureAlgorithm(pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyECDSA}, pkix.AlgorithmIdentifier{Algorithm: OIDDigestAlgorithmSHA256}); err == nil {
+		t.Errorf("should return error")
+	}
+}
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -38,6 +38,15 @@ type encryptedPrivateKeyInfo struct {
 	EncryptedData       []byte
 }
 
+// for private-key information, just to check the decrypted data
+type privateKeyInfo struct {
+	Version             int
+	PrivateKeyAlgorithm pkix.AlgorithmIdentifier
+	PrivateKey          []byte
+	Attributes          []asn1.RawValue `asn1:"optional,tag:0"`
+	PublicKey           asn1.BitString  `asn1:"optional,tag:1"`
+}
+
 var (
 	ErrUnsupportedPBES   = errors.New("pkcs8: only part of PBES1/PBES2 supported")
 	ErrUnexpectedKeyType = errors.New("pkcs8: unexpected key type")
@@ -83,6 +92,10 @@ func ParsePrivateKey(der []byte, password []byte) (any, pkcs.KDFParameters, erro
 	}
 	key, err := smx509.ParsePKCS8PrivateKey(decryptedKey)
 	if err != nil {
+		// the padding check may pass by chance with a wrong password
+		if rest, err := asn1.Unmarshal(decryptedKey, &privateKeyInfo{}); err != nil || len(rest) > 0 {
+			return nil, nil, pkcs.ErrPBEDecryption
+		}
 		return nil, nil, err
 	}
 	return key, kdfParams, nil
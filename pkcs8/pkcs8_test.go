@@ -1,13 +1,16 @@
 package pkcs8_test
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509/pkix"
 	"encoding/asn1"
+	"encoding/hex"
 	"encoding/pem"
+	"fmt"
 	"testing"
 
 	"github.com/yunmoon/gmsm/pkcs"
@@ -805,3 +808,62 @@ func TestShangMiPBES(t *testing.T) {
 		t.Fatalf("ParsePrivateKey returned: %s", err)
 	}
 }
+
+func TestSM4CBCWithHMACSM3(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	password := []byte("12345678")
+	opts := &pkcs8.Opts{
+		Cipher: pkcs.SM4CBC,
+		KDFOpts: pkcs8.PBKDF2Opts{
+			SaltSize:       8,
+			IterationCount: 1000,
+			HMACHash:       pkcs8.SM3,
+		},
+	}
+	der, err := pkcs8.MarshalPrivateKey(priv, password, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// HMAC-SM3 PRF and SM4-CBC scheme OIDs
+	for _, oid := range []string{"06092a811ccf5501831102", "06082a811ccf55016802"} {
+		oidBytes, _ := hex.DecodeString(oid)
+		if !bytes.Contains(der, oidBytes) {
+			t.Errorf("OID %s not found in %x", oid, der)
+		}
+	}
+	key, kdfParams, err := pkcs8.ParsePrivateKey(der, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !priv.Equal(key) {
+		t.Error("decoded key does not match original key")
+	}
+	if kdfParams.KeyLength() != 16 {
+		t.Errorf("unexpected key length %d", kdfParams.KeyLength())
+	}
+
+	for i := 0; i < 256; i++ {
+		if _, _, err := pkcs8.ParsePrivateKey(der, []byte(fmt.Sprintf("wrong %d", i))); err != pkcs.ErrPBEDecryption {
+			t.Fatalf("expected ErrPBEDecryption with wrong password, got %v", err)
+		}
+	}
+
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range []int{0, 1, len(info.EncryptedData) - 1} {
+		truncated := info
+		truncated.EncryptedData = info.EncryptedData[:l]
+		data, err := asn1.Marshal(truncated)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := pkcs8.ParsePrivateKey(data, password); err != pkcs.ErrInvalidCiphertextLength {
+			t.Errorf("expected ErrInvalidCiphertextLength with %d bytes encrypted data, got %v", l, err)
+		}
+	}
+}
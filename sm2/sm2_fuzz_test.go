@@ -0,0 +1,73 @@
+package sm2
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+)
+
+func FuzzDecrypt(f *testing.F) {
+	priv, err := GenerateKey(rand.Reader)
+	if err != nil {
+		f.Fatal(err)
+	}
+	nistKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		f.Fatal(err)
+	}
+	legacy := &PrivateKey{PrivateKey: *nistKey}
+
+	msg := []byte("encryption standard")
+	for _, opts := range []*EncrypterOpts{nil, ASN1EncrypterOpts, NewPlainEncrypterOpts(MarshalCompressed, C1C2C3)} {
+		ciphertext, err := Encrypt(rand.Reader, &priv.PublicKey, msg, opts)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(ciphertext)
+		ciphertext, err = Encrypt(rand.Reader, &legacy.PublicKey, msg, opts)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(ciphertext)
+	}
+	f.Add([]byte{0x04})
+	f.Add([]byte{0x30, 0x00})
+
+	f.Fuzz(func(t *testing.T, ciphertext []byte) {
+		for _, key := range []*PrivateKey{priv, legacy} {
+			for _, opts := range []*DecrypterOpts{nil, ASN1DecrypterOpts, NewPlainDecrypterOpts(C1C2C3)} {
+				if plaintext, err := key.Decrypt(nil, ciphertext, opts); err == nil && len(plaintext) == 0 {
+					t.Errorf("decrypt succeeded with empty plaintext")
+				}
+			}
+		}
+		AdjustCiphertextSplicingOrder(ciphertext, C1C3C2, C1C2C3)
+		ASN1Ciphertext2Plain(ciphertext, nil)
+		PlainCiphertext2ASN1(ciphertext, C1C2C3)
+	})
+}
+
+func FuzzVerifyASN1(f *testing.F) {
+	priv, err := GenerateKey(rand.Reader)
+	if err != nil {
+		f.Fatal(err)
+	}
+	nistKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		f.Fatal(err)
+	}
+	hash := []byte("testing testing testing testing.")
+	sig, err := SignASN1(rand.Reader, priv, hash, nil)
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(hash, sig)
+	f.Add(hash, []byte{0x30, 0x06, 0x02, 0x01, 0x00, 0x02, 0x01, 0x00})
+
+	f.Fuzz(func(t *testing.T, hash, sig []byte) {
+		VerifyASN1(&priv.PublicKey, hash, sig)
+		VerifyASN1(&nistKey.PublicKey, hash, sig)
+		VerifyASN1WithSM2(&priv.PublicKey, nil, hash, sig)
+	})
+}
@@ -0,0 +1,70 @@
+package sm2
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"io"
+)
+
+// This file contains the SM2 signature scheme sm2sig_sm3 used by the
+// TLS_SM4_GCM_SM3 and TLS_SM4_CCM_SM3 cipher suites, see RFC 8998 section 3.2.1.
+
+// TLS13Role is the role of the peer which signs the CertificateVerify message.
+type TLS13Role int
+
+const (
+	TLS13Server TLS13Role = iota
+	TLS13Client
+)
+
+// TLS13UID is the distinguishing identifier of SM2 signatures in TLS 1.3, RFC 8998 section 3.2.1.
+var TLS13UID = []byte("TLSv1.3+GM+Cipher+Suite")
+
+const (
+	serverSignatureContext = "TLS 1.3, server CertificateVerify\x00"
+	clientSignatureContext = "TLS 1.3, client CertificateVerify\x00"
+)
+
+var errInvalidTLS13Role = errors.New("sm2: invalid TLS 1.3 role")
+
+// tls13SignedContent returns the content covered by the CertificateVerify
+// signature, RFC 8446 section 4.4.3.
+func tls13SignedContent(transcriptHash []byte, role TLS13Role) ([]byte, error) {
+	var context string
+	switch role {
+	case TLS13Server:
+		context = serverSignatureContext
+	case TLS13Client:
+		context = clientSignatureContext
+	default:
+		return nil, errInvalidTLS13Role
+	}
+	content := make([]byte, 0, 64+len(context)+len(transcriptHash))
+	for range 64 {
+		content = append(content, 0x20)
+	}
+	content = append(content, context...)
+	content = append(content, transcriptHash...)
+	return content, nil
+}
+
+// SignTLS13 signs the TLS 1.3 transcript hash for a CertificateVerify message
+// sent by role, with the SM2 UID of RFC 8998. It returns the ASN.1 encoded signature.
+func SignTLS13(rand io.Reader, priv *PrivateKey, transcriptHash []byte, role TLS13Role) ([]byte, error) {
+	content, err := tls13SignedContent(transcriptHash, role)
+	if err != nil {
+		return nil, err
+	}
+	return SignASN1(rand, priv, content, NewSM2SignerOption(true, TLS13UID))
+}
+
+// VerifyTLS13 verifies the ASN.1 encoded signature of a TLS 1.3 CertificateVerify
+// message sent by role, over the transcript hash, with the SM2 UID of RFC 8998.
+// Its return value records whether the signature is valid.
+func VerifyTLS13(pub *ecdsa.PublicKey, transcriptHash, sig []byte, role TLS13Role) bool {
+	content, err := tls13SignedContent(transcriptHash, role)
+	if err != nil {
+		return false
+	}
+	return VerifyASN1WithSM2(pub, TLS13UID, content, sig)
+}
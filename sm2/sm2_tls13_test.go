@@ -0,0 +1,104 @@
+package sm2
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+
+	"github.com/yunmoon/gmsm/sm3"
+)
+
+func TestTLS13SignedContent(t *testing.T) {
+	transcriptHash := sm3.Sum([]byte("transcript"))
+	content, err := tls13SignedContent(transcriptHash[:], TLS13Server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := append(bytes.Repeat([]byte{0x20}, 64), []byte("TLS 1.3, server CertificateVerify")...)
+	expected = append(expected, 0)
+	expected = append(expected, transcriptHash[:]...)
+	if !bytes.Equal(content, expected) {
+		t.Errorf("unexpected content %x", content)
+	}
+	content, err = tls13SignedContent(transcriptHash[:], TLS13Client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(content[64:98], []byte("TLS 1.3, client CertificateVerify\x00")) {
+		t.Errorf("unexpected client context %q", content[64:98])
+	}
+	if _, err := tls13SignedContent(transcriptHash[:], TLS13Role(2)); err == nil {
+		t.Error("expected error with invalid role")
+	}
+	if hex.EncodeToString(TLS13UID) != "544c5376312e332b474d2b4369706865722b5375697465" {
+		t.Errorf("unexpected uid %x", TLS13UID)
+	}
+}
+
+func TestSignVerifyTLS13(t *testing.T) {
+	priv, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transcriptHash := sm3.Sum([]byte("ClientHello...Certificate"))
+	for _, role := range []TLS13Role{TLS13Server, TLS13Client} {
+		sig, err := SignTLS13(rand.Reader, priv, transcriptHash[:], role)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !VerifyTLS13(&priv.PublicKey, transcriptHash[:], sig, role) {
+			t.Errorf("role %v: verification failed", role)
+		}
+		if VerifyTLS13(&priv.PublicKey, transcriptHash[:], sig, 1-role) {
+			t.Errorf("role %v: verification succeeded with the other role", role)
+		}
+		if VerifyTLS13(&priv.PublicKey, transcriptHash[1:], sig, role) {
+			t.Errorf("role %v: verification succeeded with other transcript hash", role)
+		}
+		// the signature is a plain SM2 signature over the signed content with the RFC 8998 UID.
+		content, _ := tls13SignedContent(transcriptHash[:], role)
+		if !VerifyASN1WithSM2(&priv.PublicKey, TLS13UID, content, sig) {
+			t.Errorf("role %v: not a SM2 signature with the TLS 1.3 UID", role)
+		}
+		if VerifyASN1WithSM2(&priv.PublicKey, nil, content, sig) {
+			t.Errorf("role %v: verification succeeded with the default UID", role)
+		}
+	}
+	if _, err := SignTLS13(rand.Reader, priv, transcriptHash[:], TLS13Role(-1)); err == nil {
+		t.Error("expected error with invalid role")
+	}
+}
+
+// TestVerifyTLS13OpenSSL checks signatures made by OpenSSL 3.0 over the
+// RFC 8998 signed content of the SM3 hash of "ClientHello...Certificate":
+//
+//	openssl pkeyutl -sign -inkey key.pem -in content -rawin -digest sm3 \
+//		-pkeyopt distid:TLSv1.3+GM+Cipher+Suite
+//
+// They pin the SM2 signature with the RFC 8998 UID, but not the content
+// construction, which OpenSSL 3.0 doesn't implement for TLS 1.3.
+func TestVerifyTLS13OpenSSL(t *testing.T) {
+	pubBytes, _ := hex.DecodeString("04dfefb91b73e81142ebb1ac56dc07de2b5cee114f0717c64257285905099b968d918d85150e2833f5d63ca6f22d65585842f7ca12ffdd47f11ffcdd0551f3b4c0")
+	pub, err := NewPublicKey(pubBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transcriptHash := sm3.Sum([]byte("ClientHello...Certificate"))
+	tests := []struct {
+		role TLS13Role
+		sig  string
+	}{
+		{TLS13Server, "3046022100e25b06a543b059ce37399ea26b14064063a6b6994b3ca3fd0339cdfc651c8456022100b6ad9d294cb8668fec4b9f11f9b6167500cb9e486dadcbe402a94760b86050e0"},
+		{TLS13Client, "3045022005b6cade1ca99c6a55aceaefa9307d57ca6b247c6dccbb560a850096b6d802b50221009e095d298a4d69c12d8df5524b354bf1d4703932e518231de54f8ea1d967666d"},
+	}
+	for _, tt := range tests {
+		sig, _ := hex.DecodeString(tt.sig)
+		if !VerifyTLS13(pub, transcriptHash[:], sig, tt.role) {
+			t.Errorf("role %v: verification failed", tt.role)
+		}
+		if VerifyTLS13(pub, transcriptHash[:], sig, 1-tt.role) {
+			t.Errorf("role %v: verification succeeded with the other role", tt.role)
+		}
+	}
+}
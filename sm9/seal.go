@@ -0,0 +1,91 @@
+package sm9
+
+import (
+	"crypto/cipher"
+	"errors"
+	"io"
+
+	"github.com/yunmoon/gmsm/internal/byteorder"
+	"github.com/yunmoon/gmsm/sm4"
+)
+
+const (
+	sealVersion   = 1
+	sealC1Size    = 65
+	sealNonceSize = 12
+	sealTagSize   = 16
+	sealOverhead  = 1 + sealC1Size + sealNonceSize + sealTagSize
+)
+
+// SealToIdentity encrypts plaintext to the identity uid with SM9 key encapsulation
+// and SM4-GCM under the encapsulated key. The sealed message is framed as
+//
+//	version(1) || C1(65, uncompressed G1 point) || nonce(12) || ciphertext || tag(16)
+//
+// The version, hid and uid are bound into the GCM additional data together with aad,
+// so the sealed message can't be opened as if it was sealed to another identity.
+//
+// Most applications should use [crypto/rand.Reader] as rand.
+func SealToIdentity(rand io.Reader, pub *EncryptMasterPublicKey, uid []byte, hid byte, plaintext, aad []byte) ([]byte, error) {
+	key, c1, err := WrapKey(rand, pub, uid, hid, sm4.BlockSize)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newSealAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 1+sealC1Size+sealNonceSize, len(plaintext)+sealOverhead)
+	out[0] = sealVersion
+	copy(out[1:], c1)
+	nonce := out[1+sealC1Size:]
+	if _, err := io.ReadFull(rand, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(out, nonce, plaintext, sealAdditionalData(uid, hid, aad)), nil
+}
+
+// OpenWithKey decrypts the message sealed by [SealToIdentity] with the user's
+// encryption private key. The uid, hid and aad must be the same as the ones used
+// to seal the message.
+func OpenWithKey(priv *EncryptPrivateKey, uid []byte, hid byte, ciphertext, aad []byte) ([]byte, error) {
+	if len(ciphertext) < sealOverhead {
+		return nil, ErrDecryption
+	}
+	if ciphertext[0] != sealVersion {
+		return nil, errors.New("sm9: unsupported sealed message version")
+	}
+	key, err := UnwrapKey(priv, uid, ciphertext[1:1+sealC1Size], sm4.BlockSize)
+	if err != nil {
+		return nil, ErrDecryption
+	}
+	aead, err := newSealAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := ciphertext[1+sealC1Size : 1+sealC1Size+sealNonceSize]
+	plaintext, err := aead.Open(nil, nonce, ciphertext[1+sealC1Size+sealNonceSize:], sealAdditionalData(uid, hid, aad))
+	if err != nil {
+		return nil, ErrDecryption
+	}
+	return plaintext, nil
+}
+
+func newSealAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := sm4.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCMWithNonceSize(block, sealNonceSize)
+}
+
+// sealAdditionalData returns version || hid || len(uid) || uid || aad,
+// the length is a 4 bytes big-endian integer.
+func sealAdditionalData(uid []byte, hid byte, aad []byte) []byte {
+	ad := make([]byte, 6, 6+len(uid)+len(aad))
+	ad[0] = sealVersion
+	ad[1] = hid
+	byteorder.BEPutUint32(ad[2:], uint32(len(uid)))
+	ad = append(ad, uid...)
+	return append(ad, aad...)
+}
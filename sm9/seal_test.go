@@ -0,0 +1,89 @@
+package sm9_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/yunmoon/gmsm/sm9"
+)
+
+func TestSealToIdentity(t *testing.T) {
+	masterKey, err := sm9.GenerateEncryptMasterKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid := []byte("emmansun")
+	hid := byte(0x03)
+	userKey, err := masterKey.GenerateUserKey(uid, hid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext := []byte("Chinese IBE standard")
+	aad := []byte("header")
+
+	sealed, err := sm9.SealToIdentity(rand.Reader, masterKey.PublicKey(), uid, hid, plaintext, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := sm9.OpenWithKey(userKey, uid, hid, sealed, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("expected %s, got %s", plaintext, got)
+	}
+
+	// empty plaintext and aad
+	sealed2, err := sm9.SealToIdentity(rand.Reader, masterKey.PublicKey(), uid, hid, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := sm9.OpenWithKey(userKey, uid, hid, sealed2, nil); err != nil || len(got) != 0 {
+		t.Errorf("unexpected result %x, %v", got, err)
+	}
+
+	t.Run("wrong identity", func(t *testing.T) {
+		otherUID := []byte("other")
+		otherKey, err := masterKey.GenerateUserKey(otherUID, hid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := sm9.OpenWithKey(otherKey, otherUID, hid, sealed, aad); err == nil {
+			t.Errorf("expected error with other identity key")
+		}
+		if _, err := sm9.OpenWithKey(userKey, otherUID, hid, sealed, aad); err == nil {
+			t.Errorf("expected error with other uid")
+		}
+		if _, err := sm9.OpenWithKey(userKey, uid, 0x01, sealed, aad); err == nil {
+			t.Errorf("expected error with other hid")
+		}
+	})
+
+	t.Run("aad mismatch", func(t *testing.T) {
+		if _, err := sm9.OpenWithKey(userKey, uid, hid, sealed, []byte("Header")); err == nil {
+			t.Errorf("expected error with different aad")
+		}
+		if _, err := sm9.OpenWithKey(userKey, uid, hid, sealed, nil); err == nil {
+			t.Errorf("expected error without aad")
+		}
+	})
+
+	t.Run("truncation", func(t *testing.T) {
+		for i := 0; i < len(sealed); i++ {
+			if _, err := sm9.OpenWithKey(userKey, uid, hid, sealed[:i], aad); err == nil {
+				t.Fatalf("expected error with sealed message truncated to %d bytes", i)
+			}
+		}
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		for i := 0; i < len(sealed); i++ {
+			tampered := bytes.Clone(sealed)
+			tampered[i] ^= 0x80
+			if _, err := sm9.OpenWithKey(userKey, uid, hid, tampered, aad); err == nil {
+				t.Fatalf("expected error with byte %d flipped", i)
+			}
+		}
+	})
+}
@@ -25,6 +25,26 @@ const (
 	ENC_TYPE_CFB encryptType = 8
 )
 
+// H1 computes H1(uid||hid, N) defined in GM/T 0044, the cryptographic hash function
+// which maps the user identity to a scalar in [1, N-1]. The result is a 32 bytes
+// big-endian integer.
+func H1(uid []byte, hid byte) []byte {
+	z := make([]byte, 0, len(uid)+1)
+	z = append(z, uid...)
+	z = append(z, hid)
+	return sm9.HashH1(z)
+}
+
+// H2 computes H2(msg||w, N) defined in GM/T 0044, the cryptographic hash function
+// used in signature generation and verification, w is the marshaled GT element.
+// The result is a 32 bytes big-endian integer.
+func H2(msg, w []byte) []byte {
+	z := make([]byte, 0, len(msg)+len(w))
+	z = append(z, msg...)
+	z = append(z, w...)
+	return sm9.HashH2(z)
+}
+
 // Sign signs a hash (which should be the result of hashing a larger message)
 // using the user dsa key. It returns the signature as a pair of h and s.
 // Please use SignASN1 instead.
@@ -272,6 +292,9 @@ func Decrypt(priv *EncryptPrivateKey, uid, ciphertext []byte, opts EncrypterOpts
 	if opts == nil {
 		opts = DefaultEncrypterOpts
 	}
+	if len(ciphertext) <= 64+sm3.Size {
+		return nil, ErrDecryption
+	}
 
 	c1 := ciphertext[:64]
 	c3c2 := ciphertext[64:]
@@ -14,6 +14,20 @@ import (
 	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
 )
 
+// PEM block types of master public keys, the same as GMSSL.
+const (
+	signMasterPublicKeyPEMType    = "SM9 SIGN MASTER PUBLIC KEY"
+	encryptMasterPublicKeyPEMType = "SM9 ENC MASTER PUBLIC KEY"
+)
+
+// PEM block types of the encrypted private keys, the same as GMSSL.
+const (
+	signMasterPrivateKeyPEMType    = "ENCRYPTED SM9 SIGN MASTER KEY"
+	signPrivateKeyPEMType          = "ENCRYPTED SM9 SIGN PRIVATE KEY"
+	encryptMasterPrivateKeyPEMType = "ENCRYPTED SM9 ENC MASTER KEY"
+	encryptPrivateKeyPEMType       = "ENCRYPTED SM9 ENC PRIVATE KEY"
+)
+
 // SignMasterPrivateKey is a signature master private key, generated by KGC
 type SignMasterPrivateKey struct {
 	privateKey []byte
@@ -63,6 +77,18 @@ func GenerateSignMasterKey(rand io.Reader) (*SignMasterPrivateKey, error) {
 	return master, nil
 }
 
+// NewSignMasterPrivateKey creates a signature master private key from the given
+// big-endian scalar, which must be in the range [1, n-1].
+func NewSignMasterPrivateKey(key []byte) (*SignMasterPrivateKey, error) {
+	priv, err := sm9.NewSignMasterPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	master := &SignMasterPrivateKey{privateKey: priv.Bytes(), internal: priv}
+	master.publicKey = &SignMasterPublicKey{publicKey: priv.PublicKey().Bytes(), internal: priv.PublicKey()}
+	return master, nil
+}
+
 // Equal compares the receiver SignMasterPrivateKey with another SignMasterPrivateKey
 // and returns true if they are equal, otherwise it returns false.
 func (master *SignMasterPrivateKey) Equal(x crypto.PrivateKey) bool {
@@ -96,13 +122,12 @@ func UnmarshalSignMasterPrivateKeyASN1(der []byte) (*SignMasterPrivateKey, error
 	var inner cryptobyte.String
 	var pubBytes []byte
 	var err error
-	if der[0] == 0x30 {
+	if len(der) > 0 && der[0] == 0x30 {
 		if !input.ReadASN1(&inner, cryptobyte_asn1.SEQUENCE) ||
 			!input.Empty() ||
 			!inner.ReadASN1Integer(d) {
 			return nil, errors.New("sm9: invalid ASN.1 data for signature master private key")
 		}
-		// Just parse it, didn't validate it
 		if !inner.Empty() && (!inner.ReadASN1BitStringAsBytes(&pubBytes) || !inner.Empty()) {
 			return nil, errors.New("sm9: invalid ASN.1 data for signature master public key")
 		}
@@ -120,9 +145,40 @@ func UnmarshalSignMasterPrivateKeyASN1(der []byte) (*SignMasterPrivateKey, error
 		publicKey: priv.PublicKey().Bytes(),
 		internal:  priv.PublicKey(),
 	}
+	if len(pubBytes) > 0 {
+		pub, err := UnmarshalSignMasterPublicKeyRaw(pubBytes)
+		if err != nil {
+			return nil, err
+		}
+		if !pub.Equal(master.publicKey) {
+			return nil, errors.New("sm9: signature master public key does not match the private key")
+		}
+	}
 	return master, nil
 }
 
+// MarshalPEM marshals the signature master private key and its master public key to the
+// encrypted PEM format used by GMSSL, the block type is "ENCRYPTED SM9 SIGN MASTER KEY".
+// The PKCS #8 private key is encrypted with password using PBES2 with
+// PBKDF2-HMAC-SM3 and SM4-CBC.
+func (master *SignMasterPrivateKey) MarshalPEM(rand io.Reader, password []byte) ([]byte, error) {
+	der, err := marshalMasterPrivateKeySequence(master.privateKey, master.publicKey.publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return marshalEncryptedPEM(rand, password, signMasterPrivateKeyPEMType, oidSM9, oidSM9Sign, der)
+}
+
+// ParseSignMasterPrivateKeyPEM parses a signature master private key encrypted with password in
+// the format of [SignMasterPrivateKey.MarshalPEM], as written by GMSSL.
+func ParseSignMasterPrivateKeyPEM(data, password []byte) (*SignMasterPrivateKey, error) {
+	der, err := parseEncryptedPEM(data, password, signMasterPrivateKeyPEMType, oidSM9, oidSM9Sign)
+	if err != nil {
+		return nil, err
+	}
+	return UnmarshalSignMasterPrivateKeyASN1(der)
+}
+
 // GenerateUserKey generate a signature private key for the given user.
 func (master *SignMasterPrivateKey) GenerateUserKey(uid []byte, hid byte) (*SignPrivateKey, error) {
 	priv, err := master.internal.GenerateUserKey(uid, hid)
@@ -188,7 +244,7 @@ func UnmarshalSignMasterPublicKeyASN1(der []byte) (*SignMasterPublicKey, error)
 	var bytes []byte
 	var inner cryptobyte.String
 	input := cryptobyte.String(der)
-	if der[0] == 0x30 {
+	if len(der) > 0 && der[0] == 0x30 {
 		if !input.ReadASN1(&inner, cryptobyte_asn1.SEQUENCE) ||
 			!input.Empty() ||
 			!inner.ReadASN1BitStringAsBytes(&bytes) ||
@@ -201,7 +257,8 @@ func UnmarshalSignMasterPublicKeyASN1(der []byte) (*SignMasterPublicKey, error)
 	return UnmarshalSignMasterPublicKeyRaw(bytes)
 }
 
-// ParseSignMasterPublicKeyPEM just for GMSSL, there are no Algorithm pkix.AlgorithmIdentifier
+// ParseSignMasterPublicKeyPEM just for GMSSL, there are no Algorithm pkix.AlgorithmIdentifier.
+// The PEM block type is not checked.
 func ParseSignMasterPublicKeyPEM(data []byte) (*SignMasterPublicKey, error) {
 	block, _ := pem.Decode([]byte(data))
 	if block == nil {
@@ -210,6 +267,24 @@ func ParseSignMasterPublicKeyPEM(data []byte) (*SignMasterPublicKey, error) {
 	return UnmarshalSignMasterPublicKeyASN1(block.Bytes)
 }
 
+// MarshalPEM marshals the signature master public key to the PEM format used by GMSSL,
+// the block type is "SM9 SIGN MASTER PUBLIC KEY".
+func (pub *SignMasterPublicKey) MarshalPEM() ([]byte, error) {
+	der, err := marshalMasterPublicKeySequence(pub.publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: signMasterPublicKeyPEMType, Bytes: der}), nil
+}
+
+func marshalMasterPublicKeySequence(publicKey []byte) ([]byte, error) {
+	var b cryptobyte.Builder
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1BitString(publicKey)
+	})
+	return b.Bytes()
+}
+
 func (priv *SignPrivateKey) Equal(x crypto.PrivateKey) bool {
 	xx, ok := x.(*SignPrivateKey)
 	if !ok {
@@ -271,7 +346,7 @@ func UnmarshalSignPrivateKeyASN1(der []byte) (*SignPrivateKey, error) {
 	var pubBytes []byte
 	var inner cryptobyte.String
 	input := cryptobyte.String(der)
-	if der[0] == 0x30 {
+	if len(der) > 0 && der[0] == 0x30 {
 		if !input.ReadASN1(&inner, cryptobyte_asn1.SEQUENCE) ||
 			!input.Empty() ||
 			!inner.ReadASN1BitStringAsBytes(&bytes) {
@@ -298,6 +373,50 @@ func UnmarshalSignPrivateKeyASN1(der []byte) (*SignPrivateKey, error) {
 	return priv, nil
 }
 
+// MarshalPEM marshals the signature private key and its master public key to the
+// encrypted PEM format used by GMSSL, the block type is "ENCRYPTED SM9 SIGN PRIVATE KEY".
+// The PKCS #8 private key is encrypted with password using PBES2 with
+// PBKDF2-HMAC-SM3 and SM4-CBC.
+func (priv *SignPrivateKey) MarshalPEM(rand io.Reader, password []byte) ([]byte, error) {
+	der, err := marshalUserPrivateKeySequence(priv.privateKey, priv.MasterPublic().publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return marshalEncryptedPEM(rand, password, signPrivateKeyPEMType, oidSM9Sign, nil, der)
+}
+
+// ParseSignPrivateKeyPEM parses a signature private key encrypted with password in
+// the format of [SignPrivateKey.MarshalPEM], as written by GMSSL.
+func ParseSignPrivateKeyPEM(data, password []byte) (*SignPrivateKey, error) {
+	der, err := parseEncryptedPEM(data, password, signPrivateKeyPEMType, oidSM9Sign, nil)
+	if err != nil {
+		return nil, err
+	}
+	return UnmarshalSignPrivateKeyASN1(der)
+}
+
+func marshalUserPrivateKeySequence(privateKey, masterPublicKey []byte) ([]byte, error) {
+	var b cryptobyte.Builder
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1BitString(privateKey)
+		b.AddASN1BitString(masterPublicKey)
+	})
+	return b.Bytes()
+}
+
+// decodePEM returns the content of the first PEM block of data, which must be
+// of type pemType.
+func decodePEM(data []byte, pemType string) ([]byte, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("sm9: failed to parse PEM block")
+	}
+	if block.Type != pemType {
+		return nil, errors.New("sm9: unexpected PEM block type " + block.Type)
+	}
+	return block.Bytes, nil
+}
+
 // GenerateEncryptMasterKey generates an encryption master key pair.
 func GenerateEncryptMasterKey(rand io.Reader) (*EncryptMasterPrivateKey, error) {
 	priv, err := sm9.GenerateEncryptMasterKey(rand)
@@ -309,6 +428,18 @@ func GenerateEncryptMasterKey(rand io.Reader) (*EncryptMasterPrivateKey, error)
 	return master, nil
 }
 
+// NewEncryptMasterPrivateKey creates an encryption master private key from the
+// given big-endian scalar, which must be in the range [1, n-1].
+func NewEncryptMasterPrivateKey(key []byte) (*EncryptMasterPrivateKey, error) {
+	priv, err := sm9.NewEncryptMasterPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	master := &EncryptMasterPrivateKey{privateKey: priv.Bytes(), internal: priv}
+	master.publicKey = &EncryptMasterPublicKey{publicKey: priv.PublicKey().Bytes(), internal: priv.PublicKey()}
+	return master, nil
+}
+
 // Bytes returns the byte representation of the EncryptMasterPrivateKey.
 // It delegates the call to the Bytes method of the underlying privateKey.
 func (master *EncryptMasterPrivateKey) Bytes() []byte {
@@ -359,13 +490,12 @@ func UnmarshalEncryptMasterPrivateKeyASN1(der []byte) (*EncryptMasterPrivateKey,
 	d := &big.Int{}
 	var inner cryptobyte.String
 	var pubBytes []byte
-	if der[0] == 0x30 {
+	if len(der) > 0 && der[0] == 0x30 {
 		if !input.ReadASN1(&inner, cryptobyte_asn1.SEQUENCE) ||
 			!input.Empty() ||
 			!inner.ReadASN1Integer(d) {
 			return nil, errors.New("sm9: invalid ASN.1 data for encryption master private key")
 		}
-		// Just parse it, did't validate it
 		if !inner.Empty() && (!inner.ReadASN1BitStringAsBytes(&pubBytes) || !inner.Empty()) {
 			return nil, errors.New("sm9: invalid ASN.1 data for encryption master public key")
 		}
@@ -382,9 +512,40 @@ func UnmarshalEncryptMasterPrivateKeyASN1(der []byte) (*EncryptMasterPrivateKey,
 		publicKey: privateKey.PublicKey().Bytes(),
 		internal:  privateKey.PublicKey(),
 	}
+	if len(pubBytes) > 0 {
+		pub, err := UnmarshalEncryptMasterPublicKeyRaw(pubBytes)
+		if err != nil {
+			return nil, err
+		}
+		if !pub.Equal(master.publicKey) {
+			return nil, errors.New("sm9: encryption master public key does not match the private key")
+		}
+	}
 	return master, nil
 }
 
+// MarshalPEM marshals the encryption master private key and its master public key to the
+// encrypted PEM format used by GMSSL, the block type is "ENCRYPTED SM9 ENC MASTER KEY".
+// The PKCS #8 private key is encrypted with password using PBES2 with
+// PBKDF2-HMAC-SM3 and SM4-CBC.
+func (master *EncryptMasterPrivateKey) MarshalPEM(rand io.Reader, password []byte) ([]byte, error) {
+	der, err := marshalMasterPrivateKeySequence(master.privateKey, master.publicKey.publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return marshalEncryptedPEM(rand, password, encryptMasterPrivateKeyPEMType, oidSM9, oidSM9Enc, der)
+}
+
+// ParseEncryptMasterPrivateKeyPEM parses an encryption master private key encrypted with password in
+// the format of [EncryptMasterPrivateKey.MarshalPEM], as written by GMSSL.
+func ParseEncryptMasterPrivateKeyPEM(data, password []byte) (*EncryptMasterPrivateKey, error) {
+	der, err := parseEncryptedPEM(data, password, encryptMasterPrivateKeyPEMType, oidSM9, oidSM9Enc)
+	if err != nil {
+		return nil, err
+	}
+	return UnmarshalEncryptMasterPrivateKeyASN1(der)
+}
+
 // Equal compares the receiver EncryptMasterPublicKey with another EncryptMasterPublicKey
 // and returns true if they are equal, otherwise it returns false.
 func (pub *EncryptMasterPublicKey) Equal(x crypto.PublicKey) bool {
@@ -430,7 +591,8 @@ func UnmarshalEncryptMasterPublicKeyRaw(bytes []byte) (*EncryptMasterPublicKey,
 	return pub, nil
 }
 
-// ParseEncryptMasterPublicKeyPEM just for GMSSL, there are no Algorithm pkix.AlgorithmIdentifier
+// ParseEncryptMasterPublicKeyPEM just for GMSSL, there are no Algorithm pkix.AlgorithmIdentifier.
+// The PEM block type is not checked.
 func ParseEncryptMasterPublicKeyPEM(data []byte) (*EncryptMasterPublicKey, error) {
 	block, _ := pem.Decode([]byte(data))
 	if block == nil {
@@ -439,12 +601,22 @@ func ParseEncryptMasterPublicKeyPEM(data []byte) (*EncryptMasterPublicKey, error
 	return UnmarshalEncryptMasterPublicKeyASN1(block.Bytes)
 }
 
+// MarshalPEM marshals the encryption master public key to the PEM format used by GMSSL,
+// the block type is "SM9 ENC MASTER PUBLIC KEY".
+func (pub *EncryptMasterPublicKey) MarshalPEM() ([]byte, error) {
+	der, err := marshalMasterPublicKeySequence(pub.publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: encryptMasterPublicKeyPEMType, Bytes: der}), nil
+}
+
 // UnmarshalEncryptMasterPublicKeyASN1 unmarsal der data to encryption master public key
 func UnmarshalEncryptMasterPublicKeyASN1(der []byte) (*EncryptMasterPublicKey, error) {
 	var bytes []byte
 	var inner cryptobyte.String
 	input := cryptobyte.String(der)
-	if der[0] == 0x30 {
+	if len(der) > 0 && der[0] == 0x30 {
 		if !input.ReadASN1(&inner, cryptobyte_asn1.SEQUENCE) ||
 			!input.Empty() ||
 			!inner.ReadASN1BitStringAsBytes(&bytes) ||
@@ -499,7 +671,7 @@ func UnmarshalEncryptPrivateKeyASN1(der []byte) (*EncryptPrivateKey, error) {
 	var pubBytes []byte
 	var inner cryptobyte.String
 	input := cryptobyte.String(der)
-	if der[0] == 0x30 {
+	if len(der) > 0 && der[0] == 0x30 {
 		if !input.ReadASN1(&inner, cryptobyte_asn1.SEQUENCE) ||
 			!input.Empty() ||
 			!inner.ReadASN1BitStringAsBytes(&bytes) {
@@ -525,6 +697,28 @@ func UnmarshalEncryptPrivateKeyASN1(der []byte) (*EncryptPrivateKey, error) {
 	return priv, nil
 }
 
+// MarshalPEM marshals the encryption private key and its master public key to the
+// encrypted PEM format used by GMSSL, the block type is "ENCRYPTED SM9 ENC PRIVATE KEY".
+// The PKCS #8 private key is encrypted with password using PBES2 with
+// PBKDF2-HMAC-SM3 and SM4-CBC.
+func (priv *EncryptPrivateKey) MarshalPEM(rand io.Reader, password []byte) ([]byte, error) {
+	der, err := marshalUserPrivateKeySequence(priv.privateKey, priv.MasterPublic().publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return marshalEncryptedPEM(rand, password, encryptPrivateKeyPEMType, oidSM9Enc, nil, der)
+}
+
+// ParseEncryptPrivateKeyPEM parses an encryption private key encrypted with password in
+// the format of [EncryptPrivateKey.MarshalPEM], as written by GMSSL.
+func ParseEncryptPrivateKeyPEM(data, password []byte) (*EncryptPrivateKey, error) {
+	der, err := parseEncryptedPEM(data, password, encryptPrivateKeyPEMType, oidSM9Enc, nil)
+	if err != nil {
+		return nil, err
+	}
+	return UnmarshalEncryptPrivateKeyASN1(der)
+}
+
 // Equal compares the receiver EncryptPrivateKey with another EncryptPrivateKey x
 // and returns true if they are equal, otherwise false.
 func (priv *EncryptPrivateKey) Equal(x crypto.PrivateKey) bool {
@@ -0,0 +1,168 @@
+package sm9
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/yunmoon/gmsm/pkcs"
+	"github.com/yunmoon/gmsm/sm3"
+	"golang.org/x/crypto/cryptobyte"
+	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+var (
+	oidSM9         = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 302}
+	oidSM9Sign     = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 302, 1}
+	oidSM9Enc      = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 302, 3}
+	oidPBKDF2      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSM3 = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 401, 2}
+)
+
+// The salt size and iteration count GmSSL uses to encrypt SM9 private keys.
+const (
+	pbkdf2SaltSize       = 16
+	pbkdf2IterationCount = 65536
+)
+
+// gmsslPBKDF2Params are the PBKDF2 parameters as GmSSL writes them: the key
+// length is present and the HMAC-SM3 algorithm identifier has no parameters.
+type gmsslPBKDF2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLen         int
+	PRF            struct {
+		Algorithm asn1.ObjectIdentifier
+	}
+}
+
+func (p gmsslPBKDF2Params) DeriveKey(_ asn1.ObjectIdentifier, password []byte, size int) ([]byte, error) {
+	return pbkdf2.Key(password, p.Salt, p.IterationCount, size, sm3.New), nil
+}
+
+func (p gmsslPBKDF2Params) KeyLength() int {
+	return p.KeyLen
+}
+
+// gmsslPBKDF2Opts derives the PBES2 key with PBKDF2 and HMAC-SM3.
+type gmsslPBKDF2Opts struct{}
+
+func (gmsslPBKDF2Opts) DeriveKey(password, salt []byte, size int) ([]byte, pkcs.KDFParameters, error) {
+	params := gmsslPBKDF2Params{Salt: salt, IterationCount: pbkdf2IterationCount, KeyLen: size}
+	params.PRF.Algorithm = oidHMACWithSM3
+	key, err := params.DeriveKey(oidPBKDF2, password, size)
+	return key, params, err
+}
+
+func (gmsslPBKDF2Opts) GetSaltSize() int {
+	return pbkdf2SaltSize
+}
+
+func (gmsslPBKDF2Opts) OID() asn1.ObjectIdentifier {
+	return oidPBKDF2
+}
+
+type encryptedPrivateKeyInfo struct {
+	EncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedData       []byte
+}
+
+// marshalEncryptedPEM wraps key in a PKCS #8 PrivateKeyInfo with the
+// algorithm algo and, if present, the parameter param, then encrypts it as
+// GmSSL does: PBES2 with PBKDF2-HMAC-SM3 and SM4-CBC.
+func marshalEncryptedPEM(rand io.Reader, password []byte, pemType string, algo, param asn1.ObjectIdentifier, key []byte) ([]byte, error) {
+	if len(password) == 0 {
+		return nil, errors.New("sm9: empty password")
+	}
+	var b cryptobyte.Builder
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1Int64(0)
+		b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			b.AddASN1ObjectIdentifier(algo)
+			if param != nil {
+				b.AddASN1ObjectIdentifier(param)
+			}
+		})
+		b.AddASN1OctetString(key)
+	})
+	privateKeyInfo, err := b.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	encrypter := pkcs.NewPBESEncrypter(pkcs.SM4CBC, gmsslPBKDF2Opts{})
+	encryptionAlgorithm, encryptedData, err := encrypter.Encrypt(rand, password, privateKeyInfo)
+	if err != nil {
+		return nil, err
+	}
+	der, err := asn1.Marshal(encryptedPrivateKeyInfo{
+		EncryptionAlgorithm: *encryptionAlgorithm,
+		EncryptedData:       encryptedData,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: pemType, Bytes: der}), nil
+}
+
+// parseEncryptedPEM decrypts the PBES2 encrypted PKCS #8 PrivateKeyInfo in
+// the PEM block of type pemType and returns the private key it holds. The
+// algorithm of the PrivateKeyInfo must be algo with the parameter param, if
+// present.
+func parseEncryptedPEM(data, password []byte, pemType string, algo, param asn1.ObjectIdentifier) ([]byte, error) {
+	der, err := decodePEM(data, pemType)
+	if err != nil {
+		return nil, err
+	}
+	var encrypted encryptedPrivateKeyInfo
+	if rest, err := asn1.Unmarshal(der, &encrypted); err != nil || len(rest) != 0 {
+		return nil, errors.New("sm9: invalid encrypted private key")
+	}
+	if !pkcs.IsPBES2(encrypted.EncryptionAlgorithm) {
+		return nil, errors.New("sm9: unsupported private key encryption")
+	}
+	var params pkcs.PBES2Params
+	if _, err := asn1.Unmarshal(encrypted.EncryptionAlgorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, errors.New("sm9: invalid PBES2 parameters")
+	}
+	privateKeyInfo, _, err := params.Decrypt(password, encrypted.EncryptedData)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		input, inner, algorithm cryptobyte.String
+		version                 int64
+		gotAlgo, gotParam       asn1.ObjectIdentifier
+		key                     []byte
+	)
+	input = cryptobyte.String(privateKeyInfo)
+	if !input.ReadASN1(&inner, cryptobyte_asn1.SEQUENCE) ||
+		!input.Empty() ||
+		!inner.ReadASN1Integer(&version) || version != 0 ||
+		!inner.ReadASN1(&algorithm, cryptobyte_asn1.SEQUENCE) ||
+		!algorithm.ReadASN1ObjectIdentifier(&gotAlgo) ||
+		!inner.ReadASN1Bytes(&key, cryptobyte_asn1.OCTET_STRING) {
+		return nil, errors.New("sm9: invalid PKCS #8 private key")
+	}
+	if algorithm.PeekASN1Tag(cryptobyte_asn1.OBJECT_IDENTIFIER) && !algorithm.ReadASN1ObjectIdentifier(&gotParam) {
+		return nil, errors.New("sm9: invalid PKCS #8 private key")
+	}
+	if !gotAlgo.Equal(algo) || (param != nil && !gotParam.Equal(param)) {
+		return nil, errors.New("sm9: unexpected private key algorithm")
+	}
+	return key, nil
+}
+
+func marshalMasterPrivateKeySequence(privateKey, publicKey []byte) ([]byte, error) {
+	var b cryptobyte.Builder
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1BigInt(new(big.Int).SetBytes(privateKey))
+		b.AddASN1BitString(publicKey)
+	})
+	return b.Bytes()
+}
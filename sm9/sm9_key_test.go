@@ -2,8 +2,11 @@ package sm9
 
 import (
 	"crypto/rand"
+	"encoding/asn1"
 	"encoding/hex"
 	"encoding/pem"
+	"math/big"
+	"reflect"
 	"testing"
 
 	"golang.org/x/crypto/cryptobyte"
@@ -288,6 +291,24 @@ func TestParseSM9SignMasterPublicKey(t *testing.T) {
 	if pemContent != sm9SignMasterPublicKeyFromGMSSL {
 		t.Fatalf("failed %s\n", pemContent)
 	}
+
+	pemBytes, err := key.MarshalPEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(pemBytes) != sm9SignMasterPublicKeyFromGMSSL {
+		t.Fatalf("MarshalPEM failed %s\n", pemBytes)
+	}
+
+	// The block type is not checked.
+	block = &pem.Block{Bytes: data, Type: "PUBLIC KEY"}
+	key2, err := ParseSignMasterPublicKeyPEM(pem.EncodeToMemory(block))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !key.Equal(key2) {
+		t.Errorf("not same")
+	}
 }
 
 const sm9EncMasterPublicKeyFromGMSSL = `-----BEGIN SM9 ENC MASTER PUBLIC KEY-----
@@ -321,4 +342,341 @@ func TestParseSM9EncryptMasterPublicKey(t *testing.T) {
 	if pemContent != sm9EncMasterPublicKeyFromGMSSL {
 		t.Fatalf("failed %s\n", pemContent)
 	}
+
+	pemBytes, err := key.MarshalPEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(pemBytes) != sm9EncMasterPublicKeyFromGMSSL {
+		t.Fatalf("MarshalPEM failed %s\n", pemBytes)
+	}
+
+	// The block type is not checked.
+	block = &pem.Block{Bytes: data, Type: "PUBLIC KEY"}
+	key2, err := ParseEncryptMasterPublicKeyPEM(pem.EncodeToMemory(block))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !key.Equal(key2) {
+		t.Errorf("not same")
+	}
+}
+
+// The encrypted SM9 private keys generated by GMSSL, the password is "123456".
+// The encryption private key is derived from the encryption master key.
+const sm9SignMasterPrivateKeyFromGMSSL = `-----BEGIN ENCRYPTED SM9 SIGN MASTER KEY-----
+MIIBNjBhBgkqhkiG9w0BBQ0wVDA0BgkqhkiG9w0BBQwwJwQQuRb0v3wC0mAANvym
+YLiNSAIDAQAAAgEQMAsGCSqBHM9VAYMRAjAcBggqgRzPVQFoAgQQuzlPtraTjW+9
+EpGFcss7TgSB0KnwoVMBPUFcZEqiH1DPFZVqHcbdfmZheeIXCdeS5cYGL7Cg6Ohd
+YZe9LWCFNBAvJz6zxeJGiaeR3VW5QDB+jPtSYIu9ET85yFsaohLt/ZNdgdGec2s6
+rmG5/ufL+9LtprtWF4BcbwTCCPr6mX3Tvq+Xacw0YY9VHcpUDOUHGwkdhea82M5D
+nUCi5FhiNKDe4Qfp7HG597FlK9Vwy5Nn5xRKsfoG2JJuQYZkqmORFJA/aQQq/ejD
+NbR0XajuWC9+bMq3SeEyT6Je0aEeHuOfKFw=
+-----END ENCRYPTED SM9 SIGN MASTER KEY-----
+`
+
+const sm9SignPrivateKeyFromGMSSL = `-----BEGIN ENCRYPTED SM9 SIGN PRIVATE KEY-----
+MIIBVjBhBgkqhkiG9w0BBQ0wVDA0BgkqhkiG9w0BBQwwJwQQxWctHikJLVP2A7fQ
+nm6qwQIDAQAAAgEQMAsGCSqBHM9VAYMRAjAcBggqgRzPVQFoAgQQfuWLjhO7iJNX
+2owsXE8/6gSB8Ot4oMs97o7dDd6o2U29uTjvkt7Xq/ti/2OPoOvDeGr/SWTmLUHY
+6X71SpB/GAmBVE1qMXSxFHotgeq1cbwuZtwqLV2GA0etAnC2MZV/2BYcx+qOwwgX
+uljiXhlvpvxHfxxdL7HzJ5oC+AuMblQZnAvaicmS9Pr+EPk4gzusiCc4cu1q+sTh
+xl4HzCz08DYx8l5j1B/FCnN0/9tv2F2Q6j3xWARFC8EJPAEhALdO+hol56Tz7A2a
+zSK4N8ox4ip3G8L6TVMIlc8qFIfsnaVn+dQSWDubya8Lq4AieEs8mL+kPqEnSIUX
+fYuup/MCEz2zpA==
+-----END ENCRYPTED SM9 SIGN PRIVATE KEY-----
+`
+
+const sm9EncMasterPrivateKeyFromGMSSL = `-----BEGIN ENCRYPTED SM9 ENC MASTER KEY-----
+MIH2MGEGCSqGSIb3DQEFDTBUMDQGCSqGSIb3DQEFDDAnBBAjXv966WmKuBfUH1Bq
+OMwUAgMBAAACARAwCwYJKoEcz1UBgxECMBwGCCqBHM9VAWgCBBAtVvud0awyXO1r
+dz92Pn+9BIGQlAsGegoSrApDm+rbszu1wsUwAVbq+EtgkraBSZRqGYByBOSN9G9m
+p0lZJ75/TJMqRunkUhAUorNzXkdy2nab1VRs+Y8lKzhw5Y7KLnjbRsoDEPcvluSW
+UVHgVDiaGKLKlKWTdhRRzLnBOocE0LA3FnOH86eUFjGY87ss6vz8iD9JHHfap4yr
+Yut8eao1nBSY
+-----END ENCRYPTED SM9 ENC MASTER KEY-----
+`
+
+const sm9EncPrivateKeyFromGMSSL = `-----BEGIN ENCRYPTED SM9 ENC PRIVATE KEY-----
+MIIBVjBhBgkqhkiG9w0BBQ0wVDA0BgkqhkiG9w0BBQwwJwQQ7qFYth3lhEj9pHl4
+V0HeiwIDAQAAAgEQMAsGCSqBHM9VAYMRAjAcBggqgRzPVQFoAgQQvk8cAqLQcGr1
+LfRo8lz6TwSB8Ma6HVx/t1q+wbu+NLLzq1uok7zNBKM8Z9hFiqrY1pngZTtweVHP
+w/r6inOU4rI9Eex6R7C4koT9cGYN4QBur3BHxTLPM7C4knldxxYHuA98MEGHMMcE
+gJIcgZlrkdprvLSXqdKJ/Ee7Ut4SuJuMW/Ww0hTrOmnI0j4cRAaZAgEh9Lh9B5CK
+tzO+xTcb9siTzgRDKxnsZB85c1pwzQ3LH1KNR7tsg1z/AW+Hab4+8WX7mIIlvmVM
+zkRVx8ZgZCNo/MTFjw2qCNVsGrcj/xFm63p8eWoYGx6eXS6nr3IYRIDwR5F7CoNY
+h1/9v+oJWBaPxQ==
+-----END ENCRYPTED SM9 ENC PRIVATE KEY-----
+`
+
+var testPassword = []byte("123456")
+
+func TestParsePrivateKeyPEMFromGMSSL(t *testing.T) {
+	signMasterKey, err := ParseSignMasterPrivateKeyPEM([]byte(sm9SignMasterPrivateKeyFromGMSSL), testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ParseSignMasterPrivateKeyPEM([]byte(sm9SignMasterPrivateKeyFromGMSSL), []byte("654321")); err == nil {
+		t.Errorf("expected error for wrong password")
+	}
+	signKey, err := ParseSignPrivateKeyPEM([]byte(sm9SignPrivateKeyFromGMSSL), testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encMasterKey, err := ParseEncryptMasterPrivateKeyPEM([]byte(sm9EncMasterPrivateKeyFromGMSSL), testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encKey, err := ParseEncryptPrivateKeyPEM([]byte(sm9EncPrivateKeyFromGMSSL), testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !encKey.MasterPublic().Equal(encMasterKey.PublicKey()) {
+		t.Errorf("encryption private key of another master key")
+	}
+
+	// the keys survive a round trip and are usable
+	pemBytes, err := signMasterKey.MarshalPEM(rand.Reader, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signMasterKey2, err := ParseSignMasterPrivateKeyPEM(pemBytes, testPassword); err != nil || !signMasterKey2.Equal(signMasterKey) {
+		t.Errorf("round trip failed: %v", err)
+	}
+	if pemBytes, err = signKey.MarshalPEM(rand.Reader, testPassword); err != nil {
+		t.Fatal(err)
+	}
+	signKey2, err := ParseSignPrivateKeyPEM(pemBytes, testPassword)
+	if err != nil || !signKey2.Equal(signKey) {
+		t.Fatalf("round trip failed: %v", err)
+	}
+	if !signKey2.MasterPublic().Equal(signKey.MasterPublic()) {
+		t.Errorf("master public key lost in round trip")
+	}
+
+	uid := []byte("emmansun")
+	encKey2, err := encMasterKey.GenerateUserKey(uid, 0x03)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := Encrypt(rand.Reader, encKey.MasterPublic(), uid, 0x03, []byte("Chinese IBE standard"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Decrypt(encKey2, uid, ciphertext, nil); err != nil {
+		t.Errorf("decrypt with the key derived from the GMSSL master key failed: %v", err)
+	}
+}
+
+// checkGMSSLEncryptedPEM checks that the encrypted private key PEM got has
+// the same structure as want, written by GMSSL, apart from the salt, the IV
+// and the encrypted data.
+func checkGMSSLEncryptedPEM(t *testing.T, got []byte, want string) {
+	t.Helper()
+	type pbes2 struct {
+		EncryptionAlgorithm struct {
+			Algorithm asn1.ObjectIdentifier
+			Params    struct {
+				KDF struct {
+					Algorithm asn1.ObjectIdentifier
+					Params    gmsslPBKDF2Params
+				}
+				Cipher struct {
+					Algorithm asn1.ObjectIdentifier
+					IV        []byte
+				}
+			}
+		}
+		EncryptedData []byte
+	}
+	decode := func(data []byte) (pbes2, string) {
+		block, _ := pem.Decode(data)
+		if block == nil {
+			t.Fatalf("failed to decode PEM %s", data)
+		}
+		var v pbes2
+		if rest, err := asn1.Unmarshal(block.Bytes, &v); err != nil || len(rest) != 0 {
+			t.Fatalf("failed to parse %s: %v", block.Type, err)
+		}
+		params := &v.EncryptionAlgorithm.Params
+		if len(params.KDF.Params.Salt) != 16 || len(params.Cipher.IV) != 16 {
+			t.Fatalf("unexpected salt or IV size in %s", block.Type)
+		}
+		params.KDF.Params.Salt, params.Cipher.IV, v.EncryptedData = nil, nil, nil
+		return v, block.Type
+	}
+	gotInfo, gotType := decode(got)
+	wantInfo, wantType := decode([]byte(want))
+	if gotType != wantType {
+		t.Errorf("got PEM block type %q, want %q", gotType, wantType)
+	}
+	if !reflect.DeepEqual(gotInfo, wantInfo) {
+		t.Errorf("got encryption parameters %+v, want %+v", gotInfo, wantInfo)
+	}
+}
+
+func TestSignKeysPEM(t *testing.T) {
+	masterKey, err := GenerateSignMasterKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemBytes, err := masterKey.MarshalPEM(rand.Reader, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	masterKey2, err := ParseSignMasterPrivateKeyPEM(pemBytes, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !masterKey.Equal(masterKey2) || !masterKey.PublicKey().Equal(masterKey2.PublicKey()) {
+		t.Errorf("not same")
+	}
+	if _, err = ParseEncryptMasterPrivateKeyPEM(pemBytes, testPassword); err == nil {
+		t.Errorf("expected error for signature master private key PEM")
+	}
+	if _, err = ParseSignMasterPrivateKeyPEM(pemBytes, []byte("wrong password")); err == nil {
+		t.Errorf("expected error for wrong password")
+	}
+	checkGMSSLEncryptedPEM(t, pemBytes, sm9SignMasterPrivateKeyFromGMSSL)
+
+	userKey, err := masterKey.GenerateUserKey([]byte("emmansun"), 0x01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemBytes, err = userKey.MarshalPEM(rand.Reader, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userKey2, err := ParseSignPrivateKeyPEM(pemBytes, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !userKey.Equal(userKey2) || !userKey2.MasterPublic().Equal(masterKey.PublicKey()) {
+		t.Errorf("not same")
+	}
+	if _, err = ParseSignMasterPrivateKeyPEM(pemBytes, testPassword); err == nil {
+		t.Errorf("expected error for signature private key PEM")
+	}
+	checkGMSSLEncryptedPEM(t, pemBytes, sm9SignPrivateKeyFromGMSSL)
+
+	hashed := []byte("Chinese IBS standard")
+	sig, err := userKey2.Sign(rand.Reader, hashed, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !masterKey.PublicKey().Verify([]byte("emmansun"), 0x01, hashed, sig) {
+		t.Errorf("verify failed")
+	}
+}
+
+func TestEncryptKeysPEM(t *testing.T) {
+	masterKey, err := GenerateEncryptMasterKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemBytes, err := masterKey.MarshalPEM(rand.Reader, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	masterKey2, err := ParseEncryptMasterPrivateKeyPEM(pemBytes, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !masterKey.Equal(masterKey2) || !masterKey.PublicKey().Equal(masterKey2.PublicKey()) {
+		t.Errorf("not same")
+	}
+	if _, err = ParseSignMasterPrivateKeyPEM(pemBytes, testPassword); err == nil {
+		t.Errorf("expected error for encryption master private key PEM")
+	}
+	checkGMSSLEncryptedPEM(t, pemBytes, sm9EncMasterPrivateKeyFromGMSSL)
+
+	userKey, err := masterKey.GenerateUserKey([]byte("emmansun"), 0x03)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemBytes, err = userKey.MarshalPEM(rand.Reader, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userKey2, err := ParseEncryptPrivateKeyPEM(pemBytes, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !userKey.Equal(userKey2) || !userKey2.MasterPublic().Equal(masterKey.PublicKey()) {
+		t.Errorf("not same")
+	}
+	if _, err = ParseEncryptMasterPrivateKeyPEM(pemBytes, testPassword); err == nil {
+		t.Errorf("expected error for encryption private key PEM")
+	}
+	checkGMSSLEncryptedPEM(t, pemBytes, sm9EncPrivateKeyFromGMSSL)
+
+	plaintext := []byte("Chinese IBE standard")
+	ciphertext, err := Encrypt(rand.Reader, masterKey.PublicKey(), []byte("emmansun"), 0x03, plaintext, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Decrypt(userKey2, []byte("emmansun"), ciphertext, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(plaintext) {
+		t.Errorf("decrypt failed")
+	}
+}
+
+func TestParseInvalidKeys(t *testing.T) {
+	if _, err := UnmarshalSignMasterPrivateKeyASN1(nil); err == nil {
+		t.Errorf("expected error for empty signature master private key")
+	}
+	if _, err := UnmarshalEncryptPrivateKeyASN1(nil); err == nil {
+		t.Errorf("expected error for empty encryption private key")
+	}
+	if _, err := UnmarshalSignMasterPublicKeyRaw(nil); err == nil {
+		t.Errorf("expected error for empty signature master public key")
+	}
+
+	// the points at infinity
+	if _, err := UnmarshalSignMasterPublicKeyRaw(append([]byte{4}, make([]byte, 128)...)); err == nil {
+		t.Errorf("expected error for the point at infinity in G2")
+	}
+	if _, err := UnmarshalEncryptMasterPublicKeyRaw(append([]byte{4}, make([]byte, 64)...)); err == nil {
+		t.Errorf("expected error for the point at infinity in G1")
+	}
+
+	// a point on the twist curve, but not in G2
+	var found bool
+	for i := byte(1); i != 0 && !found; i++ {
+		point := make([]byte, 65)
+		point[0] = 2
+		point[64] = i
+		_, err := UnmarshalSignMasterPublicKeyRaw(point)
+		if err != nil && err.Error() == "sm9: point is not in G2" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected a point on the twist curve rejected as not in G2")
+	}
+
+	// a master private key with another master public key
+	masterKey, err := GenerateSignMasterKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := GenerateSignMasterKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b cryptobyte.Builder
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1BigInt(new(big.Int).SetBytes(masterKey.Bytes()))
+		b.AddASN1BitString(otherKey.PublicKey().Bytes())
+	})
+	der, err := b.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = UnmarshalSignMasterPrivateKeyASN1(der); err == nil {
+		t.Errorf("expected error for mismatched master public key")
+	}
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/rand"
 	"encoding/hex"
+	"math/big"
 	"testing"
 
 	"github.com/yunmoon/gmsm/sm9"
@@ -34,6 +35,53 @@ func TestSignASN1(t *testing.T) {
 	}
 }
 
+// SM9 Appendix A
+func TestHashH1H2(t *testing.T) {
+	if h := hex.EncodeToString(sm9.H1([]byte("Alice"), 0x01)); h != "2acc468c3926b0bdb2767e99ff26e084de9ced8dbc7d5fbf418027b667862fab" {
+		t.Errorf("unexpected H1 %s", h)
+	}
+	w, _ := hex.DecodeString("81377B8FDBC2839B4FA2D0E0F8AA6853BBBE9E9C4099608F8612C6078ACD7563815AEBA217AD502DA0F48704CC73CABB3C06209BD87142E14CBD99E8BCA1680F30DADC5CD9E207AEE32209F6C3CA3EC0D800A1A42D33C73153DED47C70A39D2E8EAF5D179A1836B359A9D1D9BFC19F2EFCDB829328620962BD3FDF15F2567F58A543D25609AE943920679194ED30328BB33FD15660BDE485C6B79A7B32B013983F012DB04BA59FE88DB889321CC2373D4C0C35E84F7AB1FF33679BCA575D67654F8624EB435B838CCA77B2D0347E65D5E46964412A096F4150D8C5EDE5440DDF0656FCB663D24731E80292188A2471B8B68AA993899268499D23C89755A1A89744643CEAD40F0965F28E1CD2895C3D118E4F65C9A0E3E741B6DD52C0EE2D25F5898D60848026B7EFB8FCC1B2442ECF0795F8A81CEE99A6248F294C82C90D26BD6A814AAF475F128AEF43A128E37F80154AE6CB92CAD7D1501BAE30F750B3A9BD1F96B08E97997363911314705BFB9A9DBB97F75553EC90FBB2DDAE53C8F68E42")
+	if h := hex.EncodeToString(sm9.H2([]byte("Chinese IBS standard"), w)); h != "823c4b21e4bd2dfe1ed92c606653e996668563152fc33f55d7bfbb9bd9705adb" {
+		t.Errorf("unexpected H2 %s", h)
+	}
+}
+
+// SM9 Appendix A
+func TestVerifySM9Sample(t *testing.T) {
+	kb, _ := hex.DecodeString("000130E78459D78545CB54C587E02CF480CE0B66340F319F348A1D5B1F2DC5F4")
+	masterKey, err := sm9.NewSignMasterPrivateKey(kb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, _ := new(big.Int).SetString("823c4b21e4bd2dfe1ed92c606653e996668563152fc33f55d7bfbb9bd9705adb", 16)
+	s, _ := hex.DecodeString("0473bf96923ce58b6ad0e13e9643a406d8eb98417c50ef1b29cef9adb48b6d598c856712f1c2e0968ab7769f42a99586aed139d5b8b3e15891827cc2aced9baa05")
+	msg := []byte("Chinese IBS standard")
+	uid := []byte("Alice")
+	hid := byte(0x01)
+	if !sm9.Verify(masterKey.PublicKey(), uid, hid, msg, h, s) {
+		t.Errorf("Verify sample signature failed")
+	}
+	if sm9.Verify(masterKey.PublicKey(), uid, 0x02, msg, h, s) {
+		t.Errorf("Verify sample signature with wrong hid successed")
+	}
+	if sm9.Verify(masterKey.PublicKey(), []byte("Bob"), hid, msg, h, s) {
+		t.Errorf("Verify sample signature with wrong uid successed")
+	}
+
+	// sign and verify with the sample keys
+	userKey, err := masterKey.GenerateUserKey(uid, hid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, s, err = sm9.Sign(rand.Reader, userKey, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sm9.Verify(masterKey.PublicKey(), uid, hid, msg, h, s) {
+		t.Errorf("Verify failed")
+	}
+}
+
 func TestWrapKey(t *testing.T) {
 	masterKey, err := sm9.GenerateEncryptMasterKey(rand.Reader)
 	hid := byte(0x01)
@@ -60,6 +108,34 @@ func TestWrapKey(t *testing.T) {
 	}
 }
 
+// SM9 Appendix C
+func TestUnwrapKeySM9Sample(t *testing.T) {
+	kb, _ := hex.DecodeString("0001EDEE3778F441F8DEA3D9FA0ACC4E07EE36C93F9A08618AF4AD85CEDE1C22")
+	masterKey, err := sm9.NewEncryptMasterPrivateKey(kb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid := []byte("Bob")
+	hid := byte(0x03)
+	userKey, err := masterKey.GenerateUserKey(uid, hid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cipher, _ := hex.DecodeString("041edee2c3f465914491de44cefb2cb434ab02c308d9dc5e2067b4fed5aaac8a0f1c9b4c435eca35ab83bb734174c0f78fde81a53374aff3b3602bbc5e37be9a4c")
+	key, err := sm9.UnwrapKey(userKey, uid, cipher, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(key) != "4ff5cf86d2ad40c8f4bac98d76abdbde0c0e2f0a829d3f911ef5b2bce0695480" {
+		t.Errorf("unexpected key %x", key)
+	}
+	// C is not a valid G1 element
+	cipher[len(cipher)-1] ^= 1
+	if _, err = sm9.UnwrapKey(userKey, uid, cipher, 32); err == nil {
+		t.Errorf("expected error for invalid C")
+	}
+}
+
 func TestWrapKeyASN1(t *testing.T) {
 	masterKey, err := sm9.GenerateEncryptMasterKey(rand.Reader)
 	hid := byte(0x01)
@@ -141,6 +217,56 @@ func TestEncryptDecrypt(t *testing.T) {
 	}
 }
 
+func TestDecryptTampered(t *testing.T) {
+	plaintext := []byte("Chinese IBE standard")
+	masterKey, err := sm9.GenerateEncryptMasterKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hid := byte(0x01)
+	uid := []byte("emmansun")
+	userKey, err := masterKey.GenerateUserKey(uid, hid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encTypes := []sm9.EncrypterOpts{
+		sm9.DefaultEncrypterOpts, sm9.SM4ECBEncrypterOpts, sm9.SM4CBCEncrypterOpts, sm9.SM4CFBEncrypterOpts, sm9.SM4OFBEncrypterOpts,
+	}
+	for _, opts := range encTypes {
+		cipher, err := sm9.Encrypt(rand.Reader, masterKey.PublicKey(), uid, hid, plaintext, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// C1 || C3 || C2, flip one byte of each component
+		for _, i := range []int{10, 64 + 10, 64 + 32 + 1, len(cipher) - 1} {
+			tampered := bytes.Clone(cipher)
+			tampered[i] ^= 1
+			if _, err := sm9.Decrypt(userKey, uid, tampered, opts); err == nil {
+				t.Errorf("encType %v: expected error with byte %d flipped", opts.GetEncryptType(), i)
+			}
+		}
+		if _, err := sm9.Decrypt(userKey, uid, cipher[:64+32], opts); err == nil {
+			t.Errorf("encType %v: expected error with truncated ciphertext", opts.GetEncryptType())
+		}
+		if _, err := sm9.Decrypt(userKey, []byte("other"), cipher, opts); err == nil {
+			t.Errorf("encType %v: expected error with wrong uid", opts.GetEncryptType())
+		}
+
+		cipher, err = sm9.EncryptASN1(rand.Reader, masterKey.PublicKey(), uid, hid, plaintext, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// flip bytes at the end (C2), in the middle and near the start (C1)
+		for _, i := range []int{len(cipher) - 1, len(cipher) - len(plaintext) - 3, 20} {
+			tampered := bytes.Clone(cipher)
+			tampered[i] ^= 1
+			if _, err := sm9.DecryptASN1(userKey, uid, tampered); err == nil {
+				t.Errorf("encType %v: expected error with ASN.1 byte %d flipped", opts.GetEncryptType(), i)
+			}
+		}
+	}
+}
+
 func TestEncryptEmptyPlaintext(t *testing.T) {
 	masterKey, err := sm9.GenerateEncryptMasterKey(rand.Reader)
 	hid := byte(0x01)
@@ -45,6 +45,13 @@ type lazyCert struct {
 	// not specified in the certificate itself.
 	constraint func([]*Certificate) error
 
+	// sum is the sum224 of the certificate, unless unhashed is set.
+	sum sum224
+
+	// unhashed reports whether the certificate was added with AddCertFunc,
+	// its sum224 is not in CertPool.haveSum then.
+	unhashed bool
+
 	// getCert returns the certificate.
 	//
 	// It is not meant to do network operations or anything else
@@ -79,7 +86,9 @@ func (s *CertPool) cert(n int) (*Certificate, func([]*Certificate) error, error)
 	return cert, s.lazyCerts[n].constraint, err
 }
 
-// Clone returns a copy of s.
+// Clone returns a copy of s. The copy shares the certificates already added
+// to s, including lazily parsed ones, but later additions to either pool do
+// not affect the other.
 func (s *CertPool) Clone() *CertPool {
 	p := &CertPool{
 		byName:     make(map[string][]int, len(s.byName)),
@@ -104,7 +113,9 @@ func (s *CertPool) Clone() *CertPool {
 // On Unix systems other than macOS the environment variables SSL_CERT_FILE and
 // SSL_CERT_DIR can be used to override the system default locations for the SSL
 // certificate file and SSL certificate files directory, respectively. The
-// latter can be a colon-separated list.
+// latter can be a colon-separated list. The certificates of the file and
+// directories named by SMX509_CERT_FILE and SMX509_CERT_DIR, such as the SM2
+// root bundle, are added in addition to those. See also SystemCertPoolFromPaths.
 //
 // Any mutations to the returned pool are not written to disk and do not affect
 // any other pool returned by SystemCertPool.
@@ -165,11 +176,55 @@ func (s *CertPool) findPotentialParents(cert *Certificate) []potentialParent {
 	return candidates
 }
 
+// FindBySubject returns the certificates in s whose RawSubject is rawSubject,
+// such as the candidate issuers of a certificate with that RawIssuer. These are
+// the certificates Certificate.Verify considers as issuers. Only the matching
+// certificates are loaded, the ones which fail to load are skipped.
+func (s *CertPool) FindBySubject(rawSubject []byte) []*Certificate {
+	if s == nil {
+		return nil
+	}
+	var certs []*Certificate
+	for _, i := range s.byName[string(rawSubject)] {
+		if cert, _, err := s.cert(i); err == nil {
+			certs = append(certs, cert)
+		}
+	}
+	return certs
+}
+
+// FindBySubjectKeyId returns the certificates in s whose SubjectKeyId is
+// subjectKeyId. Unlike FindBySubject, every certificate of the pool is loaded,
+// the ones which fail to load are skipped.
+func (s *CertPool) FindBySubjectKeyId(subjectKeyId []byte) []*Certificate {
+	if len(subjectKeyId) == 0 {
+		return nil
+	}
+	var certs []*Certificate
+	for i := range s.len() {
+		if cert, _, err := s.cert(i); err == nil && bytes.Equal(cert.SubjectKeyId, subjectKeyId) {
+			certs = append(certs, cert)
+		}
+	}
+	return certs
+}
+
 func (s *CertPool) contains(cert *Certificate) bool {
 	if s == nil {
 		return false
 	}
-	return s.haveSum[sha256.Sum224(cert.Raw)]
+	if s.haveSum[sha256.Sum224(cert.Raw)] {
+		return true
+	}
+	for _, i := range s.byName[string(cert.RawSubject)] {
+		if !s.lazyCerts[i].unhashed {
+			continue
+		}
+		if c, _, err := s.cert(i); err == nil && bytes.Equal(c.Raw, cert.Raw) {
+			return true
+		}
+	}
+	return false
 }
 
 // AddCert adds a certificate to a pool.
@@ -182,6 +237,26 @@ func (s *CertPool) AddCert(cert *Certificate) {
 	}, nil)
 }
 
+// AddCertFunc adds a certificate to the pool which is loaded only when
+// needed. rawSubject is the DER encoded subject of the certificate, getCert
+// is called when a certificate with that subject is considered while building
+// a chain, and must return a certificate with the same RawSubject.
+//
+// getCert may be called concurrently from multiple goroutines, and more than
+// once, so it should cache its result. Unlike AddCert, a certificate added
+// twice is not detected.
+func (s *CertPool) AddCertFunc(rawSubject []byte, getCert func() (*Certificate, error)) {
+	if getCert == nil {
+		panic("getCert can't be nil")
+	}
+	s.lazyCerts = append(s.lazyCerts, lazyCert{
+		rawSubject: bytes.Clone(rawSubject),
+		unhashed:   true,
+		getCert:    getCert,
+	})
+	s.byName[string(rawSubject)] = append(s.byName[string(rawSubject)], len(s.lazyCerts)-1)
+}
+
 // addCertFunc adds metadata about a certificate to a pool, along with
 // a func to fetch that certificate later when needed.
 //
@@ -200,6 +275,7 @@ func (s *CertPool) addCertFunc(rawSum224 sum224, rawSubject string, getCert func
 	s.haveSum[rawSum224] = true
 	s.lazyCerts = append(s.lazyCerts, lazyCert{
 		rawSubject: []byte(rawSubject),
+		sum:        rawSum224,
 		getCert:    getCert,
 		constraint: constraint,
 	})
@@ -210,6 +286,11 @@ func (s *CertPool) addCertFunc(rawSum224 sum224, rawSubject string, getCert func
 // It appends any certificates found to s and reports whether any certificates
 // were successfully parsed.
 //
+// Only the structure and the subject of each certificate are parsed when it
+// is added. The rest is parsed once, the first time the certificate is
+// considered while building a chain. A certificate which then fails to parse,
+// for instance because of an invalid public key, is skipped.
+//
 // On many Linux systems, /etc/ssl/cert.pem will contain the system wide set
 // of root CAs in a format suitable for this function.
 func (s *CertPool) AppendCertsFromPEM(pemCerts []byte) (ok bool) {
@@ -224,21 +305,27 @@ func (s *CertPool) AppendCertsFromPEM(pemCerts []byte) (ok bool) {
 		}
 
 		certBytes := block.Bytes
-		cert, err := ParseCertificate(certBytes)
+		sum := sha256.Sum224(certBytes)
+		if s.haveSum[sum] {
+			// already in the pool, e.g. when the same bundle is appended twice
+			ok = true
+			continue
+		}
+		rawSubject, err := parseRawSubject(certBytes)
 		if err != nil {
 			continue
 		}
 		var lazyCert struct {
 			sync.Once
-			v *Certificate
+			v   *Certificate
+			err error
 		}
-		s.addCertFunc(sha256.Sum224(cert.Raw), string(cert.RawSubject), func() (*Certificate, error) {
+		s.addCertFunc(sum, string(rawSubject), func() (*Certificate, error) {
 			lazyCert.Do(func() {
-				// This can't fail, as the same bytes already parsed above.
-				lazyCert.v, _ = ParseCertificate(certBytes)
+				lazyCert.v, lazyCert.err = ParseCertificate(certBytes)
 				certBytes = nil
 			})
-			return lazyCert.v, nil
+			return lazyCert.v, lazyCert.err
 		}, nil)
 		ok = true
 	}
@@ -259,22 +346,112 @@ func (s *CertPool) Subjects() [][]byte {
 	return res
 }
 
-// Equal reports whether s and other are equal.
+// Len returns the number of certificates in the pool. A nil pool is empty.
+//
+// If s was returned by SystemCertPool on a platform which verifies with the
+// platform APIs, such as Windows, the system roots are not counted,
+// only the certificates added to the pool are.
+func (s *CertPool) Len() int {
+	return s.len()
+}
+
+// CertPoolEntry is a certificate of a CertPool, as returned by CertPool.Entries.
+type CertPoolEntry struct {
+	// RawSubject is the DER encoded subject of the certificate.
+	RawSubject []byte
+	// HasConstraint reports whether the certificate was added with
+	// AddCertWithConstraint.
+	HasConstraint bool
+
+	getCert func() (*Certificate, error)
+}
+
+// Certificate returns the certificate of the entry, parsing or loading it
+// if it was added lazily.
+func (e CertPoolEntry) Certificate() (*Certificate, error) {
+	return e.getCert()
+}
+
+// Entries returns the certificates in the pool, in the order they were added,
+// without parsing or loading them. The same as Len applies to pools
+// returned by SystemCertPool.
+func (s *CertPool) Entries() []CertPoolEntry {
+	if s == nil {
+		return nil
+	}
+	entries := make([]CertPoolEntry, s.len())
+	for i, lc := range s.lazyCerts {
+		entries[i] = CertPoolEntry{
+			RawSubject:    lc.rawSubject,
+			HasConstraint: lc.constraint != nil,
+			getCert:       lc.getCert,
+		}
+	}
+	return entries
+}
+
+// Certificates returns the certificates in the pool, in the order they were
+// added. Lazily added certificates are parsed or loaded, and the first error
+// doing so is returned. The same as Len applies to pools returned by
+// SystemCertPool.
+func (s *CertPool) Certificates() ([]*Certificate, error) {
+	certs := make([]*Certificate, 0, s.len())
+	for i := range s.len() {
+		cert, _, err := s.cert(i)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+// Equal reports whether s and other are equal, that is they hold the same
+// certificates, with constraints attached to the same certificates, and
+// both or neither derive from the system roots. Certificates added with
+// AddCertFunc are loaded to be compared, a pool with a certificate which fails
+// to load is equal to no other pool.
 func (s *CertPool) Equal(other *CertPool) bool {
 	if s == nil || other == nil {
 		return s == other
 	}
-	if s.systemPool != other.systemPool || len(s.haveSum) != len(other.haveSum) {
+	if s.systemPool != other.systemPool {
+		return false
+	}
+	sums, ok := s.sums()
+	if !ok {
 		return false
 	}
-	for h := range s.haveSum {
-		if !other.haveSum[h] {
+	otherSums, ok := other.sums()
+	if !ok || len(sums) != len(otherSums) {
+		return false
+	}
+	for h, hasConstraint := range sums {
+		if otherHasConstraint, ok := otherSums[h]; !ok || hasConstraint != otherHasConstraint {
 			return false
 		}
 	}
 	return true
 }
 
+// sums returns the sum224 of the certificates in s, mapped to whether a
+// constraint is attached to them. It reports false if a certificate fails to load.
+func (s *CertPool) sums() (map[sum224]bool, bool) {
+	sums := make(map[sum224]bool, s.len())
+	for i, lc := range s.lazyCerts {
+		sum := lc.sum
+		if lc.unhashed {
+			cert, _, err := s.cert(i)
+			if err != nil {
+				return nil, false
+			}
+			sum = sha256.Sum224(cert.Raw)
+		}
+		sums[sum] = sums[sum] || lc.constraint != nil
+	}
+	return sums, true
+}
+
 // AddCertWithConstraint adds a certificate to the pool with the additional
 // constraint. When Certificate.Verify builds a chain which is rooted by cert,
 // it will additionally pass the whole chain to constraint to determine its
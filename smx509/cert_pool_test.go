@@ -1,6 +1,17 @@
 package smx509
 
-import "testing"
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"runtime"
+	"testing"
+
+	"github.com/yunmoon/gmsm/sm2"
+)
 
 func TestCertPoolEqual(t *testing.T) {
 	tc := &Certificate{Raw: []byte{1, 2, 3}, RawSubject: []byte{2}}
@@ -102,3 +113,411 @@ func TestCertPoolEqual(t *testing.T) {
 		})
 	}
 }
+
+func TestCertPoolConstraintSM2(t *testing.T) {
+	newKey := func() *sm2.PrivateKey {
+		k, err := sm2.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+	rootKeyA, rootKeyB, interKey, leafKey := newKey(), newKey(), newKey(), newKey()
+	rootA := genCertEdge(t, "root A", rootKeyA, nil, rootCertificate, nil, nil)
+	rootB := genCertEdge(t, "root B", rootKeyB, nil, rootCertificate, nil, nil)
+	// the same intermediate cross signed by both roots
+	interA := genCertEdge(t, "inter", interKey, nil, intermediateCertificate, rootA, rootKeyA)
+	interB := genCertEdge(t, "inter", interKey, nil, intermediateCertificate, rootB, rootKeyB)
+	leaf := genCertEdge(t, "leaf", leafKey, nil, leafCertificate, interA, interKey)
+
+	intermediates := NewCertPool()
+	intermediates.AddCert(interA)
+	intermediates.AddCert(interB)
+
+	var seen [][]*Certificate
+	roots := NewCertPool()
+	roots.AddCertWithConstraint(rootA, func(chain []*Certificate) error {
+		seen = append(seen, chain)
+		return errors.New("root A is restricted")
+	})
+	roots.AddCert(rootB)
+
+	chains, err := leaf.Verify(VerifyOptions{Roots: roots, Intermediates: intermediates})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chains) != 1 || chains[0][len(chains[0])-1] != rootB {
+		t.Fatalf("unexpected chains %v", chainsToStrings(chains))
+	}
+	if len(seen) != 1 || len(seen[0]) != 2 || seen[0][0] != leaf || seen[0][1] != interA {
+		t.Errorf("constraint called with unexpected chains %v", chainsToStrings(seen))
+	}
+
+	// only the constrained root
+	roots = NewCertPool()
+	roots.AddCertWithConstraint(rootA, func([]*Certificate) error {
+		return errors.New("root A is restricted")
+	})
+	if _, err := leaf.Verify(VerifyOptions{Roots: roots, Intermediates: intermediates}); err == nil {
+		t.Error("expected error when the only root rejects the chain")
+	}
+}
+
+func TestCertPoolAddCertFunc(t *testing.T) {
+	rootKey, _ := sm2.GenerateKey(rand.Reader)
+	otherKey, _ := sm2.GenerateKey(rand.Reader)
+	leafKey, _ := sm2.GenerateKey(rand.Reader)
+	root := genCertEdge(t, "root", rootKey, nil, rootCertificate, nil, nil)
+	other := genCertEdge(t, "other root", otherKey, nil, rootCertificate, nil, nil)
+	leaf := genCertEdge(t, "leaf", leafKey, nil, leafCertificate, root, rootKey)
+
+	loaded := map[string]int{}
+	lazy := func(c *Certificate) func() (*Certificate, error) {
+		return func() (*Certificate, error) {
+			loaded[c.Subject.CommonName]++
+			return c, nil
+		}
+	}
+	roots := NewCertPool()
+	roots.AddCertFunc(root.RawSubject, lazy(root))
+	roots.AddCertFunc(other.RawSubject, lazy(other))
+	if len(loaded) != 0 {
+		t.Fatalf("certificates loaded when added: %v", loaded)
+	}
+	if len(roots.Subjects()) != 2 {
+		t.Errorf("unexpected number of subjects %d", len(roots.Subjects()))
+	}
+
+	chains, err := leaf.Verify(VerifyOptions{Roots: roots})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chains) != 1 || chains[0][1] != root {
+		t.Fatalf("unexpected chains %v", chainsToStrings(chains))
+	}
+	if loaded["root"] == 0 || loaded["other root"] != 0 {
+		t.Errorf("unexpected loaded certificates %v", loaded)
+	}
+
+	// a lazily added root verifies as a leaf
+	if _, err := other.Verify(VerifyOptions{Roots: roots}); err != nil {
+		t.Error(err)
+	}
+
+	// AppendCertsFromPEM only parses the subject up front
+	for _, data := range []string{compressedSM2CertPEM, rsaPSSSelfSignedPEM, ecdsaSHA256p256CertPem} {
+		pool := NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(data)) {
+			t.Fatal("AppendCertsFromPEM failed")
+		}
+		cert, err := ParseCertificatePEM([]byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(pool.Subjects()[0], cert.RawSubject) {
+			t.Errorf("unexpected subject %x", pool.Subjects()[0])
+		}
+		if !pool.contains(cert) {
+			t.Error("certificate not found in pool")
+		}
+	}
+
+	// certificates which don't parse are appended, then skipped when needed
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ecCert := genCertEdge(t, "ec root", ecKey, nil, rootCertificate, nil, nil)
+	ecdhKey, _ := ecKey.PublicKey.ECDH()
+	point := ecdhKey.Bytes()
+	i := bytes.Index(ecCert.Raw, point)
+	if i < 0 {
+		t.Fatal("public key not found in certificate")
+	}
+	corrupted := bytes.Clone(ecCert.Raw)
+	corrupted[i+len(point)-1] ^= 0xff
+	if _, err := ParseCertificate(corrupted); err == nil {
+		t.Fatal("corrupted certificate parsed")
+	}
+	pool := NewCertPool()
+	if !pool.AppendCertsFromPEM(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: corrupted})) {
+		t.Error("AppendCertsFromPEM failed")
+	}
+	if pool.Len() != 1 {
+		t.Errorf("unexpected number of certificates %d", pool.Len())
+	}
+	if certs := pool.FindBySubject(ecCert.RawSubject); len(certs) != 0 {
+		t.Errorf("invalid certificate loaded: %v", certs)
+	}
+	if _, err := pool.Certificates(); err == nil {
+		t.Error("expected error loading the invalid certificate")
+	}
+
+	// malformed certificates are not appended
+	for _, der := range [][]byte{ecCert.Raw[:len(ecCert.Raw)-1], append(bytes.Clone(ecCert.Raw), 0)} {
+		pool := NewCertPool()
+		if pool.AppendCertsFromPEM(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})) {
+			t.Error("AppendCertsFromPEM succeeded with a malformed certificate")
+		}
+		if pool.Len() != 0 {
+			t.Errorf("unexpected number of certificates %d", pool.Len())
+		}
+	}
+
+	// failures to load are skipped
+	roots = NewCertPool()
+	roots.AddCertFunc(root.RawSubject, func() (*Certificate, error) {
+		return nil, errors.New("removed from disk")
+	})
+	if _, err := leaf.Verify(VerifyOptions{Roots: roots}); err == nil {
+		t.Error("expected error when the root fails to load")
+	}
+}
+
+// benchmarkBundle returns the system roots as a PEM bundle, a real-world
+// trust store of the size loaded at startup.
+func benchmarkBundle(b *testing.B) []byte {
+	pool, err := SystemCertPool()
+	if err != nil {
+		b.Skipf("no system roots: %v", err)
+	}
+	certs, err := pool.Certificates()
+	if err != nil {
+		b.Fatal(err)
+	}
+	if len(certs) == 0 {
+		b.Skip("no system roots")
+	}
+	var bundle []byte
+	for _, cert := range certs {
+		bundle = append(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+	}
+	return bundle
+}
+
+// heapInUse returns the live heap size after a garbage collection.
+func heapInUse() uint64 {
+	var m runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&m)
+	return m.HeapAlloc
+}
+
+func BenchmarkCertPoolAppendCertsFromPEM(b *testing.B) {
+	bundle := benchmarkBundle(b)
+	eager := func() *CertPool {
+		pool := NewCertPool()
+		for rest := bundle; ; {
+			var block *pem.Block
+			if block, rest = pem.Decode(rest); block == nil {
+				break
+			}
+			if cert, err := ParseCertificate(block.Bytes); err == nil {
+				pool.AddCert(cert)
+			}
+		}
+		return pool
+	}
+	lazy := func() *CertPool {
+		pool := NewCertPool()
+		pool.AppendCertsFromPEM(bundle)
+		return pool
+	}
+	for _, bb := range []struct {
+		name string
+		load func() *CertPool
+	}{{"eager", eager}, {"lazy", lazy}} {
+		b.Run(bb.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				bb.load()
+			}
+			// the heap retained by one pool
+			before := heapInUse()
+			pool := bb.load()
+			after := heapInUse()
+			runtime.KeepAlive(pool)
+			// the heap can shrink between the readings, don't let the difference underflow
+			b.ReportMetric(float64(after)-float64(before), "heap-B/pool")
+		})
+	}
+	b.Run("twice", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			pool := lazy()
+			pool.AppendCertsFromPEM(bundle)
+		}
+	})
+}
+
+func TestCertPoolEntries(t *testing.T) {
+	key, _ := sm2.GenerateKey(rand.Reader)
+	root := genCertEdge(t, "root", key, nil, rootCertificate, nil, nil)
+	constrained := genCertEdge(t, "constrained", key, nil, rootCertificate, nil, nil)
+	lazy := genCertEdge(t, "lazy", key, nil, rootCertificate, nil, nil)
+
+	var nilPool *CertPool
+	if nilPool.Len() != 0 || len(nilPool.Entries()) != 0 {
+		t.Error("nil pool is not empty")
+	}
+
+	pool := NewCertPool()
+	pool.AddCert(root)
+	pool.AddCertWithConstraint(constrained, func([]*Certificate) error { return nil })
+	pool.AppendCertsFromPEM([]byte(compressedSM2CertPEM))
+	loaded := 0
+	pool.AddCertFunc(lazy.RawSubject, func() (*Certificate, error) {
+		loaded++
+		return lazy, nil
+	})
+	if pool.Len() != 4 {
+		t.Fatalf("unexpected length %d", pool.Len())
+	}
+
+	entries := pool.Entries()
+	if loaded != 0 {
+		t.Error("Entries loaded a lazy certificate")
+	}
+	for i, want := range []bool{false, true, false, false} {
+		if entries[i].HasConstraint != want {
+			t.Errorf("entry %d: HasConstraint = %v", i, entries[i].HasConstraint)
+		}
+	}
+	cert, err := entries[3].Certificate()
+	if err != nil || cert != lazy || loaded != 1 {
+		t.Errorf("unexpected lazy certificate %v, %v", cert, err)
+	}
+
+	certs, err := pool.Certificates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []string{"root", "constrained", "compressed sm2", "lazy"} {
+		if certs[i].Subject.CommonName != want || !bytes.Equal(certs[i].RawSubject, entries[i].RawSubject) {
+			t.Errorf("certificate %d: got %v, want %v", i, certs[i].Subject.CommonName, want)
+		}
+	}
+
+	pool.AddCertFunc([]byte("subject"), func() (*Certificate, error) {
+		return nil, errors.New("removed from disk")
+	})
+	if _, err := pool.Certificates(); err == nil {
+		t.Error("expected error when a certificate fails to load")
+	}
+
+	// the pool returned by SystemCertPool only lists certificates it holds
+	sys, err := SystemCertPool()
+	if err != nil {
+		t.Skip(err)
+	}
+	n := sys.Len()
+	if len(sys.Entries()) != n {
+		t.Errorf("Entries returned %d certificates, Len %d", len(sys.Entries()), n)
+	}
+	sys.AddCert(root)
+	if sys.Len() != n+1 {
+		t.Errorf("unexpected length %d after adding a certificate", sys.Len())
+	}
+}
+
+func TestCertPoolClone(t *testing.T) {
+	rootKey, _ := sm2.GenerateKey(rand.Reader)
+	interKey, _ := sm2.GenerateKey(rand.Reader)
+	leafKey, _ := sm2.GenerateKey(rand.Reader)
+	root := genCertEdge(t, "root", rootKey, nil, rootCertificate, nil, nil)
+	inter := genCertEdge(t, "inter", interKey, nil, intermediateCertificate, root, rootKey)
+	leaf := genCertEdge(t, "leaf", leafKey, nil, leafCertificate, inter, interKey)
+
+	base := NewCertPool()
+	base.AppendCertsFromPEM(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: root.Raw}))
+	tenant := base.Clone()
+	if !tenant.Equal(base) {
+		t.Fatal("clone is not equal to the original pool")
+	}
+	tenant.AddCert(inter)
+	if base.Len() != 1 || tenant.Len() != 2 {
+		t.Fatalf("unexpected lengths %d, %d", base.Len(), tenant.Len())
+	}
+	if base.Equal(tenant) {
+		t.Error("pools are equal after mutating the clone")
+	}
+	if _, err := leaf.Verify(VerifyOptions{Roots: tenant}); err != nil {
+		t.Errorf("failed to verify with the cloned pool: %v", err)
+	}
+	if _, err := leaf.Verify(VerifyOptions{Roots: base}); err == nil {
+		t.Error("original pool holds the intermediate added to the clone")
+	}
+
+	// constraints and lazily added certificates
+	constrained := NewCertPool()
+	constrained.AddCertWithConstraint(root, func([]*Certificate) error { return nil })
+	if constrained.Equal(base) {
+		t.Error("pools are equal with and without constraint")
+	}
+	if !constrained.Clone().Equal(constrained) {
+		t.Error("clone with constraint is not equal to the original pool")
+	}
+	lazy := NewCertPool()
+	lazy.AddCertFunc(root.RawSubject, func() (*Certificate, error) { return root, nil })
+	if !lazy.Equal(base) || !base.Equal(lazy) {
+		t.Error("lazily added certificate is not equal to the parsed one")
+	}
+	lazy.AddCertFunc([]byte("subject"), func() (*Certificate, error) { return nil, errors.New("removed from disk") })
+	if lazy.Equal(lazy.Clone()) {
+		t.Error("pool with a certificate failing to load equals another pool")
+	}
+
+	sys, err := SystemCertPool()
+	if err != nil {
+		t.Skip(err)
+	}
+	clone := sys.Clone()
+	if clone.systemPool != sys.systemPool || !clone.Equal(sys) {
+		t.Error("clone of the system pool is not equal to the original pool")
+	}
+}
+
+func TestCertPoolFindBySubject(t *testing.T) {
+	keyA, _ := sm2.GenerateKey(rand.Reader)
+	keyB, _ := sm2.GenerateKey(rand.Reader)
+	leafKey, _ := sm2.GenerateKey(rand.Reader)
+	// two CAs sharing a subject with different keys
+	caA := genCertEdge(t, "CA", keyA, nil, rootCertificate, nil, nil)
+	caB := genCertEdge(t, "CA", keyB, nil, rootCertificate, nil, nil)
+	other := genCertEdge(t, "other CA", keyA, nil, rootCertificate, nil, nil)
+	leaf := genCertEdge(t, "leaf", leafKey, nil, leafCertificate, caB, keyB)
+
+	pool := NewCertPool()
+	pool.AddCert(caA)
+	pool.AddCertFunc(caB.RawSubject, func() (*Certificate, error) { return caB, nil })
+	pool.AddCert(other)
+
+	found := pool.FindBySubject(leaf.RawIssuer)
+	if len(found) != 2 || found[0] != caA || found[1] != caB {
+		t.Fatalf("unexpected certificates %v", chainsToStrings([][]*Certificate{found}))
+	}
+	// the issuer Verify picks is among them
+	chains, err := leaf.Verify(VerifyOptions{Roots: pool})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issuer := chains[0][1]; issuer != found[1] {
+		t.Errorf("Verify chose %v", issuer.Subject)
+	}
+	if found := pool.FindBySubject(leaf.RawSubject); len(found) != 0 {
+		t.Errorf("unexpected certificates for the leaf subject")
+	}
+
+	found = pool.FindBySubjectKeyId(caB.SubjectKeyId)
+	if len(found) != 1 || found[0] != caB {
+		t.Errorf("unexpected certificates by SubjectKeyId")
+	}
+	// caA and other share a key, hence a SubjectKeyId
+	if found := pool.FindBySubjectKeyId(caA.SubjectKeyId); len(found) != 2 {
+		t.Errorf("expected 2 certificates, got %d", len(found))
+	}
+	if found := pool.FindBySubjectKeyId(nil); len(found) != 0 {
+		t.Error("certificates found with empty SubjectKeyId")
+	}
+	var nilPool *CertPool
+	if nilPool.FindBySubject(leaf.RawIssuer) != nil || nilPool.FindBySubjectKeyId(caA.SubjectKeyId) != nil {
+		t.Error("certificates found in nil pool")
+	}
+}
@@ -64,9 +64,10 @@ func CreateCFCACertificateRequest(rand io.Reader, template *x509.CertificateRequ
 	}
 
 	var rawAttributes []asn1.RawValue
-	// Add the temporary public key and challenge password if requested.
+	// Add the challenge password and temporary public key if requested,
+	// in the same order as CFCA SADK does.
 	if tmpPub != nil {
-		rawAttributes, err = buildTmpPublicKeyAttr(key, rawAttributes, tmpPub)
+		tmpPubAttrs, err := buildTmpPublicKeyAttr(key, nil, tmpPub)
 		if err != nil {
 			return nil, err
 		}
@@ -75,6 +76,7 @@ func CreateCFCACertificateRequest(rand io.Reader, template *x509.CertificateRequ
 		if err != nil {
 			return nil, err
 		}
+		rawAttributes = append(rawAttributes, tmpPubAttrs...)
 	}
 
 	asn1Subject := template.RawSubject
@@ -242,26 +244,34 @@ func parseCFCAAttributes(out *CertificateRequestCFCA, rawAttributes []asn1.RawVa
 		case value.Type.Equal(oidChallengePassword):
 			asn1.Unmarshal(value.Value.FullBytes, &out.ChallengePassword)
 		case value.Type.Equal(oidTmpPublicKey):
-			var tmpPub tmpPublicKeyInfo
-			if _, err := asn1.Unmarshal(value.Value.Bytes, &tmpPub); err != nil {
-				continue
-			}
-			keyBytes := tmpPub.PublicKey
-			switch out.PublicKeyAlgorithm {
-			case RSA:
-				if tmpKey, err := x509.ParsePKCS1PublicKey(keyBytes); err == nil {
-					out.TmpPublicKey = tmpKey
-				}
-			case ECDSA:
-				if len(keyBytes) == 136 && bytes.Equal(tmpPublicKeyPrefix, keyBytes[:8]) {
-					// parse the public key
-					copy(keyBytes[40:72], keyBytes[72:104])
-					keyBytes[7] = 4
-					if tmpKey, err := sm2.NewPublicKey(keyBytes[7:72]); err == nil {
-						out.TmpPublicKey = tmpKey
-					}
-				}
+			if tmpKey, err := parseTmpPublicKey(value.Value.Bytes, out.PublicKeyAlgorithm); err == nil {
+				out.TmpPublicKey = tmpKey
 			}
 		}
 	}
 }
+
+// parseTmpPublicKey parses the tmpPublicKeyInfo of a tmpPublicKey attribute
+// of a request with a key of algo.
+func parseTmpPublicKey(der []byte, algo PublicKeyAlgorithm) (any, error) {
+	var tmpPub tmpPublicKeyInfo
+	if rest, err := asn1.Unmarshal(der, &tmpPub); err != nil || len(rest) != 0 {
+		return nil, errors.New("x509: malformed temporary public key")
+	}
+	keyBytes := tmpPub.PublicKey
+	switch algo {
+	case RSA:
+		return x509.ParsePKCS1PublicKey(keyBytes)
+	case ECDSA, SM2:
+		// Prefix{8} || X{32} || zero{32} || Y{32} || zero{32}
+		if len(keyBytes) != 136 || !bytes.Equal(tmpPublicKeyPrefix, keyBytes[:8]) {
+			return nil, errors.New("x509: malformed SM2 temporary public key")
+		}
+		point := make([]byte, 65)
+		point[0] = 4
+		copy(point[1:33], keyBytes[8:40])
+		copy(point[33:], keyBytes[72:104])
+		return sm2.NewPublicKey(point)
+	}
+	return nil, errors.New("x509: only RSA or SM2 temporary public key is supported")
+}
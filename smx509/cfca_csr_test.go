@@ -5,12 +5,14 @@
 package smx509
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/asn1"
 	"encoding/pem"
 	"testing"
 
@@ -261,3 +263,38 @@ LFKfGhdBvkaU/yFYn3bGjgpc0m+Wecl5XWSTOK1zj3jf0ZVr9e8lsTcvLI7Clq9T
 zwV8qP5llIORug==
 -----END CERTIFICATE REQUEST-----
 `
+
+func TestCFCAAttributesMatchSADK(t *testing.T) {
+	block, _ := pem.Decode([]byte(sadkGeneratedCSR))
+	var raw certificateRequest
+	if _, err := asn1.Unmarshal(block.Bytes, &raw); err != nil {
+		t.Fatal(err)
+	}
+	csr, err := ParseCFCACertificateRequest(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certKey, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := CreateCFCACertificateRequest(rand.Reader, &x509.CertificateRequest{RawSubject: raw.TBSCSR.Subject.FullBytes}, certKey, csr.TmpPublicKey, csr.ChallengePassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got certificateRequest
+	if _, err := asn1.Unmarshal(der, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.TBSCSR.RawAttributes) != len(raw.TBSCSR.RawAttributes) {
+		t.Fatalf("got %d attributes, want %d", len(got.TBSCSR.RawAttributes), len(raw.TBSCSR.RawAttributes))
+	}
+	for i, attr := range got.TBSCSR.RawAttributes {
+		if !bytes.Equal(attr.FullBytes, raw.TBSCSR.RawAttributes[i].FullBytes) {
+			t.Errorf("attribute %d mismatch\ngot  %x\nwant %x", i, attr.FullBytes, raw.TBSCSR.RawAttributes[i].FullBytes)
+		}
+	}
+	if !bytes.Equal(got.TBSCSR.Subject.FullBytes, raw.TBSCSR.Subject.FullBytes) {
+		t.Errorf("subject mismatch")
+	}
+}
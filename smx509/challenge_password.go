@@ -0,0 +1,122 @@
+package smx509
+
+import (
+	"encoding/asn1"
+	"errors"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/cryptobyte"
+	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+// maxChallengePasswordLength is ub-challenge-password of PKCS #9, RFC 2985,
+// 5.4.1.
+const maxChallengePasswordLength = 255
+
+// ChallengePassword returns the challengePassword attribute of c, RFC 2985,
+// 5.4.1, or an empty string if there is none.
+//
+// The value is a DirectoryString, usually a PrintableString or a
+// UTF8String. The value of CFCA requests, which is not in a SET, is accepted
+// too.
+func (c *CertificateRequest) ChallengePassword() (string, error) {
+	attributes, err := parseCSRAttributes(c.RawTBSCertificateRequest)
+	if err != nil {
+		return "", err
+	}
+
+	password, found := "", false
+	for _, attr := range attributes {
+		if !attr.Type.Equal(oidChallengePassword) {
+			continue
+		}
+		if found {
+			return "", errors.New("x509: certificate request contains duplicate challenge passwords")
+		}
+		found = true
+
+		values := attr.Values
+		var value cryptobyte.String
+		var tag cryptobyte_asn1.Tag
+		if values.PeekASN1Tag(cryptobyte_asn1.SET) {
+			var set cryptobyte.String
+			if !values.ReadASN1(&set, cryptobyte_asn1.SET) || !set.ReadAnyASN1(&value, &tag) || !set.Empty() {
+				return "", errors.New("x509: challenge password must have a single value")
+			}
+		} else if !values.ReadAnyASN1(&value, &tag) {
+			return "", errors.New("x509: malformed challenge password")
+		}
+		if !values.Empty() {
+			return "", errors.New("x509: malformed challenge password")
+		}
+		if password, err = parseASN1String(tag, value); err != nil {
+			return "", errors.New("x509: invalid challenge password: " + err.Error())
+		}
+	}
+	return password, nil
+}
+
+// csrAttribute is an attribute of a certificate request, with its full
+// encoding Raw. Values is the encoding after the type, a SET of values, or a
+// single value in CFCA requests.
+type csrAttribute struct {
+	Raw    cryptobyte.String
+	Type   asn1.ObjectIdentifier
+	Values cryptobyte.String
+}
+
+// parseCSRAttributes returns the attributes of the CertificationRequestInfo
+// rawTBS.
+func parseCSRAttributes(rawTBS []byte) ([]csrAttribute, error) {
+	input := cryptobyte.String(rawTBS)
+	var attributes cryptobyte.String
+	var hasAttributes bool
+	if !input.ReadASN1(&input, cryptobyte_asn1.SEQUENCE) ||
+		!input.SkipASN1(cryptobyte_asn1.INTEGER) ||
+		!input.SkipASN1(cryptobyte_asn1.SEQUENCE) ||
+		!input.SkipASN1(cryptobyte_asn1.SEQUENCE) ||
+		!input.ReadOptionalASN1(&attributes, &hasAttributes, cryptobyte_asn1.Tag(0).Constructed().ContextSpecific()) {
+		return nil, errors.New("x509: malformed certificate request")
+	}
+
+	var ret []csrAttribute
+	for !attributes.Empty() {
+		var attr csrAttribute
+		if !attributes.ReadASN1Element(&attr.Raw, cryptobyte_asn1.SEQUENCE) {
+			return nil, errors.New("x509: malformed certificate request attribute")
+		}
+		attr.Values = attr.Raw
+		if !attr.Values.ReadASN1(&attr.Values, cryptobyte_asn1.SEQUENCE) || !attr.Values.ReadASN1ObjectIdentifier(&attr.Type) {
+			return nil, errors.New("x509: malformed certificate request attribute")
+		}
+		ret = append(ret, attr)
+	}
+	return ret, nil
+}
+
+// marshalChallengePassword returns the challengePassword attribute of
+// password, with a PrintableString value, or a UTF8String one if password
+// has other characters.
+func marshalChallengePassword(password string) (asn1.RawValue, error) {
+	if !utf8.ValidString(password) {
+		return asn1.RawValue{}, errors.New("x509: challenge password is not valid UTF-8")
+	}
+	if utf8.RuneCountInString(password) > maxChallengePasswordLength {
+		return asn1.RawValue{}, errors.New("x509: challenge password is too long")
+	}
+	b, err := asn1.Marshal(struct {
+		Type   asn1.ObjectIdentifier
+		Values []string `asn1:"set"`
+	}{
+		Type:   oidChallengePassword,
+		Values: []string{password},
+	})
+	if err != nil {
+		return asn1.RawValue{}, err
+	}
+	var rawValue asn1.RawValue
+	if _, err := asn1.Unmarshal(b, &rawValue); err != nil {
+		return asn1.RawValue{}, err
+	}
+	return rawValue, nil
+}
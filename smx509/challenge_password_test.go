@@ -0,0 +1,147 @@
+package smx509
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/yunmoon/gmsm/sm2"
+)
+
+// Generated with:
+//
+//	openssl req -new -key sm2.key -sm3 -sigopt distid:1234567812345678 -config req.cnf
+//
+// with challengePassword = Secret-OTP-1234 in the req_attributes section, a
+// subjectAltName and keyUsage in req_extensions, and string_mask set to
+// utf8only and nombstr respectively.
+const (
+	opensslUTF8ChallengeCSRPEM = `-----BEGIN CERTIFICATE REQUEST-----
+MIIBXTCCAQMCAQAwQTELMAkGA1UEBhMCQ04xFTATBgNVBAoMDEdNIFNDRVAgVGVz
+dDEbMBkGA1UEAwwSZGV2aWNlLmV4YW1wbGUuY29tMFkwEwYHKoZIzj0CAQYIKoEc
+z1UBgi0DQgAEM/dzPRQXiunbWnehu0eq3Vd1LKDB6R8mDoHMKfWCz2BO6wTOHBNl
+7kEHjICWhhzO2l6Bj1P6I9rGLmdoaKpA6KBgMB4GCSqGSIb3DQEJBzERDA9TZWNy
+ZXQtT1RQLTEyMzQwPgYJKoZIhvcNAQkOMTEwLzAdBgNVHREEFjAUghJkZXZpY2Uu
+ZXhhbXBsZS5jb20wDgYDVR0PAQH/BAQDAgeAMAoGCCqBHM9VAYN1A0gAMEUCIDEJ
+Y8vTSIWEKFSv69VNZ5wx14acHWfSBAx0mcdOu9v3AiEAvOIx8K6T5PjN0eyivVhK
+so/lj6qZ0OL3zUur6zmX5aY=
+-----END CERTIFICATE REQUEST-----`
+	opensslPrintableChallengeCSRPEM = `-----BEGIN CERTIFICATE REQUEST-----
+MIIBXjCCAQMCAQAwQTELMAkGA1UEBhMCQ04xFTATBgNVBAoTDEdNIFNDRVAgVGVz
+dDEbMBkGA1UEAxMSZGV2aWNlLmV4YW1wbGUuY29tMFkwEwYHKoZIzj0CAQYIKoEc
+z1UBgi0DQgAEM/dzPRQXiunbWnehu0eq3Vd1LKDB6R8mDoHMKfWCz2BO6wTOHBNl
+7kEHjICWhhzO2l6Bj1P6I9rGLmdoaKpA6KBgMB4GCSqGSIb3DQEJBzEREw9TZWNy
+ZXQtT1RQLTEyMzQwPgYJKoZIhvcNAQkOMTEwLzAdBgNVHREEFjAUghJkZXZpY2Uu
+ZXhhbXBsZS5jb20wDgYDVR0PAQH/BAQDAgeAMAoGCCqBHM9VAYN1A0kAMEYCIQCO
+6Bhfyt18amuW6UZWvWJ+xqaNCAwN2QgXH5rfv8E5KwIhAMgzMS4a1CAUdib9eRyR
+bA+0i/9C8zK8F/Dnskp6ZemI
+-----END CERTIFICATE REQUEST-----`
+)
+
+func TestParseOpenSSLChallengePassword(t *testing.T) {
+	for _, data := range []string{opensslUTF8ChallengeCSRPEM, opensslPrintableChallengeCSRPEM} {
+		block, _ := pem.Decode([]byte(data))
+		csr, err := ParseCertificateRequest(block.Bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := csr.CheckSignature(); err != nil {
+			t.Fatal(err)
+		}
+		if password, err := csr.ChallengePassword(); err != nil || password != "Secret-OTP-1234" {
+			t.Errorf("unexpected challenge password %q: %v", password, err)
+		}
+		if len(csr.DNSNames) != 1 || csr.DNSNames[0] != "device.example.com" || len(csr.Extensions) != 2 {
+			t.Errorf("unexpected requested extensions %v", csr.Extensions)
+		}
+	}
+}
+
+func TestChallengePasswordRoundTrip(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	basicConstraints, err := asn1.Marshal(struct{ IsCA bool }{true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: "device.example.com"},
+		DNSNames: []string{"device.example.com"},
+		// the SAN extension is merged in the requested extensions
+		Attributes: []pkix.AttributeTypeAndValueSET{{
+			Type:  oidExtensionRequest,
+			Value: [][]pkix.AttributeTypeAndValue{{{Type: oidExtensionBasicConstraints, Value: basicConstraints}}},
+		}},
+	}
+
+	for _, tc := range []struct {
+		password string
+		tag      byte
+	}{
+		{"Secret-OTP-1234", asn1.TagPrintableString},
+		{"device@example.com", asn1.TagUTF8String},
+		{"一次性口令", asn1.TagUTF8String},
+	} {
+		der, err := CreateCertificateRequestWithOptions(rand.Reader, template, priv, &CreateCertificateRequestOptions{ChallengePassword: tc.password})
+		if err != nil {
+			t.Fatal(err)
+		}
+		csr, err := ParseCertificateRequest(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := csr.CheckSignature(); err != nil {
+			t.Fatal(err)
+		}
+		if password, err := csr.ChallengePassword(); err != nil || password != tc.password {
+			t.Errorf("unexpected challenge password %q: %v", password, err)
+		}
+		value := append([]byte{tc.tag, byte(len(tc.password))}, tc.password...)
+		if !bytes.Contains(csr.RawTBSCertificateRequest, value) {
+			t.Errorf("%q is not encoded with tag %d", tc.password, tc.tag)
+		}
+		if len(csr.DNSNames) != 1 || len(csr.Extensions) != 2 {
+			t.Errorf("unexpected requested extensions %v", csr.Extensions)
+		}
+	}
+
+	der, err := CreateCertificateRequestWithOptions(rand.Reader, template, priv, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr, err := ParseCertificateRequest(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if password, err := csr.ChallengePassword(); err != nil || password != "" {
+		t.Errorf("unexpected challenge password %q: %v", password, err)
+	}
+
+	if _, err := CreateCertificateRequestWithOptions(rand.Reader, template, priv, &CreateCertificateRequestOptions{ChallengePassword: strings.Repeat("x", 256)}); err == nil {
+		t.Error("expected error for a too long challenge password")
+	}
+
+	// CFCA requests have the value outside of a SET
+	tmpKey, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err = CreateCFCACertificateRequest(rand.Reader, template, priv, tmpKey.Public(), "111111")
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr, err = ParseCertificateRequest(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if password, err := csr.ChallengePassword(); err != nil || password != "111111" {
+		t.Errorf("unexpected CFCA challenge password %q: %v", password, err)
+	}
+}
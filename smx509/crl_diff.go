@@ -0,0 +1,88 @@
+package smx509
+
+import (
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"math/big"
+	"time"
+)
+
+// CRLDelta is the difference between two CRLs of the same issuer, see
+// DiffRevocationLists.
+type CRLDelta struct {
+	// Added are the entries of the new CRL whose serial numbers are not in
+	// the old one, and Removed the entries of the old CRL whose serial
+	// numbers are not in the new one, such as expired certificates.
+	Added   []x509.RevocationListEntry
+	Removed []x509.RevocationListEntry
+
+	// ReasonChanged are the entries of the new CRL whose reason code differs
+	// from the old one, such as a certificate hold which became a key
+	// compromise.
+	ReasonChanged []x509.RevocationListEntry
+
+	OldNumber, NewNumber         *big.Int
+	OldThisUpdate, NewThisUpdate time.Time
+	OldNextUpdate, NewNextUpdate time.Time
+
+	// NumberIncreased is set if both CRLs have a CRL number, and the new one
+	// is greater.
+	NumberIncreased bool
+}
+
+// DiffRevocationLists returns the entries added to, removed from and changed
+// in newList since oldList, and their numbers and validity. The CRLs must
+// have the same issuer, and the number of newList must not be less than the
+// one of oldList. Serial numbers are compared by value.
+func DiffRevocationLists(oldList, newList *RevocationList) (*CRLDelta, error) {
+	if oldList == nil || newList == nil {
+		return nil, errors.New("x509: CRLs can not be nil")
+	}
+	if !bytes.Equal(oldList.RawIssuer, newList.RawIssuer) {
+		return nil, errors.New("x509: CRLs have different issuers")
+	}
+	if len(oldList.AuthorityKeyId) > 0 && len(newList.AuthorityKeyId) > 0 && !bytes.Equal(oldList.AuthorityKeyId, newList.AuthorityKeyId) {
+		return nil, errors.New("x509: CRLs have different authority key identifiers")
+	}
+	delta := &CRLDelta{
+		OldNumber:     oldList.Number,
+		NewNumber:     newList.Number,
+		OldThisUpdate: oldList.ThisUpdate,
+		NewThisUpdate: newList.ThisUpdate,
+		OldNextUpdate: oldList.NextUpdate,
+		NewNextUpdate: newList.NextUpdate,
+	}
+	if oldList.Number != nil && newList.Number != nil {
+		switch newList.Number.Cmp(oldList.Number) {
+		case -1:
+			return nil, errors.New("x509: CRL number went backwards")
+		case 1:
+			delta.NumberIncreased = true
+		}
+	}
+
+	oldIndex := oldList.BuildIndex()
+	newIndex := newList.BuildIndex()
+	for _, rce := range newList.RevokedCertificateEntries {
+		if rce.SerialNumber == nil {
+			continue
+		}
+		old, ok := oldIndex.FindRevokedCertificate(rce.SerialNumber)
+		switch {
+		case !ok:
+			delta.Added = append(delta.Added, rce)
+		case old.ReasonCode != rce.ReasonCode:
+			delta.ReasonChanged = append(delta.ReasonChanged, rce)
+		}
+	}
+	for _, rce := range oldList.RevokedCertificateEntries {
+		if rce.SerialNumber == nil {
+			continue
+		}
+		if _, ok := newIndex.FindRevokedCertificate(rce.SerialNumber); !ok {
+			delta.Removed = append(delta.Removed, rce)
+		}
+	}
+	return delta, nil
+}
@@ -0,0 +1,100 @@
+package smx509
+
+import (
+	"crypto/x509"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestDiffRevocationLists(t *testing.T) {
+	issuer := []byte{0x30, 0x0f, 0x31, 0x0d, 0x30, 0x0b, 0x06, 0x03, 0x55, 0x04, 0x03, 0x13, 0x04, 0x47, 0x4d, 0x43, 0x41}
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	entry := func(serial *big.Int, reason int) x509.RevocationListEntry {
+		return x509.RevocationListEntry{SerialNumber: serial, RevocationTime: now, ReasonCode: reason}
+	}
+	oldList := &RevocationList{
+		RawIssuer:  issuer,
+		Number:     big.NewInt(7),
+		ThisUpdate: now,
+		NextUpdate: now.Add(24 * time.Hour),
+		RevokedCertificateEntries: []x509.RevocationListEntry{
+			entry(big.NewInt(1), 1),
+			entry(big.NewInt(2), 6),
+			entry(big.NewInt(3), 4),
+		},
+	}
+	newList := &RevocationList{
+		RawIssuer:  issuer,
+		Number:     big.NewInt(8),
+		ThisUpdate: now.Add(24 * time.Hour),
+		NextUpdate: now.Add(48 * time.Hour),
+		RevokedCertificateEntries: []x509.RevocationListEntry{
+			// the same serial number, parsed from a non-minimal encoding
+			entry(new(big.Int).SetBytes([]byte{0, 0, 1}), 1),
+			// the certificate on hold is now revoked
+			entry(big.NewInt(2), 1),
+			// serial 3 expired and dropped off
+			entry(big.NewInt(4), 5),
+			entry(big.NewInt(5), 0),
+		},
+	}
+
+	delta, err := DiffRevocationLists(oldList, newList)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serials := func(entries []x509.RevocationListEntry) []int64 {
+		var ret []int64
+		for _, rce := range entries {
+			ret = append(ret, rce.SerialNumber.Int64())
+		}
+		return ret
+	}
+	for _, tt := range []struct {
+		name      string
+		got, want []int64
+	}{
+		{"added", serials(delta.Added), []int64{4, 5}},
+		{"removed", serials(delta.Removed), []int64{3}},
+		{"reason changed", serials(delta.ReasonChanged), []int64{2}},
+	} {
+		if len(tt.got) != len(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+			continue
+		}
+		for i := range tt.got {
+			if tt.got[i] != tt.want[i] {
+				t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+			}
+		}
+	}
+	if delta.ReasonChanged[0].ReasonCode != 1 || delta.Added[0].ReasonCode != 5 {
+		t.Error("unexpected reason codes")
+	}
+	if !delta.NumberIncreased || delta.OldNumber.Int64() != 7 || delta.NewNumber.Int64() != 8 ||
+		!delta.OldThisUpdate.Equal(oldList.ThisUpdate) || !delta.NewThisUpdate.Equal(newList.ThisUpdate) ||
+		!delta.OldNextUpdate.Equal(oldList.NextUpdate) || !delta.NewNextUpdate.Equal(newList.NextUpdate) {
+		t.Errorf("unexpected metadata %+v", delta)
+	}
+
+	// the same CRL
+	if delta, err := DiffRevocationLists(oldList, oldList); err != nil || delta.NumberIncreased ||
+		len(delta.Added)+len(delta.Removed)+len(delta.ReasonChanged) > 0 {
+		t.Errorf("unexpected delta %+v: %v", delta, err)
+	}
+
+	if _, err := DiffRevocationLists(newList, oldList); err == nil {
+		t.Error("expected error for a CRL number going backwards")
+	}
+	other := *newList
+	other.RawIssuer = []byte{0x30, 0x00}
+	if _, err := DiffRevocationLists(oldList, &other); err == nil {
+		t.Error("expected error for different issuers")
+	}
+	other = *newList
+	oldList.AuthorityKeyId, other.AuthorityKeyId = []byte{1}, []byte{2}
+	if _, err := DiffRevocationLists(oldList, &other); err == nil {
+		t.Error("expected error for different authority key identifiers")
+	}
+}
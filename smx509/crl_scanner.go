@@ -0,0 +1,463 @@
+package smx509
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+
+	"github.com/yunmoon/gmsm/sm2"
+	"github.com/yunmoon/gmsm/sm3"
+	"golang.org/x/crypto/cryptobyte"
+	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+// maxCRLScannerElement is the maximum size of the elements a CRLScanner
+// reads at once, such as a revoked certificate entry or the extensions.
+const maxCRLScannerElement = 16 << 20
+
+// CRLScannerOptions are the options of NewCRLScanner.
+type CRLScannerOptions struct {
+	// Issuer is the certificate of the CRL issuer, whose key checks the
+	// signature once all the entries are read, as
+	// RevocationList.CheckSignatureFrom does.
+	Issuer *Certificate
+
+	// SkipSignature disables the signature check, Issuer is then not needed.
+	SkipSignature bool
+}
+
+// CRLScanner reads the revoked certificate entries of a CRL one at a time,
+// without holding the CRL in memory, for CRLs too large for
+// ParseRevocationList.
+//
+// The entries are not authenticated before the signature is checked, once
+// Next returns false and Err returns nil.
+type CRLScanner struct {
+	r      *bufio.Reader
+	issuer *Certificate
+
+	// offset is the number of bytes read, and crlEnd, tbsEnd and
+	// entriesEnd are the offsets of the end of the CRL, the TBSCertList and
+	// the revokedCertificates.
+	offset, crlEnd, tbsEnd, entriesEnd int64
+
+	// inTBS is set while reading the TBSCertList, which is written to hash,
+	// or to tbsPrefix until the signature algorithm is known.
+	inTBS     bool
+	hash      hash.Hash
+	tbsPrefix []byte
+	rawSigAI  []byte
+
+	rl    RevocationList
+	entry *x509.RevocationListEntry
+	err   error
+	done  bool
+}
+
+// NewCRLScanner returns a CRLScanner reading a DER encoded CRL, or the first
+// "X509 CRL" PEM block, from r, after reading the fields which come before the
+// entries. A nil opts is equivalent to the zero value, which requires an
+// Issuer.
+func NewCRLScanner(r io.Reader, opts *CRLScannerOptions) (*CRLScanner, error) {
+	if opts == nil {
+		opts = &CRLScannerOptions{}
+	}
+	if opts.Issuer == nil && !opts.SkipSignature {
+		return nil, errors.New("x509: CRL issuer can not be nil unless the signature is skipped")
+	}
+	s := &CRLScanner{r: bufio.NewReaderSize(r, 64<<10)}
+	if !opts.SkipSignature {
+		if err := checkCRLIssuer(opts.Issuer); err != nil {
+			return nil, err
+		}
+		s.issuer = opts.Issuer
+	}
+	if first, err := s.r.Peek(1); err != nil {
+		return nil, err
+	} else if first[0] != 0x30 {
+		s.r = bufio.NewReaderSize(base64.NewDecoder(base64.StdEncoding, &pemBodyReader{r: s.r}), 64<<10)
+	}
+	if err := s.readFields(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// readFields reads the CRL up to the revoked certificate entries.
+func (s *CRLScanner) readFields() error {
+	length, err := s.readTagAndLength(cryptobyte_asn1.SEQUENCE)
+	if err != nil {
+		return errors.New("x509: malformed crl")
+	}
+	s.crlEnd = s.offset + length
+
+	s.inTBS = true
+	if length, err = s.readTagAndLength(cryptobyte_asn1.SEQUENCE); err != nil {
+		return errors.New("x509: malformed tbs crl")
+	}
+	s.tbsEnd = s.offset + length
+
+	version, err := s.readElement(cryptobyte_asn1.INTEGER)
+	if err != nil {
+		return errors.New("x509: unsupported crl version")
+	}
+	var v int
+	if version := cryptobyte.String(version); !version.ReadASN1Integer(&v) || v != x509v2Version {
+		return errors.New("x509: unsupported crl version")
+	}
+
+	if s.rawSigAI, err = s.readElement(cryptobyte_asn1.SEQUENCE); err != nil {
+		return errors.New("x509: malformed signature algorithm identifier")
+	}
+	var sigAISeq cryptobyte.String
+	if der := cryptobyte.String(s.rawSigAI); !der.ReadASN1(&sigAISeq, cryptobyte_asn1.SEQUENCE) {
+		return errors.New("x509: malformed signature algorithm identifier")
+	}
+	sigAI, err := parseAI(sigAISeq)
+	if err != nil {
+		return err
+	}
+	s.rl.SignatureAlgorithm = getSignatureAlgorithmFromAI(sigAI)
+	if s.issuer != nil {
+		if s.hash, err = newCRLScannerHash(s.rl.SignatureAlgorithm, s.issuer); err != nil {
+			return err
+		}
+		s.hash.Write(s.tbsPrefix)
+	}
+	s.tbsPrefix = nil
+
+	issuer, err := s.readElement(cryptobyte_asn1.SEQUENCE)
+	if err != nil {
+		return errors.New("x509: malformed issuer")
+	}
+	s.rl.RawIssuer = issuer
+	issuerRDNs, err := ParseName(issuer)
+	if err != nil {
+		return err
+	}
+	s.rl.Issuer.FillFromRDNSequence(issuerRDNs)
+
+	if s.rl.ThisUpdate, err = s.readTime(); err != nil {
+		return err
+	}
+	if s.peekTag(cryptobyte_asn1.GeneralizedTime) || s.peekTag(cryptobyte_asn1.UTCTime) {
+		if s.rl.NextUpdate, err = s.readTime(); err != nil {
+			return err
+		}
+	}
+
+	s.entriesEnd = s.offset
+	if s.offset < s.tbsEnd && s.peekTag(cryptobyte_asn1.SEQUENCE) {
+		if length, err = s.readTagAndLength(cryptobyte_asn1.SEQUENCE); err != nil {
+			return errors.New("x509: malformed crl")
+		}
+		s.entriesEnd = s.offset + length
+	}
+	if s.entriesEnd > s.tbsEnd || s.tbsEnd > s.crlEnd {
+		return errors.New("x509: malformed crl")
+	}
+	return nil
+}
+
+// newCRLScannerHash returns the hash of the TBSCertList of a CRL signed with
+// algo by the key of issuer.
+func newCRLScannerHash(algo SignatureAlgorithm, issuer *Certificate) (hash.Hash, error) {
+	switch algo {
+	case SM2WithSM3:
+		pub, ok := issuer.PublicKey.(*ecdsa.PublicKey)
+		if !ok || !sm2.IsSM2PublicKey(pub) {
+			return nil, signaturePublicKeyAlgoMismatchError(ECDSA, issuer.PublicKey)
+		}
+		return sm2.NewHash(pub)
+	case RSAWithSM3:
+		return sm3.New(), nil
+	}
+	if h := hashFunc(algo); h != 0 && h.Available() {
+		return h.New(), nil
+	}
+	return nil, x509.ErrUnsupportedAlgorithm
+}
+
+// Next reads the next revoked certificate entry, which is then returned by
+// Entry. After the last one, it reads the rest of the CRL and checks its
+// signature, and returns false, as on errors, which are returned by Err.
+func (s *CRLScanner) Next() bool {
+	if s.done {
+		return false
+	}
+	if s.offset < s.entriesEnd {
+		der, err := s.readElement(cryptobyte_asn1.SEQUENCE)
+		if err != nil {
+			s.fail(err)
+			return false
+		}
+		if s.offset > s.entriesEnd {
+			s.fail(errors.New("x509: malformed crl"))
+			return false
+		}
+		rce, err := parseRevocationListEntry(der, false)
+		if err != nil {
+			s.fail(err)
+			return false
+		}
+		s.entry = &rce
+		return true
+	}
+	s.entry = nil
+	s.fail(s.finish())
+	return false
+}
+
+// Entry returns the entry read by the last call to Next.
+func (s *CRLScanner) Entry() *x509.RevocationListEntry {
+	return s.entry
+}
+
+// Err returns the error which stopped Next, nil if the CRL was read to its
+// end and its signature is valid or skipped.
+func (s *CRLScanner) Err() error {
+	return s.err
+}
+
+// RevocationList returns the fields of the CRL other than its entries. The
+// issuer, validity and signature algorithm are set by NewCRLScanner, the
+// extensions, number, authority key identifier and signature once Next has
+// returned false. RawIssuer is set, but not the other raw fields.
+func (s *CRLScanner) RevocationList() *RevocationList {
+	return &s.rl
+}
+
+func (s *CRLScanner) fail(err error) {
+	s.err = err
+	s.done = true
+}
+
+// finish reads the CRL after its entries and checks its signature.
+func (s *CRLScanner) finish() error {
+	if s.offset < s.tbsEnd {
+		der, err := s.readElement(cryptobyte_asn1.Tag(0).Constructed().ContextSpecific())
+		if err != nil {
+			return errors.New("x509: malformed extensions")
+		}
+		var extensions cryptobyte.String
+		if der := cryptobyte.String(der); !der.ReadASN1(&extensions, cryptobyte_asn1.Tag(0).Constructed().ContextSpecific()) {
+			return errors.New("x509: malformed extensions")
+		}
+		if err := parseRevocationListExtensions(extensions, &s.rl); err != nil {
+			return err
+		}
+	}
+	if s.offset != s.tbsEnd {
+		return errors.New("x509: malformed tbs crl")
+	}
+	s.inTBS = false
+
+	outerSigAI, err := s.readElement(cryptobyte_asn1.SEQUENCE)
+	if err != nil {
+		return errors.New("x509: malformed algorithm identifier")
+	}
+	var sigAISeq, outerSigAISeq cryptobyte.String
+	inner, outer := cryptobyte.String(s.rawSigAI), cryptobyte.String(outerSigAI)
+	if !inner.ReadASN1(&sigAISeq, cryptobyte_asn1.SEQUENCE) || !outer.ReadASN1(&outerSigAISeq, cryptobyte_asn1.SEQUENCE) {
+		return errors.New("x509: malformed algorithm identifier")
+	}
+	if !matchingSignatureAIs(sigAISeq, outerSigAISeq) {
+		return errors.New("x509: inner and outer signature algorithm identifiers don't match")
+	}
+	der, err := s.readElement(cryptobyte_asn1.BIT_STRING)
+	if err != nil {
+		return errors.New("x509: malformed signature")
+	}
+	var signature asn1.BitString
+	if der := cryptobyte.String(der); !der.ReadASN1BitString(&signature) {
+		return errors.New("x509: malformed signature")
+	}
+	s.rl.Signature = signature.RightAlign()
+	if s.offset != s.crlEnd {
+		return errors.New("x509: malformed crl")
+	}
+
+	if s.hash == nil {
+		return nil
+	}
+	return checkCRLSignatureWithDigest(s.issuer, s.rl.SignatureAlgorithm, s.hash.Sum(nil), s.rl.Signature)
+}
+
+// checkCRLSignatureWithDigest checks the signature of a CRL by issuer over
+// the digest of its TBSCertList, computed by the hash of newCRLScannerHash.
+// Certificate.CheckSignatureWithDigest verifies the algorithms with a
+// crypto.Hash and SM2WithSM3, the others are verified here.
+func checkCRLSignatureWithDigest(issuer *Certificate, algo SignatureAlgorithm, digest, signature []byte) error {
+	switch algo {
+	case RSAWithSM3:
+		pub, ok := issuer.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			return signaturePublicKeyAlgoMismatchError(RSA, issuer.PublicKey)
+		}
+		if len(digest) != sm3.Size {
+			return errors.New("x509: inconsistent digest and signature algorithm")
+		}
+		return rsa.VerifyPKCS1v15(pub, crypto.Hash(0), append(bytes.Clone(sm3DigestInfoPrefix), digest...), signature)
+	case SM2WithSHA1, SM2WithSHA256:
+		pub, ok := issuer.PublicKey.(*ecdsa.PublicKey)
+		if !ok || !sm2.IsSM2PublicKey(pub) {
+			return signaturePublicKeyAlgoMismatchError(ECDSA, issuer.PublicKey)
+		}
+		if len(digest) != hashFunc(algo).Size() {
+			return errors.New("x509: inconsistent digest and signature algorithm")
+		}
+		if !sm2.VerifyASN1(pub, digest, signature) {
+			return errors.New("x509: SM2 verification failure")
+		}
+		return nil
+	}
+	return issuer.CheckSignatureWithDigest(algo, digest, signature)
+}
+
+// read reads len(p) bytes, adding them to the TBSCertList if in it.
+func (s *CRLScanner) read(p []byte) error {
+	if _, err := io.ReadFull(s.r, p); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	s.offset += int64(len(p))
+	if s.inTBS {
+		if s.hash != nil {
+			s.hash.Write(p)
+		} else if s.issuer != nil {
+			s.tbsPrefix = append(s.tbsPrefix, p...)
+		}
+	}
+	return nil
+}
+
+func (s *CRLScanner) peekTag(tag cryptobyte_asn1.Tag) bool {
+	b, err := s.r.Peek(1)
+	return err == nil && b[0] == byte(tag)
+}
+
+// readTagAndLength reads the identifier and length octets of an element with
+// tag, and returns its length.
+func (s *CRLScanner) readTagAndLength(tag cryptobyte_asn1.Tag) (int64, error) {
+	_, length, err := s.readDERHeader(tag)
+	return length, err
+}
+
+// readDERHeader reads the identifier and length octets of an element with
+// tag, and returns them and the length.
+func (s *CRLScanner) readDERHeader(tag cryptobyte_asn1.Tag) ([]byte, int64, error) {
+	header := make([]byte, 2, 6)
+	if err := s.read(header); err != nil {
+		return nil, 0, err
+	}
+	if header[0] != byte(tag) {
+		return nil, 0, fmt.Errorf("x509: unexpected tag %#x", header[0])
+	}
+	length := int64(header[1])
+	if header[1]&0x80 != 0 {
+		n := int(header[1] & 0x7f)
+		if n == 0 || n > 4 {
+			return nil, 0, errors.New("x509: unsupported length")
+		}
+		header = header[:2+n]
+		if err := s.read(header[2:]); err != nil {
+			return nil, 0, err
+		}
+		length = 0
+		for _, c := range header[2:] {
+			length = length<<8 | int64(c)
+		}
+		if header[2] == 0 || length < 0x80 {
+			return nil, 0, errors.New("x509: non-minimal length")
+		}
+	}
+	return header, length, nil
+}
+
+// readElement reads an element with tag, including its tag and length.
+func (s *CRLScanner) readElement(tag cryptobyte_asn1.Tag) ([]byte, error) {
+	header, length, err := s.readDERHeader(tag)
+	if err != nil {
+		return nil, err
+	}
+	if length > maxCRLScannerElement {
+		return nil, errors.New("x509: CRL element too large")
+	}
+	element := make([]byte, len(header)+int(length))
+	copy(element, header)
+	if err := s.read(element[len(header):]); err != nil {
+		return nil, err
+	}
+	return element, nil
+}
+
+// readTime reads a UTCTime or GeneralizedTime.
+func (s *CRLScanner) readTime() (time.Time, error) {
+	tag := cryptobyte_asn1.UTCTime
+	if s.peekTag(cryptobyte_asn1.GeneralizedTime) {
+		tag = cryptobyte_asn1.GeneralizedTime
+	}
+	der, err := s.readElement(tag)
+	if err != nil {
+		return time.Time{}, errors.New("x509: malformed time")
+	}
+	t := cryptobyte.String(der)
+	return parseCRLTime(&t, false)
+}
+
+// pemBodyReader reads the base64 body of the first "X509 CRL" PEM block of r,
+// skipping its headers.
+type pemBodyReader struct {
+	r       *bufio.Reader
+	started bool
+	line    []byte
+	err     error
+}
+
+func (p *pemBodyReader) Read(b []byte) (int, error) {
+	for len(p.line) == 0 {
+		if p.err != nil {
+			return 0, p.err
+		}
+		line, err := p.r.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			p.err = errors.New("x509: PEM line too long")
+			continue
+		}
+		if err != nil && (err != io.EOF || len(line) == 0) {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			p.err = err
+			continue
+		}
+		line = bytes.TrimSpace(line)
+		switch {
+		case !p.started:
+			p.started = string(line) == "-----BEGIN X509 CRL-----"
+		case string(line) == "-----END X509 CRL-----":
+			p.err = io.EOF
+		case bytes.HasPrefix(line, []byte("-----")):
+			p.err = errors.New("x509: malformed X509 CRL PEM block")
+		case bytes.IndexByte(line, ':') >= 0:
+			// a header, such as Proc-Type
+		default:
+			p.line = line
+		}
+	}
+	n := copy(b, p.line)
+	p.line = p.line[n:]
+	return n, nil
+}
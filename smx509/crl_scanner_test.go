@@ -0,0 +1,298 @@
+package smx509
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"runtime/metrics"
+	"testing"
+	"time"
+
+	"github.com/yunmoon/gmsm/sm2"
+)
+
+func newCRLScannerTestCRL(t testing.TB, issuer *Certificate, priv crypto.Signer, entries int) []byte {
+	t.Helper()
+	now := time.Now().Truncate(time.Second)
+	template := &x509.RevocationList{
+		Number:                    big.NewInt(42),
+		ThisUpdate:                now,
+		NextUpdate:                now.Add(24 * time.Hour),
+		RevokedCertificateEntries: make([]x509.RevocationListEntry, entries),
+	}
+	for i := range template.RevokedCertificateEntries {
+		template.RevokedCertificateEntries[i] = x509.RevocationListEntry{
+			SerialNumber:   big.NewInt(int64(i) + 1000),
+			RevocationTime: now.Add(-time.Duration(i) * time.Second),
+			ReasonCode:     i % 6,
+		}
+	}
+	der, err := CreateRevocationList(rand.Reader, template, issuer, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func scanCRL(t *testing.T, data []byte, opts *CRLScannerOptions) (*RevocationList, []*x509.RevocationListEntry, error) {
+	t.Helper()
+	s, err := NewCRLScanner(bytes.NewReader(data), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []*x509.RevocationListEntry
+	for s.Next() {
+		entries = append(entries, s.Entry())
+	}
+	return s.RevocationList(), entries, s.Err()
+}
+
+func TestCRLScanner(t *testing.T) {
+	sm2Key, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, priv := range []crypto.Signer{sm2Key, ecdsaKey} {
+		issuer := newTestIssuer(t, priv, pkix.Name{CommonName: "GM CRL CA"})
+		for _, entries := range []int{0, 1, 300} {
+			der := newCRLScannerTestCRL(t, issuer, priv, entries)
+			want, err := ParseRevocationList(der)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pemData := pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Headers: map[string]string{"Comment": "test"}, Bytes: der})
+			for _, data := range [][]byte{der, append([]byte("junk before\n"), pemData...)} {
+				rl, got, err := scanCRL(t, data, &CRLScannerOptions{Issuer: issuer})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(got) != len(want.RevokedCertificateEntries) {
+					t.Fatalf("got %d entries, want %d", len(got), len(want.RevokedCertificateEntries))
+				}
+				for i, rce := range got {
+					if !reflect.DeepEqual(*rce, want.RevokedCertificateEntries[i]) {
+						t.Fatalf("entry %d: got %v, want %v", i, rce, want.RevokedCertificateEntries[i])
+					}
+				}
+				if rl.SignatureAlgorithm != want.SignatureAlgorithm || !bytes.Equal(rl.RawIssuer, want.RawIssuer) ||
+					rl.Issuer.String() != want.Issuer.String() || !rl.ThisUpdate.Equal(want.ThisUpdate) || !rl.NextUpdate.Equal(want.NextUpdate) ||
+					rl.Number.Cmp(want.Number) != 0 || !bytes.Equal(rl.AuthorityKeyId, want.AuthorityKeyId) ||
+					!reflect.DeepEqual(rl.Extensions, want.Extensions) || !bytes.Equal(rl.Signature, want.Signature) {
+					t.Errorf("unexpected revocation list %+v", rl)
+				}
+			}
+		}
+	}
+
+	issuer := newTestIssuer(t, sm2Key, pkix.Name{CommonName: "GM CRL CA"})
+	der := newCRLScannerTestCRL(t, issuer, sm2Key, 3)
+	rl, err := ParseRevocationList(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a tampered entry fails the signature check, unless skipped
+	tampered := bytes.Clone(der)
+	i := bytes.Index(tampered, rl.RevokedCertificateEntries[1].Raw)
+	tampered[i+4]++
+	if _, _, err := scanCRL(t, tampered, &CRLScannerOptions{Issuer: issuer}); err == nil {
+		t.Error("tampered CRL verified")
+	}
+	if _, entries, err := scanCRL(t, tampered, &CRLScannerOptions{SkipSignature: true}); err != nil || len(entries) != 3 {
+		t.Errorf("unexpected error or entries %d: %v", len(entries), err)
+	}
+	// another issuer
+	if _, _, err := scanCRL(t, der, &CRLScannerOptions{Issuer: newTestIssuer(t, sm2Key, pkix.Name{CommonName: "GM CRL CA"})}); err != nil {
+		t.Errorf("unexpected error with the same key: %v", err)
+	}
+	otherKey, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := scanCRL(t, der, &CRLScannerOptions{Issuer: newTestIssuer(t, otherKey, pkix.Name{CommonName: "GM CRL CA"})}); err == nil {
+		t.Error("CRL verified with another key")
+	}
+	// truncated
+	if _, _, err := scanCRL(t, der[:len(der)-10], &CRLScannerOptions{Issuer: issuer}); err == nil {
+		t.Error("truncated CRL read without error")
+	}
+	if _, err := NewCRLScanner(bytes.NewReader(der), nil); err == nil {
+		t.Error("expected error without an issuer")
+	}
+	// only the X509 CRL PEM block is read
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: issuer.Raw})
+	crlPEM := pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der})
+	if _, entries, err := scanCRL(t, append(certPEM, crlPEM...), &CRLScannerOptions{Issuer: issuer}); err != nil || len(entries) != 3 {
+		t.Errorf("unexpected error or entries %d: %v", len(entries), err)
+	}
+	if _, err := NewCRLScanner(bytes.NewReader(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})), &CRLScannerOptions{Issuer: issuer}); err == nil {
+		t.Error("expected error with a CERTIFICATE PEM block")
+	}
+}
+
+func TestCRLScannerSMAlgorithms(t *testing.T) {
+	// RSAWithSM3 CRLs are hashed with SM3 and verified with the DigestInfo
+	// of the SM3 digest.
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := newTestIssuer(t, rsaKey, pkix.Name{CommonName: "GM CRL CA"})
+	now := time.Now().Truncate(time.Second)
+	der, err := CreateRevocationList(rand.Reader, &x509.RevocationList{
+		SignatureAlgorithm: RSAWithSM3,
+		Number:             big.NewInt(1),
+		ThisUpdate:         now,
+		NextUpdate:         now.Add(time.Hour),
+		RevokedCertificateEntries: []x509.RevocationListEntry{
+			{SerialNumber: big.NewInt(1000), RevocationTime: now},
+		},
+	}, issuer, rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rl, entries, err := scanCRL(t, der, &CRLScannerOptions{Issuer: issuer})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("unexpected error or entries %d: %v", len(entries), err)
+	}
+	if rl.SignatureAlgorithm != RSAWithSM3 {
+		t.Errorf("unexpected signature algorithm %v", rl.SignatureAlgorithm)
+	}
+	tampered := bytes.Clone(der)
+	tampered[len(tampered)-1]++
+	if _, _, err := scanCRL(t, tampered, &CRLScannerOptions{Issuer: issuer}); err == nil {
+		t.Error("tampered RSAWithSM3 CRL verified")
+	}
+
+	// SM2WithSHA256 CRLs, which can't be created, are checked over the
+	// SHA-256 digest without ZA.
+	sm2Key, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer = newTestIssuer(t, sm2Key, pkix.Name{CommonName: "GM CRL CA"})
+	digest := sha256.Sum256([]byte("tbsCertList"))
+	signature, err := sm2.SignASN1(rand.Reader, sm2Key, digest[:], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkCRLSignatureWithDigest(issuer, SM2WithSHA256, digest[:], signature); err != nil {
+		t.Error(err)
+	}
+	if err := checkCRLSignatureWithDigest(issuer, SM2WithSHA256, digest[:16], signature); err == nil {
+		t.Error("expected error with a truncated digest")
+	}
+	digest[0]++
+	if err := checkCRLSignatureWithDigest(issuer, SM2WithSHA256, digest[:], signature); err == nil {
+		t.Error("SM2WithSHA256 signature verified over another digest")
+	}
+	if err := checkCRLSignatureWithDigest(newTestIssuer(t, rsaKey, pkix.Name{CommonName: "GM CRL CA"}), SM2WithSHA256, digest[:], signature); err == nil {
+		t.Error("SM2WithSHA256 signature verified with a RSA key")
+	}
+}
+
+// BenchmarkLargeCRL compares ParseRevocationList with a CRLScanner reading a
+// file, on a CRL with a million entries. peak-heap-MB is the peak heap in use
+// while the CRL is read, sampled during an extra untimed run.
+func BenchmarkLargeCRL(b *testing.B) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		b.Fatal(err)
+	}
+	issuer := newTestIssuer(b, priv, pkix.Name{CommonName: "GM CRL CA"})
+	name := filepath.Join(b.TempDir(), "large.crl")
+	if err := os.WriteFile(name, newCRLScannerTestCRL(b, issuer, priv, 1_000_000), 0o600); err != nil {
+		b.Fatal(err)
+	}
+
+	parse := func(b *testing.B) {
+		der, err := os.ReadFile(name)
+		if err != nil {
+			b.Fatal(err)
+		}
+		rl, err := ParseRevocationList(der)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := rl.CheckSignatureFrom(issuer); err != nil {
+			b.Fatal(err)
+		}
+	}
+	scan := func(b *testing.B) {
+		f, err := os.Open(name)
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer f.Close()
+		s, err := NewCRLScanner(f, &CRLScannerOptions{Issuer: issuer})
+		if err != nil {
+			b.Fatal(err)
+		}
+		for s.Next() {
+		}
+		if err := s.Err(); err != nil {
+			b.Fatal(err)
+		}
+	}
+	for _, bb := range []struct {
+		name string
+		run  func(*testing.B)
+	}{{"ParseRevocationList", parse}, {"CRLScanner", scan}} {
+		b.Run(bb.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				bb.run(b)
+			}
+			b.StopTimer()
+			peak := peakHeapInUse(func() { bb.run(b) })
+			b.ReportMetric(float64(peak)/(1<<20), "peak-heap-MB")
+		})
+	}
+}
+
+// peakHeapInUse runs f and returns the peak of the heap in use while it runs,
+// above the heap in use when it starts. The heap is sampled every millisecond.
+func peakHeapInUse(f func()) uint64 {
+	sample := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	read := func() uint64 {
+		metrics.Read(sample)
+		return sample[0].Value.Uint64()
+	}
+	runtime.GC()
+	base := read()
+	peak := base
+	done := make(chan struct{})
+	sampled := make(chan struct{})
+	go func() {
+		defer close(sampled)
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+		for {
+			peak = max(peak, read())
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	f()
+	close(done)
+	<-sampled
+	return max(peak, read()) - base
+}
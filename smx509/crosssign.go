@@ -0,0 +1,103 @@
+package smx509
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"io"
+	"math/big"
+	"time"
+)
+
+// CrossSignOption overrides the template which CrossSign builds from the
+// original certificate. The extensions of the original certificate are in
+// the template ExtraExtensions, so they take precedence over the fields which
+// would otherwise generate them.
+type CrossSignOption func(template *x509.Certificate)
+
+// CrossSignSerialNumber sets the serial number of the cross-signed
+// certificate, nil for a new random one, instead of the original one.
+func CrossSignSerialNumber(serialNumber *big.Int) CrossSignOption {
+	return func(template *x509.Certificate) {
+		template.SerialNumber = serialNumber
+	}
+}
+
+// CrossSignValidity sets the validity period of the cross-signed certificate
+// instead of the original one.
+func CrossSignValidity(notBefore, notAfter time.Time) CrossSignOption {
+	return func(template *x509.Certificate) {
+		template.NotBefore, template.NotAfter = notBefore, notAfter
+	}
+}
+
+// CrossSign re-signs cert under newParent, with priv which is the private key
+// of newParent. The cross-signed certificate has the subject, public key,
+// validity, serial number and extensions of cert, but the issuer is the
+// subject of newParent, the authority key identifier is the subject key
+// identifier of newParent, or is left out if newParent has none, and the
+// signature algorithm is the default one for priv, such as SM2WithSM3 for an
+// SM2 key. overrides are applied in order to the template before signing.
+func CrossSign(rand io.Reader, cert *Certificate, newParent *Certificate, priv crypto.Signer, overrides ...CrossSignOption) ([]byte, error) {
+	if cert == nil || newParent == nil {
+		return nil, errors.New("x509: certificate and new parent can not be nil")
+	}
+
+	template := *cert.asX509()
+	template.SignatureAlgorithm = UnknownSignatureAlgorithm
+	template.AuthorityKeyId = nil
+	template.ExtraExtensions = nil
+	for _, e := range cert.Extensions {
+		if e.Id.Equal(oidExtensionAuthorityKeyId) {
+			// replaced in place, to keep the order of the extensions
+			if len(newParent.SubjectKeyId) == 0 {
+				continue
+			}
+			value, err := asn1.Marshal(authKeyId{Id: newParent.SubjectKeyId})
+			if err != nil {
+				return nil, err
+			}
+			e = pkix.Extension{Id: e.Id, Critical: e.Critical, Value: value}
+		}
+		template.ExtraExtensions = append(template.ExtraExtensions, e)
+	}
+	for _, override := range overrides {
+		override(&template)
+	}
+
+	pub, err := reissuePublicKey(cert)
+	if err != nil {
+		return nil, err
+	}
+	return CreateCertificate(rand, &template, newParent, pub, priv)
+}
+
+// reissuePublicKey returns the public key of cert, wrapped so that
+// CreateCertificate encodes it with the algorithm OID and point form of cert.
+func reissuePublicKey(cert *Certificate) (any, error) {
+	if cert.PublicKey == nil && cert.PublicKeyAlgorithm == UnknownPublicKeyAlgorithm {
+		return UnknownPublicKey(cert.RawSubjectPublicKeyInfo), nil
+	}
+	ecKey, ok := cert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return cert.PublicKey, nil
+	}
+	var spki publicKeyInfo
+	if rest, err := asn1.Unmarshal(cert.RawSubjectPublicKeyInfo, &spki); err != nil || len(rest) > 0 {
+		return nil, errors.New("x509: malformed spki")
+	}
+	point := spki.PublicKey.RightAlign()
+	compressed := len(point) > 0 && (point[0] == 2 || point[0] == 3)
+	switch {
+	case spki.Algorithm.Algorithm.Equal(oidPublicKeySM2) && compressed:
+		return nil, errors.New("x509: cannot encode a compressed SM2 public key with the SM2 algorithm OID")
+	case spki.Algorithm.Algorithm.Equal(oidPublicKeySM2):
+		return &SM2OIDPublicKey{ecKey}, nil
+	case compressed:
+		return &CompressedECPublicKey{ecKey}, nil
+	}
+	return ecKey, nil
+}
@@ -0,0 +1,147 @@
+package smx509
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/yunmoon/gmsm/sm2"
+)
+
+func TestCrossSign(t *testing.T) {
+	oldRootKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newRootKey, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	intermediateKey, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafKey, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newCA := func(cn string, serial int64, pub any, parent *Certificate, priv any) *Certificate {
+		t.Helper()
+		template := &x509.Certificate{
+			SerialNumber:          big.NewInt(serial),
+			Subject:               pkix.Name{CommonName: cn},
+			NotBefore:             time.Now().Add(-time.Hour),
+			NotAfter:              time.Now().Add(time.Hour),
+			KeyUsage:              KeyUsageCertSign | KeyUsageCRLSign,
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+			ExtraExtensions: []pkix.Extension{
+				{Id: asn1.ObjectIdentifier{1, 2, 3, 4}, Value: []byte{0x05, 0x00}},
+			},
+		}
+		realParent := template
+		if parent != nil {
+			realParent = parent.asX509()
+		}
+		der, err := CreateCertificate(rand.Reader, template, realParent, pub, priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, err := ParseCertificate(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert
+	}
+	oldRoot := newCA("RSA Root", 1, oldRootKey.Public(), nil, oldRootKey)
+	newRoot := newCA("SM2 Root", 1, newRootKey.Public(), nil, newRootKey)
+	intermediate := newCA("SM2 Intermediate", 2, &SM2OIDPublicKey{&intermediateKey.PublicKey}, oldRoot, oldRootKey)
+
+	leafTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "leaf"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"leaf.example.com"},
+		ExtKeyUsage:  []ExtKeyUsage{ExtKeyUsageServerAuth},
+	}
+	leafDER, err := CreateCertificate(rand.Reader, leafTemplate, intermediate, leafKey.Public(), intermediateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := ParseCertificate(leafDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	crossDER, err := CrossSign(rand.Reader, intermediate, newRoot, newRootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cross, err := ParseCertificate(crossDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cross.SignatureAlgorithm != SM2WithSM3 {
+		t.Errorf("unexpected signature algorithm %v", cross.SignatureAlgorithm)
+	}
+	if !bytes.Equal(cross.RawIssuer, newRoot.RawSubject) || !bytes.Equal(cross.AuthorityKeyId, newRoot.SubjectKeyId) {
+		t.Error("the cross-signed certificate is not issued by the new root")
+	}
+	if !bytes.Equal(cross.RawSubject, intermediate.RawSubject) ||
+		!bytes.Equal(cross.RawSubjectPublicKeyInfo, intermediate.RawSubjectPublicKeyInfo) ||
+		cross.SerialNumber.Cmp(intermediate.SerialNumber) != 0 ||
+		!cross.NotBefore.Equal(intermediate.NotBefore) || !cross.NotAfter.Equal(intermediate.NotAfter) {
+		t.Error("the cross-signed certificate differs from the original")
+	}
+	if len(cross.Extensions) != len(intermediate.Extensions) {
+		t.Fatalf("expected %d extensions, got %d", len(intermediate.Extensions), len(cross.Extensions))
+	}
+	for i, e := range intermediate.Extensions {
+		got := cross.Extensions[i]
+		if !got.Id.Equal(e.Id) || got.Critical != e.Critical || !e.Id.Equal(oidExtensionAuthorityKeyId) && !bytes.Equal(got.Value, e.Value) {
+			t.Errorf("extension %v differs", e.Id)
+		}
+	}
+
+	verify := func(intermediate, root *Certificate) error {
+		intermediates, roots := NewCertPool(), NewCertPool()
+		intermediates.AddCert(intermediate)
+		roots.AddCert(root)
+		_, err := leaf.Verify(VerifyOptions{Intermediates: intermediates, Roots: roots})
+		return err
+	}
+	if err := verify(cross, newRoot); err != nil {
+		t.Errorf("the cross-signed certificate does not chain to the new root: %v", err)
+	}
+	if err := verify(intermediate, oldRoot); err != nil {
+		t.Errorf("the original certificate does not chain to the old root: %v", err)
+	}
+	if err := verify(cross, oldRoot); err == nil {
+		t.Error("the cross-signed certificate chains to the old root")
+	}
+
+	// overrides
+	notAfter := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	if crossDER, err = CrossSign(rand.Reader, intermediate, newRoot, newRootKey, CrossSignSerialNumber(nil), CrossSignValidity(intermediate.NotBefore, notAfter)); err != nil {
+		t.Fatal(err)
+	}
+	if cross, err = ParseCertificate(crossDER); err != nil {
+		t.Fatal(err)
+	}
+	if cross.SerialNumber.Cmp(intermediate.SerialNumber) == 0 || !cross.NotAfter.Equal(notAfter) {
+		t.Errorf("unexpected serial number %v and validity %v", cross.SerialNumber, cross.NotAfter)
+	}
+
+	// the key of another issuer
+	if _, err := CrossSign(rand.Reader, intermediate, newRoot, oldRootKey); err == nil {
+		t.Error("expected error with a private key not matching the new parent")
+	}
+}
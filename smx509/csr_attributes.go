@@ -0,0 +1,73 @@
+package smx509
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+)
+
+// RawAttribute is an attribute of a certificate request, RFC 2986, 4.1, such
+// as the challengePassword attribute and the proprietary ones of CFCA, which
+// CertificateRequest.Attributes can't hold.
+type RawAttribute struct {
+	Type asn1.ObjectIdentifier
+	// Value is the encoding after the type, the SET of values of standard
+	// attributes, or the single value of CFCA attributes, such as the OCTET
+	// STRING of the temporary public key.
+	Value asn1.RawValue
+}
+
+// UnhandledAttributes returns the attributes of c which are dropped from
+// c.Attributes because their values are not AttributeTypeAndValue SETs, in
+// their order in the request. They can be passed back in
+// CreateCertificateRequestOptions.ExtraAttributes.
+func (c *CertificateRequest) UnhandledAttributes() ([]RawAttribute, error) {
+	attributes, err := parseCSRAttributes(c.RawTBSCertificateRequest)
+	if err != nil {
+		return nil, err
+	}
+	var ret []RawAttribute
+	for _, attr := range attributes {
+		var atvSet pkix.AttributeTypeAndValueSET
+		if rest, err := asn1.Unmarshal(attr.Raw, &atvSet); err == nil && len(rest) == 0 {
+			continue
+		}
+		raw := RawAttribute{Type: attr.Type}
+		if rest, err := asn1.Unmarshal(attr.Values, &raw.Value); err != nil || len(rest) != 0 {
+			return nil, errors.New("x509: malformed certificate request attribute")
+		}
+		ret = append(ret, raw)
+	}
+	return ret, nil
+}
+
+// CFCATemporaryPublicKey decodes attr, the temporary public key attribute of
+// CFCA requests, 1.2.840.113549.1.9.63, which the escrowed encryption key is
+// returned encrypted to. algo is the public key algorithm of the request: the
+// temporary key is an *rsa.PublicKey for RSA, and an SM2 *ecdsa.PublicKey for
+// SM2 and ECDSA.
+func (attr RawAttribute) CFCATemporaryPublicKey(algo PublicKeyAlgorithm) (any, error) {
+	if !attr.Type.Equal(oidTmpPublicKey) {
+		return nil, errors.New("x509: not a CFCA temporary public key attribute")
+	}
+	if attr.Value.Class != asn1.ClassUniversal || attr.Value.Tag != asn1.TagOctetString || attr.Value.IsCompound {
+		return nil, errors.New("x509: malformed temporary public key")
+	}
+	return parseTmpPublicKey(attr.Value.Bytes, algo)
+}
+
+// marshalRawAttribute returns the attribute encoding of attr.
+func marshalRawAttribute(attr RawAttribute) (asn1.RawValue, error) {
+	if attr.Type.Equal(oidExtensionRequest) {
+		return asn1.RawValue{}, errors.New("x509: extension request attribute must be set with ExtraExtensions")
+	}
+	b, err := asn1.Marshal(attr)
+	if err != nil {
+		return asn1.RawValue{}, err
+	}
+	var rawValue asn1.RawValue
+	if _, err := asn1.Unmarshal(b, &rawValue); err != nil {
+		return asn1.RawValue{}, err
+	}
+	return rawValue, nil
+}
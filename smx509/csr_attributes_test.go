@@ -0,0 +1,126 @@
+package smx509
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"testing"
+
+	"github.com/yunmoon/gmsm/sm2"
+)
+
+func TestUnhandledAttributes(t *testing.T) {
+	// a CSR of the CFCA SADK, with its challengePassword and temporary
+	// public key attributes outside of SETs
+	block, _ := pem.Decode([]byte(sadkGeneratedCSR))
+	csr, err := ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attrs, err := csr.UnhandledAttributes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attrs) != 2 || !attrs[0].Type.Equal(oidChallengePassword) || !attrs[1].Type.Equal(oidTmpPublicKey) {
+		t.Fatalf("unexpected attributes %v", attrs)
+	}
+	if attrs[0].Value.Tag != asn1.TagPrintableString || string(attrs[0].Value.Bytes) != "111111" {
+		t.Errorf("unexpected challenge password value %x", attrs[0].Value.FullBytes)
+	}
+	if attrs[1].Value.Tag != asn1.TagOctetString {
+		t.Errorf("unexpected temporary public key value %x", attrs[1].Value.FullBytes)
+	}
+	cfcaCSR, err := ParseCFCACertificateRequest(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpPub, err := attrs[1].CFCATemporaryPublicKey(csr.PublicKeyAlgorithm); err != nil || !cfcaCSR.TmpPublicKey.(*ecdsa.PublicKey).Equal(tmpPub) {
+		t.Errorf("unexpected temporary public key %v: %v", tmpPub, err)
+	}
+	if _, err := attrs[0].CFCATemporaryPublicKey(csr.PublicKeyAlgorithm); err == nil {
+		t.Error("expected error for the challenge password attribute")
+	}
+	if _, err := attrs[1].CFCATemporaryPublicKey(RSA); err == nil {
+		t.Error("expected error for an SM2 temporary public key decoded as RSA")
+	}
+
+	// the attributes are copied as they are to a new CSR
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.CertificateRequest{RawSubject: csr.RawSubject, DNSNames: []string{"cfca.example.com"}}
+	der, err := CreateCertificateRequestWithOptions(rand.Reader, template, priv, &CreateCertificateRequestOptions{ExtraAttributes: attrs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, attr := range attrs {
+		if !bytes.Contains(der, attr.Value.FullBytes) {
+			t.Errorf("missing attribute %v", attr.Type)
+		}
+	}
+	copied, err := ParseCFCACertificateRequest(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := copied.CheckSignature(); err != nil {
+		t.Fatal(err)
+	}
+	if copied.ChallengePassword != "111111" || !copied.TmpPublicKey.(*ecdsa.PublicKey).Equal(cfcaCSR.TmpPublicKey) {
+		t.Errorf("unexpected CFCA attributes %q %v", copied.ChallengePassword, copied.TmpPublicKey)
+	}
+	if len(copied.DNSNames) != 1 {
+		t.Errorf("unexpected requested extensions %v", copied.Extensions)
+	}
+	if got, err := copied.UnhandledAttributes(); err != nil || len(got) != 2 {
+		t.Errorf("unexpected attributes %v: %v", got, err)
+	}
+
+	// standard attributes are in Attributes
+	der, err = CreateCertificateRequestWithOptions(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: "device"},
+		Attributes: []pkix.AttributeTypeAndValueSET{{
+			Type:  oidUnstructuredName,
+			Value: [][]pkix.AttributeTypeAndValue{{{Type: asn1.ObjectIdentifier{1, 2, 3, 4}, Value: "value"}}},
+		}},
+	}, priv, &CreateCertificateRequestOptions{ChallengePassword: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if csr, err = ParseCertificateRequest(der); err != nil {
+		t.Fatal(err)
+	}
+	if attrs, err := csr.UnhandledAttributes(); err != nil || len(attrs) != 1 || !attrs[0].Type.Equal(oidChallengePassword) || len(csr.Attributes) != 1 {
+		t.Errorf("unexpected attributes %v %v: %v", attrs, csr.Attributes, err)
+	}
+
+	// the RSA temporary public key of a CSR of the CFCA SADK
+	block, _ = pem.Decode([]byte(rsaSignedCSR))
+	if csr, err = ParseCertificateRequest(block.Bytes); err != nil {
+		t.Fatal(err)
+	}
+	if cfcaCSR, err = ParseCFCACertificateRequest(block.Bytes); err != nil {
+		t.Fatal(err)
+	}
+	rsaAttrs, err := csr.UnhandledAttributes()
+	if err != nil || len(rsaAttrs) != 2 {
+		t.Fatalf("unexpected attributes %v: %v", rsaAttrs, err)
+	}
+	if tmpPub, err := rsaAttrs[1].CFCATemporaryPublicKey(csr.PublicKeyAlgorithm); err != nil || !cfcaCSR.TmpPublicKey.(*rsa.PublicKey).Equal(tmpPub) {
+		t.Errorf("unexpected temporary public key %v: %v", tmpPub, err)
+	}
+
+	for _, opts := range []*CreateCertificateRequestOptions{
+		{ExtraAttributes: []RawAttribute{{Type: oidExtensionRequest, Value: asn1.RawValue{FullBytes: []byte{0x31, 0x00}}}}},
+		{ChallengePassword: "secret", ExtraAttributes: attrs[:1]},
+	} {
+		if _, err := CreateCertificateRequestWithOptions(rand.Reader, template, priv, opts); err == nil {
+			t.Errorf("expected error for attributes %v", opts.ExtraAttributes)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package smx509
+
+import (
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"io"
+)
+
+// CreateDualCertificateRequests creates the certificate requests of the
+// signing and encryption certificate pair of TLCP, GB/T 38636, with the
+// subject and subject alternative names of template. The request of signKey
+// asks for the digitalSignature key usage, and the one of encKey for
+// keyEncipherment and dataEncipherment. template must not request key usages
+// in ExtraExtensions, and the two keys must be different.
+func CreateDualCertificateRequests(rand io.Reader, template *x509.CertificateRequest, signKey, encKey crypto.Signer) (signCSR, encCSR []byte, err error) {
+	if template == nil {
+		return nil, nil, errors.New("x509: template can not be nil")
+	}
+	if signKey == nil || encKey == nil {
+		return nil, nil, errors.New("x509: signing and encryption keys are required")
+	}
+	signPub, ok := signKey.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok {
+		return nil, nil, errors.New("x509: unsupported public key type")
+	}
+	if signPub.Equal(encKey.Public()) {
+		return nil, nil, errors.New("x509: signing and encryption keys must be different")
+	}
+
+	signCSR, err = CreateCertificateRequestWithOptions(rand, template, signKey, &CreateCertificateRequestOptions{
+		Usage: RequestedUsage{KeyUsage: KeyUsageDigitalSignature},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	encCSR, err = CreateCertificateRequestWithOptions(rand, template, encKey, &CreateCertificateRequestOptions{
+		Usage: RequestedUsage{KeyUsage: KeyUsageKeyEncipherment | KeyUsageDataEncipherment},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return signCSR, encCSR, nil
+}
@@ -0,0 +1,74 @@
+package smx509
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+
+	"github.com/yunmoon/gmsm/sm2"
+)
+
+func TestCreateDualCertificateRequests(t *testing.T) {
+	signKey, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encKey, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.CertificateRequest{
+		Subject:  pkix.Name{Country: []string{"CN"}, Organization: []string{"GM"}, CommonName: "tlcp.example.com"},
+		DNSNames: []string{"tlcp.example.com", "www.tlcp.example.com"},
+	}
+	signDER, encDER, err := CreateDualCertificateRequests(rand.Reader, template, signKey, encKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var csrs []*CertificateRequest
+	for _, der := range [][]byte{signDER, encDER} {
+		csr, err := ParseCertificateRequest(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := csr.CheckSignature(); err != nil {
+			t.Fatal(err)
+		}
+		csrs = append(csrs, csr)
+	}
+	signCSR, encCSR := csrs[0], csrs[1]
+	if !signKey.PublicKey.Equal(signCSR.PublicKey) || !encKey.PublicKey.Equal(encCSR.PublicKey) {
+		t.Error("unexpected public keys")
+	}
+	if !bytes.Equal(signCSR.RawSubject, encCSR.RawSubject) || signCSR.Subject.CommonName != "tlcp.example.com" {
+		t.Errorf("unexpected subjects %v and %v", signCSR.Subject, encCSR.Subject)
+	}
+	for _, csr := range csrs {
+		if len(csr.DNSNames) != 2 || csr.DNSNames[1] != "www.tlcp.example.com" {
+			t.Errorf("unexpected DNS names %v", csr.DNSNames)
+		}
+	}
+	if usage, err := signCSR.RequestedUsage(); err != nil || usage.KeyUsage != KeyUsageDigitalSignature {
+		t.Errorf("unexpected signing key usage %+v: %v", usage, err)
+	}
+	if usage, err := encCSR.RequestedUsage(); err != nil || usage.KeyUsage != KeyUsageKeyEncipherment|KeyUsageDataEncipherment {
+		t.Errorf("unexpected encryption key usage %+v: %v", usage, err)
+	}
+
+	if _, _, err := CreateDualCertificateRequests(rand.Reader, template, signKey, signKey); err == nil {
+		t.Error("expected error for the same key twice")
+	}
+	sameKey, err := sm2.NewPrivateKeyFromInt(signKey.D)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := CreateDualCertificateRequests(rand.Reader, template, signKey, sameKey); err == nil {
+		t.Error("expected error for the same public key twice")
+	}
+	if _, _, err := CreateDualCertificateRequests(rand.Reader, template, signKey, nil); err == nil {
+		t.Error("expected error without an encryption key")
+	}
+}
@@ -0,0 +1,202 @@
+package smx509
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/yunmoon/gmsm/sm2"
+)
+
+// CSRValidationPolicy decides which certificate requests
+// ValidateCertificateRequest accepts. The lists allow anything of their kind
+// if empty, and the zero value only rejects the requested subject alternative
+// names and CA certificates.
+type CSRValidationPolicy struct {
+	// PublicKeyAlgorithms are the allowed public key algorithms. SM2 is
+	// reported for the keys on the SM2 curve, and ECDSA for the others.
+	PublicKeyAlgorithms []PublicKeyAlgorithm
+	// MinRSABits is the minimum size of the RSA keys.
+	MinRSABits int
+	// ECDSACurves are the allowed curves of the ECDSA keys.
+	ECDSACurves []elliptic.Curve
+
+	// SignatureAlgorithms are the allowed algorithms of the request
+	// signature, which is always checked.
+	SignatureAlgorithms []SignatureAlgorithm
+
+	// AllowDNSNames, AllowEmailAddresses, AllowIPAddresses, AllowURIs and
+	// AllowOtherNames select the subject alternative name types which may be
+	// requested. The other types are always rejected.
+	AllowDNSNames       bool
+	AllowEmailAddresses bool
+	AllowIPAddresses    bool
+	AllowURIs           bool
+	AllowOtherNames     bool
+
+	// SubjectAttributes are the allowed attribute types of the subject.
+	SubjectAttributes []asn1.ObjectIdentifier
+
+	// ForbiddenExtensions are the OIDs of the extensions which must not be
+	// requested.
+	ForbiddenExtensions []asn1.ObjectIdentifier
+	// ExtKeyUsages are the extended key usages which may be requested. If
+	// not empty, unknown extended key usages are rejected.
+	ExtKeyUsages []ExtKeyUsage
+	// AllowCA allows requesting a CA certificate in the basic constraints.
+	AllowCA bool
+}
+
+// Subject attribute types of DefaultCSRValidationPolicy.
+var (
+	oidAttributeCommonName         = asn1.ObjectIdentifier{2, 5, 4, 3}
+	oidAttributeSerialNumber       = asn1.ObjectIdentifier{2, 5, 4, 5}
+	oidAttributeCountry            = asn1.ObjectIdentifier{2, 5, 4, 6}
+	oidAttributeLocality           = asn1.ObjectIdentifier{2, 5, 4, 7}
+	oidAttributeProvince           = asn1.ObjectIdentifier{2, 5, 4, 8}
+	oidAttributeOrganization       = asn1.ObjectIdentifier{2, 5, 4, 10}
+	oidAttributeOrganizationalUnit = asn1.ObjectIdentifier{2, 5, 4, 11}
+	oidAttributeEmailAddress       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 1}
+)
+
+// DefaultCSRValidationPolicy returns the policy of a GM subscriber profile:
+// SM2 keys and SM2WithSM3 signatures only, DNS names, email addresses and IP
+// addresses, the usual subject attributes, no CA certificate and none of the
+// extensions which only belong to CA certificates. The policy may be adjusted
+// by the caller.
+func DefaultCSRValidationPolicy() *CSRValidationPolicy {
+	return &CSRValidationPolicy{
+		PublicKeyAlgorithms: []PublicKeyAlgorithm{SM2},
+		MinRSABits:          2048,
+		SignatureAlgorithms: []SignatureAlgorithm{SM2WithSM3},
+		AllowDNSNames:       true,
+		AllowEmailAddresses: true,
+		AllowIPAddresses:    true,
+		SubjectAttributes: []asn1.ObjectIdentifier{
+			oidAttributeCountry,
+			oidAttributeProvince,
+			oidAttributeLocality,
+			oidAttributeOrganization,
+			oidAttributeOrganizationalUnit,
+			oidAttributeCommonName,
+			oidAttributeSerialNumber,
+			oidAttributeEmailAddress,
+		},
+		ForbiddenExtensions: []asn1.ObjectIdentifier{
+			oidExtensionNameConstraints,
+			oidExtensionPolicyMappings,
+			oidExtensionPolicyConstraints,
+			oidExtensionInhibitAnyPolicy,
+		},
+	}
+}
+
+// ValidateCertificateRequest checks the signature of csr, and that it
+// complies with policy, DefaultCSRValidationPolicy if nil. It returns the
+// violations joined, or nil if there is none.
+func ValidateCertificateRequest(csr *CertificateRequest, policy *CSRValidationPolicy) error {
+	if csr == nil {
+		return errors.New("x509: certificate request can not be nil")
+	}
+	if policy == nil {
+		policy = DefaultCSRValidationPolicy()
+	}
+	var errs []error
+	violation := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("x509: "+format, args...))
+	}
+
+	if len(policy.PublicKeyAlgorithms) > 0 && !slices.Contains(policy.PublicKeyAlgorithms, csr.PublicKeyAlgorithm) {
+		violation("public key algorithm %s is not allowed", PublicKeyAlgorithmString(csr.PublicKeyAlgorithm))
+	}
+	switch pub := csr.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if bits := pub.N.BitLen(); bits < policy.MinRSABits {
+			violation("RSA key of %d bits is smaller than %d bits", bits, policy.MinRSABits)
+		}
+	case *ecdsa.PublicKey:
+		if pub.Curve != sm2.P256() && len(policy.ECDSACurves) > 0 && !slices.Contains(policy.ECDSACurves, pub.Curve) {
+			violation("ECDSA curve %s is not allowed", pub.Curve.Params().Name)
+		}
+	}
+
+	if len(policy.SignatureAlgorithms) > 0 && !slices.Contains(policy.SignatureAlgorithms, csr.SignatureAlgorithm) {
+		violation("signature algorithm %s is not allowed", signatureAlgorithmName(csr.SignatureAlgorithm))
+	}
+	if err := csr.CheckSignature(); err != nil {
+		violation("invalid signature: %w", err)
+	}
+
+	if !policy.AllowDNSNames && len(csr.DNSNames) > 0 {
+		violation("DNS names %q are not allowed", csr.DNSNames)
+	}
+	if !policy.AllowEmailAddresses && len(csr.EmailAddresses) > 0 {
+		violation("email addresses %q are not allowed", csr.EmailAddresses)
+	}
+	if !policy.AllowIPAddresses && len(csr.IPAddresses) > 0 {
+		violation("IP addresses %v are not allowed", csr.IPAddresses)
+	}
+	if !policy.AllowURIs && len(csr.URIs) > 0 {
+		violation("URIs %v are not allowed", csr.URIs)
+	}
+	if otherNames, err := csr.OtherNames(); err != nil {
+		errs = append(errs, err)
+	} else if !policy.AllowOtherNames {
+		for _, name := range otherNames {
+			violation("otherName %v is not allowed", name.TypeID)
+		}
+	}
+	if unhandled, err := csr.UnhandledSANs(); err != nil {
+		errs = append(errs, err)
+	} else if len(unhandled) > 0 {
+		violation("%d subject alternative names of unsupported types are not allowed", len(unhandled))
+	}
+
+	if len(policy.SubjectAttributes) > 0 {
+		for _, atv := range csr.Subject.Names {
+			if !slices.ContainsFunc(policy.SubjectAttributes, atv.Type.Equal) {
+				violation("subject attribute %v is not allowed", atv.Type)
+			}
+		}
+	}
+
+	for _, e := range csr.Extensions {
+		if slices.ContainsFunc(policy.ForbiddenExtensions, e.Id.Equal) {
+			violation("extension %v is not allowed", e.Id)
+		}
+	}
+	usage, err := csr.RequestedUsage()
+	if err != nil {
+		errs = append(errs, err)
+	} else {
+		if len(policy.ExtKeyUsages) > 0 {
+			for _, u := range usage.ExtKeyUsage {
+				if !slices.Contains(policy.ExtKeyUsages, u) {
+					oid, _ := oidFromExtKeyUsage(u)
+					violation("extended key usage %v is not allowed", oid)
+				}
+			}
+			for _, oid := range usage.UnknownExtKeyUsage {
+				violation("extended key usage %v is not allowed", oid)
+			}
+		}
+		if usage.IsCA && !policy.AllowCA {
+			violation("CA certificates are not allowed")
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// signatureAlgorithmName returns the name of algo, which String doesn't know
+// for SM2WithSM3 and the registered algorithms.
+func signatureAlgorithmName(algo SignatureAlgorithm) string {
+	if details, ok := LookupSignatureAlgorithm(algo); ok {
+		return details.Name
+	}
+	return algo.String()
+}
@@ -0,0 +1,260 @@
+package smx509
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/yunmoon/gmsm/sm2"
+)
+
+func TestValidateCertificateRequest(t *testing.T) {
+	sm2Key, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p256Key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject := pkix.Name{Country: []string{"CN"}, Organization: []string{"GM"}, CommonName: "device.example.com"}
+	names := &x509.CertificateRequest{
+		Subject:        subject,
+		DNSNames:       []string{"device.example.com"},
+		EmailAddresses: []string{"admin@example.com"},
+	}
+
+	tests := []struct {
+		name      string
+		priv      crypto.Signer
+		template  *x509.CertificateRequest
+		opts      *CreateCertificateRequestOptions
+		policy    func(*CSRValidationPolicy)
+		nilPolicy bool
+		tamper    bool
+		want      []string
+	}{
+		{
+			name:     "valid",
+			priv:     sm2Key,
+			template: names,
+		},
+		{
+			name:      "nil policy",
+			priv:      sm2Key,
+			template:  names,
+			nilPolicy: true,
+		},
+		{
+			name:     "SM2 required",
+			priv:     p256Key,
+			template: names,
+			want:     []string{"public key algorithm ECDSA is not allowed", "signature algorithm ECDSA-SHA256 is not allowed"},
+		},
+		{
+			name:     "SM2-SM3 not allowed",
+			priv:     sm2Key,
+			template: names,
+			policy: func(p *CSRValidationPolicy) {
+				p.SignatureAlgorithms = []SignatureAlgorithm{ECDSAWithSHA256}
+			},
+			want: []string{"x509: signature algorithm SM2-SM3 is not allowed"},
+		},
+		{
+			name:     "weak RSA key",
+			priv:     rsaKey,
+			template: names,
+			policy: func(p *CSRValidationPolicy) {
+				p.PublicKeyAlgorithms = []PublicKeyAlgorithm{RSA}
+				p.SignatureAlgorithms = nil
+			},
+			want: []string{"RSA key of 1024 bits is smaller than 2048 bits"},
+		},
+		{
+			name:     "ECDSA curve",
+			priv:     p384Key,
+			template: names,
+			policy: func(p *CSRValidationPolicy) {
+				p.PublicKeyAlgorithms = nil
+				p.SignatureAlgorithms = nil
+				p.ECDSACurves = []elliptic.Curve{elliptic.P256()}
+			},
+			want: []string{"ECDSA curve P-384 is not allowed"},
+		},
+		{
+			name:     "any key",
+			priv:     p384Key,
+			template: names,
+			policy: func(p *CSRValidationPolicy) {
+				p.PublicKeyAlgorithms = nil
+				p.SignatureAlgorithms = nil
+			},
+		},
+		{
+			name:     "invalid signature",
+			priv:     sm2Key,
+			template: names,
+			tamper:   true,
+			want:     []string{"invalid signature"},
+		},
+		{
+			name: "SAN types",
+			priv: sm2Key,
+			template: &x509.CertificateRequest{
+				Subject: subject,
+				URIs:    []*url.URL{{Scheme: "https", Host: "example.com"}},
+			},
+			opts: &CreateCertificateRequestOptions{OtherNames: []OtherName{{TypeID: oidUPN, Value: asn1.RawValue{Tag: asn1.TagUTF8String, Bytes: []byte("device@example.com")}}}},
+			want: []string{`URIs [https://example.com] are not allowed`, "otherName 1.3.6.1.4.1.311.20.2.3 is not allowed"},
+		},
+		{
+			name: "SAN types allowed",
+			priv: sm2Key,
+			template: &x509.CertificateRequest{
+				Subject: subject,
+				URIs:    []*url.URL{{Scheme: "https", Host: "example.com"}},
+			},
+			opts: &CreateCertificateRequestOptions{OtherNames: []OtherName{{TypeID: oidUPN, Value: asn1.RawValue{Tag: asn1.TagUTF8String, Bytes: []byte("device@example.com")}}}},
+			policy: func(p *CSRValidationPolicy) {
+				p.AllowURIs = true
+				p.AllowOtherNames = true
+			},
+		},
+		{
+			name:     "subject attribute",
+			priv:     sm2Key,
+			template: &x509.CertificateRequest{Subject: pkix.Name{CommonName: "device", StreetAddress: []string{"1 Main Street"}}},
+			want:     []string{"subject attribute 2.5.4.9 is not allowed"},
+		},
+		{
+			name:     "extended key usage",
+			priv:     sm2Key,
+			template: names,
+			opts: &CreateCertificateRequestOptions{Usage: RequestedUsage{
+				ExtKeyUsage:        []ExtKeyUsage{ExtKeyUsageServerAuth, ExtKeyUsageCodeSigning},
+				UnknownExtKeyUsage: []asn1.ObjectIdentifier{{1, 2, 156, 10197, 1, 999}},
+			}},
+			policy: func(p *CSRValidationPolicy) {
+				p.ExtKeyUsages = []ExtKeyUsage{ExtKeyUsageServerAuth, ExtKeyUsageClientAuth}
+			},
+			want: []string{"extended key usage 1.3.6.1.5.5.7.3.3 is not allowed", "extended key usage 1.2.156.10197.1.999 is not allowed"},
+		},
+		{
+			name:     "any extended key usage",
+			priv:     sm2Key,
+			template: names,
+			opts: &CreateCertificateRequestOptions{Usage: RequestedUsage{
+				ExtKeyUsage:        []ExtKeyUsage{ExtKeyUsageCodeSigning},
+				UnknownExtKeyUsage: []asn1.ObjectIdentifier{{1, 2, 156, 10197, 1, 999}},
+			}},
+		},
+		{
+			name:     "CA",
+			priv:     sm2Key,
+			template: names,
+			opts:     &CreateCertificateRequestOptions{Usage: RequestedUsage{BasicConstraintsValid: true, IsCA: true}},
+			want:     []string{"CA certificates are not allowed"},
+		},
+		{
+			name:     "CA allowed",
+			priv:     sm2Key,
+			template: names,
+			opts:     &CreateCertificateRequestOptions{Usage: RequestedUsage{BasicConstraintsValid: true, IsCA: true}},
+			policy:   func(p *CSRValidationPolicy) { p.AllowCA = true },
+		},
+		{
+			name:     "forbidden extension",
+			priv:     sm2Key,
+			template: &x509.CertificateRequest{Subject: subject, ExtraExtensions: []pkix.Extension{{Id: oidExtensionNameConstraints, Critical: true, Value: []byte{0x30, 0x00}}}},
+			want:     []string{"extension 2.5.29.30 is not allowed"},
+		},
+		{
+			name: "every violation",
+			priv: p256Key,
+			template: &x509.CertificateRequest{
+				Subject: pkix.Name{CommonName: "device", StreetAddress: []string{"1 Main Street"}},
+				URIs:    []*url.URL{{Scheme: "https", Host: "example.com"}},
+			},
+			opts: &CreateCertificateRequestOptions{Usage: RequestedUsage{BasicConstraintsValid: true, IsCA: true}},
+			want: []string{
+				"public key algorithm ECDSA is not allowed",
+				"signature algorithm ECDSA-SHA256 is not allowed",
+				"URIs [https://example.com] are not allowed",
+				"subject attribute 2.5.4.9 is not allowed",
+				"CA certificates are not allowed",
+			},
+		},
+		{
+			name:     "zero policy",
+			priv:     p384Key,
+			template: &x509.CertificateRequest{Subject: pkix.Name{StreetAddress: []string{"1 Main Street"}}, DNSNames: []string{"device.example.com"}},
+			policy: func(p *CSRValidationPolicy) {
+				*p = CSRValidationPolicy{}
+			},
+			want: []string{`DNS names ["device.example.com"] are not allowed`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			der, err := CreateCertificateRequestWithOptions(rand.Reader, tt.template, tt.priv, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			csr, err := ParseCertificateRequest(der)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.tamper {
+				csr.Signature[len(csr.Signature)-1] ^= 1
+			}
+			policy := DefaultCSRValidationPolicy()
+			if tt.policy != nil {
+				tt.policy(policy)
+			}
+			if tt.nilPolicy {
+				policy = nil
+			}
+
+			err = ValidateCertificateRequest(csr, policy)
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected violations: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected violations")
+			}
+			violations := err.(interface{ Unwrap() []error }).Unwrap()
+			if len(violations) != len(tt.want) {
+				t.Fatalf("got %d violations, want %d: %v", len(violations), len(tt.want), err)
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(violations[i].Error(), want) {
+					t.Errorf("violation %d is %q, want %q", i, violations[i], want)
+				}
+			}
+		})
+	}
+
+	if err := ValidateCertificateRequest(nil, nil); err == nil {
+		t.Error("expected error for a nil request")
+	}
+}
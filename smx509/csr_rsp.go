@@ -157,6 +157,9 @@ func (raw rawCertificates) Parse() ([]*Certificate, error) {
 	if _, err := asn1.Unmarshal(raw.Raw, &val); err != nil {
 		return nil, err
 	}
+	if len(val.Bytes) == 0 {
+		return nil, nil
+	}
 
 	return ParseCertificates(val.Bytes)
 }
@@ -0,0 +1,150 @@
+package smx509
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// CSRPolicy decides what CertificateTemplateFromCSR honors of a certificate
+// request. The zero value honors only the subject.
+type CSRPolicy struct {
+	// AllowDNSNames, AllowEmailAddresses, AllowIPAddresses and AllowURIs
+	// select the requested subject alternative names which are copied. The
+	// other ones, such as otherName, directoryName and registeredID names,
+	// are never copied.
+	AllowDNSNames       bool
+	AllowEmailAddresses bool
+	AllowIPAddresses    bool
+	AllowURIs           bool
+
+	// MaxValidity, if not zero, sets NotBefore to the current time and
+	// NotAfter to MaxValidity later. The caller may shorten it.
+	MaxValidity time.Duration
+
+	// KeyUsage and ExtKeyUsage, if not zero, are the key usages of the
+	// certificate, whatever the request asks for.
+	KeyUsage    KeyUsage
+	ExtKeyUsage []ExtKeyUsage
+
+	// AllowedExtensions are the OIDs of the requested extensions which are
+	// copied to ExtraExtensions, such as basic constraints. The subject
+	// alternative name extension is filtered by the fields above instead, and
+	// the key usage ones are not copied if forced by the policy.
+	AllowedExtensions []asn1.ObjectIdentifier
+
+	// Discarded, if not nil, is called with a description of each requested
+	// name or extension which is dropped.
+	Discarded func(item string)
+}
+
+// CertificateTemplateFromCSR checks the signature of csr and returns a
+// certificate template with the subject of csr, and the names and extensions
+// it requests which policy allows. The public key to certify is
+// csr.PublicKey, and the serial number is left for the caller to set or for
+// CreateCertificate to generate.
+func CertificateTemplateFromCSR(csr *CertificateRequest, policy CSRPolicy) (*x509.Certificate, error) {
+	if csr == nil {
+		return nil, errors.New("x509: certificate request can not be nil")
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, err
+	}
+
+	discard := func(format string, args ...any) {
+		if policy.Discarded != nil {
+			policy.Discarded(fmt.Sprintf(format, args...))
+		}
+	}
+
+	template := &x509.Certificate{
+		RawSubject:  csr.RawSubject,
+		Subject:     csr.Subject,
+		KeyUsage:    policy.KeyUsage,
+		ExtKeyUsage: policy.ExtKeyUsage,
+	}
+	if policy.MaxValidity > 0 {
+		template.NotBefore = time.Now()
+		template.NotAfter = template.NotBefore.Add(policy.MaxValidity)
+	}
+
+	if policy.AllowDNSNames {
+		template.DNSNames = csr.DNSNames
+	} else {
+		for _, name := range csr.DNSNames {
+			discard("DNS name %q", name)
+		}
+	}
+	if policy.AllowEmailAddresses {
+		template.EmailAddresses = csr.EmailAddresses
+	} else {
+		for _, email := range csr.EmailAddresses {
+			discard("email address %q", email)
+		}
+	}
+	if policy.AllowIPAddresses {
+		template.IPAddresses = csr.IPAddresses
+	} else {
+		for _, ip := range csr.IPAddresses {
+			discard("IP address %v", ip)
+		}
+	}
+	if policy.AllowURIs {
+		template.URIs = csr.URIs
+	} else {
+		for _, uri := range csr.URIs {
+			discard("URI %q", uri.String())
+		}
+	}
+
+	otherNames, err := csr.OtherNames()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range otherNames {
+		discard("otherName %v", name.TypeID)
+	}
+	unhandled, err := csr.UnhandledSANs()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range unhandled {
+		discard("%s", describeGeneralName(name))
+	}
+
+	for _, e := range csr.Extensions {
+		switch {
+		case e.Id.Equal(oidExtensionSubjectAltName):
+		case e.Id.Equal(oidExtensionKeyUsage) && policy.KeyUsage != 0,
+			e.Id.Equal(oidExtensionExtendedKeyUsage) && len(policy.ExtKeyUsage) > 0:
+			discard("extension %v, overridden by the policy", e.Id)
+		case slices.ContainsFunc(policy.AllowedExtensions, e.Id.Equal):
+			template.ExtraExtensions = append(template.ExtraExtensions, e)
+		default:
+			discard("extension %v", e.Id)
+		}
+	}
+	return template, nil
+}
+
+// describeGeneralName describes a subject alternative name returned by
+// UnhandledSANs for CSRPolicy.Discarded.
+func describeGeneralName(name asn1.RawValue) string {
+	var rdns pkix.RDNSequence
+	if rest, err := asn1.UnmarshalWithParams(name.FullBytes, &rdns, "explicit,tag:4"); err == nil && len(rest) == 0 {
+		return fmt.Sprintf("directoryName %q", rdns.String())
+	}
+	var oid asn1.ObjectIdentifier
+	if rest, err := asn1.UnmarshalWithParams(name.FullBytes, &oid, "tag:8"); err == nil && len(rest) == 0 {
+		return fmt.Sprintf("registeredID %v", oid)
+	}
+	tag := name.Tag
+	if name.IsCompound {
+		tag |= 0x20
+	}
+	return unhandledNameTypeString(tag)
+}
@@ -0,0 +1,167 @@
+package smx509
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yunmoon/gmsm/sm2"
+)
+
+func TestCertificateTemplateFromCSR(t *testing.T) {
+	key, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	basicConstraints, err := asn1.Marshal(basicConstraints{IsCA: true, MaxPathLen: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	customExt := pkix.Extension{Id: asn1.ObjectIdentifier{1, 2, 3, 4}, Value: []byte{0x05, 0x00}}
+	csrDER, err := CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:        pkix.Name{CommonName: "requester"},
+		DNSNames:       []string{"www.example.com"},
+		EmailAddresses: []string{"admin@example.com"},
+		ExtraExtensions: []pkix.Extension{
+			{Id: oidExtensionBasicConstraints, Critical: true, Value: basicConstraints},
+			customExt,
+		},
+	}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr, err := ParseCertificateRequest(csrDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var discarded []string
+	policy := CSRPolicy{
+		AllowDNSNames:     true,
+		MaxValidity:       24 * time.Hour,
+		KeyUsage:          KeyUsageDigitalSignature,
+		ExtKeyUsage:       []ExtKeyUsage{ExtKeyUsageServerAuth},
+		AllowedExtensions: []asn1.ObjectIdentifier{customExt.Id},
+		Discarded:         func(item string) { discarded = append(discarded, item) },
+	}
+	template, err := CertificateTemplateFromCSR(csr, policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`email address "admin@example.com"`, "extension 2.5.29.19"}
+	if !reflect.DeepEqual(discarded, want) {
+		t.Errorf("discarded %q, want %q", discarded, want)
+	}
+	if !reflect.DeepEqual(template.DNSNames, csr.DNSNames) || len(template.EmailAddresses) != 0 {
+		t.Errorf("unexpected names %v %v", template.DNSNames, template.EmailAddresses)
+	}
+	if template.NotAfter.Sub(template.NotBefore) != policy.MaxValidity {
+		t.Errorf("unexpected validity %v - %v", template.NotBefore, template.NotAfter)
+	}
+	if !reflect.DeepEqual(template.ExtraExtensions, []pkix.Extension{customExt}) {
+		t.Errorf("unexpected extensions %v", template.ExtraExtensions)
+	}
+
+	caKey, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "SM2 CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(48 * time.Hour),
+		KeyUsage:              KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := CreateCertificate(rand.Reader, template, caTemplate, csr.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.IsCA || oidInExtensions(oidExtensionBasicConstraints, cert.Extensions) {
+		t.Error("the requested basic constraints are not stripped")
+	}
+	if cert.KeyUsage != KeyUsageDigitalSignature || cert.Subject.CommonName != "requester" {
+		t.Errorf("unexpected certificate %v %v", cert.KeyUsage, cert.Subject)
+	}
+
+	// the request is checked first
+	csr.Signature[len(csr.Signature)-1] ^= 1
+	if _, err := CertificateTemplateFromCSR(csr, policy); err == nil {
+		t.Error("expected error with an invalid signature")
+	}
+}
+
+func TestCertificateTemplateFromCSRUnhandledSANs(t *testing.T) {
+	key, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherName, err := marshalOtherName(OtherName{
+		TypeID: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 20, 2, 3},
+		Value:  asn1.RawValue{Tag: asn1.TagUTF8String, Bytes: []byte("user@example.com")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	directoryName, err := asn1.Marshal(pkix.Name{CommonName: "directory"}.ToRDNSequence())
+	if err != nil {
+		t.Fatal(err)
+	}
+	registeredID, err := asn1.Marshal(asn1.ObjectIdentifier{1, 2, 3, 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	san, err := asn1.Marshal([]asn1.RawValue{
+		{Class: asn1.ClassContextSpecific, Tag: nameTypeDNS, Bytes: []byte("www.example.com")},
+		otherName,
+		{Class: asn1.ClassContextSpecific, Tag: 4, IsCompound: true, Bytes: directoryName},
+		{Class: asn1.ClassContextSpecific, Tag: nameTypeRegisteredID, Bytes: registeredID[2:]},
+		{Class: asn1.ClassContextSpecific, Tag: 5, IsCompound: true, Bytes: []byte{0xa1, 0x02, 0x0c, 0x00}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	csrDER, err := CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:         pkix.Name{CommonName: "requester"},
+		ExtraExtensions: []pkix.Extension{{Id: oidExtensionSubjectAltName, Value: san}},
+	}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr, err := ParseCertificateRequest(csrDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var discarded []string
+	template, err := CertificateTemplateFromCSR(csr, CSRPolicy{
+		AllowDNSNames: true,
+		Discarded:     func(item string) { discarded = append(discarded, item) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"otherName 1.3.6.1.4.1.311.20.2.3",
+		`directoryName "CN=directory"`,
+		"registeredID 1.2.3.5",
+		"ediPartyName",
+	}
+	if !reflect.DeepEqual(discarded, want) {
+		t.Errorf("discarded %q, want %q", discarded, want)
+	}
+	if !reflect.DeepEqual(template.DNSNames, []string{"www.example.com"}) || len(template.ExtraExtensions) != 0 {
+		t.Errorf("unexpected names %v and extensions %v", template.DNSNames, template.ExtraExtensions)
+	}
+}
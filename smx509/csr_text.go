@@ -0,0 +1,327 @@
+package smx509
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/yunmoon/gmsm/sm2"
+	"golang.org/x/crypto/cryptobyte"
+	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+// The number of bytes of each line of the hex dumps of
+// CertificateRequestText, as in OpenSSL.
+const (
+	textHexBytesPerLine    = 18
+	textKeyHexBytesPerLine = 15
+)
+
+var (
+	oidUnstructuredName = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 2}
+
+	textAttributeNames = []struct {
+		oid  asn1.ObjectIdentifier
+		name string
+	}{
+		{oidChallengePassword, "challengePassword"},
+		{oidUnstructuredName, "unstructuredName"},
+	}
+
+	textExtensionNames = []struct {
+		oid  asn1.ObjectIdentifier
+		name string
+	}{
+		{oidExtensionSubjectAltName, "X509v3 Subject Alternative Name"},
+		{oidExtensionKeyUsage, "X509v3 Key Usage"},
+		{oidExtensionExtendedKeyUsage, "X509v3 Extended Key Usage"},
+		{oidExtensionBasicConstraints, "X509v3 Basic Constraints"},
+		{oidExtensionSubjectKeyId, "X509v3 Subject Key Identifier"},
+	}
+
+	textKeyUsageNames = []string{
+		"Digital Signature",
+		"Non Repudiation",
+		"Key Encipherment",
+		"Data Encipherment",
+		"Key Agreement",
+		"Certificate Sign",
+		"CRL Sign",
+		"Encipher Only",
+		"Decipher Only",
+	}
+
+	textExtKeyUsageNames = map[ExtKeyUsage]string{
+		ExtKeyUsageAny:             "Any Extended Key Usage",
+		ExtKeyUsageServerAuth:      "TLS Web Server Authentication",
+		ExtKeyUsageClientAuth:      "TLS Web Client Authentication",
+		ExtKeyUsageCodeSigning:     "Code Signing",
+		ExtKeyUsageEmailProtection: "E-mail Protection",
+		ExtKeyUsageIPSECEndSystem:  "IPSec End System",
+		ExtKeyUsageIPSECTunnel:     "IPSec Tunnel",
+		ExtKeyUsageIPSECUser:       "IPSec User",
+		ExtKeyUsageTimeStamping:    "Time Stamping",
+		ExtKeyUsageOCSPSigning:     "OCSP Signing",
+	}
+)
+
+// CertificateRequestText returns a human readable rendering of csr, in the
+// style of "openssl req -text": the subject, the public key, the attributes,
+// the requested extensions and the signature. The public key algorithm is
+// "SM2" for the keys on the SM2 curve and the signature algorithm is named
+// as by LookupSignatureAlgorithm, "SM2-SM3" for SM2WithSM3.
+//
+// The attributes and extensions which can not be decoded are dumped in hex,
+// so that any parsed request can be rendered. The output only depends on
+// csr.
+func CertificateRequestText(csr *CertificateRequest) (string, error) {
+	if csr == nil {
+		return "", errors.New("x509: certificate request can not be nil")
+	}
+	var b strings.Builder
+	line := func(indent int, format string, args ...any) {
+		b.WriteString(strings.Repeat(" ", indent))
+		fmt.Fprintf(&b, format, args...)
+		b.WriteByte('\n')
+	}
+	hexDumpWidth := func(indent, width int, data []byte) {
+		if len(data) == 0 {
+			line(indent, "<empty>")
+		}
+		for len(data) > 0 {
+			n := min(len(data), width)
+			s := hexBytes(data[:n])
+			if data = data[n:]; len(data) > 0 {
+				s += ":"
+			}
+			line(indent, "%s", s)
+		}
+	}
+	hexDump := func(indent int, data []byte) {
+		hexDumpWidth(indent, textHexBytesPerLine, data)
+	}
+
+	line(0, "Certificate Request:")
+	line(4, "Data:")
+	line(8, "Version: %d (%#x)", csr.Version+1, csr.Version)
+	line(8, "Subject: %s", csr.Subject.String())
+	line(8, "Subject Public Key Info:")
+	line(12, "Public Key Algorithm: %s", PublicKeyAlgorithmString(csr.PublicKeyAlgorithm))
+	switch pub := csr.PublicKey.(type) {
+	case *rsa.PublicKey:
+		line(16, "Public-Key: (%d bit)", pub.N.BitLen())
+		line(16, "Modulus:")
+		hexDumpWidth(20, textKeyHexBytesPerLine, pub.N.Bytes())
+		line(16, "Exponent: %d (%#x)", pub.E, pub.E)
+	case *ecdsa.PublicKey:
+		curveName := "unknown"
+		if pub.Curve == sm2.P256() {
+			curveName = "SM2"
+		} else if pub.Curve != nil {
+			curveName = pub.Curve.Params().Name
+		}
+		if pub.Curve != nil {
+			line(16, "Public-Key: (%d bit)", pub.Curve.Params().BitSize)
+		}
+		line(16, "pub:")
+		hexDumpWidth(20, textKeyHexBytesPerLine, subjectPublicKeyBytes(csr.RawSubjectPublicKeyInfo))
+		line(16, "Curve: %s", curveName)
+	case ed25519.PublicKey:
+		line(16, "Public-Key: (256 bit)")
+		line(16, "pub:")
+		hexDumpWidth(20, textKeyHexBytesPerLine, pub)
+	default:
+		line(16, "Unknown Public Key:")
+		hexDumpWidth(20, textKeyHexBytesPerLine, subjectPublicKeyBytes(csr.RawSubjectPublicKeyInfo))
+	}
+
+	line(8, "Attributes:")
+	attributes, err := parseCSRAttributes(csr.RawTBSCertificateRequest)
+	if err != nil {
+		line(12, "<%v>", err)
+	}
+	for _, attr := range attributes {
+		if attr.Type.Equal(oidExtensionRequest) {
+			continue
+		}
+		name := attr.Type.String()
+		for _, n := range textAttributeNames {
+			if n.oid.Equal(attr.Type) {
+				name = n.name
+			}
+		}
+		values := attr.Values
+		if values.PeekASN1Tag(cryptobyte_asn1.SET) && !values.ReadASN1(&values, cryptobyte_asn1.SET) {
+			line(12, "%-24s :<invalid>", name)
+			continue
+		}
+		if values.Empty() {
+			line(12, "%-24s :<empty>", name)
+		}
+		for !values.Empty() {
+			var value cryptobyte.String
+			var tag cryptobyte_asn1.Tag
+			if !values.ReadAnyASN1(&value, &tag) {
+				line(12, "%-24s :<invalid>", name)
+				break
+			}
+			if s, err := parseASN1String(tag, value); err == nil {
+				line(12, "%-24s :%s", name, s)
+			} else {
+				line(12, "%-24s :", name)
+				hexDump(16, value)
+			}
+		}
+	}
+
+	if len(csr.Extensions) > 0 {
+		line(8, "Requested Extensions:")
+	}
+	for _, e := range csr.Extensions {
+		name := e.Id.String()
+		for _, n := range textExtensionNames {
+			if n.oid.Equal(e.Id) {
+				name = n.name
+			}
+		}
+		if e.Critical {
+			line(12, "%s: critical", name)
+		} else {
+			line(12, "%s:", name)
+		}
+		if s, ok := extensionText(e.Id, e.Value); ok {
+			line(16, "%s", s)
+		} else {
+			hexDump(16, e.Value)
+		}
+	}
+
+	line(4, "Signature Algorithm: %s", signatureAlgorithmName(csr.SignatureAlgorithm))
+	line(4, "Signature Value:")
+	hexDump(8, csr.Signature)
+	return b.String(), nil
+}
+
+// extensionText returns the decoded value of the extensions known to
+// CertificateRequestText, and whether it could be decoded.
+func extensionText(oid asn1.ObjectIdentifier, value []byte) (string, bool) {
+	var items []string
+	switch {
+	case oid.Equal(oidExtensionSubjectAltName):
+		err := forEachSAN(value, func(tag int, data []byte) error {
+			switch tag {
+			case nameTypeOtherName:
+				var typeID asn1.ObjectIdentifier
+				var v cryptobyte.String
+				var vTag cryptobyte_asn1.Tag
+				s := cryptobyte.String(data)
+				if !s.ReadASN1ObjectIdentifier(&typeID) ||
+					!s.ReadASN1(&s, cryptobyte_asn1.Tag(0).ContextSpecific().Constructed()) ||
+					!s.ReadAnyASN1(&v, &vTag) {
+					return errors.New("invalid otherName")
+				}
+				if str, err := parseASN1String(vTag, v); err == nil {
+					items = append(items, fmt.Sprintf("othername: %v:%s", typeID, str))
+				} else {
+					items = append(items, fmt.Sprintf("othername: %v:%s", typeID, hexBytes(v)))
+				}
+			case nameTypeEmail:
+				items = append(items, "email:"+string(data))
+			case nameTypeDNS:
+				items = append(items, "DNS:"+string(data))
+			case nameTypeURI:
+				items = append(items, "URI:"+string(data))
+			case nameTypeIP:
+				if len(data) != net.IPv4len && len(data) != net.IPv6len {
+					return errors.New("invalid IP address")
+				}
+				items = append(items, "IP Address:"+net.IP(data).String())
+			default:
+				items = append(items, fmt.Sprintf("[%d]:%s", tag&^0x20, hexBytes(data)))
+			}
+			return nil
+		})
+		if err != nil {
+			return "", false
+		}
+	case oid.Equal(oidExtensionKeyUsage):
+		usage, err := parseKeyUsageExtension(value)
+		if err != nil {
+			return "", false
+		}
+		for i, name := range textKeyUsageNames {
+			if usage&(1<<i) != 0 {
+				items = append(items, name)
+			}
+		}
+	case oid.Equal(oidExtensionExtendedKeyUsage):
+		usages, unknown, err := parseExtKeyUsageExtension(value)
+		if err != nil {
+			return "", false
+		}
+		for _, u := range usages {
+			if name, ok := textExtKeyUsageNames[u]; ok {
+				items = append(items, name)
+			} else {
+				eku, _ := oidFromExtKeyUsage(u)
+				items = append(items, eku.String())
+			}
+		}
+		for _, u := range unknown {
+			items = append(items, u.String())
+		}
+	case oid.Equal(oidExtensionBasicConstraints):
+		isCA, maxPathLen, err := parseBasicConstraintsExtension(value)
+		if err != nil {
+			return "", false
+		}
+		if isCA {
+			items = append(items, "CA:TRUE")
+		} else {
+			items = append(items, "CA:FALSE")
+		}
+		if maxPathLen >= 0 {
+			items = append(items, fmt.Sprintf("pathlen:%d", maxPathLen))
+		}
+	case oid.Equal(oidExtensionSubjectKeyId):
+		s := cryptobyte.String(value)
+		var keyID cryptobyte.String
+		if !s.ReadASN1(&keyID, cryptobyte_asn1.OCTET_STRING) || !s.Empty() {
+			return "", false
+		}
+		items = append(items, hexBytes(keyID))
+	default:
+		return "", false
+	}
+	return strings.Join(items, ", "), true
+}
+
+// subjectPublicKeyBytes returns the subjectPublicKey of the
+// SubjectPublicKeyInfo spki, or nil if it is malformed.
+func subjectPublicKeyBytes(spki []byte) []byte {
+	s := cryptobyte.String(spki)
+	var key asn1.BitString
+	if !s.ReadASN1(&s, cryptobyte_asn1.SEQUENCE) ||
+		!s.SkipASN1(cryptobyte_asn1.SEQUENCE) ||
+		!s.ReadASN1BitString(&key) {
+		return nil
+	}
+	return key.Bytes
+}
+
+// hexBytes returns the colon-separated lower case hex encoding of data.
+func hexBytes(data []byte) string {
+	var b strings.Builder
+	for i, c := range data {
+		if i > 0 {
+			b.WriteByte(':')
+		}
+		fmt.Fprintf(&b, "%02x", c)
+	}
+	return b.String()
+}
@@ -0,0 +1,130 @@
+package smx509
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/yunmoon/gmsm/sm2"
+)
+
+const opensslUTF8ChallengeCSRText = `Certificate Request:
+    Data:
+        Version: 1 (0x0)
+        Subject: CN=device.example.com,O=GM SCEP Test,C=CN
+        Subject Public Key Info:
+            Public Key Algorithm: SM2
+                Public-Key: (256 bit)
+                pub:
+                    04:33:f7:73:3d:14:17:8a:e9:db:5a:77:a1:bb:47:
+                    aa:dd:57:75:2c:a0:c1:e9:1f:26:0e:81:cc:29:f5:
+                    82:cf:60:4e:eb:04:ce:1c:13:65:ee:41:07:8c:80:
+                    96:86:1c:ce:da:5e:81:8f:53:fa:23:da:c6:2e:67:
+                    68:68:aa:40:e8
+                Curve: SM2
+        Attributes:
+            challengePassword        :Secret-OTP-1234
+        Requested Extensions:
+            X509v3 Subject Alternative Name:
+                DNS:device.example.com
+            X509v3 Key Usage: critical
+                Digital Signature
+    Signature Algorithm: SM2-SM3
+    Signature Value:
+        30:45:02:20:31:09:63:cb:d3:48:85:84:28:54:af:eb:d5:4d:
+        67:9c:31:d7:86:9c:1d:67:d2:04:0c:74:99:c7:4e:bb:db:f7:
+        02:21:00:bc:e2:31:f0:ae:93:e4:f8:cd:d1:ec:a2:bd:58:4a:
+        b2:8f:e5:8f:aa:99:d0:e2:f7:cd:4b:ab:eb:39:97:e5:a6
+`
+
+func TestCertificateRequestText(t *testing.T) {
+	block, _ := pem.Decode([]byte(opensslUTF8ChallengeCSRPEM))
+	csr, err := ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := CertificateRequestText(csr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != opensslUTF8ChallengeCSRText {
+		t.Errorf("unexpected text:\n%s", text)
+	}
+
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registeredID, err := asn1.Marshal(asn1.ObjectIdentifier{1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.CertificateRequest{
+		Subject:        pkix.Name{CommonName: "GM device"},
+		DNSNames:       []string{"device.example.com"},
+		EmailAddresses: []string{"admin@example.com"},
+		IPAddresses:    []net.IP{net.IPv4(192, 0, 2, 1)},
+		Attributes: []pkix.AttributeTypeAndValueSET{{
+			Type:  oidUnstructuredName,
+			Value: [][]pkix.AttributeTypeAndValue{{{Type: asn1.ObjectIdentifier{1, 2, 3, 4}, Value: "value"}}},
+		}},
+		ExtraExtensions: []pkix.Extension{
+			// undecodable values are dumped in hex
+			{Id: oidExtensionBasicConstraints, Critical: true, Value: []byte{0x30, 0x03, 0x01, 0x01}},
+			{Id: oidExtensionSubjectKeyId, Value: []byte{0x04, 0x08, 0x01}},
+			{Id: asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 999}, Value: []byte{0x05, 0x00}},
+		},
+	}
+	der, err := CreateCertificateRequestWithOptions(rand.Reader, template, priv, &CreateCertificateRequestOptions{
+		OtherNames:        []OtherName{{TypeID: oidUPN, Value: asn1.RawValue{Tag: asn1.TagUTF8String, Bytes: []byte("device@example.com")}}},
+		ExtraSANs:         []asn1.RawValue{{Class: asn1.ClassContextSpecific, Tag: 8, Bytes: registeredID[2:]}},
+		ChallengePassword: "一次性口令",
+		Usage: RequestedUsage{
+			KeyUsage:           KeyUsageDigitalSignature | KeyUsageKeyAgreement,
+			ExtKeyUsage:        []ExtKeyUsage{ExtKeyUsageServerAuth, ExtKeyUsageMicrosoftKernelCodeSigning},
+			UnknownExtKeyUsage: []asn1.ObjectIdentifier{{1, 2, 156, 10197, 1, 998}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if csr, err = ParseCertificateRequest(der); err != nil {
+		t.Fatal(err)
+	}
+	if text, err = CertificateRequestText(csr); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Subject: CN=GM device\n",
+		"Public Key Algorithm: SM2\n",
+		"Curve: SM2\n",
+		"unstructuredName         :\n                30:0c:06:03:2a:03:04:13:05:76:61:6c:75:65\n",
+		"challengePassword        :一次性口令\n",
+		"                DNS:device.example.com, email:admin@example.com, IP Address:192.0.2.1, othername: 1.3.6.1.4.1.311.20.2.3:device@example.com, [8]:2a:03\n",
+		"X509v3 Key Usage: critical\n                Digital Signature, Key Agreement\n",
+		"X509v3 Extended Key Usage:\n                TLS Web Server Authentication, 1.3.6.1.4.1.311.61.1.1, 1.2.156.10197.1.998\n",
+		"X509v3 Basic Constraints: critical\n                30:03:01:01\n",
+		"X509v3 Subject Key Identifier:\n                04:08:01\n",
+		"1.2.156.10197.1.999:\n                05:00\n",
+		"Signature Algorithm: SM2-SM3\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("missing %q in:\n%s", want, text)
+		}
+	}
+
+	// incomplete requests are rendered too
+	for _, csr := range []*CertificateRequest{{}, {PublicKey: priv.Public(), Extensions: []pkix.Extension{{Id: oidExtensionSubjectAltName, Value: []byte{0x30, 0x02, 0x87, 0x01}}}}} {
+		if _, err := CertificateRequestText(csr); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := CertificateRequestText(nil); err == nil {
+		t.Error("expected error for a nil request")
+	}
+}
@@ -0,0 +1,84 @@
+package smx509
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+)
+
+// RequestedUsage holds the key usage, extended key usage and basic
+// constraints extensions of a certificate request, with the meaning of the
+// Certificate fields of the same names. The zero value requests none of
+// them.
+type RequestedUsage struct {
+	// KeyUsage is not requested if zero.
+	KeyUsage KeyUsage
+
+	// ExtKeyUsage and UnknownExtKeyUsage are not requested if both are
+	// empty.
+	ExtKeyUsage        []ExtKeyUsage
+	UnknownExtKeyUsage []asn1.ObjectIdentifier
+
+	// IsCA, MaxPathLen and MaxPathLenZero are only requested if
+	// BasicConstraintsValid is true.
+	BasicConstraintsValid bool
+	IsCA                  bool
+	MaxPathLen            int
+	MaxPathLenZero        bool
+}
+
+// RequestedUsage returns the key usage, extended key usage and basic
+// constraints extensions requested by c, parsed like those of a certificate.
+func (c *CertificateRequest) RequestedUsage() (*RequestedUsage, error) {
+	usage := &RequestedUsage{}
+	for _, e := range c.Extensions {
+		var err error
+		switch {
+		case e.Id.Equal(oidExtensionKeyUsage):
+			usage.KeyUsage, err = parseKeyUsageExtension(e.Value)
+		case e.Id.Equal(oidExtensionExtendedKeyUsage):
+			usage.ExtKeyUsage, usage.UnknownExtKeyUsage, err = parseExtKeyUsageExtension(e.Value)
+		case e.Id.Equal(oidExtensionBasicConstraints):
+			usage.IsCA, usage.MaxPathLen, err = parseBasicConstraintsExtension(e.Value)
+			usage.BasicConstraintsValid = true
+			usage.MaxPathLenZero = usage.MaxPathLen == 0
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return usage, nil
+}
+
+// extensions returns the extensions requested by u. They must not be in
+// extraExtensions too.
+func (u *RequestedUsage) extensions(extraExtensions []pkix.Extension) ([]pkix.Extension, error) {
+	var ret []pkix.Extension
+	add := func(ext pkix.Extension, err error) error {
+		if err != nil {
+			return err
+		}
+		if oidInExtensions(ext.Id, extraExtensions) {
+			return errors.New("x509: extension " + ext.Id.String() + " is requested both by RequestedUsage and ExtraExtensions")
+		}
+		ret = append(ret, ext)
+		return nil
+	}
+
+	if u.KeyUsage != 0 {
+		if err := add(marshalKeyUsage(u.KeyUsage)); err != nil {
+			return nil, err
+		}
+	}
+	if len(u.ExtKeyUsage) > 0 || len(u.UnknownExtKeyUsage) > 0 {
+		if err := add(marshalExtKeyUsage(u.ExtKeyUsage, u.UnknownExtKeyUsage)); err != nil {
+			return nil, err
+		}
+	}
+	if u.BasicConstraintsValid {
+		if err := add(marshalBasicConstraints(u.IsCA, u.MaxPathLen, u.MaxPathLenZero)); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,88 @@
+package smx509
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"reflect"
+	"testing"
+
+	"github.com/yunmoon/gmsm/sm2"
+)
+
+func TestRequestedUsage(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.CertificateRequest{Subject: pkix.Name{CommonName: "GM sub CA"}}
+
+	for _, usage := range []RequestedUsage{
+		{},
+		{KeyUsage: KeyUsageDigitalSignature | KeyUsageDecipherOnly},
+		{ExtKeyUsage: []ExtKeyUsage{ExtKeyUsageServerAuth, ExtKeyUsageClientAuth}},
+		{UnknownExtKeyUsage: []asn1.ObjectIdentifier{{1, 2, 156, 10197, 1, 999}}},
+		{BasicConstraintsValid: true, MaxPathLen: -1},
+		{
+			KeyUsage:              KeyUsageCertSign | KeyUsageCRLSign,
+			ExtKeyUsage:           []ExtKeyUsage{ExtKeyUsageOCSPSigning},
+			UnknownExtKeyUsage:    []asn1.ObjectIdentifier{{1, 2, 156, 10197, 1, 999}},
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+			MaxPathLenZero:        true,
+		},
+		{BasicConstraintsValid: true, IsCA: true, MaxPathLen: 2},
+	} {
+		der, err := CreateCertificateRequestWithOptions(rand.Reader, template, priv, &CreateCertificateRequestOptions{Usage: usage})
+		if err != nil {
+			t.Fatal(err)
+		}
+		csr, err := ParseCertificateRequest(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := csr.RequestedUsage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(*got, usage) {
+			t.Errorf("got %+v, want %+v", *got, usage)
+		}
+	}
+
+	// the extensions of ExtraExtensions are parsed too
+	block, _ := pem.Decode([]byte(opensslUTF8ChallengeCSRPEM))
+	csr, err := ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage, err := csr.RequestedUsage(); err != nil || usage.KeyUsage != KeyUsageDigitalSignature || usage.BasicConstraintsValid {
+		t.Errorf("unexpected requested usage %+v: %v", usage, err)
+	}
+
+	keyUsage, err := marshalKeyUsage(KeyUsageDigitalSignature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.ExtraExtensions = []pkix.Extension{keyUsage}
+	if _, err := CreateCertificateRequestWithOptions(rand.Reader, template, priv, &CreateCertificateRequestOptions{Usage: RequestedUsage{KeyUsage: KeyUsageDigitalSignature}}); err == nil {
+		t.Error("expected error for a key usage both in Usage and ExtraExtensions")
+	}
+	if _, err := CreateCertificateRequestWithOptions(rand.Reader, template, priv, &CreateCertificateRequestOptions{Usage: RequestedUsage{BasicConstraintsValid: true}}); err != nil {
+		t.Errorf("unexpected error for different extensions: %v", err)
+	}
+
+	template.ExtraExtensions = []pkix.Extension{{Id: oidExtensionBasicConstraints, Value: []byte{0x30, 0x03, 0x01, 0x01}}}
+	der, err := CreateCertificateRequest(rand.Reader, template, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if csr, err = ParseCertificateRequest(der); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := csr.RequestedUsage(); err == nil {
+		t.Error("expected error for invalid basic constraints")
+	}
+}
@@ -0,0 +1,109 @@
+package smx509
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// reasonRemoveFromCRL is the removeFromCRL reason code of the entries of a
+// delta CRL for certificates which are no longer revoked, RFC 5280, 5.3.1.
+const reasonRemoveFromCRL = 8
+
+// BaseCRLNumber returns the number of the base CRL of the delta CRL
+// indicator extension of rl, RFC 5280, 5.2.4, or nil if rl is not a delta
+// CRL.
+func (rl *RevocationList) BaseCRLNumber() (*big.Int, error) {
+	for _, e := range rl.Extensions {
+		if e.Id.Equal(oidExtensionDeltaCRLIndicator) {
+			value := cryptobyte.String(e.Value)
+			number := new(big.Int)
+			if !value.ReadASN1Integer(number) || !value.Empty() {
+				return nil, errors.New("x509: malformed delta CRL indicator")
+			}
+			return number, nil
+		}
+	}
+	return nil, nil
+}
+
+// ApplyDeltaCRL returns the revocation list of base updated with the delta
+// CRL delta, RFC 5280, 5.2.4: the entries of delta replace those of base for
+// the same serial number, and the entries with the removeFromCRL reason
+// remove them. The list has the issuer, number and validity of delta, and is
+// not signed nor encoded, it is only meant for status checks.
+//
+// The signatures of base and delta are not checked. base must not be a
+// delta CRL itself, both must be from the same issuer, and delta must be
+// newer than base and apply to it, with a base CRL number not greater than
+// the number of base.
+func ApplyDeltaCRL(base, delta *RevocationList) (*RevocationList, error) {
+	if base == nil || delta == nil {
+		return nil, errors.New("x509: base and delta CRLs can not be nil")
+	}
+	if baseNumber, err := base.BaseCRLNumber(); err != nil {
+		return nil, err
+	} else if baseNumber != nil {
+		return nil, errors.New("x509: base CRL is a delta CRL")
+	}
+	baseNumber, err := delta.BaseCRLNumber()
+	if err != nil {
+		return nil, err
+	}
+	if baseNumber == nil {
+		return nil, errors.New("x509: delta CRL has no delta CRL indicator")
+	}
+	if !bytes.Equal(base.RawIssuer, delta.RawIssuer) {
+		return nil, errors.New("x509: base and delta CRLs have different issuers")
+	}
+	if len(base.AuthorityKeyId) > 0 && len(delta.AuthorityKeyId) > 0 && !bytes.Equal(base.AuthorityKeyId, delta.AuthorityKeyId) {
+		return nil, errors.New("x509: base and delta CRLs have different authority key identifiers")
+	}
+	if base.Number == nil || delta.Number == nil {
+		return nil, errors.New("x509: base and delta CRLs must have a CRL number")
+	}
+	if delta.Number.Cmp(base.Number) <= 0 {
+		return nil, errors.New("x509: delta CRL is not newer than the base CRL")
+	}
+	if baseNumber.Cmp(base.Number) > 0 {
+		return nil, errors.New("x509: delta CRL applies to a newer base CRL")
+	}
+
+	replaced := make(map[string]bool, len(delta.RevokedCertificateEntries))
+	for _, rce := range delta.RevokedCertificateEntries {
+		replaced[rce.SerialNumber.String()] = true
+	}
+	var entries []x509.RevocationListEntry
+	for _, rce := range base.RevokedCertificateEntries {
+		if !replaced[rce.SerialNumber.String()] {
+			entries = append(entries, rce)
+		}
+	}
+	for _, rce := range delta.RevokedCertificateEntries {
+		if rce.ReasonCode != reasonRemoveFromCRL {
+			entries = append(entries, rce)
+		}
+	}
+
+	combined := &RevocationList{
+		RawIssuer:                 delta.RawIssuer,
+		Issuer:                    delta.Issuer,
+		AuthorityKeyId:            delta.AuthorityKeyId,
+		Number:                    delta.Number,
+		ThisUpdate:                delta.ThisUpdate,
+		NextUpdate:                delta.NextUpdate,
+		RevokedCertificateEntries: entries,
+	}
+	for _, rce := range entries {
+		combined.RevokedCertificates = append(combined.RevokedCertificates, pkix.RevokedCertificate{
+			SerialNumber:   rce.SerialNumber,
+			RevocationTime: rce.RevocationTime,
+			Extensions:     rce.Extensions,
+		})
+	}
+	return combined, nil
+}
@@ -0,0 +1,100 @@
+package smx509
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/yunmoon/gmsm/sm2"
+)
+
+func TestApplyDeltaCRL(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := newTestIssuer(t, priv, pkix.Name{CommonName: "GM CRL CA"})
+	now := time.Now().Truncate(time.Second)
+	newCRL := func(issuer *Certificate, number, baseNumber int64, entries ...x509.RevocationListEntry) *RevocationList {
+		t.Helper()
+		var opts *CreateRevocationListOptions
+		if baseNumber > 0 {
+			opts = &CreateRevocationListOptions{BaseCRLNumber: big.NewInt(baseNumber)}
+		}
+		template := &x509.RevocationList{
+			Number:                    big.NewInt(number),
+			ThisUpdate:                now.Add(time.Duration(number) * time.Minute),
+			NextUpdate:                now.Add(time.Duration(number+1) * time.Minute),
+			RevokedCertificateEntries: entries,
+		}
+		der, err := CreateRevocationListWithOptions(rand.Reader, template, issuer, priv, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		crl, err := ParseRevocationList(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return crl
+	}
+	entry := func(serial int64, reason int) x509.RevocationListEntry {
+		return x509.RevocationListEntry{SerialNumber: big.NewInt(serial), RevocationTime: now, ReasonCode: reason}
+	}
+
+	base := newCRL(issuer, 10, 0, entry(1, 1), entry(2, 6), entry(3, 4))
+	delta := newCRL(issuer, 12, 10, entry(2, reasonRemoveFromCRL), entry(3, 1), entry(4, 5))
+	if n, err := base.BaseCRLNumber(); n != nil || err != nil {
+		t.Errorf("unexpected base CRL number %v: %v", n, err)
+	}
+	if n, err := delta.BaseCRLNumber(); err != nil || n.Cmp(big.NewInt(10)) != 0 {
+		t.Errorf("unexpected base CRL number %v: %v", n, err)
+	}
+	for _, e := range delta.Extensions {
+		if e.Id.Equal(oidExtensionDeltaCRLIndicator) && !e.Critical {
+			t.Error("the delta CRL indicator is not critical")
+		}
+	}
+
+	combined, err := ApplyDeltaCRL(base, delta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if combined.Number.Cmp(delta.Number) != 0 || !combined.ThisUpdate.Equal(delta.ThisUpdate) || !combined.NextUpdate.Equal(delta.NextUpdate) {
+		t.Errorf("unexpected number or validity %v %v %v", combined.Number, combined.ThisUpdate, combined.NextUpdate)
+	}
+	want := map[int64]int{1: 1, 3: 1, 4: 5}
+	if len(combined.RevokedCertificateEntries) != len(want) || len(combined.RevokedCertificates) != len(want) {
+		t.Fatalf("unexpected entries %v", combined.RevokedCertificateEntries)
+	}
+	for _, rce := range combined.RevokedCertificateEntries {
+		if reason, ok := want[rce.SerialNumber.Int64()]; !ok || reason != rce.ReasonCode {
+			t.Errorf("unexpected entry %v with reason %d", rce.SerialNumber, rce.ReasonCode)
+		}
+	}
+
+	// a delta of an older base applies to newer ones
+	if _, err := ApplyDeltaCRL(newCRL(issuer, 11, 0), delta); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	for name, crls := range map[string][2]*RevocationList{
+		"delta older than the base":  {newCRL(issuer, 13, 0), delta},
+		"delta of a newer base":      {newCRL(issuer, 9, 0), newCRL(issuer, 12, 11)},
+		"not a delta":                {base, newCRL(issuer, 12, 0)},
+		"delta as the base":          {newCRL(issuer, 11, 10), delta},
+		"different issuers":          {newCRL(newTestIssuer(t, priv, pkix.Name{CommonName: "Other CA"}), 10, 0), delta},
+		"different authority key id": {base, func() *RevocationList { d := *delta; d.AuthorityKeyId = []byte{1, 2, 3}; return &d }()},
+	} {
+		if _, err := ApplyDeltaCRL(crls[0], crls[1]); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	template := &x509.RevocationList{Number: big.NewInt(5), ThisUpdate: now, NextUpdate: now.Add(time.Hour)}
+	if _, err := CreateRevocationListWithOptions(rand.Reader, template, issuer, priv, &CreateRevocationListOptions{BaseCRLNumber: big.NewInt(5)}); err == nil {
+		t.Error("expected error for a base CRL number which is not less than the CRL number")
+	}
+}
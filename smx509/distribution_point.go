@@ -0,0 +1,308 @@
+package smx509
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+)
+
+// ReasonFlags is the set of revocation reasons covered by the CRL of a
+// distribution point, RFC 5280, 4.2.1.13. It is a bitmap of the ReasonFlag*
+// values, like KeyUsage.
+type ReasonFlags int
+
+const (
+	ReasonFlagUnused ReasonFlags = 1 << iota
+	ReasonFlagKeyCompromise
+	ReasonFlagCACompromise
+	ReasonFlagAffiliationChanged
+	ReasonFlagSuperseded
+	ReasonFlagCessationOfOperation
+	ReasonFlagCertificateHold
+	ReasonFlagPrivilegeWithdrawn
+	ReasonFlagAACompromise
+)
+
+// tagDirectoryName is the context-specific tag of a directoryName
+// GeneralName, which is constructed.
+const tagDirectoryName = 4
+
+// DistributionPoint is a distribution point of a CRL distribution points
+// extension, RFC 5280, 4.2.1.13.
+type DistributionPoint struct {
+	// URIs and DirectoryName are the full name of the distribution point.
+	URIs          []string
+	DirectoryName *pkix.Name
+
+	// Reasons are the revocation reasons covered by the CRL, zero for all
+	// reasons.
+	Reasons ReasonFlags
+
+	// CRLIssuer is the directory name of the issuer of an indirect CRL, nil
+	// if the CRL is issued by the certificate issuer.
+	CRLIssuer *pkix.Name
+}
+
+// DistributionPoints returns the distribution points of the CRL distribution
+// points extension of c, including the reasons and CRL issuer which are not
+// held by CRLDistributionPoints. Other kinds of names, such as
+// nameRelativeToCRLIssuer, are ignored.
+func (c *Certificate) DistributionPoints() ([]DistributionPoint, error) {
+	for _, e := range c.Extensions {
+		if e.Id.Equal(oidExtensionCRLDistributionPoints) {
+			return parseFullDistributionPoints(e.Value)
+		}
+	}
+	return nil, nil
+}
+
+func parseFullDistributionPoints(der []byte) ([]DistributionPoint, error) {
+	var dps []distributionPoint
+	if rest, err := asn1.Unmarshal(der, &dps); err != nil || len(rest) > 0 {
+		return nil, errors.New("x509: invalid CRL distribution points")
+	}
+	ret := make([]DistributionPoint, 0, len(dps))
+	for _, dp := range dps {
+		var out DistributionPoint
+		var err error
+		if out.URIs, out.DirectoryName, err = parseFullName(dp.DistributionPoint.FullName); err != nil {
+			return nil, err
+		}
+		out.Reasons = parseReasonFlags(dp.Reason)
+		for rest := dp.CRLIssuer.Bytes; len(rest) > 0; {
+			var name asn1.RawValue
+			var err error
+			if rest, err = asn1.Unmarshal(rest, &name); err != nil {
+				return nil, errors.New("x509: invalid CRL distribution point issuer")
+			}
+			if name.Class == asn1.ClassContextSpecific && name.Tag == tagDirectoryName && name.IsCompound {
+				if out.CRLIssuer, err = parseDirectoryName(name.Bytes); err != nil {
+					return nil, err
+				}
+				break
+			}
+		}
+		ret = append(ret, out)
+	}
+	return ret, nil
+}
+
+// parseFullName returns the URIs and the first directory name of the full
+// name of a distribution point.
+func parseFullName(names []asn1.RawValue) (uris []string, dirName *pkix.Name, err error) {
+	for _, name := range names {
+		if name.Class != asn1.ClassContextSpecific {
+			continue
+		}
+		switch {
+		case name.Tag == nameTypeURI && !name.IsCompound:
+			uris = append(uris, string(name.Bytes))
+		case name.Tag == tagDirectoryName && name.IsCompound && dirName == nil:
+			if dirName, err = parseDirectoryName(name.Bytes); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return uris, dirName, nil
+}
+
+// marshalFullName returns the full name of a distribution point with uris
+// and dirName, if not nil.
+func marshalFullName(uris []string, dirName *pkix.Name) ([]asn1.RawValue, error) {
+	var names []asn1.RawValue
+	for _, uri := range uris {
+		names = append(names, asn1.RawValue{Tag: nameTypeURI, Class: asn1.ClassContextSpecific, Bytes: []byte(uri)})
+	}
+	if dirName != nil {
+		name, err := marshalDirectoryName(dirName)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// parseReasonFlags returns the ReasonFlags of a bit string.
+func parseReasonFlags(bits asn1.BitString) ReasonFlags {
+	var reasons ReasonFlags
+	for i := 0; i < 9; i++ {
+		if bits.At(i) != 0 {
+			reasons |= 1 << uint(i)
+		}
+	}
+	return reasons
+}
+
+// parseDirectoryName parses the explicitly tagged Name of a directoryName
+// GeneralName.
+func parseDirectoryName(der []byte) (*pkix.Name, error) {
+	var rdns pkix.RDNSequence
+	if rest, err := asn1.Unmarshal(der, &rdns); err != nil || len(rest) > 0 {
+		return nil, errors.New("x509: invalid directory name")
+	}
+	name := new(pkix.Name)
+	name.FillFromRDNSequence(&rdns)
+	return name, nil
+}
+
+// marshalDirectoryName returns the directoryName GeneralName of name.
+func marshalDirectoryName(name *pkix.Name) (asn1.RawValue, error) {
+	der, err := asn1.Marshal(name.ToRDNSequence())
+	if err != nil {
+		return asn1.RawValue{}, err
+	}
+	return asn1.RawValue{Tag: tagDirectoryName, Class: asn1.ClassContextSpecific, IsCompound: true, Bytes: der}, nil
+}
+
+// marshalReasonFlags returns the ReasonFlags bit string of reasons.
+func marshalReasonFlags(reasons ReasonFlags) asn1.BitString {
+	var a [2]byte
+	a[0] = reverseBitsInAByte(byte(reasons))
+	a[1] = reverseBitsInAByte(byte(reasons >> 8))
+
+	l := 1
+	if a[1] != 0 {
+		l = 2
+	}
+
+	bitString := a[:l]
+	return asn1.BitString{Bytes: bitString, BitLength: asn1BitLength(bitString)}
+}
+
+// marshalFullDistributionPoints returns a CRL distribution points extension
+// value with a full name URI for each of uris, followed by dps.
+func marshalFullDistributionPoints(uris []string, dps []DistributionPoint) ([]byte, error) {
+	var out []distributionPoint
+	for _, name := range uris {
+		out = append(out, distributionPoint{
+			DistributionPoint: distributionPointName{
+				FullName: []asn1.RawValue{
+					{Tag: nameTypeURI, Class: asn1.ClassContextSpecific, Bytes: []byte(name)},
+				},
+			},
+		})
+	}
+	for _, dp := range dps {
+		if len(dp.URIs) == 0 && dp.DirectoryName == nil && dp.CRLIssuer == nil {
+			return nil, errors.New("x509: CRL distribution point has neither a name nor a CRL issuer")
+		}
+		var encoded distributionPoint
+		var err error
+		if encoded.DistributionPoint.FullName, err = marshalFullName(dp.URIs, dp.DirectoryName); err != nil {
+			return nil, err
+		}
+		if dp.Reasons != 0 {
+			encoded.Reason = marshalReasonFlags(dp.Reasons)
+		}
+		if dp.CRLIssuer != nil {
+			name, err := marshalDirectoryName(dp.CRLIssuer)
+			if err != nil {
+				return nil, err
+			}
+			issuer, err := asn1.Marshal(name)
+			if err != nil {
+				return nil, err
+			}
+			encoded.CRLIssuer = asn1.RawValue{Tag: 2, Class: asn1.ClassContextSpecific, IsCompound: true, Bytes: issuer}
+		}
+		out = append(out, encoded)
+	}
+	return asn1.Marshal(out)
+}
+
+// IssuingDistributionPoint is the issuing distribution point extension of a
+// CRL, RFC 5280, 5.2.5, which tells the scope of the CRL.
+type IssuingDistributionPoint struct {
+	// URIs and DirectoryName are the full name of the distribution point.
+	URIs          []string
+	DirectoryName *pkix.Name
+
+	// OnlyContainsUserCerts, OnlyContainsCACerts and
+	// OnlyContainsAttributeCerts restrict the CRL to the certificates of end
+	// entities, of CAs, or to attribute certificates. At most one of them can
+	// be set.
+	OnlyContainsUserCerts      bool
+	OnlyContainsCACerts        bool
+	OnlyContainsAttributeCerts bool
+
+	// OnlySomeReasons are the revocation reasons covered by the CRL, zero for
+	// all reasons.
+	OnlySomeReasons ReasonFlags
+
+	// IndirectCRL is set if the CRL contains revoked certificates of other
+	// issuers.
+	IndirectCRL bool
+}
+
+type issuingDistributionPoint struct {
+	DistributionPoint          distributionPointName `asn1:"optional,tag:0"`
+	OnlyContainsUserCerts      bool                  `asn1:"optional,tag:1"`
+	OnlyContainsCACerts        bool                  `asn1:"optional,tag:2"`
+	OnlySomeReasons            asn1.BitString        `asn1:"optional,tag:3"`
+	IndirectCRL                bool                  `asn1:"optional,tag:4"`
+	OnlyContainsAttributeCerts bool                  `asn1:"optional,tag:5"`
+}
+
+// IssuingDistributionPoint returns the issuing distribution point extension
+// of rl, or nil if it has none. Other kinds of names than URIs and directory
+// names, such as nameRelativeToCRLIssuer, are ignored.
+func (rl *RevocationList) IssuingDistributionPoint() (*IssuingDistributionPoint, error) {
+	for _, e := range rl.Extensions {
+		if e.Id.Equal(oidExtensionIssuingDistributionPoint) {
+			return parseIssuingDistributionPoint(e.Value)
+		}
+	}
+	return nil, nil
+}
+
+func parseIssuingDistributionPoint(der []byte) (*IssuingDistributionPoint, error) {
+	var idp issuingDistributionPoint
+	if rest, err := asn1.Unmarshal(der, &idp); err != nil || len(rest) > 0 {
+		return nil, errors.New("x509: invalid issuing distribution point")
+	}
+	out := &IssuingDistributionPoint{
+		OnlyContainsUserCerts:      idp.OnlyContainsUserCerts,
+		OnlyContainsCACerts:        idp.OnlyContainsCACerts,
+		OnlyContainsAttributeCerts: idp.OnlyContainsAttributeCerts,
+		OnlySomeReasons:            parseReasonFlags(idp.OnlySomeReasons),
+		IndirectCRL:                idp.IndirectCRL,
+	}
+	var err error
+	if out.URIs, out.DirectoryName, err = parseFullName(idp.DistributionPoint.FullName); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// marshalIssuingDistributionPoint returns the issuing distribution point
+// extension value of idp.
+func marshalIssuingDistributionPoint(idp *IssuingDistributionPoint) ([]byte, error) {
+	scopes := 0
+	for _, only := range []bool{idp.OnlyContainsUserCerts, idp.OnlyContainsCACerts, idp.OnlyContainsAttributeCerts} {
+		if only {
+			scopes++
+		}
+	}
+	if scopes > 1 {
+		return nil, errors.New("x509: issuing distribution point can only contain one of user, CA and attribute certificates")
+	}
+	if len(idp.URIs) == 0 && idp.DirectoryName == nil && scopes == 0 && idp.OnlySomeReasons == 0 && !idp.IndirectCRL {
+		return nil, errors.New("x509: issuing distribution point is empty")
+	}
+
+	encoded := issuingDistributionPoint{
+		OnlyContainsUserCerts:      idp.OnlyContainsUserCerts,
+		OnlyContainsCACerts:        idp.OnlyContainsCACerts,
+		IndirectCRL:                idp.IndirectCRL,
+		OnlyContainsAttributeCerts: idp.OnlyContainsAttributeCerts,
+	}
+	var err error
+	if encoded.DistributionPoint.FullName, err = marshalFullName(idp.URIs, idp.DirectoryName); err != nil {
+		return nil, err
+	}
+	if idp.OnlySomeReasons != 0 {
+		encoded.OnlySomeReasons = marshalReasonFlags(idp.OnlySomeReasons)
+	}
+	return asn1.Marshal(encoded)
+}
@@ -0,0 +1,349 @@
+package smx509
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yunmoon/gmsm/sm2"
+)
+
+// Generated with OpenSSL from the configuration:
+//
+//	crlDistributionPoints = crldp1, crldp2
+//	[crldp1]
+//	fullname = URI:http://crl.example.com/sm2.crl
+//	reasons = keyCompromise, CACompromise
+//	CRLissuer = dirName:issuer_sect
+//	[crldp2]
+//	fullname = URI:ldap://ldap.example.com/cn=CRL, dirName:dp_sect
+//	[issuer_sect]
+//	C = CN
+//	O = GM CRL Issuer
+//	CN = Indirect CRL
+//	[dp_sect]
+//	O = GM
+//	CN = CRL1
+const opensslDistributionPointsCertPEM = `-----BEGIN CERTIFICATE-----
+MIICFjCCAb2gAwIBAgIULvYU+JqjB3dwVllsOr90xS45bVcwCgYIKoEcz1UBg3Uw
+ETEPMA0GA1UEAwwGR00gQ0RQMCAXDTI2MTAxNjEzNTUxOVoYDzIxMjYwOTIyMTM1
+NTE5WjARMQ8wDQYDVQQDDAZHTSBDRFAwWTATBgcqhkjOPQIBBggqgRzPVQGCLQNC
+AARVJHo6My8Bm7/ZRzGeDX8jcGuLBVU/fAGE7yEIUXgvlFiLoOjLq7gqIMwDvbIC
+YUu03A/UgxHj3vY7sM5Cp5rro4HwMIHtMAwGA1UdEwEB/wQCMAAwgb0GA1UdHwSB
+tTCBsjBqoCKgIIYeaHR0cDovL2NybC5leGFtcGxlLmNvbS9zbTIuY3JsgQIFYKJA
+pD4wPDELMAkGA1UEBhMCQ04xFjAUBgNVBAoMDUdNIENSTCBJc3N1ZXIxFTATBgNV
+BAMMDEluZGlyZWN0IENSTDBEoEKgQIYebGRhcDovL2xkYXAuZXhhbXBsZS5jb20v
+Y249Q1JMpB4wHDELMAkGA1UECgwCR00xDTALBgNVBAMMBENSTDEwHQYDVR0OBBYE
+FFIVTiSH5Z+eSACba+ZMHl4WNboEMAoGCCqBHM9VAYN1A0cAMEQCIC4V1N9t3wRQ
+rv2d+8XaWTDXAh3XL67qmPaHShLqoYQnAiAY2PrgSBjK68iuqikJtVpbLJ0P40P4
+hMEQpDJE8ty/rA==
+-----END CERTIFICATE-----`
+
+// Generated with OpenSSL from the configuration:
+//
+//	crlDistributionPoints = crldp1, crldp2
+//	[crldp1]
+//	relativename = rel_sect
+//	reasons = keyCompromise
+//	[crldp2]
+//	fullname = URI:http://crl.example.com/sm2.crl
+//	[rel_sect]
+//	CN = CRL2
+const opensslRelativeDistributionPointCertPEM = `-----BEGIN CERTIFICATE-----
+MIIBnjCCAUOgAwIBAgIUEqrO+447BBDnzia/3G1ZuqScGj4wCgYIKoEcz1UBg3Uw
+ETEPMA0GA1UEAwwGR00gQ0RQMCAXDTI2MTAxNjE1MzQ0NVoYDzIxMjYwOTIyMTUz
+NDQ1WjARMQ8wDQYDVQQDDAZHTSBDRFAwWTATBgcqhkjOPQIBBggqgRzPVQGCLQNC
+AAQXU/EK7pPa4YNbHY/ysp2rHPFx/ocM5f6OSi8NEfFF1YE8RQZcp3M41vAYgB1Y
+1tbbs4/vd3eHLNuYWhOURLa+o3cwdTAMBgNVHRMBAf8EAjAAMEYGA1UdHwQ/MD0w
+FaAPoQ0wCwYDVQQDDARDUkwygQIGQDAkoCKgIIYeaHR0cDovL2NybC5leGFtcGxl
+LmNvbS9zbTIuY3JsMB0GA1UdDgQWBBStG6cWS6FV7XdNhXC0J2+ibeQWUzAKBggq
+gRzPVQGDdQNJADBGAiEApN0gwu3egxnv611O7pdmXB9nI+7vp6pmPOiuBwZt0GUC
+IQD2H8znthU7P1MoeHITkYMmR5V2hKC8eWBYikUC5D3gdw==
+-----END CERTIFICATE-----`
+
+func checkDistributionPoints(t *testing.T, got, want []DistributionPoint) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d distribution points, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i].URIs, want[i].URIs) || got[i].Reasons != want[i].Reasons {
+			t.Errorf("distribution point %d: got %v %v, want %v %v", i, got[i].URIs, got[i].Reasons, want[i].URIs, want[i].Reasons)
+		}
+		for _, names := range [][2]*pkix.Name{{got[i].DirectoryName, want[i].DirectoryName}, {got[i].CRLIssuer, want[i].CRLIssuer}} {
+			if (names[0] == nil) != (names[1] == nil) || names[0] != nil && names[0].String() != names[1].String() {
+				t.Errorf("distribution point %d: got name %v, want %v", i, names[0], names[1])
+			}
+		}
+	}
+}
+
+func TestDistributionPoints(t *testing.T) {
+	want := []DistributionPoint{
+		{
+			URIs:      []string{"http://crl.example.com/sm2.crl"},
+			Reasons:   ReasonFlagKeyCompromise | ReasonFlagCACompromise,
+			CRLIssuer: &pkix.Name{Country: []string{"CN"}, Organization: []string{"GM CRL Issuer"}, CommonName: "Indirect CRL"},
+		},
+		{
+			URIs:          []string{"ldap://ldap.example.com/cn=CRL"},
+			DirectoryName: &pkix.Name{Organization: []string{"GM"}, CommonName: "CRL1"},
+		},
+	}
+
+	block, _ := pem.Decode([]byte(opensslDistributionPointsCertPEM))
+	opensslCert, err := ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(opensslCert.CRLDistributionPoints, []string{want[0].URIs[0], want[1].URIs[0]}) {
+		t.Errorf("unexpected CRL distribution points %v", opensslCert.CRLDistributionPoints)
+	}
+	got, err := opensslCert.DistributionPoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDistributionPoints(t, got, want)
+
+	// the name relative to the CRL issuer is ignored
+	block, _ = pem.Decode([]byte(opensslRelativeDistributionPointCertPEM))
+	relativeCert, err := ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(relativeCert.CRLDistributionPoints, []string{"http://crl.example.com/sm2.crl"}) {
+		t.Errorf("unexpected CRL distribution points %v", relativeCert.CRLDistributionPoints)
+	}
+	if got, err = relativeCert.DistributionPoints(); err != nil {
+		t.Fatal(err)
+	}
+	checkDistributionPoints(t, got, []DistributionPoint{
+		{Reasons: ReasonFlagKeyCompromise},
+		{URIs: []string{"http://crl.example.com/sm2.crl"}},
+	})
+
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "GM CDP"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := CreateCertificateWithOptions(rand.Reader, template, template, priv.Public(), priv, &CreateCertificateOptions{DistributionPoints: want})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err = cert.DistributionPoints(); err != nil {
+		t.Fatal(err)
+	}
+	checkDistributionPoints(t, got, want)
+	// The names differ from OpenSSL only in the PrintableString and
+	// UTF8String choice, check the reasons encoding matches.
+	for _, c := range []*Certificate{cert, opensslCert} {
+		for _, e := range c.Extensions {
+			if e.Id.Equal(oidExtensionCRLDistributionPoints) && !bytes.Contains(e.Value, []byte{0x81, 0x02, 0x05, 0x60}) {
+				t.Errorf("unexpected reasons encoding %x", e.Value)
+			}
+		}
+	}
+
+	// the URIs of the template come first
+	template.CRLDistributionPoints = []string{"http://crl.example.com/all.crl"}
+	if der, err = CreateCertificateWithOptions(rand.Reader, template, template, priv.Public(), priv, &CreateCertificateOptions{DistributionPoints: want[:1]}); err != nil {
+		t.Fatal(err)
+	}
+	if cert, err = ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = cert.DistributionPoints(); err != nil {
+		t.Fatal(err)
+	}
+	checkDistributionPoints(t, got, []DistributionPoint{{URIs: template.CRLDistributionPoints}, want[0]})
+	if !reflect.DeepEqual(cert.CRLDistributionPoints, []string{"http://crl.example.com/all.crl", "http://crl.example.com/sm2.crl"}) {
+		t.Errorf("unexpected CRL distribution points %v", cert.CRLDistributionPoints)
+	}
+
+	// the simple path is unchanged
+	if der, err = CreateCertificate(rand.Reader, template, template, priv.Public(), priv); err != nil {
+		t.Fatal(err)
+	}
+	if cert, err = ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = cert.DistributionPoints(); err != nil {
+		t.Fatal(err)
+	}
+	checkDistributionPoints(t, got, []DistributionPoint{{URIs: template.CRLDistributionPoints}})
+	if simple, err := marshalDistributionPoints(template.CRLDistributionPoints); err != nil {
+		t.Fatal(err)
+	} else if full, err := marshalFullDistributionPoints(template.CRLDistributionPoints, nil); err != nil || !bytes.Equal(full, simple) {
+		t.Errorf("unexpected encoding %x, want %x: %v", full, simple, err)
+	}
+
+	// all the reasons need two bytes
+	if got, err := parseFullDistributionPoints(mustMarshalDistributionPoints(t, []DistributionPoint{{URIs: want[0].URIs, Reasons: 0x1ff}})); err != nil || got[0].Reasons != 0x1ff {
+		t.Errorf("unexpected reasons %v: %v", got, err)
+	}
+	if _, err := marshalFullDistributionPoints(nil, []DistributionPoint{{Reasons: ReasonFlagSuperseded}}); err == nil {
+		t.Error("expected error for a distribution point without a name")
+	}
+}
+
+func mustMarshalDistributionPoints(t *testing.T, dps []DistributionPoint) []byte {
+	t.Helper()
+	der, err := marshalFullDistributionPoints(nil, dps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+// The values of the issuingDistributionPoint extensions of CRLs generated with
+// OpenSSL from the configurations:
+//
+//	[idp]
+//	fullname = URI:http://crl.example.com/users.crl
+//	onlyuser = TRUE
+//	onlysomereasons = keyCompromise, CACompromise
+//
+// and
+//
+//	[idp]
+//	fullname = dirName:dir_sect
+//	onlyCA = TRUE
+//	indirectCRL = TRUE
+//	[dir_sect]
+//	O = GM
+//	CN = CA CRL
+//
+// and
+//
+//	[idp]
+//	relativename = rel_sect
+//	onlyuser = TRUE
+//	[rel_sect]
+//	CN = CRL2
+const (
+	opensslUserIDPHex     = "302da024a0228620687474703a2f2f63726c2e6578616d706c652e636f6d2f75736572732e63726c8101ff83020560"
+	opensslCAIDPHex       = "302ca024a022a420301e310b3009060355040a0c02474d310f300d06035504030c0643412043524c8201ff8401ff"
+	opensslRelativeIDPHex = "3014a00fa10d300b06035504030c0443524c328101ff"
+)
+
+func TestIssuingDistributionPoint(t *testing.T) {
+	userIDP := &IssuingDistributionPoint{
+		URIs:                  []string{"http://crl.example.com/users.crl"},
+		OnlyContainsUserCerts: true,
+		OnlySomeReasons:       ReasonFlagKeyCompromise | ReasonFlagCACompromise,
+	}
+	caIDP := &IssuingDistributionPoint{
+		DirectoryName:       &pkix.Name{Organization: []string{"GM"}, CommonName: "CA CRL"},
+		OnlyContainsCACerts: true,
+		IndirectCRL:         true,
+	}
+	opensslUserIDP, _ := hex.DecodeString(opensslUserIDPHex)
+	opensslCAIDP, _ := hex.DecodeString(opensslCAIDPHex)
+
+	if der, err := marshalIssuingDistributionPoint(userIDP); err != nil || !bytes.Equal(der, opensslUserIDP) {
+		t.Errorf("unexpected encoding %x, want %x: %v", der, opensslUserIDP, err)
+	}
+	if got, err := parseIssuingDistributionPoint(opensslUserIDP); err != nil || !reflect.DeepEqual(got, userIDP) {
+		t.Errorf("unexpected issuing distribution point %+v: %v", got, err)
+	}
+	// OpenSSL encodes the directory name with UTF8String
+	got, err := parseIssuingDistributionPoint(opensslCAIDP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkIssuingDistributionPoint(t, got, caIDP)
+	// the name relative to the CRL issuer is ignored
+	opensslRelativeIDP, _ := hex.DecodeString(opensslRelativeIDPHex)
+	if got, err := parseIssuingDistributionPoint(opensslRelativeIDP); err != nil || !reflect.DeepEqual(got, &IssuingDistributionPoint{OnlyContainsUserCerts: true}) {
+		t.Errorf("unexpected issuing distribution point %+v: %v", got, err)
+	}
+
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := newTestIssuer(t, priv, pkix.Name{CommonName: "GM CRL CA"})
+	template := &x509.RevocationList{
+		Number:     big.NewInt(7),
+		ThisUpdate: time.Now(),
+		NextUpdate: time.Now().Add(time.Hour),
+	}
+	for _, idp := range []*IssuingDistributionPoint{userIDP, caIDP} {
+		der, err := CreateRevocationListWithOptions(rand.Reader, template, issuer, priv, &CreateRevocationListOptions{IssuingDistributionPoint: idp})
+		if err != nil {
+			t.Fatal(err)
+		}
+		crl, err := ParseRevocationList(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := crl.CheckSignatureFrom(issuer); err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range crl.Extensions {
+			if e.Id.Equal(oidExtensionIssuingDistributionPoint) && !e.Critical {
+				t.Error("the issuing distribution point extension is not critical")
+			}
+		}
+		got, err := crl.IssuingDistributionPoint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkIssuingDistributionPoint(t, got, idp)
+	}
+
+	// CRLs without the extension
+	der, err := CreateRevocationList(rand.Reader, template, issuer, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crl, err := ParseRevocationList(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := crl.IssuingDistributionPoint(); got != nil || err != nil {
+		t.Errorf("unexpected issuing distribution point %+v: %v", got, err)
+	}
+
+	for _, idp := range []*IssuingDistributionPoint{
+		{OnlyContainsUserCerts: true, OnlyContainsCACerts: true},
+		{URIs: userIDP.URIs, OnlyContainsCACerts: true, OnlyContainsAttributeCerts: true},
+		{},
+	} {
+		if _, err := CreateRevocationListWithOptions(rand.Reader, template, issuer, priv, &CreateRevocationListOptions{IssuingDistributionPoint: idp}); err == nil {
+			t.Errorf("expected error for %+v", idp)
+		}
+	}
+}
+
+func checkIssuingDistributionPoint(t *testing.T, got, want *IssuingDistributionPoint) {
+	t.Helper()
+	if got == nil {
+		t.Fatal("missing issuing distribution point")
+	}
+	if (got.DirectoryName == nil) != (want.DirectoryName == nil) || got.DirectoryName != nil && got.DirectoryName.String() != want.DirectoryName.String() {
+		t.Errorf("got directory name %v, want %v", got.DirectoryName, want.DirectoryName)
+	}
+	gotFlags, wantFlags := *got, *want
+	gotFlags.DirectoryName, wantFlags.DirectoryName = nil, nil
+	if !reflect.DeepEqual(gotFlags, wantFlags) {
+		t.Errorf("got issuing distribution point %+v, want %+v", gotFlags, wantFlags)
+	}
+}
@@ -0,0 +1,121 @@
+package smx509
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/yunmoon/gmsm/sm3"
+)
+
+var errNoRaw = errors.New("x509: no DER encoding to fingerprint")
+
+// FingerprintSM3 returns the SM3 digest of the DER encoding of c, as used by
+// the Chinese platforms to identify certificates.
+func (c *Certificate) FingerprintSM3() ([32]byte, error) {
+	if c == nil {
+		return [32]byte{}, errNoRaw
+	}
+	return fingerprintSM3(c.Raw)
+}
+
+// FingerprintSHA256 returns the SHA-256 digest of the DER encoding of c.
+func (c *Certificate) FingerprintSHA256() ([32]byte, error) {
+	if c == nil {
+		return [32]byte{}, errNoRaw
+	}
+	return fingerprintSHA256(c.Raw)
+}
+
+// Fingerprint returns the digest of the DER encoding of c with h. SM3 is not
+// a crypto.Hash, see FingerprintSM3.
+func (c *Certificate) Fingerprint(h crypto.Hash) ([]byte, error) {
+	if c == nil {
+		return nil, errNoRaw
+	}
+	return fingerprint(c.Raw, h)
+}
+
+// FingerprintSM3 returns the SM3 digest of the DER encoding of c.
+func (c *CertificateRequest) FingerprintSM3() ([32]byte, error) {
+	if c == nil {
+		return [32]byte{}, errNoRaw
+	}
+	return fingerprintSM3(c.Raw)
+}
+
+// FingerprintSHA256 returns the SHA-256 digest of the DER encoding of c.
+func (c *CertificateRequest) FingerprintSHA256() ([32]byte, error) {
+	if c == nil {
+		return [32]byte{}, errNoRaw
+	}
+	return fingerprintSHA256(c.Raw)
+}
+
+// Fingerprint returns the digest of the DER encoding of c with h, see
+// Certificate.Fingerprint.
+func (c *CertificateRequest) Fingerprint(h crypto.Hash) ([]byte, error) {
+	if c == nil {
+		return nil, errNoRaw
+	}
+	return fingerprint(c.Raw, h)
+}
+
+// FingerprintSM3 returns the SM3 digest of the DER encoding of rl.
+func (rl *RevocationList) FingerprintSM3() ([32]byte, error) {
+	if rl == nil {
+		return [32]byte{}, errNoRaw
+	}
+	return fingerprintSM3(rl.Raw)
+}
+
+// FingerprintSHA256 returns the SHA-256 digest of the DER encoding of rl.
+func (rl *RevocationList) FingerprintSHA256() ([32]byte, error) {
+	if rl == nil {
+		return [32]byte{}, errNoRaw
+	}
+	return fingerprintSHA256(rl.Raw)
+}
+
+// Fingerprint returns the digest of the DER encoding of rl with h, see
+// Certificate.Fingerprint.
+func (rl *RevocationList) Fingerprint(h crypto.Hash) ([]byte, error) {
+	if rl == nil {
+		return nil, errNoRaw
+	}
+	return fingerprint(rl.Raw, h)
+}
+
+// FormatFingerprint returns fingerprint as colon-separated upper case hex
+// bytes, as printed by "openssl x509 -fingerprint".
+func FormatFingerprint(fingerprint []byte) string {
+	return strings.ToUpper(hexBytes(fingerprint))
+}
+
+func fingerprintSM3(raw []byte) ([32]byte, error) {
+	if len(raw) == 0 {
+		return [32]byte{}, errNoRaw
+	}
+	return sm3.Sum(raw), nil
+}
+
+func fingerprintSHA256(raw []byte) ([32]byte, error) {
+	if len(raw) == 0 {
+		return [32]byte{}, errNoRaw
+	}
+	return sha256.Sum256(raw), nil
+}
+
+func fingerprint(raw []byte, h crypto.Hash) ([]byte, error) {
+	if len(raw) == 0 {
+		return nil, errNoRaw
+	}
+	if !h.Available() {
+		return nil, fmt.Errorf("x509: hash function %v is not available", h)
+	}
+	hash := h.New()
+	hash.Write(raw)
+	return hash.Sum(nil), nil
+}
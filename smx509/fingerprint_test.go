@@ -0,0 +1,99 @@
+package smx509
+
+import (
+	"crypto"
+	"encoding/pem"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	block, _ := pem.Decode([]byte(opensslOtherNameCertPEM))
+	cert, err := ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ = pem.Decode([]byte(opensslUTF8ChallengeCSRPEM))
+	csr, err := ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ = pem.Decode([]byte(legacyCRLPEM))
+	rl, err := ParseRevocationList(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type fingerprinter interface {
+		FingerprintSM3() ([32]byte, error)
+		FingerprintSHA256() ([32]byte, error)
+		Fingerprint(h crypto.Hash) ([]byte, error)
+	}
+	// digests of openssl dgst over the DER encodings
+	for _, tt := range []struct {
+		name        string
+		f, empty    fingerprinter
+		sm3, sha256 string
+		sha1        string
+	}{
+		{
+			name:   "certificate",
+			f:      cert,
+			empty:  &Certificate{},
+			sm3:    "8B:55:45:B3:61:1F:15:30:D2:1A:72:91:1B:3D:ED:C1:B8:90:E7:B4:0E:4B:D0:71:88:EB:4C:17:B2:D9:83:05",
+			sha256: "EC:C3:F1:66:D3:15:69:20:A6:F4:C6:01:2E:8F:81:84:E7:19:18:DA:A2:9C:95:2C:60:E6:46:A8:9F:DB:9C:A2",
+			sha1:   "A6:C4:D7:C1:3F:2D:44:04:D0:12:CB:06:B2:72:24:5A:EE:B5:34:9A",
+		},
+		{
+			name:   "certificate request",
+			f:      csr,
+			empty:  &CertificateRequest{},
+			sm3:    "66:40:7C:AE:25:18:14:B2:23:26:B8:4C:99:DE:63:BD:09:42:FA:C3:EA:22:88:5C:35:95:F3:DC:02:F3:9C:09",
+			sha256: "DD:A9:A9:67:9C:8F:6F:8A:25:38:70:0A:E1:D1:BD:DA:F3:27:EC:7A:BF:60:C5:25:69:83:BA:3B:0C:59:30:19",
+		},
+		{
+			name:   "revocation list",
+			f:      rl,
+			empty:  &RevocationList{},
+			sm3:    "60:01:A6:BE:94:AD:4D:55:FE:4A:27:BE:AF:84:FA:38:BB:04:AA:84:DF:D7:E7:5B:DC:FF:EE:79:61:15:67:F0",
+			sha256: "0D:3D:DA:DF:7E:C9:F9:DC:CC:FA:3B:82:F2:B4:9C:2E:98:C8:81:E1:84:B4:E6:19:2E:84:45:F6:8F:34:99:6A",
+		},
+	} {
+		sm3Sum, err := tt.f.FingerprintSM3()
+		if err != nil || FormatFingerprint(sm3Sum[:]) != tt.sm3 {
+			t.Errorf("%s: SM3 fingerprint %s: %v", tt.name, FormatFingerprint(sm3Sum[:]), err)
+		}
+		sha256Sum, err := tt.f.FingerprintSHA256()
+		if err != nil || FormatFingerprint(sha256Sum[:]) != tt.sha256 {
+			t.Errorf("%s: SHA-256 fingerprint %s: %v", tt.name, FormatFingerprint(sha256Sum[:]), err)
+		}
+		if sum, err := tt.f.Fingerprint(crypto.SHA256); err != nil || FormatFingerprint(sum) != tt.sha256 {
+			t.Errorf("%s: SHA-256 fingerprint %s: %v", tt.name, FormatFingerprint(sum), err)
+		}
+		if tt.sha1 != "" {
+			if sum, err := tt.f.Fingerprint(crypto.SHA1); err != nil || FormatFingerprint(sum) != tt.sha1 {
+				t.Errorf("%s: SHA-1 fingerprint %s: %v", tt.name, FormatFingerprint(sum), err)
+			}
+		}
+		if _, err := tt.f.Fingerprint(crypto.Hash(0)); err == nil {
+			t.Errorf("%s: expected error for an unavailable hash", tt.name)
+		}
+
+		if _, err := tt.empty.FingerprintSM3(); err == nil {
+			t.Errorf("%s: expected error without a DER encoding", tt.name)
+		}
+		if _, err := tt.empty.FingerprintSHA256(); err == nil {
+			t.Errorf("%s: expected error without a DER encoding", tt.name)
+		}
+		if _, err := tt.empty.Fingerprint(crypto.SHA256); err == nil {
+			t.Errorf("%s: expected error without a DER encoding", tt.name)
+		}
+	}
+
+	var nilCert *Certificate
+	if _, err := nilCert.FingerprintSM3(); err == nil {
+		t.Error("expected error for a nil certificate")
+	}
+	if FormatFingerprint(nil) != "" {
+		t.Error("unexpected format of an empty fingerprint")
+	}
+}
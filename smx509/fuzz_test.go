@@ -0,0 +1,128 @@
+package smx509
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/yunmoon/gmsm/sm2"
+)
+
+// fuzzSeeds returns a SM2 signed certificate, certificate request and
+// revocation list to seed the fuzzers with.
+func fuzzSeeds(f *testing.F) (cert, csr, crl []byte) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		f.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fuzz", Organization: []string{"GMSM"}},
+		NotBefore:             time.Unix(1000, 0),
+		NotAfter:              time.Unix(100000, 0),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"example.com"},
+		EmailAddresses:        []string{"gopher@example.com"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		PermittedDNSDomains:   []string{".example.com"},
+		CRLDistributionPoints: []string{"http://crl.example.com/ca.crl"},
+		PolicyIdentifiers:     []asn1.ObjectIdentifier{{1, 2, 3}},
+	}
+	cert, err = CreateCertificate(rand.Reader, template, template, priv.Public(), priv)
+	if err != nil {
+		f.Fatal(err)
+	}
+	issuer, err := ParseCertificate(cert)
+	if err != nil {
+		f.Fatal(err)
+	}
+	csr, err = CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  template.Subject,
+		DNSNames: template.DNSNames,
+	}, priv)
+	if err != nil {
+		f.Fatal(err)
+	}
+	crl, err = CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Unix(1000, 0),
+		NextUpdate: time.Unix(2000, 0),
+		RevokedCertificateEntries: []x509.RevocationListEntry{
+			{SerialNumber: big.NewInt(2), RevocationTime: time.Unix(1500, 0), ReasonCode: 1},
+		},
+	}, issuer, priv)
+	if err != nil {
+		f.Fatal(err)
+	}
+	return cert, csr, crl
+}
+
+func addPEMSeeds(f *testing.F, blockType string, pemData ...string) {
+	for _, data := range pemData {
+		rest := []byte(data)
+		for {
+			var block *pem.Block
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				break
+			}
+			if block.Type == blockType {
+				f.Add(block.Bytes)
+			}
+		}
+	}
+}
+
+func FuzzParseCertificate(f *testing.F) {
+	cert, _, _ := fuzzSeeds(f)
+	f.Add(cert)
+	addPEMSeeds(f, "CERTIFICATE", ecdsaSHA256p256CertPem, rsaPSSSelfSignedPEM, additionalGeneralSubtreePEM,
+		multipleURLsInCRLDPPEM, optionalAuthKeyIDPEM, largeOIDPEM, uniqueIDPEM)
+
+	f.Fuzz(func(t *testing.T, der []byte) {
+		c, err := ParseCertificate(der)
+		if err != nil {
+			return
+		}
+		c.CheckSignatureFrom(c)
+		c.ToX509()
+	})
+}
+
+func FuzzParseCertificateRequest(f *testing.F) {
+	_, csr, _ := fuzzSeeds(f)
+	f.Add(csr)
+	addPEMSeeds(f, "CERTIFICATE REQUEST", csrFromAli, dupExtCSR, dupAttCSR, sadkGeneratedCSR, trustAsiaCSR,
+		opensslUTF8ChallengeCSRPEM)
+
+	f.Fuzz(func(t *testing.T, der []byte) {
+		c, err := ParseCertificateRequest(der)
+		if err != nil {
+			return
+		}
+		c.CheckSignature()
+		c.ChallengePassword()
+		c.RequestedUsage()
+		if _, err := CertificateRequestText(c); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func FuzzParseRevocationList(f *testing.F) {
+	_, _, crl := fuzzSeeds(f)
+	f.Add(crl)
+
+	f.Fuzz(func(t *testing.T, der []byte) {
+		ParseRevocationList(der)
+	})
+}
@@ -0,0 +1,116 @@
+package smx509
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+)
+
+// CheckSignatureFromDelegated verifies that the signature on rl is a valid
+// signature from signer, a CRL signer delegated by issuer: signer must be
+// the CRL issuer of rl, have the cRLSign key usage, and be signed by issuer.
+// Unlike CheckSignatureFrom, signer does not need to be a CA.
+func (rl *RevocationList) CheckSignatureFromDelegated(signer, issuer *Certificate) error {
+	if signer == nil || issuer == nil {
+		return errors.New("x509: CRL signer and issuer can not be nil")
+	}
+	if !bytes.Equal(rl.RawIssuer, signer.RawSubject) {
+		return errors.New("x509: CRL issuer is not the subject of the CRL signer")
+	}
+	if signer.KeyUsage&KeyUsageCRLSign == 0 {
+		return x509.ConstraintViolationError{}
+	}
+	if err := signer.CheckSignatureFrom(issuer); err != nil {
+		return err
+	}
+	if signer.PublicKeyAlgorithm == UnknownPublicKeyAlgorithm {
+		return x509.ErrUnsupportedAlgorithm
+	}
+	return signer.CheckSignature(rl.SignatureAlgorithm, rl.RawTBSRevocationList, rl.Signature)
+}
+
+// CertificateIssuers returns the issuer of each of rl.RevokedCertificateEntries,
+// from the certificate issuer entry extensions of indirect CRLs, RFC 5280,
+// 5.3.3. An entry without the extension has the issuer of the previous entry,
+// and the first ones the CRL issuer.
+func (rl *RevocationList) CertificateIssuers() ([]*pkix.Name, error) {
+	raw, err := rl.rawCertificateIssuers()
+	if err != nil {
+		return nil, err
+	}
+	issuers := make([]*pkix.Name, len(raw))
+	for i, der := range raw {
+		if i > 0 && bytes.Equal(der, raw[i-1]) {
+			issuers[i] = issuers[i-1]
+			continue
+		}
+		if issuers[i], err = parseDirectoryName(der); err != nil {
+			return nil, err
+		}
+	}
+	return issuers, nil
+}
+
+// RevocationEntry returns the entry of rl for cert, with its serial number
+// and issuer, taking the certificate issuer entry extensions into account, or
+// nil if cert is not revoked by rl. A certificate without a serial number is
+// never revoked.
+func (rl *RevocationList) RevocationEntry(cert *Certificate) (*x509.RevocationListEntry, error) {
+	if cert == nil || cert.SerialNumber == nil {
+		return nil, nil
+	}
+	raw, err := rl.rawCertificateIssuers()
+	if err != nil {
+		return nil, err
+	}
+	for i := range rl.RevokedCertificateEntries {
+		rce := &rl.RevokedCertificateEntries[i]
+		if rce.SerialNumber == nil || rce.SerialNumber.Cmp(cert.SerialNumber) != 0 {
+			continue
+		}
+		if bytes.Equal(raw[i], cert.RawIssuer) {
+			return rce, nil
+		}
+		if name, err := parseDirectoryName(raw[i]); err == nil && name.String() == cert.Issuer.String() {
+			return rce, nil
+		}
+	}
+	return nil, nil
+}
+
+// rawCertificateIssuers returns the DER issuer names of the entries of rl,
+// see CertificateIssuers.
+func (rl *RevocationList) rawCertificateIssuers() ([][]byte, error) {
+	issuers := make([][]byte, len(rl.RevokedCertificateEntries))
+	issuer := []byte(rl.RawIssuer)
+	for i, rce := range rl.RevokedCertificateEntries {
+		for _, e := range rce.Extensions {
+			if e.Id.Equal(oidExtensionCertificateIssuer) {
+				der, err := parseCertificateIssuer(e.Value)
+				if err != nil {
+					return nil, err
+				}
+				issuer = der
+			}
+		}
+		issuers[i] = issuer
+	}
+	return issuers, nil
+}
+
+// parseCertificateIssuer returns the directory name of a certificate issuer
+// extension, which is GeneralNames.
+func parseCertificateIssuer(der []byte) ([]byte, error) {
+	var names []asn1.RawValue
+	if rest, err := asn1.Unmarshal(der, &names); err != nil || len(rest) > 0 {
+		return nil, errors.New("x509: invalid certificate issuer")
+	}
+	for _, name := range names {
+		if name.Class == asn1.ClassContextSpecific && name.Tag == tagDirectoryName && name.IsCompound {
+			return name.Bytes, nil
+		}
+	}
+	return nil, errors.New("x509: certificate issuer has no directory name")
+}
@@ -0,0 +1,156 @@
+package smx509
+
+import (
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+
+	"github.com/yunmoon/gmsm/sm2"
+)
+
+// delegatedCRLPEM is an indirect CRL signed by the delegated CRL signer
+// delegatedCRLSignerCertPEM, issued by delegatedCRLRootCertPEM. Its entries
+// are serial 100 of the CRL signer, 101 and 102 of the root CA, and 101 of
+// another CA.
+const (
+	delegatedCRLRootCertPEM = `-----BEGIN CERTIFICATE-----
+MIIBdTCCARugAwIBAgIBATAKBggqgRzPVQGDdTAiMQswCQYDVQQKEwJHTTETMBEG
+A1UEAxMKR00gUm9vdCBDQTAeFw0yNDAxMDEwMDAwMDBaFw00NDAxMDEwMDAwMDBa
+MCIxCzAJBgNVBAoTAkdNMRMwEQYDVQQDEwpHTSBSb290IENBMFkwEwYHKoZIzj0C
+AQYIKoEcz1UBgi0DQgAER4OV423WZ0nFIgPc04DD56+rVn3GxpQhJ+gQhZWI/3Hq
+Y3GXsBkJDHm3ySUU5e8vDKtY9V2fH2+/Tn6w+M+6BqNCMEAwDgYDVR0PAQH/BAQD
+AgEGMA8GA1UdEwEB/wQFMAMBAf8wHQYDVR0OBBYEFPNuc1pwMDAmNMO4/KiYEbvG
+7eYYMAoGCCqBHM9VAYN1A0gAMEUCIQDaiTKTYIGuoO+N2HLtvTAXYNAHxbdOzSEi
+PiN+Iq5esgIgUPGmRXpswFFExTG/46qKd6bVF6hwcJ+I3lbaKS3wyr8=
+-----END CERTIFICATE-----
+`
+	delegatedCRLSignerCertPEM = `-----BEGIN CERTIFICATE-----
+MIIBlTCCATygAwIBAgIBAjAKBggqgRzPVQGDdTAiMQswCQYDVQQKEwJHTTETMBEG
+A1UEAxMKR00gUm9vdCBDQTAeFw0yNDAxMDEwMDAwMDBaFw00NDAxMDEwMDAwMDBa
+MCUxCzAJBgNVBAoTAkdNMRYwFAYDVQQDEw1HTSBDUkwgU2lnbmVyMFkwEwYHKoZI
+zj0CAQYIKoEcz1UBgi0DQgAEBjqA6W4GhAmJf18FxSt6TzxLq6mMNYZXhrThAm9z
+lk9jDjQrgdaguJd9nbCepnY4wVqYcbyhfhG9AVJkhaRlbKNgMF4wDgYDVR0PAQH/
+BAQDAgECMAwGA1UdEwEB/wQCMAAwHQYDVR0OBBYEFENSTFNpZ25lci1HTS1TTTIt
+S0lEMB8GA1UdIwQYMBaAFPNuc1pwMDAmNMO4/KiYEbvG7eYYMAoGCCqBHM9VAYN1
+A0cAMEQCIHSP3OXYK0TmdixyWPnnb0UiHRYb+1gmIbNUBdGX0lf+AiA5vbMdVeX6
+aa/028P8XppE/g/QmTH1KSlk3oG2ACFf8w==
+-----END CERTIFICATE-----
+`
+	delegatedCRLPEM = `-----BEGIN X509 CRL-----
+MIICDjCCAbMCAQEwCgYIKoEcz1UBg3UwJTELMAkGA1UEChMCR00xFjAUBgNVBAMT
+DUdNIENSTCBTaWduZXIXDTI1MDYwMTAwMDAwMFoXDTQ0MDEwMTAwMDAwMFowgfEw
+IAIBZBcNMjUwNjAxMDAwMDAwWjAMMAoGA1UdFQQDCgEBMFQCAWUXDTI1MDYwMTAw
+MDAwMFowQDAyBgNVHR0BAf8EKDAmpCQwIjELMAkGA1UEChMCR00xEzARBgNVBAMT
+CkdNIFJvb3QgQ0EwCgYDVR0VBAMKAQEwIAIBZhcNMjUwNjAxMDAwMDAwWjAMMAoG
+A1UdFQQDCgEEMFUCAWUXDTI1MDYwMTAwMDAwMFowQTAzBgNVHR0BAf8EKTAnpCUw
+IzELMAkGA1UEChMCR00xFDASBgNVBAMTC0dNIE90aGVyIENBMAoGA1UdFQQDCgED
+oGkwZzAfBgNVHSMEGDAWgBRDUkxTaWduZXItR00tU00yLUtJRDAKBgNVHRQEAwIB
+BTA4BgNVHRwBAf8ELjAsoCegJYYjaHR0cDovL2NybC5leGFtcGxlLmNvbS9pbmRp
+cmVjdC5jcmyEAf8wCgYIKoEcz1UBg3UDSQAwRgIhAL7wYCNm4IWpEOVMZzU35eUJ
+8Gyghwrbf/r+Xllu05r6AiEA0+3VZzuitbBtCJikR2XIwcjxTf8aqVmUk78cdTXq
++S8=
+-----END X509 CRL-----
+`
+)
+
+func TestCheckSignatureFromDelegated(t *testing.T) {
+	parseCert := func(data string) *Certificate {
+		t.Helper()
+		block, _ := pem.Decode([]byte(data))
+		cert, err := ParseCertificate(block.Bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert
+	}
+	root := parseCert(delegatedCRLRootCertPEM)
+	signer := parseCert(delegatedCRLSignerCertPEM)
+	block, _ := pem.Decode([]byte(delegatedCRLPEM))
+	crl, err := ParseRevocationList(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := crl.CheckSignatureFromDelegated(signer, root); err != nil {
+		t.Fatal(err)
+	}
+	// the signer is not a CA
+	if err := crl.CheckSignatureFrom(signer); err == nil {
+		t.Error("CRL signed by a delegated signer verified with CheckSignatureFrom")
+	}
+	if err := crl.CheckSignatureFromDelegated(root, root); err == nil {
+		t.Error("CRL verified with the root as the signer")
+	}
+
+	// a signer of another CA
+	otherKey, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := newTestIssuer(t, otherKey, root.Subject)
+	if err := crl.CheckSignatureFromDelegated(signer, other); err == nil {
+		t.Error("CRL verified with a signer not issued by the CA")
+	}
+
+	// a signer without cRLSign
+	signerTemplate := signer.asX509()
+	signerTemplate.KeyUsage = KeyUsageDigitalSignature
+	signerDER, err := CreateCertificate(rand.Reader, signerTemplate, other, signer.PublicKey, otherKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	noCRLSign, err := ParseCertificate(signerDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := crl.CheckSignatureFromDelegated(noCRLSign, other); err == nil {
+		t.Error("CRL verified with a signer without cRLSign")
+	}
+
+	issuers, err := crl.CertificateIssuers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{signer.Subject.String(), root.Subject.String(), root.Subject.String(), "CN=GM Other CA,O=GM"}
+	if len(issuers) != len(want) {
+		t.Fatalf("got %d issuers, want %d", len(issuers), len(want))
+	}
+	for i, issuer := range issuers {
+		if issuer.String() != want[i] {
+			t.Errorf("entry %d: got issuer %v, want %v", i, issuer, want[i])
+		}
+	}
+
+	for _, tt := range []struct {
+		serial int64
+		issuer *Certificate
+		reason int
+	}{
+		{100, signer, 1},
+		{101, root, 1},
+		{102, root, 4},
+		{100, root, -1},
+		{102, signer, -1},
+	} {
+		cert := &Certificate{SerialNumber: big.NewInt(tt.serial), RawIssuer: tt.issuer.RawSubject, Issuer: tt.issuer.Subject}
+		rce, err := crl.RevocationEntry(cert)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case tt.reason < 0 && rce != nil:
+			t.Errorf("serial %d of %v: unexpected entry", tt.serial, tt.issuer.Subject)
+		case tt.reason >= 0 && (rce == nil || rce.ReasonCode != tt.reason):
+			t.Errorf("serial %d of %v: unexpected entry %v", tt.serial, tt.issuer.Subject, rce)
+		}
+	}
+	otherCA := &Certificate{SerialNumber: big.NewInt(101), Issuer: pkix.Name{Organization: []string{"GM"}, CommonName: "GM Other CA"}}
+	if rce, err := crl.RevocationEntry(otherCA); err != nil || rce == nil || rce.ReasonCode != 3 {
+		t.Errorf("unexpected entry %v: %v", rce, err)
+	}
+	if rce, err := crl.RevocationEntry(&Certificate{}); err != nil || rce != nil {
+		t.Errorf("unexpected entry for a certificate without serial number %v: %v", rce, err)
+	}
+}
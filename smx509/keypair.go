@@ -0,0 +1,98 @@
+package smx509
+
+import (
+	"crypto"
+	sdkecdh "crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"reflect"
+
+	"github.com/yunmoon/gmsm/ecdh"
+	"github.com/yunmoon/gmsm/sm2"
+)
+
+var (
+	// ErrKeyAlgorithmMismatch is returned by CheckKeyPair and CheckPublicKey
+	// when the keys are of different algorithms or curves.
+	ErrKeyAlgorithmMismatch = errors.New("x509: public key algorithm does not match the certificate")
+	// ErrKeyMismatch is returned by CheckKeyPair and CheckPublicKey when the keys
+	// are of the same algorithm but are not the same key.
+	ErrKeyMismatch = errors.New("x509: public key does not match the certificate")
+)
+
+// CheckKeyPair checks that priv is the private key of the public key
+// in the certificate.
+func CheckKeyPair(cert *Certificate, priv crypto.Signer) error {
+	return CheckPublicKey(cert, priv.Public())
+}
+
+// CheckPublicKey checks that pub is the public key in the certificate.
+// SM2 and NIST curve keys are compared in their *ecdsa.PublicKey or
+// *ecdh.PublicKey form alike. It returns ErrKeyAlgorithmMismatch or
+// ErrKeyMismatch if the keys differ.
+func CheckPublicKey(cert *Certificate, pub crypto.PublicKey) error {
+	certPub, err := normalizePublicKey(cert.PublicKey)
+	if err != nil {
+		return err
+	}
+	if pub, err = normalizePublicKey(pub); err != nil {
+		return err
+	}
+	switch certPub := certPub.(type) {
+	case *rsa.PublicKey:
+		if pub, ok := pub.(*rsa.PublicKey); !ok {
+			return ErrKeyAlgorithmMismatch
+		} else if !certPub.Equal(pub) {
+			return ErrKeyMismatch
+		}
+	case ed25519.PublicKey:
+		if pub, ok := pub.(ed25519.PublicKey); !ok {
+			return ErrKeyAlgorithmMismatch
+		} else if !certPub.Equal(pub) {
+			return ErrKeyMismatch
+		}
+	case *ecdh.PublicKey:
+		if pub, ok := pub.(*ecdh.PublicKey); !ok || pub.Curve() != certPub.Curve() {
+			return ErrKeyAlgorithmMismatch
+		} else if !certPub.Equal(pub) {
+			return ErrKeyMismatch
+		}
+	case *sdkecdh.PublicKey:
+		if pub, ok := pub.(*sdkecdh.PublicKey); !ok || pub.Curve() != certPub.Curve() {
+			return ErrKeyAlgorithmMismatch
+		} else if !certPub.Equal(pub) {
+			return ErrKeyMismatch
+		}
+	default:
+		if reflect.TypeOf(certPub) != reflect.TypeOf(pub) {
+			return ErrKeyAlgorithmMismatch
+		}
+		key, ok := certPub.(interface{ Equal(crypto.PublicKey) bool })
+		if !ok {
+			return errors.New("x509: unsupported public key type in certificate")
+		}
+		if !key.Equal(pub) {
+			return ErrKeyMismatch
+		}
+	}
+	return nil
+}
+
+// normalizePublicKey converts EC public keys to their ecdh form, so that keys
+// on the same curve can be compared whatever their representation.
+func normalizePublicKey(pub crypto.PublicKey) (crypto.PublicKey, error) {
+	switch k := pub.(type) {
+	case *SM2OIDPublicKey:
+		return normalizePublicKey(k.PublicKey)
+	case *CompressedECPublicKey:
+		return normalizePublicKey(k.PublicKey)
+	case *ecdsa.PublicKey:
+		if k.Curve == sm2.P256() {
+			return sm2.PublicKeyToECDH(k)
+		}
+		return k.ECDH()
+	}
+	return pub, nil
+}
@@ -0,0 +1,99 @@
+package smx509
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/yunmoon/gmsm/sm2"
+)
+
+func selfSignedCertificate(t *testing.T, priv crypto.Signer) *Certificate {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "key pair"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := CreateCertificate(rand.Reader, template, template, priv.Public(), priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestCheckKeyPair(t *testing.T) {
+	sm2Key, _ := sm2.GenerateKey(rand.Reader)
+	otherSM2Key, _ := sm2.GenerateKey(rand.Reader)
+	p256Key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p384Key, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	otherRSAKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	_, ed25519Key, _ := ed25519.GenerateKey(rand.Reader)
+
+	keys := []crypto.Signer{sm2Key, p256Key, p384Key, rsaKey, ed25519Key}
+	for i, key := range keys {
+		cert := selfSignedCertificate(t, key)
+		for j, other := range keys {
+			err := CheckKeyPair(cert, other)
+			if i == j && err != nil {
+				t.Errorf("%T: %v", key, err)
+			}
+			if i != j && err != ErrKeyAlgorithmMismatch {
+				t.Errorf("%T, %T: expected ErrKeyAlgorithmMismatch, got %v", key, other, err)
+			}
+		}
+	}
+
+	sm2Cert := selfSignedCertificate(t, sm2Key)
+	if err := CheckKeyPair(sm2Cert, otherSM2Key); err != ErrKeyMismatch {
+		t.Errorf("expected ErrKeyMismatch, got %v", err)
+	}
+	if err := CheckKeyPair(selfSignedCertificate(t, rsaKey), otherRSAKey); err != ErrKeyMismatch {
+		t.Errorf("expected ErrKeyMismatch, got %v", err)
+	}
+
+	// ecdh and wrapped representations of the same EC key
+	sm2ECDH, err := sm2Key.ECDH()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p256ECDH, err := p256Key.ECDH()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p256Cert := selfSignedCertificate(t, p256Key)
+	tests := []struct {
+		cert *Certificate
+		pub  crypto.PublicKey
+		want error
+	}{
+		{sm2Cert, sm2ECDH.PublicKey(), nil},
+		{sm2Cert, &SM2OIDPublicKey{&sm2Key.PublicKey}, nil},
+		{sm2Cert, &CompressedECPublicKey{&sm2Key.PublicKey}, nil},
+		{sm2Cert, &otherSM2Key.PublicKey, ErrKeyMismatch},
+		{sm2Cert, p256ECDH.PublicKey(), ErrKeyAlgorithmMismatch},
+		{p256Cert, p256ECDH.PublicKey(), nil},
+		{p256Cert, &CompressedECPublicKey{&p256Key.PublicKey}, nil},
+		{p256Cert, sm2ECDH.PublicKey(), ErrKeyAlgorithmMismatch},
+		{p256Cert, ed25519Key.Public(), ErrKeyAlgorithmMismatch},
+	}
+	for i, tt := range tests {
+		if err := CheckPublicKey(tt.cert, tt.pub); err != tt.want {
+			t.Errorf("case %d: expected %v, got %v", i, tt.want, err)
+		}
+	}
+}
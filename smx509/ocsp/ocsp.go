@@ -0,0 +1,687 @@
+// Package ocsp creates OCSP requests and parses OCSP responses, RFC 6960,
+// with the CertIDs hashed with SM3 and the responses signed with SM2WithSM3
+// used by GM OCSP responders, as well as the SHA-1 and SHA-256 CertIDs and
+// the signature algorithms supported by smx509.
+package ocsp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/yunmoon/gmsm/sm3"
+	"github.com/yunmoon/gmsm/smx509"
+)
+
+var (
+	oidSM3    = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 401}
+	oidSHA1   = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+
+	oidOCSPBasicResponse = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 1}
+	oidOCSPNonce         = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 2}
+	oidOCSPNoCheck       = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 5}
+	oidOCSPArchiveCutoff = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 6}
+)
+
+// HashAlgorithm is the hash of the issuer name and key in a CertID.
+type HashAlgorithm int
+
+const (
+	// SM3 is the hash of GM OCSP, GM/T 0088.
+	SM3 HashAlgorithm = iota
+	SHA1
+	SHA256
+)
+
+var hashAlgorithms = []struct {
+	algo HashAlgorithm
+	name string
+	oid  asn1.ObjectIdentifier
+	new  func() hash.Hash
+}{
+	{SM3, "SM3", oidSM3, sm3.New},
+	{SHA1, "SHA-1", oidSHA1, sha1.New},
+	{SHA256, "SHA-256", oidSHA256, sha256.New},
+}
+
+func (h HashAlgorithm) String() string {
+	for _, details := range hashAlgorithms {
+		if details.algo == h {
+			return details.name
+		}
+	}
+	return strconv.Itoa(int(h))
+}
+
+func (h HashAlgorithm) oid() (asn1.ObjectIdentifier, error) {
+	for _, details := range hashAlgorithms {
+		if details.algo == h {
+			return details.oid, nil
+		}
+	}
+	return nil, fmt.Errorf("ocsp: unsupported hash algorithm %v", h)
+}
+
+func (h HashAlgorithm) new() hash.Hash {
+	for _, details := range hashAlgorithms {
+		if details.algo == h {
+			return details.new()
+		}
+	}
+	return nil
+}
+
+func hashAlgorithmFromOID(oid asn1.ObjectIdentifier) (HashAlgorithm, error) {
+	for _, details := range hashAlgorithms {
+		if details.oid.Equal(oid) {
+			return details.algo, nil
+		}
+	}
+	return 0, fmt.Errorf("ocsp: unsupported hash algorithm %v", oid)
+}
+
+// The status of a certificate in a Response.
+const (
+	Good = iota
+	Revoked
+	Unknown
+)
+
+// ResponseStatus is the status of an OCSP response, other than successful
+// ones.
+type ResponseStatus int
+
+const (
+	Success           ResponseStatus = 0
+	Malformed         ResponseStatus = 1
+	InternalError     ResponseStatus = 2
+	TryLater          ResponseStatus = 3
+	SignatureRequired ResponseStatus = 5
+	Unauthorized      ResponseStatus = 6
+)
+
+func (r ResponseStatus) String() string {
+	switch r {
+	case Success:
+		return "success"
+	case Malformed:
+		return "malformed"
+	case InternalError:
+		return "internal error"
+	case TryLater:
+		return "try later"
+	case SignatureRequired:
+		return "signature required"
+	case Unauthorized:
+		return "unauthorized"
+	default:
+		return "unknown OCSP status: " + strconv.Itoa(int(r))
+	}
+}
+
+// ResponseError is returned by ParseResponse when the responder did not
+// return a successful response.
+type ResponseError struct {
+	Status ResponseStatus
+}
+
+func (r ResponseError) Error() string {
+	return "ocsp: error from server: " + r.Status.String()
+}
+
+// These are internal structures that reflect the ASN.1 structure of an OCSP
+// request and response, RFC 6960, 4.1.1 and 4.2.1.
+
+type certID struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	NameHash      []byte
+	IssuerKeyHash []byte
+	SerialNumber  *big.Int
+}
+
+type ocspRequest struct {
+	TBSRequest tbsRequest
+}
+
+type tbsRequest struct {
+	Version           int              `asn1:"explicit,tag:0,default:0,optional"`
+	RequestorName     pkix.RDNSequence `asn1:"explicit,tag:1,optional"`
+	RequestList       []request
+	RequestExtensions []pkix.Extension `asn1:"explicit,tag:2,optional"`
+}
+
+type request struct {
+	Cert certID
+}
+
+type responseASN1 struct {
+	Status   asn1.Enumerated
+	Response responseBytes `asn1:"explicit,tag:0,optional"`
+}
+
+type responseBytes struct {
+	ResponseType asn1.ObjectIdentifier
+	Response     []byte
+}
+
+type basicResponse struct {
+	TBSResponseData    responseData
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          asn1.BitString
+	Certificates       []asn1.RawValue `asn1:"explicit,tag:0,optional"`
+}
+
+type responseData struct {
+	Raw                asn1.RawContent
+	Version            int `asn1:"optional,default:0,explicit,tag:0"`
+	RawResponderID     asn1.RawValue
+	ProducedAt         time.Time `asn1:"generalized"`
+	Responses          []singleResponse
+	ResponseExtensions []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+type singleResponse struct {
+	CertID           certID
+	Good             asn1.Flag        `asn1:"tag:0,optional"`
+	Revoked          revokedInfo      `asn1:"tag:1,optional"`
+	Unknown          asn1.Flag        `asn1:"tag:2,optional"`
+	ThisUpdate       time.Time        `asn1:"generalized"`
+	NextUpdate       time.Time        `asn1:"generalized,explicit,tag:0,optional"`
+	SingleExtensions []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+type revokedInfo struct {
+	RevocationTime time.Time       `asn1:"generalized"`
+	Reason         asn1.Enumerated `asn1:"explicit,tag:0,optional"`
+}
+
+// The limits of the length of a nonce, RFC 8954, 2.1.
+const (
+	minNonceSize = 1
+	maxNonceSize = 32
+)
+
+var (
+	// ErrNonceAbsent is returned by ParseResponseWithOptions when the
+	// request had a nonce, but the response does not, as with the
+	// responders which do not support nonces or return a cached response.
+	ErrNonceAbsent = errors.New("ocsp: OCSP response has no nonce")
+	// ErrNonceMismatch is returned by ParseResponseWithOptions when the
+	// nonce of the response is not that of the request, as in a replayed
+	// response.
+	ErrNonceMismatch = errors.New("ocsp: OCSP response nonce does not match the request")
+)
+
+// checkNonceSize returns an error if nonce is not 1 to 32 bytes long.
+func checkNonceSize(nonce []byte) error {
+	if len(nonce) < minNonceSize || len(nonce) > maxNonceSize {
+		return fmt.Errorf("ocsp: nonce of %d bytes, must be %d to %d bytes", len(nonce), minNonceSize, maxNonceSize)
+	}
+	return nil
+}
+
+// RequestOptions contains the options of CreateRequest.
+type RequestOptions struct {
+	// Hash is the hash of the CertID, SM3 by default.
+	Hash HashAlgorithm
+	// Nonce is sent in the nonce extension, RFC 8954, and echoed in
+	// Response.Nonce by the responders which support it. If empty, a
+	// random nonce of NonceSize bytes is generated, unless OmitNonce is
+	// set. A nonce is 1 to 32 bytes long.
+	Nonce []byte
+	// NonceSize is the size of a generated nonce, 32 bytes by default.
+	NonceSize int
+	// OmitNonce creates a request without nonce, which responders may
+	// answer with a cached response.
+	OmitNonce bool
+}
+
+// Request is an OCSP request for a single certificate.
+type Request struct {
+	HashAlgorithm  HashAlgorithm
+	IssuerNameHash []byte
+	IssuerKeyHash  []byte
+	SerialNumber   *big.Int
+	Nonce          []byte
+}
+
+// issuerHashes returns the hashes of the subject and the public key of
+// issuer, the latter without the tag, length and unused bits of its BIT
+// STRING, RFC 6960, 4.1.1.
+func issuerHashes(issuer *smx509.Certificate, h HashAlgorithm) (nameHash, keyHash []byte, err error) {
+	var spki struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if rest, err := asn1.Unmarshal(issuer.RawSubjectPublicKeyInfo, &spki); err != nil {
+		return nil, nil, err
+	} else if len(rest) != 0 {
+		return nil, nil, errors.New("ocsp: trailing data after the issuer public key")
+	}
+	hash := h.new()
+	if hash == nil {
+		return nil, nil, fmt.Errorf("ocsp: unsupported hash algorithm %v", h)
+	}
+	hash.Write(issuer.RawSubject)
+	nameHash = hash.Sum(nil)
+	hash.Reset()
+	hash.Write(spki.PublicKey.RightAlign())
+	keyHash = hash.Sum(nil)
+	return nameHash, keyHash, nil
+}
+
+// CreateRequest returns a DER-encoded OCSP request for the status of cert,
+// issued by issuer. A nil opts is equivalent to the zero RequestOptions.
+func CreateRequest(cert, issuer *smx509.Certificate, opts *RequestOptions) ([]byte, error) {
+	der, _, err := CreateRequestWithNonce(cert, issuer, opts)
+	return der, err
+}
+
+// CreateRequestWithNonce is like CreateRequest, and also returns the nonce of
+// the request, nil if it has none, to check the response with
+// ParseResponseWithOptions.
+func CreateRequestWithNonce(cert, issuer *smx509.Certificate, opts *RequestOptions) (der, nonce []byte, err error) {
+	if opts == nil {
+		opts = &RequestOptions{}
+	}
+	oid, err := opts.Hash.oid()
+	if err != nil {
+		return nil, nil, err
+	}
+	nameHash, keyHash, err := issuerHashes(issuer, opts.Hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !opts.OmitNonce {
+		nonce = opts.Nonce
+		if len(nonce) == 0 {
+			size := opts.NonceSize
+			if size == 0 {
+				size = maxNonceSize
+			}
+			if size < minNonceSize || size > maxNonceSize {
+				return nil, nil, fmt.Errorf("ocsp: nonce size %d, must be %d to %d bytes", size, minNonceSize, maxNonceSize)
+			}
+			nonce = make([]byte, size)
+			if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+				return nil, nil, err
+			}
+		} else if err := checkNonceSize(nonce); err != nil {
+			return nil, nil, err
+		}
+	}
+	req := ocspRequest{
+		TBSRequest: tbsRequest{
+			RequestList: []request{{
+				Cert: certID{
+					HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oid, Parameters: asn1.NullRawValue},
+					NameHash:      nameHash,
+					IssuerKeyHash: keyHash,
+					SerialNumber:  cert.SerialNumber,
+				},
+			}},
+		},
+	}
+	if nonce != nil {
+		value, err := asn1.Marshal(nonce)
+		if err != nil {
+			return nil, nil, err
+		}
+		req.TBSRequest.RequestExtensions = []pkix.Extension{{Id: oidOCSPNonce, Value: value}}
+	}
+	der, err = asn1.Marshal(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	return der, nonce, nil
+}
+
+// ParseRequest parses an OCSP request for a single certificate. Signed
+// requests are not supported.
+func ParseRequest(der []byte) (*Request, error) {
+	var req ocspRequest
+	rest, err := asn1.Unmarshal(der, &req)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, errors.New("ocsp: trailing data in OCSP request")
+	}
+	if len(req.TBSRequest.RequestList) != 1 {
+		return nil, errors.New("ocsp: OCSP request does not contain exactly one certificate")
+	}
+	id := req.TBSRequest.RequestList[0].Cert
+	h, err := hashAlgorithmFromOID(id.HashAlgorithm.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := parseNonce(req.TBSRequest.RequestExtensions)
+	if err != nil {
+		return nil, err
+	}
+	return &Request{
+		HashAlgorithm:  h,
+		IssuerNameHash: id.NameHash,
+		IssuerKeyHash:  id.IssuerKeyHash,
+		SerialNumber:   id.SerialNumber,
+		Nonce:          nonce,
+	}, nil
+}
+
+// parseNonce returns the value of the nonce extension in extensions, or nil.
+// The value is an OCTET STRING, RFC 8954, but some implementations put the
+// nonce directly in the extension, in which case it is returned as is. A
+// nonce longer than 32 bytes is rejected.
+func parseNonce(extensions []pkix.Extension) ([]byte, error) {
+	for _, ext := range extensions {
+		if ext.Id.Equal(oidOCSPNonce) {
+			var value []byte
+			if rest, err := asn1.Unmarshal(ext.Value, &value); err != nil || len(rest) != 0 {
+				value = ext.Value
+			}
+			if err := checkNonceSize(value); err != nil {
+				return nil, err
+			}
+			return value, nil
+		}
+	}
+	return nil, nil
+}
+
+// Response is an OCSP response for a single certificate.
+type Response struct {
+	Raw []byte
+
+	// Status is Good, Revoked or Unknown.
+	Status       int
+	SerialNumber *big.Int
+	// RevokedAt and RevocationReason are set if Status is Revoked. The
+	// reason is a CRL reason code, RFC 5280, 5.3.1, or zero if the
+	// response does not have one.
+	RevokedAt        time.Time
+	RevocationReason int
+
+	ProducedAt time.Time
+	ThisUpdate time.Time
+	// NextUpdate is the zero time if the responder has newer information
+	// available at any time.
+	NextUpdate time.Time
+	// ArchiveCutoff is the time of the archiveCutoff extension of the
+	// certificate status, RFC 6960, 4.4.4, or the zero time: the responder
+	// keeps the status of the certificates which expired since then.
+	ArchiveCutoff time.Time
+
+	// IssuerHash is the hash of the CertID of the response.
+	IssuerHash HashAlgorithm
+
+	// RawResponderName is the subject of the responder in a response
+	// identified by name, ResponderKeyHash its SHA-1 key hash in a response
+	// identified by key.
+	RawResponderName []byte
+	ResponderKeyHash []byte
+
+	// Certificate is the delegated responder certificate which signed the
+	// response, or nil if the issuer signed it.
+	Certificate *smx509.Certificate
+	// ResponderNoCheck reports whether Certificate has the
+	// id-pkix-ocsp-nocheck extension, RFC 6960, 4.2.2.2.1: its revocation
+	// status need not be checked, which would be recursive.
+	ResponderNoCheck bool
+
+	TBSResponseData    []byte
+	Signature          []byte
+	SignatureAlgorithm smx509.SignatureAlgorithm
+
+	// Extensions are the responseExtensions of the response, and
+	// SingleExtensions the singleExtensions of its certificate status.
+	Extensions       []pkix.Extension
+	SingleExtensions []pkix.Extension
+
+	// Nonce is the value of the nonce extension of the response, which
+	// the responder copies from the request.
+	Nonce []byte
+}
+
+// now returns the current time; tests replace it.
+var now = time.Now
+
+// ParseResponse parses an OCSP response for a single certificate, issued by
+// issuer, and checks it: the response must be signed by issuer, or by a
+// delegated responder certificate included in the response, found by the
+// responder ID, issued by issuer and valid for OCSP signing; its CertID must
+// be that of a certificate of issuer; and the current time must be between
+// its thisUpdate and nextUpdate. The revocation status of a delegated
+// responder is not checked, see Response.ResponderNoCheck.
+//
+// If the responder did not return a successful response, the error is a
+// ResponseError.
+func ParseResponse(der []byte, issuer *smx509.Certificate) (*Response, error) {
+	return ParseResponseForCert(der, nil, issuer)
+}
+
+// ParseResponseForCert is like ParseResponse, for a response which may
+// contain the statuses of several certificates: it returns the status of
+// cert. If cert is nil, the response must contain a single status.
+func ParseResponseForCert(der []byte, cert, issuer *smx509.Certificate) (*Response, error) {
+	return ParseResponseWithOptions(der, issuer, &ParseResponseOptions{Certificate: cert})
+}
+
+// ParseResponseOptions contains the options of ParseResponseWithOptions.
+type ParseResponseOptions struct {
+	// Certificate selects the status to return in a response which may
+	// contain several, as in ParseResponseForCert.
+	Certificate *smx509.Certificate
+	// Nonce, if not empty, is the nonce of the request, which the response
+	// must contain. If the response has no nonce, the error is
+	// ErrNonceAbsent, which callers may choose to accept by parsing the
+	// response again without Nonce. If it has another nonce, the error is
+	// ErrNonceMismatch.
+	Nonce []byte
+}
+
+// ParseResponseWithOptions is like ParseResponse, with options. A nil opts is
+// equivalent to the zero ParseResponseOptions.
+func ParseResponseWithOptions(der []byte, issuer *smx509.Certificate, opts *ParseResponseOptions) (*Response, error) {
+	if opts == nil {
+		opts = &ParseResponseOptions{}
+	}
+	cert := opts.Certificate
+	if issuer == nil {
+		return nil, errors.New("ocsp: issuer is required")
+	}
+	var resp responseASN1
+	rest, err := asn1.Unmarshal(der, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, errors.New("ocsp: trailing data in OCSP response")
+	}
+	if status := ResponseStatus(resp.Status); status != Success {
+		return nil, ResponseError{status}
+	}
+	if !resp.Response.ResponseType.Equal(oidOCSPBasicResponse) {
+		return nil, errors.New("ocsp: bad OCSP response type")
+	}
+
+	var basic basicResponse
+	rest, err = asn1.Unmarshal(resp.Response.Response, &basic)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, errors.New("ocsp: trailing data in OCSP response")
+	}
+	var single *singleResponse
+	if cert == nil {
+		if n := len(basic.TBSResponseData.Responses); n != 1 {
+			return nil, fmt.Errorf("ocsp: OCSP response contains %d results, expected 1", n)
+		}
+		single = &basic.TBSResponseData.Responses[0]
+	} else {
+		for i, resp := range basic.TBSResponseData.Responses {
+			if resp.CertID.SerialNumber != nil && resp.CertID.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+				single = &basic.TBSResponseData.Responses[i]
+				break
+			}
+		}
+		if single == nil {
+			return nil, errors.New("ocsp: OCSP response does not contain the status of the certificate")
+		}
+	}
+
+	r := &Response{
+		Raw:                der,
+		SerialNumber:       single.CertID.SerialNumber,
+		ProducedAt:         basic.TBSResponseData.ProducedAt,
+		ThisUpdate:         single.ThisUpdate,
+		NextUpdate:         single.NextUpdate,
+		TBSResponseData:    basic.TBSResponseData.Raw,
+		Signature:          basic.Signature.RightAlign(),
+		SignatureAlgorithm: smx509.SignatureAlgorithmFromOID(basic.SignatureAlgorithm.Algorithm),
+		Extensions:         basic.TBSResponseData.ResponseExtensions,
+		SingleExtensions:   single.SingleExtensions,
+	}
+	r.Nonce, err = parseNonce(r.Extensions)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ext := range single.SingleExtensions {
+		if ext.Id.Equal(oidOCSPArchiveCutoff) {
+			if rest, err := asn1.UnmarshalWithParams(ext.Value, &r.ArchiveCutoff, "generalized"); err != nil || len(rest) != 0 {
+				return nil, errors.New("ocsp: malformed archive cutoff extension")
+			}
+		}
+	}
+
+	responderID := basic.TBSResponseData.RawResponderID
+	switch {
+	case responderID.Class == asn1.ClassContextSpecific && responderID.Tag == 1:
+		r.RawResponderName = responderID.Bytes
+	case responderID.Class == asn1.ClassContextSpecific && responderID.Tag == 2:
+		if rest, err := asn1.Unmarshal(responderID.Bytes, &r.ResponderKeyHash); err != nil || len(rest) != 0 {
+			return nil, errors.New("ocsp: malformed responder key hash")
+		}
+	default:
+		return nil, errors.New("ocsp: invalid responder ID")
+	}
+
+	switch {
+	case bool(single.Good):
+		r.Status = Good
+	case bool(single.Unknown):
+		r.Status = Unknown
+	default:
+		r.Status = Revoked
+		r.RevokedAt = single.Revoked.RevocationTime
+		r.RevocationReason = int(single.Revoked.Reason)
+	}
+
+	signer, err := findResponder(r, basic.Certificates, issuer)
+	if err != nil {
+		return nil, err
+	}
+	if err := signer.CheckSignature(r.SignatureAlgorithm, r.TBSResponseData, r.Signature); err != nil {
+		return nil, fmt.Errorf("ocsp: bad signature on OCSP response: %w", err)
+	}
+
+	r.IssuerHash, err = hashAlgorithmFromOID(single.CertID.HashAlgorithm.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	nameHash, keyHash, err := issuerHashes(issuer, r.IssuerHash)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(single.CertID.NameHash, nameHash) || !bytes.Equal(single.CertID.IssuerKeyHash, keyHash) {
+		return nil, errors.New("ocsp: OCSP response is for a certificate of another issuer")
+	}
+
+	t := now()
+	if t.Before(r.ThisUpdate) {
+		return nil, errors.New("ocsp: OCSP response is not yet valid")
+	}
+	if !r.NextUpdate.IsZero() && t.After(r.NextUpdate) {
+		return nil, errors.New("ocsp: OCSP response has expired")
+	}
+
+	if len(opts.Nonce) > 0 {
+		if r.Nonce == nil {
+			return nil, ErrNonceAbsent
+		}
+		if !bytes.Equal(r.Nonce, opts.Nonce) {
+			return nil, ErrNonceMismatch
+		}
+	}
+	return r, nil
+}
+
+// findResponder returns the certificate which signed r, after checking it:
+// either issuer, or the certificate among certs identified by the responder
+// ID of r, which must be issued by issuer and valid for OCSP signing, and
+// which is then set as r.Certificate.
+func findResponder(r *Response, certs []asn1.RawValue, issuer *smx509.Certificate) (*smx509.Certificate, error) {
+	if ok, err := matchesResponderID(r, issuer); err != nil {
+		return nil, err
+	} else if ok {
+		return issuer, nil
+	}
+	for _, raw := range certs {
+		responder, err := smx509.ParseCertificate(raw.FullBytes)
+		if err != nil {
+			return nil, err
+		}
+		if ok, err := matchesResponderID(r, responder); err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+		if err := responder.CheckSignatureFrom(issuer); err != nil {
+			return nil, fmt.Errorf("ocsp: bad responder certificate: %w", err)
+		}
+		if !hasOCSPSigning(responder) {
+			return nil, errors.New("ocsp: responder certificate is not valid for OCSP signing")
+		}
+		r.Certificate = responder
+		for _, ext := range responder.Extensions {
+			if ext.Id.Equal(oidOCSPNoCheck) {
+				r.ResponderNoCheck = true
+			}
+		}
+		return responder, nil
+	}
+	return nil, errors.New("ocsp: OCSP response is not signed by the issuer nor by an included responder certificate")
+}
+
+// matchesResponderID reports whether cert is the responder identified in r.
+func matchesResponderID(r *Response, cert *smx509.Certificate) (bool, error) {
+	if r.RawResponderName != nil {
+		return bytes.Equal(r.RawResponderName, cert.RawSubject), nil
+	}
+	_, keyHash, err := issuerHashes(cert, SHA1)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(r.ResponderKeyHash, keyHash), nil
+}
+
+func hasOCSPSigning(cert *smx509.Certificate) bool {
+	for _, eku := range cert.ExtKeyUsage {
+		if eku == smx509.ExtKeyUsageOCSPSigning {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,382 @@
+package ocsp
+
+import (
+	"bytes"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yunmoon/gmsm/smx509"
+)
+
+func loadCertificate(t *testing.T, data string) *smx509.Certificate {
+	t.Helper()
+	block, _ := pem.Decode([]byte(data))
+	cert, err := smx509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func decodeBase64(t *testing.T, data string) []byte {
+	t.Helper()
+	der, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+// setNow makes ParseResponse check the fixtures at t.
+func setNow(tb testing.TB, t time.Time) {
+	saved := now
+	now = func() time.Time { return t }
+	tb.Cleanup(func() { now = saved })
+}
+
+func TestCreateRequest(t *testing.T) {
+	issuer := loadCertificate(t, gmOCSPCACertPEM)
+	leaf := loadCertificate(t, gmOCSPLeafCertPEM)
+
+	// same encoding as OpenSSL
+	for _, test := range []struct {
+		opts *RequestOptions
+		want string
+	}{
+		{&RequestOptions{OmitNonce: true}, opensslSM3RequestBase64},
+		{&RequestOptions{Hash: SHA1, OmitNonce: true}, opensslSHA1RequestBase64},
+	} {
+		der, err := CreateRequest(leaf, issuer, test.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := decodeBase64(t, test.want); !bytes.Equal(der, want) {
+			t.Errorf("got request %x, want %x", der, want)
+		}
+	}
+
+	for _, h := range []HashAlgorithm{SM3, SHA1, SHA256} {
+		der, err := CreateRequest(leaf, issuer, &RequestOptions{Hash: h, Nonce: []byte("nonce")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := ParseRequest(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nameHash, keyHash, err := issuerHashes(issuer, h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if req.HashAlgorithm != h || !bytes.Equal(req.IssuerNameHash, nameHash) || !bytes.Equal(req.IssuerKeyHash, keyHash) ||
+			req.SerialNumber.Cmp(leaf.SerialNumber) != 0 || string(req.Nonce) != "nonce" {
+			t.Errorf("%v: unexpected request %+v", h, req)
+		}
+	}
+
+	if _, err := CreateRequest(leaf, issuer, &RequestOptions{Hash: HashAlgorithm(42)}); err == nil {
+		t.Error("expected error with an unsupported hash")
+	}
+}
+
+func TestRequestNonce(t *testing.T) {
+	issuer := loadCertificate(t, gmOCSPCACertPEM)
+	leaf := loadCertificate(t, gmOCSPLeafCertPEM)
+
+	for _, test := range []struct {
+		opts *RequestOptions
+		size int
+	}{
+		{nil, 32},
+		{&RequestOptions{NonceSize: 16}, 16},
+		{&RequestOptions{Nonce: []byte("nonce")}, 5},
+		{&RequestOptions{OmitNonce: true}, 0},
+	} {
+		der, nonce, err := CreateRequestWithNonce(leaf, issuer, test.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(nonce) != test.size {
+			t.Errorf("got a nonce of %d bytes, want %d", len(nonce), test.size)
+		}
+		req, err := ParseRequest(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(req.Nonce, nonce) {
+			t.Errorf("request nonce %x, want %x", req.Nonce, nonce)
+		}
+	}
+	_, first, _ := CreateRequestWithNonce(leaf, issuer, nil)
+	_, second, _ := CreateRequestWithNonce(leaf, issuer, nil)
+	if bytes.Equal(first, second) {
+		t.Error("the same nonce was generated twice")
+	}
+
+	// RFC 8954 limits
+	for _, opts := range []*RequestOptions{{NonceSize: 33}, {NonceSize: -1}, {Nonce: make([]byte, 33)}} {
+		if _, err := CreateRequest(leaf, issuer, opts); err == nil {
+			t.Errorf("expected error with %+v", opts)
+		}
+	}
+	der, err := CreateRequest(leaf, issuer, &RequestOptions{OmitNonce: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := ParseRequest(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Nonce != nil {
+		t.Errorf("unexpected nonce %x", req.Nonce)
+	}
+	var ocspReq ocspRequest
+	if _, err := asn1.Unmarshal(der, &ocspReq); err != nil {
+		t.Fatal(err)
+	}
+	value, _ := asn1.Marshal(bytes.Repeat([]byte{1}, 1024))
+	ocspReq.TBSRequest.RequestExtensions = []pkix.Extension{{Id: oidOCSPNonce, Value: value}}
+	if der, err = asn1.Marshal(ocspReq); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseRequest(der); err == nil {
+		t.Error("request with a nonce of 1024 bytes parsed")
+	}
+}
+
+func TestParseResponse(t *testing.T) {
+	setNow(t, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC))
+	issuer := loadCertificate(t, gmOCSPCACertPEM)
+	responder := loadCertificate(t, gmOCSPResponderCertPEM)
+	leaf := loadCertificate(t, gmOCSPLeafCertPEM)
+	revoked := loadCertificate(t, gmOCSPRevokedCertPEM)
+	thisUpdate := time.Date(2026, 10, 16, 14, 34, 5, 0, time.UTC)
+
+	resp, err := ParseResponse(decodeBase64(t, gmOCSPGoodResponseBase64), issuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != Good || resp.SerialNumber.Cmp(leaf.SerialNumber) != 0 || resp.IssuerHash != SM3 {
+		t.Errorf("unexpected status %d, serial number %v or hash %v", resp.Status, resp.SerialNumber, resp.IssuerHash)
+	}
+	if resp.SignatureAlgorithm != smx509.SM2WithSM3 || resp.Certificate == nil || !resp.Certificate.Equal(responder) {
+		t.Errorf("unexpected signature algorithm %v or certificate", resp.SignatureAlgorithm)
+	}
+	if !resp.ThisUpdate.Equal(thisUpdate) || !resp.ProducedAt.Equal(thisUpdate) || resp.NextUpdate.Year() != 2126 {
+		t.Errorf("unexpected times %v %v %v", resp.ProducedAt, resp.ThisUpdate, resp.NextUpdate)
+	}
+	if string(resp.Nonce) != "0123456789abcdef" {
+		t.Errorf("unexpected nonce %q", resp.Nonce)
+	}
+	if !bytes.Equal(resp.RawResponderName, responder.RawSubject) || resp.ResponderKeyHash != nil {
+		t.Errorf("unexpected responder ID %x %x", resp.RawResponderName, resp.ResponderKeyHash)
+	}
+
+	resp, err = ParseResponse(decodeBase64(t, gmOCSPRevokedResponseBase64), issuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != Revoked || resp.SerialNumber.Cmp(revoked.SerialNumber) != 0 || resp.RevocationReason != 1 ||
+		!resp.RevokedAt.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) || resp.Nonce != nil {
+		t.Errorf("unexpected response %+v", resp)
+	}
+
+	resp, err = ParseResponse(decodeBase64(t, gmOCSPIssuerSignedResponseBase64), issuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != Good || resp.IssuerHash != SHA1 || resp.Certificate != nil || resp.SerialNumber.Cmp(big.NewInt(0x1001)) != 0 ||
+		!bytes.Equal(resp.ResponderKeyHash, issuer.SubjectKeyId) {
+		t.Errorf("unexpected response %+v", resp)
+	}
+}
+
+func TestParseResponseNonce(t *testing.T) {
+	setNow(t, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC))
+	issuer := loadCertificate(t, gmOCSPCACertPEM)
+	good := decodeBase64(t, gmOCSPGoodResponseBase64)
+	revoked := decodeBase64(t, gmOCSPRevokedResponseBase64)
+
+	// matched
+	resp, err := ParseResponseWithOptions(good, issuer, &ParseResponseOptions{Nonce: []byte("0123456789abcdef")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp.Nonce) != "0123456789abcdef" {
+		t.Errorf("unexpected nonce %q", resp.Nonce)
+	}
+	// mismatched
+	if _, err := ParseResponseWithOptions(good, issuer, &ParseResponseOptions{Nonce: []byte("fedcba9876543210")}); !errors.Is(err, ErrNonceMismatch) {
+		t.Errorf("got error %v, want ErrNonceMismatch", err)
+	}
+	// absent
+	if _, err := ParseResponseWithOptions(revoked, issuer, &ParseResponseOptions{Nonce: []byte("0123456789abcdef")}); !errors.Is(err, ErrNonceAbsent) {
+		t.Errorf("got error %v, want ErrNonceAbsent", err)
+	}
+	// not checked without a request nonce
+	for _, der := range [][]byte{good, revoked} {
+		if _, err := ParseResponseWithOptions(der, issuer, nil); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestParseResponseErrors(t *testing.T) {
+	setNow(t, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC))
+	issuer := loadCertificate(t, gmOCSPCACertPEM)
+	good := decodeBase64(t, gmOCSPGoodResponseBase64)
+
+	// another issuer
+	if _, err := ParseResponse(good, loadCertificate(t, gmOCSPResponderCertPEM)); err == nil {
+		t.Error("response accepted with another issuer")
+	}
+	// tampered response
+	tampered := bytes.Clone(good)
+	i := bytes.Index(tampered, []byte("0123456789abcdef"))
+	tampered[i] = 'X'
+	if _, err := ParseResponse(tampered, issuer); err == nil || !strings.Contains(err.Error(), "bad signature") {
+		t.Errorf("expected signature error, got %v", err)
+	}
+	// validity period
+	setNow(t, time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC))
+	if _, err := ParseResponse(good, issuer); err == nil || !strings.Contains(err.Error(), "not yet valid") {
+		t.Errorf("expected not yet valid error, got %v", err)
+	}
+	setNow(t, time.Date(2127, 1, 1, 0, 0, 0, 0, time.UTC))
+	if _, err := ParseResponse(good, issuer); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("expected expired error, got %v", err)
+	}
+	// unsuccessful response
+	_, err := ParseResponse([]byte{0x30, 0x03, 0x0a, 0x01, 0x03}, issuer)
+	var respErr ResponseError
+	if !errors.As(err, &respErr) || respErr.Status != TryLater {
+		t.Errorf("expected try later error, got %v", err)
+	}
+}
+
+// The fixtures were produced by the OpenSSL 3.0 OCSP responder, with SM2 keys
+// and the default SM2 user ID: gmOCSPCACertPEM issued the delegated responder
+// gmOCSPResponderCertPEM and the leaf certificates gmOCSPLeafCertPEM, serial
+// 0x1001, good, and gmOCSPRevokedCertPEM, serial 0x1002, revoked.
+//
+// They are synthetic. No response captured from an operational GM OCSP
+// responder is checked in yet, and one should be added here when available.
+const gmOCSPCACertPEM = `-----BEGIN CERTIFICATE-----
+MIIB4zCCAYmgAwIBAgIUI9rjoFioC0dAtQibu0KAMfcHL3IwCgYIKoEcz1UBg3Uw
+PjELMAkGA1UEBhMCQ04xFTATBgNVBAoMDEdNIE9DU1AgVGVzdDEYMBYGA1UEAwwP
+R00gT0NTUCBUZXN0IENBMCAXDTI2MTAxNjE0MzM1NVoYDzIxMjYwOTIyMTQzMzU1
+WjA+MQswCQYDVQQGEwJDTjEVMBMGA1UECgwMR00gT0NTUCBUZXN0MRgwFgYDVQQD
+DA9HTSBPQ1NQIFRlc3QgQ0EwWTATBgcqhkjOPQIBBggqgRzPVQGCLQNCAASybaXh
+40vH1AUT73B4omMSYsI/QWyry+pPUnZ2hCISQzHfS19m3cA5bGJ+7RBuG7lNWKlj
+6T/+7eE2yqOpXw5xo2MwYTAdBgNVHQ4EFgQUBGm8rWnBHXBh7CxYti6iCuVNY3gw
+HwYDVR0jBBgwFoAUBGm8rWnBHXBh7CxYti6iCuVNY3gwDgYDVR0PAQH/BAQDAgEG
+MA8GA1UdEwEB/wQFMAMBAf8wCgYIKoEcz1UBg3UDSAAwRQIgZ5TL0AnDMSleppBb
+F7cMg0jJe7r6nqQYwliZ7U8tzKUCIQDu/nUJvpfjxZDsUBjO3wvHejwXKIg4zjW6
++l02hDNR2w==
+-----END CERTIFICATE-----`
+
+const gmOCSPResponderCertPEM = `-----BEGIN CERTIFICATE-----
+MIIB1jCCAXygAwIBAgIBAjAKBggqgRzPVQGDdTA+MQswCQYDVQQGEwJDTjEVMBMG
+A1UECgwMR00gT0NTUCBUZXN0MRgwFgYDVQQDDA9HTSBPQ1NQIFRlc3QgQ0EwIBcN
+MjYxMDE2MTQzMzU1WhgPMjEyNjA5MjIxNDMzNTVaMEAxCzAJBgNVBAYTAkNOMRUw
+EwYDVQQKDAxHTSBPQ1NQIFRlc3QxGjAYBgNVBAMMEUdNIE9DU1AgUmVzcG9uZGVy
+MFkwEwYHKoZIzj0CAQYIKoEcz1UBgi0DQgAEQceKt7tKoJqN4sdeBF8WbvApvSrj
+yuiJIAbv2X/mjyhOhgXxeehq+SyFkyMkP1FO6loArnjT+pt7bp0nPJ5JGaNnMGUw
+DgYDVR0PAQH/BAQDAgeAMBMGA1UdJQQMMAoGCCsGAQUFBwMJMB0GA1UdDgQWBBSj
+h1A0LS940lcekyUqQ1nsXuz5MDAfBgNVHSMEGDAWgBQEabytacEdcGHsLFi2LqIK
+5U1jeDAKBggqgRzPVQGDdQNIADBFAiEAtrpnwG1fgiKhO0+s8n8aRhaBk5v9sE9Z
+uEK8dPUGxvgCIExO+lNXyGVa8NJp2FV6R+W1PP7sNaICo7Sdfk9BIVVp
+-----END CERTIFICATE-----`
+
+const gmOCSPLeafCertPEM = `-----BEGIN CERTIFICATE-----
+MIIBaTCCAQ4CAhABMAoGCCqBHM9VAYN1MD4xCzAJBgNVBAYTAkNOMRUwEwYDVQQK
+DAxHTSBPQ1NQIFRlc3QxGDAWBgNVBAMMD0dNIE9DU1AgVGVzdCBDQTAgFw0yNjEw
+MTYxNDMzNTVaGA8yMTI2MDkyMjE0MzM1NVowPzELMAkGA1UEBhMCQ04xFTATBgNV
+BAoMDEdNIE9DU1AgVGVzdDEZMBcGA1UEAwwQbGVhZi5leGFtcGxlLmNvbTBZMBMG
+ByqGSM49AgEGCCqBHM9VAYItA0IABH8Mz4Jlo7PbZSKT9EM6Oh9I3E67U82nlApf
+tWZvBtBp4a60YZVtNbczF1Qns3JHTfTBh1ntmnh1X8vlf4VvnPEwCgYIKoEcz1UB
+g3UDSQAwRgIhANgxhIqJdDsVrCkvKH1Kmt3clJlEjtg9DN5xocbD0blHAiEAx2vl
+rjLkdNlbIUaESmlflBgkhVFRPoUoKp5LOiz6JNE=
+-----END CERTIFICATE-----`
+
+const gmOCSPRevokedCertPEM = `-----BEGIN CERTIFICATE-----
+MIIBaTCCAQ4CAhACMAoGCCqBHM9VAYN1MD4xCzAJBgNVBAYTAkNOMRUwEwYDVQQK
+DAxHTSBPQ1NQIFRlc3QxGDAWBgNVBAMMD0dNIE9DU1AgVGVzdCBDQTAgFw0yNjEw
+MTYxNDMzNTVaGA8yMTI2MDkyMjE0MzM1NVowPzELMAkGA1UEBhMCQ04xFTATBgNV
+BAoMDEdNIE9DU1AgVGVzdDEZMBcGA1UEAwwQbGVhZi5leGFtcGxlLmNvbTBZMBMG
+ByqGSM49AgEGCCqBHM9VAYItA0IABH8Mz4Jlo7PbZSKT9EM6Oh9I3E67U82nlApf
+tWZvBtBp4a60YZVtNbczF1Qns3JHTfTBh1ntmnh1X8vlf4VvnPEwCgYIKoEcz1UB
+g3UDSQAwRgIhAL5I7CrvjfpcyrVYNCYGlIwduL+7W+sTiiCzWXOVSTs+AiEAxiHx
+Pel7iJyubefkxC8aCQFlVtOrn4XUDm7tIFzJ9jo=
+-----END CERTIFICATE-----`
+
+// opensslSM3RequestBase64 and opensslSHA1RequestBase64 are the requests of
+// openssl ocsp -sm3 (or -sha1) -no_nonce for gmOCSPLeafCertPEM.
+const opensslSM3RequestBase64 = "" +
+	"MF4wXDBaMFgwVjAMBggqgRzPVQGDEQUABCC2RIy0pz1hmcCQjMM6HIZDunqk1RCC" +
+	"IoMBoAIQ/OulRQQg0kmefoWsqXjmJcguRE11PkbwLo/DgOLV5ZV7fylbm1ECAhAB"
+
+const opensslSHA1RequestBase64 = "" +
+	"MEMwQTA/MD0wOzAJBgUrDgMCGgUABBQj9kq4kUNJpN+VsIOiWnz3YgctfQQUBGm8" +
+	"rWnBHXBh7CxYti6iCuVNY3gCAhAB"
+
+// gmOCSPGoodResponseBase64 answers a request with an SM3 CertID and the nonce
+// "0123456789abcdef", signed with SM2WithSM3 by the delegated responder.
+const gmOCSPGoodResponseBase64 = "" +
+	"MIIDVgoBAKCCA08wggNLBgkrBgEFBQcwAQEEggM8MIIDODCB/aFCMEAxCzAJBgNV" +
+	"BAYTAkNOMRUwEwYDVQQKDAxHTSBPQ1NQIFRlc3QxGjAYBgNVBAMMEUdNIE9DU1Ag" +
+	"UmVzcG9uZGVyGA8yMDI2MTAxNjE0MzQwNVowgYAwfjBWMAwGCCqBHM9VAYMRBQAE" +
+	"ILZEjLSnPWGZwJCMwzochkO6eqTVEIIigwGgAhD866VFBCDSSZ5+haypeOYlyC5E" +
+	"TXU+RvAuj8OA4tXllXt/KVubUQICEAGAABgPMjAyNjEwMTYxNDM0MDVaoBEYDzIx" +
+	"MjYwOTIyMTQzNDA1WqEjMCEwHwYJKwYBBQUHMAECBBIEEDAxMjM0NTY3ODlhYmNk" +
+	"ZWYwCgYIKoEcz1UBg3UDSAAwRQIhAK8NkWL3m1Z6h7Y7A4Z+EwdlfyGikwLDhkmh" +
+	"L44g1c22AiB34YpKrxZDLpUDH17kaIWJpeIp3ht+YJJuZ/MmCANKOqCCAd4wggHa" +
+	"MIIB1jCCAXygAwIBAgIBAjAKBggqgRzPVQGDdTA+MQswCQYDVQQGEwJDTjEVMBMG" +
+	"A1UECgwMR00gT0NTUCBUZXN0MRgwFgYDVQQDDA9HTSBPQ1NQIFRlc3QgQ0EwIBcN" +
+	"MjYxMDE2MTQzMzU1WhgPMjEyNjA5MjIxNDMzNTVaMEAxCzAJBgNVBAYTAkNOMRUw" +
+	"EwYDVQQKDAxHTSBPQ1NQIFRlc3QxGjAYBgNVBAMMEUdNIE9DU1AgUmVzcG9uZGVy" +
+	"MFkwEwYHKoZIzj0CAQYIKoEcz1UBgi0DQgAEQceKt7tKoJqN4sdeBF8WbvApvSrj" +
+	"yuiJIAbv2X/mjyhOhgXxeehq+SyFkyMkP1FO6loArnjT+pt7bp0nPJ5JGaNnMGUw" +
+	"DgYDVR0PAQH/BAQDAgeAMBMGA1UdJQQMMAoGCCsGAQUFBwMJMB0GA1UdDgQWBBSj" +
+	"h1A0LS940lcekyUqQ1nsXuz5MDAfBgNVHSMEGDAWgBQEabytacEdcGHsLFi2LqIK" +
+	"5U1jeDAKBggqgRzPVQGDdQNIADBFAiEAtrpnwG1fgiKhO0+s8n8aRhaBk5v9sE9Z" +
+	"uEK8dPUGxvgCIExO+lNXyGVa8NJp2FV6R+W1PP7sNaICo7Sdfk9BIVVp"
+
+// gmOCSPRevokedResponseBase64 reports gmOCSPRevokedCertPEM revoked on
+// 2024-03-01 for key compromise.
+const gmOCSPRevokedResponseBase64 = "" +
+	"MIIDSQoBAKCCA0IwggM+BgkrBgEFBQcwAQEEggMvMIIDKzCB76FCMEAxCzAJBgNV" +
+	"BAYTAkNOMRUwEwYDVQQKDAxHTSBPQ1NQIFRlc3QxGjAYBgNVBAMMEUdNIE9DU1Ag" +
+	"UmVzcG9uZGVyGA8yMDI2MTAxNjE0MzQwNVowgZcwgZQwVjAMBggqgRzPVQGDEQUA" +
+	"BCC2RIy0pz1hmcCQjMM6HIZDunqk1RCCIoMBoAIQ/OulRQQg0kmefoWsqXjmJcgu" +
+	"RE11PkbwLo/DgOLV5ZV7fylbm1ECAhACoRYYDzIwMjQwMzAxMDAwMDAwWqADCgEB" +
+	"GA8yMDI2MTAxNjE0MzQwNVqgERgPMjEyNjA5MjIxNDM0MDVaMAoGCCqBHM9VAYN1" +
+	"A0kAMEYCIQC43+Om7Z1mrW5mjI8LJv/YMNcT9kn4LFG8cLKhPzZXfQIhANJRIEM+" +
+	"1P+iikbGW21agkTWbTjQTNZ40cc7cGPzkxc1oIIB3jCCAdowggHWMIIBfKADAgEC" +
+	"AgECMAoGCCqBHM9VAYN1MD4xCzAJBgNVBAYTAkNOMRUwEwYDVQQKDAxHTSBPQ1NQ" +
+	"IFRlc3QxGDAWBgNVBAMMD0dNIE9DU1AgVGVzdCBDQTAgFw0yNjEwMTYxNDMzNTVa" +
+	"GA8yMTI2MDkyMjE0MzM1NVowQDELMAkGA1UEBhMCQ04xFTATBgNVBAoMDEdNIE9D" +
+	"U1AgVGVzdDEaMBgGA1UEAwwRR00gT0NTUCBSZXNwb25kZXIwWTATBgcqhkjOPQIB" +
+	"BggqgRzPVQGCLQNCAARBx4q3u0qgmo3ix14EXxZu8Cm9KuPK6IkgBu/Zf+aPKE6G" +
+	"BfF56Gr5LIWTIyQ/UU7qWgCueNP6m3tunSc8nkkZo2cwZTAOBgNVHQ8BAf8EBAMC" +
+	"B4AwEwYDVR0lBAwwCgYIKwYBBQUHAwkwHQYDVR0OBBYEFKOHUDQtL3jSVx6TJSpD" +
+	"Wexe7PkwMB8GA1UdIwQYMBaAFARpvK1pwR1wYewsWLYuogrlTWN4MAoGCCqBHM9V" +
+	"AYN1A0gAMEUCIQC2umfAbV+CIqE7T6zyfxpGFoGTm/2wT1m4Qrx09QbG+AIgTE76" +
+	"U1fIZVrw0mnYVXpH5bU8/uw1ogKjtJ1+T0EhVWk="
+
+// gmOCSPIssuerSignedResponseBase64 answers a request with a SHA-1 CertID,
+// signed by the CA itself, identified by key and without certificates.
+const gmOCSPIssuerSignedResponseBase64 = "" +
+	"MIIBAwoBAKCB/TCB+gYJKwYBBQUHMAEBBIHsMIHpMIGQohYEFARpvK1pwR1wYews" +
+	"WLYuogrlTWN4GA8yMDI2MTAxNjE0MzQwNlowZTBjMDswCQYFKw4DAhoFAAQUI/ZK" +
+	"uJFDSaTflbCDolp892IHLX0EFARpvK1pwR1wYewsWLYuogrlTWN4AgIQAYAAGA8y" +
+	"MDI2MTAxNjE0MzQwNlqgERgPMjEyNjA5MjIxNDM0MDZaMAoGCCqBHM9VAYN1A0gA" +
+	"MEUCIHm+9XMdogkDpzQADsudLaRoLpQ9jrk7U+k3oukVoyOZAiEA57i+MVPU4msg" +
+	"AgIvQFqQUeC8wGiZPGg/En0am4FTAOY="
@@ -0,0 +1,233 @@
+package ocsp
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/yunmoon/gmsm/smx509"
+)
+
+// ResponderIDType selects how a response identifies its responder, RFC 6960,
+// 4.2.2.3.
+type ResponderIDType int
+
+const (
+	// ByName identifies the responder by the subject of its certificate.
+	ByName ResponderIDType = iota
+	// ByKey identifies the responder by the SHA-1 hash of its public key.
+	ByKey
+)
+
+// SingleResponse is the status of a certificate in a ResponseTemplate.
+type SingleResponse struct {
+	SerialNumber *big.Int
+	// IssuerHash is the hash of the CertID, which should be that of the
+	// request.
+	IssuerHash HashAlgorithm
+
+	// Status is Good, Revoked or Unknown. RevokedAt is required if it is
+	// Revoked, RevocationReason is a CRL reason code, RFC 5280, 5.3.1,
+	// omitted if zero.
+	Status           int
+	RevokedAt        time.Time
+	RevocationReason int
+
+	ThisUpdate time.Time
+	// NextUpdate is omitted if zero.
+	NextUpdate time.Time
+	// ArchiveCutoff is the time of the archiveCutoff extension, RFC 6960,
+	// 4.4.4, omitted if zero.
+	ArchiveCutoff time.Time
+
+	// ExtraExtensions are appended to the singleExtensions.
+	ExtraExtensions []pkix.Extension
+}
+
+// ResponseTemplate contains the fields of a response created by
+// CreateResponse.
+type ResponseTemplate struct {
+	// Responses are the statuses of the certificates, at least one.
+	Responses []SingleResponse
+
+	// ProducedAt is the current time if zero.
+	ProducedAt  time.Time
+	ResponderID ResponderIDType
+
+	// Nonce, if not empty, is the nonce of the request, Request.Nonce,
+	// copied in the nonce extension so that the requester can match the
+	// response. It is 1 to 32 bytes long.
+	Nonce []byte
+	// ExtraExtensions are appended to the responseExtensions.
+	ExtraExtensions []pkix.Extension
+
+	// SignatureAlgorithm is the default one for priv if zero.
+	SignatureAlgorithm smx509.SignatureAlgorithm
+}
+
+// CreateResponse returns a DER-encoded OCSP response with the statuses of
+// the certificates of issuer in template, signed by priv, the private key of
+// responderCert. responderCert is either issuer itself, or a delegated
+// responder certificate issued by issuer, which is then included in the
+// response. If responderCert is nil, issuer signs the response.
+//
+// SM2 keys sign with SM2WithSM3, and the signature is checked with the public
+// key of responderCert.
+func CreateResponse(issuer, responderCert *smx509.Certificate, template ResponseTemplate, priv crypto.Signer) ([]byte, error) {
+	if issuer == nil {
+		return nil, errors.New("ocsp: issuer is required")
+	}
+	if responderCert == nil {
+		responderCert = issuer
+	}
+	if len(template.Responses) == 0 {
+		return nil, errors.New("ocsp: no certificate status in the response")
+	}
+
+	var responses []singleResponse
+	for _, single := range template.Responses {
+		resp, err := newSingleResponse(issuer, single)
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, resp)
+	}
+
+	var responderID asn1.RawValue
+	switch template.ResponderID {
+	case ByName:
+		responderID = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 1, IsCompound: true, Bytes: responderCert.RawSubject}
+	case ByKey:
+		_, keyHash, err := issuerHashes(responderCert, SHA1)
+		if err != nil {
+			return nil, err
+		}
+		value, err := asn1.Marshal(keyHash)
+		if err != nil {
+			return nil, err
+		}
+		responderID = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 2, IsCompound: true, Bytes: value}
+	default:
+		return nil, fmt.Errorf("ocsp: unknown responder ID type %d", template.ResponderID)
+	}
+
+	producedAt := template.ProducedAt
+	if producedAt.IsZero() {
+		producedAt = now()
+	}
+	data := responseData{
+		RawResponderID:     responderID,
+		ProducedAt:         producedAt.UTC(),
+		Responses:          responses,
+		ResponseExtensions: template.ExtraExtensions,
+	}
+	if len(template.Nonce) > 0 {
+		if err := checkNonceSize(template.Nonce); err != nil {
+			return nil, err
+		}
+		value, err := asn1.Marshal(template.Nonce)
+		if err != nil {
+			return nil, err
+		}
+		data.ResponseExtensions = append([]pkix.Extension{{Id: oidOCSPNonce, Value: value}}, data.ResponseExtensions...)
+	}
+	tbsDER, err := asn1.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, sigAlgo, sigAI, err := smx509.SignTBS(rand.Reader, tbsDER, priv, template.SignatureAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	if err := responderCert.CheckSignature(sigAlgo, tbsDER, signature); err != nil {
+		return nil, fmt.Errorf("ocsp: the private key does not match the responder certificate: %w", err)
+	}
+
+	data.Raw = tbsDER
+	basic := basicResponse{
+		TBSResponseData:    data,
+		SignatureAlgorithm: sigAI,
+		Signature:          asn1.BitString{Bytes: signature, BitLength: 8 * len(signature)},
+	}
+	if !bytes.Equal(responderCert.Raw, issuer.Raw) {
+		basic.Certificates = []asn1.RawValue{{FullBytes: responderCert.Raw}}
+	}
+	basicDER, err := asn1.Marshal(basic)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(responseASN1{
+		Status: asn1.Enumerated(Success),
+		Response: responseBytes{
+			ResponseType: oidOCSPBasicResponse,
+			Response:     basicDER,
+		},
+	})
+}
+
+// newSingleResponse returns the SingleResponse structure of single, for a
+// certificate of issuer.
+func newSingleResponse(issuer *smx509.Certificate, single SingleResponse) (singleResponse, error) {
+	var resp singleResponse
+	if single.SerialNumber == nil {
+		return resp, errors.New("ocsp: serial number is required")
+	}
+	if single.ThisUpdate.IsZero() {
+		return resp, errors.New("ocsp: thisUpdate is required")
+	}
+	oid, err := single.IssuerHash.oid()
+	if err != nil {
+		return resp, err
+	}
+	nameHash, keyHash, err := issuerHashes(issuer, single.IssuerHash)
+	if err != nil {
+		return resp, err
+	}
+	resp = singleResponse{
+		CertID: certID{
+			HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oid, Parameters: asn1.NullRawValue},
+			NameHash:      nameHash,
+			IssuerKeyHash: keyHash,
+			SerialNumber:  single.SerialNumber,
+		},
+		ThisUpdate: single.ThisUpdate.UTC(),
+		NextUpdate: single.NextUpdate.UTC(),
+	}
+
+	switch single.Status {
+	case Good:
+		resp.Good = true
+	case Unknown:
+		resp.Unknown = true
+	case Revoked:
+		if single.RevokedAt.IsZero() {
+			return resp, errors.New("ocsp: revocation time is required")
+		}
+		if single.RevocationReason < 0 || single.RevocationReason > 10 || single.RevocationReason == 7 {
+			return resp, fmt.Errorf("ocsp: invalid revocation reason %d", single.RevocationReason)
+		}
+		resp.Revoked = revokedInfo{
+			RevocationTime: single.RevokedAt.UTC(),
+			Reason:         asn1.Enumerated(single.RevocationReason),
+		}
+	default:
+		return resp, fmt.Errorf("ocsp: invalid status %d", single.Status)
+	}
+
+	if !single.ArchiveCutoff.IsZero() {
+		value, err := asn1.MarshalWithParams(single.ArchiveCutoff.UTC(), "generalized")
+		if err != nil {
+			return resp, err
+		}
+		resp.SingleExtensions = append(resp.SingleExtensions, pkix.Extension{Id: oidOCSPArchiveCutoff, Value: value})
+	}
+	resp.SingleExtensions = append(resp.SingleExtensions, single.ExtraExtensions...)
+	return resp, nil
+}
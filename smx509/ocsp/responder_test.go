@@ -0,0 +1,405 @@
+package ocsp
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yunmoon/gmsm/sm2"
+	"github.com/yunmoon/gmsm/smx509"
+)
+
+func newTestCertificate(t *testing.T, template *x509.Certificate, parent *smx509.Certificate, pub crypto.PublicKey, priv crypto.Signer) *smx509.Certificate {
+	t.Helper()
+	template.NotBefore = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	template.NotAfter = time.Date(2036, 1, 1, 0, 0, 0, 0, time.UTC)
+	parentTemplate := template
+	if parent != nil {
+		parentTemplate = parent.ToX509()
+	}
+	der, err := smx509.CreateCertificate(rand.Reader, template, parentTemplate, pub, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := smx509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestCreateResponse(t *testing.T) {
+	setNow(t, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC))
+	caKey, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := newTestCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "GM OCSP CA"},
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}, nil, caKey.Public(), caKey)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm2Key, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	thisUpdate := time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC)
+	template := ResponseTemplate{
+		Responses: []SingleResponse{
+			{SerialNumber: big.NewInt(100), IssuerHash: SM3, Status: Good, ThisUpdate: thisUpdate,
+				NextUpdate: thisUpdate.AddDate(1, 0, 0), ArchiveCutoff: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+			{SerialNumber: big.NewInt(101), IssuerHash: SHA1, Status: Revoked, ThisUpdate: thisUpdate,
+				RevokedAt: time.Date(2026, 6, 1, 8, 0, 0, 0, time.UTC), RevocationReason: 1},
+			{SerialNumber: big.NewInt(102), IssuerHash: SHA256, Status: Unknown, ThisUpdate: thisUpdate},
+		},
+		ProducedAt: thisUpdate,
+		Nonce:      []byte("nonce"),
+	}
+
+	for i, priv := range []crypto.Signer{sm2Key, rsaKey, ecdsaKey, caKey} {
+		responder := issuer
+		if priv != caKey {
+			responder = newTestCertificate(t, &x509.Certificate{
+				SerialNumber: big.NewInt(int64(i) + 2),
+				Subject:      pkix.Name{CommonName: "GM OCSP Responder"},
+				KeyUsage:     x509.KeyUsageDigitalSignature,
+				ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning},
+			}, issuer, priv.Public(), caKey)
+		}
+		for _, responderID := range []ResponderIDType{ByName, ByKey} {
+			template.ResponderID = responderID
+			der, err := CreateResponse(issuer, responder, template, priv)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, single := range template.Responses {
+				resp, err := ParseResponseForCert(der, &smx509.Certificate{SerialNumber: single.SerialNumber}, issuer)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if resp.Status != single.Status || resp.IssuerHash != single.IssuerHash || !resp.ThisUpdate.Equal(single.ThisUpdate) ||
+					!resp.NextUpdate.Equal(single.NextUpdate) || !resp.ArchiveCutoff.Equal(single.ArchiveCutoff) ||
+					!resp.RevokedAt.Equal(single.RevokedAt) || resp.RevocationReason != single.RevocationReason {
+					t.Errorf("serial number %v: unexpected response %+v", single.SerialNumber, resp)
+				}
+				if string(resp.Nonce) != "nonce" || !resp.ProducedAt.Equal(template.ProducedAt) {
+					t.Errorf("unexpected nonce %q or production time %v", resp.Nonce, resp.ProducedAt)
+				}
+				if (resp.Certificate != nil) != (responder != issuer) {
+					t.Errorf("unexpected responder certificate %v", resp.Certificate)
+				}
+				if (resp.RawResponderName != nil) != (responderID == ByName) || (resp.ResponderKeyHash != nil) != (responderID == ByKey) {
+					t.Errorf("unexpected responder ID %x %x", resp.RawResponderName, resp.ResponderKeyHash)
+				}
+			}
+			if _, err := ParseResponse(der, issuer); err == nil {
+				t.Error("response with several statuses parsed as a single one")
+			}
+		}
+		if priv == sm2Key {
+			if _, err := CreateResponse(issuer, responder, template, rsaKey); err == nil {
+				t.Error("expected error with the key of another responder")
+			}
+		}
+	}
+
+	// issuer signed, without responder certificate
+	der, err := CreateResponse(issuer, nil, ResponseTemplate{Responses: template.Responses[:1]}, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := ParseResponse(der, issuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Certificate != nil || resp.SignatureAlgorithm != smx509.SM2WithSM3 || resp.Nonce != nil || !resp.ProducedAt.Equal(now()) {
+		t.Errorf("unexpected response %+v", resp)
+	}
+
+	// the nonce of the request is copied to the response
+	leaf := newTestCertificate(t, &x509.Certificate{SerialNumber: big.NewInt(100), Subject: pkix.Name{CommonName: "leaf"}}, issuer, sm2Key.Public(), caKey)
+	reqDER, nonce, err := CreateRequestWithNonce(leaf, issuer, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := ParseRequest(reqDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err = CreateResponse(issuer, nil, ResponseTemplate{
+		Responses: []SingleResponse{{SerialNumber: req.SerialNumber, IssuerHash: req.HashAlgorithm, Status: Good, ThisUpdate: thisUpdate}},
+		Nonce:     req.Nonce,
+	}, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseResponseWithOptions(der, issuer, &ParseResponseOptions{Certificate: leaf, Nonce: nonce}); err != nil {
+		t.Errorf("response to a request with nonce: %v", err)
+	}
+	if _, err := CreateResponse(issuer, nil, ResponseTemplate{Responses: template.Responses[:1], Nonce: make([]byte, 33)}, caKey); err == nil {
+		t.Error("expected error with a nonce of 33 bytes")
+	}
+
+	for _, single := range []SingleResponse{
+		{IssuerHash: SM3, Status: Good, ThisUpdate: thisUpdate},
+		{SerialNumber: big.NewInt(1), IssuerHash: SM3, Status: Good},
+		{SerialNumber: big.NewInt(1), IssuerHash: SM3, Status: Revoked, ThisUpdate: thisUpdate},
+		{SerialNumber: big.NewInt(1), IssuerHash: SM3, Status: Revoked, ThisUpdate: thisUpdate, RevokedAt: thisUpdate, RevocationReason: 7},
+		{SerialNumber: big.NewInt(1), IssuerHash: SM3, Status: 3, ThisUpdate: thisUpdate},
+	} {
+		if _, err := CreateResponse(issuer, nil, ResponseTemplate{Responses: []SingleResponse{single}}, caKey); err == nil {
+			t.Errorf("expected error for %+v", single)
+		}
+	}
+}
+
+func TestDelegatedResponder(t *testing.T) {
+	setNow(t, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC))
+	newCA := func(name string) (*smx509.Certificate, *sm2.PrivateKey) {
+		key, err := sm2.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return newTestCertificate(t, &x509.Certificate{
+			SerialNumber:          big.NewInt(1),
+			Subject:               pkix.Name{CommonName: name},
+			KeyUsage:              x509.KeyUsageCertSign,
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+		}, nil, key.Public(), key), key
+	}
+	issuer, caKey := newCA("GM OCSP CA")
+	otherCA, otherKey := newCA("GM OCSP CA")
+	responderKey, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	noCheck := pkix.Extension{Id: oidOCSPNoCheck, Value: []byte{0x05, 0x00}}
+
+	thisUpdate := time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC)
+	template := ResponseTemplate{
+		Responses: []SingleResponse{{SerialNumber: big.NewInt(100), Status: Good, ThisUpdate: thisUpdate}},
+	}
+	tests := []struct {
+		name        string
+		parent      *smx509.Certificate
+		parentKey   *sm2.PrivateKey
+		extKeyUsage []x509.ExtKeyUsage
+		extensions  []pkix.Extension
+		err         string
+	}{
+		{"nocheck", issuer, caKey, []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning}, []pkix.Extension{noCheck}, ""},
+		{"without nocheck", issuer, caKey, []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning}, nil, ""},
+		{"without EKU", issuer, caKey, nil, []pkix.Extension{noCheck}, "not valid for OCSP signing"},
+		{"other EKU", issuer, caKey, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}, nil, "not valid for OCSP signing"},
+		{"other issuer", otherCA, otherKey, []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning}, nil, "bad responder certificate"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			responder := newTestCertificate(t, &x509.Certificate{
+				SerialNumber:    big.NewInt(2),
+				Subject:         pkix.Name{CommonName: "GM OCSP Responder"},
+				KeyUsage:        x509.KeyUsageDigitalSignature,
+				ExtKeyUsage:     test.extKeyUsage,
+				ExtraExtensions: test.extensions,
+			}, test.parent, responderKey.Public(), test.parentKey)
+			for _, responderID := range []ResponderIDType{ByName, ByKey} {
+				template.ResponderID = responderID
+				der, err := CreateResponse(issuer, responder, template, responderKey)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp, err := ParseResponse(der, issuer)
+				if test.err != "" {
+					if err == nil || !strings.Contains(err.Error(), test.err) {
+						t.Errorf("got error %v, want %q", err, test.err)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !resp.Certificate.Equal(responder) || resp.SignatureAlgorithm != smx509.SM2WithSM3 {
+					t.Errorf("unexpected responder %v or signature algorithm %v", resp.Certificate.Subject, resp.SignatureAlgorithm)
+				}
+				if resp.ResponderNoCheck != (test.extensions != nil) {
+					t.Errorf("got ResponderNoCheck %v", resp.ResponderNoCheck)
+				}
+			}
+		})
+	}
+
+	// the responder ID matches neither the issuer nor an included certificate
+	template.ResponderID = ByKey
+	der, err := CreateResponse(otherCA, nil, template, otherKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseResponse(der, issuer); err == nil || !strings.Contains(err.Error(), "not signed by the issuer") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+// TestCreateResponseOpenSSL checks the responses with "openssl ocsp". OpenSSL
+// 3.0 verifies the SM2 signatures of certificates and OCSP responses with an
+// empty signer ID, not the default one of GB/T 35276, so the SM2 responses
+// are only parsed by "openssl ocsp" and their signatures are checked with
+// "openssl pkeyutl".
+func TestCreateResponseOpenSSL(t *testing.T) {
+	if _, err := exec.LookPath("openssl"); err != nil {
+		t.Skip("openssl not found")
+	}
+	setNow(t, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC))
+	sm2CAKey, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdsaCAKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newCA := func(key crypto.Signer) *smx509.Certificate {
+		return newTestCertificate(t, &x509.Certificate{
+			SerialNumber:          big.NewInt(1),
+			Subject:               pkix.Name{CommonName: "GM OCSP CA"},
+			KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+		}, nil, key.Public(), key)
+	}
+	sm2CA, ecdsaCA := newCA(sm2CAKey), newCA(ecdsaCAKey)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm2Key, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// OpenSSL checks thisUpdate against the current time
+	thisUpdate := time.Now().Add(-time.Hour).Truncate(time.Second)
+	template := ResponseTemplate{
+		Responses: []SingleResponse{
+			{SerialNumber: big.NewInt(100), IssuerHash: SHA1, Status: Good, ThisUpdate: thisUpdate},
+			{SerialNumber: big.NewInt(101), IssuerHash: SHA1, Status: Revoked, ThisUpdate: thisUpdate,
+				RevokedAt: thisUpdate.Add(-time.Hour), RevocationReason: 1},
+		},
+	}
+	for i, test := range []struct {
+		name    string
+		issuer  *smx509.Certificate
+		caKey   crypto.Signer
+		priv    crypto.Signer
+		sigAlgo smx509.SignatureAlgorithm
+	}{
+		{"SM2 issuer", sm2CA, sm2CAKey, sm2CAKey, 0},
+		{"SM2 responder", sm2CA, sm2CAKey, sm2Key, 0},
+		{"ECDSA issuer", ecdsaCA, ecdsaCAKey, ecdsaCAKey, 0},
+		{"ECDSA responder", ecdsaCA, ecdsaCAKey, ecdsaKey, 0},
+		{"RSA responder", ecdsaCA, ecdsaCAKey, rsaKey, 0},
+		{"RSA-PSS responder", ecdsaCA, ecdsaCAKey, rsaKey, smx509.SHA256WithRSAPSS},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			responder := test.issuer
+			if test.priv != test.caKey {
+				responder = newTestCertificate(t, &x509.Certificate{
+					SerialNumber: big.NewInt(int64(i) + 2),
+					Subject:      pkix.Name{CommonName: "GM OCSP Responder"},
+					KeyUsage:     x509.KeyUsageDigitalSignature,
+					ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning},
+				}, test.issuer, test.priv.Public(), test.caKey)
+			}
+			template.SignatureAlgorithm = test.sigAlgo
+			der, err := CreateResponse(test.issuer, responder, template, test.priv)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dir := t.TempDir()
+			caFile := filepath.Join(dir, "ca.pem")
+			respFile := filepath.Join(dir, "resp.der")
+			writeTestFile(t, caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: test.issuer.Raw}))
+			writeTestFile(t, respFile, der)
+			_, isSM2 := test.priv.(*sm2.PrivateKey)
+			// the certificates are valid from 2026 to 2036
+			args := []string{"ocsp", "-respin", respFile, "-issuer", caFile, "-serial", "100", "-serial", "101"}
+			if isSM2 {
+				args = append(args, "-noverify")
+			} else {
+				args = append(args, "-CAfile", caFile, "-attime", "1798761600")
+			}
+			out, err := exec.Command("openssl", args...).CombinedOutput()
+			if err != nil {
+				t.Fatalf("openssl ocsp: %v\n%s", err, out)
+			}
+			want := []string{"100: good", "101: revoked", "Reason: keyCompromise"}
+			if !isSM2 {
+				want = append(want, "Response verify OK")
+			}
+			for _, want := range want {
+				if !strings.Contains(string(out), want) {
+					t.Errorf("openssl output does not contain %q:\n%s", want, out)
+				}
+			}
+			if !isSM2 {
+				return
+			}
+
+			resp, err := ParseResponseForCert(der, &smx509.Certificate{SerialNumber: big.NewInt(100)}, test.issuer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pub, err := smx509.MarshalPKIXPublicKey(test.priv.Public())
+			if err != nil {
+				t.Fatal(err)
+			}
+			pubFile := filepath.Join(dir, "pub.pem")
+			tbsFile := filepath.Join(dir, "tbs.der")
+			sigFile := filepath.Join(dir, "sig.der")
+			writeTestFile(t, pubFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}))
+			writeTestFile(t, tbsFile, resp.TBSResponseData)
+			writeTestFile(t, sigFile, resp.Signature)
+			out, err = exec.Command("openssl", "pkeyutl", "-verify", "-pubin", "-inkey", pubFile, "-rawin", "-digest", "sm3",
+				"-pkeyopt", "distid:1234567812345678", "-in", tbsFile, "-sigfile", sigFile).CombinedOutput()
+			if err != nil || !strings.Contains(string(out), "Signature Verified Successfully") {
+				t.Errorf("openssl pkeyutl: %v\n%s", err, out)
+			}
+		})
+	}
+}
+
+func writeTestFile(t *testing.T, name string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(name, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,134 @@
+package smx509
+
+import (
+	"encoding/asn1"
+	"errors"
+
+	"golang.org/x/crypto/cryptobyte"
+	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+// OtherName is an otherName subject alternative name, RFC 5280, 4.2.1.6:
+//
+//	OtherName ::= SEQUENCE {
+//	     type-id    OBJECT IDENTIFIER,
+//	     value      [0] EXPLICIT ANY DEFINED BY type-id }
+//
+// Value is the ASN.1 value without the explicit [0] wrapper, for example the
+// UTF8String of a Microsoft UPN.
+type OtherName struct {
+	TypeID asn1.ObjectIdentifier
+	Value  asn1.RawValue
+}
+
+// OtherNames returns the otherName entries of the subject alternative name
+// extension of c.
+func (c *Certificate) OtherNames() ([]OtherName, error) {
+	return parseOtherNames(c.getSANExtension())
+}
+
+// OtherNames returns the otherName entries of the subject alternative name
+// extension requested by c, see Certificate.OtherNames.
+func (c *CertificateRequest) OtherNames() ([]OtherName, error) {
+	for _, e := range c.Extensions {
+		if e.Id.Equal(oidExtensionSubjectAltName) {
+			return parseOtherNames(e.Value)
+		}
+	}
+	return nil, nil
+}
+
+// UnhandledSANs returns the entries of the subject alternative name extension
+// of c which are stored neither in the Certificate fields nor by OtherNames,
+// such as directoryName, ediPartyName and registeredID ones. The values keep
+// their class, tag and full encoding, so that they can be passed back in
+// CreateCertificateOptions.ExtraSANs.
+func (c *Certificate) UnhandledSANs() ([]asn1.RawValue, error) {
+	return parseUnhandledSANs(c.getSANExtension())
+}
+
+// UnhandledSANs returns the entries of the subject alternative name extension
+// requested by c which are stored neither in the CertificateRequest fields
+// nor by OtherNames, see Certificate.UnhandledSANs.
+func (c *CertificateRequest) UnhandledSANs() ([]asn1.RawValue, error) {
+	for _, e := range c.Extensions {
+		if e.Id.Equal(oidExtensionSubjectAltName) {
+			return parseUnhandledSANs(e.Value)
+		}
+	}
+	return nil, nil
+}
+
+func parseUnhandledSANs(der []byte) ([]asn1.RawValue, error) {
+	if der == nil {
+		return nil, nil
+	}
+	var seq asn1.RawValue
+	if rest, err := asn1.Unmarshal(der, &seq); err != nil || len(rest) > 0 || seq.Tag != asn1.TagSequence {
+		return nil, errors.New("x509: invalid subject alternative names")
+	}
+	var unhandled []asn1.RawValue
+	for rest := seq.Bytes; len(rest) > 0; {
+		var v asn1.RawValue
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &v); err != nil {
+			return nil, errors.New("x509: invalid subject alternative name")
+		}
+		// otherName, rfc822Name, dNSName, uniformResourceIdentifier and iPAddress
+		handled := v.Class == asn1.ClassContextSpecific &&
+			(v.IsCompound && v.Tag == 0 ||
+				!v.IsCompound && (v.Tag == nameTypeEmail || v.Tag == nameTypeDNS || v.Tag == nameTypeURI || v.Tag == nameTypeIP))
+		if !handled {
+			unhandled = append(unhandled, v)
+		}
+	}
+	return unhandled, nil
+}
+
+func parseOtherNames(der []byte) ([]OtherName, error) {
+	if der == nil {
+		return nil, nil
+	}
+	var otherNames []OtherName
+	err := forEachSAN(der, func(tag int, data []byte) error {
+		if tag != nameTypeOtherName {
+			return nil
+		}
+		var oid asn1.ObjectIdentifier
+		var value cryptobyte.String
+		s := cryptobyte.String(data)
+		if !s.ReadASN1ObjectIdentifier(&oid) ||
+			!s.ReadASN1(&value, cryptobyte_asn1.Tag(0).ContextSpecific().Constructed()) ||
+			!s.Empty() {
+			return errors.New("x509: SAN otherName is malformed")
+		}
+		otherName := OtherName{TypeID: oid}
+		if rest, err := asn1.Unmarshal(value, &otherName.Value); err != nil || len(rest) > 0 {
+			return errors.New("x509: SAN otherName is malformed")
+		}
+		otherNames = append(otherNames, otherName)
+		return nil
+	})
+	return otherNames, err
+}
+
+// marshalOtherName returns the otherName GeneralName of o.
+func marshalOtherName(o OtherName) (asn1.RawValue, error) {
+	value := o.Value.FullBytes
+	if len(value) == 0 {
+		var err error
+		if value, err = asn1.Marshal(o.Value); err != nil {
+			return asn1.RawValue{}, err
+		}
+	}
+	var b cryptobyte.Builder
+	b.AddASN1ObjectIdentifier(o.TypeID)
+	b.AddASN1(cryptobyte_asn1.Tag(0).ContextSpecific().Constructed(), func(b *cryptobyte.Builder) {
+		b.AddBytes(value)
+	})
+	content, err := b.Bytes()
+	if err != nil {
+		return asn1.RawValue{}, errors.New("x509: invalid otherName type-id")
+	}
+	return asn1.RawValue{Tag: 0, Class: 2, IsCompound: true, Bytes: content}, nil
+}
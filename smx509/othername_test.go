@@ -0,0 +1,251 @@
+package smx509
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yunmoon/gmsm/sm2"
+)
+
+// Generated with:
+//
+//	openssl req -x509 -key sm2.key -sm3 -subj "/CN=GM device" -addext "subjectAltName=
+//	otherName:1.3.6.1.4.1.311.20.2.3;UTF8:device@example.com,
+//	otherName:1.2.156.10197.1.999;PRINTABLESTRING:SN20240001,DNS:device.example.com"
+const opensslOtherNameCertPEM = `-----BEGIN CERTIFICATE-----
+MIIB3jCCAYSgAwIBAgIUf/6GIolwzb+H+UQBcOzhNZYjLbMwCgYIKoEcz1UBg3Uw
+FDESMBAGA1UEAwwJR00gZGV2aWNlMCAXDTI2MTAxNjEzNDUzN1oYDzIxMjYwOTIy
+MTM0NTM3WjAUMRIwEAYDVQQDDAlHTSBkZXZpY2UwWTATBgcqhkjOPQIBBggqgRzP
+VQGCLQNCAARVJHo6My8Bm7/ZRzGeDX8jcGuLBVU/fAGE7yEIUXgvlFiLoOjLq7gq
+IMwDvbICYUu03A/UgxHj3vY7sM5Cp5rro4GxMIGuMB0GA1UdDgQWBBRSFU4kh+Wf
+nkgAm2vmTB5eFjW6BDAfBgNVHSMEGDAWgBRSFU4kh+WfnkgAm2vmTB5eFjW6BDAP
+BgNVHRMBAf8EBTADAQH/MFsGA1UdEQRUMFKgIgYKKwYBBAGCNxQCA6AUDBJkZXZp
+Y2VAZXhhbXBsZS5jb22gGAYIKoEcz1UBh2egDBMKU04yMDI0MDAwMYISZGV2aWNl
+LmV4YW1wbGUuY29tMAoGCCqBHM9VAYN1A0gAMEUCICSNSRhT/JeS15N+j7lEYkYV
+O/4B8wh83hQFyM6yR9GJAiEAlI1hIlwDBU+N6kshRmVmxQUwhei6EnFIC5+gElkg
+iQY=
+-----END CERTIFICATE-----`
+
+var (
+	oidUPN          = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 20, 2, 3}
+	oidDeviceSerial = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 999}
+)
+
+func checkOtherNames(t *testing.T, got []OtherName, upn, serial string) {
+	t.Helper()
+	if len(got) != 2 {
+		t.Fatalf("expected 2 otherNames, got %d", len(got))
+	}
+	var s string
+	if !got[0].TypeID.Equal(oidUPN) || got[0].Value.Tag != asn1.TagUTF8String || string(got[0].Value.Bytes) != upn {
+		t.Errorf("unexpected UPN %v %q", got[0].TypeID, got[0].Value.Bytes)
+	}
+	if !got[1].TypeID.Equal(oidDeviceSerial) {
+		t.Errorf("unexpected type-id %v", got[1].TypeID)
+	} else if _, err := asn1.Unmarshal(got[1].Value.FullBytes, &s); err != nil || s != serial {
+		t.Errorf("unexpected device serial %q: %v", s, err)
+	}
+}
+
+func TestParseOpenSSLOtherNames(t *testing.T) {
+	block, _ := pem.Decode([]byte(opensslOtherNameCertPEM))
+	cert, err := ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cert.DNSNames) != 1 || cert.DNSNames[0] != "device.example.com" {
+		t.Errorf("unexpected DNS names %v", cert.DNSNames)
+	}
+	otherNames, err := cert.OtherNames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOtherNames(t, otherNames, "device@example.com", "SN20240001")
+}
+
+func TestOtherNamesRoundTrip(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, err := asn1.MarshalWithParams("SN0001", "printable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherNames := []OtherName{
+		{TypeID: oidUPN, Value: asn1.RawValue{Tag: asn1.TagUTF8String, Bytes: []byte("user@example.com")}},
+		{TypeID: oidDeviceSerial, Value: asn1.RawValue{FullBytes: serial}},
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "device"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"device.example.com"},
+	}
+	der, err := CreateCertificateWithOptions(rand.Reader, template, template, priv.Public(), priv, &CreateCertificateOptions{OtherNames: otherNames})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cert.DNSNames) != 1 {
+		t.Errorf("unexpected DNS names %v", cert.DNSNames)
+	}
+	got, err := cert.OtherNames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOtherNames(t, got, "user@example.com", "SN0001")
+
+	csrDER, err := CreateCertificateRequestWithOptions(rand.Reader, &x509.CertificateRequest{Subject: template.Subject}, priv, &CreateCertificateRequestOptions{OtherNames: otherNames})
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr, err := ParseCertificateRequest(csrDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err = csr.OtherNames(); err != nil {
+		t.Fatal(err)
+	}
+	checkOtherNames(t, got, "user@example.com", "SN0001")
+
+	// only otherNames with an empty subject, the extension is critical
+	template.Subject = pkix.Name{}
+	template.DNSNames = nil
+	if der, err = CreateCertificateWithOptions(rand.Reader, template, template, priv.Public(), priv, &CreateCertificateOptions{OtherNames: otherNames}); err != nil {
+		t.Fatal(err)
+	}
+	if cert, err = ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+	if len(cert.UnhandledCriticalExtensions) != 0 {
+		t.Errorf("unexpected unhandled critical extensions %v", cert.UnhandledCriticalExtensions)
+	}
+	if got, err = cert.OtherNames(); err != nil {
+		t.Fatal(err)
+	}
+	checkOtherNames(t, got, "user@example.com", "SN0001")
+
+	// no subject alternative name
+	template.Subject = pkix.Name{CommonName: "device"}
+	if der, err = CreateCertificate(rand.Reader, template, template, priv.Public(), priv); err != nil {
+		t.Fatal(err)
+	}
+	if cert, err = ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := cert.OtherNames(); err != nil || got != nil {
+		t.Errorf("unexpected otherNames %v: %v", got, err)
+	}
+
+	if _, err := marshalSANs(nil, nil, nil, nil, []OtherName{{Value: otherNames[0].Value}}, nil); err == nil {
+		t.Error("expected error with an empty type-id")
+	}
+	if der, err := marshalSANs(nil, nil, nil, nil, otherNames[:1], nil); err != nil {
+		t.Fatal(err)
+	} else if want := []byte{0x30, 0x22, 0xa0, 0x20, 0x06, 0x0a}; !bytes.HasPrefix(der, want) || !bytes.Contains(der, []byte{0xa0, 0x12, 0x0c, 0x10}) {
+		t.Errorf("unexpected encoding %x", der)
+	}
+}
+
+func TestUnhandledSANs(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, err := asn1.Marshal(pkix.Name{CommonName: "directory", Organization: []string{"GM"}}.ToRDNSequence())
+	if err != nil {
+		t.Fatal(err)
+	}
+	directoryName := asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 4, IsCompound: true, Bytes: name}
+	registeredID := asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 8, Bytes: []byte{0x2a, 0x03, 0x04}}
+	extraSANs := []asn1.RawValue{directoryName, registeredID}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "device"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"device.example.com"},
+		IPAddresses:  []net.IP{net.IPv4(192, 0, 2, 1)},
+	}
+	opts := &CreateCertificateOptions{
+		OtherNames: []OtherName{{TypeID: oidUPN, Value: asn1.RawValue{Tag: asn1.TagUTF8String, Bytes: []byte("user@example.com")}}},
+		ExtraSANs:  extraSANs,
+	}
+	der, err := CreateCertificateWithOptions(rand.Reader, template, template, priv.Public(), priv, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cert.DNSNames, template.DNSNames) || len(cert.IPAddresses) != 1 || !cert.IPAddresses[0].Equal(template.IPAddresses[0]) {
+		t.Errorf("unexpected names %v %v", cert.DNSNames, cert.IPAddresses)
+	}
+	unhandled, err := cert.UnhandledSANs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unhandled) != 2 {
+		t.Fatalf("expected 2 unhandled SANs, got %d", len(unhandled))
+	}
+	if unhandled[0].Tag != 4 || !unhandled[0].IsCompound || !bytes.Equal(unhandled[0].Bytes, name) {
+		t.Errorf("unexpected directoryName %+v", unhandled[0])
+	}
+	if unhandled[1].Tag != 8 || !bytes.Equal(unhandled[1].Bytes, registeredID.Bytes) {
+		t.Errorf("unexpected registeredID %+v", unhandled[1])
+	}
+
+	// reissuance from the parsed certificate keeps the names
+	reissued, err := CreateCertificateWithOptions(rand.Reader, cert.ToX509(), template, priv.Public(), priv, &CreateCertificateOptions{OtherNames: opts.OtherNames, ExtraSANs: unhandled})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reissuedCert, err := ParseCertificate(reissued)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(reissuedCert.getSANExtension(), cert.getSANExtension()) {
+		t.Error("subject alternative names changed on reissuance")
+	}
+
+	csrDER, err := CreateCertificateRequestWithOptions(rand.Reader, &x509.CertificateRequest{Subject: template.Subject}, priv, &CreateCertificateRequestOptions{ExtraSANs: extraSANs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr, err := ParseCertificateRequest(csrDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unhandled, err = csr.UnhandledSANs(); err != nil {
+		t.Fatal(err)
+	}
+	if len(unhandled) != 2 || unhandled[0].Tag != 4 || !bytes.Equal(unhandled[0].Bytes, name) {
+		t.Errorf("unexpected unhandled SANs of the request %v", unhandled)
+	}
+
+	// the OpenSSL certificate has only handled names
+	block, _ := pem.Decode([]byte(opensslOtherNameCertPEM))
+	if cert, err = ParseCertificate(block.Bytes); err != nil {
+		t.Fatal(err)
+	}
+	if unhandled, err := cert.UnhandledSANs(); err != nil || unhandled != nil {
+		t.Errorf("unexpected unhandled SANs %v: %v", unhandled, err)
+	}
+}
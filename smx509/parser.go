@@ -18,6 +18,7 @@ import (
 	"math/big"
 	"net"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -196,6 +197,30 @@ func parseAI(der cryptobyte.String) (pkix.AlgorithmIdentifier, error) {
 	return ai, nil
 }
 
+// matchingSignatureAIs reports whether the contents of the inner and outer
+// signature algorithm identifiers match. The parameters of SM2WithSM3 may be
+// absent in one and NULL in the other, as some older CFCA systems encode them.
+func matchingSignatureAIs(inner, outer []byte) bool {
+	if bytes.Equal(inner, outer) {
+		return true
+	}
+	innerAI, err := parseAI(inner)
+	if err != nil {
+		return false
+	}
+	outerAI, err := parseAI(outer)
+	if err != nil {
+		return false
+	}
+	return innerAI.Algorithm.Equal(oidSignatureSM2WithSM3) && outerAI.Algorithm.Equal(oidSignatureSM2WithSM3) &&
+		isAbsentOrNull(innerAI.Parameters) && isAbsentOrNull(outerAI.Parameters)
+}
+
+// isAbsentOrNull reports whether params are absent or NULL.
+func isAbsentOrNull(params asn1.RawValue) bool {
+	return len(params.FullBytes) == 0 || bytes.Equal(params.FullBytes, asn1.NullBytes)
+}
+
 func parseTime(der *cryptobyte.String) (time.Time, error) {
 	var t time.Time
 	switch {
@@ -213,38 +238,44 @@ func parseTime(der *cryptobyte.String) (time.Time, error) {
 	return t, nil
 }
 
+// parseUTCTime parses a UTCTime, with or without seconds as some legacy
+// certificates and CRLs omit them.
+func parseUTCTime(der *cryptobyte.String) (time.Time, error) {
+	// TODO(rolandshoemaker): once #45411 is fixed, the following code
+	// should be replaced with a call to der.ReadASN1UTCTime.
+	var utc cryptobyte.String
+	if !der.ReadASN1(&utc, cryptobyte_asn1.UTCTime) {
+		return time.Time{}, errors.New("x509: malformed UTCTime")
+	}
+	s := string(utc)
+
+	formatStr := "0601021504Z0700"
+	t, err := time.Parse(formatStr, s)
+	if err != nil {
+		formatStr = "060102150405Z0700"
+		t, err = time.Parse(formatStr, s)
+	}
+	if err != nil {
+		return t, err
+	}
+
+	if serialized := t.Format(formatStr); serialized != s {
+		return t, errors.New("x509: malformed UTCTime")
+	}
+
+	if t.Year() >= 2050 {
+		// UTCTime only encodes times prior to 2050. See https://tools.ietf.org/html/rfc5280#section-4.1.2.5.1
+		t = t.AddDate(-100, 0, 0)
+	}
+	return t, nil
+}
+
 func parseValidity(der cryptobyte.String) (time.Time, time.Time, error) {
 	extract := func() (time.Time, error) {
 		var t time.Time
 		switch {
 		case der.PeekASN1Tag(cryptobyte_asn1.UTCTime):
-			// TODO(rolandshoemaker): once #45411 is fixed, the following code
-			// should be replaced with a call to der.ReadASN1UTCTime.
-			var utc cryptobyte.String
-			if !der.ReadASN1(&utc, cryptobyte_asn1.UTCTime) {
-				return t, errors.New("x509: malformed UTCTime")
-			}
-			s := string(utc)
-
-			formatStr := "0601021504Z0700"
-			var err error
-			t, err = time.Parse(formatStr, s)
-			if err != nil {
-				formatStr = "060102150405Z0700"
-				t, err = time.Parse(formatStr, s)
-			}
-			if err != nil {
-				return t, err
-			}
-
-			if serialized := t.Format(formatStr); serialized != s {
-				return t, errors.New("x509: malformed UTCTime")
-			}
-
-			if t.Year() >= 2050 {
-				// UTCTime only encodes times prior to 2050. See https://tools.ietf.org/html/rfc5280#section-4.1.2.5.1
-				t = t.AddDate(-100, 0, 0)
-			}
+			return parseUTCTime(&der)
 		case der.PeekASN1Tag(cryptobyte_asn1.GeneralizedTime):
 			if !der.ReadASN1GeneralizedTime(&t) {
 				return t, errors.New("x509: malformed GeneralizedTime")
@@ -267,6 +298,28 @@ func parseValidity(der cryptobyte.String) (time.Time, time.Time, error) {
 	return notBefore, notAfter, nil
 }
 
+// parseCRLTime parses a time of a CRL. Unless strict, a UTCTime may omit the
+// seconds, and a GeneralizedTime may encode a date before 2050; if strict,
+// these are rejected as required by RFC 5280, 5.1.2.4.
+func parseCRLTime(der *cryptobyte.String, strict bool) (time.Time, error) {
+	if strict {
+		var value cryptobyte.String
+		var tag cryptobyte_asn1.Tag
+		if peek := *der; peek.ReadAnyASN1(&value, &tag) && tag == cryptobyte_asn1.UTCTime && len(value) != len("YYMMDDHHMMSSZ") {
+			return time.Time{}, errors.New("x509: malformed UTCTime")
+		}
+		t, err := parseTime(der)
+		if err == nil && tag == cryptobyte_asn1.GeneralizedTime && t.Year() < 2050 {
+			return t, errors.New("x509: GeneralizedTime used for a date before 2050")
+		}
+		return t, err
+	}
+	if der.PeekASN1Tag(cryptobyte_asn1.UTCTime) {
+		return parseUTCTime(der)
+	}
+	return parseTime(der)
+}
+
 func parseExtension(der cryptobyte.String) (pkix.Extension, error) {
 	var ext pkix.Extension
 	if !der.ReadASN1ObjectIdentifier(&ext.Id) {
@@ -285,6 +338,20 @@ func parseExtension(der cryptobyte.String) (pkix.Extension, error) {
 	return ext, nil
 }
 
+// unmarshalECPoint converts a point in uncompressed or compressed form,
+// SEC 1, Version 2.0, Section 2.3.4, into x, y. It returns x = nil if the
+// point is malformed or not on the curve.
+func unmarshalECPoint(curve elliptic.Curve, data []byte) (x, y *big.Int) {
+	if len(data) > 0 && (data[0] == 2 || data[0] == 3) {
+		x, y = elliptic.UnmarshalCompressed(curve, data)
+		if x == nil || !curve.IsOnCurve(x, y) {
+			return nil, nil
+		}
+		return x, y
+	}
+	return elliptic.Unmarshal(curve, data)
+}
+
 func parsePublicKey(keyData *publicKeyInfo) (any, error) {
 	oid := keyData.Algorithm.Algorithm
 	params := keyData.Algorithm.Parameters
@@ -330,7 +397,7 @@ func parsePublicKey(keyData *publicKeyInfo) (any, error) {
 		if namedCurve == nil {
 			return nil, errors.New("x509: unsupported elliptic curve")
 		}
-		x, y := elliptic.Unmarshal(namedCurve, der)
+		x, y := unmarshalECPoint(namedCurve, der)
 		if x == nil {
 			return nil, errors.New("x509: failed to unmarshal elliptic curve point")
 		}
@@ -341,16 +408,20 @@ func parsePublicKey(keyData *publicKeyInfo) (any, error) {
 		}
 		return pub, nil
 	case oid.Equal(oidPublicKeySM2):
-		paramsDer := cryptobyte.String(params.FullBytes)
-		namedCurveOID := new(asn1.ObjectIdentifier)
-		if !paramsDer.ReadASN1ObjectIdentifier(namedCurveOID) {
-			return nil, errors.New("x509: invalid SM2 parameters")
+		// The SM2 algorithm OID implies the SM2 curve, the parameters may be absent or NULL.
+		namedCurve := sm2.P256()
+		if len(params.FullBytes) != 0 && !bytes.Equal(params.FullBytes, asn1.NullBytes) {
+			paramsDer := cryptobyte.String(params.FullBytes)
+			namedCurveOID := new(asn1.ObjectIdentifier)
+			if !paramsDer.ReadASN1ObjectIdentifier(namedCurveOID) {
+				return nil, errors.New("x509: invalid SM2 parameters")
+			}
+			namedCurve = namedCurveFromOID(*namedCurveOID)
 		}
-		namedCurve := namedCurveFromOID(*namedCurveOID)
 		if namedCurve != sm2.P256() {
 			return nil, errors.New("x509: unsupported SM2 curve")
 		}
-		x, y := elliptic.Unmarshal(namedCurve, der)
+		x, y := unmarshalECPoint(namedCurve, der)
 		if x == nil {
 			return nil, errors.New("x509: failed to unmarshal SM2 curve point")
 		}
@@ -570,6 +641,110 @@ func parseCertificatePoliciesExtension(der cryptobyte.String) ([]x509.OID, error
 	return oids, nil
 }
 
+// policyMapping is an entry of the policyMappings extension.
+type policyMapping struct {
+	issuerDomainPolicy  x509.OID
+	subjectDomainPolicy x509.OID
+}
+
+// policyConstraints holds the policyMappings, policyConstraints and
+// inhibitAnyPolicy extensions of a certificate. Negative values mean that the
+// field is absent.
+type policyConstraints struct {
+	mappings              []policyMapping
+	requireExplicitPolicy int
+	inhibitPolicyMapping  int
+	inhibitAnyPolicy      int
+}
+
+// certPolicyConstraints parses the policy extensions of c. They are only
+// stored in the Certificate fields from Go 1.24, so they are read from
+// c.Extensions.
+func certPolicyConstraints(c *Certificate) (*policyConstraints, error) {
+	pc := &policyConstraints{requireExplicitPolicy: -1, inhibitPolicyMapping: -1, inhibitAnyPolicy: -1}
+	var err error
+	for _, e := range c.Extensions {
+		if len(e.Id) != 4 || e.Id[0] != 2 || e.Id[1] != 5 || e.Id[2] != 29 {
+			continue
+		}
+		switch e.Id[3] {
+		case 33:
+			pc.mappings, err = parsePolicyMappingsExtension(e.Value)
+		case 36:
+			pc.requireExplicitPolicy, pc.inhibitPolicyMapping, err = parsePolicyConstraintsExtension(e.Value)
+		case 54:
+			pc.inhibitAnyPolicy, err = parseInhibitAnyPolicyExtension(e.Value)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return pc, nil
+}
+
+// RFC 5280, 4.2.1.5
+func parsePolicyMappingsExtension(der cryptobyte.String) ([]policyMapping, error) {
+	var mappings []policyMapping
+	if !der.ReadASN1(&der, cryptobyte_asn1.SEQUENCE) {
+		return nil, errors.New("x509: invalid policy mappings extension")
+	}
+	for !der.Empty() {
+		var s, issuer, subject cryptobyte.String
+		if !der.ReadASN1(&s, cryptobyte_asn1.SEQUENCE) ||
+			!s.ReadASN1(&issuer, cryptobyte_asn1.OBJECT_IDENTIFIER) ||
+			!s.ReadASN1(&subject, cryptobyte_asn1.OBJECT_IDENTIFIER) {
+			return nil, errors.New("x509: invalid policy mappings extension")
+		}
+		issuerOID, ok1 := newOIDFromDER(issuer)
+		subjectOID, ok2 := newOIDFromDER(subject)
+		if !ok1 || !ok2 {
+			return nil, errors.New("x509: invalid policy mappings extension")
+		}
+		mappings = append(mappings, policyMapping{issuerOID, subjectOID})
+	}
+	return mappings, nil
+}
+
+// RFC 5280, 4.2.1.11
+func parsePolicyConstraintsExtension(der cryptobyte.String) (requireExplicitPolicy, inhibitPolicyMapping int, err error) {
+	requireExplicitPolicy, inhibitPolicyMapping = -1, -1
+	if !der.ReadASN1(&der, cryptobyte_asn1.SEQUENCE) {
+		return 0, 0, errors.New("x509: invalid policy constraints extension")
+	}
+	if der.PeekASN1Tag(cryptobyte_asn1.Tag(0).ContextSpecific()) {
+		var v int64
+		if !der.ReadASN1Int64WithTag(&v, cryptobyte_asn1.Tag(0).ContextSpecific()) {
+			return 0, 0, errors.New("x509: invalid policy constraints extension")
+		}
+		requireExplicitPolicy = int(v)
+		// Check for overflow.
+		if int64(requireExplicitPolicy) != v {
+			return 0, 0, errors.New("x509: policy constraints requireExplicitPolicy field overflows int")
+		}
+	}
+	if der.PeekASN1Tag(cryptobyte_asn1.Tag(1).ContextSpecific()) {
+		var v int64
+		if !der.ReadASN1Int64WithTag(&v, cryptobyte_asn1.Tag(1).ContextSpecific()) {
+			return 0, 0, errors.New("x509: invalid policy constraints extension")
+		}
+		inhibitPolicyMapping = int(v)
+		// Check for overflow.
+		if int64(inhibitPolicyMapping) != v {
+			return 0, 0, errors.New("x509: policy constraints inhibitPolicyMapping field overflows int")
+		}
+	}
+	return requireExplicitPolicy, inhibitPolicyMapping, nil
+}
+
+// RFC 5280, 4.2.1.14
+func parseInhibitAnyPolicyExtension(der cryptobyte.String) (int, error) {
+	var skipCerts int
+	if !der.ReadASN1Integer(&skipCerts) {
+		return 0, errors.New("x509: invalid inhibit any policy extension")
+	}
+	return skipCerts, nil
+}
+
 // isValidIPMask reports whether mask consists of zero or more 1 bits, followed by zero bits.
 func isValidIPMask(mask []byte) bool {
 	seenZero := false
@@ -640,10 +815,14 @@ func parseNameConstraintsExtension(out *Certificate, e pkix.Extension) (unhandle
 			}
 
 			var (
-				dnsTag   = cryptobyte_asn1.Tag(2).ContextSpecific()
-				emailTag = cryptobyte_asn1.Tag(1).ContextSpecific()
-				ipTag    = cryptobyte_asn1.Tag(7).ContextSpecific()
-				uriTag   = cryptobyte_asn1.Tag(6).ContextSpecific()
+				dnsTag          = cryptobyte_asn1.Tag(2).ContextSpecific()
+				emailTag        = cryptobyte_asn1.Tag(1).ContextSpecific()
+				ipTag           = cryptobyte_asn1.Tag(7).ContextSpecific()
+				uriTag          = cryptobyte_asn1.Tag(6).ContextSpecific()
+				registeredIDTag = cryptobyte_asn1.Tag(nameTypeRegisteredID).ContextSpecific()
+				otherNameTag    = cryptobyte_asn1.Tag(0).ContextSpecific().Constructed()
+				x400AddressTag  = cryptobyte_asn1.Tag(3).ContextSpecific().Constructed()
+				ediPartyNameTag = cryptobyte_asn1.Tag(5).ContextSpecific().Constructed()
 			)
 
 			switch tag {
@@ -736,6 +915,9 @@ func parseNameConstraintsExtension(out *Certificate, e pkix.Extension) (unhandle
 				}
 				uriDomains = append(uriDomains, domain)
 
+			case registeredIDTag, otherNameTag, x400AddressTag, ediPartyNameTag:
+				// Checked by Verify, see certOtherNameConstraints.
+
 			default:
 				unhandled = true
 			}
@@ -755,6 +937,138 @@ func parseNameConstraintsExtension(out *Certificate, e pkix.Extension) (unhandle
 	return unhandled, nil
 }
 
+// The forEachSAN tags of the GeneralName types which have no Certificate
+// field. The constructed types have the 0x20 bit set.
+const (
+	nameTypeOtherName    = 0x20
+	nameTypeX400Address  = 0x23
+	nameTypeEDIPartyName = 0x25
+	nameTypeRegisteredID = 8
+)
+
+// unhandledNameTypeString returns the RFC 5280 name of the GeneralName types
+// whose constraints can't be evaluated.
+func unhandledNameTypeString(tag int) string {
+	switch tag {
+	case nameTypeOtherName:
+		return "otherName"
+	case nameTypeX400Address:
+		return "x400Address"
+	case nameTypeEDIPartyName:
+		return "ediPartyName"
+	}
+	return fmt.Sprintf("GeneralName [%d]", tag&^0x20)
+}
+
+// otherNameConstraints holds the name constraints which are not stored in the
+// Certificate fields: the registeredID ones, which are matched exactly, and
+// the forEachSAN tags of the constrained types which can't be evaluated.
+type otherNameConstraints struct {
+	permittedRegisteredIDs []x509.OID
+	excludedRegisteredIDs  []x509.OID
+	unhandled              []int
+}
+
+// certOtherNameConstraints parses the name constraints of c for the
+// GeneralName types which have no Certificate field.
+func certOtherNameConstraints(c *Certificate) (*otherNameConstraints, error) {
+	nc := &otherNameConstraints{}
+	getValues := func(subtrees cryptobyte.String) (ids []x509.OID, err error) {
+		for !subtrees.Empty() {
+			var seq, value cryptobyte.String
+			var tag cryptobyte_asn1.Tag
+			if !subtrees.ReadASN1(&seq, cryptobyte_asn1.SEQUENCE) ||
+				!seq.ReadAnyASN1(&value, &tag) {
+				return nil, errors.New("x509: invalid NameConstraints extension")
+			}
+			switch nameType := int(tag ^ 0x80); nameType {
+			case nameTypeRegisteredID:
+				id, ok := newOIDFromDER(value)
+				if !ok {
+					return nil, errors.New("x509: invalid registeredID constraint")
+				}
+				ids = append(ids, id)
+			case nameTypeOtherName, nameTypeX400Address, nameTypeEDIPartyName:
+				if !slices.Contains(nc.unhandled, nameType) {
+					nc.unhandled = append(nc.unhandled, nameType)
+				}
+			}
+		}
+		return ids, nil
+	}
+	for _, e := range c.Extensions {
+		if !e.Id.Equal(oidExtensionNameConstraints) {
+			continue
+		}
+		outer := cryptobyte.String(e.Value)
+		var toplevel, permitted, excluded cryptobyte.String
+		if !outer.ReadASN1(&toplevel, cryptobyte_asn1.SEQUENCE) ||
+			!toplevel.ReadOptionalASN1(&permitted, nil, cryptobyte_asn1.Tag(0).ContextSpecific().Constructed()) ||
+			!toplevel.ReadOptionalASN1(&excluded, nil, cryptobyte_asn1.Tag(1).ContextSpecific().Constructed()) {
+			return nil, errors.New("x509: invalid NameConstraints extension")
+		}
+		var err error
+		if nc.permittedRegisteredIDs, err = getValues(permitted); err != nil {
+			return nil, err
+		}
+		if nc.excludedRegisteredIDs, err = getValues(excluded); err != nil {
+			return nil, err
+		}
+	}
+	return nc, nil
+}
+
+// parseDistributionPoints returns the URIs of the full names of the
+// distribution points of a CRL distribution points or freshest CRL extension.
+func parseDistributionPoints(der cryptobyte.String) ([]string, error) {
+	// RFC 5280, 4.2.1.13
+
+	// CRLDistributionPoints ::= SEQUENCE SIZE (1..MAX) OF DistributionPoint
+	//
+	// DistributionPoint ::= SEQUENCE {
+	//     distributionPoint       [0]     DistributionPointName OPTIONAL,
+	//     reasons                 [1]     ReasonFlags OPTIONAL,
+	//     cRLIssuer               [2]     GeneralNames OPTIONAL }
+	//
+	// DistributionPointName ::= CHOICE {
+	//     fullName                [0]     GeneralNames,
+	//     nameRelativeToCRLIssuer [1]     RelativeDistinguishedName }
+	var uris []string
+	if !der.ReadASN1(&der, cryptobyte_asn1.SEQUENCE) {
+		return nil, errors.New("x509: invalid CRL distribution points")
+	}
+	for !der.Empty() {
+		var dpDER cryptobyte.String
+		if !der.ReadASN1(&dpDER, cryptobyte_asn1.SEQUENCE) {
+			return nil, errors.New("x509: invalid CRL distribution point")
+		}
+		var dpNameDER cryptobyte.String
+		var dpNamePresent bool
+		if !dpDER.ReadOptionalASN1(&dpNameDER, &dpNamePresent, cryptobyte_asn1.Tag(0).Constructed().ContextSpecific()) {
+			return nil, errors.New("x509: invalid CRL distribution point")
+		}
+		// Only the URIs of the full names are kept, the names relative to
+		// the CRL issuer are skipped.
+		if !dpNamePresent || dpNameDER.PeekASN1Tag(cryptobyte_asn1.Tag(1).Constructed().ContextSpecific()) {
+			continue
+		}
+		if !dpNameDER.ReadASN1(&dpNameDER, cryptobyte_asn1.Tag(0).Constructed().ContextSpecific()) {
+			return nil, errors.New("x509: invalid CRL distribution point")
+		}
+		for !dpNameDER.Empty() {
+			if !dpNameDER.PeekASN1Tag(cryptobyte_asn1.Tag(6).ContextSpecific()) {
+				break
+			}
+			var uri cryptobyte.String
+			if !dpNameDER.ReadASN1(&uri, cryptobyte_asn1.Tag(6).ContextSpecific()) {
+				return nil, errors.New("x509: invalid CRL distribution point")
+			}
+			uris = append(uris, string(uri))
+		}
+	}
+	return uris, nil
+}
+
 func processExtensions(out *Certificate) error {
 	var err error
 	for _, e := range out.Extensions {
@@ -781,8 +1095,10 @@ func processExtensions(out *Certificate) error {
 				}
 
 				if len(out.DNSNames) == 0 && len(out.EmailAddresses) == 0 && len(out.IPAddresses) == 0 && len(out.URIs) == 0 {
-					// If we didn't parse anything then we do the critical check, below.
-					unhandled = true
+					// If we didn't parse anything then we do the critical check, below,
+					// unless there are otherNames, see Certificate.OtherNames.
+					otherNames, err := parseOtherNames(e.Value)
+					unhandled = err != nil || len(otherNames) == 0
 				}
 
 			case 30:
@@ -792,48 +1108,9 @@ func processExtensions(out *Certificate) error {
 				}
 
 			case 31:
-				// RFC 5280, 4.2.1.13
-
-				// CRLDistributionPoints ::= SEQUENCE SIZE (1..MAX) OF DistributionPoint
-				//
-				// DistributionPoint ::= SEQUENCE {
-				//     distributionPoint       [0]     DistributionPointName OPTIONAL,
-				//     reasons                 [1]     ReasonFlags OPTIONAL,
-				//     cRLIssuer               [2]     GeneralNames OPTIONAL }
-				//
-				// DistributionPointName ::= CHOICE {
-				//     fullName                [0]     GeneralNames,
-				//     nameRelativeToCRLIssuer [1]     RelativeDistinguishedName }
-				val := cryptobyte.String(e.Value)
-				if !val.ReadASN1(&val, cryptobyte_asn1.SEQUENCE) {
-					return errors.New("x509: invalid CRL distribution points")
-				}
-				for !val.Empty() {
-					var dpDER cryptobyte.String
-					if !val.ReadASN1(&dpDER, cryptobyte_asn1.SEQUENCE) {
-						return errors.New("x509: invalid CRL distribution point")
-					}
-					var dpNameDER cryptobyte.String
-					var dpNamePresent bool
-					if !dpDER.ReadOptionalASN1(&dpNameDER, &dpNamePresent, cryptobyte_asn1.Tag(0).Constructed().ContextSpecific()) {
-						return errors.New("x509: invalid CRL distribution point")
-					}
-					if !dpNamePresent {
-						continue
-					}
-					if !dpNameDER.ReadASN1(&dpNameDER, cryptobyte_asn1.Tag(0).Constructed().ContextSpecific()) {
-						return errors.New("x509: invalid CRL distribution point")
-					}
-					for !dpNameDER.Empty() {
-						if !dpNameDER.PeekASN1Tag(cryptobyte_asn1.Tag(6).ContextSpecific()) {
-							break
-						}
-						var uri cryptobyte.String
-						if !dpNameDER.ReadASN1(&uri, cryptobyte_asn1.Tag(6).ContextSpecific()) {
-							return errors.New("x509: invalid CRL distribution point")
-						}
-						out.CRLDistributionPoints = append(out.CRLDistributionPoints, string(uri))
-					}
+				out.CRLDistributionPoints, err = parseDistributionPoints(e.Value)
+				if err != nil {
+					return err
 				}
 
 			case 35:
@@ -881,6 +1158,24 @@ func processExtensions(out *Certificate) error {
 						out.PolicyIdentifiers = append(out.PolicyIdentifiers, oid)
 					}
 				}
+			case 33:
+				var mappings []policyMapping
+				if mappings, err = parsePolicyMappingsExtension(e.Value); err != nil {
+					return err
+				}
+				setPolicyMappings(out, mappings)
+			case 36:
+				var requireExplicitPolicy, inhibitPolicyMapping int
+				if requireExplicitPolicy, inhibitPolicyMapping, err = parsePolicyConstraintsExtension(e.Value); err != nil {
+					return err
+				}
+				setPolicyConstraints(out, requireExplicitPolicy, inhibitPolicyMapping)
+			case 54:
+				var inhibitAnyPolicy int
+				if inhibitAnyPolicy, err = parseInhibitAnyPolicyExtension(e.Value); err != nil {
+					return err
+				}
+				setInhibitAnyPolicy(out, inhibitAnyPolicy)
 			default:
 				// Unknown extensions are recorded if critical.
 				unhandled = true
@@ -930,7 +1225,29 @@ func processExtensions(out *Certificate) error {
 	return nil
 }
 
-func parseCertificate(der []byte) (*Certificate, error) {
+// parseRawSubject returns the RawSubject of the certificate in der. Only the
+// structure of the certificate is checked, the fields other than the subject
+// are skipped without being parsed.
+func parseRawSubject(der []byte) ([]byte, error) {
+	input := cryptobyte.String(der)
+	var cert, tbs, subject cryptobyte.String
+	if !input.ReadASN1(&cert, cryptobyte_asn1.SEQUENCE) || !input.Empty() ||
+		!cert.ReadASN1(&tbs, cryptobyte_asn1.SEQUENCE) ||
+		!cert.SkipASN1(cryptobyte_asn1.SEQUENCE) ||
+		!cert.SkipASN1(cryptobyte_asn1.BIT_STRING) || !cert.Empty() ||
+		!tbs.SkipOptionalASN1(cryptobyte_asn1.Tag(0).Constructed().ContextSpecific()) ||
+		!tbs.SkipASN1(cryptobyte_asn1.INTEGER) ||
+		!tbs.SkipASN1(cryptobyte_asn1.SEQUENCE) ||
+		!tbs.SkipASN1(cryptobyte_asn1.SEQUENCE) ||
+		!tbs.SkipASN1(cryptobyte_asn1.SEQUENCE) ||
+		!tbs.ReadASN1Element(&subject, cryptobyte_asn1.SEQUENCE) ||
+		!tbs.SkipASN1(cryptobyte_asn1.SEQUENCE) {
+		return nil, errors.New("x509: malformed certificate")
+	}
+	return subject, nil
+}
+
+func parseCertificate(der []byte, opts *ParseOptions) (*Certificate, error) {
 	cert := &Certificate{}
 
 	input := cryptobyte.String(der)
@@ -979,6 +1296,11 @@ func parseCertificate(der []byte) (*Certificate, error) {
 	// only 10 trusted certificates with negative serial numbers
 	// according to censys.io.
 	cert.SerialNumber = serial
+	if opts.StrictSerialNumber {
+		if err := checkSerialNumber(serial); err != nil {
+			return nil, err
+		}
+	}
 
 	var sigAISeq cryptobyte.String
 	if !tbs.ReadASN1(&sigAISeq, cryptobyte_asn1.SEQUENCE) {
@@ -991,7 +1313,7 @@ func parseCertificate(der []byte) (*Certificate, error) {
 	if !input.ReadASN1(&outerSigAISeq, cryptobyte_asn1.SEQUENCE) {
 		return nil, errors.New("x509: malformed algorithm identifier")
 	}
-	if !bytes.Equal(outerSigAISeq, sigAISeq) {
+	if !matchingSignatureAIs(sigAISeq, outerSigAISeq) {
 		return nil, errors.New("x509: inner and outer signature algorithm identifiers don't match")
 	}
 	sigAI, err := parseAI(sigAISeq)
@@ -1047,7 +1369,7 @@ func parseCertificate(der []byte) (*Certificate, error) {
 	if err != nil {
 		return nil, err
 	}
-	cert.PublicKeyAlgorithm = getPublicKeyAlgorithmFromOID(pkAI.Algorithm)
+	cert.PublicKeyAlgorithm = getPublicKeyAlgorithmFromAI(pkAI)
 	var spk asn1.BitString
 	if !spki.ReadASN1BitString(&spk) {
 		return nil, errors.New("x509: malformed subjectPublicKey")
@@ -1076,7 +1398,8 @@ func parseCertificate(der []byte) (*Certificate, error) {
 				return nil, errors.New("x509: malformed extensions")
 			}
 			if present {
-				seenExts := make(map[string]bool)
+				seenExts := make(map[string]cryptobyte.String)
+				var unique []pkix.Extension
 				if !extensions.ReadASN1(&extensions, cryptobyte_asn1.SEQUENCE) {
 					return nil, errors.New("x509: malformed extensions")
 				}
@@ -1090,13 +1413,35 @@ func parseCertificate(der []byte) (*Certificate, error) {
 						return nil, err
 					}
 					oidStr := ext.Id.String()
-					if seenExts[oidStr] {
-						return nil, fmt.Errorf("x509: certificate contains duplicate extension with OID %q", oidStr)
+					if seen, ok := seenExts[oidStr]; ok {
+						if !opts.AllowDuplicateExtensions {
+							return nil, fmt.Errorf("x509: certificate contains duplicate extension with OID %q", oidStr)
+						}
+						if !bytes.Equal(seen, extension) {
+							return nil, fmt.Errorf("x509: certificate contains conflicting duplicate extensions with OID %q", oidStr)
+						}
+						if unique == nil {
+							unique = slices.Clone(cert.Extensions)
+						}
+						cert.Extensions = append(cert.Extensions, ext)
+						continue
 					}
-					seenExts[oidStr] = true
+					seenExts[oidStr] = extension
 					cert.Extensions = append(cert.Extensions, ext)
+					if unique != nil {
+						unique = append(unique, ext)
+					}
+				}
+				if unique != nil {
+					// Only the first of identical duplicates is processed,
+					// they are all kept in Extensions for DuplicateExtensions.
+					all := cert.Extensions
+					cert.Extensions = unique
+					err = processExtensions(cert)
+					cert.Extensions = all
+				} else {
+					err = processExtensions(cert)
 				}
-				err = processExtensions(cert)
 				if err != nil {
 					return nil, err
 				}
@@ -1115,7 +1460,27 @@ func parseCertificate(der []byte) (*Certificate, error) {
 
 // ParseCertificate parses a single certificate from the given ASN.1 DER data.
 func ParseCertificate(der []byte) (*Certificate, error) {
-	cert, err := parseCertificate(der)
+	return ParseCertificateWithOptions(der, ParseOptions{})
+}
+
+// ParseOptions relaxes or tightens the checks of ParseCertificateWithOptions.
+// The zero value is equivalent to ParseCertificate.
+type ParseOptions struct {
+	// AllowDuplicateExtensions accepts an extension present more than once,
+	// as in some legacy GM certificates, if all the occurrences have the
+	// same DER encoding. Only the first one is processed, the duplicates are
+	// kept in Extensions and reported by Certificate.DuplicateExtensions.
+	// Conflicting duplicates are still rejected.
+	AllowDuplicateExtensions bool
+
+	// StrictSerialNumber rejects a serial number which is not positive or
+	// encodes to more than 20 octets, with a SerialNumberError.
+	StrictSerialNumber bool
+}
+
+// ParseCertificateWithOptions is like ParseCertificate, with options.
+func ParseCertificateWithOptions(der []byte, opts ParseOptions) (*Certificate, error) {
+	cert, err := parseCertificate(der, &opts)
 	if err != nil {
 		return nil, err
 	}
@@ -1125,14 +1490,31 @@ func ParseCertificate(der []byte) (*Certificate, error) {
 	return cert, err
 }
 
+// DuplicateExtensions returns the OIDs of the extensions which c contains
+// more than once, which is only possible if it was parsed with
+// ParseOptions.AllowDuplicateExtensions.
+func (c *Certificate) DuplicateExtensions() []asn1.ObjectIdentifier {
+	var duplicates []asn1.ObjectIdentifier
+	for i, e := range c.Extensions {
+		if oidInExtensions(e.Id, c.Extensions[:i]) && !slices.ContainsFunc(duplicates, e.Id.Equal) {
+			duplicates = append(duplicates, e.Id)
+		}
+	}
+	return duplicates
+}
+
 // ParseCertificates parses one or more certificates from the given ASN.1 DER
 // data. The certificates must be concatenated with no intermediate padding.
+// The returned error reports the index of the certificate which failed to parse.
 func ParseCertificates(der []byte) ([]*Certificate, error) {
+	if len(der) == 0 {
+		return nil, errors.New("x509: no certificate in DER data")
+	}
 	var certs []*Certificate
 	for len(der) > 0 {
-		cert, err := parseCertificate(der)
+		cert, err := parseCertificate(der, &ParseOptions{})
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("x509: failed to parse certificate at index %d: %w", len(certs), err)
 		}
 		certs = append(certs, cert)
 		der = der[len(cert.Raw):]
@@ -1148,13 +1530,61 @@ func ParseCertificatePEM(data []byte) (*Certificate, error) {
 	return ParseCertificate(block.Bytes)
 }
 
+// ParseCertificatesPEM parses all the "CERTIFICATE" blocks of the PEM data,
+// such as a bundle of intermediate certificates. Blocks of other types are skipped.
+// The returned error reports the index of the certificate block which failed to parse.
+func ParseCertificatesPEM(data []byte) ([]*Certificate, error) {
+	var certs []*Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("x509: failed to parse certificate at index %d: %w", len(certs), err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("x509: no certificate in PEM data")
+	}
+	return certs, nil
+}
+
 // The X.509 standards confusingly 1-indexed the version names, but 0-indexed
 // the actual encoded version, so the version for X.509v2 is 1.
 const x509v2Version = 1
 
 // ParseRevocationList parses a X509 v2 [Certificate] Revocation List from the given
 // ASN.1 DER data.
+//
+// It accepts the variants found in legacy GM CRLs: a missing NextUpdate,
+// which is left as the zero time, a UTCTime without seconds, a GeneralizedTime
+// for a date before 2050, and an SM2WithSM3 AlgorithmIdentifier with NULL
+// parameters. Use [ParseRevocationListWithOptions] to reject them.
 func ParseRevocationList(der []byte) (*RevocationList, error) {
+	return ParseRevocationListWithOptions(der, ParseRevocationListOptions{})
+}
+
+// ParseRevocationListOptions tightens the checks of
+// ParseRevocationListWithOptions. The zero value is equivalent to
+// ParseRevocationList.
+type ParseRevocationListOptions struct {
+	// Strict rejects the CRLs which do not conform to RFC 5280 in the ways
+	// ParseRevocationList tolerates: a missing NextUpdate, a UTCTime without
+	// seconds, a GeneralizedTime for a date before 2050, and an SM2WithSM3
+	// AlgorithmIdentifier with parameters, or differing inner and outer
+	// AlgorithmIdentifiers.
+	Strict bool
+}
+
+// ParseRevocationListWithOptions is like ParseRevocationList, with options.
+func ParseRevocationListWithOptions(der []byte, opts ParseRevocationListOptions) (*RevocationList, error) {
 	rl := &RevocationList{}
 
 	input := cryptobyte.String(der)
@@ -1202,13 +1632,16 @@ func ParseRevocationList(der []byte) (*RevocationList, error) {
 	if !input.ReadASN1(&outerSigAISeq, cryptobyte_asn1.SEQUENCE) {
 		return nil, errors.New("x509: malformed algorithm identifier")
 	}
-	if !bytes.Equal(outerSigAISeq, sigAISeq) {
+	if opts.Strict && !bytes.Equal(sigAISeq, outerSigAISeq) || !matchingSignatureAIs(sigAISeq, outerSigAISeq) {
 		return nil, errors.New("x509: inner and outer signature algorithm identifiers don't match")
 	}
 	sigAI, err := parseAI(sigAISeq)
 	if err != nil {
 		return nil, err
 	}
+	if opts.Strict && sigAI.Algorithm.Equal(oidSignatureSM2WithSM3) && len(sigAI.Parameters.FullBytes) != 0 {
+		return nil, errors.New("x509: SM2WithSM3 signature algorithm identifier with parameters")
+	}
 	rl.SignatureAlgorithm = getSignatureAlgorithmFromAI(sigAI)
 
 	var signature asn1.BitString
@@ -1228,15 +1661,17 @@ func ParseRevocationList(der []byte) (*RevocationList, error) {
 	}
 	rl.Issuer.FillFromRDNSequence(issuerRDNs)
 
-	rl.ThisUpdate, err = parseTime(&tbs)
+	rl.ThisUpdate, err = parseCRLTime(&tbs, opts.Strict)
 	if err != nil {
 		return nil, err
 	}
 	if tbs.PeekASN1Tag(cryptobyte_asn1.GeneralizedTime) || tbs.PeekASN1Tag(cryptobyte_asn1.UTCTime) {
-		rl.NextUpdate, err = parseTime(&tbs)
+		rl.NextUpdate, err = parseCRLTime(&tbs, opts.Strict)
 		if err != nil {
 			return nil, err
 		}
+	} else if opts.Strict {
+		return nil, errors.New("x509: crl has no next update")
 	}
 
 	if tbs.PeekASN1Tag(cryptobyte_asn1.SEQUENCE) {
@@ -1245,49 +1680,14 @@ func ParseRevocationList(der []byte) (*RevocationList, error) {
 			return nil, errors.New("x509: malformed crl")
 		}
 		for !revokedSeq.Empty() {
-			rce := x509.RevocationListEntry{}
-
 			var certSeq cryptobyte.String
 			if !revokedSeq.ReadASN1Element(&certSeq, cryptobyte_asn1.SEQUENCE) {
 				return nil, errors.New("x509: malformed crl")
 			}
-			rce.Raw = certSeq
-			if !certSeq.ReadASN1(&certSeq, cryptobyte_asn1.SEQUENCE) {
-				return nil, errors.New("x509: malformed crl")
-			}
-
-			rce.SerialNumber = new(big.Int)
-			if !certSeq.ReadASN1Integer(rce.SerialNumber) {
-				return nil, errors.New("x509: malformed serial number")
-			}
-			rce.RevocationTime, err = parseTime(&certSeq)
+			rce, err := parseRevocationListEntry(certSeq, opts.Strict)
 			if err != nil {
 				return nil, err
 			}
-			var extensions cryptobyte.String
-			var present bool
-			if !certSeq.ReadOptionalASN1(&extensions, &present, cryptobyte_asn1.SEQUENCE) {
-				return nil, errors.New("x509: malformed extensions")
-			}
-			if present {
-				for !extensions.Empty() {
-					var extension cryptobyte.String
-					if !extensions.ReadASN1(&extension, cryptobyte_asn1.SEQUENCE) {
-						return nil, errors.New("x509: malformed extension")
-					}
-					ext, err := parseExtension(extension)
-					if err != nil {
-						return nil, err
-					}
-					if ext.Id.Equal(oidExtensionReasonCode) {
-						val := cryptobyte.String(ext.Value)
-						if !val.ReadASN1Enum(&rce.ReasonCode) {
-							return nil, fmt.Errorf("x509: malformed reasonCode extension")
-						}
-					}
-					rce.Extensions = append(rce.Extensions, ext)
-				}
-			}
 
 			rl.RevokedCertificateEntries = append(rl.RevokedCertificateEntries, rce)
 			rcDeprecated := pkix.RevokedCertificate{
@@ -1305,33 +1705,88 @@ func ParseRevocationList(der []byte) (*RevocationList, error) {
 		return nil, errors.New("x509: malformed extensions")
 	}
 	if present {
-		if !extensions.ReadASN1(&extensions, cryptobyte_asn1.SEQUENCE) {
-			return nil, errors.New("x509: malformed extensions")
+		if err := parseRevocationListExtensions(extensions, rl); err != nil {
+			return nil, err
 		}
+	}
+
+	return rl, nil
+}
+
+// parseRevocationListEntry parses a revoked certificate entry of a CRL,
+// including its tag and length, with the revocation time checks of
+// parseCRLTime.
+func parseRevocationListEntry(der cryptobyte.String, strict bool) (x509.RevocationListEntry, error) {
+	rce := x509.RevocationListEntry{Raw: der}
+	var certSeq cryptobyte.String
+	if !der.ReadASN1(&certSeq, cryptobyte_asn1.SEQUENCE) {
+		return rce, errors.New("x509: malformed crl")
+	}
+
+	rce.SerialNumber = new(big.Int)
+	if !certSeq.ReadASN1Integer(rce.SerialNumber) {
+		return rce, errors.New("x509: malformed serial number")
+	}
+	var err error
+	rce.RevocationTime, err = parseCRLTime(&certSeq, strict)
+	if err != nil {
+		return rce, err
+	}
+	var extensions cryptobyte.String
+	var present bool
+	if !certSeq.ReadOptionalASN1(&extensions, &present, cryptobyte_asn1.SEQUENCE) {
+		return rce, errors.New("x509: malformed extensions")
+	}
+	if present {
 		for !extensions.Empty() {
 			var extension cryptobyte.String
 			if !extensions.ReadASN1(&extension, cryptobyte_asn1.SEQUENCE) {
-				return nil, errors.New("x509: malformed extension")
+				return rce, errors.New("x509: malformed extension")
 			}
 			ext, err := parseExtension(extension)
 			if err != nil {
-				return nil, err
+				return rce, err
 			}
-			if ext.Id.Equal(oidExtensionAuthorityKeyId) {
-				rl.AuthorityKeyId, err = parseAuthorityKeyIdentifier(ext)
-				if err != nil {
-					return nil, err
-				}
-			} else if ext.Id.Equal(oidExtensionCRLNumber) {
-				value := cryptobyte.String(ext.Value)
-				rl.Number = new(big.Int)
-				if !value.ReadASN1Integer(rl.Number) {
-					return nil, errors.New("x509: malformed crl number")
+			if ext.Id.Equal(oidExtensionReasonCode) {
+				val := cryptobyte.String(ext.Value)
+				if !val.ReadASN1Enum(&rce.ReasonCode) {
+					return rce, fmt.Errorf("x509: malformed reasonCode extension")
 				}
 			}
-			rl.Extensions = append(rl.Extensions, ext)
+			rce.Extensions = append(rce.Extensions, ext)
 		}
 	}
+	return rce, nil
+}
 
-	return rl, nil
+// parseRevocationListExtensions parses the crlExtensions of a CRL, the
+// content of their explicit tag, into rl.
+func parseRevocationListExtensions(extensions cryptobyte.String, rl *RevocationList) error {
+	if !extensions.ReadASN1(&extensions, cryptobyte_asn1.SEQUENCE) {
+		return errors.New("x509: malformed extensions")
+	}
+	for !extensions.Empty() {
+		var extension cryptobyte.String
+		if !extensions.ReadASN1(&extension, cryptobyte_asn1.SEQUENCE) {
+			return errors.New("x509: malformed extension")
+		}
+		ext, err := parseExtension(extension)
+		if err != nil {
+			return err
+		}
+		if ext.Id.Equal(oidExtensionAuthorityKeyId) {
+			rl.AuthorityKeyId, err = parseAuthorityKeyIdentifier(ext)
+			if err != nil {
+				return err
+			}
+		} else if ext.Id.Equal(oidExtensionCRLNumber) {
+			value := cryptobyte.String(ext.Value)
+			rl.Number = new(big.Int)
+			if !value.ReadASN1Integer(rl.Number) {
+				return errors.New("x509: malformed crl number")
+			}
+		}
+		rl.Extensions = append(rl.Extensions, ext)
+	}
+	return nil
 }
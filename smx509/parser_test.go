@@ -1,11 +1,18 @@
 package smx509
 
 import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509/pkix"
 	"encoding/asn1"
 	"encoding/hex"
 	"encoding/pem"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/yunmoon/gmsm/sm2"
+	"golang.org/x/crypto/cryptobyte"
 	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
 )
 
@@ -194,3 +201,312 @@ d5l1tRhScKu2NBgm74nYmJxJYgvuTA38wGhRrGU=
 		}
 	}
 }
+
+func TestParseCertificatesPEM(t *testing.T) {
+	bundle := compressedSM2CertPEM + pemPublicKey + rsaPSSSelfSignedPEM + ecdsaSHA256p256CertPem
+	certs, err := ParseCertificatesPEM([]byte(bundle))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PublicKeyAlgorithm{SM2, RSA, ECDSA}
+	if len(certs) != len(want) {
+		t.Fatalf("got %d certificates, want %d", len(certs), len(want))
+	}
+	var der []byte
+	for i, cert := range certs {
+		if cert.PublicKeyAlgorithm != want[i] {
+			t.Errorf("certificate %d: got %v, want %v", i, PublicKeyAlgorithmString(cert.PublicKeyAlgorithm), PublicKeyAlgorithmString(want[i]))
+		}
+		der = append(der, cert.Raw...)
+	}
+
+	// back-to-back DER certificates
+	parsed, err := ParseCertificates(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, cert := range parsed {
+		if !cert.Equal(certs[i]) {
+			t.Errorf("certificate %d differs", i)
+		}
+	}
+
+	// corrupted middle entry
+	corrupted := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte{0x30, 0x03, 0x02, 0x01, 0x01}})
+	_, err = ParseCertificatesPEM([]byte(compressedSM2CertPEM + string(corrupted) + rsaPSSSelfSignedPEM))
+	if err == nil || !strings.Contains(err.Error(), "index 1") {
+		t.Errorf("unexpected error %v", err)
+	}
+	_, err = ParseCertificates(append(append(append([]byte{}, certs[0].Raw...), 0x30, 0x03, 0x02, 0x01, 0x01), certs[1].Raw...))
+	if err == nil || !strings.Contains(err.Error(), "index 1") {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// empty input
+	if _, err := ParseCertificatesPEM(nil); err == nil {
+		t.Error("expected error with empty input")
+	}
+	if _, err := ParseCertificatesPEM([]byte(pemPublicKey)); err == nil {
+		t.Error("expected error without certificate blocks")
+	}
+	if _, err := ParseCertificates(nil); err == nil {
+		t.Error("expected error with empty input")
+	}
+}
+
+// The SM2 certificates below contain the keyUsage extension twice, once with
+// identical and once with conflicting values. They were created by passing
+// the extensions in ExtraExtensions.
+const dupIdenticalExtSM2Cert = `-----BEGIN CERTIFICATE-----
+MIIBlzCCAT2gAwIBAgICB94wCgYIKoEcz1UBg3UwMzEWMBQGA1UEChMNUHJvdmlu
+Y2lhbCBDQTEZMBcGA1UEAxMQR00gbGVnYWN5IGRldmljZTAeFw0xNDAzMDEwMDAw
+MDBaFw00OTAzMDEwMDAwMDBaMDMxFjAUBgNVBAoTDVByb3ZpbmNpYWwgQ0ExGTAX
+BgNVBAMTEEdNIGxlZ2FjeSBkZXZpY2UwWTATBgcqhkjOPQIBBggqgRzPVQGCLQNC
+AATBsCroCUQmmh3Fiijx5vaZJ9etFvINZ3hHHiOLB1kg+AA3Rfp6PKe/rFZjtXJ0
+vp5oeFLas/6x7KQb3FU9ooWto0EwPzAdBgNVHREEFjAUghJkZXZpY2UuZXhhbXBs
+ZS5jb20wDgYDVR0PAQH/BAQDAgWgMA4GA1UdDwEB/wQEAwIFoDAKBggqgRzPVQGD
+dQNIADBFAiBlxOazN6WM3i6HYK1DqBt2+uTEe12JUNBtG41SnZljJAIhAIP3MpDt
+Bc26ZNsoOgEO7EbE/07hiX/F4lhowcvnJtq0
+-----END CERTIFICATE-----`
+
+const dupConflictingExtSM2Cert = `-----BEGIN CERTIFICATE-----
+MIIBljCCAT2gAwIBAgICB94wCgYIKoEcz1UBg3UwMzEWMBQGA1UEChMNUHJvdmlu
+Y2lhbCBDQTEZMBcGA1UEAxMQR00gbGVnYWN5IGRldmljZTAeFw0xNDAzMDEwMDAw
+MDBaFw00OTAzMDEwMDAwMDBaMDMxFjAUBgNVBAoTDVByb3ZpbmNpYWwgQ0ExGTAX
+BgNVBAMTEEdNIGxlZ2FjeSBkZXZpY2UwWTATBgcqhkjOPQIBBggqgRzPVQGCLQNC
+AATBsCroCUQmmh3Fiijx5vaZJ9etFvINZ3hHHiOLB1kg+AA3Rfp6PKe/rFZjtXJ0
+vp5oeFLas/6x7KQb3FU9ooWto0EwPzAdBgNVHREEFjAUghJkZXZpY2UuZXhhbXBs
+ZS5jb20wDgYDVR0PAQH/BAQDAgWgMA4GA1UdDwEB/wQEAwIHgDAKBggqgRzPVQGD
+dQNHADBEAiAbOTZ8XfrzCHv6kAedDN1Dn1eVvGf+5ZJzOp0CEQwvOQIgIMqEtAx5
+QlPcB48DjsplVaMpXGrwyIxt3MJLOQQPFmg=
+-----END CERTIFICATE-----`
+
+func TestParseCertificateAllowDuplicateExtensions(t *testing.T) {
+	identical, _ := pem.Decode([]byte(dupIdenticalExtSM2Cert))
+	conflicting, _ := pem.Decode([]byte(dupConflictingExtSM2Cert))
+
+	// strict by default
+	for _, der := range [][]byte{identical.Bytes, conflicting.Bytes} {
+		if _, err := ParseCertificate(der); err == nil || !strings.Contains(err.Error(), "duplicate extension") {
+			t.Errorf("expected duplicate extension error, got %v", err)
+		}
+		if _, err := ParseCertificateWithOptions(der, ParseOptions{}); err == nil {
+			t.Error("expected duplicate extension error with the zero options")
+		}
+	}
+
+	lax := ParseOptions{AllowDuplicateExtensions: true}
+	cert, err := ParseCertificateWithOptions(identical.Bytes, lax)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.KeyUsage != KeyUsageDigitalSignature|KeyUsageKeyEncipherment {
+		t.Errorf("unexpected key usage %v", cert.KeyUsage)
+	}
+	if len(cert.DNSNames) != 1 || cert.DNSNames[0] != "device.example.com" {
+		t.Errorf("unexpected DNS names %v", cert.DNSNames)
+	}
+	if len(cert.Extensions) != 3 {
+		t.Errorf("expected all 3 extensions to be kept, got %d", len(cert.Extensions))
+	}
+	if dups := cert.DuplicateExtensions(); len(dups) != 1 || !dups[0].Equal(asn1.ObjectIdentifier(oidExtensionKeyUsage)) {
+		t.Errorf("unexpected duplicate extensions %v", dups)
+	}
+	if err := cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature); err != nil {
+		t.Errorf("signature check failed: %v", err)
+	}
+
+	if _, err := ParseCertificateWithOptions(conflicting.Bytes, lax); err == nil || !strings.Contains(err.Error(), "conflicting duplicate") {
+		t.Errorf("expected conflicting duplicate error, got %v", err)
+	}
+
+	// no duplicates to report in strictly parsed certificates
+	block, _ := pem.Decode([]byte(opensslOtherNameCertPEM))
+	if cert, err = ParseCertificateWithOptions(block.Bytes, lax); err != nil {
+		t.Fatal(err)
+	}
+	if dups := cert.DuplicateExtensions(); dups != nil {
+		t.Errorf("unexpected duplicate extensions %v", dups)
+	}
+}
+
+// legacyCRLIssuerCertPEM issued legacyCRLPEM, a CRL with the quirks of some
+// provincial CAs: SM2WithSM3 with NULL parameters, a thisUpdate UTCTime
+// without seconds, no nextUpdate, and an entry with a GeneralizedTime before
+// 2050.
+const legacyCRLIssuerCertPEM = `-----BEGIN CERTIFICATE-----
+MIIBwjCCAWegAwIBAgIBATAKBggqgRzPVQGDdTBIMQswCQYDVQQGEwJDTjEdMBsG
+A1UEChMUTGVnYWN5IFByb3ZpbmNpYWwgQ0ExGjAYBgNVBAMTEUxlZ2FjeSBTTTIg
+Q1JMIENBMB4XDTIwMDEwMTAwMDAwMFoXDTQwMDEwMTAwMDAwMFowSDELMAkGA1UE
+BhMCQ04xHTAbBgNVBAoTFExlZ2FjeSBQcm92aW5jaWFsIENBMRowGAYDVQQDExFM
+ZWdhY3kgU00yIENSTCBDQTBZMBMGByqGSM49AgEGCCqBHM9VAYItA0IABPtjpO8L
+7gB18C7OHw7Mx/P2guOHpXDo58COiY7J1oyWkYghT1Cr39fhz2GnlnRK2mwWW9D6
+ZemW0zP/V6SHrxSjQjBAMA4GA1UdDwEB/wQEAwIBBjAPBgNVHRMBAf8EBTADAQH/
+MB0GA1UdDgQWBBTFcvO0tCxMiS9O/f5diKwFCFgKmTAKBggqgRzPVQGDdQNJADBG
+AiEAuM/d6meISs2iWFJeLbGfIy/QJtWpYKgpx9TxRWHVoAQCIQDbhJaxdvDWLBcS
+8mRHhfia8M2FpxQIXYU5NtAr9nCCFg==
+-----END CERTIFICATE-----`
+
+const legacyCRLPEM = `-----BEGIN X509 CRL-----
+MIIBDjCBtAIBATAMBggqgRzPVQGDdQUAMEgxCzAJBgNVBAYTAkNOMR0wGwYDVQQK
+ExRMZWdhY3kgUHJvdmluY2lhbCBDQTEaMBgGA1UEAxMRTGVnYWN5IFNNMiBDUkwg
+Q0EXCzI0MDUwMTEyMDBaMDowEwICEAEXDTI0MDQxMDA4MDAwMFowIwICEAIYDzIw
+MjQwNDE1MDgzMDAwWjAMMAoGA1UdFQQDCgEBoA4wDDAKBgNVHRQEAwIBBzAMBggq
+gRzPVQGDdQUAA0cAMEQCIEUARa5aXXqKvVVp60PzWKKX7GYI37sFnYjSUuWByMGT
+AiBE8OuDennXagX8Y/rKRenvNjz/5xLoszb6qR9rFsrrlA==
+-----END X509 CRL-----`
+
+// legacyCRLQuirks selects the quirks of a CRL built by newLegacyCRL.
+type legacyCRLQuirks struct {
+	nullParams       bool
+	noSeconds        bool
+	noNextUpdate     bool
+	generalizedEntry bool
+}
+
+// newLegacyCRL encodes and signs a CRL of issuer with the given quirks, which
+// CreateRevocationList can not produce.
+func newLegacyCRL(t *testing.T, issuer *Certificate, priv crypto.Signer, quirks legacyCRLQuirks) []byte {
+	t.Helper()
+	addTime := func(b *cryptobyte.Builder, tag cryptobyte_asn1.Tag, s string) {
+		b.AddASN1(tag, func(b *cryptobyte.Builder) { b.AddBytes([]byte(s)) })
+	}
+	addAI := func(b *cryptobyte.Builder) {
+		b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			b.AddASN1ObjectIdentifier(oidSignatureSM2WithSM3)
+			if quirks.nullParams {
+				b.AddASN1NULL()
+			}
+		})
+	}
+	var tbs cryptobyte.Builder
+	tbs.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1Int64(x509v2Version)
+		addAI(b)
+		b.AddBytes(issuer.RawSubject)
+		if quirks.noSeconds {
+			addTime(b, cryptobyte_asn1.UTCTime, "2405011200Z")
+		} else {
+			addTime(b, cryptobyte_asn1.UTCTime, "240501120000Z")
+		}
+		if !quirks.noNextUpdate {
+			addTime(b, cryptobyte_asn1.UTCTime, "240601120000Z")
+		}
+		b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+				b.AddASN1Int64(0x1001)
+				addTime(b, cryptobyte_asn1.UTCTime, "240410080000Z")
+			})
+			b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+				b.AddASN1Int64(0x1002)
+				if quirks.generalizedEntry {
+					addTime(b, cryptobyte_asn1.GeneralizedTime, "20240415083000Z")
+				} else {
+					addTime(b, cryptobyte_asn1.UTCTime, "240415083000Z")
+				}
+			})
+		})
+	})
+	tbsDER := tbs.BytesOrPanic()
+	signature, err := signTBS(tbsDER, priv, SM2WithSM3, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var crl cryptobyte.Builder
+	crl.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddBytes(tbsDER)
+		addAI(b)
+		b.AddASN1BitString(signature)
+	})
+	return crl.BytesOrPanic()
+}
+
+func TestParseLegacyRevocationList(t *testing.T) {
+	block, _ := pem.Decode([]byte(legacyCRLIssuerCertPEM))
+	issuer, err := ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ = pem.Decode([]byte(legacyCRLPEM))
+	rl, err := ParseRevocationList(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rl.SignatureAlgorithm != SM2WithSM3 {
+		t.Errorf("unexpected signature algorithm %v", rl.SignatureAlgorithm)
+	}
+	if err := rl.CheckSignatureFrom(issuer); err != nil {
+		t.Errorf("signature check failed: %v", err)
+	}
+	if want := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC); !rl.ThisUpdate.Equal(want) {
+		t.Errorf("got ThisUpdate %v, want %v", rl.ThisUpdate, want)
+	}
+	if !rl.NextUpdate.IsZero() {
+		t.Errorf("got NextUpdate %v, want the zero time", rl.NextUpdate)
+	}
+	if rl.Number == nil || rl.Number.Int64() != 7 {
+		t.Errorf("unexpected number %v", rl.Number)
+	}
+	wantTimes := []time.Time{
+		time.Date(2024, 4, 10, 8, 0, 0, 0, time.UTC),
+		time.Date(2024, 4, 15, 8, 30, 0, 0, time.UTC),
+	}
+	if len(rl.RevokedCertificateEntries) != len(wantTimes) {
+		t.Fatalf("got %d entries, want %d", len(rl.RevokedCertificateEntries), len(wantTimes))
+	}
+	for i, rce := range rl.RevokedCertificateEntries {
+		if !rce.RevocationTime.Equal(wantTimes[i]) {
+			t.Errorf("entry %d: got revocation time %v, want %v", i, rce.RevocationTime, wantTimes[i])
+		}
+	}
+	if rl.RevokedCertificateEntries[1].ReasonCode != 1 {
+		t.Errorf("unexpected reason code %d", rl.RevokedCertificateEntries[1].ReasonCode)
+	}
+	if _, err := ParseRevocationListWithOptions(block.Bytes, ParseRevocationListOptions{Strict: true}); err == nil {
+		t.Error("legacy CRL parsed in strict mode")
+	}
+
+	// each quirk alone
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer = newTestIssuer(t, priv, pkix.Name{CommonName: "GM CRL CA"})
+	tests := []struct {
+		name   string
+		quirks legacyCRLQuirks
+		err    string
+	}{
+		{"conformant", legacyCRLQuirks{}, ""},
+		{"NULL parameters", legacyCRLQuirks{nullParams: true}, "with parameters"},
+		{"UTCTime without seconds", legacyCRLQuirks{noSeconds: true}, "malformed UTCTime"},
+		{"no NextUpdate", legacyCRLQuirks{noNextUpdate: true}, "no next update"},
+		{"GeneralizedTime entry", legacyCRLQuirks{generalizedEntry: true}, "GeneralizedTime used for a date before 2050"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			der := newLegacyCRL(t, issuer, priv, test.quirks)
+			rl, err := ParseRevocationList(der)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := rl.CheckSignatureFrom(issuer); err != nil {
+				t.Errorf("signature check failed: %v", err)
+			}
+			if rl.NextUpdate.IsZero() != test.quirks.noNextUpdate {
+				t.Errorf("unexpected NextUpdate %v", rl.NextUpdate)
+			}
+			_, err = ParseRevocationListWithOptions(der, ParseRevocationListOptions{Strict: true})
+			if test.err == "" && err != nil {
+				t.Errorf("conformant CRL rejected in strict mode: %v", err)
+			}
+			if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+				t.Errorf("got error %v in strict mode, want %q", err, test.err)
+			}
+			// the streaming parser is as lenient as ParseRevocationList
+			if _, entries, err := scanCRL(t, der, &CRLScannerOptions{Issuer: issuer}); err != nil || len(entries) != 2 {
+				t.Errorf("unexpected error or entries %d: %v", len(entries), err)
+			}
+		})
+	}
+}
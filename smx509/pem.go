@@ -0,0 +1,171 @@
+package smx509
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+
+	"github.com/yunmoon/gmsm/pkcs"
+)
+
+// ErrPasswordRequired is returned when an encrypted private key is found in
+// the PEM data but no password is supplied.
+var ErrPasswordRequired = errors.New("x509: password required to decrypt the private key")
+
+type pemKind int
+
+const (
+	pemKindPrivateKey pemKind = 1 << iota
+	pemKindPublicKey
+	pemKindCertificate
+)
+
+// ParsePEM parses the first private key, public key or certificate in the
+// PEM encoded data, skipping blocks of other types.
+//
+// The supported block types are "PRIVATE KEY", "ENCRYPTED PRIVATE KEY",
+// "EC PRIVATE KEY", "SM2 PRIVATE KEY", "RSA PRIVATE KEY", "PUBLIC KEY",
+// "RSA PUBLIC KEY" and "CERTIFICATE". Private keys are returned as by
+// ParsePKCS8PrivateKey, public keys as by ParsePKIXPublicKey and certificates
+// as *Certificate.
+//
+// Password is used to decrypt encrypted PKCS#8 private keys and legacy RFC 1423
+// encrypted blocks. If such a key is found and password is empty,
+// ErrPasswordRequired is returned.
+func ParsePEM(data, password []byte) (any, error) {
+	return parsePEM(data, password, pemKindPrivateKey|pemKindPublicKey|pemKindCertificate)
+}
+
+// ParsePrivateKeyPEM parses the first private key in the PEM encoded data,
+// skipping blocks of other types. See ParsePEM for the supported block types
+// and the use of password.
+func ParsePrivateKeyPEM(data, password []byte) (any, error) {
+	return parsePEM(data, password, pemKindPrivateKey)
+}
+
+// ParsePublicKeyPEM parses the first public key in the PEM encoded data,
+// skipping blocks of other types. The public key of a "CERTIFICATE" block
+// is returned as well.
+func ParsePublicKeyPEM(data []byte) (any, error) {
+	key, err := parsePEM(data, nil, pemKindPublicKey|pemKindCertificate)
+	if err != nil {
+		return nil, err
+	}
+	if cert, ok := key.(*Certificate); ok {
+		return cert.PublicKey, nil
+	}
+	return key, nil
+}
+
+func parsePEM(data, password []byte, kinds pemKind) (any, error) {
+	var block *pem.Block
+	decoded := false
+	for {
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		decoded = true
+		switch block.Type {
+		case "PRIVATE KEY", "ENCRYPTED PRIVATE KEY", "EC PRIVATE KEY", "SM2 PRIVATE KEY", "RSA PRIVATE KEY":
+			if kinds&pemKindPrivateKey != 0 {
+				return parsePrivateKeyBlock(block, password)
+			}
+		case "PUBLIC KEY":
+			if kinds&pemKindPublicKey != 0 {
+				return ParsePKIXPublicKey(block.Bytes)
+			}
+		case "RSA PUBLIC KEY":
+			if kinds&pemKindPublicKey != 0 {
+				return ParsePKCS1PublicKey(block.Bytes)
+			}
+		case "CERTIFICATE":
+			if kinds&pemKindCertificate != 0 {
+				return ParseCertificate(block.Bytes)
+			}
+		}
+	}
+	if !decoded {
+		return nil, errors.New("x509: failed to decode PEM data")
+	}
+	switch kinds {
+	case pemKindPrivateKey:
+		return nil, errors.New("x509: no private key found in PEM data")
+	case pemKindPublicKey | pemKindCertificate:
+		return nil, errors.New("x509: no public key or certificate found in PEM data")
+	default:
+		return nil, errors.New("x509: no key or certificate found in PEM data")
+	}
+}
+
+func parsePrivateKeyBlock(block *pem.Block, password []byte) (any, error) {
+	der := block.Bytes
+	if IsEncryptedPEMBlock(block) {
+		if len(password) == 0 {
+			return nil, ErrPasswordRequired
+		}
+		var err error
+		if der, err = DecryptPEMBlock(block, password); err != nil {
+			return nil, err
+		}
+	}
+	switch block.Type {
+	case "ENCRYPTED PRIVATE KEY":
+		if len(password) == 0 {
+			return nil, ErrPasswordRequired
+		}
+		return parseEncryptedPKCS8PrivateKey(der, password)
+	case "PRIVATE KEY":
+		return ParsePKCS8PrivateKey(der)
+	case "RSA PRIVATE KEY":
+		return ParsePKCS1PrivateKey(der)
+	case "SM2 PRIVATE KEY":
+		return ParseSM2PrivateKey(der)
+	default:
+		return ParseTypedECPrivateKey(der)
+	}
+}
+
+// parseEncryptedPKCS8PrivateKey decrypts and parses a PKCS#8 EncryptedPrivateKeyInfo.
+// It returns pkcs.ErrPBEDecryption if the password is wrong.
+func parseEncryptedPKCS8PrivateKey(der, password []byte) (any, error) {
+	var encrypted struct {
+		EncryptionAlgorithm pkix.AlgorithmIdentifier
+		EncryptedData       []byte
+	}
+	if rest, err := asn1.Unmarshal(der, &encrypted); err != nil || len(rest) > 0 {
+		return nil, errors.New("x509: malformed encrypted private key")
+	}
+
+	var decrypted []byte
+	var err error
+	switch {
+	case pkcs.IsPBES2(encrypted.EncryptionAlgorithm) || pkcs.IsSMPBES(encrypted.EncryptionAlgorithm):
+		var params pkcs.PBES2Params
+		if _, err := asn1.Unmarshal(encrypted.EncryptionAlgorithm.Parameters.FullBytes, &params); err != nil {
+			return nil, errors.New("x509: invalid PBES2 parameters")
+		}
+		decrypted, _, err = params.Decrypt(password, encrypted.EncryptedData)
+	case pkcs.IsPBES1(encrypted.EncryptionAlgorithm):
+		pbes1 := &pkcs.PBES1{Algorithm: encrypted.EncryptionAlgorithm}
+		decrypted, _, err = pbes1.Decrypt(password, encrypted.EncryptedData)
+	default:
+		return nil, errors.New("x509: unsupported private key encryption algorithm")
+	}
+	if err != nil {
+		return nil, err
+	}
+	key, err := ParsePKCS8PrivateKey(decrypted)
+	if err != nil {
+		// the padding check may pass by chance with a wrong password, then
+		// the decrypted data is not a PKCS#8 structure
+		var structuralErr asn1.StructuralError
+		var syntaxErr asn1.SyntaxError
+		if errors.As(err, &structuralErr) || errors.As(err, &syntaxErr) {
+			return nil, pkcs.ErrPBEDecryption
+		}
+		return nil, err
+	}
+	return key, nil
+}
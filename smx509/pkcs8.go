@@ -257,7 +257,21 @@ func marshalPKCS8SM9EncMasterPrivateKey(k *sm9.EncryptMasterPrivateKey) ([]byte,
 	return asn1.Marshal(privKey)
 }
 
+// MarshalPKCS8SM2PrivateKey converts a SM2 private key to PKCS #8, ASN.1 DER form,
+// with the SM2 algorithm OID 1.2.156.10197.1.301 (GM/T 0015) instead of id-ecPublicKey
+// as the private key algorithm, which is required by some GM middlewares and HSMs.
+// This is also the encoding used by OpenSSL 3.
+//
+// [ParsePKCS8PrivateKey] accepts both encodings.
+func MarshalPKCS8SM2PrivateKey(key *sm2.PrivateKey) ([]byte, error) {
+	return marshalPKCS8ECPrivateKeyWithAlgorithm(&key.PrivateKey, oidPublicKeySM2)
+}
+
 func marshalPKCS8ECPrivateKey(k *ecdsa.PrivateKey) ([]byte, error) {
+	return marshalPKCS8ECPrivateKeyWithAlgorithm(k, oidPublicKeyECDSA)
+}
+
+func marshalPKCS8ECPrivateKeyWithAlgorithm(k *ecdsa.PrivateKey, algorithm asn1.ObjectIdentifier) ([]byte, error) {
 	var privKey pkcs8
 	oid, ok := oidFromNamedCurve(k.Curve)
 	if !ok {
@@ -270,7 +284,7 @@ func marshalPKCS8ECPrivateKey(k *ecdsa.PrivateKey) ([]byte, error) {
 	}
 
 	privKey.Algo = pkix.AlgorithmIdentifier{
-		Algorithm: oidPublicKeyECDSA,
+		Algorithm: algorithm,
 		Parameters: asn1.RawValue{
 			FullBytes: oidBytes,
 		},
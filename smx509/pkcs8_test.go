@@ -8,6 +8,7 @@ import (
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"encoding/asn1"
 	"encoding/hex"
 	"fmt"
 	"reflect"
@@ -198,6 +199,64 @@ func TestMarshalPKCS8SM2PrivateKey(t *testing.T) {
 	fmt.Printf("%s\n", hex.EncodeToString(res))
 }
 
+func TestMarshalPKCS8SM2PrivateKeyWithSM2OID(t *testing.T) {
+	// the OpenSSL 3 generated key uses the SM2 algorithm OID
+	der, _ := hex.DecodeString(pkcs8SM2PrivateKeyHex)
+	key, err := ParsePKCS8PrivateKey(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv, ok := key.(*sm2.PrivateKey)
+	if !ok {
+		t.Fatalf("expected *sm2.PrivateKey, got %T", key)
+	}
+	res, err := MarshalPKCS8SM2PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(res, der) {
+		t.Errorf("expected %x, got %x", der, res)
+	}
+
+	priv, err = sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecOIDEncoding, err := MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm2OIDEncoding, err := MarshalPKCS8SM2PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(ecOIDEncoding, sm2OIDEncoding) {
+		t.Fatal("expected different encodings")
+	}
+	msg := []byte("SM2 signature with SM3")
+	for _, der := range [][]byte{ecOIDEncoding, sm2OIDEncoding} {
+		var privKey pkcs8
+		if _, err := asn1.Unmarshal(der, &privKey); err != nil {
+			t.Fatal(err)
+		}
+		key, err := ParsePKCS8PrivateKey(der)
+		if err != nil {
+			t.Fatalf("%v: %v", privKey.Algo.Algorithm, err)
+		}
+		parsed, ok := key.(*sm2.PrivateKey)
+		if !ok || !priv.Equal(parsed) {
+			t.Fatalf("%v: parsed key does not match", privKey.Algo.Algorithm)
+		}
+		sig, err := parsed.SignWithSM2(rand.Reader, nil, msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sm2.VerifyASN1WithSM2(&priv.PublicKey, nil, msg, sig) {
+			t.Errorf("%v: failed to verify signature", privKey.Algo.Algorithm)
+		}
+	}
+}
+
 func TestMarshalPKCS8SM9SignPrivateKey(t *testing.T) {
 	masterKey, err := sm9.GenerateSignMasterKey(rand.Reader)
 	if err != nil {
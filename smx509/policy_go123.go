@@ -0,0 +1,27 @@
+//go:build !go1.24
+
+package smx509
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+)
+
+// NoValidChains results when there are no valid chains to return, for example
+// when none of the candidate chains satisfies the certificate policies.
+// Before Go 1.24, CertificateInvalidError reports it as an unknown error.
+const NoValidChains = x509.CANotAuthorizedForExtKeyUsage + 1
+
+// The Certificate fields for the policy mappings, policy constraints and
+// inhibitAnyPolicy extensions were added in Go 1.24, before that they are
+// only available in Extensions, and can only be created with ExtraExtensions.
+
+func marshalPolicyExtensions(template *x509.Certificate) ([]pkix.Extension, error) {
+	return nil, nil
+}
+
+func setPolicyMappings(out *Certificate, mappings []policyMapping) {}
+
+func setPolicyConstraints(out *Certificate, requireExplicitPolicy, inhibitPolicyMapping int) {}
+
+func setInhibitAnyPolicy(out *Certificate, inhibitAnyPolicy int) {}
@@ -0,0 +1,107 @@
+//go:build go1.24
+
+package smx509
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+
+	"golang.org/x/crypto/cryptobyte"
+	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+// NoValidChains results when there are no valid chains to return, for example
+// when none of the candidate chains satisfies the certificate policies.
+const NoValidChains = x509.NoValidChains
+
+func setPolicyMappings(out *Certificate, mappings []policyMapping) {
+	for _, m := range mappings {
+		out.PolicyMappings = append(out.PolicyMappings, x509.PolicyMapping{
+			IssuerDomainPolicy:  m.issuerDomainPolicy,
+			SubjectDomainPolicy: m.subjectDomainPolicy,
+		})
+	}
+}
+
+func setPolicyConstraints(out *Certificate, requireExplicitPolicy, inhibitPolicyMapping int) {
+	if requireExplicitPolicy >= 0 {
+		out.RequireExplicitPolicy = requireExplicitPolicy
+		out.RequireExplicitPolicyZero = requireExplicitPolicy == 0
+	}
+	if inhibitPolicyMapping >= 0 {
+		out.InhibitPolicyMapping = inhibitPolicyMapping
+		out.InhibitPolicyMappingZero = inhibitPolicyMapping == 0
+	}
+}
+
+func setInhibitAnyPolicy(out *Certificate, inhibitAnyPolicy int) {
+	if inhibitAnyPolicy >= 0 {
+		out.InhibitAnyPolicy = inhibitAnyPolicy
+		out.InhibitAnyPolicyZero = inhibitAnyPolicy == 0
+	}
+}
+
+// marshalPolicyExtensions returns the policyMappings, policyConstraints and
+// inhibitAnyPolicy extensions of template, unless they are in ExtraExtensions.
+// As required by RFC 5280, they are all marked critical.
+func marshalPolicyExtensions(template *x509.Certificate) ([]pkix.Extension, error) {
+	var ret []pkix.Extension
+
+	if len(template.PolicyMappings) > 0 && !oidInExtensions(oidExtensionPolicyMappings, template.ExtraExtensions) {
+		var b cryptobyte.Builder
+		b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			for _, m := range template.PolicyMappings {
+				b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+					issuer, subject := m.IssuerDomainPolicy, m.SubjectDomainPolicy
+					if len(getDer(&issuer)) == 0 || len(getDer(&subject)) == 0 {
+						b.SetError(errors.New("x509: invalid policy mapping"))
+						return
+					}
+					b.AddASN1(cryptobyte_asn1.OBJECT_IDENTIFIER, func(b *cryptobyte.Builder) {
+						b.AddBytes(getDer(&issuer))
+					})
+					b.AddASN1(cryptobyte_asn1.OBJECT_IDENTIFIER, func(b *cryptobyte.Builder) {
+						b.AddBytes(getDer(&subject))
+					})
+				})
+			}
+		})
+		value, err := b.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, pkix.Extension{Id: oidExtensionPolicyMappings, Critical: true, Value: value})
+	}
+
+	requireExplicitPolicy := template.RequireExplicitPolicy > 0 || template.RequireExplicitPolicyZero
+	inhibitPolicyMapping := template.InhibitPolicyMapping > 0 || template.InhibitPolicyMappingZero
+	if (requireExplicitPolicy || inhibitPolicyMapping) && !oidInExtensions(oidExtensionPolicyConstraints, template.ExtraExtensions) {
+		var b cryptobyte.Builder
+		b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			if requireExplicitPolicy {
+				b.AddASN1Int64WithTag(int64(template.RequireExplicitPolicy), cryptobyte_asn1.Tag(0).ContextSpecific())
+			}
+			if inhibitPolicyMapping {
+				b.AddASN1Int64WithTag(int64(template.InhibitPolicyMapping), cryptobyte_asn1.Tag(1).ContextSpecific())
+			}
+		})
+		value, err := b.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, pkix.Extension{Id: oidExtensionPolicyConstraints, Critical: true, Value: value})
+	}
+
+	if (template.InhibitAnyPolicy > 0 || template.InhibitAnyPolicyZero) && !oidInExtensions(oidExtensionInhibitAnyPolicy, template.ExtraExtensions) {
+		var b cryptobyte.Builder
+		b.AddASN1Int64(int64(template.InhibitAnyPolicy))
+		value, err := b.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, pkix.Extension{Id: oidExtensionInhibitAnyPolicy, Critical: true, Value: value})
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,147 @@
+//go:build go1.24
+
+package smx509
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"testing"
+
+	"github.com/yunmoon/gmsm/sm2"
+)
+
+func TestParsePolicyFields(t *testing.T) {
+	oid1 := mustNewOIDFromInts([]uint64{1, 2, 156, 9999, 1})
+	oid2 := mustNewOIDFromInts([]uint64{1, 2, 156, 9999, 2})
+	key, _ := sm2.GenerateKey(rand.Reader)
+	cert := genCertEdge(t, "policy fields", key, func(c *Certificate) {
+		mappings, _ := asn1.Marshal([]struct{ Issuer, Subject asn1.ObjectIdentifier }{{
+			asn1.ObjectIdentifier{1, 2, 156, 9999, 1}, asn1.ObjectIdentifier{1, 2, 156, 9999, 2},
+		}})
+		inhibitAny, _ := asn1.Marshal(2)
+		c.ExtraExtensions = append(c.ExtraExtensions,
+			pkix.Extension{Id: asn1.ObjectIdentifier{2, 5, 29, 33}, Critical: true, Value: mappings},
+			// requireExplicitPolicy [0] 0, inhibitPolicyMapping [1] 1
+			pkix.Extension{Id: asn1.ObjectIdentifier{2, 5, 29, 36}, Critical: true, Value: []byte{0x30, 0x06, 0x80, 0x01, 0x00, 0x81, 0x01, 0x01}},
+			pkix.Extension{Id: asn1.ObjectIdentifier{2, 5, 29, 54}, Value: inhibitAny},
+		)
+	}, rootCertificate, nil, nil)
+
+	if len(cert.UnhandledCriticalExtensions) != 0 {
+		t.Errorf("unexpected unhandled critical extensions %v", cert.UnhandledCriticalExtensions)
+	}
+	want := x509.PolicyMapping{IssuerDomainPolicy: oid1, SubjectDomainPolicy: oid2}
+	if len(cert.PolicyMappings) != 1 || !cert.PolicyMappings[0].IssuerDomainPolicy.Equal(want.IssuerDomainPolicy) ||
+		!cert.PolicyMappings[0].SubjectDomainPolicy.Equal(want.SubjectDomainPolicy) {
+		t.Errorf("unexpected PolicyMappings %v", cert.PolicyMappings)
+	}
+	if cert.RequireExplicitPolicy != 0 || !cert.RequireExplicitPolicyZero {
+		t.Errorf("unexpected RequireExplicitPolicy %d, %v", cert.RequireExplicitPolicy, cert.RequireExplicitPolicyZero)
+	}
+	if cert.InhibitPolicyMapping != 1 || cert.InhibitPolicyMappingZero {
+		t.Errorf("unexpected InhibitPolicyMapping %d, %v", cert.InhibitPolicyMapping, cert.InhibitPolicyMappingZero)
+	}
+	if cert.InhibitAnyPolicy != 2 || cert.InhibitAnyPolicyZero {
+		t.Errorf("unexpected InhibitAnyPolicy %d, %v", cert.InhibitAnyPolicy, cert.InhibitAnyPolicyZero)
+	}
+}
+
+// Generated with OpenSSL 3.0 from an SM2 key, with the extensions:
+//
+//	certificatePolicies = 1.2.156.9999.1,1.2.156.9999.2
+//	policyMappings = critical,1.2.156.9999.1:1.2.156.9999.3,1.2.156.9999.2:1.2.156.9999.4
+//	policyConstraints = critical,requireExplicitPolicy:0,inhibitPolicyMapping:1
+//	inhibitAnyPolicy = critical,2
+const opensslPolicyCAPEM = `-----BEGIN CERTIFICATE-----
+MIIB2TCCAX+gAwIBAgIBATAKBggqgRzPVQGDdTAYMRYwFAYDVQQDDA1TTTIgUG9s
+aWN5IENBMCAXDTI2MTAxNjEzMzc0MFoYDzIxMjYwOTIyMTMzNzQwWjAYMRYwFAYD
+VQQDDA1TTTIgUG9saWN5IENBMFkwEwYHKoZIzj0CAQYIKoEcz1UBgi0DQgAETcd9
+WtmUvmdKgRF+msYCobrcXi1cmdQDMfR2bgv+1M+IWmTSdjmBfEgSNAvK6jCR+787
+gDOM/xq/hq2xXo0vpKOBtzCBtDAPBgNVHRMBAf8EBTADAQH/MA4GA1UdDwEB/wQE
+AwIBBjAdBgNVHSAEFjAUMAgGBiqBHM4PATAIBgYqgRzODwIwMAYDVR0hAQH/BCYw
+JDAQBgYqgRzODwEGBiqBHM4PAzAQBgYqgRzODwIGBiqBHM4PBDASBgNVHSQBAf8E
+CDAGgAEAgQEBMA0GA1UdNgEB/wQDAgECMB0GA1UdDgQWBBR+Lez9T8IqSSQ8Gc8d
+gG+RwWuDUDAKBggqgRzPVQGDdQNIADBFAiAN9pJAEGpGz89W1AS3ogmG3QHcLY2/
+zNaPhv3YNfOwhwIhAJDq+fiA8znKiXSrMr5MsWKMDia5UmnjUqZKRYkWfxC6
+-----END CERTIFICATE-----
+`
+
+func TestMarshalPolicyExtensions(t *testing.T) {
+	reference, err := ParseCertificatePEM([]byte(opensslPolicyCAPEM))
+	if err != nil {
+		t.Fatal(err)
+	}
+	oid := func(n uint64) x509.OID { return mustNewOIDFromInts([]uint64{1, 2, 156, 9999, n}) }
+	wantMappings := []x509.PolicyMapping{
+		{IssuerDomainPolicy: oid(1), SubjectDomainPolicy: oid(3)},
+		{IssuerDomainPolicy: oid(2), SubjectDomainPolicy: oid(4)},
+	}
+	check := func(name string, cert *Certificate) {
+		t.Helper()
+		if len(cert.PolicyMappings) != len(wantMappings) {
+			t.Fatalf("%s: unexpected PolicyMappings %v", name, cert.PolicyMappings)
+		}
+		for i, m := range cert.PolicyMappings {
+			if !m.IssuerDomainPolicy.Equal(wantMappings[i].IssuerDomainPolicy) || !m.SubjectDomainPolicy.Equal(wantMappings[i].SubjectDomainPolicy) {
+				t.Errorf("%s: unexpected policy mapping %v", name, m)
+			}
+		}
+		if cert.RequireExplicitPolicy != 0 || !cert.RequireExplicitPolicyZero ||
+			cert.InhibitPolicyMapping != 1 || cert.InhibitPolicyMappingZero ||
+			cert.InhibitAnyPolicy != 2 || cert.InhibitAnyPolicyZero {
+			t.Errorf("%s: unexpected policy constraints %+v", name, cert)
+		}
+	}
+	check("OpenSSL", reference)
+
+	key, _ := sm2.GenerateKey(rand.Reader)
+	cert := genCertEdge(t, "SM2 Policy CA", key, func(c *Certificate) {
+		c.Policies = []x509.OID{oid(1), oid(2)}
+		c.PolicyMappings = wantMappings
+		c.RequireExplicitPolicyZero = true
+		c.InhibitPolicyMapping = 1
+		c.InhibitAnyPolicy = 2
+	}, rootCertificate, nil, nil)
+	check("CreateCertificate", cert)
+
+	// the DER and criticality match the OpenSSL encoding
+	for _, id := range []asn1.ObjectIdentifier{oidExtensionPolicyMappings, oidExtensionPolicyConstraints, oidExtensionInhibitAnyPolicy} {
+		var got, want *pkix.Extension
+		for i := range cert.Extensions {
+			if cert.Extensions[i].Id.Equal(id) {
+				got = &cert.Extensions[i]
+			}
+		}
+		for i := range reference.Extensions {
+			if reference.Extensions[i].Id.Equal(id) {
+				want = &reference.Extensions[i]
+			}
+		}
+		if got == nil || want == nil {
+			t.Fatalf("extension %v missing", id)
+		}
+		if got.Critical != want.Critical || !bytes.Equal(got.Value, want.Value) {
+			t.Errorf("extension %v = %v %x, want %v %x", id, got.Critical, got.Value, want.Critical, want.Value)
+		}
+	}
+
+	// explicit zero values, and ExtraExtensions take precedence
+	cert = genCertEdge(t, "zero", key, func(c *Certificate) {
+		c.InhibitPolicyMappingZero = true
+		c.InhibitAnyPolicyZero = true
+		c.ExtraExtensions = []pkix.Extension{{Id: oidExtensionPolicyMappings, Value: reference.Extensions[3].Value}}
+		c.PolicyMappings = wantMappings[:1]
+	}, rootCertificate, nil, nil)
+	if cert.InhibitPolicyMapping != 0 || !cert.InhibitPolicyMappingZero || cert.InhibitAnyPolicy != 0 || !cert.InhibitAnyPolicyZero {
+		t.Errorf("unexpected zero values %+v", cert)
+	}
+	if cert.RequireExplicitPolicy != 0 || cert.RequireExplicitPolicyZero {
+		t.Error("unexpected requireExplicitPolicy")
+	}
+	if len(cert.PolicyMappings) != 2 {
+		t.Errorf("ExtraExtensions should take precedence, got %v", cert.PolicyMappings)
+	}
+}
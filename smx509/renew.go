@@ -0,0 +1,54 @@
+package smx509
+
+import (
+	"crypto"
+	"errors"
+	"io"
+	"time"
+)
+
+// RenewCertificate issues a new certificate for the subject of old, valid from
+// notBefore to notAfter, with a new random serial number, signed by priv, the
+// private key of parent. pub is the public key of the new certificate, nil for
+// the key of old.
+//
+// The extensions of old, such as the subject alternative names, key usages,
+// policies, name constraints and custom extensions, are copied as they are,
+// criticality included. The subject and authority key identifiers are
+// regenerated, for the public key and parent, and the embedded SCT list and
+// precertificate poison, which are bound to old, are left out. The signature
+// algorithm is the default one for priv.
+func RenewCertificate(rand io.Reader, old *Certificate, parent *Certificate, pub any, priv crypto.Signer, notBefore, notAfter time.Time) ([]byte, error) {
+	if old == nil || parent == nil {
+		return nil, errors.New("x509: certificate and parent can not be nil")
+	}
+
+	template := *old.asX509()
+	template.SerialNumber = nil
+	template.NotBefore, template.NotAfter = notBefore, notAfter
+	template.SignatureAlgorithm = UnknownSignatureAlgorithm
+	template.SubjectKeyId = nil
+	template.AuthorityKeyId = nil
+	template.ExtraExtensions = nil
+	hasSubjectKeyId := false
+	for _, e := range old.Extensions {
+		switch {
+		case e.Id.Equal(oidExtensionSubjectKeyId):
+			hasSubjectKeyId = true
+		case e.Id.Equal(oidExtensionAuthorityKeyId),
+			e.Id.Equal(oidExtensionSCTList),
+			e.Id.Equal(oidExtensionCTPoison):
+		default:
+			template.ExtraExtensions = append(template.ExtraExtensions, e)
+		}
+	}
+
+	if pub == nil {
+		var err error
+		if pub, err = reissuePublicKey(old); err != nil {
+			return nil, err
+		}
+	}
+	opts := &CreateCertificateOptions{GenerateSubjectKeyId: hasSubjectKeyId}
+	return CreateCertificateWithOptions(rand, &template, parent, pub, priv, opts)
+}
@@ -0,0 +1,171 @@
+package smx509
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yunmoon/gmsm/sm2"
+)
+
+func TestRenewCertificate(t *testing.T) {
+	rootKey, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "SM2 Root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(48 * time.Hour),
+		KeyUsage:              KeyUsageCertSign | KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	rootDER, err := CreateCertificate(rand.Reader, rootTemplate, rootTemplate, rootKey.Public(), rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := ParseCertificate(rootDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldKey, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	criticalExt := pkix.Extension{Id: asn1.ObjectIdentifier{1, 2, 3, 4, 5}, Critical: true, Value: []byte{0x04, 0x01, 0x2a}}
+	customExt := pkix.Extension{Id: asn1.ObjectIdentifier{1, 2, 3, 4, 6}, Value: []byte{0x05, 0x00}}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: "SM2 Intermediate", Organization: []string{"GM"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              KeyUsageCertSign | KeyUsageDigitalSignature,
+		ExtKeyUsage:           []ExtKeyUsage{ExtKeyUsageServerAuth, ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLen:            0,
+		MaxPathLenZero:        true,
+		DNSNames:              []string{"ca.example.com"},
+		EmailAddresses:        []string{"ca@example.com"},
+		IPAddresses:           []net.IP{net.IPv4(192, 0, 2, 1).To4()},
+		PolicyIdentifiers:     []asn1.ObjectIdentifier{{1, 2, 156, 112559, 1, 1}},
+		PermittedDNSDomains:   []string{".example.com"},
+		ExcludedDNSDomains:    []string{".example.org"},
+		ExtraExtensions:       []pkix.Extension{criticalExt, customExt},
+	}
+	oldDER, err := CreateCertificate(rand.Reader, template, root, oldKey.Public(), rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := ParseCertificate(oldDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(old.UnhandledCriticalExtensions) != 1 {
+		t.Fatalf("unexpected unhandled critical extensions %v", old.UnhandledCriticalExtensions)
+	}
+
+	notBefore := time.Now().Add(-time.Minute).Truncate(time.Second)
+	notAfter := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	checkRenewed := func(der []byte, parent *Certificate) *Certificate {
+		t.Helper()
+		renewed, err := ParseCertificate(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := renewed.CheckSignatureFrom(parent); err != nil {
+			t.Fatal(err)
+		}
+		if renewed.SerialNumber.Cmp(old.SerialNumber) == 0 {
+			t.Error("the serial number is not renewed")
+		}
+		if !renewed.NotBefore.Equal(notBefore) || !renewed.NotAfter.Equal(notAfter) {
+			t.Errorf("unexpected validity %v - %v", renewed.NotBefore, renewed.NotAfter)
+		}
+		if !bytes.Equal(renewed.RawSubject, old.RawSubject) || !bytes.Equal(renewed.RawIssuer, parent.RawSubject) {
+			t.Error("unexpected subject or issuer")
+		}
+		if renewed.KeyUsage != old.KeyUsage || !reflect.DeepEqual(renewed.ExtKeyUsage, old.ExtKeyUsage) ||
+			renewed.MaxPathLen != 0 || !renewed.MaxPathLenZero ||
+			!reflect.DeepEqual(renewed.DNSNames, old.DNSNames) || !reflect.DeepEqual(renewed.EmailAddresses, old.EmailAddresses) ||
+			!reflect.DeepEqual(renewed.IPAddresses, old.IPAddresses) || !reflect.DeepEqual(renewed.PolicyIdentifiers, old.PolicyIdentifiers) ||
+			!reflect.DeepEqual(renewed.PermittedDNSDomains, old.PermittedDNSDomains) || !reflect.DeepEqual(renewed.ExcludedDNSDomains, old.ExcludedDNSDomains) {
+			t.Error("the renewed certificate has other fields than the original")
+		}
+		for _, want := range []pkix.Extension{criticalExt, customExt} {
+			found := false
+			for _, e := range renewed.Extensions {
+				if e.Id.Equal(want.Id) {
+					found = e.Critical == want.Critical && bytes.Equal(e.Value, want.Value)
+				}
+			}
+			if !found {
+				t.Errorf("extension %v is not copied", want.Id)
+			}
+		}
+		if !bytes.Equal(renewed.AuthorityKeyId, parent.SubjectKeyId) {
+			t.Errorf("unexpected authority key identifier %x", renewed.AuthorityKeyId)
+		}
+		return renewed
+	}
+
+	// the same key and parent
+	der, err := RenewCertificate(rand.Reader, old, root, nil, rootKey, notBefore, notAfter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	renewed := checkRenewed(der, root)
+	if !bytes.Equal(renewed.RawSubjectPublicKeyInfo, old.RawSubjectPublicKeyInfo) || !bytes.Equal(renewed.SubjectKeyId, old.SubjectKeyId) {
+		t.Error("the public key or subject key identifier changed")
+	}
+
+	// a new key and another parent
+	newRootDER, err := CreateCertificate(rand.Reader, rootTemplate, rootTemplate, rootKey.Public(), rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newRoot, err := ParseCertificate(newRootDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKey, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if der, err = RenewCertificate(rand.Reader, old, newRoot, newKey.Public(), rootKey, notBefore, notAfter); err != nil {
+		t.Fatal(err)
+	}
+	renewed = checkRenewed(der, newRoot)
+	if !newKey.PublicKey.Equal(renewed.PublicKey) {
+		t.Error("the renewed certificate has not the new key")
+	}
+	if len(renewed.SubjectKeyId) == 0 || bytes.Equal(renewed.SubjectKeyId, old.SubjectKeyId) {
+		t.Errorf("the subject key identifier %x is not regenerated", renewed.SubjectKeyId)
+	}
+
+	// SCTs are bound to the original certificate
+	template.ExtraExtensions = append(template.ExtraExtensions, pkix.Extension{Id: oidExtensionSCTList, Value: []byte{0x04, 0x02, 0x00, 0x00}})
+	if oldDER, err = CreateCertificate(rand.Reader, template, root, oldKey.Public(), rootKey); err != nil {
+		t.Fatal(err)
+	}
+	if old, err = ParseCertificate(oldDER); err != nil {
+		t.Fatal(err)
+	}
+	if der, err = RenewCertificate(rand.Reader, old, root, nil, rootKey, notBefore, notAfter); err != nil {
+		t.Fatal(err)
+	}
+	renewed = checkRenewed(der, root)
+	if oidInExtensions(oidExtensionSCTList, renewed.Extensions) {
+		t.Error("the SCT list is copied")
+	}
+}
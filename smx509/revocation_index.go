@@ -0,0 +1,80 @@
+package smx509
+
+import (
+	"crypto/x509"
+	"math/big"
+)
+
+// FindRevokedCertificate returns the entry of rl.RevokedCertificateEntries
+// with serial, the first one if there are several, scanning all of them. See
+// BuildIndex for repeated lookups. A nil serial is never found.
+func (rl *RevocationList) FindRevokedCertificate(serial *big.Int) (*x509.RevocationListEntry, bool) {
+	if serial == nil {
+		return nil, false
+	}
+	for i := range rl.RevokedCertificateEntries {
+		rce := &rl.RevokedCertificateEntries[i]
+		if rce.SerialNumber != nil && rce.SerialNumber.Cmp(serial) == 0 {
+			return rce, true
+		}
+	}
+	return nil, false
+}
+
+// RevocationIndex is an index of the revoked certificate entries of a
+// RevocationList by serial number. It is safe for concurrent use.
+type RevocationIndex struct {
+	rl       *RevocationList
+	entries  []x509.RevocationListEntry
+	bySerial map[string]int
+}
+
+// BuildIndex indexes rl.RevokedCertificateEntries by serial number, for
+// lookups in constant time.
+//
+// The index holds the entries slice of rl. If rl.RevokedCertificateEntries
+// is replaced, or appended to or truncated, the index is stale and
+// RevocationIndex.FindRevokedCertificate scans the current entries instead.
+// Changing the serial numbers of the entries in place is not detected, and
+// requires a new index.
+func (rl *RevocationList) BuildIndex() *RevocationIndex {
+	idx := &RevocationIndex{
+		rl:       rl,
+		entries:  rl.RevokedCertificateEntries,
+		bySerial: make(map[string]int, len(rl.RevokedCertificateEntries)),
+	}
+	for i, rce := range idx.entries {
+		if rce.SerialNumber == nil {
+			continue
+		}
+		key := serialIndexKey(rce.SerialNumber)
+		if _, ok := idx.bySerial[key]; !ok {
+			idx.bySerial[key] = i
+		}
+	}
+	return idx
+}
+
+// FindRevokedCertificate is like RevocationList.FindRevokedCertificate, in
+// constant time unless the index is stale.
+func (idx *RevocationIndex) FindRevokedCertificate(serial *big.Int) (*x509.RevocationListEntry, bool) {
+	if serial == nil {
+		return nil, false
+	}
+	current := idx.rl.RevokedCertificateEntries
+	if len(current) != len(idx.entries) || len(current) > 0 && &current[0] != &idx.entries[0] {
+		return idx.rl.FindRevokedCertificate(serial)
+	}
+	i, ok := idx.bySerial[serialIndexKey(serial)]
+	if !ok {
+		return nil, false
+	}
+	return &current[i], true
+}
+
+// serialIndexKey returns the canonical form of serial, its sign and minimal
+// big-endian magnitude.
+func serialIndexKey(serial *big.Int) string {
+	sign := byte(serial.Sign() + 1)
+	return string(append([]byte{sign}, serial.Bytes()...))
+}
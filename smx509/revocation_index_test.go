@@ -0,0 +1,93 @@
+package smx509
+
+import (
+	"crypto/x509"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newRevocationIndexTestList(n int) *RevocationList {
+	rl := &RevocationList{RevokedCertificateEntries: make([]x509.RevocationListEntry, n)}
+	for i := range rl.RevokedCertificateEntries {
+		serial := new(big.Int).Lsh(big.NewInt(int64(i)+1), 64)
+		rl.RevokedCertificateEntries[i] = x509.RevocationListEntry{SerialNumber: serial, RevocationTime: time.Unix(int64(i), 0), ReasonCode: i % 6}
+	}
+	return rl
+}
+
+func TestRevocationIndex(t *testing.T) {
+	rl := newRevocationIndexTestList(100)
+	rl.RevokedCertificateEntries = append(rl.RevokedCertificateEntries,
+		x509.RevocationListEntry{SerialNumber: big.NewInt(-5), ReasonCode: 1},
+		x509.RevocationListEntry{SerialNumber: big.NewInt(5), ReasonCode: 2},
+		// duplicates are found as the first entry
+		x509.RevocationListEntry{SerialNumber: big.NewInt(5), ReasonCode: 3},
+		x509.RevocationListEntry{},
+	)
+	idx := rl.BuildIndex()
+
+	check := func(serial *big.Int, want *x509.RevocationListEntry) {
+		t.Helper()
+		for _, find := range []func(*big.Int) (*x509.RevocationListEntry, bool){rl.FindRevokedCertificate, idx.FindRevokedCertificate} {
+			got, ok := find(serial)
+			if ok != (want != nil) || got != want {
+				t.Errorf("serial %v: got %v %v, want %v", serial, got, ok, want)
+			}
+		}
+	}
+	for i := 0; i < 100; i++ {
+		check(new(big.Int).Lsh(big.NewInt(int64(i)+1), 64), &rl.RevokedCertificateEntries[i])
+	}
+	check(big.NewInt(-5), &rl.RevokedCertificateEntries[100])
+	check(big.NewInt(5), &rl.RevokedCertificateEntries[101])
+	check(big.NewInt(0), nil)
+	check(big.NewInt(6), nil)
+	// the entry without a serial number is not found for a nil serial
+	check(nil, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if _, ok := idx.FindRevokedCertificate(new(big.Int).Lsh(big.NewInt(int64(j)+1), 64)); !ok {
+					t.Error("entry not found")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// a stale index scans the current entries
+	rl.RevokedCertificateEntries = rl.RevokedCertificateEntries[:50]
+	check(new(big.Int).Lsh(big.NewInt(10), 64), &rl.RevokedCertificateEntries[9])
+	check(new(big.Int).Lsh(big.NewInt(60), 64), nil)
+	rl.RevokedCertificateEntries = append([]x509.RevocationListEntry{{SerialNumber: big.NewInt(7)}}, rl.RevokedCertificateEntries...)
+	check(big.NewInt(7), &rl.RevokedCertificateEntries[0])
+	check(nil, nil)
+}
+
+func BenchmarkFindRevokedCertificate(b *testing.B) {
+	rl := newRevocationIndexTestList(1_000_000)
+	serial := new(big.Int).Lsh(big.NewInt(900_000), 64)
+	b.Run("Scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, ok := rl.FindRevokedCertificate(serial); !ok {
+				b.Fatal("entry not found")
+			}
+		}
+	})
+	b.Run("Index", func(b *testing.B) {
+		idx := rl.BuildIndex()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, ok := idx.FindRevokedCertificate(serial); !ok {
+				b.Fatal("entry not found")
+			}
+		}
+	})
+}
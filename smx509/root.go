@@ -12,6 +12,9 @@ var (
 	systemRoots    *CertPool
 	systemRootsErr error
 	fallbacksSet   bool
+	// fallbackRoots is the pool installed by SetFallbackRoots or
+	// AddFallbackRoots, it is in use while it is systemRoots.
+	fallbackRoots *CertPool
 )
 
 func systemRootsPool() *CertPool {
@@ -40,7 +43,7 @@ func initSystemRoots() {
 //
 // The fallback behavior can be forced on all platforms, even when there is a
 // system certificate pool, by setting GODEBUG=x509usefallbackroots=1 (note that
-// on Windows and macOS this will disable usage of the platform verification
+// on Windows this will disable usage of the platform verification
 // APIs and cause the pure Go verifier to be used). Setting
 // x509usefallbackroots=1 without calling SetFallbackRoots has no effect.
 func SetFallbackRoots(roots *CertPool) {
@@ -59,8 +62,65 @@ func SetFallbackRoots(roots *CertPool) {
 		panic("SetFallbackRoots has already been called")
 	}
 	fallbacksSet = true
-	if systemRoots != nil && (systemRoots.len() > 0 || systemRoots.systemPool) && (godebug.Get("x509usefallbackroots") != "1") {
+	switch {
+	case systemRoots != nil && systemRoots == fallbackRoots:
+		// merge with the roots added by AddFallbackRoots
+		systemRoots = mergeCertPools(systemRoots, roots)
+	case useFallbackRoots():
+		systemRoots = roots
+	default:
 		return
 	}
-	systemRoots, systemRootsErr = roots, nil
+	fallbackRoots, systemRootsErr = systemRoots, nil
+}
+
+// AddFallbackRoots adds roots to the fallback roots, see SetFallbackRoots for
+// when they are used. Unlike SetFallbackRoots, it may be called several times,
+// before or after SetFallbackRoots, so that independent packages can each
+// contribute roots. Certificates already in the fallback roots are skipped.
+// AddFallbackRoots will panic if roots is nil.
+//
+// Later changes to roots do not affect the fallback roots.
+func AddFallbackRoots(roots *CertPool) {
+	if roots == nil {
+		panic("roots must be non-nil")
+	}
+
+	// trigger initSystemRoots if it hasn't already been called before we
+	// take the lock
+	_ = systemRootsPool()
+
+	systemRootsMu.Lock()
+	defer systemRootsMu.Unlock()
+
+	switch {
+	case systemRoots != nil && systemRoots == fallbackRoots:
+		systemRoots = mergeCertPools(systemRoots, roots)
+	case useFallbackRoots():
+		systemRoots = mergeCertPools(NewCertPool(), roots)
+	default:
+		return
+	}
+	fallbackRoots, systemRootsErr = systemRoots, nil
+}
+
+// useFallbackRoots reports whether the fallback roots replace systemRoots,
+// that is there is no system pool or x509usefallbackroots=1 is set.
+// systemRootsMu must be held.
+func useFallbackRoots() bool {
+	return systemRoots == nil || (systemRoots.len() == 0 && !systemRoots.systemPool) || godebug.Get("x509usefallbackroots") == "1"
+}
+
+// mergeCertPools returns a copy of dst with the certificates of src added.
+// Neither pool is modified, as dst may be in use concurrently.
+func mergeCertPools(dst, src *CertPool) *CertPool {
+	pool := dst.Clone()
+	for _, lc := range src.lazyCerts {
+		if lc.unhashed {
+			pool.AddCertFunc(lc.rawSubject, lc.getCert)
+			continue
+		}
+		pool.addCertFunc(lc.sum, string(lc.rawSubject), lc.getCert, lc.constraint)
+	}
+	return pool
 }
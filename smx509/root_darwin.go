@@ -1,12 +1,191 @@
+//go:build darwin && !ios
+
 package smx509
 
-//
-// We DO NOT support system verify on darwin due to complex internal package dependencies.
-//
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// The system keychains holding the trusted roots; all will be read. The
+// certificates of SystemRootCertificates.keychain are trusted by default,
+// those of System.keychain only if the trust settings say so.
+var certKeychains = []struct {
+	path    string
+	trusted bool
+}{
+	{"/System/Library/Keychains/SystemRootCertificates.keychain", true},
+	{"/Library/Keychains/System.keychain", false},
+}
+
 func (c *Certificate) systemVerify(opts *VerifyOptions) (chains [][]*Certificate, err error) {
 	return nil, nil
 }
 
+// loadSystemRoots exports the certificates of the system keychains with the
+// security tool, as Go did before using the platform verifier, and verifies
+// them with the pure Go verifier.
+//
+// The certificates with admin or user trust settings are only added if
+// "security verify-cert" trusts them, so that the roots distrusted in the
+// keychain settings are not trusted.
 func loadSystemRoots() (*CertPool, error) {
-	return &CertPool{systemPool: true}, nil
+	hasSettings, err := certsWithTrustSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	roots := NewCertPool()
+	var firstErr error
+	var needsVerify []*Certificate
+	for _, keychain := range certKeychains {
+		data, err := exec.Command("/usr/bin/security", "find-certificate", "-a", "-p", keychain.path).Output()
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		for len(data) > 0 {
+			var block *pem.Block
+			block, data = pem.Decode(data)
+			if block == nil {
+				break
+			}
+			if block.Type != "CERTIFICATE" || len(block.Headers) != 0 {
+				continue
+			}
+			cert, err := ParseCertificate(block.Bytes)
+			if err != nil {
+				continue
+			}
+			sum := sha1.Sum(cert.Raw)
+			if hasSettings[hex.EncodeToString(sum[:])] {
+				needsVerify = append(needsVerify, cert)
+			} else if keychain.trusted {
+				roots.AddCert(cert)
+			}
+		}
+	}
+
+	// "security verify-cert" takes a while, run it concurrently.
+	trusted := make([]bool, len(needsVerify))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 8)
+	for i, cert := range needsVerify {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			trusted[i] = verifyCertWithSystem(cert)
+			<-sem
+		}()
+	}
+	wg.Wait()
+	for i, cert := range needsVerify {
+		if trusted[i] {
+			roots.AddCert(cert)
+		}
+	}
+
+	if roots.len() > 0 {
+		return roots, nil
+	}
+	if firstErr == nil {
+		firstErr = errors.New("x509: no certificates found in the system keychains")
+	}
+	return nil, firstErr
+}
+
+// certsWithTrustSettings returns the lower case hex encoded SHA-1
+// fingerprints of the certificates with admin or user trust settings.
+func certsWithTrustSettings() (map[string]bool, error) {
+	dir, err := os.MkdirTemp("", "smx509trustsettings")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	ret := make(map[string]bool)
+	for _, domain := range []struct {
+		name string
+		args []string
+	}{
+		{"user", nil},
+		{"admin", []string{"-d"}},
+	} {
+		file := filepath.Join(dir, domain.name)
+		args := append([]string{"trust-settings-export"}, domain.args...)
+		var stderr bytes.Buffer
+		cmd := exec.Command("/usr/bin/security", append(args, file)...)
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			// The export fails if the domain has no trust settings.
+			if strings.Contains(stderr.String(), "No Trust Settings were found") {
+				continue
+			}
+			return nil, errors.New("x509: failed to export the " + domain.name + " trust settings: " + strings.TrimSpace(stderr.String()))
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, sum := range parseTrustSettingsFingerprints(data) {
+			ret[sum] = true
+		}
+	}
+	return ret, nil
+}
+
+// parseTrustSettingsFingerprints returns the lower case SHA-1 fingerprints
+// keying the trustList dictionary of the trust settings plist exported by
+// "security trust-settings-export".
+func parseTrustSettingsFingerprints(plist []byte) []string {
+	var ret []string
+	s := bufio.NewScanner(bytes.NewReader(plist))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		key, ok := strings.CutPrefix(line, "<key>")
+		if !ok {
+			continue
+		}
+		key, ok = strings.CutSuffix(key, "</key>")
+		if !ok || len(key) != 2*sha1.Size {
+			continue
+		}
+		if _, err := hex.DecodeString(key); err != nil {
+			continue
+		}
+		ret = append(ret, strings.ToLower(key))
+	}
+	return ret
+}
+
+// verifyCertWithSystem reports whether "security verify-cert" trusts cert as
+// a root, which takes the admin and user trust settings into account.
+func verifyCertWithSystem(cert *Certificate) bool {
+	f, err := os.CreateTemp("", "smx509cert")
+	if err != nil {
+		return false
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	if err := pem.Encode(f, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}); err != nil {
+		return false
+	}
+	if err := f.Close(); err != nil {
+		return false
+	}
+	// -l: cert is a leaf, -L: only use the local keychains.
+	cmd := exec.Command("/usr/bin/security", "verify-cert", "-p", "ssl", "-c", f.Name(), "-l", "-L")
+	return cmd.Run() == nil
 }
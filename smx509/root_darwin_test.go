@@ -0,0 +1,78 @@
+//go:build darwin && !ios
+
+package smx509
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLoadSystemRootsDarwin(t *testing.T) {
+	roots, err := loadSystemRoots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if roots.systemPool {
+		t.Error("system roots should be verified with the Go verifier")
+	}
+	certs, err := roots.Certificates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(certs) == 0 {
+		t.Fatal("no system roots loaded")
+	}
+}
+
+// TestVerifyWithSystemRootsDarwin verifies a chain to a publicly trusted
+// root, GlobalSign Root CA, with nil Roots, so that the roots loaded from the
+// system keychains are used.
+func TestVerifyWithSystemRootsDarwin(t *testing.T) {
+	// The chain is signed with SHA-1.
+	defer SetAllowSHA1(allowSHA1.Load())
+	SetAllowSHA1(true)
+
+	i := slices.IndexFunc(verifyTests, func(test verifyTest) bool {
+		return test.name == "MultipleConstraints"
+	})
+	if i < 0 {
+		t.Fatal("verify test MultipleConstraints not found")
+	}
+	testVerify(t, verifyTests[i], true)
+}
+
+func TestParseTrustSettingsFingerprints(t *testing.T) {
+	plist := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>trustList</key>
+	<dict>
+		<key>0563B8630D62D75ABBC8AB1E4BDFB5A899B24D43</key>
+		<dict>
+			<key>issuerName</key>
+			<data>
+			MGUxCzAJBgNVBAYTAlVT
+			</data>
+			<key>trustSettings</key>
+			<array>
+				<dict>
+					<key>kSecTrustSettingsResult</key>
+					<integer>3</integer>
+				</dict>
+			</array>
+		</dict>
+		<key>d1eb23a46d17d68fd92564c2f1f1601764d8e349</key>
+		<dict/>
+	</dict>
+	<key>trustVersion</key>
+	<integer>1</integer>
+</dict>
+</plist>
+`
+	got := parseTrustSettingsFingerprints([]byte(plist))
+	want := []string{"0563b8630d62d75abbc8ab1e4bdfb5a899b24d43", "d1eb23a46d17d68fd92564c2f1f1601764d8e349"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,79 @@
+package smx509
+
+import (
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// SystemCertPoolFromPaths returns a copy of the system cert pool with the
+// certificates of files, and of the files in dirs, added. It is meant for SM2
+// root bundles kept apart from the general system roots.
+//
+// Files without PEM data, such as the hash links of OpenSSL certificate
+// directories, are skipped. If a file cannot be read or one of its
+// certificates cannot be parsed, the pool of the other certificates is
+// returned along with an error joining all the failures.
+func SystemCertPoolFromPaths(files, dirs []string) (*CertPool, error) {
+	pool, err := SystemCertPool()
+	if err != nil {
+		return nil, err
+	}
+	return pool, appendCertsFromPaths(pool, files, dirs)
+}
+
+// appendCertsFromPaths adds the certificates of files, and of the files in
+// dirs, to pool. It returns the failures to read the files and to parse their
+// certificates, joined.
+func appendCertsFromPaths(pool *CertPool, files, dirs []string) error {
+	var errs []error
+	for _, file := range files {
+		if err := appendCertsFromFile(pool, file); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			if err := appendCertsFromFile(pool, filepath.Join(dir, entry.Name())); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func appendCertsFromFile(pool *CertPool, file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for index := 0; ; {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" || len(block.Headers) != 0 {
+			continue
+		}
+		cert, err := ParseCertificate(block.Bytes)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("x509: %s: certificate %d: %w", file, index, err))
+		} else {
+			pool.AddCert(cert)
+		}
+		index++
+	}
+	return errors.Join(errs...)
+}
@@ -5,7 +5,12 @@
 package smx509
 
 import (
+	"crypto/rand"
+	"errors"
+	"sync"
 	"testing"
+
+	"github.com/yunmoon/gmsm/sm2"
 )
 
 func TestFallbackPanic(t *testing.T) {
@@ -106,3 +111,112 @@ func TestFallback(t *testing.T) {
 		})
 	}
 }
+
+func TestAddFallbackRoots(t *testing.T) {
+	systemRootsPool()
+	originalSystemRoots, originalFallbackRoots, originalFallbacksSet := systemRoots, fallbackRoots, fallbacksSet
+	defer func() {
+		systemRoots, fallbackRoots, fallbacksSet = originalSystemRoots, originalFallbackRoots, originalFallbacksSet
+	}()
+	t.Setenv("GODEBUG", "x509usefallbackroots=0")
+
+	var certs []*Certificate
+	for _, name := range []string{"fallback 1", "fallback 2", "fallback 3"} {
+		key, _ := sm2.GenerateKey(rand.Reader)
+		certs = append(certs, genCertEdge(t, name, key, nil, rootCertificate, nil, nil))
+	}
+	poolOf := func(certs ...*Certificate) *CertPool {
+		pool := NewCertPool()
+		for _, c := range certs {
+			pool.AddCert(c)
+		}
+		return pool
+	}
+
+	// a populated system pool is kept
+	systemRoots, fallbackRoots, fallbacksSet = poolOf(certs[0]), nil, false
+	system := systemRoots
+	AddFallbackRoots(poolOf(certs[1]))
+	SetFallbackRoots(poolOf(certs[2]))
+	if systemRoots != system || systemRoots.len() != 1 {
+		t.Error("fallback roots replaced a populated system pool")
+	}
+
+	// added roots are merged with the roots of SetFallbackRoots, without duplicates
+	systemRoots, fallbackRoots, fallbacksSet = nil, nil, false
+	added := poolOf(certs[0], certs[1])
+	AddFallbackRoots(added)
+	SetFallbackRoots(poolOf(certs[1], certs[2]))
+	AddFallbackRoots(poolOf(certs[0]))
+	if systemRoots.len() != 3 {
+		t.Errorf("expected 3 fallback roots, got %d", systemRoots.len())
+	}
+	for _, c := range certs {
+		if !systemRoots.contains(c) {
+			t.Errorf("fallback roots missing %s", c.Subject.CommonName)
+		}
+	}
+	if added.len() != 2 {
+		t.Error("AddFallbackRoots modified its argument")
+	}
+
+	// concurrent callers
+	systemRoots, fallbackRoots, fallbacksSet = nil, nil, false
+	var wg sync.WaitGroup
+	for _, c := range certs {
+		for range 4 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				AddFallbackRoots(poolOf(c))
+			}()
+		}
+	}
+	wg.Wait()
+	if systemRoots.len() != 3 {
+		t.Errorf("expected 3 fallback roots, got %d", systemRoots.len())
+	}
+}
+
+func TestForceGoVerifier(t *testing.T) {
+	rootKey, _ := sm2.GenerateKey(rand.Reader)
+	leafKey, _ := sm2.GenerateKey(rand.Reader)
+	root := genCertEdge(t, "SM2 root", rootKey, nil, rootCertificate, nil, nil)
+	leaf := genCertEdge(t, "SM2 leaf", leafKey, nil, leafCertificate, root, rootKey)
+
+	// Use a fake platform verifier, as on Windows, which doesn't support SM2.
+	defer func(use bool, verify func(*Certificate, *VerifyOptions) ([][]*Certificate, error)) {
+		usePlatformVerifier, platformVerify = use, verify
+	}(usePlatformVerifier, platformVerify)
+	usePlatformVerifier = true
+	errPlatform := errors.New("platform verifier failure")
+	var called bool
+	platformVerify = func(c *Certificate, opts *VerifyOptions) ([][]*Certificate, error) {
+		called = true
+		return nil, errPlatform
+	}
+
+	// A system pool without additional roots only uses the platform verifier.
+	roots := NewCertPool()
+	roots.systemPool = true
+	if _, err := leaf.Verify(VerifyOptions{Roots: roots, DNSName: "localhost"}); err != errPlatform || !called {
+		t.Errorf("expected the platform verifier error, got %v", err)
+	}
+	called = false
+	if _, err := leaf.Verify(VerifyOptions{Roots: roots, DNSName: "localhost", ForceGoVerifier: true}); err == nil || err == errPlatform || called {
+		t.Errorf("expected an error of the Go verifier, got %v", err)
+	}
+
+	roots.AddCert(root)
+	called = false
+	chains, err := leaf.Verify(VerifyOptions{Roots: roots, DNSName: "localhost", ForceGoVerifier: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if called {
+		t.Error("the platform verifier was used with ForceGoVerifier")
+	}
+	if len(chains) != 1 || len(chains[0]) != 2 || chains[0][1] != root {
+		t.Errorf("unexpected chains %v", chainsToStrings(chains))
+	}
+}
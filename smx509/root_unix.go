@@ -19,6 +19,16 @@ const (
 	// It is a colon separated list of directories.
 	// See https://www.openssl.org/docs/man1.0.2/man1/c_rehash.html.
 	certDirEnv = "SSL_CERT_DIR"
+
+	// smCertFileEnv is the environment variable which identifies an additional
+	// certificate file, such as the SM2 root bundle, loaded before the system
+	// default or SSL_CERT_FILE.
+	smCertFileEnv = "SMX509_CERT_FILE"
+
+	// smCertDirEnv is the environment variable which identifies additional
+	// directories of certificate files, loaded before the system default or
+	// SSL_CERT_DIR. It is a colon separated list of directories.
+	smCertDirEnv = "SMX509_CERT_DIR"
 )
 
 func (c *Certificate) systemVerify(opts *VerifyOptions) (chains [][]*Certificate, err error) {
@@ -28,12 +38,23 @@ func (c *Certificate) systemVerify(opts *VerifyOptions) (chains [][]*Certificate
 func loadSystemRoots() (*CertPool, error) {
 	roots := NewCertPool()
 
+	var firstErr error
+	var smFiles, smDirs []string
+	if f := os.Getenv(smCertFileEnv); f != "" {
+		smFiles = []string{f}
+	}
+	if d := os.Getenv(smCertDirEnv); d != "" {
+		smDirs = strings.Split(d, ":")
+	}
+	if err := appendCertsFromPaths(roots, smFiles, smDirs); err != nil {
+		firstErr = err
+	}
+
 	files := certFiles
 	if f := os.Getenv(certFileEnv); f != "" {
 		files = []string{f}
 	}
 
-	var firstErr error
 	for _, file := range files {
 		data, err := os.ReadFile(file)
 		if err == nil {
@@ -0,0 +1,68 @@
+//go:build dragonfly || freebsd || linux || netbsd || openbsd || solaris
+
+package smx509
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSMCertEnvVars(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	smDir := filepath.Join(dir, "sm")
+	if err := os.Mkdir(smDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	write("sm/sm2.pem", compressedSM2CertPEM)
+	write("sm/README", "not a certificate")
+	smFile := write("sm-root.pem", sm2Certificate)
+	sslFile := write("ssl-root.pem", rsaPSSSelfSignedPEM)
+
+	sm2Cert, _ := certificateFromPEM(compressedSM2CertPEM)
+	smRoot, _ := certificateFromPEM(sm2Certificate)
+	sslRoot, _ := certificateFromPEM(rsaPSSSelfSignedPEM)
+
+	t.Setenv(certFileEnv, sslFile)
+	t.Setenv(certDirEnv, t.TempDir())
+	t.Setenv(smCertFileEnv, smFile)
+	t.Setenv(smCertDirEnv, smDir)
+	roots, err := loadSystemRoots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cert := range []*Certificate{sm2Cert, smRoot, sslRoot} {
+		if !roots.contains(cert) {
+			t.Errorf("%v not loaded", cert.Subject)
+		}
+	}
+
+	// a broken SM bundle does not hide the other roots
+	t.Setenv(smCertFileEnv, write("broken.pem", "-----BEGIN CERTIFICATE-----\nMAA=\n-----END CERTIFICATE-----\n"))
+	t.Setenv(smCertDirEnv, "")
+	if roots, err = loadSystemRoots(); err != nil {
+		t.Fatal(err)
+	}
+	if roots.Len() != 1 || !roots.contains(sslRoot) {
+		t.Errorf("unexpected roots %d", roots.Len())
+	}
+
+	pool, err := SystemCertPoolFromPaths([]string{smFile, filepath.Join(dir, "broken.pem")}, []string{smDir})
+	if err == nil || !strings.Contains(err.Error(), "broken.pem: certificate 0") {
+		t.Errorf("unexpected error %v", err)
+	}
+	if pool == nil || !pool.contains(sm2Cert) || !pool.contains(smRoot) {
+		t.Error("certificates not added to the system pool")
+	}
+	if _, err := SystemCertPoolFromPaths(nil, []string{filepath.Join(dir, "missing")}); err == nil {
+		t.Error("expected error with missing directory")
+	}
+}
@@ -0,0 +1,228 @@
+package smx509
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+// RFC 6962, 3.1 and 3.3 the precertificate poison extension, the
+// extended key usage of the precertificate signing certificates and the
+// embedded SCT list extension.
+var (
+	oidExtensionCTPoison    = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 3}
+	oidExtKeyUsagePrecertCA = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 4}
+	oidExtensionSCTList     = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 2}
+)
+
+// SignedCertificateTimestamp is a version 1 SCT, RFC 6962, 3.2.
+type SignedCertificateTimestamp struct {
+	Version    uint8
+	LogID      [32]byte
+	Timestamp  time.Time // millisecond precision
+	Extensions []byte
+
+	// HashAlgorithm and SignatureAlgorithm are the TLS 1.2 HashAlgorithm and
+	// SignatureAlgorithm of the digitally-signed struct, RFC 5246, 7.4.1.4.1.
+	HashAlgorithm      uint8
+	SignatureAlgorithm uint8
+	Signature          []byte
+}
+
+// SCTList returns the TLS encoded SCTs of the embedded SCT list extension of
+// c, or nil if it has none. ParseCertificate doesn't check the extension, so
+// an error is only returned here if it is malformed. The SCTs can be decoded
+// with ParseSCT.
+func (c *Certificate) SCTList() ([][]byte, error) {
+	for _, e := range c.Extensions {
+		if e.Id.Equal(oidExtensionSCTList) {
+			return parseSCTList(e.Value)
+		}
+	}
+	return nil, nil
+}
+
+func parseSCTList(der cryptobyte.String) ([][]byte, error) {
+	var list, scts cryptobyte.String
+	if !der.ReadASN1(&list, cryptobyte_asn1.OCTET_STRING) || !der.Empty() ||
+		!list.ReadUint16LengthPrefixed(&scts) || !list.Empty() || scts.Empty() {
+		return nil, errors.New("x509: malformed SCT list")
+	}
+	var ret [][]byte
+	for !scts.Empty() {
+		var sct cryptobyte.String
+		if !scts.ReadUint16LengthPrefixed(&sct) || sct.Empty() {
+			return nil, errors.New("x509: malformed SCT list")
+		}
+		ret = append(ret, sct)
+	}
+	return ret, nil
+}
+
+// ParseSCT parses a TLS encoded version 1 SCT, as returned by
+// Certificate.SCTList.
+func ParseSCT(data []byte) (*SignedCertificateTimestamp, error) {
+	s := cryptobyte.String(data)
+	sct := &SignedCertificateTimestamp{}
+	var logID []byte
+	var extensions, signature cryptobyte.String
+	var timestamp uint64
+	if !s.ReadUint8(&sct.Version) {
+		return nil, errors.New("x509: malformed SCT")
+	}
+	if sct.Version != 0 {
+		return nil, errors.New("x509: unsupported SCT version")
+	}
+	if !s.ReadBytes(&logID, len(sct.LogID)) ||
+		!s.ReadUint64(&timestamp) ||
+		!s.ReadUint16LengthPrefixed(&extensions) ||
+		!s.ReadUint8(&sct.HashAlgorithm) ||
+		!s.ReadUint8(&sct.SignatureAlgorithm) ||
+		!s.ReadUint16LengthPrefixed(&signature) ||
+		!s.Empty() {
+		return nil, errors.New("x509: malformed SCT")
+	}
+	copy(sct.LogID[:], logID)
+	sct.Timestamp = time.UnixMilli(int64(timestamp)).UTC()
+	sct.Extensions = extensions
+	sct.Signature = signature
+	return sct, nil
+}
+
+// MarshalSCTList returns the value of an embedded SCT list extension holding
+// the TLS encoded scts.
+func MarshalSCTList(scts [][]byte) ([]byte, error) {
+	if len(scts) == 0 {
+		return nil, errors.New("x509: empty SCT list")
+	}
+	var b cryptobyte.Builder
+	b.AddASN1(cryptobyte_asn1.OCTET_STRING, func(b *cryptobyte.Builder) {
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			for _, sct := range scts {
+				if len(sct) == 0 {
+					b.SetError(errors.New("x509: empty SCT"))
+					return
+				}
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					b.AddBytes(sct)
+				})
+			}
+		})
+	})
+	return b.Bytes()
+}
+
+// BuildPrecertTBS returns the TBSCertificate that is signed by the SCTs of
+// cert, RFC 6962, 3.2: the DER of cert.RawTBSCertificate without the
+// precertificate poison and embedded SCT list extensions, so that a
+// precertificate and its final certificate give the same result.
+//
+// If the precertificate was issued by a precertificate signing certificate,
+// it must be passed as preIssuer, the issuer and authority key identifier of
+// the result are then the ones of the final certificate, taken from preIssuer.
+// Otherwise preIssuer should be nil.
+func BuildPrecertTBS(cert, preIssuer *Certificate) ([]byte, error) {
+	var issuer, authorityKeyId []byte
+	if preIssuer != nil {
+		isPrecertCA := false
+		for _, oid := range preIssuer.UnknownExtKeyUsage {
+			if oid.Equal(oidExtKeyUsagePrecertCA) {
+				isPrecertCA = true
+				break
+			}
+		}
+		if !isPrecertCA {
+			return nil, errors.New("x509: the issuer of the precertificate is not a precertificate signing certificate")
+		}
+		issuer = preIssuer.RawIssuer
+		for _, e := range preIssuer.Extensions {
+			if e.Id.Equal(oidExtensionAuthorityKeyId) {
+				authorityKeyId = e.Value
+				break
+			}
+		}
+	}
+
+	extensions := precertExtensions(cert.Extensions, preIssuer != nil, authorityKeyId)
+
+	input := cryptobyte.String(cert.RawTBSCertificate)
+	var tbs cryptobyte.String
+	if !input.ReadASN1(&tbs, cryptobyte_asn1.SEQUENCE) || !input.Empty() {
+		return nil, errors.New("x509: malformed tbs certificate")
+	}
+	var b cryptobyte.Builder
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		for i := 0; !tbs.Empty(); i++ {
+			var element cryptobyte.String
+			var tag cryptobyte_asn1.Tag
+			if !tbs.ReadAnyASN1Element(&element, &tag) {
+				b.SetError(errors.New("x509: malformed tbs certificate"))
+				return
+			}
+			switch {
+			case tag == cryptobyte_asn1.Tag(3).Constructed().ContextSpecific():
+				if len(extensions) == 0 {
+					continue
+				}
+				b.AddASN1(tag, func(b *cryptobyte.Builder) {
+					b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+						for _, e := range extensions {
+							b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+								b.AddASN1ObjectIdentifier(e.Id)
+								if e.Critical {
+									b.AddASN1Boolean(true)
+								}
+								b.AddASN1OctetString(e.Value)
+							})
+						}
+					})
+				})
+			case issuer != nil && isTBSIssuer(cert, i, tag):
+				b.AddBytes(issuer)
+			default:
+				b.AddBytes(element)
+			}
+		}
+	})
+	return b.Bytes()
+}
+
+// isTBSIssuer reports whether the i-th element of the TBSCertificate of cert,
+// with the given tag, is the issuer.
+func isTBSIssuer(cert *Certificate, i int, tag cryptobyte_asn1.Tag) bool {
+	// The version is omitted in v1 certificates.
+	issuerIndex := 3
+	if cert.Version == 1 {
+		issuerIndex = 2
+	}
+	return i == issuerIndex && tag == cryptobyte_asn1.SEQUENCE
+}
+
+// precertExtensions returns extensions without the poison and SCT list
+// extensions. If replaceAuthorityKeyId is true, the authority key identifier
+// extension is set to authorityKeyId, or removed if it is nil.
+func precertExtensions(extensions []pkix.Extension, replaceAuthorityKeyId bool, authorityKeyId []byte) []pkix.Extension {
+	var ret []pkix.Extension
+	haveAuthorityKeyId := false
+	for _, e := range extensions {
+		if e.Id.Equal(oidExtensionCTPoison) || e.Id.Equal(oidExtensionSCTList) {
+			continue
+		}
+		if replaceAuthorityKeyId && e.Id.Equal(oidExtensionAuthorityKeyId) {
+			haveAuthorityKeyId = true
+			if authorityKeyId == nil {
+				continue
+			}
+			e.Value = authorityKeyId
+		}
+		ret = append(ret, e)
+	}
+	if replaceAuthorityKeyId && !haveAuthorityKeyId && authorityKeyId != nil {
+		ret = append(ret, pkix.Extension{Id: oidExtensionAuthorityKeyId, Value: authorityKeyId})
+	}
+	return ret
+}
@@ -0,0 +1,199 @@
+package smx509
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/yunmoon/gmsm/sm2"
+	"golang.org/x/crypto/cryptobyte"
+)
+
+func marshalTestSCT(logID byte, timestamp time.Time, extensions, signature []byte) []byte {
+	var b cryptobyte.Builder
+	b.AddUint8(0)
+	b.AddBytes(bytes.Repeat([]byte{logID}, 32))
+	b.AddUint64(uint64(timestamp.UnixMilli()))
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(extensions) })
+	b.AddUint8(4) // sha256
+	b.AddUint8(3) // ecdsa
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(signature) })
+	return b.BytesOrPanic()
+}
+
+func TestSCTList(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	timestamp := time.Date(2024, 5, 1, 8, 30, 0, 123e6, time.UTC)
+	scts := [][]byte{
+		marshalTestSCT(0xaa, timestamp, nil, []byte{0x30, 0x01, 0x02}),
+		marshalTestSCT(0xbb, timestamp.Add(time.Second), []byte{0x01}, []byte{0x30, 0x03, 0x04}),
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := CreateCertificateWithOptions(rand.Reader, template, template, priv.Public(), priv, &CreateCertificateOptions{SCTList: scts})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := cert.SCTList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || !bytes.Equal(got[0], scts[0]) || !bytes.Equal(got[1], scts[1]) {
+		t.Fatalf("unexpected SCT list %x", got)
+	}
+
+	sct, err := ParseSCT(got[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sct.Version != 0 || sct.LogID != [32]byte(bytes.Repeat([]byte{0xbb}, 32)) ||
+		!sct.Timestamp.Equal(timestamp.Add(time.Second)) || !bytes.Equal(sct.Extensions, []byte{0x01}) ||
+		sct.HashAlgorithm != 4 || sct.SignatureAlgorithm != 3 || !bytes.Equal(sct.Signature, []byte{0x30, 0x03, 0x04}) {
+		t.Errorf("unexpected SCT %+v", sct)
+	}
+
+	// malformed SCTs
+	for _, data := range [][]byte{nil, {1}, scts[0][:40], append(scts[0], 0)} {
+		if _, err := ParseSCT(data); err == nil {
+			t.Errorf("expected error parsing %x", data)
+		}
+	}
+
+	// a malformed list only fails SCTList
+	template.ExtraExtensions = []pkix.Extension{{Id: oidExtensionSCTList, Value: []byte{0x04, 0x03, 0x00, 0x05, 0x00}}}
+	if der, err = CreateCertificateWithOptions(rand.Reader, template, template, priv.Public(), priv, &CreateCertificateOptions{SCTList: scts}); err != nil {
+		t.Fatal(err)
+	}
+	if cert, err = ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cert.SCTList(); err == nil {
+		t.Error("expected error with a malformed SCT list")
+	}
+
+	template.ExtraExtensions = nil
+	if der, err = CreateCertificate(rand.Reader, template, template, priv.Public(), priv); err != nil {
+		t.Fatal(err)
+	}
+	if cert, err = ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := cert.SCTList(); err != nil || got != nil {
+		t.Errorf("unexpected SCT list %x: %v", got, err)
+	}
+	if _, err := MarshalSCTList(nil); err == nil {
+		t.Error("expected error with an empty SCT list")
+	}
+	if _, err := MarshalSCTList([][]byte{scts[0], nil}); err == nil {
+		t.Error("expected error with an empty SCT")
+	}
+}
+
+func TestBuildPrecertTBS(t *testing.T) {
+	rootKey, _ := sm2.GenerateKey(rand.Reader)
+	precertCAKey, _ := sm2.GenerateKey(rand.Reader)
+	leafKey, _ := sm2.GenerateKey(rand.Reader)
+
+	create := func(template, parent *x509.Certificate, pub any, priv any, opts *CreateCertificateOptions) *Certificate {
+		t.Helper()
+		der, err := CreateCertificateWithOptions(rand.Reader, template, parent, pub, priv, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, err := ParseCertificate(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert
+	}
+	caTemplate := func(cn string) *x509.Certificate {
+		return &x509.Certificate{
+			SerialNumber:          big.NewInt(1),
+			Subject:               pkix.Name{CommonName: cn},
+			NotBefore:             time.Now().Add(-time.Hour),
+			NotAfter:              time.Now().Add(time.Hour),
+			KeyUsage:              KeyUsageCertSign,
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+		}
+	}
+	root := create(caTemplate("SM2 root"), caTemplate("SM2 root"), rootKey.Public(), rootKey, nil)
+	precertCATemplate := caTemplate("SM2 precertificate signing")
+	precertCATemplate.UnknownExtKeyUsage = []asn1.ObjectIdentifier{oidExtKeyUsagePrecertCA}
+	precertCA := create(precertCATemplate, root.ToX509(), precertCAKey.Public(), rootKey, nil)
+
+	leafTemplate := func(poison bool) *x509.Certificate {
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(1234),
+			Subject:      pkix.Name{CommonName: "example.com"},
+			NotBefore:    time.Unix(1700000000, 0),
+			NotAfter:     time.Unix(1800000000, 0),
+			DNSNames:     []string{"example.com"},
+			ExtKeyUsage:  []ExtKeyUsage{ExtKeyUsageServerAuth},
+		}
+		if poison {
+			template.ExtraExtensions = []pkix.Extension{{Id: oidExtensionCTPoison, Critical: true, Value: asn1.NullBytes}}
+		}
+		return template
+	}
+	scts := [][]byte{marshalTestSCT(0xaa, time.Now(), nil, []byte{1})}
+	precert := create(leafTemplate(true), root.ToX509(), leafKey.Public(), rootKey, nil)
+	final := create(leafTemplate(false), root.ToX509(), leafKey.Public(), rootKey, &CreateCertificateOptions{SCTList: scts})
+	withoutSCTs := create(leafTemplate(false), root.ToX509(), leafKey.Public(), rootKey, nil)
+
+	poisoned := false
+	for _, e := range precert.Extensions {
+		if e.Id.Equal(oidExtensionCTPoison) {
+			poisoned = e.Critical && bytes.Equal(e.Value, asn1.NullBytes)
+		}
+	}
+	if !poisoned {
+		t.Fatal("the precertificate has no critical poison extension")
+	}
+	if len(precert.UnhandledCriticalExtensions) != 1 {
+		t.Errorf("unexpected unhandled critical extensions %v", precert.UnhandledCriticalExtensions)
+	}
+
+	precertTBS, err := BuildPrecertTBS(precert, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	finalTBS, err := BuildPrecertTBS(final, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(precertTBS, finalTBS) {
+		t.Errorf("TBS of the precertificate and final certificate differ:\n%x\n%x", precertTBS, finalTBS)
+	}
+	if !bytes.Equal(finalTBS, withoutSCTs.RawTBSCertificate) {
+		t.Error("unexpected TBS of the final certificate")
+	}
+
+	// precertificate issued by a precertificate signing certificate
+	precert = create(leafTemplate(true), precertCA.ToX509(), leafKey.Public(), precertCAKey, nil)
+	if precertTBS, err = BuildPrecertTBS(precert, precertCA); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(precertTBS, finalTBS) {
+		t.Errorf("TBS of the precertificate and final certificate differ:\n%x\n%x", precertTBS, finalTBS)
+	}
+	if _, err := BuildPrecertTBS(precert, root); err == nil {
+		t.Error("expected error with an issuer which is not a precertificate signing certificate")
+	}
+}
@@ -36,9 +36,16 @@ func ParseECPrivateKey(der []byte) (*ecdsa.PrivateKey, error) {
 	return parseECPrivateKey(nil, der)
 }
 
-// ParseSM2PrivateKey parses an SM2 private key in SEC 1, ASN.1 DER form.
+// ParseSM2PrivateKey parses an SM2 private key in SEC 1 (GM/T 0009), ASN.1 DER form.
+//
+// The named curve OID may be omitted from the structure, as it is when carried by
+// an outer PKCS #8 wrapper, in which case the SM2 curve is assumed.
 func ParseSM2PrivateKey(der []byte) (*sm2.PrivateKey, error) {
-	key, err := parseECPrivateKey(nil, der)
+	var privKey ecPrivateKey
+	if _, err := asn1.Unmarshal(der, &privKey); err == nil && len(privKey.NamedCurveOID) > 0 && !privKey.NamedCurveOID.Equal(oidNamedCurveP256SM2) {
+		return nil, errors.New("x509: not a SM2 private key")
+	}
+	key, err := parseECPrivateKey(&oidNamedCurveP256SM2, der)
 	if err != nil {
 		return nil, err
 	}
@@ -126,7 +133,7 @@ func parseECPrivateKey(namedCurveOID *asn1.ObjectIdentifier, der []byte) (key *e
 
 	k := new(big.Int).SetBytes(privKey.PrivateKey)
 	curveOrder := curve.Params().N
-	if k.Cmp(curveOrder) >= 0 {
+	if k.Sign() == 0 || k.Cmp(curveOrder) >= 0 {
 		return nil, errors.New("x509: invalid elliptic curve private key value")
 	}
 	priv := new(ecdsa.PrivateKey)
@@ -150,6 +157,20 @@ func parseECPrivateKey(namedCurveOID *asn1.ObjectIdentifier, der []byte) (key *e
 	copy(privateKey[len(privateKey)-len(privKey.PrivateKey):], privKey.PrivateKey)
 	priv.X, priv.Y = curve.ScalarBaseMult(privateKey)
 
+	// The optional public key of SM2 private keys must match the derived one.
+	if curve == sm2.P256() && len(privKey.PublicKey.Bytes) > 0 {
+		x, y := elliptic.Unmarshal(curve, privKey.PublicKey.Bytes)
+		if x == nil {
+			x, y = elliptic.UnmarshalCompressed(curve, privKey.PublicKey.Bytes)
+		}
+		if x == nil {
+			return nil, errors.New("x509: invalid SM2 public key in private key")
+		}
+		if x.Cmp(priv.X) != 0 || y.Cmp(priv.Y) != 0 {
+			return nil, errors.New("x509: SM2 public key does not match private key")
+		}
+	}
+
 	return priv, nil
 }
 
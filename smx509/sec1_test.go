@@ -3,7 +3,9 @@ package smx509
 import (
 	"bytes"
 	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
+	"encoding/asn1"
 	"encoding/hex"
 	"fmt"
 	"strings"
@@ -103,3 +105,87 @@ func TestParseTypedECPrivateKey(t *testing.T) {
 		}
 	}
 }
+
+// Generated using:
+//
+//	openssl ecparam -genkey -name SM2 -noout -outform DER
+//	openssl ec -conv_form compressed / -no_public
+const (
+	opensslSM2PKCS8Key         = "308187020100301306072a8648ce3d020106082a811ccf5501822d046d306b0201010420ca636533e8f3def71aafe937da793e2946567d4eba32d377e60c1a4d24d2a1baa14403420004c54a5479dcbdafa54f26a83bcff8a37f3bee8c055c70f86297042f2dbf5d347370b2cb89d8386006a77423bbdc324b3fba373bcdd95ea04c95fb9f905a339263"
+	opensslSM2KeyWithoutCurve  = "306b0201010420ca636533e8f3def71aafe937da793e2946567d4eba32d377e60c1a4d24d2a1baa14403420004c54a5479dcbdafa54f26a83bcff8a37f3bee8c055c70f86297042f2dbf5d347370b2cb89d8386006a77423bbdc324b3fba373bcdd95ea04c95fb9f905a339263"
+	opensslSM2KeyCompressed    = "30570201010420ca636533e8f3def71aafe937da793e2946567d4eba32d377e60c1a4d24d2a1baa00a06082a811ccf5501822da12403220003c54a5479dcbdafa54f26a83bcff8a37f3bee8c055c70f86297042f2dbf5d3473"
+	opensslSM2KeyWithoutPublic = "30310201010420ca636533e8f3def71aafe937da793e2946567d4eba32d377e60c1a4d24d2a1baa00a06082a811ccf5501822d"
+	opensslSM2KeyD             = "ca636533e8f3def71aafe937da793e2946567d4eba32d377e60c1a4d24d2a1ba"
+)
+
+func TestParseSM2PrivateKey(t *testing.T) {
+	for _, keyHex := range []string{opensslSM2KeyWithoutCurve, opensslSM2KeyCompressed, opensslSM2KeyWithoutPublic} {
+		der, _ := hex.DecodeString(keyHex)
+		key, err := ParseSM2PrivateKey(der)
+		if err != nil {
+			t.Fatalf("%s: %v", keyHex, err)
+		}
+		if hex.EncodeToString(key.D.Bytes()) != opensslSM2KeyD {
+			t.Errorf("%s: unexpected private key %x", keyHex, key.D.Bytes())
+		}
+		serialized, err := MarshalSM2PrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key2, err := ParseSM2PrivateKey(serialized)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !key.Equal(key2) {
+			t.Errorf("%s: round trip failed", keyHex)
+		}
+	}
+
+	der, _ := hex.DecodeString(opensslSM2PKCS8Key)
+	key, err := ParsePKCS8PrivateKey(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sm2Key, ok := key.(*sm2.PrivateKey); !ok || hex.EncodeToString(sm2Key.D.Bytes()) != opensslSM2KeyD {
+		t.Errorf("unexpected PKCS8 key %v", key)
+	}
+}
+
+func TestParseSM2PrivateKeyInvalid(t *testing.T) {
+	validKey, _ := hex.DecodeString(opensslSM2KeyCompressed)
+	var privKey ecPrivateKey
+	if _, err := asn1.Unmarshal(validKey, &privKey); err != nil {
+		t.Fatal(err)
+	}
+	marshal := func(f func(k *ecPrivateKey)) []byte {
+		k := privKey
+		f(&k)
+		der, err := asn1.Marshal(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return der
+	}
+	otherKey, _ := sm2.GenerateKey(rand.Reader)
+	p256Key, _ := hex.DecodeString(ecKeyTests[2].derHex)
+
+	tests := []struct {
+		name          string
+		der           []byte
+		errorContains string
+	}{
+		{"zero scalar", marshal(func(k *ecPrivateKey) { k.PrivateKey = make([]byte, 32) }), "invalid elliptic curve private key value"},
+		{"scalar equals to n", marshal(func(k *ecPrivateKey) { k.PrivateKey = sm2.P256().Params().N.Bytes() }), "invalid elliptic curve private key value"},
+		{"invalid public key", marshal(func(k *ecPrivateKey) { k.PublicKey.Bytes = []byte{4, 1, 2, 3} }), "invalid SM2 public key"},
+		{"public key mismatch", marshal(func(k *ecPrivateKey) {
+			k.PublicKey = asn1.BitString{Bytes: elliptic.Marshal(sm2.P256(), otherKey.X, otherKey.Y)}
+		}), "does not match"},
+		{"not SM2 curve", p256Key, "not a SM2 private key"},
+	}
+	for _, tt := range tests {
+		_, err := ParseSM2PrivateKey(tt.der)
+		if err == nil || !strings.Contains(err.Error(), tt.errorContains) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.errorContains, err)
+		}
+	}
+}
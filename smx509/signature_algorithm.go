@@ -0,0 +1,160 @@
+package smx509
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	registerSignatureAlgorithmMu sync.Mutex
+	// registeredSignatureAlgorithmDetails is signatureAlgorithmDetails
+	// followed by the registered algorithms, nil until one is registered.
+	registeredSignatureAlgorithmDetails atomic.Pointer[[]signatureAlgorithmDetail]
+)
+
+// allSignatureAlgorithmDetails returns the built-in and registered signature
+// algorithms.
+func allSignatureAlgorithmDetails() []signatureAlgorithmDetail {
+	if details := registeredSignatureAlgorithmDetails.Load(); details != nil {
+		return *details
+	}
+	return signatureAlgorithmDetails
+}
+
+// RegisterSignatureAlgorithm registers a signature algorithm which is not
+// built in, such as one with a vendor OID, so that certificates, certificate
+// requests and CRLs signed with it can be parsed and checked, and signed with
+// it when it is the SignatureAlgorithm of a template.
+//
+// algo and oid must not be those of a built-in or registered algorithm.
+// params are the parameters of the AlgorithmIdentifier when signing.
+// Signing hashes the data with hash, unless it is zero, and passes the digest
+// to the crypto.Signer with hash as options. verify is called in the same
+// way with the digest, or the data, and checks the public key type, as
+// pubKeyAlgo only selects the algorithm matching a signing key.
+//
+// It is safe to call RegisterSignatureAlgorithm concurrently with other
+// functions of this package, but it is usually called from an init function.
+func RegisterSignatureAlgorithm(algo SignatureAlgorithm, name string, oid asn1.ObjectIdentifier, params asn1.RawValue, pubKeyAlgo PublicKeyAlgorithm, hash crypto.Hash, verify func(pub crypto.PublicKey, signed, signature []byte) error) error {
+	if algo == UnknownSignatureAlgorithm || len(oid) == 0 || verify == nil {
+		return errors.New("x509: signature algorithm, OID and verify function are required")
+	}
+	if hash == crypto.MD5 {
+		return errors.New("x509: signing with MD5 is not supported")
+	}
+
+	registerSignatureAlgorithmMu.Lock()
+	defer registerSignatureAlgorithmMu.Unlock()
+
+	current := allSignatureAlgorithmDetails()
+	for _, details := range current {
+		if details.algo == algo {
+			return fmt.Errorf("x509: signature algorithm %d is already registered as %s", algo, details.name)
+		}
+		if details.oid.Equal(oid) {
+			return fmt.Errorf("x509: signature algorithm OID %v is already registered as %s", oid, details.name)
+		}
+	}
+	next := append(current[:len(current):len(current)], signatureAlgorithmDetail{
+		algo:       algo,
+		name:       name,
+		oid:        oid,
+		params:     params,
+		pubKeyAlgo: pubKeyAlgo,
+		hash:       hash,
+		verify:     verify,
+	})
+	registeredSignatureAlgorithmDetails.Store(&next)
+	return nil
+}
+
+// registeredSignatureAlgorithm returns the details of algo if it is a
+// registered algorithm.
+func registeredSignatureAlgorithm(algo SignatureAlgorithm) (signatureAlgorithmDetail, bool) {
+	for _, details := range allSignatureAlgorithmDetails() {
+		if details.algo == algo && details.verify != nil {
+			return details, true
+		}
+	}
+	return signatureAlgorithmDetail{}, false
+}
+
+// SignatureAlgorithmDetails describes a built-in or registered signature
+// algorithm.
+type SignatureAlgorithmDetails struct {
+	Name               string
+	PublicKeyAlgorithm PublicKeyAlgorithm
+	// Hash is the digest signed, zero if the algorithm signs the data, as
+	// PureEd25519 and SM2WithSM3 do, or if it is SM3, as for RSAWithSM3.
+	Hash     crypto.Hash
+	IsRSAPSS bool
+}
+
+// LookupSignatureAlgorithm returns the details of algo, and whether it is a
+// built-in or registered algorithm.
+func LookupSignatureAlgorithm(algo SignatureAlgorithm) (SignatureAlgorithmDetails, bool) {
+	for _, details := range allSignatureAlgorithmDetails() {
+		if details.algo == algo {
+			return SignatureAlgorithmDetails{
+				Name:               details.name,
+				PublicKeyAlgorithm: details.pubKeyAlgo,
+				Hash:               details.hash,
+				IsRSAPSS:           details.isRSAPSS,
+			}, true
+		}
+	}
+	return SignatureAlgorithmDetails{}, false
+}
+
+// SignatureAlgorithmFromOID returns the built-in or registered signature
+// algorithm identified by oid, or UnknownSignatureAlgorithm. The RSASSA-PSS
+// algorithms have the same OID and are told apart by the parameters, so they
+// are not returned.
+func SignatureAlgorithmFromOID(oid asn1.ObjectIdentifier) SignatureAlgorithm {
+	if oid.Equal(oidSignatureRSAPSS) {
+		return UnknownSignatureAlgorithm
+	}
+	return getSignatureAlgorithmFromAI(pkix.AlgorithmIdentifier{Algorithm: oid})
+}
+
+// OIDFromSignatureAlgorithm returns the OID and the parameters of the
+// AlgorithmIdentifier of algo when signing, and whether algo is a built-in or
+// registered algorithm.
+func OIDFromSignatureAlgorithm(algo SignatureAlgorithm) (asn1.ObjectIdentifier, asn1.RawValue, bool) {
+	for _, details := range allSignatureAlgorithmDetails() {
+		if details.algo == algo {
+			params := details.params
+			params.FullBytes = bytes.Clone(params.FullBytes)
+			return slices.Clone(details.oid), params, true
+		}
+	}
+	return nil, asn1.RawValue{}, false
+}
+
+// SignTBS signs tbsDER, the DER encoded to-be-signed part of a structure this
+// package does not create, such as the tbsResponseData of an OCSP response,
+// as CreateCertificate signs a TBSCertificate. sigAlgo is the signature
+// algorithm, or zero for the default one for the key of priv. The signature
+// is checked with the public key of priv. It returns the signature, the
+// signature algorithm used, and the AlgorithmIdentifier to encode with it.
+func SignTBS(rand io.Reader, tbsDER []byte, priv crypto.Signer, sigAlgo SignatureAlgorithm) (signature []byte, usedAlgo SignatureAlgorithm, sigAI pkix.AlgorithmIdentifier, err error) {
+	usedAlgo, sigAI, err = signingParamsForKey(priv, sigAlgo)
+	if err != nil {
+		return nil, 0, sigAI, err
+	}
+	signature, err = signTBS(tbsDER, priv, usedAlgo, rand)
+	if err != nil {
+		return nil, 0, sigAI, err
+	}
+	sigAI.Algorithm = slices.Clone(sigAI.Algorithm)
+	sigAI.Parameters.FullBytes = bytes.Clone(sigAI.Parameters.FullBytes)
+	return signature, usedAlgo, sigAI, nil
+}
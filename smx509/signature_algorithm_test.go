@@ -0,0 +1,208 @@
+package smx509
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yunmoon/gmsm/sm2"
+)
+
+// sm2WithSHA384 is a made-up SM2 signature of the SHA-384 digest.
+const sm2WithSHA384 SignatureAlgorithm = 200
+
+var registerSM2WithSHA384 = sync.OnceValue(func() error {
+	return RegisterSignatureAlgorithm(sm2WithSHA384, "SM2-SHA384", asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 1, 1}, emptyRawValue, ECDSA, crypto.SHA384,
+		func(pub crypto.PublicKey, digest, signature []byte) error {
+			ecPub, ok := pub.(*ecdsa.PublicKey)
+			if !ok || !sm2.IsSM2PublicKey(ecPub) {
+				return errors.New("not a SM2 public key")
+			}
+			if !sm2.VerifyASN1(ecPub, digest, signature) {
+				return errors.New("SM2-SHA384 verification failure")
+			}
+			return nil
+		})
+})
+
+func TestRegisterSignatureAlgorithm(t *testing.T) {
+	if err := registerSM2WithSHA384(); err != nil {
+		t.Fatal(err)
+	}
+
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Vendor SM2-SHA384 CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		SignatureAlgorithm:    sm2WithSHA384,
+	}
+	der, err := CreateCertificate(rand.Reader, template, template, priv.Public(), priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.SignatureAlgorithm != sm2WithSHA384 {
+		t.Fatalf("unexpected signature algorithm %v", cert.SignatureAlgorithm)
+	}
+	if err := cert.CheckSignatureFrom(cert); err != nil {
+		t.Fatal(err)
+	}
+	roots := NewCertPool()
+	roots.AddCert(cert)
+	if _, err := cert.Verify(VerifyOptions{Roots: roots}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a tampered certificate
+	cert.Signature[len(cert.Signature)-1] ^= 1
+	if err := cert.CheckSignatureFrom(cert); err == nil {
+		t.Error("expected error with a tampered signature")
+	}
+
+	// collisions with built-in and registered algorithms
+	verify := func(crypto.PublicKey, []byte, []byte) error { return nil }
+	for _, test := range []struct {
+		algo SignatureAlgorithm
+		oid  asn1.ObjectIdentifier
+	}{
+		{201, oidSignatureSM2WithSM3},
+		{201, oidSignatureSHA256WithRSA},
+		{201, asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 1, 1}},
+		{SM2WithSM3, asn1.ObjectIdentifier{1, 2, 3, 4}},
+		{sm2WithSHA384, asn1.ObjectIdentifier{1, 2, 3, 4}},
+	} {
+		if err := RegisterSignatureAlgorithm(test.algo, "collision", test.oid, emptyRawValue, ECDSA, crypto.SHA256, verify); err == nil {
+			t.Errorf("expected error registering %v %v", test.algo, test.oid)
+		}
+	}
+}
+
+func TestSignatureAlgorithmOIDs(t *testing.T) {
+	tests := []struct {
+		algo       SignatureAlgorithm
+		oid        asn1.ObjectIdentifier
+		name       string
+		pubKeyAlgo PublicKeyAlgorithm
+		hash       crypto.Hash
+		isRSAPSS   bool
+	}{
+		{MD2WithRSA, asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 2}, "MD2-RSA", RSA, 0, false},
+		{MD5WithRSA, asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 4}, "MD5-RSA", RSA, crypto.MD5, false},
+		{SHA1WithRSA, asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 5}, "SHA1-RSA", RSA, crypto.SHA1, false},
+		{SHA256WithRSA, asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}, "SHA256-RSA", RSA, crypto.SHA256, false},
+		{SHA384WithRSA, asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 12}, "SHA384-RSA", RSA, crypto.SHA384, false},
+		{SHA512WithRSA, asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 13}, "SHA512-RSA", RSA, crypto.SHA512, false},
+		{SHA256WithRSAPSS, asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 10}, "SHA256-RSAPSS", RSA, crypto.SHA256, true},
+		{SHA384WithRSAPSS, asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 10}, "SHA384-RSAPSS", RSA, crypto.SHA384, true},
+		{SHA512WithRSAPSS, asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 10}, "SHA512-RSAPSS", RSA, crypto.SHA512, true},
+		{DSAWithSHA1, asn1.ObjectIdentifier{1, 2, 840, 10040, 4, 3}, "DSA-SHA1", DSA, crypto.SHA1, false},
+		{DSAWithSHA256, asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 2}, "DSA-SHA256", DSA, crypto.SHA256, false},
+		{ECDSAWithSHA1, asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 1}, "ECDSA-SHA1", ECDSA, crypto.SHA1, false},
+		{ECDSAWithSHA256, asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}, "ECDSA-SHA256", ECDSA, crypto.SHA256, false},
+		{ECDSAWithSHA384, asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}, "ECDSA-SHA384", ECDSA, crypto.SHA384, false},
+		{ECDSAWithSHA512, asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}, "ECDSA-SHA512", ECDSA, crypto.SHA512, false},
+		{PureEd25519, asn1.ObjectIdentifier{1, 3, 101, 112}, "Ed25519", Ed25519, 0, false},
+		{SM2WithSM3, asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 501}, "SM2-SM3", ECDSA, 0, false},
+		{SM2WithSHA1, asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 502}, "SM2-SHA1", ECDSA, crypto.SHA1, false},
+		{SM2WithSHA256, asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 503}, "SM2-SHA256", ECDSA, crypto.SHA256, false},
+		{RSAWithSM3, asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 504}, "SM3-RSA", RSA, 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			oid, params, ok := OIDFromSignatureAlgorithm(test.algo)
+			if !ok || !oid.Equal(test.oid) {
+				t.Errorf("OIDFromSignatureAlgorithm = %v, %v, want %v", oid, ok, test.oid)
+			}
+			want := UnknownSignatureAlgorithm
+			if !test.isRSAPSS {
+				want = test.algo
+			}
+			if got := SignatureAlgorithmFromOID(test.oid); got != want {
+				t.Errorf("SignatureAlgorithmFromOID = %v, want %v", got, want)
+			}
+			if got := getSignatureAlgorithmFromAI(pkix.AlgorithmIdentifier{Algorithm: oid, Parameters: params}); got != test.algo {
+				t.Errorf("the identifier maps to %v", got)
+			}
+			details, ok := LookupSignatureAlgorithm(test.algo)
+			wantDetails := SignatureAlgorithmDetails{Name: test.name, PublicKeyAlgorithm: test.pubKeyAlgo, Hash: test.hash, IsRSAPSS: test.isRSAPSS}
+			if !ok || details != wantDetails {
+				t.Errorf("LookupSignatureAlgorithm = %+v, %v, want %+v", details, ok, wantDetails)
+			}
+		})
+	}
+
+	// the alternative OID of SHA1WithRSA
+	if got := SignatureAlgorithmFromOID(asn1.ObjectIdentifier{1, 3, 14, 3, 2, 29}); got != SHA1WithRSA {
+		t.Errorf("SignatureAlgorithmFromOID = %v, want SHA1WithRSA", got)
+	}
+	if got := SignatureAlgorithmFromOID(asn1.ObjectIdentifier{1, 2, 3, 4}); got != UnknownSignatureAlgorithm {
+		t.Errorf("SignatureAlgorithmFromOID = %v for an unknown OID", got)
+	}
+	if _, _, ok := OIDFromSignatureAlgorithm(UnknownSignatureAlgorithm); ok {
+		t.Error("OIDFromSignatureAlgorithm found UnknownSignatureAlgorithm")
+	}
+
+	// registered algorithms
+	if err := registerSM2WithSHA384(); err != nil {
+		t.Fatal(err)
+	}
+	oid, _, ok := OIDFromSignatureAlgorithm(sm2WithSHA384)
+	if !ok || SignatureAlgorithmFromOID(oid) != sm2WithSHA384 {
+		t.Errorf("the registered algorithm is not mapped: %v %v", oid, ok)
+	}
+	if details, ok := LookupSignatureAlgorithm(sm2WithSHA384); !ok || details.Name != "SM2-SHA384" || details.Hash != crypto.SHA384 {
+		t.Errorf("unexpected details of the registered algorithm %+v", details)
+	}
+}
+
+func TestSignTBS(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tbs := []byte{0x30, 0x03, 0x02, 0x01, 0x01}
+	signature, algo, ai, err := SignTBS(rand.Reader, tbs, priv, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if algo != SM2WithSM3 || SignatureAlgorithmFromOID(ai.Algorithm) != SM2WithSM3 || len(ai.Parameters.FullBytes) != 0 {
+		t.Errorf("unexpected algorithm %v, identifier %v", algo, ai)
+	}
+	if err := checkSignature(SM2WithSM3, tbs, signature, priv.Public(), false); err != nil {
+		t.Error(err)
+	}
+	if _, _, _, err := SignTBS(rand.Reader, tbs, priv, SHA256WithRSA); err == nil {
+		t.Error("expected error with an algorithm of another key type")
+	}
+
+	// the RSASSA-PSS algorithms can't be told from the OID
+	signature, algo, _, err = SignTBS(rand.Reader, tbs, testPrivateKey, SHA384WithRSAPSS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if algo != SHA384WithRSAPSS {
+		t.Errorf("unexpected algorithm %v", algo)
+	}
+	if err := checkSignature(algo, tbs, signature, testPrivateKey.Public(), false); err != nil {
+		t.Error(err)
+	}
+}
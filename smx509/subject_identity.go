@@ -0,0 +1,129 @@
+package smx509
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/cryptobyte"
+	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+// GM/T 0015-2012, 7.3 the subject identity extensions of personal and
+// organization certificates.
+var (
+	oidExtensionIdentityCode         = asn1.ObjectIdentifier{1, 2, 156, 10260, 4, 1, 1}
+	oidExtensionInsuranceNumber      = asn1.ObjectIdentifier{1, 2, 156, 10260, 4, 1, 2}
+	oidExtensionOrganizationCode     = asn1.ObjectIdentifier{1, 2, 156, 10260, 4, 1, 3}
+	oidExtensionICRegistrationNumber = asn1.ObjectIdentifier{1, 2, 156, 10260, 4, 1, 4}
+	oidExtensionTaxationNumber       = asn1.ObjectIdentifier{1, 2, 156, 10260, 4, 1, 5}
+)
+
+// SubjectIdentity holds the GM/T 0015 subject identity extensions of a
+// certificate.
+type SubjectIdentity struct {
+	IdentityCode         string // personal identity code, 1.2.156.10260.4.1.1
+	InsuranceNumber      string // personal social insurance number, 1.2.156.10260.4.1.2
+	OrganizationCode     string // organization code, 1.2.156.10260.4.1.3
+	ICRegistrationNumber string // industrial and commercial registration number, 1.2.156.10260.4.1.4
+	TaxationNumber       string // taxation number, 1.2.156.10260.4.1.5
+
+	// Raw holds the identity extensions whose value is malformed or not an
+	// ASN.1 string, instead of failing the whole parse.
+	Raw []pkix.Extension
+}
+
+// SubjectIdentity returns the GM/T 0015 subject identity extensions of c, or
+// nil if it has none. The values are ASN.1 strings such as PrintableString or
+// UTF8String, optionally wrapped in an explicit context-specific tag, as the
+// CHOICE of IdentifyCode is encoded by some CAs.
+func (c *Certificate) SubjectIdentity() *SubjectIdentity {
+	var id SubjectIdentity
+	found := false
+	for _, e := range c.Extensions {
+		var field *string
+		switch {
+		case e.Id.Equal(oidExtensionIdentityCode):
+			field = &id.IdentityCode
+		case e.Id.Equal(oidExtensionInsuranceNumber):
+			field = &id.InsuranceNumber
+		case e.Id.Equal(oidExtensionOrganizationCode):
+			field = &id.OrganizationCode
+		case e.Id.Equal(oidExtensionICRegistrationNumber):
+			field = &id.ICRegistrationNumber
+		case e.Id.Equal(oidExtensionTaxationNumber):
+			field = &id.TaxationNumber
+		default:
+			continue
+		}
+		found = true
+		if value, err := parseIdentityString(e.Value); err == nil {
+			*field = value
+		} else {
+			id.Raw = append(id.Raw, e)
+		}
+	}
+	if !found {
+		return nil
+	}
+	return &id
+}
+
+// parseIdentityString parses an ASN.1 string, optionally wrapped in an
+// explicit context-specific tag.
+func parseIdentityString(der cryptobyte.String) (string, error) {
+	var value cryptobyte.String
+	var tag cryptobyte_asn1.Tag
+	if !der.ReadAnyASN1(&value, &tag) || !der.Empty() {
+		return "", errors.New("x509: malformed subject identity extension")
+	}
+	if tag&0xe0 == 0xa0 {
+		// explicit [n]
+		inner := value
+		if !inner.ReadAnyASN1(&value, &tag) || !inner.Empty() {
+			return "", errors.New("x509: malformed subject identity extension")
+		}
+	}
+	return parseASN1String(tag, value)
+}
+
+// MarshalSubjectIdentityExtensions returns the non-critical GM/T 0015
+// extensions of the non-empty fields of id, in OID order, followed by the
+// extensions of id.Raw whose field is empty. A value is encoded as a
+// PrintableString if possible, or else as a UTF8String. An error is returned
+// if a value is not valid UTF-8.
+func MarshalSubjectIdentityExtensions(id *SubjectIdentity) ([]pkix.Extension, error) {
+	var ret []pkix.Extension
+	for _, f := range []struct {
+		oid   asn1.ObjectIdentifier
+		value string
+	}{
+		{oidExtensionIdentityCode, id.IdentityCode},
+		{oidExtensionInsuranceNumber, id.InsuranceNumber},
+		{oidExtensionOrganizationCode, id.OrganizationCode},
+		{oidExtensionICRegistrationNumber, id.ICRegistrationNumber},
+		{oidExtensionTaxationNumber, id.TaxationNumber},
+	} {
+		if f.value == "" {
+			continue
+		}
+		value, err := asn1.MarshalWithParams(f.value, "printable")
+		if err != nil {
+			if !utf8.ValidString(f.value) {
+				return nil, fmt.Errorf("x509: subject identity %v value %q is not valid UTF-8", f.oid, f.value)
+			}
+			if value, err = asn1.MarshalWithParams(f.value, "utf8"); err != nil {
+				return nil, err
+			}
+		}
+		ret = append(ret, pkix.Extension{Id: f.oid, Value: value})
+	}
+	for _, e := range id.Raw {
+		if !oidInExtensions(e.Id, ret) {
+			ret = append(ret, e)
+		}
+	}
+	return ret, nil
+}
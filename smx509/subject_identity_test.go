@@ -0,0 +1,211 @@
+package smx509
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yunmoon/gmsm/sm2"
+)
+
+// A synthetic certificate generated with OpenSSL, not a real-world one; no
+// real-world certificate with these extensions was available, so the
+// encodings of actual CAs, beyond the explicit [0] wrapping, are not covered:
+//
+//	1.2.156.10260.4.1.1 = ASN1:EXPLICIT:0,PRINTABLESTRING:11010519491231002X
+//	1.2.156.10260.4.1.2 = ASN1:UTF8String:SI-1234567890
+//	1.2.156.10260.4.1.3 = ASN1:PRINTABLESTRING:MA1234567
+//	1.2.156.10260.4.1.4 = ASN1:UTF8String:110000000000001
+//	1.2.156.10260.4.1.5 = ASN1:INTEGER:12345
+const subjectIdentityCertPEM = `-----BEGIN CERTIFICATE-----
+MIICFzCCAbygAwIBAgIUWaKn/49UM6ClnAufAQ98v8AikYswCgYIKoEcz1UBg3Uw
+KzEUMBIGA1UEAwwLR00gaWRlbnRpdHkxEzARBgNVBAoMCkV4YW1wbGUgQ28wIBcN
+MjYxMDE2MTM0ODEyWhgPMjEyNjA5MjIxMzQ4MTJaMCsxFDASBgNVBAMMC0dNIGlk
+ZW50aXR5MRMwEQYDVQQKDApFeGFtcGxlIENvMFkwEwYHKoZIzj0CAQYIKoEcz1UB
+gi0DQgAEVSR6OjMvAZu/2Ucxng1/I3BriwVVP3wBhO8hCFF4L5RYi6Doy6u4KiDM
+A72yAmFLtNwP1IMR4972O7DOQqea66OBuzCBuDAMBgNVHRMBAf8EAjAAMCIGCCqB
+HNAUBAEBBBagFBMSMTEwMTA1MTk0OTEyMzEwMDJYMBsGCCqBHNAUBAECBA8MDVNJ
+LTEyMzQ1Njc4OTAwFwYIKoEc0BQEAQMECxMJTUExMjM0NTY3MB0GCCqBHNAUBAEE
+BBEMDzExMDAwMDAwMDAwMDAwMTAQBggqgRzQFAQBBQQEAgIwOTAdBgNVHQ4EFgQU
+UhVOJIfln55IAJtr5kweXhY1ugQwCgYIKoEcz1UBg3UDSQAwRgIhAKMlyBg9/IQ/
+TN6aL8syMWAFMXNtO9cxlGw716/MEow4AiEA5h4GpsdUdJYTKmZ3BgD+HwiM+S8t
+wJI8NdSPwBLotBU=
+-----END CERTIFICATE-----`
+
+// A synthetic certificate generated with OpenSSL:
+//
+//	1.2.156.10260.4.1.1 = ASN1:PRINTABLESTRING:11010519491231002X
+//	1.2.156.10260.4.1.3 = ASN1:PRINTABLESTRING:MA1234567
+//	1.2.156.10260.4.1.5 = ASN1:FORMAT:UTF8,UTF8String:税号91110000MA0000000X
+const subjectIdentityReferenceCertPEM = `-----BEGIN CERTIFICATE-----
+MIIBvTCCAWSgAwIBAgIUBuS1/eEKlVjRKl3cRuqgEanVFSUwCgYIKoEcz1UBg3Uw
+GjEYMBYGA1UEAwwPR00gb3JnYW5pemF0aW9uMCAXDTI2MTAxNjEzNDk0MVoYDzIx
+MjYwOTIyMTM0OTQxWjAaMRgwFgYDVQQDDA9HTSBvcmdhbml6YXRpb24wWTATBgcq
+hkjOPQIBBggqgRzPVQGCLQNCAARVJHo6My8Bm7/ZRzGeDX8jcGuLBVU/fAGE7yEI
+UXgvlFiLoOjLq7gqIMwDvbICYUu03A/UgxHj3vY7sM5Cp5rro4GFMIGCMCAGCCqB
+HNAUBAEBBBQTEjExMDEwNTE5NDkxMjMxMDAyWDAXBggqgRzQFAQBAwQLEwlNQTEy
+MzQ1NjcwJgYIKoEc0BQEAQUEGgwY56iO5Y+3OTExMTAwMDBNQTAwMDAwMDBYMB0G
+A1UdDgQWBBRSFU4kh+WfnkgAm2vmTB5eFjW6BDAKBggqgRzPVQGDdQNHADBEAiA+
+oPJQQQotLHIQ1JU8azAjK+VUgK6P2GvetJRNUZELDgIgfnkPuf7ZF+vCXLlFo+YF
+tYbeB0jaXV3gMZGZz+j0Qgk=
+-----END CERTIFICATE-----`
+
+func TestSubjectIdentity(t *testing.T) {
+	block, _ := pem.Decode([]byte(subjectIdentityCertPEM))
+	cert, err := ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := cert.SubjectIdentity()
+	if id == nil {
+		t.Fatal("no subject identity")
+	}
+	if id.IdentityCode != "11010519491231002X" || id.InsuranceNumber != "SI-1234567890" ||
+		id.OrganizationCode != "MA1234567" || id.ICRegistrationNumber != "110000000000001" || id.TaxationNumber != "" {
+		t.Errorf("unexpected subject identity %+v", id)
+	}
+	if len(id.Raw) != 1 || !id.Raw[0].Id.Equal(oidExtensionTaxationNumber) {
+		t.Errorf("expected the taxation number in Raw, got %v", id.Raw)
+	}
+
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	utf8Value, _ := asn1.MarshalWithParams("91110000MA0000000X 税号", "utf8")
+	printableValue, _ := asn1.MarshalWithParams("91110000MA0000000X", "printable")
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "GM organization"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtraExtensions: []pkix.Extension{
+			{Id: oidExtensionTaxationNumber, Value: utf8Value},
+			// explicit [1] wrapping
+			{Id: oidExtensionOrganizationCode, Value: append([]byte{0xa1, byte(len(printableValue))}, printableValue...)},
+			// truncated
+			{Id: oidExtensionIdentityCode, Value: printableValue[:5]},
+		},
+	}
+	der, err := CreateCertificate(rand.Reader, template, template, priv.Public(), priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert, err = ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+	id = cert.SubjectIdentity()
+	if id == nil {
+		t.Fatal("no subject identity")
+	}
+	if id.TaxationNumber != "91110000MA0000000X 税号" || id.OrganizationCode != "91110000MA0000000X" || id.IdentityCode != "" {
+		t.Errorf("unexpected subject identity %+v", id)
+	}
+	if len(id.Raw) != 1 || !id.Raw[0].Id.Equal(oidExtensionIdentityCode) {
+		t.Errorf("expected the identity code in Raw, got %v", id.Raw)
+	}
+
+	template.ExtraExtensions = nil
+	if der, err = CreateCertificate(rand.Reader, template, template, priv.Public(), priv); err != nil {
+		t.Fatal(err)
+	}
+	if cert, err = ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+	if id := cert.SubjectIdentity(); id != nil {
+		t.Errorf("unexpected subject identity %+v", id)
+	}
+}
+
+func TestMarshalSubjectIdentityExtensions(t *testing.T) {
+	block, _ := pem.Decode([]byte(subjectIdentityReferenceCertPEM))
+	reference, err := ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := &SubjectIdentity{
+		IdentityCode:     "11010519491231002X",
+		OrganizationCode: "MA1234567",
+		TaxationNumber:   "税号91110000MA0000000X",
+	}
+	if got := reference.SubjectIdentity(); !reflect.DeepEqual(got, id) {
+		t.Errorf("unexpected subject identity %+v", got)
+	}
+	exts, err := MarshalSubjectIdentityExtensions(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []pkix.Extension
+	for _, e := range reference.Extensions {
+		if len(e.Id) == 7 && e.Id[3] == 10260 {
+			want = append(want, e)
+		}
+	}
+	if !reflect.DeepEqual(exts, want) {
+		t.Errorf("extensions differ from OpenSSL:\n got %v\nwant %v", exts, want)
+	}
+
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	taxation, _ := asn1.MarshalWithParams("overridden", "utf8")
+	template := &x509.Certificate{
+		SerialNumber:    big.NewInt(1),
+		Subject:         pkix.Name{CommonName: "GM organization"},
+		NotBefore:       time.Now().Add(-time.Hour),
+		NotAfter:        time.Now().Add(time.Hour),
+		ExtraExtensions: []pkix.Extension{{Id: oidExtensionTaxationNumber, Value: taxation}},
+	}
+	der, err := CreateCertificateWithOptions(rand.Reader, template, template, priv.Public(), priv, &CreateCertificateOptions{SubjectIdentity: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for _, e := range cert.Extensions {
+		if e.Id.Equal(oidExtensionTaxationNumber) {
+			n++
+		}
+		if len(e.Id) == 7 && e.Id[3] == 10260 && e.Critical {
+			t.Errorf("extension %v is critical", e.Id)
+		}
+	}
+	if n != 1 {
+		t.Errorf("expected one taxation number extension, got %d", n)
+	}
+	want2 := *id
+	want2.TaxationNumber = "overridden"
+	if got := cert.SubjectIdentity(); !reflect.DeepEqual(got, &want2) {
+		t.Errorf("unexpected subject identity %+v", got)
+	}
+
+	// round trip of the extensions which aren't strings
+	block, _ = pem.Decode([]byte(subjectIdentityCertPEM))
+	if cert, err = ParseCertificate(block.Bytes); err != nil {
+		t.Fatal(err)
+	}
+	if exts, err = MarshalSubjectIdentityExtensions(cert.SubjectIdentity()); err != nil {
+		t.Fatal(err)
+	}
+	if len(exts) != 5 || !exts[4].Id.Equal(oidExtensionTaxationNumber) || !bytes.Equal(exts[4].Value, cert.SubjectIdentity().Raw[0].Value) {
+		t.Errorf("unexpected extensions %v", exts)
+	}
+
+	if _, err := MarshalSubjectIdentityExtensions(&SubjectIdentity{InsuranceNumber: "\xff"}); err == nil {
+		t.Error("expected error with invalid UTF-8")
+	}
+	if _, err := CreateCertificateWithOptions(rand.Reader, template, template, priv.Public(), priv, &CreateCertificateOptions{SubjectIdentity: &SubjectIdentity{InsuranceNumber: "\xff"}}); err == nil {
+		t.Error("expected error with invalid UTF-8")
+	}
+}
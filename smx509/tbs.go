@@ -0,0 +1,257 @@
+package smx509
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/cryptobyte"
+	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+// errExternalSigner is returned by externalSigner.Sign, which is never called.
+var errExternalSigner = errors.New("x509: internal error: the TBS is signed externally")
+
+// externalSigner stands for a signing key kept out of reach, such as in an
+// offline HSM, when only the to-be-signed bytes are created. Its public key
+// selects the signature algorithm.
+type externalSigner struct {
+	pub crypto.PublicKey
+}
+
+func (s externalSigner) Public() crypto.PublicKey {
+	return s.pub
+}
+
+func (s externalSigner) Sign(io.Reader, []byte, crypto.SignerOpts) ([]byte, error) {
+	return nil, errExternalSigner
+}
+
+// CreateTBSCertificate returns the DER encoded TBSCertificate which
+// CreateCertificate would sign, and the algorithm to sign it with, so that it
+// can be signed out of band, for example during an offline HSM ceremony. The
+// certificate is then put together by AssembleCertificate.
+//
+// The signature algorithm is template.SignatureAlgorithm, or the default one
+// for the public key of parent, which is pub when parent is template and has
+// no public key. A missing serial number is generated from crypto/rand.
+func CreateTBSCertificate(template, parent, pub any) (tbsDER []byte, sigAlg SignatureAlgorithm, err error) {
+	return CreateTBSCertificateWithOptions(template, parent, pub, nil)
+}
+
+// CreateTBSCertificateWithOptions is like CreateTBSCertificate, with options.
+// A nil opts is equivalent to the zero value, AllowOpaqueSigner is ignored.
+func CreateTBSCertificateWithOptions(template, parent, pub any, opts *CreateCertificateOptions) (tbsDER []byte, sigAlg SignatureAlgorithm, err error) {
+	realTemplate, err := toCertificate(template)
+	if err != nil {
+		return nil, 0, fmt.Errorf("x509: unsupported template parameter type: %T", template)
+	}
+
+	realParent, err := toCertificate(parent)
+	if err != nil {
+		return nil, 0, fmt.Errorf("x509: unsupported parent parameter type: %T", parent)
+	}
+
+	signerPub := realParent.PublicKey
+	if signerPub == nil {
+		if template != parent {
+			return nil, 0, errors.New("x509: parent's PublicKey is required to select the signature algorithm")
+		}
+		signerPub = pub
+	}
+
+	c, sigAlg, err := createTBSCertificate(rand.Reader, realTemplate, realParent, pub, externalSigner{signerPub}, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	return c.Raw, sigAlg, nil
+}
+
+// AssembleCertificate returns the DER encoded certificate made of tbsDER, as
+// returned by CreateTBSCertificate, and its signature with sigAlg. The
+// signature is checked with parentPub, the public key of the issuer, or with
+// the public key of the certificate itself if parentPub is nil, for a
+// self-signed certificate.
+func AssembleCertificate(tbsDER []byte, sigAlg SignatureAlgorithm, signature []byte, parentPub crypto.PublicKey) ([]byte, error) {
+	der, err := assemble(tbsDER, sigAlg, tbsSignatureAI(tbsDER), signature)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAssembled(cert.RawTBSCertificate, tbsDER, cert.SignatureAlgorithm, sigAlg); err != nil {
+		return nil, err
+	}
+	if parentPub == nil {
+		parentPub = cert.PublicKey
+	}
+	if err := checkSignature(sigAlg, tbsDER, cert.Signature, parentPub, true); err != nil {
+		return nil, fmt.Errorf("x509: invalid external signature: %w", err)
+	}
+	return der, nil
+}
+
+// CreateTBSCertificateRequest returns the DER encoded CertificationRequestInfo
+// which CreateCertificateRequest would sign with the private key of pub, and
+// the algorithm to sign it with. The request is then put together by
+// AssembleCertificateRequest.
+func CreateTBSCertificateRequest(template *x509.CertificateRequest, pub any) (tbsDER []byte, sigAlg SignatureAlgorithm, err error) {
+	return CreateTBSCertificateRequestWithOptions(template, pub, nil)
+}
+
+// CreateTBSCertificateRequestWithOptions is like CreateTBSCertificateRequest,
+// with options. A nil opts is equivalent to the zero value.
+func CreateTBSCertificateRequestWithOptions(template *x509.CertificateRequest, pub any, opts *CreateCertificateRequestOptions) (tbsDER []byte, sigAlg SignatureAlgorithm, err error) {
+	tbsCSR, sigAlg, _, err := createTBSCertificateRequest(template, pub, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	return tbsCSR.Raw, sigAlg, nil
+}
+
+// AssembleCertificateRequest returns the DER encoded certificate request made
+// of tbsDER, as returned by CreateTBSCertificateRequest, and its signature
+// with sigAlg, which is checked with the public key of the request.
+func AssembleCertificateRequest(tbsDER []byte, sigAlg SignatureAlgorithm, signature []byte) ([]byte, error) {
+	der, err := assemble(tbsDER, sigAlg, nil, signature)
+	if err != nil {
+		return nil, err
+	}
+	csr, err := ParseCertificateRequest(der)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAssembled(csr.RawTBSCertificateRequest, tbsDER, csr.SignatureAlgorithm, sigAlg); err != nil {
+		return nil, err
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("x509: invalid external signature: %w", err)
+	}
+	return der, nil
+}
+
+// CreateTBSRevocationList returns the DER encoded TBSCertList which
+// CreateRevocationList would sign with the private key of issuer, and the
+// algorithm to sign it with. The CRL is then put together by
+// AssembleRevocationList.
+func CreateTBSRevocationList(template *x509.RevocationList, issuer *Certificate) (tbsDER []byte, sigAlg SignatureAlgorithm, err error) {
+	return CreateTBSRevocationListWithOptions(template, issuer, nil)
+}
+
+// CreateTBSRevocationListWithOptions is like CreateTBSRevocationList, with
+// options. A nil opts is equivalent to the zero value.
+func CreateTBSRevocationListWithOptions(template *x509.RevocationList, issuer *Certificate, opts *CreateRevocationListOptions) (tbsDER []byte, sigAlg SignatureAlgorithm, err error) {
+	if issuer == nil {
+		return nil, 0, errors.New("x509: issuer can not be nil")
+	}
+	tbsCertList, sigAlg, err := createTBSRevocationList(template, issuer, issuer.PublicKey, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	return tbsCertList.Raw, sigAlg, nil
+}
+
+// AssembleRevocationList returns the DER encoded CRL made of tbsDER, as
+// returned by CreateTBSRevocationList, and its signature with sigAlg, which is
+// checked with the public key of issuer.
+func AssembleRevocationList(tbsDER []byte, sigAlg SignatureAlgorithm, signature []byte, issuer *Certificate) ([]byte, error) {
+	der, err := assemble(tbsDER, sigAlg, tbsSignatureAI(tbsDER), signature)
+	if err != nil {
+		return nil, err
+	}
+	rl, err := ParseRevocationList(der)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAssembled(rl.RawTBSRevocationList, tbsDER, rl.SignatureAlgorithm, sigAlg); err != nil {
+		return nil, err
+	}
+	if err := rl.CheckSignatureFrom(issuer); err != nil {
+		return nil, fmt.Errorf("x509: invalid external signature: %w", err)
+	}
+	return der, nil
+}
+
+// tbsSignatureAI returns the signature AlgorithmIdentifier of a TBSCertificate
+// or TBSCertList, the first SEQUENCE in it, or nil if it is malformed.
+func tbsSignatureAI(tbsDER []byte) []byte {
+	input := cryptobyte.String(tbsDER)
+	var tbs cryptobyte.String
+	if !input.ReadASN1(&tbs, cryptobyte_asn1.SEQUENCE) {
+		return nil
+	}
+	for !tbs.Empty() {
+		var element cryptobyte.String
+		var tag cryptobyte_asn1.Tag
+		if !tbs.ReadAnyASN1Element(&element, &tag) {
+			return nil
+		}
+		if tag == cryptobyte_asn1.SEQUENCE {
+			return element
+		}
+	}
+	return nil
+}
+
+// assemble returns the SEQUENCE of tbsDER, the AlgorithmIdentifier and the
+// BIT STRING of signature. The AlgorithmIdentifier is algorithmIdentifier,
+// such as the one in tbsDER to keep its parameters, if it identifies sigAlg,
+// or the one of sigAlg.
+func assemble(tbsDER []byte, sigAlg SignatureAlgorithm, algorithmIdentifier []byte, signature []byte) ([]byte, error) {
+	if algorithmIdentifier != nil {
+		var ai cryptobyte.String
+		input := cryptobyte.String(algorithmIdentifier)
+		if !input.ReadASN1(&ai, cryptobyte_asn1.SEQUENCE) {
+			algorithmIdentifier = nil
+		} else if parsed, err := parseAI(ai); err != nil || getSignatureAlgorithmFromAI(parsed) != sigAlg {
+			algorithmIdentifier = nil
+		}
+	}
+	if algorithmIdentifier == nil {
+		for _, details := range allSignatureAlgorithmDetails() {
+			if details.algo == sigAlg {
+				var err error
+				algorithmIdentifier, err = asn1.Marshal(pkix.AlgorithmIdentifier{Algorithm: details.oid, Parameters: details.params})
+				if err != nil {
+					return nil, err
+				}
+				break
+			}
+		}
+		if algorithmIdentifier == nil {
+			return nil, errors.New("x509: unknown SignatureAlgorithm")
+		}
+	}
+
+	input := cryptobyte.String(tbsDER)
+	if !input.SkipASN1(cryptobyte_asn1.SEQUENCE) || !input.Empty() {
+		return nil, errors.New("x509: malformed TBS")
+	}
+	var b cryptobyte.Builder
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddBytes(tbsDER)
+		b.AddBytes(algorithmIdentifier)
+		b.AddASN1BitString(signature)
+	})
+	return b.Bytes()
+}
+
+// checkAssembled checks that the parsed TBS and signature algorithm of an
+// assembled object are the given ones.
+func checkAssembled(parsedTBS, tbsDER []byte, parsedSigAlg, sigAlg SignatureAlgorithm) error {
+	if !bytes.Equal(parsedTBS, tbsDER) {
+		return errors.New("x509: malformed TBS")
+	}
+	if parsedSigAlg != sigAlg {
+		return fmt.Errorf("x509: the TBS is to be signed with %v, not %v", parsedSigAlg, sigAlg)
+	}
+	return nil
+}
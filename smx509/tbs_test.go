@@ -0,0 +1,213 @@
+package smx509
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/yunmoon/gmsm/sm2"
+)
+
+func TestAssembleCertificate(t *testing.T) {
+	// caKey stands for the key of an offline HSM
+	caKey, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Offline SM2 Root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              KeyUsageCertSign | KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	tbs, sigAlg, err := CreateTBSCertificate(caTemplate, caTemplate, caKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sigAlg != SM2WithSM3 {
+		t.Fatalf("unexpected signature algorithm %v", sigAlg)
+	}
+	signature, err := caKey.Sign(rand.Reader, tbs, sm2.DefaultSM2SignerOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caDER, err := AssembleCertificate(tbs, sigAlg, signature, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ca.CheckSignatureFrom(ca); err != nil {
+		t.Fatal(err)
+	}
+
+	leafKey, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "leaf"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"leaf.example.com"},
+	}
+	if tbs, sigAlg, err = CreateTBSCertificate(template, ca, leafKey.Public()); err != nil {
+		t.Fatal(err)
+	}
+	if signature, err = caKey.Sign(rand.Reader, tbs, sm2.DefaultSM2SignerOpts); err != nil {
+		t.Fatal(err)
+	}
+	der, err := AssembleCertificate(tbs, sigAlg, signature, ca.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.CheckSignatureFrom(ca); err != nil {
+		t.Fatal(err)
+	}
+
+	// the same TBS as CreateCertificate
+	direct, err := CreateCertificate(rand.Reader, template, ca, leafKey.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if directCert, err := ParseCertificate(direct); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(directCert.RawTBSCertificate, tbs) {
+		t.Error("the TBS differs from the one of CreateCertificate")
+	}
+
+	// signed by the wrong key
+	if signature, err = leafKey.Sign(rand.Reader, tbs, sm2.DefaultSM2SignerOpts); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AssembleCertificate(tbs, sigAlg, signature, ca.PublicKey); err == nil {
+		t.Error("expected error with a signature of another key")
+	}
+	// another signature algorithm than the TBS one
+	if _, err := AssembleCertificate(tbs, ECDSAWithSHA256, signature, ca.PublicKey); err == nil {
+		t.Error("expected error with a mismatched signature algorithm")
+	}
+	if _, err := AssembleCertificate(append(tbs, 0), sigAlg, signature, ca.PublicKey); err == nil {
+		t.Error("expected error with trailing data")
+	}
+	// parent without a public key
+	if _, _, err := CreateTBSCertificate(template, caTemplate, leafKey.Public()); err == nil {
+		t.Error("expected error without the public key of parent")
+	}
+}
+
+func TestAssembleCertificateRequest(t *testing.T) {
+	key, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: "request"},
+		DNSNames: []string{"request.example.com"},
+	}
+	tbs, sigAlg, err := CreateTBSCertificateRequest(template, key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := key.Sign(rand.Reader, tbs, sm2.DefaultSM2SignerOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := AssembleCertificateRequest(tbs, sigAlg, signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr, err := ParseCertificateRequest(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		t.Fatal(err)
+	}
+	if len(csr.DNSNames) != 1 || csr.DNSNames[0] != "request.example.com" {
+		t.Errorf("unexpected DNS names %v", csr.DNSNames)
+	}
+
+	otherKey, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signature, err = otherKey.Sign(rand.Reader, tbs, sm2.DefaultSM2SignerOpts); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AssembleCertificateRequest(tbs, sigAlg, signature); err == nil {
+		t.Error("expected error with a signature of another key")
+	}
+}
+
+func TestAssembleRevocationList(t *testing.T) {
+	caKey, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Offline SM2 Root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              KeyUsageCertSign | KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := CreateCertificate(rand.Reader, caTemplate, caTemplate, caKey.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.RevocationList{
+		Number:     big.NewInt(7),
+		ThisUpdate: time.Now(),
+		NextUpdate: time.Now().Add(24 * time.Hour),
+		RevokedCertificateEntries: []x509.RevocationListEntry{
+			{SerialNumber: big.NewInt(2), RevocationTime: time.Now(), ReasonCode: 1},
+		},
+	}
+	tbs, sigAlg, err := CreateTBSRevocationList(template, ca)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := caKey.Sign(rand.Reader, tbs, sm2.DefaultSM2SignerOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := AssembleRevocationList(tbs, sigAlg, signature, ca)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rl, err := ParseRevocationList(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rl.CheckSignatureFrom(ca); err != nil {
+		t.Fatal(err)
+	}
+	if len(rl.RevokedCertificateEntries) != 1 || rl.Number.Cmp(template.Number) != 0 {
+		t.Errorf("unexpected CRL %v %v", rl.Number, rl.RevokedCertificateEntries)
+	}
+
+	if _, err := AssembleRevocationList(tbs, sigAlg, signature[:len(signature)-1], ca); err == nil {
+		t.Error("expected error with a truncated signature")
+	}
+}
@@ -0,0 +1,79 @@
+package smx509
+
+import (
+	"encoding/asn1"
+	"errors"
+
+	"golang.org/x/crypto/cryptobyte"
+	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+// RFC 7633 the TLS feature extension.
+var oidExtensionTLSFeature = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 24}
+
+// TLSFeatureStatusRequest is the status_request TLS extension, RFC 6066, 8,
+// which as a TLS feature requires OCSP stapling.
+const TLSFeatureStatusRequest = 5
+
+// TLSFeatures returns the TLS extension types of the TLS feature extension of
+// c, or nil if it has none.
+func (c *Certificate) TLSFeatures() ([]int, error) {
+	for _, e := range c.Extensions {
+		if e.Id.Equal(oidExtensionTLSFeature) {
+			return parseTLSFeatures(e.Value)
+		}
+	}
+	return nil, nil
+}
+
+// RequiresOCSPStaple reports whether c has the status_request TLS feature, in
+// which case a TLS server must staple an OCSP response. A malformed TLS
+// feature extension is taken as requiring it.
+func (c *Certificate) RequiresOCSPStaple() bool {
+	for _, e := range c.Extensions {
+		if !e.Id.Equal(oidExtensionTLSFeature) {
+			continue
+		}
+		features, err := parseTLSFeatures(e.Value)
+		if err != nil {
+			return true
+		}
+		for _, feature := range features {
+			if feature == TLSFeatureStatusRequest {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func parseTLSFeatures(der cryptobyte.String) ([]int, error) {
+	// Features ::= SEQUENCE OF INTEGER
+	var seq cryptobyte.String
+	if !der.ReadASN1(&seq, cryptobyte_asn1.SEQUENCE) || !der.Empty() {
+		return nil, errors.New("x509: invalid TLS feature extension")
+	}
+	features := []int{}
+	for !seq.Empty() {
+		var feature int
+		if !seq.ReadASN1Integer(&feature) || feature < 0 || feature > 0xffff {
+			return nil, errors.New("x509: invalid TLS feature extension")
+		}
+		features = append(features, feature)
+	}
+	return features, nil
+}
+
+func marshalTLSFeatures(features []int) ([]byte, error) {
+	var b cryptobyte.Builder
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		for _, feature := range features {
+			if feature < 0 || feature > 0xffff {
+				b.SetError(errors.New("x509: invalid TLS feature"))
+				return
+			}
+			b.AddASN1Int64(int64(feature))
+		}
+	})
+	return b.Bytes()
+}
@@ -0,0 +1,101 @@
+package smx509
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yunmoon/gmsm/sm2"
+)
+
+func TestTLSFeatures(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"example.com"},
+	}
+	create := func(opts *CreateCertificateOptions) *Certificate {
+		t.Helper()
+		der, err := CreateCertificateWithOptions(rand.Reader, template, template, priv.Public(), priv, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, err := ParseCertificate(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert
+	}
+
+	cert := create(&CreateCertificateOptions{TLSFeatures: []int{TLSFeatureStatusRequest, 17}})
+	features, err := cert.TLSFeatures()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(features, []int{5, 17}) {
+		t.Errorf("unexpected TLS features %v", features)
+	}
+	if !cert.RequiresOCSPStaple() {
+		t.Error("expected the certificate to require OCSP stapling")
+	}
+	for _, e := range cert.Extensions {
+		if e.Id.Equal(oidExtensionTLSFeature) {
+			if e.Critical || !bytes.Equal(e.Value, []byte{0x30, 0x06, 0x02, 0x01, 0x05, 0x02, 0x01, 0x11}) {
+				t.Errorf("unexpected TLS feature extension %v %x", e.Critical, e.Value)
+			}
+		}
+	}
+
+	cert = create(nil)
+	if features, err := cert.TLSFeatures(); err != nil || features != nil {
+		t.Errorf("unexpected TLS features %v: %v", features, err)
+	}
+	if cert.RequiresOCSPStaple() {
+		t.Error("unexpected OCSP stapling requirement")
+	}
+
+	// ExtraExtensions takes precedence
+	template.ExtraExtensions = []pkix.Extension{{Id: oidExtensionTLSFeature, Value: []byte{0x30, 0x03, 0x02, 0x01, 0x11}}}
+	cert = create(&CreateCertificateOptions{TLSFeatures: []int{TLSFeatureStatusRequest}})
+	n := 0
+	for _, e := range cert.Extensions {
+		if e.Id.Equal(oidExtensionTLSFeature) {
+			n++
+		}
+	}
+	if n != 1 {
+		t.Errorf("expected one TLS feature extension, got %d", n)
+	}
+	if features, err := cert.TLSFeatures(); err != nil || !reflect.DeepEqual(features, []int{17}) {
+		t.Errorf("unexpected TLS features %v: %v", features, err)
+	}
+	if cert.RequiresOCSPStaple() {
+		t.Error("unexpected OCSP stapling requirement")
+	}
+
+	// malformed
+	template.ExtraExtensions = []pkix.Extension{{Id: oidExtensionTLSFeature, Value: []byte{0x30, 0x03, 0x04, 0x01, 0x05}}}
+	cert = create(nil)
+	if _, err := cert.TLSFeatures(); err == nil {
+		t.Error("expected error with a malformed TLS feature extension")
+	}
+	if !cert.RequiresOCSPStaple() {
+		t.Error("expected a malformed TLS feature extension to require OCSP stapling")
+	}
+
+	template.ExtraExtensions = nil
+	if _, err := CreateCertificateWithOptions(rand.Reader, template, template, priv.Public(), priv, &CreateCertificateOptions{TLSFeatures: []int{-1}}); err == nil {
+		t.Error("expected error with an invalid TLS feature")
+	}
+}
@@ -3,18 +3,23 @@ package smx509
 import (
 	"bytes"
 	"crypto"
+	"crypto/ecdsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"errors"
 	"fmt"
+	"maps"
 	"net"
 	"net/netip"
 	"net/url"
 	"reflect"
 	"runtime"
+	"slices"
 	"strings"
 	"time"
 	"unicode/utf8"
+
+	"github.com/yunmoon/gmsm/sm2"
 )
 
 const (
@@ -28,6 +33,11 @@ const (
 	UnconstrainedName             = x509.UnconstrainedName
 	TooManyConstraints            = x509.TooManyConstraints
 	CANotAuthorizedForExtKeyUsage = x509.CANotAuthorizedForExtKeyUsage
+	// NotSMAlgorithm results when VerifyOptions.RequireSMAlgorithms is set and
+	// a certificate in the chain has a non SM2 public key or a signature other
+	// than SM2WithSM3. CertificateInvalidError.Error reports it as an unknown
+	// error, the Detail field names the certificate and its algorithm.
+	NotSMAlgorithm = NoValidChains + 1
 )
 
 type CertificateInvalidError = x509.CertificateInvalidError
@@ -92,6 +102,69 @@ type VerifyOptions struct {
 	// certificates from consuming excessive amounts of CPU time when
 	// validating. It does not apply to the platform verifier.
 	MaxConstraintComparisions int
+
+	// ForceGoVerifier makes Verify always build and check the chains itself,
+	// instead of using the platform verifier, which on Windows does not
+	// support SM2 signatures. The platform verifier is used otherwise when
+	// Roots is nil or was returned by SystemCertPool. Note that the system
+	// roots of Windows are not available to the Go verifier.
+	ForceGoVerifier bool
+
+	// CertificatePolicies specifies which certificate policy OIDs are
+	// acceptable during policy validation. An empty CertificatePolicies
+	// field implies any valid policy is acceptable.
+	//
+	// As per RFC 5280, Section 6.1, the policies are only enforced when an
+	// explicit policy is required, by RequireExplicitPolicy or by the
+	// policy constraints of a certificate in the chain.
+	CertificatePolicies []x509.OID
+
+	// RequireExplicitPolicy, InhibitPolicyMapping and InhibitAnyPolicy are
+	// the initial-explicit-policy, initial-policy-mapping-inhibit and
+	// initial-any-policy-inhibit inputs of RFC 5280, Section 6.1.1.
+	//
+	// RequireExplicitPolicy requires every chain to be valid for at least one
+	// of CertificatePolicies, or for any policy if CertificatePolicies is empty.
+	RequireExplicitPolicy bool
+	// InhibitPolicyMapping disables the policy mappings of the certificates.
+	InhibitPolicyMapping bool
+	// InhibitAnyPolicy makes the anyPolicy OID not match other policies.
+	InhibitAnyPolicy bool
+
+	// EnforceEKUNesting requires every certificate in the chain which has an
+	// extended key usage extension to allow each of KeyUsages, or
+	// ExtKeyUsageAny. By default, a chain is accepted if at least one of
+	// KeyUsages is allowed by all its certificates.
+	EnforceEKUNesting bool
+
+	// MaxChainDepth is the maximum number of certificates in a chain,
+	// including the leaf and the root. The chain builder abandons longer
+	// candidate paths, so that pathological pools of cross-signed
+	// intermediates don't consume excessive amounts of CPU time. If no
+	// chain fits, an UnknownAuthorityError is returned. If zero, the depth
+	// is not limited. It does not apply to the platform verifier.
+	MaxChainDepth int
+
+	// ClockSkew widens the validity period of every certificate in the chain
+	// on both ends, to tolerate clocks which are a few minutes off, see
+	// Certificate.IsValidAt. It does not apply to the platform verifier.
+	ClockSkew time.Duration
+
+	// RequireSMAlgorithms rejects the chains in which any certificate, the
+	// root included, has a non SM2 public key or a signature other than
+	// SM2WithSM3. If no chain is left, a CertificateInvalidError with reason
+	// NotSMAlgorithm is returned.
+	RequireSMAlgorithms bool
+	// AllowNonSMRootSignature exempts the self-signature of the root from
+	// RequireSMAlgorithms, the root must still have an SM2 public key.
+	AllowNonSMRootSignature bool
+
+	// IgnoreUnhandledNameConstraints accepts the subject alternative names
+	// of the types, such as otherName, which are constrained by a CA of the
+	// chain but whose constraints can't be evaluated. By default, such a
+	// name makes the chain invalid with reason CANotAuthorizedForThisName,
+	// as RFC 5280 requires when the name constraints extension is critical.
+	IgnoreUnhandledNameConstraints bool
 }
 
 const (
@@ -469,18 +542,8 @@ func (c *Certificate) isValid(certType int, currentChain []*Certificate, opts *V
 	if now.IsZero() {
 		now = time.Now()
 	}
-	if now.Before(c.NotBefore) {
-		return CertificateInvalidError{
-			Cert:   c.asX509(),
-			Reason: Expired,
-			Detail: fmt.Sprintf("current time %s is before %s", now.Format(time.RFC3339), c.NotBefore.Format(time.RFC3339)),
-		}
-	} else if now.After(c.NotAfter) {
-		return CertificateInvalidError{
-			Cert:   c.asX509(),
-			Reason: Expired,
-			Detail: fmt.Sprintf("current time %s is after %s", now.Format(time.RFC3339), c.NotAfter.Format(time.RFC3339)),
-		}
+	if err := c.checkValidity(now, opts.ClockSkew); err != nil {
+		return err
 	}
 
 	maxConstraintComparisons := opts.MaxConstraintComparisions
@@ -503,7 +566,12 @@ func (c *Certificate) isValid(certType int, currentChain []*Certificate, opts *V
 				toCheck = append(toCheck, c)
 			}
 		}
+		otherConstraints, err := certOtherNameConstraints(c)
+		if err != nil {
+			return err
+		}
 		for _, sanCert := range toCheck {
+			var unhandledTypes []string
 			err := forEachSAN(sanCert.getSANExtension(), func(tag int, data []byte) error {
 				switch tag {
 				case nameTypeEmail:
@@ -560,8 +628,28 @@ func (c *Certificate) isValid(certType int, currentChain []*Certificate, opts *V
 						return err
 					}
 
+				case nameTypeRegisteredID:
+					id, ok := newOIDFromDER(data)
+					if !ok {
+						return fmt.Errorf("x509: cannot parse registeredID %x", data)
+					}
+
+					if err := c.checkNameConstraints(&comparisonCount, maxConstraintComparisons, "registeredID", id.String(), id,
+						func(parsedName, constraint any) (bool, error) {
+							return parsedName.(x509.OID).Equal(constraint.(x509.OID)), nil
+						}, otherConstraints.permittedRegisteredIDs, otherConstraints.excludedRegisteredIDs); err != nil {
+						return err
+					}
+
 				default:
-					// Unknown SAN types are ignored.
+					// Unknown SAN types are ignored, unless they are
+					// constrained by c.
+					if slices.Contains(otherConstraints.unhandled, tag) && !opts.IgnoreUnhandledNameConstraints {
+						name := unhandledNameTypeString(tag)
+						if !slices.Contains(unhandledTypes, name) {
+							unhandledTypes = append(unhandledTypes, name)
+						}
+					}
 				}
 
 				return nil
@@ -570,6 +658,13 @@ func (c *Certificate) isValid(certType int, currentChain []*Certificate, opts *V
 			if err != nil {
 				return err
 			}
+			if len(unhandledTypes) > 0 {
+				return CertificateInvalidError{
+					Cert:   c.asX509(),
+					Reason: CANotAuthorizedForThisName,
+					Detail: fmt.Sprintf("cannot check the %s names against the name constraints", strings.Join(unhandledTypes, ", ")),
+				}
+			}
 		}
 	}
 
@@ -594,8 +689,15 @@ func (c *Certificate) isValid(certType int, currentChain []*Certificate, opts *V
 		return CertificateInvalidError{Cert: c.asX509(), Reason: NotAuthorizedToSign, Detail: ""}
 	}
 
-	if c.BasicConstraintsValid && c.MaxPathLen >= 0 {
-		numIntermediates := len(currentChain) - 1
+	if c.BasicConstraintsValid && c.MaxPathLen >= 0 && len(currentChain) > 0 {
+		// RFC 5280, Section 6.1.4 (l): self-issued intermediates don't count
+		// towards the path length.
+		numIntermediates := 0
+		for _, intermediate := range currentChain[1:] {
+			if !intermediate.isSelfIssued() {
+				numIntermediates++
+			}
+		}
 		if numIntermediates > c.MaxPathLen {
 			return CertificateInvalidError{Cert: c.asX509(), Reason: TooManyIntermediates, Detail: ""}
 		}
@@ -604,6 +706,37 @@ func (c *Certificate) isValid(certType int, currentChain []*Certificate, opts *V
 	return nil
 }
 
+// IsValidAt reports whether t is within the validity period of c, widened by
+// skew on both ends: from NotBefore-skew to NotAfter+skew inclusive.
+func (c *Certificate) IsValidAt(t time.Time, skew time.Duration) bool {
+	return c.checkValidity(t, skew) == nil
+}
+
+func (c *Certificate) checkValidity(now time.Time, skew time.Duration) error {
+	if now.Before(c.NotBefore.Add(-skew)) {
+		return CertificateInvalidError{
+			Cert:   c.asX509(),
+			Reason: Expired,
+			Detail: fmt.Sprintf("current time %s is before %s", now.Format(time.RFC3339), c.NotBefore.Format(time.RFC3339)),
+		}
+	} else if now.After(c.NotAfter.Add(skew)) {
+		return CertificateInvalidError{
+			Cert:   c.asX509(),
+			Reason: Expired,
+			Detail: fmt.Sprintf("current time %s is after %s", now.Format(time.RFC3339), c.NotAfter.Format(time.RFC3339)),
+		}
+	}
+	return nil
+}
+
+// usePlatformVerifier and platformVerify select the platform verifier Verify
+// uses for the system roots. They are variables so that the tests can check
+// when the platform verifier is used.
+var (
+	usePlatformVerifier = runtime.GOOS == "windows"
+	platformVerify      = (*Certificate).systemVerify
+)
+
 // Verify attempts to verify c by building one or more chains from c to a
 // certificate in opts.Roots, using certificates in opts.Intermediates if
 // needed. If successful, it returns one or more chains where the first
@@ -617,7 +750,9 @@ func (c *Certificate) isValid(certType int, currentChain []*Certificate, opts *V
 // in the chain, not just opts.DNSName. Thus it is invalid for a leaf to claim
 // example.com if an intermediate doesn't permit it, even if example.com is not
 // the name being validated. Note that DirectoryName constraints are not
-// supported.
+// supported. RegisteredID constraints are matched exactly. Names of the other
+// types, such as otherName, are rejected if their type is constrained, see
+// VerifyOptions.IgnoreUnhandledNameConstraints.
 //
 // Name constraint validation follows the rules from RFC 5280, with the
 // addition that DNS name constraints may use the leading period format
@@ -630,6 +765,10 @@ func (c *Certificate) isValid(certType int, currentChain []*Certificate, opts *V
 // list. (While this is not specified, it is common practice in order to limit
 // the types of certificates a CA can issue.)
 //
+// All valid chains are returned, ordered as by SortChains: shortest chains
+// first and, among chains of the same length, those made only of SM2
+// certificates before the others.
+//
 // Certificates other than c in the returned chains should not be modified.
 //
 // WARNING: this function doesn't do any revocation checking.
@@ -650,15 +789,15 @@ func (c *Certificate) Verify(opts VerifyOptions) (chains [][]*Certificate, err e
 	}
 
 	// Use platform verifiers, where available, if Roots is from SystemCertPool.
-	if runtime.GOOS == "windows" {
+	if usePlatformVerifier && !opts.ForceGoVerifier {
 		// Don't use the system verifier if the system pool was replaced with a non-system pool,
 		// i.e. if SetFallbackRoots was called with x509usefallbackroots=1.
 		systemPool := systemRootsPool()
 		if opts.Roots == nil && (systemPool == nil || systemPool.systemPool) {
-			return c.systemVerify(&opts)
+			return platformVerify(c, &opts)
 		}
 		if opts.Roots != nil && opts.Roots.systemPool {
-			platformChains, err := c.systemVerify(&opts)
+			platformChains, err := platformVerify(c, &opts)
 			// If the platform verifier succeeded, or there are no additional
 			// roots, return the platform verifier result. Otherwise, continue
 			// with the Go verifier.
@@ -697,32 +836,95 @@ func (c *Certificate) Verify(opts VerifyOptions) (chains [][]*Certificate, err e
 		}
 	}
 
-	if len(opts.KeyUsages) == 0 {
-		opts.KeyUsages = []ExtKeyUsage{ExtKeyUsageServerAuth}
-	}
-
+	anyKeyUsage := false
 	for _, eku := range opts.KeyUsages {
 		if eku == ExtKeyUsageAny {
-			// If any key usage is acceptable, no need to check the chain for
-			// key usages.
-			return candidateChains, nil
+			// The presence of anyExtendedKeyUsage overrides any other key usage.
+			anyKeyUsage = true
+			break
 		}
 	}
 
+	if len(opts.KeyUsages) == 0 {
+		opts.KeyUsages = []ExtKeyUsage{ExtKeyUsageServerAuth}
+	}
+
+	var invalidPoliciesChains int
+	var incompatibleKeyUsageChains int
+	var nonSMChains int
+	var nonSMErr error
 	chains = make([][]*Certificate, 0, len(candidateChains))
 	for _, candidate := range candidateChains {
-		if checkChainForKeyUsage(candidate, opts.KeyUsages) {
-			chains = append(chains, candidate)
+		if opts.RequireSMAlgorithms {
+			if err := checkChainSMAlgorithms(candidate, &opts); err != nil {
+				if nonSMErr == nil {
+					nonSMErr = err
+				}
+				nonSMChains++
+				continue
+			}
+		}
+		if !policiesValid(candidate, opts) {
+			invalidPoliciesChains++
+			continue
 		}
+		// If any key usage is acceptable, no need to check the chain for
+		// key usages.
+		if !anyKeyUsage && (!checkChainForKeyUsage(candidate, opts.KeyUsages) ||
+			opts.EnforceEKUNesting && !checkChainForAllKeyUsages(candidate, opts.KeyUsages)) {
+			incompatibleKeyUsageChains++
+			continue
+		}
+		chains = append(chains, candidate)
 	}
 
 	if len(chains) == 0 {
-		return nil, CertificateInvalidError{Cert: c.asX509(), Reason: IncompatibleUsage, Detail: ""}
+		var details []string
+		if incompatibleKeyUsageChains > 0 {
+			if invalidPoliciesChains == 0 && nonSMChains == 0 {
+				return nil, CertificateInvalidError{Cert: c.asX509(), Reason: IncompatibleUsage, Detail: ""}
+			}
+			details = append(details, fmt.Sprintf("%d candidate chains with incompatible key usage", incompatibleKeyUsageChains))
+		}
+		if invalidPoliciesChains > 0 {
+			details = append(details, fmt.Sprintf("%d candidate chains with invalid policies", invalidPoliciesChains))
+		}
+		if nonSMChains > 0 {
+			if incompatibleKeyUsageChains == 0 && invalidPoliciesChains == 0 {
+				return nil, nonSMErr
+			}
+			details = append(details, fmt.Sprintf("%d candidate chains with non SM algorithms", nonSMChains))
+		}
+		return nil, CertificateInvalidError{Cert: c.asX509(), Reason: NoValidChains, Detail: strings.Join(details, ", ")}
 	}
 
+	SortChains(chains)
 	return chains, nil
 }
 
+// SortChains sorts chains in place, shortest chains first. Among chains of
+// the same length, those whose certificates all have SM2 public keys and
+// SM2WithSM3 signatures come first. The sort is stable, so chains that
+// compare equal keep their relative order.
+func SortChains(chains [][]*Certificate) {
+	slices.SortStableFunc(chains, compareChains)
+}
+
+func compareChains(a, b []*Certificate) int {
+	if n := len(a) - len(b); n != 0 {
+		return n
+	}
+	smA := checkChainSMAlgorithms(a, &VerifyOptions{}) == nil
+	smB := checkChainSMAlgorithms(b, &VerifyOptions{}) == nil
+	switch {
+	case smA && !smB:
+		return -1
+	case !smA && smB:
+		return 1
+	}
+	return 0
+}
+
 func appendToFreshChain(chain []*Certificate, cert *Certificate) []*Certificate {
 	n := make([]*Certificate, len(chain)+1)
 	copy(n, chain)
@@ -780,6 +982,8 @@ func alreadyInChain(candidate *Certificate, chain []*Certificate) bool {
 // for failed checks due to different intermediates having the same Subject.
 const maxChainSignatureChecks = 100
 
+var errChainTooDeep = errors.New("x509: chain depth limit reached")
+
 func (c *Certificate) buildChains(currentChain []*Certificate, sigChecks *int, opts *VerifyOptions) (chains [][]*Certificate, err error) {
 	var (
 		hintErr  error
@@ -791,6 +995,19 @@ func (c *Certificate) buildChains(currentChain []*Certificate, sigChecks *int, o
 			return
 		}
 
+		// an intermediate needs at least a root above it
+		depth := len(currentChain) + 1
+		if certType == intermediateCertificate {
+			depth++
+		}
+		if opts.MaxChainDepth > 0 && depth > opts.MaxChainDepth {
+			if hintErr == nil {
+				hintErr = errChainTooDeep
+				hintCert = candidate.cert
+			}
+			return
+		}
+
 		if sigChecks == nil {
 			sigChecks = new(int)
 		}
@@ -800,7 +1017,7 @@ func (c *Certificate) buildChains(currentChain []*Certificate, sigChecks *int, o
 			return
 		}
 
-		if err := c.CheckSignatureFrom(candidate.cert); err != nil {
+		if err := c.checkSignatureFrom(candidate.cert, nil); err != nil {
 			if hintErr == nil {
 				hintErr = err
 				hintCert = candidate.cert
@@ -1079,6 +1296,55 @@ NextCert:
 	return true
 }
 
+// checkChainSMAlgorithms checks that every certificate in chain has an SM2
+// public key and an SM2WithSM3 signature, see VerifyOptions.RequireSMAlgorithms.
+func checkChainSMAlgorithms(chain []*Certificate, opts *VerifyOptions) error {
+	for i, cert := range chain {
+		if pub, ok := cert.PublicKey.(*ecdsa.PublicKey); !ok || pub.Curve != sm2.P256() {
+			return CertificateInvalidError{
+				Cert:   cert.asX509(),
+				Reason: NotSMAlgorithm,
+				Detail: fmt.Sprintf("certificate %d in the chain has a non SM2 %v public key", i, PublicKeyAlgorithmString(cert.PublicKeyAlgorithm)),
+			}
+		}
+		isRoot := i == len(chain)-1 && cert.isSelfIssued()
+		if cert.SignatureAlgorithm != SM2WithSM3 && !(isRoot && opts.AllowNonSMRootSignature) {
+			return CertificateInvalidError{
+				Cert:   cert.asX509(),
+				Reason: NotSMAlgorithm,
+				Detail: fmt.Sprintf("certificate %d in the chain is signed with %s, not SM2-SM3", i, signatureAlgorithmName(cert.SignatureAlgorithm)),
+			}
+		}
+	}
+	return nil
+}
+
+// checkChainForAllKeyUsages reports whether each of keyUsages is allowed by
+// every certificate in chain with an extended key usage extension.
+func checkChainForAllKeyUsages(chain []*Certificate, keyUsages []ExtKeyUsage) bool {
+NextCert:
+	for _, cert := range chain {
+		if len(cert.ExtKeyUsage) == 0 && len(cert.UnknownExtKeyUsage) == 0 {
+			continue
+		}
+		for _, usage := range cert.ExtKeyUsage {
+			if usage == ExtKeyUsageAny {
+				continue NextCert
+			}
+		}
+	NextRequestedUsage:
+		for _, requestedUsage := range keyUsages {
+			for _, usage := range cert.ExtKeyUsage {
+				if requestedUsage == usage {
+					continue NextRequestedUsage
+				}
+			}
+			return false
+		}
+	}
+	return true
+}
+
 func mustNewOIDFromInts(ints []uint64) x509.OID {
 	oid, err := x509.OIDFromInts(ints)
 	if err != nil {
@@ -1086,3 +1352,383 @@ func mustNewOIDFromInts(ints []uint64) x509.OID {
 	}
 	return oid
 }
+
+// oidKey returns the DER encoding of oid as a map key.
+func oidKey(oid x509.OID) string {
+	return string(getDer(&oid))
+}
+
+// oidFromKey is the inverse of oidKey.
+func oidFromKey(key string) x509.OID {
+	var oid x509.OID
+	setDer(&oid, []byte(key))
+	return oid
+}
+
+type policyGraphNode struct {
+	validPolicy       x509.OID
+	expectedPolicySet []x509.OID
+	// we do not implement qualifiers, so we don't track qualifier_set
+
+	parents  map[*policyGraphNode]bool
+	children map[*policyGraphNode]bool
+}
+
+func newPolicyGraphNode(valid x509.OID, parents []*policyGraphNode) *policyGraphNode {
+	n := &policyGraphNode{
+		validPolicy:       valid,
+		expectedPolicySet: []x509.OID{valid},
+		children:          map[*policyGraphNode]bool{},
+		parents:           map[*policyGraphNode]bool{},
+	}
+	for _, p := range parents {
+		p.children[n] = true
+		n.parents[p] = true
+	}
+	return n
+}
+
+type policyGraph struct {
+	strata []map[string]*policyGraphNode
+	// map of OID -> nodes at strata[depth-1] with OID in their expectedPolicySet
+	parentIndex map[string][]*policyGraphNode
+	depth       int
+}
+
+var anyPolicyOID = mustNewOIDFromInts([]uint64{2, 5, 29, 32, 0})
+
+func newPolicyGraph() *policyGraph {
+	root := policyGraphNode{
+		validPolicy:       anyPolicyOID,
+		expectedPolicySet: []x509.OID{anyPolicyOID},
+		children:          map[*policyGraphNode]bool{},
+		parents:           map[*policyGraphNode]bool{},
+	}
+	return &policyGraph{
+		depth:  0,
+		strata: []map[string]*policyGraphNode{{oidKey(anyPolicyOID): &root}},
+	}
+}
+
+func (pg *policyGraph) insert(n *policyGraphNode) {
+	pg.strata[pg.depth][oidKey(n.validPolicy)] = n
+}
+
+func (pg *policyGraph) parentsWithExpected(expected x509.OID) []*policyGraphNode {
+	if pg.depth == 0 {
+		return nil
+	}
+	return pg.parentIndex[oidKey(expected)]
+}
+
+func (pg *policyGraph) parentWithAnyPolicy() *policyGraphNode {
+	if pg.depth == 0 {
+		return nil
+	}
+	return pg.strata[pg.depth-1][oidKey(anyPolicyOID)]
+}
+
+func (pg *policyGraph) parents() map[string]*policyGraphNode {
+	if pg.depth == 0 {
+		return nil
+	}
+	return pg.strata[pg.depth-1]
+}
+
+func (pg *policyGraph) leaves() map[string]*policyGraphNode {
+	return pg.strata[pg.depth]
+}
+
+func (pg *policyGraph) leafWithPolicy(policy x509.OID) *policyGraphNode {
+	return pg.strata[pg.depth][oidKey(policy)]
+}
+
+func (pg *policyGraph) deleteLeaf(policy x509.OID) {
+	n := pg.strata[pg.depth][oidKey(policy)]
+	if n == nil {
+		return
+	}
+	for p := range n.parents {
+		delete(p.children, n)
+	}
+	for c := range n.children {
+		delete(c.parents, n)
+	}
+	delete(pg.strata[pg.depth], oidKey(policy))
+}
+
+func (pg *policyGraph) validPolicyNodes() []*policyGraphNode {
+	var validNodes []*policyGraphNode
+	for i := pg.depth; i >= 0; i-- {
+		for _, n := range pg.strata[i] {
+			if n.validPolicy.Equal(anyPolicyOID) {
+				continue
+			}
+
+			if len(n.parents) == 1 {
+				for p := range n.parents {
+					if p.validPolicy.Equal(anyPolicyOID) {
+						validNodes = append(validNodes, n)
+					}
+				}
+			}
+		}
+	}
+	return validNodes
+}
+
+func (pg *policyGraph) prune() {
+	for i := pg.depth - 1; i > 0; i-- {
+		for _, n := range pg.strata[i] {
+			if len(n.children) == 0 {
+				for p := range n.parents {
+					delete(p.children, n)
+				}
+				delete(pg.strata[i], oidKey(n.validPolicy))
+			}
+		}
+	}
+}
+
+func (pg *policyGraph) incrDepth() {
+	pg.parentIndex = map[string][]*policyGraphNode{}
+	for _, n := range pg.strata[pg.depth] {
+		for _, e := range n.expectedPolicySet {
+			pg.parentIndex[oidKey(e)] = append(pg.parentIndex[oidKey(e)], n)
+		}
+	}
+
+	pg.depth++
+	pg.strata = append(pg.strata, map[string]*policyGraphNode{})
+}
+
+func policiesValid(chain []*Certificate, opts VerifyOptions) bool {
+	// The following code implements the policy verification algorithm as
+	// specified in RFC 5280 and updated by RFC 9618. In particular the
+	// following sections are replaced by RFC 9618:
+	//	* 6.1.2 (a)
+	//	* 6.1.3 (d)
+	//	* 6.1.3 (e)
+	//	* 6.1.3 (f)
+	//	* 6.1.4 (b)
+	//	* 6.1.5 (g)
+
+	if len(chain) == 1 {
+		return true
+	}
+
+	// n is the length of the chain minus the trust anchor
+	n := len(chain) - 1
+
+	constraints := make([]*policyConstraints, len(chain))
+	for i, cert := range chain {
+		pc, err := certPolicyConstraints(cert)
+		if err != nil {
+			return false
+		}
+		constraints[i] = pc
+	}
+
+	pg := newPolicyGraph()
+	var inhibitAnyPolicy, explicitPolicy, policyMapping int
+	if !opts.InhibitAnyPolicy {
+		inhibitAnyPolicy = n + 1
+	}
+	if !opts.RequireExplicitPolicy {
+		explicitPolicy = n + 1
+	}
+	if !opts.InhibitPolicyMapping {
+		policyMapping = n + 1
+	}
+
+	initialUserPolicySet := map[string]bool{}
+	for _, p := range opts.CertificatePolicies {
+		initialUserPolicySet[oidKey(p)] = true
+	}
+	// If the user does not pass any policies, we consider
+	// that equivalent to passing anyPolicyOID.
+	if len(initialUserPolicySet) == 0 {
+		initialUserPolicySet[oidKey(anyPolicyOID)] = true
+	}
+
+	for i := n - 1; i >= 0; i-- {
+		cert, pc := chain[i], constraints[i]
+
+		isSelfSigned := cert.isSelfIssued()
+
+		// 6.1.3 (e) -- as updated by RFC 9618
+		if len(cert.Policies) == 0 {
+			pg = nil
+		}
+
+		// 6.1.3 (f) -- as updated by RFC 9618
+		if explicitPolicy == 0 && pg == nil {
+			return false
+		}
+
+		if pg != nil {
+			pg.incrDepth()
+
+			policies := map[string]bool{}
+
+			// 6.1.3 (d) (1) -- as updated by RFC 9618
+			for _, policy := range cert.Policies {
+				policies[oidKey(policy)] = true
+
+				if policy.Equal(anyPolicyOID) {
+					continue
+				}
+
+				// 6.1.3 (d) (1) (i) -- as updated by RFC 9618
+				parents := pg.parentsWithExpected(policy)
+				if len(parents) == 0 {
+					// 6.1.3 (d) (1) (ii) -- as updated by RFC 9618
+					if anyParent := pg.parentWithAnyPolicy(); anyParent != nil {
+						parents = []*policyGraphNode{anyParent}
+					}
+				}
+				if len(parents) > 0 {
+					pg.insert(newPolicyGraphNode(policy, parents))
+				}
+			}
+
+			// 6.1.3 (d) (2) -- as updated by RFC 9618
+			// NOTE: in the check "n-i < n" our i is different from the i in the specification.
+			// In the specification chains go from the trust anchor to the leaf, whereas our
+			// chains go from the leaf to the trust anchor, so our i's our inverted. Our
+			// check here matches the check "i < n" in the specification.
+			if policies[oidKey(anyPolicyOID)] && (inhibitAnyPolicy > 0 || (n-i < n && isSelfSigned)) {
+				missing := map[string][]*policyGraphNode{}
+				leaves := pg.leaves()
+				for _, p := range pg.parents() {
+					for _, expected := range p.expectedPolicySet {
+						if leaves[oidKey(expected)] == nil {
+							missing[oidKey(expected)] = append(missing[oidKey(expected)], p)
+						}
+					}
+				}
+
+				for oidStr, parents := range missing {
+					pg.insert(newPolicyGraphNode(oidFromKey(oidStr), parents))
+				}
+			}
+
+			// 6.1.3 (d) (3) -- as updated by RFC 9618
+			pg.prune()
+
+			if i != 0 {
+				// 6.1.4 (b) -- as updated by RFC 9618
+				if len(pc.mappings) > 0 {
+					// collect map of issuer -> []subject
+					mappings := map[string][]x509.OID{}
+
+					for _, mapping := range pc.mappings {
+						if policyMapping > 0 {
+							if mapping.issuerDomainPolicy.Equal(anyPolicyOID) || mapping.subjectDomainPolicy.Equal(anyPolicyOID) {
+								// Invalid mapping
+								return false
+							}
+							key := oidKey(mapping.issuerDomainPolicy)
+							mappings[key] = append(mappings[key], mapping.subjectDomainPolicy)
+						} else {
+							// 6.1.4 (b) (3) (i) -- as updated by RFC 9618
+							pg.deleteLeaf(mapping.issuerDomainPolicy)
+						}
+					}
+
+					// 6.1.4 (b) (3) (ii) -- as updated by RFC 9618
+					pg.prune()
+
+					for issuerStr, subjectPolicies := range mappings {
+						// 6.1.4 (b) (1) -- as updated by RFC 9618
+						if matching := pg.leafWithPolicy(oidFromKey(issuerStr)); matching != nil {
+							matching.expectedPolicySet = subjectPolicies
+						} else if matching := pg.leafWithPolicy(anyPolicyOID); matching != nil {
+							// 6.1.4 (b) (2) -- as updated by RFC 9618
+							n := newPolicyGraphNode(oidFromKey(issuerStr), []*policyGraphNode{matching})
+							n.expectedPolicySet = subjectPolicies
+							pg.insert(n)
+						}
+					}
+				}
+			}
+		}
+
+		if i != 0 {
+			// 6.1.4 (h)
+			if !isSelfSigned {
+				if explicitPolicy > 0 {
+					explicitPolicy--
+				}
+				if policyMapping > 0 {
+					policyMapping--
+				}
+				if inhibitAnyPolicy > 0 {
+					inhibitAnyPolicy--
+				}
+			}
+
+			// 6.1.4 (i)
+			if pc.requireExplicitPolicy >= 0 && pc.requireExplicitPolicy < explicitPolicy {
+				explicitPolicy = pc.requireExplicitPolicy
+			}
+			if pc.inhibitPolicyMapping >= 0 && pc.inhibitPolicyMapping < policyMapping {
+				policyMapping = pc.inhibitPolicyMapping
+			}
+			// 6.1.4 (j)
+			if pc.inhibitAnyPolicy >= 0 && pc.inhibitAnyPolicy < inhibitAnyPolicy {
+				inhibitAnyPolicy = pc.inhibitAnyPolicy
+			}
+		}
+	}
+
+	// 6.1.5 (a)
+	if explicitPolicy > 0 {
+		explicitPolicy--
+	}
+
+	// 6.1.5 (b)
+	if constraints[0].requireExplicitPolicy == 0 {
+		explicitPolicy = 0
+	}
+
+	// 6.1.5 (g) (1) -- as updated by RFC 9618
+	var validPolicyNodeSet []*policyGraphNode
+	// 6.1.5 (g) (2) -- as updated by RFC 9618
+	if pg != nil {
+		validPolicyNodeSet = pg.validPolicyNodes()
+		// 6.1.5 (g) (3) -- as updated by RFC 9618
+		if currentAny := pg.leafWithPolicy(anyPolicyOID); currentAny != nil {
+			validPolicyNodeSet = append(validPolicyNodeSet, currentAny)
+		}
+	}
+
+	// 6.1.5 (g) (4) -- as updated by RFC 9618
+	authorityConstrainedPolicySet := map[string]bool{}
+	for _, n := range validPolicyNodeSet {
+		authorityConstrainedPolicySet[oidKey(n.validPolicy)] = true
+	}
+	// 6.1.5 (g) (5) -- as updated by RFC 9618
+	userConstrainedPolicySet := maps.Clone(authorityConstrainedPolicySet)
+	// 6.1.5 (g) (6) -- as updated by RFC 9618
+	if len(initialUserPolicySet) != 1 || !initialUserPolicySet[oidKey(anyPolicyOID)] {
+		// 6.1.5 (g) (6) (i) -- as updated by RFC 9618
+		for p := range userConstrainedPolicySet {
+			if !initialUserPolicySet[p] {
+				delete(userConstrainedPolicySet, p)
+			}
+		}
+		// 6.1.5 (g) (6) (ii) -- as updated by RFC 9618
+		if authorityConstrainedPolicySet[oidKey(anyPolicyOID)] {
+			for policy := range initialUserPolicySet {
+				userConstrainedPolicySet[policy] = true
+			}
+		}
+	}
+
+	if explicitPolicy == 0 && len(userConstrainedPolicySet) == 0 {
+		return false
+	}
+
+	return true
+}
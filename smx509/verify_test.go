@@ -5,6 +5,7 @@ import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/asn1"
@@ -18,6 +19,10 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/yunmoon/gmsm/sm2"
+	"golang.org/x/crypto/cryptobyte"
+	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
 )
 
 type verifyTest struct {
@@ -538,8 +543,8 @@ func testVerify(t *testing.T, test verifyTest, useSystemRoots bool) {
 func TestGoVerify(t *testing.T) {
 	// Temporarily enable SHA-1 verification since a number of test chains
 	// require it. TODO(filippo): regenerate test chains.
-	defer func(old bool) { debugAllowSHA1 = old }(debugAllowSHA1)
-	debugAllowSHA1 = true
+	defer SetAllowSHA1(allowSHA1.Load())
+	SetAllowSHA1(true)
 
 	for _, test := range verifyTests {
 		t.Run(test.name, func(t *testing.T) {
@@ -1838,6 +1843,384 @@ func TestLongChain(t *testing.T) {
 	t.Logf("verification took %v", time.Since(start))
 }
 
+func TestMaxChainDepth(t *testing.T) {
+	newKey := func() *sm2.PrivateKey {
+		k, err := sm2.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+
+	// a deep chain: root, 10 intermediates and a leaf
+	roots, intermediates := NewCertPool(), NewCertPool()
+	parentKey := newKey()
+	parent := genCertEdge(t, "deep root", parentKey, nil, rootCertificate, nil, nil)
+	roots.AddCert(parent)
+	for i := range 10 {
+		key := newKey()
+		parent = genCertEdge(t, fmt.Sprintf("deep intermediate %d", i), key, nil, intermediateCertificate, parent, parentKey)
+		parentKey = key
+		intermediates.AddCert(parent)
+	}
+	leaf := genCertEdge(t, "deep leaf", newKey(), nil, leafCertificate, parent, parentKey)
+
+	opts := VerifyOptions{Roots: roots, Intermediates: intermediates, MaxChainDepth: 12}
+	chains, err := leaf.Verify(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chains) != 1 || len(chains[0]) != 12 {
+		t.Fatalf("unexpected chains %v", chainsToStrings(chains))
+	}
+	opts.MaxChainDepth = 11
+	if _, err := leaf.Verify(opts); !errors.As(err, new(UnknownAuthorityError)) {
+		t.Errorf("expected UnknownAuthorityError, got %v", err)
+	}
+	// a root counts as one certificate
+	opts.MaxChainDepth = 1
+	if _, err := chains[0][11].Verify(opts); err != nil {
+		t.Errorf("unexpected error verifying the root: %v", err)
+	}
+
+	// layers of cross-signed intermediates: each of the k keys of a layer
+	// has a certificate issued by each of the k keys of the layer above
+	const layers, k = 5, 3
+	roots, intermediates = NewCertPool(), NewCertPool()
+	rootKey := newKey()
+	root := genCertEdge(t, "cross root", rootKey, nil, rootCertificate, nil, nil)
+	roots.AddCert(root)
+	above := []*Certificate{root}
+	aboveKeys := []*sm2.PrivateKey{rootKey}
+	var layerKeys []*sm2.PrivateKey
+	for i := layers - 1; i >= 0; i-- {
+		var layer []*Certificate
+		layerKeys = nil
+		for range k {
+			key := newKey()
+			layerKeys = append(layerKeys, key)
+			for j, issuer := range above {
+				cert := genCertEdge(t, fmt.Sprintf("cross layer %d", i), key, nil, intermediateCertificate, issuer, aboveKeys[j])
+				intermediates.AddCert(cert)
+				layer = append(layer, cert)
+			}
+		}
+		above, aboveKeys = layer[:0:0], layerKeys
+		for j := range layerKeys {
+			// one certificate per key is enough to issue the layer below
+			above = append(above, layer[j*len(layer)/k])
+		}
+	}
+	leaf = genCertEdge(t, "cross leaf", newKey(), nil, leafCertificate, above[0], layerKeys[0])
+
+	// without a limit, the search goes on up to the signature checks budget
+	opts = VerifyOptions{Roots: roots, Intermediates: intermediates}
+	var sigChecks int
+	if _, err := leaf.buildChains([]*Certificate{leaf}, &sigChecks, &opts); err != nil {
+		t.Fatal(err)
+	}
+	if sigChecks <= maxChainSignatureChecks {
+		t.Errorf("expected more than %d signature checks, got %d", maxChainSignatureChecks, sigChecks)
+	}
+	// with a limit, only the candidates of the first two layers are checked,
+	// every certificate of a layer having the same subject
+	opts.MaxChainDepth = 4
+	sigChecks = 0
+	if _, err := leaf.buildChains([]*Certificate{leaf}, &sigChecks, &opts); !errors.As(err, new(UnknownAuthorityError)) {
+		t.Errorf("expected UnknownAuthorityError, got %v", err)
+	}
+	if want := k*k + k*k*k; sigChecks != want {
+		t.Errorf("expected %d signature checks, got %d", want, sigChecks)
+	}
+	opts.MaxChainDepth = layers + 2
+	chains, err = leaf.Verify(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, chain := range chains {
+		if len(chain) != layers+2 {
+			t.Errorf("unexpected chain %v", chainsToStrings([][]*Certificate{chain}))
+		}
+	}
+}
+
+func TestClockSkew(t *testing.T) {
+	const skew = 5 * time.Minute
+	notBefore := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	notAfter := notBefore.AddDate(1, 0, 0)
+	validity := func(c *Certificate) {
+		c.NotBefore, c.NotAfter = notBefore, notAfter
+	}
+	rootKey, _ := sm2.GenerateKey(rand.Reader)
+	intermediateKey, _ := sm2.GenerateKey(rand.Reader)
+	root := genCertEdge(t, "skew root", rootKey, func(c *Certificate) {
+		c.NotBefore, c.NotAfter = notBefore.AddDate(-1, 0, 0), notAfter.AddDate(1, 0, 0)
+	}, rootCertificate, nil, nil)
+	intermediate := genCertEdge(t, "skew intermediate", intermediateKey, validity, intermediateCertificate, root, rootKey)
+	leaf := genCertEdge(t, "skew leaf", intermediateKey, func(c *Certificate) {
+		// the leaf is valid for longer than the intermediate
+		c.NotBefore, c.NotAfter = notBefore.AddDate(0, 0, -1), notAfter.AddDate(0, 0, 1)
+	}, leafCertificate, intermediate, intermediateKey)
+
+	tests := []struct {
+		t     time.Time
+		skew  time.Duration
+		valid bool
+	}{
+		{notBefore, 0, true},
+		{notBefore.Add(-time.Nanosecond), 0, false},
+		{notBefore.Add(-skew), skew, true},
+		{notBefore.Add(-skew - time.Nanosecond), skew, false},
+		{notAfter, 0, true},
+		{notAfter.Add(time.Nanosecond), 0, false},
+		{notAfter.Add(skew), skew, true},
+		{notAfter.Add(skew + time.Nanosecond), skew, false},
+	}
+	roots, intermediates := NewCertPool(), NewCertPool()
+	roots.AddCert(root)
+	intermediates.AddCert(intermediate)
+	for _, tt := range tests {
+		if valid := intermediate.IsValidAt(tt.t, tt.skew); valid != tt.valid {
+			t.Errorf("IsValidAt(%s, %s) = %v, want %v", tt.t, tt.skew, valid, tt.valid)
+		}
+		// the skew applies to the intermediate as well as to the leaf
+		_, err := leaf.Verify(VerifyOptions{Roots: roots, Intermediates: intermediates, DNSName: "localhost", CurrentTime: tt.t, ClockSkew: tt.skew})
+		if tt.valid && err != nil {
+			t.Errorf("Verify at %s with skew %s: unexpected error %v", tt.t, tt.skew, err)
+		} else if !tt.valid && err == nil {
+			t.Errorf("Verify at %s with skew %s: expected error", tt.t, tt.skew)
+		}
+	}
+}
+
+func TestRequireSMAlgorithms(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootKey, _ := sm2.GenerateKey(rand.Reader)
+	intermediateKey, _ := sm2.GenerateKey(rand.Reader)
+	leafKey, _ := sm2.GenerateKey(rand.Reader)
+
+	verify := func(root *Certificate, rootKey crypto.Signer, opts VerifyOptions) error {
+		t.Helper()
+		intermediate := genCertEdge(t, "SM intermediate", intermediateKey, nil, intermediateCertificate, root, rootKey)
+		leaf := genCertEdge(t, "SM leaf", leafKey, nil, leafCertificate, intermediate, intermediateKey)
+		opts.Roots, opts.Intermediates = NewCertPool(), NewCertPool()
+		opts.Roots.AddCert(root)
+		opts.Intermediates.AddCert(intermediate)
+		_, err := leaf.Verify(opts)
+		return err
+	}
+	expectNotSM := func(err error, detail string) {
+		t.Helper()
+		var invalidErr CertificateInvalidError
+		if !errors.As(err, &invalidErr) || invalidErr.Reason != NotSMAlgorithm {
+			t.Fatalf("expected NotSMAlgorithm error, got %v", err)
+		}
+		if invalidErr.Detail != detail {
+			t.Errorf("unexpected detail %q, want %q", invalidErr.Detail, detail)
+		}
+	}
+
+	// all SM2
+	sm2Root := genCertEdge(t, "SM2 root", rootKey, nil, rootCertificate, nil, nil)
+	if err := verify(sm2Root, rootKey, VerifyOptions{RequireSMAlgorithms: true}); err != nil {
+		t.Errorf("unexpected error for an all SM2 chain: %v", err)
+	}
+
+	// RSA root, the intermediate is signed with RSA
+	rsaRoot := genCertEdge(t, "RSA root", rsaKey, nil, rootCertificate, nil, nil)
+	if err := verify(rsaRoot, rsaKey, VerifyOptions{}); err != nil {
+		t.Errorf("unexpected error without RequireSMAlgorithms: %v", err)
+	}
+	expectNotSM(verify(rsaRoot, rsaKey, VerifyOptions{RequireSMAlgorithms: true}), "certificate 1 in the chain is signed with SHA256-RSA, not SM2-SM3")
+	expectNotSM(verify(rsaRoot, rsaKey, VerifyOptions{RequireSMAlgorithms: true, AllowNonSMRootSignature: true}), "certificate 1 in the chain is signed with SHA256-RSA, not SM2-SM3")
+	expectNotSM(checkChainSMAlgorithms([]*Certificate{rsaRoot}, &VerifyOptions{AllowNonSMRootSignature: true}), "certificate 0 in the chain has a non SM2 RSA public key")
+
+	// SM2 root self-signed with another algorithm, as produced by some tools
+	otherRoot := *sm2Root
+	otherRoot.SignatureAlgorithm = ECDSAWithSHA256
+	expectNotSM(verify(&otherRoot, rootKey, VerifyOptions{RequireSMAlgorithms: true}), "certificate 2 in the chain is signed with ECDSA-SHA256, not SM2-SM3")
+	if err := verify(&otherRoot, rootKey, VerifyOptions{RequireSMAlgorithms: true, AllowNonSMRootSignature: true}); err != nil {
+		t.Errorf("unexpected error with AllowNonSMRootSignature: %v", err)
+	}
+}
+
+func TestSortChains(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootKey, _ := sm2.GenerateKey(rand.Reader)
+	intermediateKey, _ := sm2.GenerateKey(rand.Reader)
+	leafKey, _ := sm2.GenerateKey(rand.Reader)
+
+	legacyRoot := genCertEdge(t, "legacy root", rsaKey, nil, rootCertificate, nil, nil)
+	sm2Root := genCertEdge(t, "SM2 root", rootKey, nil, rootCertificate, nil, nil)
+	// the SM2 root cross-signed by the legacy RSA root
+	crossSigned := genCertEdge(t, "SM2 root", rootKey, nil, intermediateCertificate, legacyRoot, rsaKey)
+	intermediate := genCertEdge(t, "SM2 intermediate", intermediateKey, nil, intermediateCertificate, sm2Root, rootKey)
+	leaf := genCertEdge(t, "SM2 leaf", leafKey, nil, leafCertificate, intermediate, intermediateKey)
+
+	roots, intermediates := NewCertPool(), NewCertPool()
+	roots.AddCert(legacyRoot)
+	roots.AddCert(crossSigned)
+	roots.AddCert(sm2Root)
+	intermediates.AddCert(crossSigned)
+	intermediates.AddCert(intermediate)
+
+	want := []string{
+		"CN=SM2 leaf -> CN=SM2 intermediate -> CN=SM2 root",
+		"CN=SM2 leaf -> CN=SM2 intermediate -> CN=SM2 root",
+		"CN=SM2 leaf -> CN=SM2 intermediate -> CN=SM2 root -> CN=legacy root",
+	}
+	for i := 0; i < 5; i++ {
+		chains, err := leaf.Verify(VerifyOptions{Roots: roots, Intermediates: intermediates})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := chainsToStrings(chains); !reflect.DeepEqual(got, want) {
+			t.Fatalf("unexpected chains:\n%s", strings.Join(got, "\n"))
+		}
+		if chains[0][2] != sm2Root {
+			t.Fatal("the all SM2 chain is not ranked first")
+		}
+		if chains[1][2] != crossSigned || len(chains[2]) != 4 {
+			t.Fatal("the cross-signed chains are not ranked last")
+		}
+	}
+
+	chains := [][]*Certificate{
+		{leaf, intermediate, crossSigned, legacyRoot},
+		{leaf, intermediate, crossSigned},
+		{leaf, intermediate, sm2Root},
+		{sm2Root},
+	}
+	SortChains(chains)
+	if len(chains[0]) != 1 || chains[1][2] != sm2Root || chains[2][2] != crossSigned || len(chains[3]) != 4 {
+		t.Errorf("unexpected order:\n%s", strings.Join(chainsToStrings(chains), "\n"))
+	}
+}
+
+func TestOtherNameConstraints(t *testing.T) {
+	oidContent := func(oid asn1.ObjectIdentifier) []byte {
+		der, err := asn1.Marshal(oid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return der[2:]
+	}
+	otherNameTag := cryptobyte_asn1.Tag(0).ContextSpecific().Constructed()
+	registeredIDTag := cryptobyte_asn1.Tag(8).ContextSpecific()
+	addOtherName := func(b *cryptobyte.Builder, value string) {
+		b.AddASN1(otherNameTag, func(b *cryptobyte.Builder) {
+			b.AddASN1ObjectIdentifier(asn1.ObjectIdentifier{1, 2, 3, 4})
+			b.AddASN1(cryptobyte_asn1.Tag(0).ContextSpecific().Constructed(), func(b *cryptobyte.Builder) {
+				b.AddASN1(cryptobyte_asn1.UTF8String, func(b *cryptobyte.Builder) {
+					b.AddBytes([]byte(value))
+				})
+			})
+		})
+	}
+
+	var b cryptobyte.Builder
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1(cryptobyte_asn1.Tag(0).ContextSpecific().Constructed(), func(b *cryptobyte.Builder) {
+			b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+				addOtherName(b, "device")
+			})
+			b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+				b.AddASN1(registeredIDTag, func(b *cryptobyte.Builder) {
+					b.AddBytes(oidContent(asn1.ObjectIdentifier{1, 2, 3, 5}))
+				})
+			})
+		})
+		b.AddASN1(cryptobyte_asn1.Tag(1).ContextSpecific().Constructed(), func(b *cryptobyte.Builder) {
+			b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+				b.AddASN1(registeredIDTag, func(b *cryptobyte.Builder) {
+					b.AddBytes(oidContent(asn1.ObjectIdentifier{1, 2, 3, 6}))
+				})
+			})
+		})
+	})
+	constraints := b.BytesOrPanic()
+
+	rootKey, _ := sm2.GenerateKey(rand.Reader)
+	caKey, _ := sm2.GenerateKey(rand.Reader)
+	leafKey, _ := sm2.GenerateKey(rand.Reader)
+	root := genCertEdge(t, "root", rootKey, nil, rootCertificate, nil, nil)
+	ca := genCertEdge(t, "constrained CA", caKey, func(c *Certificate) {
+		c.ExtraExtensions = []pkix.Extension{{Id: oidExtensionNameConstraints, Critical: true, Value: constraints}}
+	}, intermediateCertificate, root, rootKey)
+	if len(ca.UnhandledCriticalExtensions) != 0 {
+		t.Fatalf("unexpected unhandled critical extensions %v", ca.UnhandledCriticalExtensions)
+	}
+
+	roots, intermediates := NewCertPool(), NewCertPool()
+	roots.AddCert(root)
+	intermediates.AddCert(ca)
+	verify := func(san func(b *cryptobyte.Builder), opts VerifyOptions) error {
+		t.Helper()
+		leaf := genCertEdge(t, "leaf", leafKey, func(c *Certificate) {
+			if san == nil {
+				return
+			}
+			var b cryptobyte.Builder
+			b.AddASN1(cryptobyte_asn1.SEQUENCE, san)
+			c.ExtraExtensions = []pkix.Extension{{Id: oidExtensionSubjectAltName, Value: b.BytesOrPanic()}}
+		}, leafCertificate, ca, caKey)
+		opts.Roots, opts.Intermediates = roots, intermediates
+		_, err := leaf.Verify(opts)
+		return err
+	}
+	registeredID := func(oid asn1.ObjectIdentifier) func(b *cryptobyte.Builder) {
+		return func(b *cryptobyte.Builder) {
+			b.AddASN1(registeredIDTag, func(b *cryptobyte.Builder) {
+				b.AddBytes(oidContent(oid))
+			})
+		}
+	}
+	otherName := func(b *cryptobyte.Builder) {
+		addOtherName(b, "device-1")
+	}
+
+	tests := []struct {
+		name   string
+		san    func(b *cryptobyte.Builder)
+		opts   VerifyOptions
+		detail string
+	}{
+		{name: "dNSName"},
+		{name: "permitted registeredID", san: registeredID(asn1.ObjectIdentifier{1, 2, 3, 5})},
+		{name: "excluded registeredID", san: registeredID(asn1.ObjectIdentifier{1, 2, 3, 6}),
+			detail: `registeredID "1.2.3.6" is excluded by constraint "1.2.3.6"`},
+		{name: "other registeredID", san: registeredID(asn1.ObjectIdentifier{1, 2, 3, 7}),
+			detail: `registeredID "1.2.3.7" is not permitted by any constraint`},
+		{name: "otherName", san: otherName,
+			detail: "cannot check the otherName names against the name constraints"},
+		{name: "ignored otherName", san: otherName, opts: VerifyOptions{IgnoreUnhandledNameConstraints: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verify(tt.san, tt.opts)
+			if tt.detail == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var invalidErr CertificateInvalidError
+			if !errors.As(err, &invalidErr) || invalidErr.Reason != CANotAuthorizedForThisName {
+				t.Fatalf("expected CANotAuthorizedForThisName error, got %v", err)
+			}
+			if invalidErr.Detail != tt.detail {
+				t.Errorf("unexpected detail %q, want %q", invalidErr.Detail, tt.detail)
+			}
+		})
+	}
+}
+
 func TestSystemRootsError(t *testing.T) {
 	if runtime.GOOS == "windows" {
 		t.Skip("Windows and darwin do not use (or support) systemRoots")
@@ -1930,7 +2313,7 @@ type trustGraphDescription struct {
 	Graph []trustGraphEdge
 }
 
-func genCertEdge(t *testing.T, subject string, key crypto.Signer, mutateTmpl func(*Certificate), certType int, issuer *Certificate, signer crypto.Signer) *Certificate {
+func genCertEdge(t testing.TB, subject string, key crypto.Signer, mutateTmpl func(*Certificate), certType int, issuer *Certificate, signer crypto.Signer) *Certificate {
 	t.Helper()
 
 	serial, err := rand.Int(rand.Reader, big.NewInt(100))
@@ -2585,6 +2968,7 @@ func TestEKUEnforcement(t *testing.T) {
 		inters     []ekuDescs
 		leaf       ekuDescs
 		verifyEKUs []ExtKeyUsage
+		nesting    bool
 		err        string
 	}{
 		{
@@ -2652,6 +3036,56 @@ func TestEKUEnforcement(t *testing.T) {
 			verifyEKUs: []ExtKeyUsage{ExtKeyUsageServerAuth},
 			err:        "x509: certificate specifies an incompatible key usage",
 		},
+		{
+			name:       "valid, intermediate lacks serverAuth",
+			root:       ekuDescs{},
+			inters:     []ekuDescs{{EKUs: []ExtKeyUsage{ExtKeyUsageClientAuth}}},
+			leaf:       ekuDescs{EKUs: []ExtKeyUsage{ExtKeyUsageServerAuth, ExtKeyUsageClientAuth}},
+			verifyEKUs: []ExtKeyUsage{ExtKeyUsageServerAuth, ExtKeyUsageClientAuth},
+		},
+		{
+			name:       "invalid, nesting, intermediate lacks serverAuth",
+			root:       ekuDescs{},
+			inters:     []ekuDescs{{EKUs: []ExtKeyUsage{ExtKeyUsageClientAuth}}},
+			leaf:       ekuDescs{EKUs: []ExtKeyUsage{ExtKeyUsageServerAuth, ExtKeyUsageClientAuth}},
+			verifyEKUs: []ExtKeyUsage{ExtKeyUsageServerAuth, ExtKeyUsageClientAuth},
+			nesting:    true,
+			err:        "x509: certificate specifies an incompatible key usage",
+		},
+		{
+			name:       "invalid, nesting, root lacks clientAuth",
+			root:       ekuDescs{EKUs: []ExtKeyUsage{ExtKeyUsageServerAuth}},
+			inters:     []ekuDescs{{EKUs: []ExtKeyUsage{ExtKeyUsageServerAuth, ExtKeyUsageClientAuth}}},
+			leaf:       ekuDescs{EKUs: []ExtKeyUsage{ExtKeyUsageServerAuth, ExtKeyUsageClientAuth}},
+			verifyEKUs: []ExtKeyUsage{ExtKeyUsageServerAuth, ExtKeyUsageClientAuth},
+			nesting:    true,
+			err:        "x509: certificate specifies an incompatible key usage",
+		},
+		{
+			name:       "invalid, nesting, leaf lacks clientAuth",
+			root:       ekuDescs{},
+			inters:     []ekuDescs{{}},
+			leaf:       ekuDescs{EKUs: []ExtKeyUsage{ExtKeyUsageServerAuth}},
+			verifyEKUs: []ExtKeyUsage{ExtKeyUsageServerAuth, ExtKeyUsageClientAuth},
+			nesting:    true,
+			err:        "x509: certificate specifies an incompatible key usage",
+		},
+		{
+			name:       "valid, nesting, intermediate has any",
+			root:       ekuDescs{},
+			inters:     []ekuDescs{{EKUs: []ExtKeyUsage{ExtKeyUsageAny}}},
+			leaf:       ekuDescs{EKUs: []ExtKeyUsage{ExtKeyUsageServerAuth, ExtKeyUsageClientAuth}},
+			verifyEKUs: []ExtKeyUsage{ExtKeyUsageServerAuth, ExtKeyUsageClientAuth},
+			nesting:    true,
+		},
+		{
+			name:       "valid, nesting, full chain",
+			root:       ekuDescs{EKUs: []ExtKeyUsage{ExtKeyUsageServerAuth}},
+			inters:     []ekuDescs{{EKUs: []ExtKeyUsage{ExtKeyUsageServerAuth, ExtKeyUsageClientAuth}}, {}},
+			leaf:       ekuDescs{EKUs: []ExtKeyUsage{ExtKeyUsageServerAuth}},
+			verifyEKUs: []ExtKeyUsage{ExtKeyUsageServerAuth},
+			nesting:    true,
+		},
 	}
 
 	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
@@ -2684,7 +3118,7 @@ func TestEKUEnforcement(t *testing.T) {
 				c.UnknownExtKeyUsage = tc.leaf.Unknown
 			}, intermediateCertificate, parent, k)
 
-			_, err := leaf.Verify(VerifyOptions{Roots: rootPool, Intermediates: interPool, KeyUsages: tc.verifyEKUs})
+			_, err := leaf.Verify(VerifyOptions{Roots: rootPool, Intermediates: interPool, KeyUsages: tc.verifyEKUs, EnforceEKUNesting: tc.nesting})
 			if err == nil && tc.err != "" {
 				t.Errorf("expected error")
 			} else if err != nil && err.Error() != tc.err {
@@ -2809,3 +3243,238 @@ func TestVerifyBareWildcard(t *testing.T) {
 		t.Fatalf("VerifyHostname unexpected success with bare wildcard SAN")
 	}
 }
+
+func TestPathLenConstraint(t *testing.T) {
+	newKey := func() crypto.Signer {
+		k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("failed to generate key: %s", err)
+		}
+		return k
+	}
+	pathLen := func(n int) func(*Certificate) {
+		return func(c *Certificate) {
+			c.MaxPathLen, c.MaxPathLenZero = n, n == 0
+		}
+	}
+
+	// buildChain issues a chain of intermediates below a root, each
+	// intermediate being named by subjects, so that repeating a subject makes a
+	// self-issued certificate. It verifies the leaf issued by the last one.
+	buildChain := func(rootPathLen int, subjects []string, mutate []func(*Certificate)) error {
+		rootKey := newKey()
+		root := genCertEdge(t, "root", rootKey, pathLen(rootPathLen), rootCertificate, nil, nil)
+		roots, intermediates := NewCertPool(), NewCertPool()
+		roots.AddCert(root)
+		parent, parentKey := root, rootKey
+		for i, subject := range subjects {
+			key := newKey()
+			parent = genCertEdge(t, subject, key, mutate[i], intermediateCertificate, parent, parentKey)
+			parentKey = key
+			intermediates.AddCert(parent)
+		}
+		leaf := genCertEdge(t, "leaf", newKey(), nil, leafCertificate, parent, parentKey)
+		_, err := leaf.Verify(VerifyOptions{Roots: roots, Intermediates: intermediates})
+		return err
+	}
+
+	tests := []struct {
+		name        string
+		rootPathLen int
+		subjects    []string
+		mutate      []func(*Certificate)
+		wantErr     bool
+	}{
+		{"root pathlen 0, one intermediate", 0, []string{"a"}, []func(*Certificate){nil}, true},
+		{"root pathlen 1, one intermediate", 1, []string{"a"}, []func(*Certificate){nil}, false},
+		{"root pathlen 1, two intermediates", 1, []string{"a", "b"}, []func(*Certificate){nil, nil}, true},
+		{"intermediate pathlen 0, sub CA", -1, []string{"a", "b"}, []func(*Certificate){pathLen(0), nil}, true},
+		{"intermediate pathlen 1, sub CAs", -1, []string{"a", "b", "c", "d"}, []func(*Certificate){nil, pathLen(1), nil, nil}, true},
+		{"root pathlen 1, self-issued intermediate", 1, []string{"a", "a"}, []func(*Certificate){nil, nil}, false},
+		{"intermediate pathlen 0, self-issued intermediates", 0, []string{"a", "a", "a"}, []func(*Certificate){pathLen(0), nil, nil}, true},
+		{"intermediate pathlen 0, self-issued sub CA", -1, []string{"a", "a"}, []func(*Certificate){pathLen(0), nil}, false},
+		{"root pathlen 0, self-issued sub CA", 0, []string{"root"}, []func(*Certificate){nil}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := buildChain(tt.rootPathLen, tt.subjects, tt.mutate)
+			if tt.wantErr {
+				var invalidErr CertificateInvalidError
+				if !errors.As(err, &invalidErr) || invalidErr.Reason != TooManyIntermediates {
+					t.Errorf("expected TooManyIntermediates error, got %v", err)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+
+	// CheckSignatureFrom rejects a CA issued by a parent with pathlen 0
+	parentKey := newKey()
+	parent := genCertEdge(t, "parent", parentKey, pathLen(0), rootCertificate, nil, nil)
+	subCA := genCertEdge(t, "sub CA", newKey(), nil, intermediateCertificate, parent, parentKey)
+	if err := subCA.CheckSignatureFrom(parent); !errors.As(err, new(x509.ConstraintViolationError)) {
+		t.Errorf("expected ConstraintViolationError, got %v", err)
+	}
+	leaf := genCertEdge(t, "leaf", newKey(), nil, leafCertificate, parent, parentKey)
+	if err := leaf.CheckSignatureFrom(parent); err != nil {
+		t.Errorf("unexpected error for leaf: %v", err)
+	}
+	selfIssued := genCertEdge(t, "parent", newKey(), nil, intermediateCertificate, parent, parentKey)
+	if err := selfIssued.CheckSignatureFrom(parent); err != nil {
+		t.Errorf("unexpected error for self-issued CA: %v", err)
+	}
+	if err := parent.CheckSignatureFrom(parent); err != nil {
+		t.Errorf("unexpected error for self-signed CA: %v", err)
+	}
+}
+
+func TestPolicies(t *testing.T) {
+	oid1 := mustNewOIDFromInts([]uint64{1, 2, 156, 9999, 1})
+	oid2 := mustNewOIDFromInts([]uint64{1, 2, 156, 9999, 2})
+	oid3 := mustNewOIDFromInts([]uint64{1, 2, 156, 9999, 3})
+
+	// template mutators, the extensions are marked critical as required by
+	// RFC 5280, so that they must be handled by the parser
+	with := func(mutators ...func(*Certificate)) func(*Certificate) {
+		return func(c *Certificate) {
+			for _, m := range mutators {
+				m(c)
+			}
+		}
+	}
+	policies := func(oids ...x509.OID) func(*Certificate) {
+		return func(c *Certificate) { c.Policies = oids }
+	}
+	mapping := func(issuer, subject x509.OID) func(*Certificate) {
+		return func(c *Certificate) {
+			var b cryptobyte.Builder
+			b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+				b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+					b.AddASN1(cryptobyte_asn1.OBJECT_IDENTIFIER, func(b *cryptobyte.Builder) { b.AddBytes(getDer(&issuer)) })
+					b.AddASN1(cryptobyte_asn1.OBJECT_IDENTIFIER, func(b *cryptobyte.Builder) { b.AddBytes(getDer(&subject)) })
+				})
+			})
+			c.ExtraExtensions = append(c.ExtraExtensions, pkix.Extension{Id: asn1.ObjectIdentifier{2, 5, 29, 33}, Critical: true, Value: b.BytesOrPanic()})
+		}
+	}
+	requireExplicit := func(skipCerts int) func(*Certificate) {
+		return func(c *Certificate) {
+			var b cryptobyte.Builder
+			b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+				b.AddASN1Int64WithTag(int64(skipCerts), cryptobyte_asn1.Tag(0).ContextSpecific())
+			})
+			c.ExtraExtensions = append(c.ExtraExtensions, pkix.Extension{Id: asn1.ObjectIdentifier{2, 5, 29, 36}, Critical: true, Value: b.BytesOrPanic()})
+		}
+	}
+	inhibitAny := func(skipCerts int) func(*Certificate) {
+		return func(c *Certificate) {
+			value, _ := asn1.Marshal(skipCerts)
+			c.ExtraExtensions = append(c.ExtraExtensions, pkix.Extension{Id: asn1.ObjectIdentifier{2, 5, 29, 54}, Critical: true, Value: value})
+		}
+	}
+
+	rootKey, _ := sm2.GenerateKey(rand.Reader)
+	intermediateKey, _ := sm2.GenerateKey(rand.Reader)
+	leafKey, _ := sm2.GenerateKey(rand.Reader)
+	root := genCertEdge(t, "SM2 policy root", rootKey, nil, rootCertificate, nil, nil)
+	newIntermediate := func(mutate func(*Certificate)) *Certificate {
+		return genCertEdge(t, "SM2 policy intermediate", intermediateKey, mutate, intermediateCertificate, root, rootKey)
+	}
+	intermediate := newIntermediate(policies(oid1, oid2))
+	newLeaf := func(mutate func(*Certificate)) *Certificate {
+		return genCertEdge(t, "SM2 policy leaf", leafKey, mutate, leafCertificate, intermediate, intermediateKey)
+	}
+
+	intermediateAny := newIntermediate(policies(anyPolicyOID))
+	intermediateRequire := newIntermediate(with(policies(oid1, oid2), requireExplicit(0)))
+	intermediateRequire1 := newIntermediate(with(policies(oid1, oid2), requireExplicit(1)))
+	intermediateRequire2 := newIntermediate(with(policies(oid1, oid2), requireExplicit(2)))
+	intermediateRequireNoPolicies := newIntermediate(requireExplicit(0))
+	intermediateInhibitAny := newIntermediate(with(policies(oid1, oid2), inhibitAny(0)))
+	intermediateMapped := newIntermediate(with(policies(oid1), mapping(oid1, oid3)))
+	intermediateMappedAny := newIntermediate(with(policies(anyPolicyOID), mapping(oid1, oid3)))
+	intermediateMappedToAny := newIntermediate(with(policies(oid1), mapping(oid1, anyPolicyOID)))
+	// below intermediate, self-issued or not
+	selfIssued := genCertEdge(t, "SM2 policy intermediate", intermediateKey, policies(oid1, oid2), intermediateCertificate, intermediate, intermediateKey)
+	subCA := genCertEdge(t, "SM2 policy sub CA", intermediateKey, policies(oid1, oid2), intermediateCertificate, intermediate, intermediateKey)
+
+	leaf := newLeaf(policies(oid1, oid2))
+	leafNone := newLeaf(nil)
+	leafAny := newLeaf(policies(anyPolicyOID))
+	leafOID3 := newLeaf(policies(oid3))
+	leafRequire := newLeaf(with(policies(oid1, oid2), requireExplicit(0)))
+	leafRequire1 := newLeaf(with(policies(oid1, oid2), requireExplicit(1)))
+
+	tests := []struct {
+		name     string
+		chain    []*Certificate
+		opts     VerifyOptions
+		policies []x509.OID
+		valid    bool
+	}{
+		{"explicit", []*Certificate{leaf, intermediate, root}, VerifyOptions{RequireExplicitPolicy: true}, nil, true},
+		{"explicit oid1", []*Certificate{leaf, intermediate, root}, VerifyOptions{RequireExplicitPolicy: true}, []x509.OID{oid1}, true},
+		{"explicit oid3", []*Certificate{leaf, intermediate, root}, VerifyOptions{RequireExplicitPolicy: true}, []x509.OID{oid3}, false},
+		{"explicit oid1 or oid3", []*Certificate{leaf, intermediate, root}, VerifyOptions{RequireExplicitPolicy: true}, []x509.OID{oid1, oid3}, true},
+		{"not explicit oid3", []*Certificate{leaf, intermediate, root}, VerifyOptions{}, []x509.OID{oid3}, true},
+		{"single certificate", []*Certificate{root}, VerifyOptions{RequireExplicitPolicy: true}, []x509.OID{oid3}, true},
+
+		// policy constraints of the certificates
+		{"intermediate requires oid1", []*Certificate{leaf, intermediateRequire, root}, VerifyOptions{}, []x509.OID{oid1}, true},
+		{"intermediate requires oid3", []*Certificate{leaf, intermediateRequire, root}, VerifyOptions{}, []x509.OID{oid3}, false},
+		{"intermediate requires, leaf without policies", []*Certificate{leafNone, intermediateRequire, root}, VerifyOptions{}, nil, false},
+		{"intermediate requires without policies", []*Certificate{leaf, intermediateRequireNoPolicies, root}, VerifyOptions{}, []x509.OID{oid1}, false},
+		{"leaf requires oid1", []*Certificate{leafRequire, intermediate, root}, VerifyOptions{}, []x509.OID{oid1}, true},
+		{"leaf requires oid3", []*Certificate{leafRequire, intermediate, root}, VerifyOptions{}, []x509.OID{oid3}, false},
+
+		// requireExplicitPolicy is a count of certificates to skip
+		{"intermediate requires after 1", []*Certificate{leaf, intermediateRequire1, root}, VerifyOptions{}, []x509.OID{oid3}, false},
+		{"intermediate requires after 2", []*Certificate{leaf, intermediateRequire2, root}, VerifyOptions{}, []x509.OID{oid3}, true},
+		{"leaf requires after 1", []*Certificate{leafRequire1, intermediate, root}, VerifyOptions{}, []x509.OID{oid3}, true},
+		{"more constrained value wins", []*Certificate{leafRequire1, intermediateRequire1, root}, VerifyOptions{}, []x509.OID{oid3}, false},
+		{"sub CA counts", []*Certificate{leafNone, subCA, intermediateRequire2, root}, VerifyOptions{}, nil, false},
+		{"self-issued does not count", []*Certificate{leafNone, selfIssued, intermediateRequire2, root}, VerifyOptions{}, nil, true},
+
+		// anyPolicy
+		{"intermediate any", []*Certificate{leaf, intermediateAny, root}, VerifyOptions{RequireExplicitPolicy: true}, []x509.OID{oid1}, true},
+		{"any all along", []*Certificate{leafAny, intermediateAny, root}, VerifyOptions{RequireExplicitPolicy: true}, []x509.OID{oid3}, true},
+		{"leaf any", []*Certificate{leafAny, intermediate, root}, VerifyOptions{RequireExplicitPolicy: true}, []x509.OID{oid1}, true},
+		{"leaf any, oid3", []*Certificate{leafAny, intermediate, root}, VerifyOptions{RequireExplicitPolicy: true}, []x509.OID{oid3}, false},
+		{"intermediate any inhibited", []*Certificate{leaf, intermediateAny, root}, VerifyOptions{RequireExplicitPolicy: true, InhibitAnyPolicy: true}, []x509.OID{oid1}, false},
+		{"leaf any inhibited", []*Certificate{leafAny, intermediate, root}, VerifyOptions{RequireExplicitPolicy: true, InhibitAnyPolicy: true}, []x509.OID{oid1}, false},
+		{"leaf any inhibited by intermediate", []*Certificate{leafAny, intermediateInhibitAny, root}, VerifyOptions{RequireExplicitPolicy: true}, []x509.OID{oid1}, false},
+		{"leaf under intermediate inhibiting any", []*Certificate{leaf, intermediateInhibitAny, root}, VerifyOptions{RequireExplicitPolicy: true}, []x509.OID{oid1}, true},
+
+		// policy mappings
+		{"mapped oid1", []*Certificate{leafOID3, intermediateMapped, root}, VerifyOptions{RequireExplicitPolicy: true}, []x509.OID{oid1}, true},
+		{"mapped oid3", []*Certificate{leafOID3, intermediateMapped, root}, VerifyOptions{RequireExplicitPolicy: true}, []x509.OID{oid3}, false},
+		{"mapped, leaf with oid1", []*Certificate{leaf, intermediateMapped, root}, VerifyOptions{RequireExplicitPolicy: true}, []x509.OID{oid1}, false},
+		{"mapping inhibited", []*Certificate{leafOID3, intermediateMapped, root}, VerifyOptions{RequireExplicitPolicy: true, InhibitPolicyMapping: true}, []x509.OID{oid1}, false},
+		{"mapped from any", []*Certificate{leafOID3, intermediateMappedAny, root}, VerifyOptions{RequireExplicitPolicy: true}, []x509.OID{oid1}, true},
+		{"mapped to any", []*Certificate{leafOID3, intermediateMappedToAny, root}, VerifyOptions{}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.CertificatePolicies = tt.policies
+			if valid := policiesValid(tt.chain, tt.opts); valid != tt.valid {
+				t.Errorf("policiesValid() = %v, want %v", valid, tt.valid)
+			}
+		})
+	}
+
+	// Verify rejects the chains with invalid policies
+	roots, intermediates := NewCertPool(), NewCertPool()
+	roots.AddCert(root)
+	intermediates.AddCert(intermediateRequire)
+	opts := VerifyOptions{Roots: roots, Intermediates: intermediates, DNSName: "localhost", CertificatePolicies: []x509.OID{oid1}}
+	if _, err := leaf.Verify(opts); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	opts.CertificatePolicies = []x509.OID{oid3}
+	_, err := leaf.Verify(opts)
+	var invalidErr CertificateInvalidError
+	if !errors.As(err, &invalidErr) || invalidErr.Reason != NoValidChains {
+		t.Errorf("expected NoValidChains error, got %v", err)
+	}
+}
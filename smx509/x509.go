@@ -15,8 +15,9 @@
 // the package aims to apply consistent validation rules across operating
 // systems.
 //
-// On macOS, we did NOT support to use system root CA yet due to too many SDK internal
-// package's dependencies.
+// On macOS, the system roots are exported from the system keychains with the
+// security tool, honoring the admin and user trust settings, and verified with
+// the pure Go verifier. On iOS, the system roots are not supported.
 package smx509
 
 import (
@@ -38,6 +39,7 @@ import (
 	"math/big"
 	"net"
 	"net/url"
+	"sync/atomic"
 	"time"
 	"unicode"
 
@@ -53,6 +55,7 @@ import (
 	"github.com/yunmoon/gmsm/ecdh"
 	"github.com/yunmoon/gmsm/internal/godebug"
 	"github.com/yunmoon/gmsm/sm2"
+	"github.com/yunmoon/gmsm/sm3"
 )
 
 // pkixPublicKey reflects a PKIX public key structure. See SubjectPublicKeyInfo
@@ -114,6 +117,20 @@ func marshalPublicKey(pub any) (publicKeyBytes []byte, publicKeyAlgorithm pkix.A
 			return
 		}
 		publicKeyAlgorithm.Parameters.FullBytes = paramBytes
+	case *CompressedECPublicKey:
+		if pub.PublicKey == nil {
+			return nil, pkix.AlgorithmIdentifier{}, errors.New("x509: nil elliptic curve public key")
+		}
+		if _, publicKeyAlgorithm, err = marshalPublicKey(pub.PublicKey); err != nil {
+			return nil, pkix.AlgorithmIdentifier{}, err
+		}
+		publicKeyBytes = elliptic.MarshalCompressed(pub.Curve, pub.X, pub.Y)
+	case *SM2OIDPublicKey:
+		if pub.PublicKey == nil || pub.Curve != sm2.P256() {
+			return nil, pkix.AlgorithmIdentifier{}, errors.New("x509: not a SM2 public key")
+		}
+		publicKeyBytes, publicKeyAlgorithm, err = marshalPublicKey(pub.PublicKey)
+		publicKeyAlgorithm.Algorithm = oidPublicKeySM2
 	case ed25519.PublicKey:
 		publicKeyBytes = pub
 		publicKeyAlgorithm.Algorithm = oidPublicKeyEd25519
@@ -147,6 +164,13 @@ func marshalPublicKey(pub any) (publicKeyBytes []byte, publicKeyAlgorithm pkix.A
 			return
 		}
 		publicKeyAlgorithm.Parameters.FullBytes = paramBytes
+	case UnknownPublicKey:
+		var spki publicKeyInfo
+		if rest, err := asn1.Unmarshal(pub, &spki); err != nil || len(rest) > 0 {
+			return nil, pkix.AlgorithmIdentifier{}, errors.New("x509: malformed spki")
+		}
+		publicKeyBytes = spki.PublicKey.RightAlign()
+		publicKeyAlgorithm = spki.Algorithm
 	default:
 		return nil, pkix.AlgorithmIdentifier{}, fmt.Errorf("x509: unsupported public key type: %T", pub)
 	}
@@ -154,12 +178,76 @@ func marshalPublicKey(pub any) (publicKeyBytes []byte, publicKeyAlgorithm pkix.A
 	return publicKeyBytes, publicKeyAlgorithm, nil
 }
 
+// SM2OIDPublicKey wraps a SM2 public key, so that its SubjectPublicKeyInfo algorithm is
+// encoded as the SM2 algorithm OID 1.2.156.10197.1.301, as permitted by GB/T 33560,
+// instead of id-ecPublicKey. The parameters are the SM2 curve OID in both encodings.
+//
+// It can be passed to [MarshalPKIXPublicKey] and as the public key of [CreateCertificate].
+type SM2OIDPublicKey struct {
+	*ecdsa.PublicKey
+}
+
+// CompressedECPublicKey wraps an ECDSA or SM2 public key, so that its point is encoded
+// in the compressed form of SEC 1, Version 2.0, Section 2.3.3, to save space in
+// constrained environments.
+//
+// It can be passed to [MarshalPKIXPublicKey] and as the public key of [CreateCertificate].
+type CompressedECPublicKey struct {
+	*ecdsa.PublicKey
+}
+
+// UnknownPublicKey is the DER SubjectPublicKeyInfo of a public key of an
+// algorithm which is not supported, such as a SM9 identity-based key, usually
+// the RawSubjectPublicKeyInfo of a certificate whose PublicKey is nil.
+//
+// It can be passed to [MarshalPKIXPublicKey] and as the public key of
+// [CreateCertificate], which encode it as it is.
+type UnknownPublicKey []byte
+
+// UnknownPublicKeyOID returns the algorithm OID of the SubjectPublicKeyInfo of
+// c if it is not supported, as for the SM9 keys of OID 1.2.156.10197.1.302.x,
+// in which case PublicKeyAlgorithm is UnknownPublicKeyAlgorithm and PublicKey
+// is nil. It returns nil for supported algorithms.
+func (c *Certificate) UnknownPublicKeyOID() asn1.ObjectIdentifier {
+	if spki := c.unknownPublicKeyInfo(); spki != nil {
+		return spki.Algorithm.Algorithm
+	}
+	return nil
+}
+
+// RawPublicKeyBytes returns the subjectPublicKey of the SubjectPublicKeyInfo
+// of c if its algorithm is not supported, see [Certificate.UnknownPublicKeyOID],
+// or nil.
+func (c *Certificate) RawPublicKeyBytes() []byte {
+	if spki := c.unknownPublicKeyInfo(); spki != nil {
+		return spki.PublicKey.RightAlign()
+	}
+	return nil
+}
+
+func (c *Certificate) unknownPublicKeyInfo() *publicKeyInfo {
+	if c.PublicKeyAlgorithm != UnknownPublicKeyAlgorithm || c.PublicKey != nil {
+		return nil
+	}
+	var spki publicKeyInfo
+	if rest, err := asn1.Unmarshal(c.RawSubjectPublicKeyInfo, &spki); err != nil || len(rest) > 0 {
+		return nil
+	}
+	return &spki
+}
+
+// MarshalPKIXPublicKeySM2 converts a SM2 public key to PKIX, ASN.1 DER form
+// with the SM2 algorithm OID, see [SM2OIDPublicKey].
+func MarshalPKIXPublicKeySM2(pub *ecdsa.PublicKey) ([]byte, error) {
+	return MarshalPKIXPublicKey(&SM2OIDPublicKey{pub})
+}
+
 // MarshalPKIXPublicKey converts a public key to PKIX, ASN.1 DER form.
 // The encoded public key is a SubjectPublicKeyInfo structure
 // (see RFC 5280, Section 4.1).
 //
-// The following key types are currently supported: *rsa.PublicKey, *ecdsa.PublicKey
-// and ed25519.PublicKey. Unsupported key types result in an error.
+// The following key types are currently supported: *rsa.PublicKey, *ecdsa.PublicKey,
+// *SM2OIDPublicKey, *CompressedECPublicKey, ed25519.PublicKey and UnknownPublicKey. Unsupported key types result in an error.
 //
 // This kind of key is commonly encoded in PEM blocks of type "PUBLIC KEY".
 func MarshalPKIXPublicKey(pub any) ([]byte, error) {
@@ -247,10 +335,19 @@ const (
 	PureEd25519      = x509.PureEd25519
 
 	SM2WithSM3 SignatureAlgorithm = 99 // Make sure the vaule is not conflict with x509.SignatureAlgorithm
+
+	// SM2WithSHA1 and SM2WithSHA256 are SM2 signatures of the SHA-1 or SHA-256
+	// digest, without the ZA of GB/T 32918.2, of some legacy CAs. They are
+	// only supported for verification.
+	SM2WithSHA1   SignatureAlgorithm = 100
+	SM2WithSHA256 SignatureAlgorithm = 101
+
+	// RSAWithSM3 is a PKCS #1 v1.5 RSA signature of the SM3 digest, GB/T 33560.
+	RSAWithSM3 SignatureAlgorithm = 102
 )
 
 func isRSAPSS(algo SignatureAlgorithm) bool {
-	for _, details := range signatureAlgorithmDetails {
+	for _, details := range allSignatureAlgorithmDetails() {
 		if details.algo == algo {
 			return details.isRSAPSS
 		}
@@ -259,7 +356,7 @@ func isRSAPSS(algo SignatureAlgorithm) bool {
 }
 
 func hashFunc(algo SignatureAlgorithm) crypto.Hash {
-	for _, details := range signatureAlgorithmDetails {
+	for _, details := range allSignatureAlgorithmDetails() {
 		if details.algo == algo {
 			return details.hash
 		}
@@ -276,8 +373,21 @@ const (
 	DSA     = x509.DSA // Only supported for parsing.
 	ECDSA   = x509.ECDSA
 	Ed25519 = x509.Ed25519
+
+	// SM2 is reported for the public keys declared with the SM2 algorithm OID or
+	// the SM2 named curve. As PublicKeyAlgorithm is an alias of x509.PublicKeyAlgorithm,
+	// its String method can't name it, use PublicKeyAlgorithmString instead.
+	SM2 PublicKeyAlgorithm = 99 // Make sure the value does not conflict with x509.PublicKeyAlgorithm
 )
 
+// PublicKeyAlgorithmString returns the name of the public key algorithm, it's "SM2" for [SM2].
+func PublicKeyAlgorithmString(algo PublicKeyAlgorithm) string {
+	if algo == SM2 {
+		return "SM2"
+	}
+	return algo.String()
+}
+
 // OIDs for signature algorithms
 //
 //	pkcs-1 OBJECT IDENTIFIER ::= {
@@ -359,12 +469,13 @@ var (
 	// 附录A（规范性附录）商用密码领域中的相关OID定义
 	//
 	// http://gmssl.org/docs/oid.html
-	oidSignatureSM2WithSM3 = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 501}
-	//oidSignatureSM2WithSHA1   = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 502}
-	//oidSignatureSM2WithSHA256 = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 503}
+	oidSignatureSM2WithSM3    = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 501}
+	oidSignatureSM2WithSHA1   = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 502}
+	oidSignatureSM2WithSHA256 = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 503}
+	oidSignatureRSAWithSM3    = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 504}
 )
 
-var signatureAlgorithmDetails = []struct {
+type signatureAlgorithmDetail struct {
 	algo       SignatureAlgorithm
 	name       string
 	oid        asn1.ObjectIdentifier
@@ -372,29 +483,45 @@ var signatureAlgorithmDetails = []struct {
 	pubKeyAlgo PublicKeyAlgorithm
 	hash       crypto.Hash
 	isRSAPSS   bool
-}{
-	{MD2WithRSA, "MD2-RSA", oidSignatureMD2WithRSA, asn1.NullRawValue, RSA, crypto.Hash(0) /* no value for MD2 */, false},
-	{MD5WithRSA, "MD5-RSA", oidSignatureMD5WithRSA, asn1.NullRawValue, RSA, crypto.MD5, false},
-	{SHA1WithRSA, "SHA1-RSA", oidSignatureSHA1WithRSA, asn1.NullRawValue, RSA, crypto.SHA1, false},
-	{SHA1WithRSA, "SHA1-RSA", oidISOSignatureSHA1WithRSA, asn1.NullRawValue, RSA, crypto.SHA1, false},
-	{SHA256WithRSA, "SHA256-RSA", oidSignatureSHA256WithRSA, asn1.NullRawValue, RSA, crypto.SHA256, false},
-	{SHA384WithRSA, "SHA384-RSA", oidSignatureSHA384WithRSA, asn1.NullRawValue, RSA, crypto.SHA384, false},
-	{SHA512WithRSA, "SHA512-RSA", oidSignatureSHA512WithRSA, asn1.NullRawValue, RSA, crypto.SHA512, false},
-	{SHA256WithRSAPSS, "SHA256-RSAPSS", oidSignatureRSAPSS, pssParametersSHA256, RSA, crypto.SHA256, true},
-	{SHA384WithRSAPSS, "SHA384-RSAPSS", oidSignatureRSAPSS, pssParametersSHA384, RSA, crypto.SHA384, true},
-	{SHA512WithRSAPSS, "SHA512-RSAPSS", oidSignatureRSAPSS, pssParametersSHA512, RSA, crypto.SHA512, true},
-	{DSAWithSHA1, "DSA-SHA1", oidSignatureDSAWithSHA1, emptyRawValue, DSA, crypto.SHA1, false},
-	{DSAWithSHA256, "DSA-SHA256", oidSignatureDSAWithSHA256, emptyRawValue, DSA, crypto.SHA256, false},
-	{ECDSAWithSHA1, "ECDSA-SHA1", oidSignatureECDSAWithSHA1, emptyRawValue, ECDSA, crypto.SHA1, false},
-	{ECDSAWithSHA256, "ECDSA-SHA256", oidSignatureECDSAWithSHA256, emptyRawValue, ECDSA, crypto.SHA256, false},
-	{ECDSAWithSHA384, "ECDSA-SHA384", oidSignatureECDSAWithSHA384, emptyRawValue, ECDSA, crypto.SHA384, false},
-	{ECDSAWithSHA512, "ECDSA-SHA512", oidSignatureECDSAWithSHA512, emptyRawValue, ECDSA, crypto.SHA512, false},
-	{PureEd25519, "Ed25519", oidSignatureEd25519, emptyRawValue, Ed25519, crypto.Hash(0) /* no pre-hashing */, false},
-	{SM2WithSM3, "SM2-SM3", oidSignatureSM2WithSM3, emptyRawValue, ECDSA, crypto.Hash(0) /* no pre-hashing */, false},
+	verify     func(pub crypto.PublicKey, signed, signature []byte) error // registered algorithms only
+}
+
+var signatureAlgorithmDetails = []signatureAlgorithmDetail{
+	{MD2WithRSA, "MD2-RSA", oidSignatureMD2WithRSA, asn1.NullRawValue, RSA, crypto.Hash(0) /* no value for MD2 */, false, nil},
+	{MD5WithRSA, "MD5-RSA", oidSignatureMD5WithRSA, asn1.NullRawValue, RSA, crypto.MD5, false, nil},
+	{SHA1WithRSA, "SHA1-RSA", oidSignatureSHA1WithRSA, asn1.NullRawValue, RSA, crypto.SHA1, false, nil},
+	{SHA1WithRSA, "SHA1-RSA", oidISOSignatureSHA1WithRSA, asn1.NullRawValue, RSA, crypto.SHA1, false, nil},
+	{SHA256WithRSA, "SHA256-RSA", oidSignatureSHA256WithRSA, asn1.NullRawValue, RSA, crypto.SHA256, false, nil},
+	{SHA384WithRSA, "SHA384-RSA", oidSignatureSHA384WithRSA, asn1.NullRawValue, RSA, crypto.SHA384, false, nil},
+	{SHA512WithRSA, "SHA512-RSA", oidSignatureSHA512WithRSA, asn1.NullRawValue, RSA, crypto.SHA512, false, nil},
+	{SHA256WithRSAPSS, "SHA256-RSAPSS", oidSignatureRSAPSS, pssParametersSHA256, RSA, crypto.SHA256, true, nil},
+	{SHA384WithRSAPSS, "SHA384-RSAPSS", oidSignatureRSAPSS, pssParametersSHA384, RSA, crypto.SHA384, true, nil},
+	{SHA512WithRSAPSS, "SHA512-RSAPSS", oidSignatureRSAPSS, pssParametersSHA512, RSA, crypto.SHA512, true, nil},
+	{DSAWithSHA1, "DSA-SHA1", oidSignatureDSAWithSHA1, emptyRawValue, DSA, crypto.SHA1, false, nil},
+	{DSAWithSHA256, "DSA-SHA256", oidSignatureDSAWithSHA256, emptyRawValue, DSA, crypto.SHA256, false, nil},
+	{ECDSAWithSHA1, "ECDSA-SHA1", oidSignatureECDSAWithSHA1, emptyRawValue, ECDSA, crypto.SHA1, false, nil},
+	{ECDSAWithSHA256, "ECDSA-SHA256", oidSignatureECDSAWithSHA256, emptyRawValue, ECDSA, crypto.SHA256, false, nil},
+	{ECDSAWithSHA384, "ECDSA-SHA384", oidSignatureECDSAWithSHA384, emptyRawValue, ECDSA, crypto.SHA384, false, nil},
+	{ECDSAWithSHA512, "ECDSA-SHA512", oidSignatureECDSAWithSHA512, emptyRawValue, ECDSA, crypto.SHA512, false, nil},
+	{PureEd25519, "Ed25519", oidSignatureEd25519, emptyRawValue, Ed25519, crypto.Hash(0) /* no pre-hashing */, false, nil},
+	{SM2WithSM3, "SM2-SM3", oidSignatureSM2WithSM3, emptyRawValue, ECDSA, crypto.Hash(0) /* no pre-hashing */, false, nil},
+	{SM2WithSHA1, "SM2-SHA1", oidSignatureSM2WithSHA1, emptyRawValue, ECDSA, crypto.SHA1, false, nil},
+	{SM2WithSHA256, "SM2-SHA256", oidSignatureSM2WithSHA256, emptyRawValue, ECDSA, crypto.SHA256, false, nil},
+	{RSAWithSM3, "SM3-RSA", oidSignatureRSAWithSM3, asn1.NullRawValue, RSA, crypto.Hash(0) /* SM3 is not a crypto.Hash */, false, nil},
 }
 
 var emptyRawValue = asn1.RawValue{}
 
+// sm3DigestInfoPrefix is the DER encoded DigestInfo of a SM3 digest, up to the
+// digest, which RSAWithSM3 signs with PKCS #1 v1.5 padding.
+var sm3DigestInfoPrefix = []byte{0x30, 0x30, 0x30, 0x0c, 0x06, 0x08, 0x2a, 0x81, 0x1c, 0xcf, 0x55, 0x01, 0x83, 0x11, 0x05, 0x00, 0x04, 0x20}
+
+// sm3DigestInfo returns the DigestInfo of the SM3 digest of signed.
+func sm3DigestInfo(signed []byte) []byte {
+	digest := sm3.Sum(signed)
+	return append(append(make([]byte, 0, len(sm3DigestInfoPrefix)+len(digest)), sm3DigestInfoPrefix...), digest[:]...)
+}
+
 // DER encoded RSA PSS parameters for the
 // SHA256, SHA384, and SHA512 hashes as defined in RFC 3447, Appendix A.2.3.
 // The parameters contain the following values:
@@ -430,7 +557,7 @@ func getSignatureAlgorithmFromAI(ai pkix.AlgorithmIdentifier) SignatureAlgorithm
 	}
 
 	if !ai.Algorithm.Equal(oidSignatureRSAPSS) {
-		for _, details := range signatureAlgorithmDetails {
+		for _, details := range allSignatureAlgorithmDetails() {
 			if ai.Algorithm.Equal(details.oid) {
 				return details.algo
 			}
@@ -516,13 +643,26 @@ func getPublicKeyAlgorithmFromOID(oid asn1.ObjectIdentifier) PublicKeyAlgorithm
 	case oid.Equal(oidPublicKeyECDSA):
 		return ECDSA
 	case oid.Equal(oidPublicKeySM2):
-		return ECDSA
+		return SM2
 	case oid.Equal(oidPublicKeyEd25519):
 		return Ed25519
 	}
 	return UnknownPublicKeyAlgorithm
 }
 
+// getPublicKeyAlgorithmFromAI is like getPublicKeyAlgorithmFromOID, but it
+// also reports SM2 for id-ecPublicKey with the SM2 named curve.
+func getPublicKeyAlgorithmFromAI(ai pkix.AlgorithmIdentifier) PublicKeyAlgorithm {
+	algo := getPublicKeyAlgorithmFromOID(ai.Algorithm)
+	if algo == ECDSA {
+		var namedCurveOID asn1.ObjectIdentifier
+		if rest, err := asn1.Unmarshal(ai.Parameters.FullBytes, &namedCurveOID); err == nil && len(rest) == 0 && namedCurveOID.Equal(oidNamedCurveP256SM2) {
+			return SM2
+		}
+	}
+	return algo
+}
+
 // RFC 5480, 2.1.1.1. Named Curve
 var (
 	oidNamedCurveP224 = asn1.ObjectIdentifier{1, 3, 132, 0, 33}
@@ -699,8 +839,75 @@ func oidFromExtKeyUsage(eku ExtKeyUsage) (oid asn1.ObjectIdentifier, ok bool) {
 	return
 }
 
-// debugAllowSHA1 allows SHA-1 signatures. See issue 41682.
-var debugAllowSHA1 = godebug.Get("x509sha1") == "1"
+// allowSHA1 allows SHA-1 signatures. See issue 41682.
+// It defaults to the x509sha1 GODEBUG setting, see SetAllowSHA1.
+var allowSHA1 atomic.Bool
+
+// usePoliciesOverride overrides the x509usepolicies GODEBUG setting if non-zero,
+// 1 means Policies is used and -1 means PolicyIdentifiers is used, see SetUsePolicies.
+var usePoliciesOverride atomic.Int32
+
+func init() {
+	allowSHA1.Store(godebug.Get("x509sha1") == "1")
+}
+
+// SetAllowSHA1 controls whether SHA-1 based signatures are accepted when checking
+// certificate signatures, it takes precedence over the x509sha1 GODEBUG setting.
+// It is safe to call concurrently, the setting applies to subsequent checks.
+func SetAllowSHA1(allow bool) {
+	allowSHA1.Store(allow)
+}
+
+// insecureSHA1Policy is the policy of SetInsecureSHA1Policy, nil if unset.
+var insecureSHA1Policy atomic.Pointer[func(cert *Certificate) bool]
+
+// SetInsecureSHA1Policy sets a policy accepting the SHA-1 based signatures of
+// the certificates for which it returns true, such as a single known legacy
+// cross-signed certificate, when checking certificate signatures while SHA-1
+// is otherwise rejected. A nil policy, the default, accepts none. It is safe
+// to call concurrently, the policy applies to subsequent checks and may be
+// called concurrently.
+func SetInsecureSHA1Policy(policy func(cert *Certificate) bool) {
+	if policy == nil {
+		insecureSHA1Policy.Store(nil)
+		return
+	}
+	insecureSHA1Policy.Store(&policy)
+}
+
+// allowSHA1For reports whether the SHA-1 based signature of cert is accepted.
+func allowSHA1For(cert *Certificate) bool {
+	if allowSHA1.Load() {
+		return true
+	}
+	if hashFunc(cert.SignatureAlgorithm) != crypto.SHA1 {
+		return false
+	}
+	policy := insecureSHA1Policy.Load()
+	return policy != nil && (*policy)(cert)
+}
+
+// SetUsePolicies controls whether the Policies field (true) or the PolicyIdentifiers
+// field (false) of the template is marshaled into the certificatePolicies extension
+// by CreateCertificate, it takes precedence over the x509usepolicies GODEBUG setting.
+// It is safe to call concurrently, the setting applies to subsequent calls.
+func SetUsePolicies(use bool) {
+	if use {
+		usePoliciesOverride.Store(1)
+	} else {
+		usePoliciesOverride.Store(-1)
+	}
+}
+
+func usePolicies() bool {
+	switch usePoliciesOverride.Load() {
+	case 1:
+		return true
+	case -1:
+		return false
+	}
+	return godebug.Get("x509usepolicies") != "0"
+}
 
 // A Certificate represents an X.509 certificate.
 type Certificate x509.Certificate
@@ -721,6 +928,12 @@ func (c *Certificate) Equal(other *Certificate) bool {
 	return bytes.Equal(c.Raw, other.Raw)
 }
 
+// isSelfIssued reports whether the issuer and subject of c are the same,
+// as defined in RFC 5280, Section 3.3.
+func (c *Certificate) isSelfIssued() bool {
+	return bytes.Equal(c.RawIssuer, c.RawSubject)
+}
+
 func (c *Certificate) hasSANExtension() bool {
 	return oidInExtensions(oidExtensionSubjectAltName, c.Extensions)
 }
@@ -729,7 +942,29 @@ func (c *Certificate) hasSANExtension() bool {
 //
 // This is a low-level API that performs very limited checks, and not a full
 // path verifier. Most users should use [Certificate.Verify] instead.
+//
+// A parent with a path length constraint of zero may only sign end entity
+// or self-issued certificates, otherwise a ConstraintViolationError is returned.
 func (c *Certificate) CheckSignatureFrom(parent *Certificate) error {
+	return c.CheckSignatureFromWithOptions(parent, nil)
+}
+
+// CheckSignatureFromWithOptions is like CheckSignatureFrom, with options, such
+// as the signer ID of a SM2WithSM3 signature. A nil opts is equivalent to the
+// zero value.
+func (c *Certificate) CheckSignatureFromWithOptions(parent *Certificate, opts *SMSignatureOptions) error {
+	// RFC 5280, 4.2.1.9: a path length constraint of zero means the parent may
+	// only issue end entity certificates, or self-issued certificates.
+	if parent.BasicConstraintsValid && parent.MaxPathLen == 0 && parent.MaxPathLenZero &&
+		c.BasicConstraintsValid && c.IsCA && !c.isSelfIssued() {
+		return x509.ConstraintViolationError{}
+	}
+	return c.checkSignatureFrom(parent, opts)
+}
+
+// checkSignatureFrom is CheckSignatureFrom without the path length check,
+// which the chain builder performs over the whole chain in isValid.
+func (c *Certificate) checkSignatureFrom(parent *Certificate, opts *SMSignatureOptions) error {
 	// RFC 5280, 4.2.1.9:
 	// "If the basic constraints extension is not present in a version 3
 	// certificate, or the extension is present but the cA boolean is not
@@ -748,9 +983,7 @@ func (c *Certificate) CheckSignatureFrom(parent *Certificate) error {
 		return x509.ErrUnsupportedAlgorithm
 	}
 
-	// TODO(agl): don't ignore the path length constraint.
-
-	return checkSignature(c.SignatureAlgorithm, c.RawTBSCertificate, c.Signature, parent.PublicKey, debugAllowSHA1)
+	return checkSignatureWithOptions(c.SignatureAlgorithm, c.RawTBSCertificate, c.Signature, parent.PublicKey, allowSHA1For(c), opts)
 }
 
 // CheckSignature verifies that signature is a valid signature over signed from
@@ -764,6 +997,13 @@ func (c *Certificate) CheckSignature(algo SignatureAlgorithm, signed, signature
 	return checkSignature(algo, signed, signature, c.PublicKey, true)
 }
 
+// CheckSignatureWithOptions is like CheckSignature, with options, such as the
+// signer ID of a SM2WithSM3 signature. A nil opts is equivalent to the zero
+// value.
+func (c *Certificate) CheckSignatureWithOptions(algo SignatureAlgorithm, signed, signature []byte, opts *SMSignatureOptions) error {
+	return checkSignatureWithOptions(algo, signed, signature, c.PublicKey, true, opts)
+}
+
 // CheckSignatureWithDigest verifies the signature of a certificate using the specified
 // signature algorithm and digest. It supports RSA, ECDSA, and SM2 public keys.
 //
@@ -775,7 +1015,7 @@ func (c *Certificate) CheckSignatureWithDigest(algo SignatureAlgorithm, digest,
 	publicKey := c.PublicKey
 
 	isSM2 := (algo == SM2WithSM3)
-	for _, details := range signatureAlgorithmDetails {
+	for _, details := range allSignatureAlgorithmDetails() {
 		if details.algo == algo {
 			hashType = details.hash
 			pubKeyAlgo = details.pubKeyAlgo
@@ -841,18 +1081,77 @@ func (c *Certificate) getSANExtension() []byte {
 	return nil
 }
 
+// FreshestCRL returns the URIs of the delta CRL distribution points of the
+// freshestCRL extension of c, RFC 5280, 4.2.1.15.
+func (c *Certificate) FreshestCRL() ([]string, error) {
+	return freshestCRL(c.Extensions)
+}
+
+func freshestCRL(extensions []pkix.Extension) ([]string, error) {
+	for _, e := range extensions {
+		if e.Id.Equal(oidExtensionFreshestCRL) {
+			return parseDistributionPoints(e.Value)
+		}
+	}
+	return nil, nil
+}
+
 func signaturePublicKeyAlgoMismatchError(expectedPubKeyAlgo PublicKeyAlgorithm, pubKey any) error {
 	return fmt.Errorf("x509: signature algorithm specifies an %s public key, but have public key of type %T", expectedPubKeyAlgo.String(), pubKey)
 }
 
+// SMSignatureOptions are the options of the signature checks of
+// Certificate.CheckSignatureWithOptions and the like.
+type SMSignatureOptions struct {
+	// UID is the signer ID of SM2WithSM3 signatures, the default
+	// "1234567812345678" of GB/T 32918.2 if empty.
+	UID []byte
+
+	// AllowWithoutZA accepts SM2WithSM3 signatures which fail the standard
+	// check but are SM2 signatures of the plain SM3 digest of the data,
+	// without the ZA of GB/T 32918.2, as some HSMs and legacy middleware
+	// produce them. Certificate.SignedWithoutZA reports whether a signature
+	// is one of them.
+	AllowWithoutZA bool
+}
+
+// uid returns the SM2 signer ID of opts, nil for the default one.
+func (opts *SMSignatureOptions) uid() []byte {
+	if opts == nil || len(opts.UID) == 0 {
+		return nil
+	}
+	return opts.UID
+}
+
 // checkSignature verifies that signature is a valid signature over signed from
 // a crypto.PublicKey.
 func checkSignature(algo SignatureAlgorithm, signed, signature []byte, publicKey crypto.PublicKey, allowSHA1 bool) (err error) {
+	return checkSignatureWithOptions(algo, signed, signature, publicKey, allowSHA1, nil)
+}
+
+// checkSignatureWithOptions is like checkSignature, with the SM2 signer ID of
+// opts.
+func checkSignatureWithOptions(algo SignatureAlgorithm, signed, signature []byte, publicKey crypto.PublicKey, allowSHA1 bool, opts *SMSignatureOptions) (err error) {
+	if details, ok := registeredSignatureAlgorithm(algo); ok {
+		if details.hash == crypto.SHA1 && !allowSHA1 {
+			return x509.InsecureAlgorithmError(algo)
+		}
+		if details.hash != 0 {
+			if !details.hash.Available() {
+				return x509.ErrUnsupportedAlgorithm
+			}
+			h := details.hash.New()
+			h.Write(signed)
+			signed = h.Sum(nil)
+		}
+		return details.verify(publicKey, signed, signature)
+	}
+
 	var hashType crypto.Hash
 	var pubKeyAlgo PublicKeyAlgorithm
 
 	isSM2 := (algo == SM2WithSM3)
-	for _, details := range signatureAlgorithmDetails {
+	for _, details := range allSignatureAlgorithmDetails() {
 		if details.algo == algo {
 			hashType = details.hash
 			pubKeyAlgo = details.pubKeyAlgo
@@ -862,7 +1161,10 @@ func checkSignature(algo SignatureAlgorithm, signed, signature []byte, publicKey
 
 	switch hashType {
 	case crypto.Hash(0):
-		if !isSM2 && pubKeyAlgo != Ed25519 {
+		if algo == RSAWithSM3 {
+			// verified as a signature of the DigestInfo, without hash
+			signed = sm3DigestInfo(signed)
+		} else if !isSM2 && pubKeyAlgo != Ed25519 {
 			return x509.ErrUnsupportedAlgorithm
 		}
 	case crypto.MD5:
@@ -897,7 +1199,14 @@ func checkSignature(algo SignatureAlgorithm, signed, signature []byte, publicKey
 			return signaturePublicKeyAlgoMismatchError(pubKeyAlgo, pub)
 		}
 		if isSM2 {
-			if !sm2.VerifyASN1WithSM2(pub, nil, signed, signature) {
+			if !sm2.VerifyASN1WithSM2(pub, opts.uid(), signed, signature) {
+				if opts == nil || !opts.AllowWithoutZA || !verifySM2WithoutZA(pub, signed, signature) {
+					return errors.New("x509: SM2 verification failure")
+				}
+			}
+		} else if algo == SM2WithSHA1 || algo == SM2WithSHA256 {
+			// the digest is signed as is, without ZA
+			if !sm2.IsSM2PublicKey(pub) || !sm2.VerifyASN1(pub, signed, signature) {
 				return errors.New("x509: SM2 verification failure")
 			}
 		} else if !ecdsa.VerifyASN1(pub, signed, signature) {
@@ -916,6 +1225,32 @@ func checkSignature(algo SignatureAlgorithm, signed, signature []byte, publicKey
 	return x509.ErrUnsupportedAlgorithm
 }
 
+// SignedWithoutZA reports whether signature is a SM2WithSM3 signature of
+// signed by the key of c which is only valid without the ZA of GB/T 32918.2,
+// as accepted with SMSignatureOptions.AllowWithoutZA. The UID of opts is the
+// signer ID of the standard check.
+//
+// For example, parent.SignedWithoutZA(cert.RawTBSCertificate,
+// cert.Signature, opts) reports whether cert was verified without ZA by
+// cert.CheckSignatureFromWithOptions(parent, opts).
+func (c *Certificate) SignedWithoutZA(signed, signature []byte, opts *SMSignatureOptions) bool {
+	pub, ok := c.PublicKey.(*ecdsa.PublicKey)
+	if !ok || !sm2.IsSM2PublicKey(pub) {
+		return false
+	}
+	return !sm2.VerifyASN1WithSM2(pub, opts.uid(), signed, signature) && verifySM2WithoutZA(pub, signed, signature)
+}
+
+// verifySM2WithoutZA reports whether signature is the SM2 signature of the
+// SM3 digest of signed, computed without ZA.
+func verifySM2WithoutZA(pub *ecdsa.PublicKey, signed, signature []byte) bool {
+	if !sm2.IsSM2PublicKey(pub) {
+		return false
+	}
+	digest := sm3.Sum(signed)
+	return sm2.VerifyASN1(pub, digest[:], signature)
+}
+
 // CheckCRLSignature checks that the signature in crl is from c.
 // Deprecated: Use RevocationList.CheckSignatureFrom instead.
 func (c *Certificate) CheckCRLSignature(crl *pkix.CertificateList) error {
@@ -954,9 +1289,12 @@ type distributionPoint struct {
 	CRLIssuer         asn1.RawValue         `asn1:"optional,tag:2"`
 }
 
+// distributionPointName is the DistributionPointName CHOICE. The
+// nameRelativeToCRLIssuer is a single RelativeDistinguishedName, which
+// pkix.RDNSequence doesn't decode, so it is kept raw.
 type distributionPointName struct {
-	FullName     []asn1.RawValue  `asn1:"optional,tag:0"`
-	RelativeName pkix.RDNSequence `asn1:"optional,tag:1"`
+	FullName     []asn1.RawValue `asn1:"optional,tag:0"`
+	RelativeName asn1.RawValue   `asn1:"optional,tag:1"`
 }
 
 func reverseBitsInAByte(in byte) byte {
@@ -987,18 +1325,28 @@ func asn1BitLength(bitString []byte) int {
 }
 
 var (
-	oidExtensionSubjectKeyId          = []int{2, 5, 29, 14}
-	oidExtensionKeyUsage              = []int{2, 5, 29, 15}
-	oidExtensionExtendedKeyUsage      = []int{2, 5, 29, 37}
-	oidExtensionAuthorityKeyId        = []int{2, 5, 29, 35}
-	oidExtensionBasicConstraints      = []int{2, 5, 29, 19}
-	oidExtensionSubjectAltName        = []int{2, 5, 29, 17}
-	oidExtensionCertificatePolicies   = []int{2, 5, 29, 32}
-	oidExtensionNameConstraints       = []int{2, 5, 29, 30}
-	oidExtensionCRLDistributionPoints = []int{2, 5, 29, 31}
-	oidExtensionAuthorityInfoAccess   = []int{1, 3, 6, 1, 5, 5, 7, 1, 1}
-	oidExtensionCRLNumber             = []int{2, 5, 29, 20}
-	oidExtensionReasonCode            = []int{2, 5, 29, 21}
+	oidExtensionSubjectKeyId             = []int{2, 5, 29, 14}
+	oidExtensionKeyUsage                 = []int{2, 5, 29, 15}
+	oidExtensionExtendedKeyUsage         = []int{2, 5, 29, 37}
+	oidExtensionAuthorityKeyId           = []int{2, 5, 29, 35}
+	oidExtensionBasicConstraints         = []int{2, 5, 29, 19}
+	oidExtensionSubjectAltName           = []int{2, 5, 29, 17}
+	oidExtensionCertificatePolicies      = []int{2, 5, 29, 32}
+	oidExtensionPolicyMappings           = []int{2, 5, 29, 33}
+	oidExtensionPolicyConstraints        = []int{2, 5, 29, 36}
+	oidExtensionInhibitAnyPolicy         = []int{2, 5, 29, 54}
+	oidExtensionNameConstraints          = []int{2, 5, 29, 30}
+	oidExtensionCRLDistributionPoints    = []int{2, 5, 29, 31}
+	oidExtensionFreshestCRL              = []int{2, 5, 29, 46}
+	oidExtensionIssuingDistributionPoint = []int{2, 5, 29, 28}
+	oidExtensionAuthorityInfoAccess      = []int{1, 3, 6, 1, 5, 5, 7, 1, 1}
+	oidExtensionCRLNumber                = []int{2, 5, 29, 20}
+	oidExtensionDeltaCRLIndicator        = []int{2, 5, 29, 27}
+	oidExtensionReasonCode               = []int{2, 5, 29, 21}
+	oidExtensionCertificateIssuer        = []int{2, 5, 29, 29}
+	oidExtensionExpiredCertsOnCRL        = []int{2, 5, 29, 60}
+	// oidExtensionNextPublish is the Microsoft CRL next publish extension.
+	oidExtensionNextPublish = []int{1, 3, 6, 1, 4, 1, 311, 21, 4}
 )
 
 var (
@@ -1019,7 +1367,7 @@ func oidInExtensions(oid asn1.ObjectIdentifier, extensions []pkix.Extension) boo
 
 // marshalSANs marshals a list of addresses into a the contents of an X.509
 // SubjectAlternativeName extension.
-func marshalSANs(dnsNames, emailAddresses []string, ipAddresses []net.IP, uris []*url.URL) (derBytes []byte, err error) {
+func marshalSANs(dnsNames, emailAddresses []string, ipAddresses []net.IP, uris []*url.URL, otherNames []OtherName, extraSANs []asn1.RawValue) (derBytes []byte, err error) {
 	var rawValues []asn1.RawValue
 	for _, name := range dnsNames {
 		if err := isIA5String(name); err != nil {
@@ -1048,6 +1396,14 @@ func marshalSANs(dnsNames, emailAddresses []string, ipAddresses []net.IP, uris [
 		}
 		rawValues = append(rawValues, asn1.RawValue{Tag: nameTypeURI, Class: 2, Bytes: []byte(uriStr)})
 	}
+	for _, otherName := range otherNames {
+		rawValue, err := marshalOtherName(otherName)
+		if err != nil {
+			return nil, err
+		}
+		rawValues = append(rawValues, rawValue)
+	}
+	rawValues = append(rawValues, extraSANs...)
 	return asn1.Marshal(rawValues)
 }
 
@@ -1062,8 +1418,29 @@ func isIA5String(s string) error {
 	return nil
 }
 
-func buildCertExtensions(template *x509.Certificate, subjectIsEmpty bool, authorityKeyId, subjectKeyId []byte) (ret []pkix.Extension, err error) {
-	ret = make([]pkix.Extension, 10 /* maximum number of elements. */)
+// marshalDistributionPoints returns a CRL distribution points or freshest CRL
+// extension value with a full name URI for each of uris.
+func marshalDistributionPoints(uris []string) ([]byte, error) {
+	var dps []distributionPoint
+	for _, name := range uris {
+		dp := distributionPoint{
+			DistributionPoint: distributionPointName{
+				FullName: []asn1.RawValue{
+					{Tag: 6, Class: 2, Bytes: []byte(name)},
+				},
+			},
+		}
+		dps = append(dps, dp)
+	}
+	return asn1.Marshal(dps)
+}
+
+func buildCertExtensions(template *x509.Certificate, opts *CreateCertificateOptions, subjectIsEmpty bool, authorityKeyId, subjectKeyId []byte) (ret []pkix.Extension, err error) {
+	if opts == nil {
+		opts = &CreateCertificateOptions{}
+	}
+
+	ret = make([]pkix.Extension, 14 /* maximum number of elements. */)
 	n := 0
 
 	if template.KeyUsage != 0 &&
@@ -1133,30 +1510,36 @@ func buildCertExtensions(template *x509.Certificate, subjectIsEmpty bool, author
 		n++
 	}
 
-	if (len(template.DNSNames) > 0 || len(template.EmailAddresses) > 0 || len(template.IPAddresses) > 0 || len(template.URIs) > 0) &&
+	if (len(template.DNSNames) > 0 || len(template.EmailAddresses) > 0 || len(template.IPAddresses) > 0 || len(template.URIs) > 0 || len(opts.OtherNames) > 0 || len(opts.ExtraSANs) > 0) &&
 		!oidInExtensions(oidExtensionSubjectAltName, template.ExtraExtensions) {
 		ret[n].Id = oidExtensionSubjectAltName
 		// From RFC 5280, Section 4.2.1.6:
 		// “If the subject field contains an empty sequence ... then
 		// subjectAltName extension ... is marked as critical”
 		ret[n].Critical = subjectIsEmpty
-		ret[n].Value, err = marshalSANs(template.DNSNames, template.EmailAddresses, template.IPAddresses, template.URIs)
+		ret[n].Value, err = marshalSANs(template.DNSNames, template.EmailAddresses, template.IPAddresses, template.URIs, opts.OtherNames, opts.ExtraSANs)
 		if err != nil {
 			return
 		}
 		n++
 	}
 
-	var usePolicies = godebug.Get("x509usepolicies") != "0"
+	var usePolicies = usePolicies()
 	if ((!usePolicies && len(template.PolicyIdentifiers) > 0) || (usePolicies && len(template.Policies) > 0)) &&
 		!oidInExtensions(oidExtensionCertificatePolicies, template.ExtraExtensions) {
-		ret[n], err = marshalCertificatePolicies(template.Policies, template.PolicyIdentifiers)
+		ret[n], err = marshalCertificatePolicies(usePolicies, template.Policies, template.PolicyIdentifiers)
 		if err != nil {
 			return nil, err
 		}
 		n++
 	}
 
+	policyExtensions, err := marshalPolicyExtensions(template)
+	if err != nil {
+		return nil, err
+	}
+	n += copy(ret[n:], policyExtensions)
+
 	if (len(template.PermittedDNSDomains) > 0 || len(template.ExcludedDNSDomains) > 0 ||
 		len(template.PermittedIPRanges) > 0 || len(template.ExcludedIPRanges) > 0 ||
 		len(template.PermittedEmailAddresses) > 0 || len(template.ExcludedEmailAddresses) > 0 ||
@@ -1255,23 +1638,20 @@ func buildCertExtensions(template *x509.Certificate, subjectIsEmpty bool, author
 		n++
 	}
 
-	if len(template.CRLDistributionPoints) > 0 &&
+	if (len(template.CRLDistributionPoints) > 0 || len(opts.DistributionPoints) > 0) &&
 		!oidInExtensions(oidExtensionCRLDistributionPoints, template.ExtraExtensions) {
 		ret[n].Id = oidExtensionCRLDistributionPoints
-
-		var crlDp []distributionPoint
-		for _, name := range template.CRLDistributionPoints {
-			dp := distributionPoint{
-				DistributionPoint: distributionPointName{
-					FullName: []asn1.RawValue{
-						{Tag: 6, Class: 2, Bytes: []byte(name)},
-					},
-				},
-			}
-			crlDp = append(crlDp, dp)
+		ret[n].Value, err = marshalFullDistributionPoints(template.CRLDistributionPoints, opts.DistributionPoints)
+		if err != nil {
+			return
 		}
+		n++
+	}
 
-		ret[n].Value, err = asn1.Marshal(crlDp)
+	if len(opts.FreshestCRL) > 0 &&
+		!oidInExtensions(oidExtensionFreshestCRL, template.ExtraExtensions) {
+		ret[n].Id = oidExtensionFreshestCRL
+		ret[n].Value, err = marshalDistributionPoints(opts.FreshestCRL)
 		if err != nil {
 			return
 		}
@@ -1282,7 +1662,34 @@ func buildCertExtensions(template *x509.Certificate, subjectIsEmpty bool, author
 	// of elements in the make() at the top of the function and the list of
 	// template fields used in CreateCertificate documentation.
 
-	return append(ret[:n], template.ExtraExtensions...), nil
+	ret = ret[:n]
+	if len(opts.TLSFeatures) > 0 && !oidInExtensions(oidExtensionTLSFeature, template.ExtraExtensions) {
+		value, err := marshalTLSFeatures(opts.TLSFeatures)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, pkix.Extension{Id: oidExtensionTLSFeature, Value: value})
+	}
+	if len(opts.SCTList) > 0 && !oidInExtensions(oidExtensionSCTList, template.ExtraExtensions) {
+		value, err := MarshalSCTList(opts.SCTList)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, pkix.Extension{Id: oidExtensionSCTList, Value: value})
+	}
+	if opts.SubjectIdentity != nil {
+		identityExtensions, err := MarshalSubjectIdentityExtensions(opts.SubjectIdentity)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range identityExtensions {
+			if !oidInExtensions(e.Id, template.ExtraExtensions) {
+				ret = append(ret, e)
+			}
+		}
+	}
+
+	return append(ret, template.ExtraExtensions...), nil
 }
 
 func marshalKeyUsage(ku KeyUsage) (pkix.Extension, error) {
@@ -1335,10 +1742,9 @@ func marshalBasicConstraints(isCA bool, maxPathLen int, maxPathLenZero bool) (pk
 	return ext, err
 }
 
-func marshalCertificatePolicies(policies []x509.OID, policyIdentifiers []asn1.ObjectIdentifier) (pkix.Extension, error) {
+func marshalCertificatePolicies(usePolicies bool, policies []x509.OID, policyIdentifiers []asn1.ObjectIdentifier) (pkix.Extension, error) {
 	ext := pkix.Extension{Id: oidExtensionCertificatePolicies}
 
-	var usePolicies = godebug.Get("x509usepolicies") != "0"
 	b := cryptobyte.NewBuilder(make([]byte, 0, 128))
 	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(child *cryptobyte.Builder) {
 		if usePolicies {
@@ -1363,12 +1769,12 @@ func marshalCertificatePolicies(policies []x509.OID, policyIdentifiers []asn1.Ob
 	return ext, err
 }
 
-func buildCSRExtensions(template *x509.CertificateRequest) ([]pkix.Extension, error) {
+func buildCSRExtensions(template *x509.CertificateRequest, otherNames []OtherName, extraSANs []asn1.RawValue, usage *RequestedUsage) ([]pkix.Extension, error) {
 	var ret []pkix.Extension
 
-	if (len(template.DNSNames) > 0 || len(template.EmailAddresses) > 0 || len(template.IPAddresses) > 0 || len(template.URIs) > 0) &&
+	if (len(template.DNSNames) > 0 || len(template.EmailAddresses) > 0 || len(template.IPAddresses) > 0 || len(template.URIs) > 0 || len(otherNames) > 0 || len(extraSANs) > 0) &&
 		!oidInExtensions(oidExtensionSubjectAltName, template.ExtraExtensions) {
-		sanBytes, err := marshalSANs(template.DNSNames, template.EmailAddresses, template.IPAddresses, template.URIs)
+		sanBytes, err := marshalSANs(template.DNSNames, template.EmailAddresses, template.IPAddresses, template.URIs, otherNames, extraSANs)
 		if err != nil {
 			return nil, err
 		}
@@ -1379,6 +1785,14 @@ func buildCSRExtensions(template *x509.CertificateRequest) ([]pkix.Extension, er
 		})
 	}
 
+	if usage != nil {
+		usageExtensions, err := usage.extensions(template.ExtraExtensions)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, usageExtensions...)
+	}
+
 	return append(ret, template.ExtraExtensions...), nil
 }
 
@@ -1390,15 +1804,32 @@ func subjectBytes(cert *x509.Certificate) ([]byte, error) {
 	return asn1.Marshal(cert.Subject.ToRDNSequence())
 }
 
+// isRawName reports whether der is exactly one well-formed RDNSequence.
+func isRawName(der []byte) bool {
+	input := cryptobyte.String(der)
+	var name cryptobyte.String
+	if !input.ReadASN1Element(&name, cryptobyte_asn1.SEQUENCE) || !input.Empty() {
+		return false
+	}
+	_, err := ParseName(name)
+	return err == nil
+}
+
 // signingParamsForKey returns the signature algorithm and its Algorithm
 // Identifier to use for signing, based on the key type. If sigAlgo is not zero
 // then it overrides the default.
 func signingParamsForKey(key crypto.Signer, sigAlgo SignatureAlgorithm) (SignatureAlgorithm, pkix.AlgorithmIdentifier, error) {
+	return signingParamsForPublicKey(key.Public(), sigAlgo)
+}
+
+// signingParamsForPublicKey is like signingParamsForKey, for the public key of
+// the signer.
+func signingParamsForPublicKey(pub crypto.PublicKey, sigAlgo SignatureAlgorithm) (SignatureAlgorithm, pkix.AlgorithmIdentifier, error) {
 	var ai pkix.AlgorithmIdentifier
 	var pubType PublicKeyAlgorithm
 	var defaultAlgo SignatureAlgorithm
 
-	switch pub := key.Public().(type) {
+	switch pub := pub.(type) {
 	case *rsa.PublicKey:
 		pubType = RSA
 		defaultAlgo = SHA256WithRSA
@@ -1429,10 +1860,14 @@ func signingParamsForKey(key crypto.Signer, sigAlgo SignatureAlgorithm) (Signatu
 	if sigAlgo == 0 {
 		sigAlgo = defaultAlgo
 	}
+	if sigAlgo == SM2WithSHA1 || sigAlgo == SM2WithSHA256 {
+		return 0, ai, errors.New("x509: signing with SM2WithSHA1 or SM2WithSHA256 is not supported, they are only verified for legacy certificates")
+	}
 
-	for _, details := range signatureAlgorithmDetails {
+	for _, details := range allSignatureAlgorithmDetails() {
 		if details.algo == sigAlgo {
-			if details.pubKeyAlgo != pubType || (sigAlgo != defaultAlgo && defaultAlgo == SM2WithSM3) {
+			// SM2 keys sign only with SM2WithSM3, or a registered algorithm
+			if details.pubKeyAlgo != pubType || (sigAlgo != defaultAlgo && defaultAlgo == SM2WithSM3 && details.verify == nil) {
 				return 0, ai, errors.New("x509: requested SignatureAlgorithm does not match private key type")
 			}
 			if details.hash == crypto.MD5 {
@@ -1449,7 +1884,21 @@ func signingParamsForKey(key crypto.Signer, sigAlgo SignatureAlgorithm) (Signatu
 	return 0, ai, errors.New("x509: unknown SignatureAlgorithm")
 }
 
+// setSM2NullParameters sets the parameters of ai to NULL if sigAlgo is
+// SM2WithSM3.
+func setSM2NullParameters(sigAlgo SignatureAlgorithm, ai *pkix.AlgorithmIdentifier) {
+	if sigAlgo == SM2WithSM3 {
+		ai.Parameters = asn1.NullRawValue
+	}
+}
+
 func signTBS(tbs []byte, key crypto.Signer, sigAlg SignatureAlgorithm, rand io.Reader) ([]byte, error) {
+	return signTBSWithUID(tbs, key, sigAlg, nil, rand)
+}
+
+// signTBSWithUID is like signTBS, with the SM2 signer ID uid, the default one
+// if empty.
+func signTBSWithUID(tbs []byte, key crypto.Signer, sigAlg SignatureAlgorithm, uid []byte, rand io.Reader) ([]byte, error) {
 	signed := tbs
 	hashFunc := hashFunc(sigAlg)
 	if hashFunc != 0 {
@@ -1466,6 +1915,12 @@ func signTBS(tbs []byte, key crypto.Signer, sigAlg SignatureAlgorithm, rand io.R
 		}
 	} else if sigAlg == SM2WithSM3 {
 		signerOpts = sm2.DefaultSM2SignerOpts
+		if len(uid) > 0 {
+			signerOpts = sm2.NewSM2SignerOption(true, uid)
+		}
+	} else if sigAlg == RSAWithSM3 {
+		// the signer pads the DigestInfo as is, as there is no SM3 crypto.Hash
+		signed = sm3DigestInfo(tbs)
 	}
 
 	signature, err := key.Sign(rand, signed, signerOpts)
@@ -1474,13 +1929,53 @@ func signTBS(tbs []byte, key crypto.Signer, sigAlg SignatureAlgorithm, rand io.R
 	}
 
 	// Check the signature to ensure the crypto.Signer behaved correctly.
-	if err := checkSignature(sigAlg, tbs, signature, key.Public(), true); err != nil {
-		return nil, fmt.Errorf("x509: signature returned by signer is invalid: %w", err)
+	checkOpts := &SMSignatureOptions{UID: uid}
+	if err := checkSignatureWithOptions(sigAlg, tbs, signature, key.Public(), true, checkOpts); err != nil {
+		// Some PKCS #11 tokens and KMS return SM2 and ECDSA P-256 signatures
+		// as r || s instead of ASN.1 DER.
+		if !isRawECSignature(signature, sigAlg, key.Public()) {
+			return nil, fmt.Errorf("x509: signature returned by signer is invalid: %w", err)
+		}
+		der, derErr := rawECSignatureToASN1(signature)
+		if derErr != nil || checkSignatureWithOptions(sigAlg, tbs, der, key.Public(), true, checkOpts) != nil {
+			return nil, fmt.Errorf("x509: signature returned by signer is invalid: %w", err)
+		}
+		signature = der
 	}
 
 	return signature, nil
 }
 
+// isRawECSignature reports whether signature may be the r || s form of a
+// SM2 or ECDSA signature with a 256-bit key.
+func isRawECSignature(signature []byte, sigAlg SignatureAlgorithm, pub crypto.PublicKey) bool {
+	ecPub, ok := pub.(*ecdsa.PublicKey)
+	if !ok || ecPub.Curve.Params().BitSize != 256 || len(signature) != 64 {
+		return false
+	}
+	if sigAlg == SM2WithSM3 {
+		return true
+	}
+	for _, details := range allSignatureAlgorithmDetails() {
+		if details.algo == sigAlg {
+			return details.pubKeyAlgo == ECDSA
+		}
+	}
+	return false
+}
+
+// rawECSignatureToASN1 encodes the r || s form of an elliptic curve signature
+// as an ASN.1 DER SEQUENCE of two INTEGERs.
+func rawECSignatureToASN1(signature []byte) ([]byte, error) {
+	half := len(signature) / 2
+	var b cryptobyte.Builder
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1BigInt(new(big.Int).SetBytes(signature[:half]))
+		b.AddASN1BigInt(new(big.Int).SetBytes(signature[half:]))
+	})
+	return b.Bytes()
+}
+
 // emptyASN1Subject is the ASN.1 DER encoding of an empty Subject, which is
 // just an empty SEQUENCE.
 var emptyASN1Subject = []byte{0x30, 0}
@@ -1514,6 +2009,11 @@ var emptyASN1Subject = []byte{0x30, 0}
 //   - PermittedIPRanges
 //   - PermittedURIDomains
 //   - PolicyIdentifiers
+//   - Policies
+//   - PolicyMappings (Go 1.24 or later)
+//   - RequireExplicitPolicy, RequireExplicitPolicyZero (Go 1.24 or later)
+//   - InhibitPolicyMapping, InhibitPolicyMappingZero (Go 1.24 or later)
+//   - InhibitAnyPolicy, InhibitAnyPolicyZero (Go 1.24 or later)
 //   - SerialNumber
 //   - SignatureAlgorithm
 //   - Subject
@@ -1530,18 +2030,137 @@ var emptyASN1Subject = []byte{0x30, 0}
 //
 // The currently supported key types are *rsa.PublicKey, *ecdsa.PublicKey and
 // ed25519.PublicKey. pub must be a supported key type, and priv must be a
-// crypto.Signer with a supported public key.
+// crypto.Signer with a supported public key. pub may also be an SM2 or NIST
+// curve *ecdh.PublicKey, of this module or of crypto/ecdh, such as the key of
+// an SM2 encryption certificate, it is then encoded as the equivalent
+// *ecdsa.PublicKey. A 64 bytes r || s signature returned by priv for SM2 or
+// ECDSA P-256, as some PKCS #11 tokens and KMS do, is encoded in ASN.1 DER.
 //
 // The AuthorityKeyId will be taken from the SubjectKeyId of parent, if any,
 // unless the resulting certificate is self-signed. Otherwise the value from
 // template will be used.
 //
 // If SubjectKeyId from template is empty and the template is a CA, SubjectKeyId
-// will be generated from the hash of the public key.
+// will be generated from the hash of the public key. See
+// CreateCertificateOptions.GenerateSubjectKeyId for other certificates.
 //
 // If template.SerialNumber is nil, a serial number will be generated which
 // conforms to RFC 5280, Section 4.1.2.2 using entropy from rand.
 func CreateCertificate(rand io.Reader, template, parent, pub, priv any) ([]byte, error) {
+	return CreateCertificateWithOptions(rand, template, parent, pub, priv, nil)
+}
+
+// CreateCertificateOptions contains options for CreateCertificateWithOptions.
+type CreateCertificateOptions struct {
+	// AllowOpaqueSigner allows a crypto.Signer whose public key does not
+	// implement Equal, such as the wrapper types returned by some HSM signers.
+	// The signer is then checked by signing a random challenge and verifying it
+	// with the parent's public key, or pub for a self-signed certificate whose
+	// parent has no public key.
+	AllowOpaqueSigner bool
+
+	// OtherNames are added to the subject alternative name extension, after
+	// the names of template, unless template.ExtraExtensions has one.
+	OtherNames []OtherName
+	// ExtraSANs are complete GeneralName values, such as the ones returned by
+	// Certificate.UnhandledSANs, added to the subject alternative name
+	// extension after OtherNames.
+	ExtraSANs []asn1.RawValue
+
+	// SubjectIdentity adds the GM/T 0015 subject identity extensions, as
+	// encoded by MarshalSubjectIdentityExtensions, except the ones present in
+	// template.ExtraExtensions.
+	SubjectIdentity *SubjectIdentity
+
+	// DistributionPoints are added to the CRL distribution points extension
+	// after the URIs of template.CRLDistributionPoints, unless
+	// template.ExtraExtensions has one. Unlike the URIs, they can carry the
+	// reasons and the issuer of an indirect CRL.
+	DistributionPoints []DistributionPoint
+
+	// FreshestCRL are the URIs of the delta CRL distribution points, encoded
+	// in a freshestCRL extension like template.CRLDistributionPoints.
+	FreshestCRL []string
+
+	// SCTList are the TLS encoded SCTs of an embedded SCT list extension,
+	// see MarshalSCTList, unless template.ExtraExtensions has one.
+	SCTList [][]byte
+
+	// TLSFeatures are the TLS extension types of a TLS feature extension,
+	// RFC 7633, such as TLSFeatureStatusRequest for OCSP must-staple, unless
+	// template.ExtraExtensions has one.
+	TLSFeatures []int
+
+	// GenerateSubjectKeyId generates the SubjectKeyId of end-entity
+	// certificates too, when template.SubjectKeyId is empty, as required by
+	// some GM/T application profiles.
+	GenerateSubjectKeyId bool
+	// SubjectKeyIdHash is the hash of the generated SubjectKeyId, of CA and
+	// end-entity certificates alike.
+	SubjectKeyIdHash SubjectKeyIdHash
+
+	// RawIssuer is the DER encoded issuer Name, used instead of the subject
+	// of parent, so that the string types of an issuer parsed elsewhere are
+	// kept when parent is built in memory without a RawSubject.
+	RawIssuer []byte
+
+	// StrictSerialNumber rejects a zero template.SerialNumber or one which
+	// encodes to more than 20 octets, including the leading zero octet of a
+	// serial number whose top bit is set, with a SerialNumberError.
+	StrictSerialNumber bool
+
+	// SM2NullParameters encodes the parameters of the SM2WithSM3 signature
+	// algorithm identifier as NULL instead of absent, for verifiers which
+	// demand it.
+	SM2NullParameters bool
+}
+
+// SerialNumberError results when a serial number doesn't conform to
+// RFC 5280, Section 4.1.2.2 in the strict serial number mode of
+// CreateCertificateOptions and ParseOptions.
+type SerialNumberError struct {
+	SerialNumber *big.Int
+	// Length is the length in octets of the encoded serial number, without
+	// the tag and length.
+	Length int
+}
+
+func (e SerialNumberError) Error() string {
+	switch e.SerialNumber.Sign() {
+	case 0:
+		return "x509: serial number is zero"
+	case -1:
+		return "x509: serial number is negative"
+	}
+	return fmt.Sprintf("x509: serial number is %d octets long, more than 20", e.Length)
+}
+
+// checkSerialNumber returns a SerialNumberError if serial is not positive or
+// encodes to more than 20 octets.
+func checkSerialNumber(serial *big.Int) error {
+	var b cryptobyte.Builder
+	b.AddASN1BigInt(serial)
+	der := cryptobyte.String(b.BytesOrPanic())
+	var content cryptobyte.String
+	der.ReadASN1(&content, cryptobyte_asn1.INTEGER)
+	if serial.Sign() <= 0 || len(content) > 20 {
+		return SerialNumberError{SerialNumber: serial, Length: len(content)}
+	}
+	return nil
+}
+
+// SubjectKeyIdHash is the hash of the public key used to generate a
+// SubjectKeyId with method 1 of RFC 7093, Section 2.
+type SubjectKeyIdHash int
+
+const (
+	SubjectKeyIdSHA256 SubjectKeyIdHash = iota // the leftmost 160 bits of the SHA-256 hash
+	SubjectKeyIdSM3                            // the leftmost 160 bits of the SM3 hash
+)
+
+// CreateCertificateWithOptions is like CreateCertificate, with options.
+// A nil opts is equivalent to the zero value.
+func CreateCertificateWithOptions(rand io.Reader, template, parent, pub, priv any, opts *CreateCertificateOptions) ([]byte, error) {
 	realTemplate, err := toCertificate(template)
 	if err != nil {
 		return nil, fmt.Errorf("x509: unsupported template parameter type: %T", template)
@@ -1557,6 +2176,37 @@ func CreateCertificate(rand io.Reader, template, parent, pub, priv any) ([]byte,
 		return nil, errors.New("x509: certificate private key does not implement crypto.Signer")
 	}
 
+	if opts != nil && opts.AllowOpaqueSigner {
+		if _, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool }); !ok {
+			parentPub := realParent.PublicKey
+			if parentPub == nil && template == parent {
+				parentPub = pub
+			}
+			if key, err = newOpaqueSigner(rand, key, parentPub, realTemplate.SignatureAlgorithm); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	c, signatureAlgorithm, err := createTBSCertificate(rand, realTemplate, realParent, pub, key, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := signTBS(c.Raw, key, signatureAlgorithm, rand)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(certificate{
+		TBSCertificate:     c,
+		SignatureAlgorithm: c.SignatureAlgorithm,
+		SignatureValue:     asn1.BitString{Bytes: signature, BitLength: len(signature) * 8},
+	})
+}
+
+// createTBSCertificate returns the TBSCertificate of CreateCertificate, with
+// its Raw encoding, and the algorithm to sign it with using key.
+func createTBSCertificate(rand io.Reader, realTemplate, realParent *x509.Certificate, pub any, key crypto.Signer, opts *CreateCertificateOptions) (tbsCertificate, SignatureAlgorithm, error) {
 	serialNumber := realTemplate.SerialNumber
 	if serialNumber == nil {
 		// Generate a serial number following RFC 5280, Section 4.1.2.2 if one
@@ -1564,7 +2214,7 @@ func CreateCertificate(rand io.Reader, template, parent, pub, priv any) ([]byte,
 		// octets *when encoded*.
 		serialBytes := make([]byte, 20)
 		if _, err := io.ReadFull(rand, serialBytes); err != nil {
-			return nil, err
+			return tbsCertificate{}, 0, err
 		}
 		// If the top bit is set, the serial will be padded with a leading zero
 		// byte during encoding, so that it's not interpreted as a negative
@@ -1578,41 +2228,56 @@ func CreateCertificate(rand io.Reader, template, parent, pub, priv any) ([]byte,
 
 	// We _should_ also restrict serials to <= 20 octets, but it turns out a lot of people
 	// get this wrong, in part because the encoding can itself alter the length of the
-	// serial. For now we accept these non-conformant serials.
+	// serial. For now we accept these non-conformant serials, unless
+	// StrictSerialNumber is set.
 	if serialNumber.Sign() == -1 {
-		return nil, errors.New("x509: serial number must be positive")
+		return tbsCertificate{}, 0, errors.New("x509: serial number must be positive")
+	}
+	if opts != nil && opts.StrictSerialNumber {
+		if err := checkSerialNumber(serialNumber); err != nil {
+			return tbsCertificate{}, 0, err
+		}
 	}
 
 	if realTemplate.BasicConstraintsValid && realTemplate.MaxPathLen < -1 {
-		return nil, errors.New("x509: invalid MaxPathLen, must be greater or equal to -1")
+		return tbsCertificate{}, 0, errors.New("x509: invalid MaxPathLen, must be greater or equal to -1")
 	}
 
 	if realTemplate.BasicConstraintsValid && !realTemplate.IsCA && realTemplate.MaxPathLen != -1 && (realTemplate.MaxPathLen != 0 || realTemplate.MaxPathLenZero) {
-		return nil, errors.New("x509: only CAs are allowed to specify MaxPathLen")
+		return tbsCertificate{}, 0, errors.New("x509: only CAs are allowed to specify MaxPathLen")
 	}
 
 	signatureAlgorithm, algorithmIdentifier, err := signingParamsForKey(key, realTemplate.SignatureAlgorithm)
 	if err != nil {
-		return nil, err
+		return tbsCertificate{}, 0, err
+	}
+	if opts != nil && opts.SM2NullParameters {
+		setSM2NullParameters(signatureAlgorithm, &algorithmIdentifier)
 	}
 
 	publicKeyBytes, publicKeyAlgorithm, err := marshalPublicKey(pub)
 	if err != nil {
-		return nil, err
+		return tbsCertificate{}, 0, err
 	}
 
-	if getPublicKeyAlgorithmFromOID(publicKeyAlgorithm.Algorithm) == UnknownPublicKeyAlgorithm {
-		return nil, fmt.Errorf("x509: unsupported public key type: %T", pub)
+	if _, ok := pub.(UnknownPublicKey); !ok && getPublicKeyAlgorithmFromOID(publicKeyAlgorithm.Algorithm) == UnknownPublicKeyAlgorithm {
+		return tbsCertificate{}, 0, fmt.Errorf("x509: unsupported public key type: %T", pub)
 	}
 
 	asn1Issuer, err := subjectBytes(realParent)
 	if err != nil {
-		return nil, err
+		return tbsCertificate{}, 0, err
+	}
+	if opts != nil && len(opts.RawIssuer) > 0 {
+		if !isRawName(opts.RawIssuer) {
+			return tbsCertificate{}, 0, errors.New("x509: RawIssuer is not a well-formed RDNSequence")
+		}
+		asn1Issuer = opts.RawIssuer
 	}
 
 	asn1Subject, err := subjectBytes(realTemplate)
 	if err != nil {
-		return nil, err
+		return tbsCertificate{}, 0, err
 	}
 
 	authorityKeyId := realTemplate.AuthorityKeyId
@@ -1621,13 +2286,19 @@ func CreateCertificate(rand io.Reader, template, parent, pub, priv any) ([]byte,
 	}
 
 	subjectKeyId := realTemplate.SubjectKeyId
-	if len(subjectKeyId) == 0 && realTemplate.IsCA {
+	if len(subjectKeyId) == 0 && (realTemplate.IsCA || opts != nil && opts.GenerateSubjectKeyId) {
 		// SubjectKeyId generated using method 1 in RFC 7093, Section 2:
 		//    1) The keyIdentifier is composed of the leftmost 160-bits of the
 		//    SHA-256 hash of the value of the BIT STRING subjectPublicKey
 		//    (excluding the tag, length, and number of unused bits).
-		h := sha256.Sum256(publicKeyBytes)
-		subjectKeyId = h[:20]
+		// SM3 may be used instead of SHA-256, as GM/T profiles do.
+		if opts != nil && opts.SubjectKeyIdHash == SubjectKeyIdSM3 {
+			h := sm3.Sum(publicKeyBytes)
+			subjectKeyId = h[:20]
+		} else {
+			h := sha256.Sum256(publicKeyBytes)
+			subjectKeyId = h[:20]
+		}
 	}
 
 	// Check that the signer's public key matches the private key, if available.
@@ -1636,14 +2307,16 @@ func CreateCertificate(rand io.Reader, template, parent, pub, priv any) ([]byte,
 	}
 
 	if privPub, ok := key.Public().(privateKey); !ok {
-		return nil, errors.New("x509: internal error: supported public key does not implement Equal")
-	} else if realParent.PublicKey != nil && !privPub.Equal(realParent.PublicKey) {
-		return nil, errors.New("x509: provided PrivateKey doesn't match parent's PublicKey")
+		return tbsCertificate{}, 0, errors.New("x509: internal error: supported public key does not implement Equal")
+	} else if realParent.PublicKey != nil && !privPub.Equal(realParent.PublicKey) &&
+		// The parent's EC key may be in its ecdh form.
+		CheckPublicKey((*Certificate)(realParent), privPub) != nil {
+		return tbsCertificate{}, 0, errors.New("x509: provided PrivateKey doesn't match parent's PublicKey")
 	}
 
-	extensions, err := buildCertExtensions(realTemplate, bytes.Equal(asn1Subject, emptyASN1Subject), authorityKeyId, subjectKeyId)
+	extensions, err := buildCertExtensions(realTemplate, opts, bytes.Equal(asn1Subject, emptyASN1Subject), authorityKeyId, subjectKeyId)
 	if err != nil {
-		return nil, err
+		return tbsCertificate{}, 0, err
 	}
 
 	encodedPublicKey := asn1.BitString{BitLength: len(publicKeyBytes) * 8, Bytes: publicKeyBytes}
@@ -1660,19 +2333,42 @@ func CreateCertificate(rand io.Reader, template, parent, pub, priv any) ([]byte,
 
 	tbsCertContents, err := asn1.Marshal(c)
 	if err != nil {
-		return nil, err
+		return tbsCertificate{}, 0, err
 	}
 	c.Raw = tbsCertContents
+	return c, signatureAlgorithm, nil
+}
 
-	signature, err := signTBS(tbsCertContents, key, signatureAlgorithm, rand)
+// opaqueSigner is a crypto.Signer whose public key is known only to match
+// the one of the parent certificate.
+type opaqueSigner struct {
+	crypto.Signer
+	pub crypto.PublicKey
+}
+
+func (s *opaqueSigner) Public() crypto.PublicKey {
+	return s.pub
+}
+
+// newOpaqueSigner checks that key signs for pub with a random challenge, and
+// returns key with pub as its public key.
+func newOpaqueSigner(rand io.Reader, key crypto.Signer, pub crypto.PublicKey, sigAlgo SignatureAlgorithm) (crypto.Signer, error) {
+	if pub == nil {
+		return nil, errors.New("x509: parent's PublicKey is required for an opaque signer")
+	}
+	signer := &opaqueSigner{key, pub}
+	sigAlgo, _, err := signingParamsForKey(signer, sigAlgo)
 	if err != nil {
 		return nil, err
 	}
-	return asn1.Marshal(certificate{
-		TBSCertificate:     c,
-		SignatureAlgorithm: algorithmIdentifier,
-		SignatureValue:     asn1.BitString{Bytes: signature, BitLength: len(signature) * 8},
-	})
+	challenge := make([]byte, 32)
+	if _, err := io.ReadFull(rand, challenge); err != nil {
+		return nil, err
+	}
+	if _, err := signTBS(challenge, signer, sigAlgo, rand); err != nil {
+		return nil, fmt.Errorf("x509: provided PrivateKey doesn't match parent's PublicKey: %w", err)
+	}
+	return signer, nil
 }
 
 func toCertificate(in any) (*x509.Certificate, error) {
@@ -1891,26 +2587,104 @@ func parseCSRExtensions(rawAttributes []asn1.RawValue) ([]pkix.Extension, error)
 //
 // The returned slice is the certificate request in DER encoding.
 func CreateCertificateRequest(rand io.Reader, template *x509.CertificateRequest, priv any) (csr []byte, err error) {
+	return CreateCertificateRequestWithOptions(rand, template, priv, nil)
+}
+
+// CreateCertificateRequestOptions contains options for
+// CreateCertificateRequestWithOptions.
+type CreateCertificateRequestOptions struct {
+	// OtherNames are added to the requested subject alternative name
+	// extension, after the names of template, unless template.ExtraExtensions
+	// has one.
+	OtherNames []OtherName
+	// ExtraSANs are complete GeneralName values added to the requested
+	// subject alternative name extension after OtherNames.
+	ExtraSANs []asn1.RawValue
+
+	// Usage is the key usage, extended key usage and basic constraints
+	// extensions to request, which must not be in template.ExtraExtensions
+	// too. CertificateRequest.RequestedUsage parses them back.
+	Usage RequestedUsage
+
+	// SM2NullParameters is like CreateCertificateOptions.SM2NullParameters.
+	SM2NullParameters bool
+	// UID is the signer ID of SM2 keys, the default "1234567812345678" of
+	// GB/T 32918.2 if empty. The request is then checked with
+	// CertificateRequest.CheckSignatureWithOptions and the same UID.
+	UID []byte
+
+	// ExtraAttributes are added as they are after the other attributes, for
+	// example the attributes of UnhandledAttributes. They can't be
+	// extension request attributes.
+	ExtraAttributes []RawAttribute
+
+	// ChallengePassword, if not empty, is added as the challengePassword
+	// attribute, RFC 2985, 5.4.1, for example the one-time password of a
+	// SCEP enrollment. It is at most 255 characters, and encoded as a
+	// PrintableString, or a UTF8String if it has other characters.
+	ChallengePassword string
+}
+
+// CreateCertificateRequestWithOptions is like CreateCertificateRequest, with
+// options. A nil opts is equivalent to the zero value.
+func CreateCertificateRequestWithOptions(rand io.Reader, template *x509.CertificateRequest, priv any, opts *CreateCertificateRequestOptions) (csr []byte, err error) {
 	key, ok := priv.(crypto.Signer)
 	if !ok {
 		return nil, errors.New("x509: certificate private key does not implement crypto.Signer")
 	}
 
-	signatureAlgorithm, algorithmIdentifier, err := signingParamsForKey(key, template.SignatureAlgorithm)
+	tbsCSR, signatureAlgorithm, algorithmIdentifier, err := createTBSCertificateRequest(template, key.Public(), opts)
 	if err != nil {
 		return nil, err
 	}
 
+	var uid []byte
+	if opts != nil {
+		uid = opts.UID
+	}
+	signature, err := signTBSWithUID(tbsCSR.Raw, key, signatureAlgorithm, uid, rand)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(certificateRequest{
+		TBSCSR:             tbsCSR,
+		SignatureAlgorithm: algorithmIdentifier,
+		SignatureValue: asn1.BitString{
+			Bytes:     signature,
+			BitLength: len(signature) * 8,
+		},
+	})
+}
+
+// createTBSCertificateRequest returns the CertificationRequestInfo of
+// CreateCertificateRequest for the public key pub, with its Raw encoding, and
+// the algorithm to sign it with.
+func createTBSCertificateRequest(template *x509.CertificateRequest, pub crypto.PublicKey, opts *CreateCertificateRequestOptions) (tbsCertificateRequest, SignatureAlgorithm, pkix.AlgorithmIdentifier, error) {
+	signatureAlgorithm, algorithmIdentifier, err := signingParamsForPublicKey(pub, template.SignatureAlgorithm)
+	if err != nil {
+		return tbsCertificateRequest{}, 0, pkix.AlgorithmIdentifier{}, err
+	}
+	if opts != nil && opts.SM2NullParameters {
+		setSM2NullParameters(signatureAlgorithm, &algorithmIdentifier)
+	}
+
 	var publicKeyBytes []byte
 	var publicKeyAlgorithm pkix.AlgorithmIdentifier
-	publicKeyBytes, publicKeyAlgorithm, err = marshalPublicKey(key.Public())
+	publicKeyBytes, publicKeyAlgorithm, err = marshalPublicKey(pub)
 	if err != nil {
-		return nil, err
+		return tbsCertificateRequest{}, 0, pkix.AlgorithmIdentifier{}, err
 	}
 
-	extensions, err := buildCSRExtensions(template)
+	var otherNames []OtherName
+	var extraSANs []asn1.RawValue
+	var usage *RequestedUsage
+	if opts != nil {
+		otherNames, extraSANs, usage = opts.OtherNames, opts.ExtraSANs, &opts.Usage
+	}
+	extensions, err := buildCSRExtensions(template, otherNames, extraSANs, usage)
 	if err != nil {
-		return nil, err
+		return tbsCertificateRequest{}, 0, pkix.AlgorithmIdentifier{}, err
 	}
 
 	// Make a copy of template.Attributes because we may alter it below.
@@ -1968,7 +2742,7 @@ func CreateCertificateRequest(rand io.Reader, template *x509.CertificateRequest,
 
 	rawAttributes, err := newRawAttributes(attributes)
 	if err != nil {
-		return nil, err
+		return tbsCertificateRequest{}, 0, pkix.AlgorithmIdentifier{}, err
 	}
 
 	// If not included in attributes, add a new attribute for the
@@ -1984,22 +2758,42 @@ func CreateCertificateRequest(rand io.Reader, template *x509.CertificateRequest,
 
 		b, err := asn1.Marshal(attr)
 		if err != nil {
-			return nil, errors.New("x509: failed to serialise extensions attribute: " + err.Error())
+			return tbsCertificateRequest{}, 0, pkix.AlgorithmIdentifier{}, errors.New("x509: failed to serialise extensions attribute: " + err.Error())
 		}
 
 		var rawValue asn1.RawValue
 		if _, err := asn1.Unmarshal(b, &rawValue); err != nil {
-			return nil, err
+			return tbsCertificateRequest{}, 0, pkix.AlgorithmIdentifier{}, err
 		}
 
 		rawAttributes = append(rawAttributes, rawValue)
 	}
 
+	if opts != nil && opts.ChallengePassword != "" {
+		rawValue, err := marshalChallengePassword(opts.ChallengePassword)
+		if err != nil {
+			return tbsCertificateRequest{}, 0, pkix.AlgorithmIdentifier{}, err
+		}
+		rawAttributes = append(rawAttributes, rawValue)
+	}
+	if opts != nil {
+		for _, attr := range opts.ExtraAttributes {
+			if opts.ChallengePassword != "" && attr.Type.Equal(oidChallengePassword) {
+				return tbsCertificateRequest{}, 0, pkix.AlgorithmIdentifier{}, errors.New("x509: challenge password both in ChallengePassword and ExtraAttributes")
+			}
+			rawValue, err := marshalRawAttribute(attr)
+			if err != nil {
+				return tbsCertificateRequest{}, 0, pkix.AlgorithmIdentifier{}, err
+			}
+			rawAttributes = append(rawAttributes, rawValue)
+		}
+	}
+
 	asn1Subject := template.RawSubject
 	if len(asn1Subject) == 0 {
 		asn1Subject, err = asn1.Marshal(template.Subject.ToRDNSequence())
 		if err != nil {
-			return nil, err
+			return tbsCertificateRequest{}, 0, pkix.AlgorithmIdentifier{}, err
 		}
 	}
 
@@ -2016,25 +2810,11 @@ func CreateCertificateRequest(rand io.Reader, template *x509.CertificateRequest,
 		RawAttributes: rawAttributes,
 	}
 
-	tbsCSRContents, err := asn1.Marshal(tbsCSR)
-	if err != nil {
-		return nil, err
-	}
-	tbsCSR.Raw = tbsCSRContents
-
-	signature, err := signTBS(tbsCSRContents, key, signatureAlgorithm, rand)
+	tbsCSR.Raw, err = asn1.Marshal(tbsCSR)
 	if err != nil {
-		return nil, err
+		return tbsCertificateRequest{}, 0, pkix.AlgorithmIdentifier{}, err
 	}
-
-	return asn1.Marshal(certificateRequest{
-		TBSCSR:             tbsCSR,
-		SignatureAlgorithm: algorithmIdentifier,
-		SignatureValue: asn1.BitString{
-			Bytes:     signature,
-			BitLength: len(signature) * 8,
-		},
-	})
+	return tbsCSR, signatureAlgorithm, algorithmIdentifier, nil
 }
 
 // ParseCertificateRequest parses a single certificate request from the
@@ -2072,7 +2852,7 @@ func parseCertificateRequest(in *certificateRequest) (*CertificateRequest, error
 		Signature:          in.SignatureValue.RightAlign(),
 		SignatureAlgorithm: getSignatureAlgorithmFromAI(in.SignatureAlgorithm),
 
-		PublicKeyAlgorithm: getPublicKeyAlgorithmFromOID(in.TBSCSR.PublicKey.Algorithm.Algorithm),
+		PublicKeyAlgorithm: getPublicKeyAlgorithmFromAI(in.TBSCSR.PublicKey.Algorithm),
 
 		Version:    in.TBSCSR.Version,
 		Attributes: parseRawAttributes(in.TBSCSR.RawAttributes),
@@ -2117,6 +2897,12 @@ func (c *CertificateRequest) CheckSignature() error {
 	return checkSignature(c.SignatureAlgorithm, c.RawTBSCertificateRequest, c.Signature, c.PublicKey, true)
 }
 
+// CheckSignatureWithOptions is like CheckSignature, with options. A nil opts
+// is equivalent to the zero value.
+func (c *CertificateRequest) CheckSignatureWithOptions(opts *SMSignatureOptions) error {
+	return checkSignatureWithOptions(c.SignatureAlgorithm, c.RawTBSCertificateRequest, c.Signature, c.PublicKey, true, opts)
+}
+
 type RevocationList x509.RevocationList
 
 func (c *RevocationList) asX509() *x509.RevocationList {
@@ -2163,28 +2949,88 @@ type tbsCertificateList struct {
 // extension are populated using the issuer certificate. issuer must have
 // SubjectKeyId set.
 func CreateRevocationList(rand io.Reader, template *x509.RevocationList, issuer *Certificate, priv crypto.Signer) ([]byte, error) {
+	return CreateRevocationListWithOptions(rand, template, issuer, priv, nil)
+}
+
+// CreateRevocationListOptions contains options for
+// CreateRevocationListWithOptions.
+type CreateRevocationListOptions struct {
+	// FreshestCRL are the URIs of the delta CRL distribution points, encoded
+	// in a freshestCRL extension unless template.ExtraExtensions has one.
+	FreshestCRL []string
+
+	// IssuingDistributionPoint, if not nil, is encoded in a critical issuing
+	// distribution point extension unless template.ExtraExtensions has one.
+	IssuingDistributionPoint *IssuingDistributionPoint
+
+	// BaseCRLNumber, if not nil, makes the CRL a delta CRL of the base CRL
+	// with that number, which must be less than template.Number, with a
+	// critical delta CRL indicator extension, RFC 5280, 5.2.4.
+	BaseCRLNumber *big.Int
+
+	// ExpiredCertsOnCRL, if not zero, is encoded in an expired certificates
+	// on CRL extension, RFC 5280, 5.2.7 and X.509, 9.5.2.4: the CRL keeps the
+	// revoked certificates which expired since then.
+	ExpiredCertsOnCRL time.Time
+
+	// NextPublish, if not zero, is encoded in a Microsoft next publish
+	// extension, the time the next CRL will be published, which may be
+	// before NextUpdate.
+	NextPublish time.Time
+
+	// SM2NullParameters is like CreateCertificateOptions.SM2NullParameters.
+	SM2NullParameters bool
+}
+
+// CreateRevocationListWithOptions is like CreateRevocationList, with options.
+// A nil opts is equivalent to the zero value.
+func CreateRevocationListWithOptions(rand io.Reader, template *x509.RevocationList, issuer *Certificate, priv crypto.Signer, opts *CreateRevocationListOptions) ([]byte, error) {
+	tbsCertList, signatureAlgorithm, err := createTBSRevocationList(template, issuer, priv.Public(), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := signTBS(tbsCertList.Raw, priv, signatureAlgorithm, rand)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(certificateList{
+		TBSCertList:        tbsCertList,
+		SignatureAlgorithm: tbsCertList.Signature,
+		SignatureValue:     asn1.BitString{Bytes: signature, BitLength: len(signature) * 8},
+	})
+}
+
+// createTBSRevocationList returns the TBSCertList of CreateRevocationList, with
+// its Raw encoding, and the algorithm to sign it with for the public key pub
+// of the signer.
+func createTBSRevocationList(template *x509.RevocationList, issuer *Certificate, pub crypto.PublicKey, opts *CreateRevocationListOptions) (tbsCertificateList, SignatureAlgorithm, error) {
 	if template == nil {
-		return nil, errors.New("x509: template can not be nil")
+		return tbsCertificateList{}, 0, errors.New("x509: template can not be nil")
 	}
 	if issuer == nil {
-		return nil, errors.New("x509: issuer can not be nil")
+		return tbsCertificateList{}, 0, errors.New("x509: issuer can not be nil")
 	}
 	if (issuer.KeyUsage & KeyUsageCRLSign) == 0 {
-		return nil, errors.New("x509: issuer must have the crlSign key usage bit set")
+		return tbsCertificateList{}, 0, errors.New("x509: issuer must have the crlSign key usage bit set")
 	}
 	if len(issuer.SubjectKeyId) == 0 {
-		return nil, errors.New("x509: issuer certificate doesn't contain a subject key identifier")
+		return tbsCertificateList{}, 0, errors.New("x509: issuer certificate doesn't contain a subject key identifier")
 	}
 	if template.NextUpdate.Before(template.ThisUpdate) {
-		return nil, errors.New("x509: template.ThisUpdate is after template.NextUpdate")
+		return tbsCertificateList{}, 0, errors.New("x509: template.ThisUpdate is after template.NextUpdate")
 	}
 	if template.Number == nil {
-		return nil, errors.New("x509: template contains nil Number field")
+		return tbsCertificateList{}, 0, errors.New("x509: template contains nil Number field")
 	}
 
-	signatureAlgorithm, algorithmIdentifier, err := signingParamsForKey(priv, template.SignatureAlgorithm)
+	signatureAlgorithm, algorithmIdentifier, err := signingParamsForPublicKey(pub, template.SignatureAlgorithm)
 	if err != nil {
-		return nil, err
+		return tbsCertificateList{}, 0, err
+	}
+	if opts != nil && opts.SM2NullParameters {
+		setSM2NullParameters(signatureAlgorithm, &algorithmIdentifier)
 	}
 
 	var revokedCerts []pkix.RevokedCertificate
@@ -2203,10 +3049,10 @@ func CreateRevocationList(rand io.Reader, template *x509.RevocationList, issuer
 		revokedCerts = make([]pkix.RevokedCertificate, len(template.RevokedCertificateEntries))
 		for i, rce := range template.RevokedCertificateEntries {
 			if rce.SerialNumber == nil {
-				return nil, errors.New("x509: template contains entry with nil SerialNumber field")
+				return tbsCertificateList{}, 0, errors.New("x509: template contains entry with nil SerialNumber field")
 			}
 			if rce.RevocationTime.IsZero() {
-				return nil, errors.New("x509: template contains entry with zero RevocationTime field")
+				return tbsCertificateList{}, 0, errors.New("x509: template contains entry with zero RevocationTime field")
 			}
 
 			rc := pkix.RevokedCertificate{
@@ -2219,7 +3065,7 @@ func CreateRevocationList(rand io.Reader, template *x509.RevocationList, issuer
 			exts := make([]pkix.Extension, 0, len(rce.ExtraExtensions))
 			for _, ext := range rce.ExtraExtensions {
 				if ext.Id.Equal(oidExtensionReasonCode) {
-					return nil, errors.New("x509: template contains entry with ReasonCode ExtraExtension; use ReasonCode field instead")
+					return tbsCertificateList{}, 0, errors.New("x509: template contains entry with ReasonCode ExtraExtension; use ReasonCode field instead")
 				}
 				exts = append(exts, ext)
 			}
@@ -2229,7 +3075,7 @@ func CreateRevocationList(rand io.Reader, template *x509.RevocationList, issuer
 			if rce.ReasonCode != 0 {
 				reasonBytes, err := asn1.Marshal(asn1.Enumerated(rce.ReasonCode))
 				if err != nil {
-					return nil, err
+					return tbsCertificateList{}, 0, err
 				}
 
 				exts = append(exts, pkix.Extension{
@@ -2247,20 +3093,29 @@ func CreateRevocationList(rand io.Reader, template *x509.RevocationList, issuer
 
 	aki, err := asn1.Marshal(authKeyId{Id: issuer.SubjectKeyId})
 	if err != nil {
-		return nil, err
+		return tbsCertificateList{}, 0, err
 	}
 	if numBytes := template.Number.Bytes(); len(numBytes) > 20 || (len(numBytes) == 20 && numBytes[0]&0x80 != 0) {
-		return nil, errors.New("x509: CRL number exceeds 20 octets")
+		return tbsCertificateList{}, 0, errors.New("x509: CRL number exceeds 20 octets")
 	}
 	crlNum, err := asn1.Marshal(template.Number)
 	if err != nil {
-		return nil, err
+		return tbsCertificateList{}, 0, err
+	}
+	var baseCRLNum []byte
+	if opts != nil && opts.BaseCRLNumber != nil {
+		if opts.BaseCRLNumber.Sign() < 0 || opts.BaseCRLNumber.Cmp(template.Number) >= 0 {
+			return tbsCertificateList{}, 0, errors.New("x509: base CRL number must be less than the CRL number")
+		}
+		if baseCRLNum, err = asn1.Marshal(opts.BaseCRLNumber); err != nil {
+			return tbsCertificateList{}, 0, err
+		}
 	}
 
 	// Correctly use the issuer's subject sequence if one is specified.
 	issuerSubject, err := subjectBytes(issuer.asX509())
 	if err != nil {
-		return nil, err
+		return tbsCertificateList{}, 0, err
 	}
 
 	tbsCertList := tbsCertificateList{
@@ -2283,6 +3138,42 @@ func CreateRevocationList(rand io.Reader, template *x509.RevocationList, issuer
 	if len(revokedCerts) > 0 {
 		tbsCertList.RevokedCertificates = revokedCerts
 	}
+	if baseCRLNum != nil {
+		tbsCertList.Extensions = append(tbsCertList.Extensions, pkix.Extension{Id: oidExtensionDeltaCRLIndicator, Critical: true, Value: baseCRLNum})
+	}
+
+	if opts != nil && len(opts.FreshestCRL) > 0 && !oidInExtensions(oidExtensionFreshestCRL, template.ExtraExtensions) {
+		freshestCRL, err := marshalDistributionPoints(opts.FreshestCRL)
+		if err != nil {
+			return tbsCertificateList{}, 0, err
+		}
+		tbsCertList.Extensions = append(tbsCertList.Extensions, pkix.Extension{Id: oidExtensionFreshestCRL, Value: freshestCRL})
+	}
+
+	if opts != nil && opts.IssuingDistributionPoint != nil && !oidInExtensions(oidExtensionIssuingDistributionPoint, template.ExtraExtensions) {
+		idp, err := marshalIssuingDistributionPoint(opts.IssuingDistributionPoint)
+		if err != nil {
+			return tbsCertificateList{}, 0, err
+		}
+		tbsCertList.Extensions = append(tbsCertList.Extensions, pkix.Extension{Id: oidExtensionIssuingDistributionPoint, Critical: true, Value: idp})
+	}
+
+	if opts != nil && !opts.ExpiredCertsOnCRL.IsZero() && !oidInExtensions(oidExtensionExpiredCertsOnCRL, template.ExtraExtensions) {
+		expiredCertsOnCRL, err := asn1.MarshalWithParams(opts.ExpiredCertsOnCRL.UTC(), "generalized")
+		if err != nil {
+			return tbsCertificateList{}, 0, err
+		}
+		tbsCertList.Extensions = append(tbsCertList.Extensions, pkix.Extension{Id: oidExtensionExpiredCertsOnCRL, Value: expiredCertsOnCRL})
+	}
+
+	if opts != nil && !opts.NextPublish.IsZero() && !oidInExtensions(oidExtensionNextPublish, template.ExtraExtensions) {
+		// a UTCTime until 2049, as thisUpdate and nextUpdate
+		nextPublish, err := asn1.Marshal(opts.NextPublish.UTC())
+		if err != nil {
+			return tbsCertificateList{}, 0, err
+		}
+		tbsCertList.Extensions = append(tbsCertList.Extensions, pkix.Extension{Id: oidExtensionNextPublish, Value: nextPublish})
+	}
 
 	if len(template.ExtraExtensions) > 0 {
 		tbsCertList.Extensions = append(tbsCertList.Extensions, template.ExtraExtensions...)
@@ -2290,28 +3181,68 @@ func CreateRevocationList(rand io.Reader, template *x509.RevocationList, issuer
 
 	tbsCertListContents, err := asn1.Marshal(tbsCertList)
 	if err != nil {
-		return nil, err
+		return tbsCertificateList{}, 0, err
 	}
 
 	// Optimization to only marshal this struct once, when signing and
-	// then embedding in certificateList below.
+	// then embedding in certificateList.
 	tbsCertList.Raw = tbsCertListContents
+	return tbsCertList, signatureAlgorithm, nil
+}
 
-	signature, err := signTBS(tbsCertListContents, priv, signatureAlgorithm, rand)
-	if err != nil {
-		return nil, err
-	}
+// FreshestCRL returns the URIs of the delta CRL distribution points of the
+// freshestCRL extension of rl, RFC 5280, 5.2.6.
+func (rl *RevocationList) FreshestCRL() ([]string, error) {
+	return freshestCRL(rl.Extensions)
+}
 
-	return asn1.Marshal(certificateList{
-		TBSCertList:        tbsCertList,
-		SignatureAlgorithm: algorithmIdentifier,
-		SignatureValue:     asn1.BitString{Bytes: signature, BitLength: len(signature) * 8},
-	})
+// ExpiredCertsOnCRL returns the time of the expired certificates on CRL
+// extension of rl, RFC 5280, 5.2.7, since which revoked certificates are kept
+// on the CRL after they expire, or the zero time if rl does not assert it.
+func (rl *RevocationList) ExpiredCertsOnCRL() (time.Time, error) {
+	return crlTimeExtension(rl.Extensions, oidExtensionExpiredCertsOnCRL)
+}
+
+// NextPublish returns the time of the Microsoft next publish extension of rl,
+// when the next CRL will be published, or the zero time if it has none.
+func (rl *RevocationList) NextPublish() (time.Time, error) {
+	return crlTimeExtension(rl.Extensions, oidExtensionNextPublish)
+}
+
+// crlTimeExtension returns the time of the extension of a CRL with oid, whose
+// value is a UTCTime or a GeneralizedTime, or the zero time if there is none.
+func crlTimeExtension(extensions []pkix.Extension, oid asn1.ObjectIdentifier) (time.Time, error) {
+	for _, e := range extensions {
+		if e.Id.Equal(oid) {
+			value := cryptobyte.String(e.Value)
+			t, err := parseTime(&value)
+			if err != nil || !value.Empty() {
+				return time.Time{}, fmt.Errorf("x509: malformed extension %v", oid)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, nil
 }
 
 // CheckSignatureFrom verifies that the signature on rl is a valid signature
 // from issuer.
 func (rl *RevocationList) CheckSignatureFrom(parent *Certificate) error {
+	return rl.CheckSignatureFromWithOptions(parent, nil)
+}
+
+// CheckSignatureFromWithOptions is like CheckSignatureFrom, with options. A
+// nil opts is equivalent to the zero value.
+func (rl *RevocationList) CheckSignatureFromWithOptions(parent *Certificate, opts *SMSignatureOptions) error {
+	if err := checkCRLIssuer(parent); err != nil {
+		return err
+	}
+
+	return parent.CheckSignatureWithOptions(rl.SignatureAlgorithm, rl.RawTBSRevocationList, rl.Signature, opts)
+}
+
+// checkCRLIssuer checks that parent can sign CRLs.
+func checkCRLIssuer(parent *Certificate) error {
 	if parent.Version == 3 && !parent.BasicConstraintsValid ||
 		parent.BasicConstraintsValid && !parent.IsCA {
 		return x509.ConstraintViolationError{}
@@ -2324,6 +3255,5 @@ func (rl *RevocationList) CheckSignatureFrom(parent *Certificate) error {
 	if parent.PublicKeyAlgorithm == UnknownPublicKeyAlgorithm {
 		return x509.ErrUnsupportedAlgorithm
 	}
-
-	return parent.CheckSignature(rl.SignatureAlgorithm, rl.RawTBSRevocationList, rl.Signature)
+	return nil
 }
@@ -1,7 +1,9 @@
 package smx509
 
 import (
+	"bytes"
 	"crypto"
+	sdkecdh "crypto/ecdh"
 	"crypto/ecdsa"
 	"crypto/ed25519"
 	"crypto/elliptic"
@@ -14,15 +16,24 @@ import (
 	"crypto/x509/pkix"
 	"encoding/asn1"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"encoding/pem"
 	"errors"
+	"io"
 	"math/big"
+	"reflect"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/yunmoon/gmsm/ecdh"
 	"github.com/yunmoon/gmsm/sm2"
+	"github.com/yunmoon/gmsm/sm3"
+	"golang.org/x/crypto/cryptobyte"
+	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
 )
 
 const publicKeyPemFromAliKms = `-----BEGIN PUBLIC KEY-----
@@ -64,6 +75,30 @@ bxIHjKZHc2sztHCXe7cseWGiLq0syg==
 -----END CERTIFICATE REQUEST-----
 `
 
+// newTestIssuer returns a self-signed CA certificate for the key priv with
+// the given subject, valid for an hour either side of now.
+func newTestIssuer(t testing.TB, priv crypto.Signer, subject pkix.Name) *Certificate {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               subject,
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              KeyUsageCertSign | KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := CreateCertificate(rand.Reader, template, template, priv.Public(), priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer, err := ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return issuer
+}
+
 func TestParsePKIXPublicKeyFromExternal(t *testing.T) {
 	tests := []struct {
 		name string
@@ -167,8 +202,8 @@ func Test_ParseCertificate(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if cert.PublicKeyAlgorithm != x509.ECDSA {
-		t.Fatal("should be ECDSA")
+	if cert.PublicKeyAlgorithm != SM2 {
+		t.Fatal("should be SM2")
 	}
 	if cert.SignatureAlgorithm != SM2WithSM3 {
 		t.Fatal("should be SM2WithSM3")
@@ -219,6 +254,247 @@ func TestMarshalPKIXPublicKey(t *testing.T) {
 	}
 }
 
+func TestMarshalPKIXPublicKeySM2(t *testing.T) {
+	der, _ := hex.DecodeString(sm2PublicKeyHex)
+	pub, err := ParsePKIXPublicKey(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := MarshalPKIXPublicKeySM2(pub.(*ecdsa.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(result, der) {
+		t.Errorf("expected %x, got %x", der, result)
+	}
+
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "SM2 OID"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	for _, pub := range []any{&priv.PublicKey, &SM2OIDPublicKey{&priv.PublicKey}} {
+		pkixDer, err := MarshalPKIXPublicKey(pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, err := ParsePKIXPublicKey(pkixDer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !priv.PublicKey.Equal(parsed) {
+			t.Errorf("%T: public key does not round trip", pub)
+		}
+
+		certDer, err := CreateCertificate(rand.Reader, template, template, pub, priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, err := ParseCertificate(certDer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(cert.RawSubjectPublicKeyInfo, pkixDer) {
+			t.Errorf("%T: unexpected SubjectPublicKeyInfo %x", pub, cert.RawSubjectPublicKeyInfo)
+		}
+		if cert.PublicKeyAlgorithm != SM2 {
+			t.Errorf("%T: unexpected public key algorithm %v", pub, PublicKeyAlgorithmString(cert.PublicKeyAlgorithm))
+		}
+		if err := cert.CheckSignatureFrom(cert); err != nil {
+			t.Errorf("%T: %v", pub, err)
+		}
+	}
+
+	// the SM2 algorithm OID without parameters
+	spki, _ := asn1.Marshal(pkixPublicKey{
+		Algo:      pkix.AlgorithmIdentifier{Algorithm: oidPublicKeySM2},
+		BitString: asn1.BitString{Bytes: elliptic.Marshal(sm2.P256(), priv.X, priv.Y), BitLength: 520},
+	})
+	if parsed, err := ParsePKIXPublicKey(spki); err != nil || !priv.PublicKey.Equal(parsed) {
+		t.Errorf("failed to parse SM2 public key without parameters: %v", err)
+	}
+
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if _, err := MarshalPKIXPublicKeySM2(&p256.PublicKey); err == nil {
+		t.Error("expected error with NIST P-256 public key")
+	}
+}
+
+func TestSM2PublicKeyAlgorithm(t *testing.T) {
+	sm2Key, _ := sm2.GenerateKey(rand.Reader)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "SM2"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	tests := []struct {
+		pub  any
+		priv crypto.Signer
+		want PublicKeyAlgorithm
+	}{
+		{&sm2Key.PublicKey, sm2Key, SM2},
+		{&SM2OIDPublicKey{&sm2Key.PublicKey}, sm2Key, SM2},
+		{&ecKey.PublicKey, ecKey, ECDSA},
+	}
+	for _, tt := range tests {
+		der, err := CreateCertificate(rand.Reader, template, template, tt.pub, tt.priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, err := ParseCertificate(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cert.PublicKeyAlgorithm != tt.want {
+			t.Errorf("%T: expected %v, got %v", tt.pub, PublicKeyAlgorithmString(tt.want), PublicKeyAlgorithmString(cert.PublicKeyAlgorithm))
+		}
+		if err := cert.CheckSignatureFrom(cert); err != nil {
+			t.Errorf("%T: %v", tt.pub, err)
+		}
+		// the parsed certificate can issue other certificates
+		if _, err := CreateCertificate(rand.Reader, template, cert, tt.pub, tt.priv); err != nil {
+			t.Errorf("%T: %v", tt.pub, err)
+		}
+
+		csrDer, err := CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: template.Subject}, tt.priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		csr, err := ParseCertificateRequest(csrDer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if csr.PublicKeyAlgorithm != tt.want {
+			t.Errorf("%T: expected CSR public key algorithm %v, got %v", tt.pub, PublicKeyAlgorithmString(tt.want), PublicKeyAlgorithmString(csr.PublicKeyAlgorithm))
+		}
+		if err := csr.CheckSignature(); err != nil {
+			t.Errorf("%T: %v", tt.pub, err)
+		}
+	}
+	if PublicKeyAlgorithmString(SM2) != "SM2" || PublicKeyAlgorithmString(ECDSA) != "ECDSA" {
+		t.Error("unexpected public key algorithm names")
+	}
+}
+
+// Generated with: openssl ec -in sm2.key -pubout -conv_form compressed
+const compressedSM2PublicKeyPEM = `-----BEGIN PUBLIC KEY-----
+MDkwEwYHKoZIzj0CAQYIKoEcz1UBgi0DIgACVa4iwn+BklBcV5EBCdNUxWw2DIo8
+1damt3IQQlHsoqc=
+-----END PUBLIC KEY-----
+`
+
+// The uncompressed form of compressedSM2PublicKeyPEM.
+const uncompressedSM2PublicKeyPEM = `-----BEGIN PUBLIC KEY-----
+MFkwEwYHKoZIzj0CAQYIKoEcz1UBgi0DQgAEVa4iwn+BklBcV5EBCdNUxWw2DIo8
+1damt3IQQlHsoqeZ5BWcJquk8d4VGrGtq8xq0jn3tLVXAceNNi5+AURDBg==
+-----END PUBLIC KEY-----
+`
+
+const compressedSM2CertPEM = `-----BEGIN CERTIFICATE-----
+MIIBNTCB26ADAgECAgEBMAoGCCqBHM9VAYN1MBkxFzAVBgNVBAMTDmNvbXByZXNz
+ZWQgc20yMCAXDTI0MDEwMTAwMDAwMFoYDzIxMjQwMTAxMDAwMDAwWjAZMRcwFQYD
+VQQDEw5jb21wcmVzc2VkIHNtMjA5MBMGByqGSM49AgEGCCqBHM9VAYItAyIAA9Nk
+DpszokQHSxcALoOWkVQCG/9mWJlAYxuZfhwMdC00ozIwMDAPBgNVHRMBAf8EBTAD
+AQH/MB0GA1UdDgQWBBQcFD4TGkJgLOrj/Zrzjwps9XgLCzAKBggqgRzPVQGDdQNJ
+ADBGAiEAyaPIpwZPv1FidJXZwoFQ3gU3NBc8uUmdLTROKfHriRoCIQCI0s5dKuhc
+yTYnv2XOk7Kot87zKmmOfp2tRXwTVGo+6w==
+-----END CERTIFICATE-----
+`
+
+func TestCompressedECPublicKey(t *testing.T) {
+	block, _ := pem.Decode([]byte(compressedSM2PublicKeyPEM))
+	compressed, err := ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ = pem.Decode([]byte(uncompressedSM2PublicKeyPEM))
+	uncompressed, err := ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !uncompressed.(*ecdsa.PublicKey).Equal(compressed) {
+		t.Error("compressed and uncompressed public keys differ")
+	}
+
+	block, _ = pem.Decode([]byte(compressedSM2CertPEM))
+	cert, err := ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.PublicKeyAlgorithm != SM2 {
+		t.Errorf("unexpected public key algorithm %v", PublicKeyAlgorithmString(cert.PublicKeyAlgorithm))
+	}
+	if err := cert.CheckSignatureFrom(cert); err != nil {
+		t.Error(err)
+	}
+
+	sm2Key, _ := sm2.GenerateKey(rand.Reader)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "compressed"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	for _, priv := range []crypto.Signer{sm2Key, ecKey} {
+		pub := priv.Public().(*ecdsa.PublicKey)
+		der, err := MarshalPKIXPublicKey(&CompressedECPublicKey{pub})
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, err := ParsePKIXPublicKey(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !pub.Equal(parsed) {
+			t.Errorf("%v: public key does not round trip", pub.Curve.Params().Name)
+		}
+
+		certDer, err := CreateCertificate(rand.Reader, template, template, &CompressedECPublicKey{pub}, priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, err := ParseCertificate(certDer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(cert.RawSubjectPublicKeyInfo, der) {
+			t.Errorf("%v: unexpected SubjectPublicKeyInfo %x", pub.Curve.Params().Name, cert.RawSubjectPublicKeyInfo)
+		}
+		if err := cert.CheckSignatureFrom(cert); err != nil {
+			t.Errorf("%v: %v", pub.Curve.Params().Name, err)
+		}
+
+		// malformed points
+		point := elliptic.MarshalCompressed(pub.Curve, pub.X, pub.Y)
+		invalidX := append([]byte{point[0]}, bytes.Repeat([]byte{0xff}, len(point)-1)...)
+		var info pkixPublicKey
+		if _, err := asn1.Unmarshal(der, &info); err != nil {
+			t.Fatal(err)
+		}
+		for _, bad := range [][]byte{point[:len(point)-1], append([]byte{5}, point[1:]...), invalidX, {2}} {
+			info.BitString = asn1.BitString{Bytes: bad, BitLength: 8 * len(bad)}
+			spki, _ := asn1.Marshal(info)
+			if _, err := ParsePKIXPublicKey(spki); err == nil {
+				t.Errorf("%v: expected error with point %x", pub.Curve.Params().Name, bad)
+			}
+		}
+	}
+}
+
 func parseAndCheckCsr(csrPem []byte) error {
 	csr, err := ParseCertificateRequestPEM(csrPem)
 	if err != nil {
@@ -523,3 +799,1346 @@ func mustSignSM2(t *testing.T, priv *sm2.PrivateKey, digest []byte) []byte {
 	}
 	return signature
 }
+
+// hsmPublicKey is an opaque public key which does not implement Equal.
+type hsmPublicKey struct {
+	handle int
+}
+
+type hsmSigner struct {
+	crypto.Signer
+}
+
+func (s *hsmSigner) Public() crypto.PublicKey {
+	return &hsmPublicKey{1}
+}
+
+func TestCreateCertificateWithOpaqueSigner(t *testing.T) {
+	sm2Key, _ := sm2.GenerateKey(rand.Reader)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	otherKey, _ := sm2.GenerateKey(rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "HSM"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	opts := &CreateCertificateOptions{AllowOpaqueSigner: true}
+	for _, key := range []crypto.Signer{sm2Key, ecKey} {
+		signer := &hsmSigner{key}
+		if _, err := CreateCertificate(rand.Reader, template, template, key.Public(), signer); err == nil {
+			t.Fatalf("%T: expected error without AllowOpaqueSigner", key)
+		}
+		// self-signed
+		der, err := CreateCertificateWithOptions(rand.Reader, template, template, key.Public(), signer, opts)
+		if err != nil {
+			t.Fatalf("%T: %v", key, err)
+		}
+		parent, err := ParseCertificate(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := parent.CheckSignatureFrom(parent); err != nil {
+			t.Errorf("%T: %v", key, err)
+		}
+		// issued by the parsed certificate
+		leaf := &x509.Certificate{
+			SerialNumber: big.NewInt(2),
+			Subject:      pkix.Name{CommonName: "leaf"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+		}
+		der, err = CreateCertificateWithOptions(rand.Reader, leaf, parent, otherKey.Public(), signer, opts)
+		if err != nil {
+			t.Fatalf("%T: %v", key, err)
+		}
+		cert, err := ParseCertificate(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cert.CheckSignatureFrom(parent); err != nil {
+			t.Errorf("%T: %v", key, err)
+		}
+		// the signer does not match the parent
+		if _, err := CreateCertificateWithOptions(rand.Reader, leaf, parent, otherKey.Public(), &hsmSigner{otherKey}, opts); err == nil {
+			t.Errorf("%T: expected error with mismatched signer", key)
+		}
+	}
+	// no public key to check the signer against
+	if _, err := CreateCertificateWithOptions(rand.Reader, template, &x509.Certificate{}, sm2Key.Public(), &hsmSigner{sm2Key}, opts); err == nil {
+		t.Error("expected error without parent's public key")
+	}
+}
+
+func TestFreshestCRL(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cdp := []string{"http://crl.example.com/ca.crl", "ldap://ldap.example.com/cn=ca?certificateRevocationList"}
+	delta := []string{"http://crl.example.com/delta.crl"}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "SM2 CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              KeyUsageCertSign | KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		CRLDistributionPoints: cdp,
+	}
+	der, err := CreateCertificateWithOptions(rand.Reader, template, template, priv.Public(), priv, &CreateCertificateOptions{FreshestCRL: delta})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cert.CRLDistributionPoints, cdp) {
+		t.Errorf("unexpected CRL distribution points %v", cert.CRLDistributionPoints)
+	}
+	got, err := cert.FreshestCRL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, delta) {
+		t.Errorf("unexpected freshest CRL %v", got)
+	}
+	for _, e := range cert.Extensions {
+		if e.Id.Equal(oidExtensionFreshestCRL) && e.Critical {
+			t.Error("freshestCRL extension is critical")
+		}
+	}
+
+	// the freshestCRL value has the encoding of the CRL distribution points
+	var cdpValue, freshestValue []byte
+	for _, e := range cert.Extensions {
+		switch {
+		case e.Id.Equal(oidExtensionCRLDistributionPoints):
+			cdpValue = e.Value
+		case e.Id.Equal(oidExtensionFreshestCRL):
+			freshestValue = e.Value
+		}
+	}
+	if want, _ := marshalDistributionPoints(delta); !bytes.Equal(freshestValue, want) {
+		t.Errorf("unexpected freshestCRL encoding %x", freshestValue)
+	}
+	if want, _ := marshalDistributionPoints(cdp); !bytes.Equal(cdpValue, want) {
+		t.Errorf("unexpected CRL distribution points encoding %x", cdpValue)
+	}
+
+	// without the extension
+	if der, err = CreateCertificate(rand.Reader, template, template, priv.Public(), priv); err != nil {
+		t.Fatal(err)
+	}
+	if cert, err = ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := cert.FreshestCRL(); err != nil || got != nil {
+		t.Errorf("unexpected freshest CRL %v: %v", got, err)
+	}
+
+	// revocation lists
+	crlDER, err := CreateRevocationListWithOptions(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now(),
+		NextUpdate: time.Now().Add(time.Hour),
+	}, cert, priv, &CreateRevocationListOptions{FreshestCRL: delta})
+	if err != nil {
+		t.Fatal(err)
+	}
+	crl, err := ParseRevocationList(crlDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := crl.CheckSignatureFrom(cert); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := crl.FreshestCRL(); err != nil || !reflect.DeepEqual(got, delta) {
+		t.Errorf("unexpected freshest CRL %v: %v", got, err)
+	}
+
+	// malformed extension
+	template.ExtraExtensions = []pkix.Extension{{Id: oidExtensionFreshestCRL, Value: []byte{0x30, 0x03, 0x30}}}
+	if der, err = CreateCertificate(rand.Reader, template, template, priv.Public(), priv); err != nil {
+		t.Fatal(err)
+	}
+	// it is only decoded by FreshestCRL, and doesn't fail the parsing
+	if cert, err = ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cert.FreshestCRL(); err == nil {
+		t.Error("expected error with a malformed freshestCRL extension")
+	}
+}
+
+func TestCreateCertificateWithECDHPublicKey(t *testing.T) {
+	caKey, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "SM2 CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := CreateCertificate(rand.Reader, caTemplate, caTemplate, caKey.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := NewCertPool()
+	roots.AddCert(ca)
+	caECDHPub, err := sm2.PublicKeyToECDH(&caKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdhParent := *caTemplate
+	ecdhParent.PublicKey = caECDHPub
+
+	sm2ECDHKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p256ECDHKey, err := sdkecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		name  string
+		pub   any
+		curve elliptic.Curve
+	}{
+		{"gmsm ecdh SM2", sm2ECDHKey.PublicKey(), sm2.P256()},
+		{"crypto ecdh P-256", p256ECDHKey.PublicKey(), elliptic.P256()},
+	} {
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(2),
+			Subject:      pkix.Name{CommonName: "encryption"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     KeyUsageKeyEncipherment | KeyUsageDataEncipherment | KeyUsageKeyAgreement,
+			ExtKeyUsage:  []ExtKeyUsage{ExtKeyUsageAny},
+		}
+		// the parent public key is parsed, absent or in the ecdh form
+		for _, parent := range []any{ca, caTemplate, &ecdhParent} {
+			der, err := CreateCertificate(rand.Reader, template, parent, test.pub, caKey)
+			if err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+			cert, err := ParseCertificate(der)
+			if err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+			pub, ok := cert.PublicKey.(*ecdsa.PublicKey)
+			if !ok || pub.Curve != test.curve {
+				t.Fatalf("%s: unexpected public key %T", test.name, cert.PublicKey)
+			}
+			if err := CheckPublicKey(cert, test.pub); err != nil {
+				t.Errorf("%s: %v", test.name, err)
+			}
+			if _, err := cert.Verify(VerifyOptions{Roots: roots}); err != nil {
+				t.Errorf("%s: %v", test.name, err)
+			}
+		}
+	}
+}
+
+func TestGenerateSubjectKeyId(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "leaf"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	create := func(opts *CreateCertificateOptions) *Certificate {
+		t.Helper()
+		der, err := CreateCertificateWithOptions(rand.Reader, template, template, priv.Public(), priv, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, err := ParseCertificate(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert
+	}
+	subjectPublicKey := func(cert *Certificate) []byte {
+		t.Helper()
+		var spki publicKeyInfo
+		if _, err := asn1.Unmarshal(cert.RawSubjectPublicKeyInfo, &spki); err != nil {
+			t.Fatal(err)
+		}
+		return spki.PublicKey.RightAlign()
+	}
+
+	// unchanged by default
+	if cert := create(nil); cert.SubjectKeyId != nil {
+		t.Errorf("unexpected SubjectKeyId %x", cert.SubjectKeyId)
+	}
+	if cert := create(&CreateCertificateOptions{SubjectKeyIdHash: SubjectKeyIdSM3}); cert.SubjectKeyId != nil {
+		t.Errorf("unexpected SubjectKeyId %x", cert.SubjectKeyId)
+	}
+
+	cert := create(&CreateCertificateOptions{GenerateSubjectKeyId: true})
+	sha256Sum := sha256.Sum256(subjectPublicKey(cert))
+	if !bytes.Equal(cert.SubjectKeyId, sha256Sum[:20]) {
+		t.Errorf("SubjectKeyId %x, want %x", cert.SubjectKeyId, sha256Sum[:20])
+	}
+
+	cert = create(&CreateCertificateOptions{GenerateSubjectKeyId: true, SubjectKeyIdHash: SubjectKeyIdSM3})
+	sm3Sum := sm3.Sum(subjectPublicKey(cert))
+	if !bytes.Equal(cert.SubjectKeyId, sm3Sum[:20]) {
+		t.Errorf("SubjectKeyId %x, want %x", cert.SubjectKeyId, sm3Sum[:20])
+	}
+
+	// the SubjectKeyId of the template is kept
+	template.SubjectKeyId = []byte{1, 2, 3, 4}
+	if cert := create(&CreateCertificateOptions{GenerateSubjectKeyId: true}); !bytes.Equal(cert.SubjectKeyId, template.SubjectKeyId) {
+		t.Errorf("unexpected SubjectKeyId %x", cert.SubjectKeyId)
+	}
+
+	// the hash applies to CAs too
+	template.SubjectKeyId = nil
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	if cert := create(&CreateCertificateOptions{SubjectKeyIdHash: SubjectKeyIdSM3}); !bytes.Equal(cert.SubjectKeyId, sm3Sum[:20]) {
+		t.Errorf("SubjectKeyId %x, want %x", cert.SubjectKeyId, sm3Sum[:20])
+	}
+}
+
+// Generated with:
+//
+//	openssl req -x509 -key sm2.key -sm3 -subj "/CN=negative serial" -set_serial -4660
+const negativeSerialSM2CertPEM = `-----BEGIN CERTIFICATE-----
+MIIBeTCCAR+gAwIBAgIC7cwwCgYIKoEcz1UBg3UwGjEYMBYGA1UEAwwPbmVnYXRp
+dmUgc2VyaWFsMCAXDTI2MTAxNjE0MDAyM1oYDzIxMjYwOTIyMTQwMDIzWjAaMRgw
+FgYDVQQDDA9uZWdhdGl2ZSBzZXJpYWwwWTATBgcqhkjOPQIBBggqgRzPVQGCLQNC
+AARVJHo6My8Bm7/ZRzGeDX8jcGuLBVU/fAGE7yEIUXgvlFiLoOjLq7gqIMwDvbIC
+YUu03A/UgxHj3vY7sM5Cp5rro1MwUTAdBgNVHQ4EFgQUUhVOJIfln55IAJtr5kwe
+XhY1ugQwHwYDVR0jBBgwFoAUUhVOJIfln55IAJtr5kweXhY1ugQwDwYDVR0TAQH/
+BAUwAwEB/zAKBggqgRzPVQGDdQNIADBFAiEA0WkKmYKZWDh6euFtM+lToMzkijkH
+3upeCD9EMuqehhUCIEgG08uYAJJsRJVX0rG9GIxdYdk7RpRUuGWojtezid3f
+-----END CERTIFICATE-----`
+
+func TestStrictSerialNumber(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		Subject:   pkix.Name{CommonName: "serial"},
+		NotBefore: time.Now().Add(-time.Hour),
+		NotAfter:  time.Now().Add(time.Hour),
+	}
+	strict := &CreateCertificateOptions{StrictSerialNumber: true}
+
+	topBitSet := func(n int) *big.Int {
+		b := bytes.Repeat([]byte{0x11}, n)
+		b[0] = 0x80
+		return new(big.Int).SetBytes(b)
+	}
+	for _, test := range []struct {
+		name   string
+		serial *big.Int
+		length int // of the SerialNumberError, 0 if valid
+	}{
+		{"one", big.NewInt(1), 0},
+		{"20 octets", new(big.Int).SetBytes(bytes.Repeat([]byte{0x7f}, 20)), 0},
+		{"19 octets padded to 20", topBitSet(19), 0},
+		{"21 octets", new(big.Int).SetBytes(bytes.Repeat([]byte{0x7f}, 21)), 21},
+		{"20 octets padded to 21", topBitSet(20), 21},
+		{"zero", big.NewInt(0), 1},
+	} {
+		template.SerialNumber = test.serial
+		// lenient by default
+		der, err := CreateCertificate(rand.Reader, template, template, priv.Public(), priv)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if _, err := ParseCertificate(der); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		_, createErr := CreateCertificateWithOptions(rand.Reader, template, template, priv.Public(), priv, strict)
+		_, parseErr := ParseCertificateWithOptions(der, ParseOptions{StrictSerialNumber: true})
+		for _, err := range []error{createErr, parseErr} {
+			var serialErr SerialNumberError
+			if test.length == 0 {
+				if err != nil {
+					t.Errorf("%s: %v", test.name, err)
+				}
+			} else if !errors.As(err, &serialErr) || serialErr.Length != test.length || serialErr.SerialNumber.Cmp(test.serial) != 0 {
+				t.Errorf("%s: unexpected error %v", test.name, err)
+			}
+		}
+	}
+	if err := (SerialNumberError{SerialNumber: topBitSet(20), Length: 21}); err.Error() != "x509: serial number is 21 octets long, more than 20" {
+		t.Errorf("unexpected error message %q", err)
+	}
+
+	// the generated serial numbers conform
+	template.SerialNumber = nil
+	if _, err := CreateCertificateWithOptions(rand.Reader, template, template, priv.Public(), priv, strict); err != nil {
+		t.Error(err)
+	}
+
+	block, _ := pem.Decode([]byte(negativeSerialSM2CertPEM))
+	if _, err := ParseCertificate(block.Bytes); err != nil {
+		t.Fatal(err)
+	}
+	_, err = ParseCertificateWithOptions(block.Bytes, ParseOptions{StrictSerialNumber: true})
+	if err == nil || err.Error() != "x509: serial number is negative" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestCreateCertificateRawIssuer(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// an issuer with UTF8String attributes, which Go encodes as
+	// PrintableString
+	utf8Value := func(s string) asn1.RawValue {
+		return asn1.RawValue{Tag: asn1.TagUTF8String, Bytes: []byte(s)}
+	}
+	rawSubject, err := asn1.Marshal(pkix.RDNSequence{
+		{{Type: asn1.ObjectIdentifier{2, 5, 4, 10}, Value: utf8Value("GM")}},
+		{{Type: asn1.ObjectIdentifier{2, 5, 4, 3}, Value: utf8Value("GM Root CA")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		RawSubject:            rawSubject,
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := CreateCertificate(rand.Reader, caTemplate, caTemplate, priv.Public(), priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := NewCertPool()
+	roots.AddCert(ca)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "leaf"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	// the parent synthesized in memory, as from a configuration
+	synthesized := &x509.Certificate{Subject: ca.Subject, SubjectKeyId: ca.SubjectKeyId}
+	for _, test := range []struct {
+		name      string
+		parent    any
+		rawIssuer []byte
+		want      bool
+	}{
+		{"parsed parent", ca, nil, true},
+		{"synthesized parent", synthesized, nil, false},
+		{"synthesized parent with RawIssuer", synthesized, ca.RawSubject, true},
+	} {
+		der, err := CreateCertificateWithOptions(rand.Reader, template, test.parent, priv.Public(), priv, &CreateCertificateOptions{RawIssuer: test.rawIssuer})
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		cert, err := ParseCertificate(der)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if got := bytes.Equal(cert.RawIssuer, ca.RawSubject); got != test.want {
+			t.Errorf("%s: issuer %x, CA subject %x", test.name, cert.RawIssuer, ca.RawSubject)
+		}
+		if !bytes.Equal(cert.AuthorityKeyId, ca.SubjectKeyId) {
+			t.Errorf("%s: unexpected AuthorityKeyId %x", test.name, cert.AuthorityKeyId)
+		}
+		if _, err := cert.Verify(VerifyOptions{Roots: roots}); (err == nil) != test.want {
+			t.Errorf("%s: unexpected verification result %v", test.name, err)
+		}
+	}
+
+	for _, rawIssuer := range [][]byte{{0x30, 0x03, 0x31, 0x01}, append(slices.Clone(ca.RawSubject), 0), {0x04, 0x00}} {
+		if _, err := CreateCertificateWithOptions(rand.Reader, template, ca, priv.Public(), priv, &CreateCertificateOptions{RawIssuer: rawIssuer}); err == nil {
+			t.Errorf("expected error with RawIssuer %x", rawIssuer)
+		}
+	}
+}
+
+// rawSignatureSigner returns the r || s form of the signatures of an SM2 or
+// ECDSA P-256 key, like some PKCS #11 tokens and KMS.
+type rawSignatureSigner struct {
+	crypto.Signer
+}
+
+func (s *rawSignatureSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	der, err := s.Signer.Sign(rand, digest, opts)
+	if err != nil {
+		return nil, err
+	}
+	r, ss := new(big.Int), new(big.Int)
+	input := cryptobyte.String(der)
+	var inner cryptobyte.String
+	if !input.ReadASN1(&inner, cryptobyte_asn1.SEQUENCE) ||
+		!inner.ReadASN1Integer(r) || !inner.ReadASN1Integer(ss) {
+		return nil, errors.New("malformed signature")
+	}
+	raw := make([]byte, 64)
+	r.FillBytes(raw[:32])
+	ss.FillBytes(raw[32:])
+	return raw, nil
+}
+
+// mismatchedSigner signs with the key of Signer, but claims pub.
+type mismatchedSigner struct {
+	crypto.Signer
+	pub crypto.PublicKey
+}
+
+func (s *mismatchedSigner) Public() crypto.PublicKey {
+	return s.pub
+}
+
+func TestCreateWithRawSignatureSigner(t *testing.T) {
+	sm2Key, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		name   string
+		key    crypto.Signer
+		sigAlg SignatureAlgorithm
+	}{
+		{"SM2", sm2Key, SM2WithSM3},
+		{"P-256", ecKey, ECDSAWithSHA256},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			signer := &rawSignatureSigner{test.key}
+			template := &x509.Certificate{
+				SerialNumber:          big.NewInt(1),
+				Subject:               pkix.Name{CommonName: "KMS " + test.name},
+				NotBefore:             time.Now().Add(-time.Hour),
+				NotAfter:              time.Now().Add(time.Hour),
+				KeyUsage:              KeyUsageCertSign | KeyUsageCRLSign,
+				BasicConstraintsValid: true,
+				IsCA:                  true,
+			}
+			der, err := CreateCertificate(rand.Reader, template, template, signer.Public(), signer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cert, err := ParseCertificate(der)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cert.SignatureAlgorithm != test.sigAlg {
+				t.Errorf("unexpected signature algorithm %v", cert.SignatureAlgorithm)
+			}
+			if err := cert.CheckSignatureFrom(cert); err != nil {
+				t.Fatal(err)
+			}
+
+			csrDER, err := CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: template.Subject}, signer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			csr, err := ParseCertificateRequest(csrDER)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := csr.CheckSignature(); err != nil {
+				t.Fatal(err)
+			}
+
+			crlDER, err := CreateRevocationList(rand.Reader, &x509.RevocationList{
+				Number:     big.NewInt(1),
+				ThisUpdate: time.Now(),
+				NextUpdate: time.Now().Add(time.Hour),
+			}, cert, signer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			crl, err := ParseRevocationList(crlDER)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := crl.CheckSignatureFrom(cert); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	// raw signatures of a wrong key are still rejected
+	otherKey, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "KMS"}}
+	signer := &rawSignatureSigner{&mismatchedSigner{otherKey, sm2Key.Public()}}
+	if _, err := CreateCertificate(rand.Reader, template, template, sm2Key.Public(), signer); err == nil {
+		t.Error("expected error with a raw signature of another key")
+	}
+}
+
+// legacySM2Certificate returns a self-signed certificate of priv, signed
+// with the SM2 signature of the hash digest of the TBS, without ZA, as some
+// legacy CAs did with SM2WithSHA1 and SM2WithSHA256.
+func legacySM2Certificate(t *testing.T, priv *sm2.PrivateKey, sigAlg SignatureAlgorithm, hash crypto.Hash) []byte {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(2015),
+		Subject:               pkix.Name{CommonName: "Legacy SM2 CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := CreateCertificate(rand.Reader, template, template, priv.Public(), priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 1.2.156.10197.1.501 becomes 502 or 503, of the same length
+	sm3OID, err := asn1.Marshal(oidSignatureSM2WithSM3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacyOID := bytes.Clone(sm3OID)
+	legacyOID[len(legacyOID)-1] += byte(sigAlg - SM2WithSHA1 + 1)
+	tbs := bytes.Replace(cert.RawTBSCertificate, sm3OID, legacyOID, 1)
+
+	h := hash.New()
+	h.Write(tbs)
+	signature, err := sm2.SignASN1(rand.Reader, priv, h.Sum(nil), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if der, err = assemble(tbs, sigAlg, tbsSignatureAI(tbs), signature); err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func TestSM2WithSHALegacyCertificates(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der := legacySM2Certificate(t, priv, SM2WithSHA256, crypto.SHA256)
+	cert, err := ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.SignatureAlgorithm != SM2WithSHA256 {
+		t.Fatalf("unexpected signature algorithm %v", cert.SignatureAlgorithm)
+	}
+	if err := cert.CheckSignatureFrom(cert); err != nil {
+		t.Fatal(err)
+	}
+	roots := NewCertPool()
+	roots.AddCert(cert)
+	if _, err := cert.Verify(VerifyOptions{Roots: roots}); err != nil {
+		t.Fatal(err)
+	}
+	// with ZA, as SM2WithSM3, the signature is invalid
+	signature, err := priv.Sign(rand.Reader, cert.RawTBSCertificate, sm2.DefaultSM2SignerOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.CheckSignature(SM2WithSHA256, cert.RawTBSCertificate, signature); err == nil {
+		t.Error("expected error with a signature with ZA")
+	}
+
+	der = legacySM2Certificate(t, priv, SM2WithSHA1, crypto.SHA1)
+	if cert, err = ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+	if cert.SignatureAlgorithm != SM2WithSHA1 {
+		t.Fatalf("unexpected signature algorithm %v", cert.SignatureAlgorithm)
+	}
+	// SHA-1 is only accepted by CheckSignature
+	if err := cert.CheckSignatureFrom(cert); !errors.As(err, new(x509.InsecureAlgorithmError)) {
+		t.Errorf("expected InsecureAlgorithmError, got %v", err)
+	}
+	if err := cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature); err != nil {
+		t.Fatal(err)
+	}
+
+	// signing is refused
+	template := &x509.Certificate{
+		SerialNumber:       big.NewInt(1),
+		Subject:            pkix.Name{CommonName: "SM2-SHA256"},
+		SignatureAlgorithm: SM2WithSHA256,
+	}
+	if _, err := CreateCertificate(rand.Reader, template, template, priv.Public(), priv); err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("expected error signing with SM2WithSHA256, got %v", err)
+	}
+}
+
+// rsaWithSM3CertPEM is a self-signed RSAWithSM3 timestamping certificate.
+// OpenSSL 3 refuses to sign with SM3 and RSA, but recovers from its signature
+// the SM3 DigestInfo of its TBS with pkeyutl -verifyrecover.
+const rsaWithSM3CertPEM = `
+-----BEGIN CERTIFICATE-----
+MIIC6TCCAdKgAwIBAgICEjQwDAYIKoEcz1UBg3gFADAjMQswCQYDVQQGEwJDTjEU
+MBIGA1UEAxMLUlNBLVNNMyBUU0EwIBcNMjYxMDE2MDAwMDAwWhgPMjEyNjEwMTYw
+MDAwMDBaMCMxCzAJBgNVBAYTAkNOMRQwEgYDVQQDEwtSU0EtU00zIFRTQTCCASIw
+DQYJKoZIhvcNAQEBBQADggEPADCCAQoCggEBAJiN54ExBvMQVON3USEC7YeHJA2o
+2N7KQPx+hBH2aLAe8EKeTArObKtUTKJw2cyab5UUXrhVXTGH0J/Lyk1+ck5YnHsY
+T2lY7EEKnD7MNrU+mAPpdKskh7HlgZhBDbED/V97yxA0Ige0bZMIoVRJ8wpmftuA
+oFg+bkljifa+fjGWVGwxmMUslAj69QyYYirOzdAfyRad3JMImEmvOlJDMljqSoSj
+n9dMMrOnM0Ric8eJ7fHtrroaA5IG4qqL/ybKhD5zYL4tdwba7nYiIHwC6brBwnHz
++eKya39wj8MN5CWMY40L+HtYxIE2Qdf0NGaJ0aT2sw26n5CM8/M+OC35VW0CAwEA
+AaMnMCUwDgYDVR0PAQH/BAQDAgeAMBMGA1UdJQQMMAoGCCsGAQUFBwMIMAwGCCqB
+HM9VAYN4BQADggEBAGgGBDQkzqCjerUoloisTURT+StUsn3N0hisUcGLhEIpfGS3
+Cp/tr1qba3uwqLMbpE9L6+F7tauDO07VnWpjrsnmXBJYUadNyf0R6o0R/r53MQYf
+yYWSmfCTiTQLsQuhXLCm9/GPnl1iYl+K+XU7Y6Z6gRIiVogEM3uOIezsMCs+8tr4
+Z5pqitFPkX6CrofDHnmuxMx4MFcptMambRqxqWGGE7c/1hLRd139cKcfpdN7CyEu
+s+vw3pHhHI5+f2LI0Jxepv5/gxOUssa4Zrxap9J18m/FqTA5ad4L7Z6xkRRSoxGr
+Uvofyb4vssNrkbpc0e3vrulI5Nzv+wmTEd7l39o=
+-----END CERTIFICATE-----
+`
+
+func TestRSAWithSM3(t *testing.T) {
+	block, _ := pem.Decode([]byte(rsaWithSM3CertPEM))
+	fixture, err := ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fixture.SignatureAlgorithm != RSAWithSM3 {
+		t.Fatalf("unexpected signature algorithm %v", fixture.SignatureAlgorithm)
+	}
+	if err := fixture.CheckSignature(fixture.SignatureAlgorithm, fixture.RawTBSCertificate, fixture.Signature); err != nil {
+		t.Fatal(err)
+	}
+
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "RSA-SM3 CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              KeyUsageCertSign | KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		SignatureAlgorithm:    RSAWithSM3,
+	}
+	der, err := CreateCertificate(rand.Reader, template, template, priv.Public(), priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.SignatureAlgorithm != RSAWithSM3 {
+		t.Fatalf("unexpected signature algorithm %v", cert.SignatureAlgorithm)
+	}
+	if err := cert.CheckSignatureFrom(cert); err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.CheckSignature(fixture.SignatureAlgorithm, fixture.RawTBSCertificate, fixture.Signature); err == nil {
+		t.Error("expected error with the key of another certificate")
+	}
+
+	csrDER, err := CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: template.Subject, SignatureAlgorithm: RSAWithSM3}, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr, err := ParseCertificateRequest(csrDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := csr.CheckSignature(); err != nil || csr.SignatureAlgorithm != RSAWithSM3 {
+		t.Fatalf("unexpected CSR %v: %v", csr.SignatureAlgorithm, err)
+	}
+
+	crlDER, err := CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:             big.NewInt(1),
+		ThisUpdate:         time.Now(),
+		NextUpdate:         time.Now().Add(time.Hour),
+		SignatureAlgorithm: RSAWithSM3,
+	}, cert, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crl, err := ParseRevocationList(crlDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := crl.CheckSignatureFrom(cert); err != nil || crl.SignatureAlgorithm != RSAWithSM3 {
+		t.Fatalf("unexpected CRL %v: %v", crl.SignatureAlgorithm, err)
+	}
+
+	// SHA256WithRSA remains the default
+	template.SignatureAlgorithm = UnknownSignatureAlgorithm
+	if der, err = CreateCertificate(rand.Reader, template, template, priv.Public(), priv); err != nil {
+		t.Fatal(err)
+	}
+	if cert, err = ParseCertificate(der); err != nil || cert.SignatureAlgorithm != SHA256WithRSA {
+		t.Fatalf("unexpected default signature algorithm: %v", err)
+	}
+}
+
+func TestSM2NullParameters(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nullAI, err := asn1.Marshal(pkix.AlgorithmIdentifier{Algorithm: oidSignatureSM2WithSM3, Parameters: asn1.NullRawValue})
+	if err != nil {
+		t.Fatal(err)
+	}
+	absentAI, err := asn1.Marshal(pkix.AlgorithmIdentifier{Algorithm: oidSignatureSM2WithSM3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "CFCA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              KeyUsageCertSign | KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := CreateCertificateWithOptions(rand.Reader, template, template, priv.Public(), priv, &CreateCertificateOptions{SM2NullParameters: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(der, nullAI); n != 2 {
+		t.Errorf("expected NULL parameters in the inner and outer identifiers, found %d", n)
+	}
+	cert, err := ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.SignatureAlgorithm != SM2WithSM3 {
+		t.Fatalf("unexpected signature algorithm %v", cert.SignatureAlgorithm)
+	}
+	if err := cert.CheckSignatureFrom(cert); err != nil {
+		t.Fatal(err)
+	}
+
+	// NULL parameters in the TBS, absent in the outer identifier
+	mixed, err := assemble(cert.RawTBSCertificate, SM2WithSM3, absentAI, cert.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mixedCert, err := ParseCertificate(mixed); err != nil {
+		t.Fatal(err)
+	} else if err := mixedCert.CheckSignatureFrom(mixedCert); err != nil {
+		t.Fatal(err)
+	}
+	// other identifiers must still match
+	ecdsaAI, err := asn1.Marshal(pkix.AlgorithmIdentifier{Algorithm: oidSignatureECDSAWithSHA256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mismatched, err := assemble(cert.RawTBSCertificate, ECDSAWithSHA256, ecdsaAI, cert.Signature); err != nil {
+		t.Fatal(err)
+	} else if _, err := ParseCertificate(mismatched); err == nil {
+		t.Error("expected error with mismatched signature algorithm identifiers")
+	}
+
+	// re-signing keeps the parameters of the TBS
+	tbs, sigAlg, err := CreateTBSCertificateWithOptions(template, template, priv.Public(), &CreateCertificateOptions{SM2NullParameters: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := priv.Sign(rand.Reader, tbs, sm2.DefaultSM2SignerOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if der, err = AssembleCertificate(tbs, sigAlg, signature, nil); err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(der, nullAI); n != 2 {
+		t.Errorf("expected NULL parameters in the assembled certificate, found %d", n)
+	}
+
+	csrDER, err := CreateCertificateRequestWithOptions(rand.Reader, &x509.CertificateRequest{Subject: template.Subject}, priv, &CreateCertificateRequestOptions{SM2NullParameters: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(csrDER, nullAI) {
+		t.Error("expected NULL parameters in the CSR")
+	}
+	if csr, err := ParseCertificateRequest(csrDER); err != nil {
+		t.Fatal(err)
+	} else if err := csr.CheckSignature(); err != nil {
+		t.Fatal(err)
+	}
+
+	crlDER, err := CreateRevocationListWithOptions(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now(),
+		NextUpdate: time.Now().Add(time.Hour),
+	}, cert, priv, &CreateRevocationListOptions{SM2NullParameters: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(crlDER, nullAI); n != 2 {
+		t.Errorf("expected NULL parameters in the inner and outer CRL identifiers, found %d", n)
+	}
+	if crl, err := ParseRevocationList(crlDER); err != nil {
+		t.Fatal(err)
+	} else if err := crl.CheckSignatureFrom(cert); err != nil {
+		t.Fatal(err)
+	}
+
+	// absent by default
+	if der, err = CreateCertificate(rand.Reader, template, template, priv.Public(), priv); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(der, nullAI) || bytes.Count(der, absentAI) != 2 {
+		t.Error("expected absent parameters by default")
+	}
+}
+
+func TestCheckSignatureWithUID(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid := []byte("issuer@example.com")
+	signWithUID := func(tbs []byte) []byte {
+		t.Helper()
+		signature, err := priv.Sign(rand.Reader, tbs, sm2.NewSM2SignerOption(true, uid))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signature
+	}
+	opts := &SMSignatureOptions{UID: uid}
+
+	// the issuer is signed with the default UID, cert with uid
+	issuer := newTestIssuer(t, priv, pkix.Name{CommonName: "SM2 UID CA"})
+	tbs, sigAlg := issuer.RawTBSCertificate, issuer.SignatureAlgorithm
+	der, err := assemble(tbs, sigAlg, tbsSignatureAI(tbs), signWithUID(tbs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.CheckSignatureFrom(cert); err == nil {
+		t.Error("expected error with the default UID")
+	}
+	if err := cert.CheckSignatureFromWithOptions(cert, opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.CheckSignature(sigAlg, cert.RawTBSCertificate, cert.Signature); err == nil {
+		t.Error("expected error with the default UID")
+	}
+	if err := cert.CheckSignatureWithOptions(sigAlg, cert.RawTBSCertificate, cert.Signature, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	tbs, sigAlg, err = CreateTBSCertificateRequest(&x509.CertificateRequest{Subject: issuer.Subject}, priv.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if der, err = assemble(tbs, sigAlg, nil, signWithUID(tbs)); err != nil {
+		t.Fatal(err)
+	}
+	csr, err := ParseCertificateRequest(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := csr.CheckSignature(); err == nil {
+		t.Error("expected error with the default UID")
+	}
+	if err := csr.CheckSignatureWithOptions(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	tbs, sigAlg, err = CreateTBSRevocationList(&x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now(),
+		NextUpdate: time.Now().Add(time.Hour),
+	}, cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if der, err = assemble(tbs, sigAlg, tbsSignatureAI(tbs), signWithUID(tbs)); err != nil {
+		t.Fatal(err)
+	}
+	rl, err := ParseRevocationList(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rl.CheckSignatureFrom(cert); err == nil {
+		t.Error("expected error with the default UID")
+	}
+	if err := rl.CheckSignatureFromWithOptions(cert, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	// nil and empty options use the default UID
+	for _, opts := range []*SMSignatureOptions{nil, {}} {
+		if err := issuer.CheckSignatureFromWithOptions(issuer, opts); err != nil {
+			t.Errorf("%v: %v", opts, err)
+		}
+	}
+	if err := issuer.CheckSignatureFromWithOptions(issuer, opts); err == nil {
+		t.Error("expected error with another UID")
+	}
+}
+
+func TestCreateCertificateRequestWithUID(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid := []byte("account-20240001")
+	opts := &SMSignatureOptions{UID: uid}
+	template := &x509.CertificateRequest{Subject: pkix.Name{CommonName: "RA subscriber"}}
+
+	der, err := CreateCertificateRequestWithOptions(rand.Reader, template, priv, &CreateCertificateRequestOptions{UID: uid})
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr, err := ParseCertificateRequest(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := csr.CheckSignature(); err == nil {
+		t.Error("expected error with the default UID")
+	}
+	if err := csr.CheckSignatureWithOptions(opts); err != nil {
+		t.Fatal(err)
+	}
+	if !sm2.VerifyASN1WithSM2(priv.Public().(*ecdsa.PublicKey), uid, csr.RawTBSCertificateRequest, csr.Signature) {
+		t.Error("signature is not made with the UID")
+	}
+
+	// the default UID signs the same request
+	for _, createOpts := range []*CreateCertificateRequestOptions{nil, {}} {
+		defaultDER, err := CreateCertificateRequestWithOptions(rand.Reader, template, priv, createOpts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defaultCSR, err := ParseCertificateRequest(defaultDER)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(defaultCSR.RawTBSCertificateRequest, csr.RawTBSCertificateRequest) {
+			t.Error("the UID changed the request")
+		}
+		if err := defaultCSR.CheckSignature(); err != nil {
+			t.Errorf("%v: %v", createOpts, err)
+		}
+		if err := defaultCSR.CheckSignatureWithOptions(opts); err == nil {
+			t.Error("expected error with another UID")
+		}
+	}
+
+	// the UID is ignored by other keys
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if der, err = CreateCertificateRequestWithOptions(rand.Reader, template, ecKey, &CreateCertificateRequestOptions{UID: uid}); err != nil {
+		t.Fatal(err)
+	}
+	if csr, err = ParseCertificateRequest(der); err != nil {
+		t.Fatal(err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// sm2WithoutZACACertPEM and sm2WithoutZALeafCertPEM are signed with the SM2
+// signature of the SM3 digest of the TBS, without ZA, as some HSMs do.
+const sm2WithoutZACACertPEM = `-----BEGIN CERTIFICATE-----
+MIIBjTCCATOgAwIBAgIDAICWMAoGCCqBHM9VAYN1MC0xDzANBgNVBAoTBkdNIEhT
+TTEaMBgGA1UEAxMRU00yIENBIHdpdGhvdXQgWkEwHhcNMjQwMTAxMDAwMDAwWhcN
+NDQwMTAxMDAwMDAwWjAtMQ8wDQYDVQQKEwZHTSBIU00xGjAYBgNVBAMTEVNNMiBD
+QSB3aXRob3V0IFpBMFkwEwYHKoZIzj0CAQYIKoEcz1UBgi0DQgAELXixIs5QqQVV
+UsTX80azAYUIyPy1sxunzR5POPwO2HQbMJ1593TQWwiMilFNm5Ch/bL8MIq+i7Qb
+UZQDWA0ll6NCMEAwDgYDVR0PAQH/BAQDAgEGMA8GA1UdEwEB/wQFMAMBAf8wHQYD
+VR0OBBYEFCbvm1oxT8X/+NILHG+Fh0nF9lkeMAoGCCqBHM9VAYN1A0gAMEUCIF++
+A93V8fKYHOd7pZ6HidKuO1O8z7FXH4RZgRsSYCheAiEArlwI9OzgHrRyZypjNpji
+ofTAmqTdYAf9SG79r/LOm3o=
+-----END CERTIFICATE-----
+`
+
+const sm2WithoutZALeafCertPEM = `-----BEGIN CERTIFICATE-----
+MIIBrTCCAVOgAwIBAgIDAICXMAoGCCqBHM9VAYN1MC0xDzANBgNVBAoTBkdNIEhT
+TTEaMBgGA1UEAxMRU00yIENBIHdpdGhvdXQgWkEwHhcNMjQwMTAxMDAwMDAwWhcN
+NDQwMTAxMDAwMDAwWjArMQ8wDQYDVQQKEwZHTSBIU00xGDAWBgNVBAMTD3d3dy5l
+eGFtcGxlLmNvbTBZMBMGByqGSM49AgEGCCqBHM9VAYItA0IABHKnHLiWkDJOE+hi
+APbt2aNnf94M0hUxCyvXarqT9J/V0t3dD3cCJ1qEDOi9YyMz08dPoge7R23BeUH5
+5GiEMc+jZDBiMA4GA1UdDwEB/wQEAwIHgDATBgNVHSUEDDAKBggrBgEFBQcDATAf
+BgNVHSMEGDAWgBQm75taMU/F//jSCxxvhYdJxfZZHjAaBgNVHREEEzARgg93d3cu
+ZXhhbXBsZS5jb20wCgYIKoEcz1UBg3UDSAAwRQIhAM76VsHtMz+STcTDmIYOEtR4
+GuR2/5kFiaKNkgJKjMm/AiAIwoVPKdvmVUyyHUq8bLSzbqCALXQZKCpioMAquc+a
+ng==
+-----END CERTIFICATE-----
+`
+
+func TestCheckSignatureWithoutZA(t *testing.T) {
+	parse := func(data string) *Certificate {
+		t.Helper()
+		block, _ := pem.Decode([]byte(data))
+		cert, err := ParseCertificate(block.Bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert
+	}
+	ca := parse(sm2WithoutZACACertPEM)
+	leaf := parse(sm2WithoutZALeafCertPEM)
+
+	// rejected by default
+	if err := leaf.CheckSignatureFrom(ca); err == nil {
+		t.Fatal("signature without ZA verified by default")
+	}
+	if err := leaf.CheckSignatureFromWithOptions(ca, &SMSignatureOptions{}); err == nil {
+		t.Fatal("signature without ZA verified without AllowWithoutZA")
+	}
+	roots := NewCertPool()
+	roots.AddCert(ca)
+	if _, err := leaf.Verify(VerifyOptions{Roots: roots, CurrentTime: ca.NotBefore.Add(time.Hour)}); err == nil {
+		t.Fatal("chain signed without ZA verified")
+	}
+
+	opts := &SMSignatureOptions{AllowWithoutZA: true}
+	if err := ca.CheckSignatureFromWithOptions(ca, opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := leaf.CheckSignatureFromWithOptions(ca, opts); err != nil {
+		t.Fatal(err)
+	}
+	if !ca.SignedWithoutZA(leaf.RawTBSCertificate, leaf.Signature, opts) {
+		t.Error("signature not reported as computed without ZA")
+	}
+	// the options are only read, and may be shared by concurrent checks
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := leaf.CheckSignatureFromWithOptions(ca, opts); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	other, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkSignatureWithOptions(leaf.SignatureAlgorithm, leaf.RawTBSCertificate, leaf.Signature, other.Public(), false, opts); err == nil {
+		t.Error("signature without ZA verified with another key")
+	}
+
+	// standard signatures are still reported as such
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := newTestIssuer(t, priv, pkix.Name{CommonName: "SM2 CA"})
+	if err := cert.CheckSignatureFromWithOptions(cert, opts); err != nil {
+		t.Fatal(err)
+	}
+	if cert.SignedWithoutZA(cert.RawTBSCertificate, cert.Signature, opts) {
+		t.Error("standard signature reported as computed without ZA")
+	}
+	if leaf.SignedWithoutZA(leaf.RawTBSCertificate, leaf.Signature, opts) {
+		t.Error("signature reported as computed without ZA by another key")
+	}
+}
+
+func TestUnknownPublicKey(t *testing.T) {
+	// a synthetic SM9 signature master public key, a point of G2
+	sm9Point := make([]byte, 129)
+	sm9Point[0] = 4
+	if _, err := io.ReadFull(rand.Reader, sm9Point[1:]); err != nil {
+		t.Fatal(err)
+	}
+	oidSM9 := asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 302, 1}
+	spki, err := asn1.Marshal(publicKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidSM9, Parameters: asn1.NullRawValue},
+		PublicKey: asn1.BitString{Bytes: sm9Point, BitLength: 8 * len(sm9Point)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if der, err := MarshalPKIXPublicKey(UnknownPublicKey(spki)); err != nil || !bytes.Equal(der, spki) {
+		t.Fatalf("unexpected SubjectPublicKeyInfo %x, %v", der, err)
+	}
+
+	caKey, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := newTestIssuer(t, caKey, pkix.Name{CommonName: "SM2 CA"})
+	if ca.UnknownPublicKeyOID() != nil || ca.RawPublicKeyBytes() != nil {
+		t.Error("unexpected unknown public key of a SM2 certificate")
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "SM9 user"},
+		DNSNames:     []string{"sm9.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     KeyUsageDigitalSignature,
+		ExtKeyUsage:  []ExtKeyUsage{ExtKeyUsageClientAuth},
+	}
+	der, err := CreateCertificate(rand.Reader, template, ca, UnknownPublicKey(spki), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.PublicKeyAlgorithm != UnknownPublicKeyAlgorithm || cert.PublicKey != nil {
+		t.Fatalf("unexpected public key %v %v", cert.PublicKeyAlgorithm, cert.PublicKey)
+	}
+	if !cert.UnknownPublicKeyOID().Equal(oidSM9) || !bytes.Equal(cert.RawPublicKeyBytes(), sm9Point) {
+		t.Errorf("unexpected public key %v %x", cert.UnknownPublicKeyOID(), cert.RawPublicKeyBytes())
+	}
+
+	roots := NewCertPool()
+	roots.AddCert(ca)
+	if _, err := cert.Verify(VerifyOptions{Roots: roots, KeyUsages: []ExtKeyUsage{ExtKeyUsageClientAuth}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the key survives a new certificate from the parsed one
+	if der, err = CreateCertificate(rand.Reader, cert.asX509(), ca, UnknownPublicKey(cert.RawSubjectPublicKeyInfo), caKey); err != nil {
+		t.Fatal(err)
+	}
+	reissued, err := ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(reissued.RawSubjectPublicKeyInfo, spki) {
+		t.Errorf("unexpected SubjectPublicKeyInfo %x", reissued.RawSubjectPublicKeyInfo)
+	}
+	if der, err = RenewCertificate(rand.Reader, cert, ca, nil, caKey, cert.NotBefore, cert.NotAfter); err != nil {
+		t.Fatal(err)
+	}
+	if renewed, err := ParseCertificate(der); err != nil || !bytes.Equal(renewed.RawSubjectPublicKeyInfo, spki) {
+		t.Errorf("unexpected renewed certificate: %v", err)
+	}
+}
+
+func TestRevocationListTimeExtensions(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := newTestIssuer(t, priv, pkix.Name{CommonName: "GM CRL CA"})
+	template := &x509.RevocationList{
+		Number:     big.NewInt(3),
+		ThisUpdate: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+		NextUpdate: time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC),
+	}
+	createCRL := func(opts *CreateRevocationListOptions) *RevocationList {
+		t.Helper()
+		der, err := CreateRevocationListWithOptions(rand.Reader, template, issuer, priv, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		crl, err := ParseRevocationList(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return crl
+	}
+
+	// not asserted
+	crl := createCRL(nil)
+	if expired, err := crl.ExpiredCertsOnCRL(); err != nil || !expired.IsZero() {
+		t.Errorf("unexpected ExpiredCertsOnCRL %v: %v", expired, err)
+	}
+	if next, err := crl.NextPublish(); err != nil || !next.IsZero() {
+		t.Errorf("unexpected NextPublish %v: %v", next, err)
+	}
+
+	expiredCertsOnCRL := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	nextPublish := time.Date(2025, 6, 8, 0, 0, 0, 0, time.UTC)
+	crl = createCRL(&CreateRevocationListOptions{ExpiredCertsOnCRL: expiredCertsOnCRL, NextPublish: nextPublish})
+	if expired, err := crl.ExpiredCertsOnCRL(); err != nil || !expired.Equal(expiredCertsOnCRL) {
+		t.Errorf("unexpected ExpiredCertsOnCRL %v: %v", expired, err)
+	}
+	if next, err := crl.NextPublish(); err != nil || !next.Equal(nextPublish) {
+		t.Errorf("unexpected NextPublish %v: %v", next, err)
+	}
+	wantOrder := []asn1.ObjectIdentifier{oidExtensionAuthorityKeyId, oidExtensionCRLNumber, oidExtensionExpiredCertsOnCRL, oidExtensionNextPublish}
+	if len(crl.Extensions) != len(wantOrder) {
+		t.Fatalf("unexpected extensions %v", crl.Extensions)
+	}
+	for i, e := range crl.Extensions {
+		if !e.Id.Equal(wantOrder[i]) || e.Critical {
+			t.Errorf("extension %d: got %v, want %v", i, e.Id, wantOrder[i])
+		}
+	}
+	// ExpiredCertsOnCRL is a GeneralizedTime, next publish a UTCTime
+	if v := crl.Extensions[2].Value; v[0] != asn1.TagGeneralizedTime || string(v[2:]) != "20200101000000Z" {
+		t.Errorf("unexpected ExpiredCertsOnCRL encoding %x", v)
+	}
+	// the next publish extension value, a bare UTCTime
+	encodedNextPublish, _ := hex.DecodeString("170d3235303630383030303030305a")
+	if !bytes.Equal(crl.Extensions[3].Value, encodedNextPublish) {
+		t.Errorf("unexpected next publish encoding %x", crl.Extensions[3].Value)
+	}
+
+	// ExtraExtensions take precedence
+	template.ExtraExtensions = []pkix.Extension{{Id: oidExtensionNextPublish, Value: encodedNextPublish}}
+	crl = createCRL(&CreateRevocationListOptions{NextPublish: nextPublish.Add(time.Hour)})
+	if next, err := crl.NextPublish(); err != nil || !next.Equal(nextPublish) {
+		t.Errorf("unexpected NextPublish %v: %v", next, err)
+	}
+	if len(crl.Extensions) != 3 {
+		t.Errorf("unexpected extensions %v", crl.Extensions)
+	}
+
+	crl.Extensions[2].Value = []byte{0x05, 0x00}
+	if _, err := crl.NextPublish(); err == nil {
+		t.Error("expected error for a malformed next publish extension")
+	}
+}
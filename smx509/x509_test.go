@@ -25,6 +25,7 @@ import (
 	"runtime"
 	"slices"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -1297,7 +1298,7 @@ func marshalAndParseCSR(t *testing.T, template *x509.CertificateRequest) *Certif
 }
 
 func TestCertificateRequestOverrides(t *testing.T) {
-	sanContents, err := marshalSANs([]string{"foo.example.com"}, nil, nil, nil)
+	sanContents, err := marshalSANs([]string{"foo.example.com"}, nil, nil, nil, nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1359,7 +1360,7 @@ func TestCertificateRequestOverrides(t *testing.T) {
 		t.Errorf("bad attributes: %#v\n", csr.Attributes)
 	}
 
-	sanContents2, err := marshalSANs([]string{"foo2.example.com"}, nil, nil, nil)
+	sanContents2, err := marshalSANs([]string{"foo2.example.com"}, nil, nil, nil, nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1705,7 +1706,7 @@ func TestSHA1(t *testing.T) {
 	if sa := cert.SignatureAlgorithm; sa != ECDSAWithSHA1 {
 		t.Errorf("signature algorithm is %v, want %v", sa, ECDSAWithSHA1)
 	}
-	if !debugAllowSHA1 {
+	if !allowSHA1.Load() {
 		if err = cert.CheckSignatureFrom(cert); err == nil {
 			t.Fatalf("certificate verification succeeded incorrectly")
 		}
@@ -1713,8 +1714,8 @@ func TestSHA1(t *testing.T) {
 			t.Fatalf("certificate verification returned %v (%T), wanted InsecureAlgorithmError", err, err)
 		}
 
-		defer func(old bool) { debugAllowSHA1 = old }(debugAllowSHA1)
-		debugAllowSHA1 = true
+		defer SetAllowSHA1(allowSHA1.Load())
+		SetAllowSHA1(true)
 	}
 	if err = cert.CheckSignatureFrom(cert); err != nil {
 		t.Fatalf("SHA-1 certificate did not verify with GODEBUG=x509sha1=1: %v", err)
@@ -3269,8 +3270,8 @@ func TestParseUniqueID(t *testing.T) {
 }
 
 func TestDisableSHA1ForCertOnly(t *testing.T) {
-	defer func(old bool) { debugAllowSHA1 = old }(debugAllowSHA1)
-	debugAllowSHA1 = false
+	defer SetAllowSHA1(allowSHA1.Load())
+	SetAllowSHA1(false)
 
 	tmpl := &Certificate{
 		SerialNumber:          big.NewInt(1),
@@ -3721,6 +3722,132 @@ func TestCertificateOIDPoliciesGODEBUG(t *testing.T) {
 	}
 }
 
+func TestSetUsePolicies(t *testing.T) {
+	defer usePoliciesOverride.Store(usePoliciesOverride.Load())
+	// the override takes precedence over GODEBUG
+	t.Setenv("GODEBUG", "x509usepolicies=0")
+
+	template := Certificate{
+		SerialNumber:      big.NewInt(1),
+		Subject:           pkix.Name{CommonName: "Cert"},
+		NotBefore:         time.Unix(1000, 0),
+		NotAfter:          time.Unix(100000, 0),
+		PolicyIdentifiers: []asn1.ObjectIdentifier{[]int{1, 2, 3}},
+		Policies:          []x509.OID{mustNewOIDFromInts([]uint64{1, 2, 4})},
+	}
+	for _, use := range []bool{true, false, true} {
+		SetUsePolicies(use)
+		certDER, err := CreateCertificate(rand.Reader, &template, &template, rsaPrivateKey.Public(), rsaPrivateKey)
+		if err != nil {
+			t.Fatalf("CreateCertificate() unexpected error: %v", err)
+		}
+		cert, err := ParseCertificate(certDER)
+		if err != nil {
+			t.Fatalf("ParseCertificate() unexpected error: %v", err)
+		}
+		want := template.PolicyIdentifiers
+		if use {
+			want = []asn1.ObjectIdentifier{[]int{1, 2, 4}}
+		}
+		if !slices.EqualFunc(cert.PolicyIdentifiers, want, slices.Equal) {
+			t.Errorf("SetUsePolicies(%v): cert.PolicyIdentifiers = %v, want: %v", use, cert.PolicyIdentifiers, want)
+		}
+	}
+}
+
+func TestSetAllowSHA1(t *testing.T) {
+	defer SetAllowSHA1(allowSHA1.Load())
+	pemBlock, _ := pem.Decode([]byte(ecdsaSHA1CertPem))
+	cert, err := ParseCertificate(pemBlock.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetAllowSHA1(false)
+	if err = cert.CheckSignatureFrom(cert); err == nil {
+		t.Fatal("certificate verification succeeded incorrectly")
+	}
+	SetAllowSHA1(true)
+	if err = cert.CheckSignatureFrom(cert); err != nil {
+		t.Fatalf("SHA-1 certificate did not verify with SetAllowSHA1(true): %v", err)
+	}
+	SetAllowSHA1(false)
+	if err = cert.CheckSignatureFrom(cert); err == nil {
+		t.Fatal("certificate verification succeeded incorrectly")
+	}
+}
+
+func TestSetInsecureSHA1Policy(t *testing.T) {
+	defer SetAllowSHA1(allowSHA1.Load())
+	defer SetInsecureSHA1Policy(nil)
+	SetAllowSHA1(false)
+
+	pemBlock, _ := pem.Decode([]byte(ecdsaSHA1CertPem))
+	legacy, err := ParseCertificate(pemBlock.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:       big.NewInt(1),
+		Subject:            pkix.Name{CommonName: "SHA-1"},
+		NotBefore:          time.Unix(1000, 0),
+		NotAfter:           time.Unix(100000, 0),
+		SignatureAlgorithm: SHA1WithRSA,
+	}
+	der, err := CreateCertificate(rand.Reader, template, template, rsaPrivateKey.Public(), rsaPrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSHA1 := func(cert *Certificate) error {
+		return checkSignatureWithOptions(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature, cert.PublicKey, allowSHA1For(cert), nil)
+	}
+
+	if err := legacy.CheckSignatureFrom(legacy); err == nil {
+		t.Fatal("certificate verification succeeded incorrectly")
+	}
+	var calls atomic.Int32
+	SetInsecureSHA1Policy(func(cert *Certificate) bool {
+		calls.Add(1)
+		return bytes.Equal(cert.Raw, legacy.Raw)
+	})
+	if err := legacy.CheckSignatureFrom(legacy); err != nil {
+		t.Fatalf("SHA-1 certificate accepted by the policy did not verify: %v", err)
+	}
+	if err := checkSHA1(other); err == nil {
+		t.Fatal("SHA-1 certificate rejected by the policy verified")
+	}
+	// the policy is only consulted for SHA-1 signatures
+	calls.Store(0)
+	sm2Key, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.SignatureAlgorithm = SM2WithSM3
+	if der, err = CreateCertificate(rand.Reader, template, template, sm2Key.Public(), sm2Key); err != nil {
+		t.Fatal(err)
+	}
+	cert, err := ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkSHA1(cert); err != nil || calls.Load() != 0 {
+		t.Fatalf("unexpected policy calls %d: %v", calls.Load(), err)
+	}
+
+	SetInsecureSHA1Policy(nil)
+	if err := legacy.CheckSignatureFrom(legacy); err == nil {
+		t.Fatal("certificate verification succeeded incorrectly without a policy")
+	}
+	// SetAllowSHA1 still accepts them all
+	SetAllowSHA1(true)
+	if err := checkSHA1(other); err != nil {
+		t.Fatalf("SHA-1 certificate did not verify with SetAllowSHA1(true): %v", err)
+	}
+}
+
 func TestCertificatePolicies(t *testing.T) {
 	var usePolicies = godebug.Get("x509usepolicies") != "0"
 	if !usePolicies {
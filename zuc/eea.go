@@ -2,11 +2,17 @@
 package zuc
 
 import (
+	"errors"
+
 	"github.com/yunmoon/gmsm/cipher"
 	"github.com/yunmoon/gmsm/internal/zuc"
 )
 
 const (
+	// key size in bytes for zuc 128
+	KeySize128 = 16
+	// key size in bytes for zuc 256
+	KeySize256 = 32
 	// IV size in bytes for zuc 128
 	IVSize128 = zuc.IVSize128
 	// IV size in bytes for zuc 256
@@ -27,6 +33,31 @@ func NewCipher(key, iv []byte) (cipher.SeekableStream, error) {
 	return zuc.NewCipher(key, iv)
 }
 
+// NewCipher128 creates a zuc 128 stream cipher, the key and iv must be both 16 bytes long.
+// Unlike NewCipher, it never falls back to zuc 256 according to the key length.
+func NewCipher128(key, iv []byte) (cipher.SeekableStream, error) {
+	if len(key) != KeySize128 {
+		return nil, zuc.KeySizeError(len(key))
+	}
+	if len(iv) != IVSize128 {
+		return nil, zuc.IVSizeError(len(iv))
+	}
+	return zuc.NewCipher(key, iv)
+}
+
+// NewCipher256 creates a zuc 256 stream cipher, the key must be 32 bytes long and
+// the iv must be 23 bytes (184 bits) long.
+// Unlike NewCipher, it never falls back to zuc 128 according to the key length.
+func NewCipher256(key, iv []byte) (cipher.SeekableStream, error) {
+	if len(key) != KeySize256 {
+		return nil, zuc.KeySizeError(len(key))
+	}
+	if len(iv) != IVSize256 {
+		return nil, zuc.IVSizeError(len(iv))
+	}
+	return zuc.NewCipher(key, iv)
+}
+
 // NewEEACipher create a stream cipher based on key, count, bearer and direction arguments according specification.
 // The key must be 16 bytes long and iv must be 16 bytes long, otherwise, an error will be returned.
 // The count is the 32-bit counter value, the bearer is the 5-bit bearer identity and the direction is the 1-bit
@@ -35,6 +66,30 @@ func NewEEACipher(key []byte, count, bearer, direction uint32) (cipher.SeekableS
 	return zuc.NewEEACipher(key, count, bearer, direction)
 }
 
+// NewEEA3Cipher creates a 128-EEA3 stream cipher from the 3GPP parameters, the IV
+// is packed from count, bearer and direction as specified by 3GPP TS 35.221 and
+// GB/T 33133.2. The key must be 16 bytes long, the bearer must fit in 5 bits
+// and the direction must be 0 or 1.
+func NewEEA3Cipher(key []byte, count uint32, bearer, direction uint8) (cipher.SeekableStream, error) {
+	if err := checkEEA3Params(bearer, direction); err != nil {
+		return nil, err
+	}
+	if len(key) != KeySize128 {
+		return nil, zuc.KeySizeError(len(key))
+	}
+	return zuc.NewEEACipher(key, count, uint32(bearer), uint32(direction))
+}
+
+func checkEEA3Params(bearer, direction uint8) error {
+	if bearer > 0x1f {
+		return errors.New("zuc: bearer must be a 5-bit value")
+	}
+	if direction > 1 {
+		return errors.New("zuc: direction must be a 1-bit value")
+	}
+	return nil
+}
+
 // NewCipherWithBucketSize create a new instance of the eea cipher with the specified
 // bucket size. The bucket size is rounded up to the nearest multiple of RoundBytes.
 //
@@ -53,3 +108,24 @@ func NewCipherWithBucketSize(key, iv []byte, bucketSize int) (cipher.SeekableStr
 func NewEEACipherWithBucketSize(key []byte, count, bearer, direction uint32, bucketSize int) (cipher.SeekableStream, error) {
 	return zuc.NewEEACipherWithBucketSize(key, count, bearer, direction, bucketSize)
 }
+
+// XORKeyStreamBits XORs the first nbits bits of src with the key stream of the
+// stream cipher and writes the result to dst. As 128-EEA3 specifies for messages
+// whose length is not a multiple of 8, the unused trailing bits of the last output
+// byte are set to zero.
+//
+// Dst and src must overlap entirely or not at all. XORKeyStreamBits panics if
+// src is shorter than nbits bits or dst is shorter than the used part of src.
+func XORKeyStreamBits(stream cipher.SeekableStream, dst, src []byte, nbits int) {
+	if nbits < 0 || len(src)*8 < nbits {
+		panic("zuc: input not enough bits")
+	}
+	n := (nbits + 7) / 8
+	if len(dst) < n {
+		panic("zuc: output smaller than input")
+	}
+	stream.XORKeyStream(dst[:n], src[:n])
+	if remain := nbits % 8; remain != 0 {
+		dst[n-1] &= byte(0xff << (8 - remain))
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/cipher"
 	"encoding/hex"
+	mrand "math/rand"
 	"testing"
 
 	"github.com/yunmoon/gmsm/internal/cryptotest"
@@ -49,9 +50,9 @@ func Test_EEA(t *testing.T) {
 		if err != nil {
 			t.Error(err)
 		}
-		c, err := NewEEACipher(key, test.count, test.bearer, test.direction)
+		c, err := NewEEA3Cipher(key, test.count, uint8(test.bearer), uint8(test.direction))
 		if err != nil {
-			t.Error(err)
+			t.Fatal(err)
 		}
 		in, err := hex.DecodeString(test.in)
 		if err != nil {
@@ -227,3 +228,142 @@ func BenchmarkEncrypt1K(b *testing.B) {
 func BenchmarkEncrypt8K(b *testing.B) {
 	benchmarkStream(b, make([]byte, almost8K))
 }
+
+func TestXORKeyStreamBits(t *testing.T) {
+	// 3GPP 128-EEA3 test sets, the message lengths are in bits.
+	tests := []struct {
+		key       string
+		count     uint32
+		bearer    uint32
+		direction uint32
+		nbits     int
+		in        string
+		out       string
+	}{
+		{
+			"173d14ba5003731d7a60049470f00a29",
+			0x66035492,
+			0xf,
+			0,
+			193,
+			"6cf65340735552ab0c9752fa6f9025fe0bd675d9005875b200000000",
+			"a6c85fc66afb8533aafc2518dfe784940ee1e4b030238cc800000000",
+		},
+		{
+			zucEEATests[1].key,
+			zucEEATests[1].count,
+			zucEEATests[1].bearer,
+			zucEEATests[1].direction,
+			800,
+			zucEEATests[1].in,
+			zucEEATests[1].out,
+		},
+	}
+	for i, test := range tests {
+		key, _ := hex.DecodeString(test.key)
+		in, _ := hex.DecodeString(test.in)
+		want, _ := hex.DecodeString(test.out)
+		c, err := NewEEACipher(key, test.count, test.bearer, test.direction)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := make([]byte, len(in))
+		XORKeyStreamBits(c, out, in, test.nbits)
+		if !bytes.Equal(out, want) {
+			t.Errorf("case %d, expected=%x, result=%x", i+1, want, out)
+		}
+	}
+
+	// trailing bits must be cleared
+	key, _ := hex.DecodeString(zucEEATests[0].key)
+	c, _ := NewEEACipher(key, zucEEATests[0].count, zucEEATests[0].bearer, zucEEATests[0].direction)
+	out := make([]byte, 2)
+	XORKeyStreamBits(c, out, []byte{0xff, 0xff}, 11)
+	if out[1]&0x1f != 0 {
+		t.Errorf("unused bits not cleared: %x", out)
+	}
+}
+
+func TestNewCipher256(t *testing.T) {
+	// ZUC-256 design team test vector, all zero key and iv.
+	key := make([]byte, KeySize256)
+	iv := make([]byte, IVSize256)
+	c, err := NewCipher256(key, iv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make([]byte, 8)
+	c.XORKeyStream(out, out)
+	if hex.EncodeToString(out) != "58d03ad62e032ce2" {
+		t.Errorf("unexpected key stream %x", out)
+	}
+
+	if _, err := NewCipher256(key[:KeySize128], iv); err == nil {
+		t.Errorf("expected error for zuc 128 key")
+	}
+	if _, err := NewCipher256(key, iv[:IVSize128]); err == nil {
+		t.Errorf("expected error for zuc 128 iv")
+	}
+	if _, err := NewCipher128(key, iv); err == nil {
+		t.Errorf("expected error for zuc 256 key")
+	}
+	if _, err := NewCipher128(key[:KeySize128], iv); err == nil {
+		t.Errorf("expected error for zuc 256 iv")
+	}
+	if _, err := NewCipher128(key[:KeySize128], iv[:IVSize128]); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestXORKeyStreamAtRandomOffsets(t *testing.T) {
+	key, _ := hex.DecodeString(zucEEATests[0].key)
+	iv := make([]byte, IVSize128)
+	src := make([]byte, 2000)
+	expected := make([]byte, len(src))
+	c, err := NewCipher(key, iv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.XORKeyStream(expected, src)
+
+	rnd := mrand.New(mrand.NewSource(1))
+	for _, bucketSize := range []int{0, 128, 300} {
+		c, err := NewCipherWithBucketSize(key, iv, bucketSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dst := make([]byte, len(src))
+		for i := 0; i < 500; i++ {
+			offset := rnd.Intn(len(src))
+			length := rnd.Intn(len(src) - offset + 1)
+			if i%4 == 0 {
+				// force offsets in the middle of a word
+				offset |= 1
+				if offset+length > len(src) {
+					length = len(src) - offset
+				}
+			}
+			clear(dst[offset : offset+length])
+			c.XORKeyStreamAt(dst[offset:offset+length], src[offset:offset+length], uint64(offset))
+			if !bytes.Equal(dst[offset:offset+length], expected[offset:offset+length]) {
+				t.Fatalf("bucket size %d: mismatch at offset %d, length %d", bucketSize, offset, length)
+			}
+		}
+	}
+}
+
+func TestNewEEA3CipherParams(t *testing.T) {
+	key := make([]byte, KeySize128)
+	if _, err := NewEEA3Cipher(key, 0, 0x20, 0); err == nil {
+		t.Errorf("expected error for 6-bit bearer")
+	}
+	if _, err := NewEEA3Cipher(key, 0, 0, 2); err == nil {
+		t.Errorf("expected error for 2-bit direction")
+	}
+	if _, err := NewEEA3Cipher(make([]byte, KeySize256), 0, 0, 0); err == nil {
+		t.Errorf("expected error for zuc 256 key")
+	}
+	if _, err := NewEEA3Cipher(key, 0xffffffff, 0x1f, 1); err != nil {
+		t.Error(err)
+	}
+}
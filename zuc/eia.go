@@ -3,6 +3,8 @@
 package zuc
 
 import (
+	"crypto/subtle"
+	"errors"
 	"hash"
 
 	"github.com/yunmoon/gmsm/internal/zuc"
@@ -28,8 +30,46 @@ func NewEIAHash(key []byte, count, bearer, direction uint32) (EIA, error) {
 	return zuc.NewEIAHash(key, count, bearer, direction)
 }
 
+// NewEIA3MAC creates a 128-EIA3 MAC from the 3GPP parameters, the IV is packed
+// from count, bearer and direction as specified by 3GPP TS 35.222 and GB/T 33133.3.
+// The key must be 16 bytes long, the bearer must fit in 5 bits and the direction
+// must be 0 or 1.
+func NewEIA3MAC(key []byte, count uint32, bearer, direction uint8) (EIA, error) {
+	if err := checkEEA3Params(bearer, direction); err != nil {
+		return nil, err
+	}
+	if len(key) != KeySize128 {
+		return nil, zuc.KeySizeError(len(key))
+	}
+	return zuc.NewEIAHash(key, count, uint32(bearer), uint32(direction))
+}
+
 // NewHash256 creates a new instance of the ZUC256-based hash function with the
 // given key, initialization vector (IV), and tag size.
 func NewHash256(key, iv []byte, tagSize int) (EIA, error) {
 	return zuc.NewHash256(key, iv, tagSize)
 }
+
+// EIAMac computes the 128-EIA3 MAC over the first nbits bits of msg with the
+// given key, count, bearer and direction. nbits may be any value up to len(msg)*8,
+// the unused trailing bits of msg are ignored.
+func EIAMac(key []byte, count, bearer, direction uint32, msg []byte, nbits int) ([]byte, error) {
+	if nbits < 0 || len(msg)*8 < nbits {
+		return nil, errors.New("zuc: invalid message bit length")
+	}
+	h, err := zuc.NewEIAHash(key, count, bearer, direction)
+	if err != nil {
+		return nil, err
+	}
+	return h.Finish(msg, nbits), nil
+}
+
+// EIAVerify reports whether mac is the valid 128-EIA3 MAC over the first nbits
+// bits of msg. The MAC comparison is done in constant time.
+func EIAVerify(key []byte, count, bearer, direction uint32, msg []byte, nbits int, mac []byte) (bool, error) {
+	expected, err := EIAMac(key, count, bearer, direction, msg, nbits)
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(expected, mac) == 1, nil
+}
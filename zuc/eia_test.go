@@ -138,9 +138,9 @@ var zucEIATests = []struct {
 
 func TestEIA_Finish(t *testing.T) {
 	for i, test := range zucEIATests {
-		h, err := NewEIAHash(test.key, test.count, test.bearer, test.direction)
+		h, err := NewEIA3MAC(test.key, test.count, uint8(test.bearer), uint8(test.direction))
 		if err != nil {
-			t.Error(err)
+			t.Fatal(err)
 		}
 		in := make([]byte, len(test.in)*4)
 		for j, v := range test.in {
@@ -154,6 +154,19 @@ func TestEIA_Finish(t *testing.T) {
 	}
 }
 
+func TestNewEIA3MACParams(t *testing.T) {
+	key := make([]byte, KeySize128)
+	if _, err := NewEIA3MAC(key, 0, 0x20, 0); err == nil {
+		t.Errorf("expected error for 6-bit bearer")
+	}
+	if _, err := NewEIA3MAC(key, 0, 0, 2); err == nil {
+		t.Errorf("expected error for 2-bit direction")
+	}
+	if _, err := NewEIA3MAC(key[:8], 0, 0, 0); err == nil {
+		t.Errorf("expected error for short key")
+	}
+}
+
 func TestEIA_NewHash(t *testing.T) {
 	key := make([]byte, 16)
 	iv := make([]byte, 16)
@@ -208,6 +221,39 @@ func TestEIA_Sum(t *testing.T) {
 	}
 }
 
+func TestEIAMacAndVerify(t *testing.T) {
+	for i, test := range zucEIATests {
+		in := make([]byte, len(test.in)*4)
+		for j, v := range test.in {
+			byteorder.BEPutUint32(in[j*4:], v)
+		}
+		mac, err := EIAMac(test.key, test.count, test.bearer, test.direction, in, test.nbits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(mac) != test.mac {
+			t.Errorf("case %d, expected=%s, result=%s\n", i+1, test.mac, hex.EncodeToString(mac))
+		}
+		ok, err := EIAVerify(test.key, test.count, test.bearer, test.direction, in, test.nbits, mac)
+		if err != nil || !ok {
+			t.Errorf("case %d, verify failed: %v", i+1, err)
+		}
+		mac[0] ^= 1
+		if ok, _ := EIAVerify(test.key, test.count, test.bearer, test.direction, in, test.nbits, mac); ok {
+			t.Errorf("case %d, tampered mac should not verify", i+1)
+		}
+		if ok, _ := EIAVerify(test.key, test.count, test.bearer, test.direction, in, test.nbits, mac[:2]); ok {
+			t.Errorf("case %d, truncated mac should not verify", i+1)
+		}
+	}
+	if _, err := EIAMac(zucEIATests[0].key, 0, 0, 0, []byte{0}, 9); err == nil {
+		t.Errorf("expected error for too many bits")
+	}
+	if _, err := EIAMac(zucEIATests[0].key[:8], 0, 0, 0, []byte{0}, 1); err == nil {
+		t.Errorf("expected error for invalid key")
+	}
+}
+
 func TestEIAHash(t *testing.T) {
 	t.Run("EIA-128", func(t *testing.T) {
 		cryptotest.TestHash(t, func() hash.Hash {